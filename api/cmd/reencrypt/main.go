@@ -0,0 +1,80 @@
+// reencrypt encrypts posts and media stored before encryption was enabled, and moves
+// data keys onto the active master key after a rotation. Retired master keys can be
+// removed from ENCRYPTION_KEYS once a run reports nothing left to rewrap
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+
+	"github.com/Xebec19/jibe/api/internal/db"
+	"github.com/Xebec19/jibe/api/internal/layers/container"
+	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/pkg/config"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func main() {
+
+	envPath := flag.String("env", "../../.env", "path to env file")
+	batchSize := flag.Int("batch", 100, "records loaded per query")
+	dryRun := flag.Bool("dry-run", false, "report what would change without writing")
+	flag.Parse()
+
+	cfg, err := config.NewConfig(*envPath)
+	if err != nil {
+		slog.Error("Config is invalid", "error", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	logger := logger.NewLogger(slog.LevelInfo)
+
+	pool, err := pgxpool.New(ctx, cfg.DbConn)
+	if err != nil {
+		logger.Error("DB Pool creation failed!", "error", err)
+		os.Exit(1)
+	}
+	defer pool.Close()
+
+	c := container.NewContainer(ctx, cfg, logger, pool, db.New(pool))
+
+	if err := c.SetupStorage(); err != nil {
+		logger.Error("Storage setup failed!", "error", err)
+		os.Exit(1)
+	}
+
+	if err := c.SetupKeyring(); err != nil {
+		logger.Error("Encryption keyring setup failed!", "error", err)
+		os.Exit(1)
+	}
+
+	c.SetupRepositories()
+
+	encryption := services.NewEncryptionService(ctx, logger, c.Keyring, c.Storage, c.PostRepository, c.MediaRepository, c.UploadRepository)
+
+	report, err := encryption.Reencrypt(*batchSize, *dryRun)
+	if report != nil {
+		logger.Info("Re-encryption finished",
+			"dry_run", *dryRun,
+			"posts_sealed", report.PostsSealed,
+			"posts_rewrapped", report.PostsRewrapped,
+			"revisions_sealed", report.RevisionsSealed,
+			"media_sealed", report.MediaSealed,
+			"media_rewrapped", report.MediaRewrapped,
+			"uploads_rewrapped", report.UploadsRewrapped,
+			"failed", report.Failed,
+		)
+	}
+	if err != nil {
+		logger.Error("Re-encryption failed", "error", err)
+		os.Exit(1)
+	}
+
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,23 @@
+// vapid generates the key pair push messages are signed with, the output is meant for
+// the env file
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/Xebec19/jibe/api/pkg/webpush"
+)
+
+func main() {
+
+	publicKey, privateKey, err := webpush.GenerateVAPID()
+	if err != nil {
+		slog.Error("VAPID key generation failed", "error", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("VAPID_PUBLIC_KEY=%s\n", publicKey)
+	fmt.Printf("VAPID_PRIVATE_KEY=%s\n", privateKey)
+}
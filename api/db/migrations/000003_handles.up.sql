@@ -0,0 +1,13 @@
+-- handles table :- it holds @handles claimed by accounts. Retired handles are kept
+-- so that old share links keep redirecting to the account's current handle
+CREATE TABLE IF NOT EXISTS handles(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    handle VARCHAR(30) NOT NULL,
+    skeleton VARCHAR(30) NOT NULL,   -- confusable-folded form used for uniqueness
+    eth_address VARCHAR(42) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    retired_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS handles_skeleton_idx ON handles(skeleton);
+CREATE UNIQUE INDEX IF NOT EXISTS handles_active_address_idx ON handles(eth_address) WHERE retired_at IS NULL;
@@ -0,0 +1,30 @@
+-- posts table :- it holds content written by creators. access_policy decides who can
+-- read the body, a NULL policy makes the post public
+CREATE TABLE IF NOT EXISTS posts(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    author_address VARCHAR(42) NOT NULL,
+    title VARCHAR(200) NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    body_format VARCHAR(16) NOT NULL DEFAULT 'markdown' CHECK (body_format IN ('markdown', 'richtext')),
+    status VARCHAR(16) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'published')),
+    access_policy JSONB,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS posts_author_status_idx ON posts(author_address, status, created_at DESC);
+
+-- post_attachments table :- files linked to a post, readable under the post's access policy
+CREATE TABLE IF NOT EXISTS post_attachments(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    url TEXT NOT NULL,
+    content_type VARCHAR(127) NOT NULL,
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS post_attachments_post_idx ON post_attachments(post_id, position);
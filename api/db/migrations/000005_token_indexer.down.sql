@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS indexed_blocks;
+DROP TABLE IF EXISTS indexer_checkpoints;
+DROP TABLE IF EXISTS token_holdings;
+DROP TABLE IF EXISTS token_transfers;
+DROP TABLE IF EXISTS indexed_contracts;
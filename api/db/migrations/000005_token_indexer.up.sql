@@ -0,0 +1,65 @@
+-- indexed_contracts table :- token contracts referenced by gating rules which the indexer
+-- follows. backfilled_to is the last block whose logs were applied for the contract
+CREATE TABLE IF NOT EXISTS indexed_contracts(
+    chain_id BIGINT NOT NULL,
+    address VARCHAR(42) NOT NULL,
+    token_kind VARCHAR(8) NOT NULL CHECK (token_kind IN ('erc20', 'erc721', 'erc1155')),
+    backfilled_to BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (chain_id, address)
+);
+
+-- token_transfers table :- every Transfer, TransferSingle and TransferBatch log seen by the
+-- indexer. It is the source of token_holdings and is rolled back on reorgs
+CREATE TABLE IF NOT EXISTS token_transfers(
+    id BIGSERIAL PRIMARY KEY,
+    chain_id BIGINT NOT NULL,
+    contract VARCHAR(42) NOT NULL,
+    token_kind VARCHAR(8) NOT NULL,
+    token_id NUMERIC(78, 0) NOT NULL DEFAULT 0,   -- 0 for erc20
+    from_address VARCHAR(42) NOT NULL,
+    to_address VARCHAR(42) NOT NULL,
+    amount NUMERIC(78, 0) NOT NULL,
+    block_number BIGINT NOT NULL,
+    block_hash VARCHAR(66) NOT NULL,
+    tx_hash VARCHAR(66) NOT NULL,
+    log_index INT NOT NULL,
+    batch_index INT NOT NULL DEFAULT 0,           -- position inside a TransferBatch
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (chain_id, tx_hash, log_index, batch_index)
+);
+
+CREATE INDEX IF NOT EXISTS token_transfers_block_idx ON token_transfers(chain_id, block_number);
+CREATE INDEX IF NOT EXISTS token_transfers_token_idx ON token_transfers(chain_id, contract, token_id, block_number);
+
+-- token_holdings table :- current balance of every holder of an indexed contract
+CREATE TABLE IF NOT EXISTS token_holdings(
+    chain_id BIGINT NOT NULL,
+    contract VARCHAR(42) NOT NULL,
+    token_kind VARCHAR(8) NOT NULL,
+    token_id NUMERIC(78, 0) NOT NULL DEFAULT 0,
+    holder VARCHAR(42) NOT NULL,
+    balance NUMERIC(78, 0) NOT NULL DEFAULT 0,
+    updated_block BIGINT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (chain_id, contract, token_id, holder)
+);
+
+CREATE INDEX IF NOT EXISTS token_holdings_holder_idx ON token_holdings(chain_id, holder);
+
+-- indexer_checkpoints table :- last block fully indexed per chain
+CREATE TABLE IF NOT EXISTS indexer_checkpoints(
+    chain_id BIGINT PRIMARY KEY,
+    block_number BIGINT NOT NULL,
+    block_hash VARCHAR(66) NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- indexed_blocks table :- hashes of recently indexed blocks, used to find the last
+-- common ancestor when the chain reorganizes
+CREATE TABLE IF NOT EXISTS indexed_blocks(
+    chain_id BIGINT NOT NULL,
+    block_number BIGINT NOT NULL,
+    block_hash VARCHAR(66) NOT NULL,
+    PRIMARY KEY (chain_id, block_number)
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS token_transfers_to_idx;
+DROP INDEX IF EXISTS token_transfers_from_idx;
+ALTER TABLE token_transfers DROP COLUMN IF EXISTS block_time;
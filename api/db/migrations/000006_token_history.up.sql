@@ -0,0 +1,6 @@
+-- token_transfers.block_time :- timestamp of the block a transfer was mined in, used by
+-- held_for_days conditions. Transfers indexed before this column existed have none
+ALTER TABLE token_transfers ADD COLUMN IF NOT EXISTS block_time TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS token_transfers_from_idx ON token_transfers(chain_id, contract, from_address, block_number);
+CREATE INDEX IF NOT EXISTS token_transfers_to_idx ON token_transfers(chain_id, contract, to_address, block_number);
@@ -0,0 +1,16 @@
+-- media_files table :- files uploaded by creators. A file linked to a post can be
+-- downloaded under the post's access policy, other files only by their owner
+CREATE TABLE IF NOT EXISTS media_files(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    owner_address VARCHAR(42) NOT NULL,
+    post_id UUID REFERENCES posts(id) ON DELETE SET NULL,
+    storage_key TEXT NOT NULL UNIQUE,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(127) NOT NULL,   -- sniffed from the content, not the client's claim
+    size_bytes BIGINT NOT NULL,
+    sha256 VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS media_files_owner_idx ON media_files(owner_address, created_at DESC);
+CREATE INDEX IF NOT EXISTS media_files_post_idx ON media_files(post_id);
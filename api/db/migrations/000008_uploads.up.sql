@@ -0,0 +1,28 @@
+-- uploads table :- resumable tus uploads in progress. Received bytes are kept as chunk
+-- objects in media storage until the upload completes and is handed over as a media file
+CREATE TABLE IF NOT EXISTS uploads(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    owner_address VARCHAR(42) NOT NULL,
+    upload_length BIGINT NOT NULL,
+    upload_offset BIGINT NOT NULL DEFAULT 0,
+    metadata TEXT NOT NULL DEFAULT '',     -- raw Upload-Metadata header, echoed back on HEAD
+    filename VARCHAR(255) NOT NULL DEFAULT '',
+    post_id UUID REFERENCES posts(id) ON DELETE SET NULL,
+    media_id UUID REFERENCES media_files(id) ON DELETE SET NULL,  -- set once completed
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS uploads_owner_idx ON uploads(owner_address) WHERE completed_at IS NULL;
+CREATE INDEX IF NOT EXISTS uploads_expires_idx ON uploads(expires_at);
+
+-- upload_chunks table :- the bytes received by one PATCH request of an upload
+CREATE TABLE IF NOT EXISTS upload_chunks(
+    upload_id UUID NOT NULL REFERENCES uploads(id) ON DELETE CASCADE,
+    chunk_offset BIGINT NOT NULL,
+    storage_key TEXT NOT NULL UNIQUE,
+    size_bytes BIGINT NOT NULL,
+    PRIMARY KEY (upload_id, chunk_offset)
+);
@@ -0,0 +1,12 @@
+ALTER TABLE uploads DROP COLUMN IF EXISTS key_id;
+ALTER TABLE uploads DROP COLUMN IF EXISTS data_key;
+
+ALTER TABLE media_files DROP COLUMN IF EXISTS key_id;
+ALTER TABLE media_files DROP COLUMN IF EXISTS data_key;
+
+ALTER TABLE post_attachments DROP COLUMN IF EXISTS url_ciphertext;
+ALTER TABLE post_attachments DROP COLUMN IF EXISTS name_ciphertext;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS key_id;
+ALTER TABLE posts DROP COLUMN IF EXISTS data_key;
+ALTER TABLE posts DROP COLUMN IF EXISTS body_ciphertext;
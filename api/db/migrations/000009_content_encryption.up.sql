@@ -0,0 +1,16 @@
+-- content encryption :- every post has a random data key, stored wrapped by the master
+-- key named in key_id. The body and attachment names and urls are kept encrypted under
+-- it, the plaintext columns stay empty once a row is encrypted
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS body_ciphertext BYTEA;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS data_key BYTEA;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS key_id VARCHAR(64);
+
+ALTER TABLE post_attachments ADD COLUMN IF NOT EXISTS name_ciphertext BYTEA;
+ALTER TABLE post_attachments ADD COLUMN IF NOT EXISTS url_ciphertext BYTEA;
+
+-- media objects and upload chunks are encrypted as streams under their own data key
+ALTER TABLE media_files ADD COLUMN IF NOT EXISTS data_key BYTEA;
+ALTER TABLE media_files ADD COLUMN IF NOT EXISTS key_id VARCHAR(64);
+
+ALTER TABLE uploads ADD COLUMN IF NOT EXISTS data_key BYTEA;
+ALTER TABLE uploads ADD COLUMN IF NOT EXISTS key_id VARCHAR(64);
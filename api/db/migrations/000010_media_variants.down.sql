@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS media_variants;
+
+DROP INDEX IF EXISTS media_files_processing_idx;
+
+ALTER TABLE media_files DROP COLUMN IF EXISTS blurhash;
+ALTER TABLE media_files DROP COLUMN IF EXISTS height;
+ALTER TABLE media_files DROP COLUMN IF EXISTS width;
+ALTER TABLE media_files DROP COLUMN IF EXISTS processing_started_at;
+ALTER TABLE media_files DROP COLUMN IF EXISTS processing_attempts;
+ALTER TABLE media_files DROP COLUMN IF EXISTS processing_status;
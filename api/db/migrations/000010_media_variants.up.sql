@@ -0,0 +1,25 @@
+-- image processing :- uploaded images wait in processing_status 'pending' until the
+-- image worker stripped their metadata and rendered the variants. Other files stay 'none'
+ALTER TABLE media_files ADD COLUMN IF NOT EXISTS processing_status VARCHAR(16) NOT NULL DEFAULT 'none';
+ALTER TABLE media_files ADD COLUMN IF NOT EXISTS processing_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE media_files ADD COLUMN IF NOT EXISTS processing_started_at TIMESTAMP;
+ALTER TABLE media_files ADD COLUMN IF NOT EXISTS width INT;
+ALTER TABLE media_files ADD COLUMN IF NOT EXISTS height INT;
+ALTER TABLE media_files ADD COLUMN IF NOT EXISTS blurhash VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS media_files_processing_idx ON media_files(created_at)
+    WHERE processing_status IN ('pending', 'processing');
+
+-- media variants :- resized renditions and the blurred preview of an image, encrypted
+-- under the data key of their media file
+CREATE TABLE IF NOT EXISTS media_variants(
+    media_id UUID NOT NULL REFERENCES media_files(id) ON DELETE CASCADE,
+    name VARCHAR(16) NOT NULL,
+    storage_key TEXT NOT NULL UNIQUE,
+    content_type VARCHAR(127) NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (media_id, name)
+);
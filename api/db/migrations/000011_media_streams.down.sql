@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS media_stream_renditions;
+DROP TABLE IF EXISTS media_streams;
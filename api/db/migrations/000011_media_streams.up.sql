@@ -0,0 +1,22 @@
+-- media_streams table :- HLS packages of uploaded videos. Segments are encrypted with
+-- AES-128 under segment_key, which is kept sealed by the data key of the media file and
+-- only handed to players passing the post's access policy
+CREATE TABLE IF NOT EXISTS media_streams(
+    media_id UUID PRIMARY KEY REFERENCES media_files(id) ON DELETE CASCADE,
+    storage_prefix TEXT NOT NULL UNIQUE,   -- segments live at {prefix}/{rendition}/{segment}
+    segment_key BYTEA NOT NULL,
+    duration_ms BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- media_stream_renditions table :- one quality of a stream, its media playlist lists
+-- the segment names relative to the rendition
+CREATE TABLE IF NOT EXISTS media_stream_renditions(
+    media_id UUID NOT NULL REFERENCES media_streams(media_id) ON DELETE CASCADE,
+    name VARCHAR(16) NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    bandwidth INT NOT NULL,
+    playlist TEXT NOT NULL,
+    PRIMARY KEY (media_id, name)
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS token_transfers_tx_idx;
+DROP TABLE IF EXISTS community_members;
+DROP TABLE IF EXISTS communities;
@@ -0,0 +1,44 @@
+-- communities table :- spaces owned by a creator. join_policy decides how accounts become
+-- members: open to anyone, token_gated by access_policy, paid with an ERC-20 transfer of
+-- price_amount to the owner, or invite_only
+CREATE TABLE IF NOT EXISTS communities(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    slug VARCHAR(40) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    owner_address VARCHAR(42) NOT NULL,
+    join_policy VARCHAR(16) NOT NULL CHECK (join_policy IN ('open', 'token_gated', 'paid', 'invite_only')),
+    access_policy JSONB,
+    price_chain_id BIGINT,
+    price_token VARCHAR(42),
+    price_amount NUMERIC(78, 0),
+    member_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS communities_slug_idx ON communities(slug);
+CREATE INDEX IF NOT EXISTS communities_owner_idx ON communities(owner_address, created_at DESC);
+
+-- community_members table :- accounts belonging to a community. granted_via, granted_policy
+-- and granted_blocks record the policy a membership was granted under and the chain heads
+-- it was evaluated at, payment_tx the transfer which paid for it
+CREATE TABLE IF NOT EXISTS community_members(
+    community_id UUID NOT NULL REFERENCES communities(id) ON DELETE CASCADE,
+    member_address VARCHAR(42) NOT NULL,
+    role VARCHAR(16) NOT NULL DEFAULT 'member' CHECK (role IN ('owner', 'admin', 'moderator', 'member')),
+    granted_via VARCHAR(16) NOT NULL CHECK (granted_via IN ('owner', 'open', 'token_gated', 'paid', 'invite')),
+    granted_policy JSONB,
+    granted_blocks JSONB,
+    payment_tx VARCHAR(66),
+    invited_by VARCHAR(42),
+    joined_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (community_id, member_address)
+);
+
+CREATE INDEX IF NOT EXISTS community_members_address_idx ON community_members(member_address, joined_at DESC);
+-- a payment buys a single membership
+CREATE UNIQUE INDEX IF NOT EXISTS community_members_payment_idx ON community_members(payment_tx) WHERE payment_tx IS NOT NULL;
+
+-- paid joins look up the transfers of a transaction
+CREATE INDEX IF NOT EXISTS token_transfers_tx_idx ON token_transfers(chain_id, tx_hash);
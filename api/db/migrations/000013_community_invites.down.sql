@@ -0,0 +1,2 @@
+ALTER TABLE community_members DROP COLUMN IF EXISTS invite_id;
+DROP TABLE IF EXISTS community_invites;
@@ -0,0 +1,24 @@
+-- community_invites table :- shareable links into a community. Links created by admins
+-- admit on their own, others are referrals subject to the join policy. access_policy is
+-- a gating policy the redeemer must satisfy on top. clicks, sign_ins and uses count
+-- opened links, sign-ins through the link and joins
+CREATE TABLE IF NOT EXISTS community_invites(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    community_id UUID NOT NULL REFERENCES communities(id) ON DELETE CASCADE,
+    code VARCHAR(32) NOT NULL,
+    inviter_address VARCHAR(42) NOT NULL,
+    max_uses INT CHECK (max_uses > 0),
+    uses INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP,
+    access_policy JSONB,
+    clicks INT NOT NULL DEFAULT 0,
+    sign_ins INT NOT NULL DEFAULT 0,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS community_invites_code_idx ON community_invites(code);
+CREATE INDEX IF NOT EXISTS community_invites_community_idx ON community_invites(community_id, inviter_address, created_at DESC);
+
+-- invite_id attributes a membership to the link it was redeemed through
+ALTER TABLE community_members ADD COLUMN IF NOT EXISTS invite_id UUID REFERENCES community_invites(id) ON DELETE SET NULL;
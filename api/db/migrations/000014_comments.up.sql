@@ -0,0 +1,40 @@
+-- post_comments table :- threaded discussion under posts, parent_id is NULL for top
+-- level comments. Deleted comments keep their row so their replies stay threaded,
+-- deleted_by tells an author's own deletion from moderation. mentions holds the
+-- addresses of accounts mentioned in the body
+CREATE TABLE IF NOT EXISTS post_comments(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    parent_id UUID REFERENCES post_comments(id) ON DELETE CASCADE,
+    depth INT NOT NULL DEFAULT 0,
+    author_address VARCHAR(42) NOT NULL,
+    body TEXT NOT NULL,
+    mentions VARCHAR(42)[] NOT NULL DEFAULT '{}',
+    reply_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    edited_at TIMESTAMP,
+    deleted_at TIMESTAMP,
+    deleted_by VARCHAR(42)
+);
+
+CREATE INDEX IF NOT EXISTS post_comments_top_level_idx ON post_comments(post_id, created_at, id) WHERE parent_id IS NULL;
+CREATE INDEX IF NOT EXISTS post_comments_parent_idx ON post_comments(parent_id, created_at, id);
+CREATE INDEX IF NOT EXISTS post_comments_mentions_idx ON post_comments USING GIN(mentions);
+
+-- post_reactions table :- emoji reactions on posts, an account adds each emoji once
+CREATE TABLE IF NOT EXISTS post_reactions(
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    reactor_address VARCHAR(42) NOT NULL,
+    emoji VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (post_id, reactor_address, emoji)
+);
+
+-- comment_reactions table :- emoji reactions on comments, an account adds each emoji once
+CREATE TABLE IF NOT EXISTS comment_reactions(
+    comment_id UUID NOT NULL REFERENCES post_comments(id) ON DELETE CASCADE,
+    reactor_address VARCHAR(42) NOT NULL,
+    emoji VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (comment_id, reactor_address, emoji)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS chat_presence;
+DROP TABLE IF EXISTS chat_messages;
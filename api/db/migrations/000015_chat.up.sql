@@ -0,0 +1,24 @@
+-- chat_messages table :- history of the chat channel every community has
+CREATE TABLE IF NOT EXISTS chat_messages(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    community_id UUID NOT NULL REFERENCES communities(id) ON DELETE CASCADE,
+    author_address VARCHAR(42) NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS chat_messages_community_idx ON chat_messages(community_id, created_at DESC, id DESC);
+
+-- chat_presence table :- open chat connections across api replicas, one row per
+-- connection. Replicas refresh seen_at of their connections, rows of a replica which
+-- went away stop counting once seen_at is stale
+CREATE UNLOGGED TABLE IF NOT EXISTS chat_presence(
+    session_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    community_id UUID NOT NULL REFERENCES communities(id) ON DELETE CASCADE,
+    member_address VARCHAR(42) NOT NULL,
+    connected_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    seen_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS chat_presence_community_idx ON chat_presence(community_id, member_address);
+CREATE INDEX IF NOT EXISTS chat_presence_seen_idx ON chat_presence(seen_at);
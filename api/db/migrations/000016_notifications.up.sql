@@ -0,0 +1,19 @@
+-- notifications table :- in-app notifications of an account. id grows with every insert
+-- and doubles as the event id of the live stream, so clients resume after the last id
+-- they saw. subject_id is the post, comment or community the notification is about and
+-- group_key folds repeats, a recipient has one unread notification per group_key
+CREATE TABLE IF NOT EXISTS notifications(
+    id BIGSERIAL PRIMARY KEY,
+    recipient_address VARCHAR(42) NOT NULL,
+    kind VARCHAR(32) NOT NULL CHECK (kind IN ('new_post', 'reply', 'mention', 'payment_received', 'membership_expiring')),
+    actor_address VARCHAR(42),
+    subject_id VARCHAR(66) NOT NULL,
+    data JSONB NOT NULL DEFAULT '{}',
+    group_key VARCHAR(128),
+    read_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS notifications_recipient_idx ON notifications(recipient_address, id DESC);
+CREATE INDEX IF NOT EXISTS notifications_unread_idx ON notifications(recipient_address) WHERE read_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS notifications_group_idx ON notifications(recipient_address, group_key) WHERE read_at IS NULL AND group_key IS NOT NULL;
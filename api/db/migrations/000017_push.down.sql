@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS push_deliveries;
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS push_subscriptions;
\ No newline at end of file
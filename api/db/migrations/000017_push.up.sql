@@ -0,0 +1,42 @@
+-- push_subscriptions table :- browser push subscriptions of accounts. endpoint is the
+-- push service url handed out by the browser, p256dh and auth are the keys messages
+-- are encrypted for
+CREATE TABLE IF NOT EXISTS push_subscriptions(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    account_address VARCHAR(42) NOT NULL,
+    endpoint TEXT NOT NULL,
+    p256dh VARCHAR(128) NOT NULL,
+    auth VARCHAR(64) NOT NULL,
+    user_agent VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS push_subscriptions_endpoint_idx ON push_subscriptions(endpoint);
+CREATE INDEX IF NOT EXISTS push_subscriptions_account_idx ON push_subscriptions(account_address);
+
+-- notification_preferences table :- delivery channels an account switched per kind of
+-- notification, kinds without a row keep the channel default
+CREATE TABLE IF NOT EXISTS notification_preferences(
+    account_address VARCHAR(42) NOT NULL,
+    channel VARCHAR(16) NOT NULL CHECK (channel IN ('push')),
+    kind VARCHAR(32) NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (account_address, channel, kind)
+);
+
+-- push_deliveries table :- outbox of push messages, one row per notification and
+-- subscription. next_attempt_at schedules retries and leases claimed rows, so sends
+-- cut short by a crash are retried
+CREATE TABLE IF NOT EXISTS push_deliveries(
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id UUID NOT NULL REFERENCES push_subscriptions(id) ON DELETE CASCADE,
+    notification_id BIGINT NOT NULL REFERENCES notifications(id) ON DELETE CASCADE,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS push_deliveries_due_idx ON push_deliveries(next_attempt_at);
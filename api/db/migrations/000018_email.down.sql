@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS email_deliveries;
+
+DELETE FROM notification_preferences WHERE channel = 'email';
+ALTER TABLE notification_preferences DROP CONSTRAINT IF EXISTS notification_preferences_channel_check;
+ALTER TABLE notification_preferences ADD CONSTRAINT notification_preferences_channel_check CHECK (channel IN ('push'));
+
+DROP TABLE IF EXISTS account_emails;
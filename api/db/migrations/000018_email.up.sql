@@ -0,0 +1,33 @@
+-- account_emails table :- email addresses accounts are mailed at. Nothing is sent
+-- before verified_at is set by the double opt-in, verify_token_hash is the sha256 of
+-- the token mailed for it. digest is how often a summary is sent, off, daily or weekly
+CREATE TABLE IF NOT EXISTS account_emails(
+    account_address VARCHAR(42) PRIMARY KEY,
+    email VARCHAR(254) NOT NULL,
+    verified_at TIMESTAMP,
+    verify_token_hash VARCHAR(64),
+    verify_expires_at TIMESTAMP,
+    digest VARCHAR(8) NOT NULL DEFAULT 'off' CHECK (digest IN ('off', 'daily', 'weekly')),
+    digest_sent_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS account_emails_verify_token_idx ON account_emails(verify_token_hash) WHERE verify_token_hash IS NOT NULL;
+CREATE INDEX IF NOT EXISTS account_emails_digest_idx ON account_emails(digest, digest_sent_at) WHERE verified_at IS NOT NULL AND digest <> 'off';
+
+ALTER TABLE notification_preferences DROP CONSTRAINT IF EXISTS notification_preferences_channel_check;
+ALTER TABLE notification_preferences ADD CONSTRAINT notification_preferences_channel_check CHECK (channel IN ('push', 'email'));
+
+-- email_deliveries table :- outbox of notification emails, leased and retried like
+-- push_deliveries. The address is read when the email is sent
+CREATE TABLE IF NOT EXISTS email_deliveries(
+    id BIGSERIAL PRIMARY KEY,
+    notification_id BIGINT NOT NULL REFERENCES notifications(id) ON DELETE CASCADE,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS email_deliveries_due_idx ON email_deliveries(next_attempt_at);
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS handles_handle_trgm_idx;
+
+DROP TRIGGER IF EXISTS communities_search_trigger ON communities;
+DROP FUNCTION IF EXISTS index_community_search();
+DROP TABLE IF EXISTS community_search;
+
+DROP TABLE IF EXISTS post_search;
+ALTER TABLE posts DROP COLUMN IF EXISTS tags;
+ALTER TABLE posts DROP COLUMN IF EXISTS teaser;
@@ -0,0 +1,53 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- teaser is the public summary of a post, shown to readers the post is locked for. tags
+-- label posts for search
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS teaser VARCHAR(300) NOT NULL DEFAULT '';
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS tags VARCHAR(32)[] NOT NULL DEFAULT '{}';
+
+-- post_search table :- full-text index of posts, weighted title, tags, teaser and body.
+-- It is written by the post service along with the post since bodies may be stored
+-- encrypted. Bodies of gated posts are left out so their content can not be probed
+-- through search
+CREATE TABLE IF NOT EXISTS post_search(
+    post_id UUID PRIMARY KEY REFERENCES posts(id) ON DELETE CASCADE,
+    search_vector TSVECTOR NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS post_search_vector_idx ON post_search USING GIN(search_vector);
+
+-- encrypted bodies read as empty, those posts are indexed by title until saved again
+INSERT INTO post_search(post_id, search_vector)
+SELECT id, setweight(to_tsvector('english', title), 'A') || setweight(to_tsvector('english', CASE WHEN access_policy IS NULL THEN body ELSE '' END), 'D') FROM posts
+ON CONFLICT (post_id) DO NOTHING;
+
+-- community_search table :- full-text index of communities, kept current by a trigger
+-- as communities are stored in plaintext
+CREATE TABLE IF NOT EXISTS community_search(
+    community_id UUID PRIMARY KEY REFERENCES communities(id) ON DELETE CASCADE,
+    search_vector TSVECTOR NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS community_search_vector_idx ON community_search USING GIN(search_vector);
+
+CREATE OR REPLACE FUNCTION index_community_search() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO community_search(community_id, search_vector)
+    VALUES(NEW.id, setweight(to_tsvector('english', NEW.name), 'A')
+        || setweight(to_tsvector('simple', replace(NEW.slug, '-', ' ')), 'A')
+        || setweight(to_tsvector('english', NEW.description), 'C'))
+    ON CONFLICT (community_id) DO UPDATE SET search_vector = EXCLUDED.search_vector;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS communities_search_trigger ON communities;
+CREATE TRIGGER communities_search_trigger AFTER INSERT OR UPDATE OF name, slug, description ON communities
+FOR EACH ROW EXECUTE FUNCTION index_community_search();
+
+INSERT INTO community_search(community_id, search_vector)
+SELECT id, setweight(to_tsvector('english', name), 'A') || setweight(to_tsvector('simple', replace(slug, '-', ' ')), 'A') || setweight(to_tsvector('english', description), 'C') FROM communities
+ON CONFLICT (community_id) DO NOTHING;
+
+-- creators are found by fuzzy matches on their handle
+CREATE INDEX IF NOT EXISTS handles_handle_trgm_idx ON handles USING GIN(handle gin_trgm_ops) WHERE retired_at IS NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS posts_author_published_idx;
+DROP TABLE IF EXISTS follows;
@@ -0,0 +1,14 @@
+-- follows table :- creators an account follows, their posts show up in the follower's
+-- home feed next to those of the communities the follower belongs to
+CREATE TABLE IF NOT EXISTS follows(
+    follower_address VARCHAR(42) NOT NULL,
+    followee_address VARCHAR(42) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (follower_address, followee_address),
+    CHECK (follower_address <> followee_address)
+);
+
+CREATE INDEX IF NOT EXISTS follows_followee_idx ON follows(followee_address, created_at DESC);
+
+-- the feed reads the published posts of a set of authors newest first
+CREATE INDEX IF NOT EXISTS posts_author_published_idx ON posts(author_address, published_at DESC) WHERE status = 'published';
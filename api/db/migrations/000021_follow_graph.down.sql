@@ -0,0 +1 @@
+DROP INDEX IF EXISTS follows_follower_idx;
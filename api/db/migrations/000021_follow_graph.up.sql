@@ -0,0 +1,34 @@
+-- follow_counts table :- follower and following counts of accounts, adjusted along with
+-- the follows they count so profiles do not count rows
+CREATE TABLE IF NOT EXISTS follow_counts(
+    account_address VARCHAR(42) PRIMARY KEY,
+    follower_count INT NOT NULL DEFAULT 0 CHECK (follower_count >= 0),
+    following_count INT NOT NULL DEFAULT 0 CHECK (following_count >= 0)
+);
+
+INSERT INTO follow_counts(account_address, follower_count, following_count)
+SELECT account_address, sum(follower_count), sum(following_count) FROM (
+    SELECT followee_address AS account_address, count(*) AS follower_count, 0 AS following_count FROM follows GROUP BY followee_address
+    UNION ALL
+    SELECT follower_address, 0, count(*) FROM follows GROUP BY follower_address
+) counts
+GROUP BY account_address
+ON CONFLICT (account_address) DO NOTHING;
+
+-- account_restrictions table :- blocks and mutes between accounts. A block ends follows
+-- both ways and hides the two accounts from each other, a mute only hides the target
+-- from the actor
+CREATE TABLE IF NOT EXISTS account_restrictions(
+    actor_address VARCHAR(42) NOT NULL,
+    target_address VARCHAR(42) NOT NULL,
+    kind VARCHAR(8) NOT NULL CHECK (kind IN ('block', 'mute')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (actor_address, kind, target_address),
+    CHECK (actor_address <> target_address)
+);
+
+CREATE INDEX IF NOT EXISTS account_restrictions_target_idx ON account_restrictions(target_address, kind);
+CREATE INDEX IF NOT EXISTS account_restrictions_actor_idx ON account_restrictions(actor_address, kind, created_at DESC);
+
+-- following lists page through the follows of an account newest first
+CREATE INDEX IF NOT EXISTS follows_follower_idx ON follows(follower_address, created_at DESC);
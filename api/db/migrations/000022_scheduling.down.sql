@@ -0,0 +1,17 @@
+DELETE FROM notifications WHERE kind = 'content_unlocked';
+
+ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_kind_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_kind_check CHECK (kind IN ('new_post', 'reply', 'mention', 'payment_received', 'membership_expiring'));
+
+DROP TABLE IF EXISTS drip_releases;
+
+DROP TABLE IF EXISTS post_drips;
+
+DROP INDEX IF EXISTS posts_publish_at_idx;
+
+UPDATE posts SET status = 'draft' WHERE status = 'scheduled';
+
+ALTER TABLE posts DROP COLUMN IF EXISTS publish_at;
+
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_status_check;
+ALTER TABLE posts ADD CONSTRAINT posts_status_check CHECK (status IN ('draft', 'published'));
@@ -0,0 +1,30 @@
+-- scheduled posts are published by the scheduler once publish_at is reached
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_status_check;
+ALTER TABLE posts ADD CONSTRAINT posts_status_check CHECK (status IN ('draft', 'scheduled', 'published'));
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS publish_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS posts_publish_at_idx ON posts(publish_at) WHERE status = 'scheduled';
+
+-- post_drips table :- posts unlocked for the members of a community delay_seconds after
+-- each of them joined. Authors only drip posts to communities they own
+CREATE TABLE IF NOT EXISTS post_drips(
+    post_id UUID PRIMARY KEY REFERENCES posts(id) ON DELETE CASCADE,
+    community_id UUID NOT NULL REFERENCES communities(id) ON DELETE CASCADE,
+    delay_seconds INT NOT NULL CHECK (delay_seconds >= 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS post_drips_community_idx ON post_drips(community_id);
+
+-- drip_releases table :- members told a dripped post unlocked for them, the scheduler
+-- notifies every member once
+CREATE TABLE IF NOT EXISTS drip_releases(
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    member_address VARCHAR(42) NOT NULL,
+    released_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (post_id, member_address)
+);
+
+ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_kind_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_kind_check CHECK (kind IN ('new_post', 'reply', 'mention', 'payment_received', 'membership_expiring', 'content_unlocked'));
@@ -0,0 +1,31 @@
+-- post_revisions table :- immutable snapshots of a post, one per save. The body is stored
+-- like the post's, sealed under the data key of the post when it is encrypted. status is
+-- the post's when saved, changed_fields, lines_added and lines_removed compare a revision
+-- to the one before it and restored_from is the revision a restore copied
+CREATE TABLE IF NOT EXISTS post_revisions(
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    number INT NOT NULL CHECK (number > 0),
+    author_address VARCHAR(42) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    title VARCHAR(200) NOT NULL,
+    teaser VARCHAR(300) NOT NULL DEFAULT '',
+    tags VARCHAR(32)[] NOT NULL DEFAULT '{}',
+    body TEXT NOT NULL DEFAULT '',
+    body_format VARCHAR(16) NOT NULL,
+    access_policy JSONB,
+    body_ciphertext BYTEA,
+    changed_fields TEXT[] NOT NULL DEFAULT '{}',
+    lines_added INT NOT NULL DEFAULT 0,
+    lines_removed INT NOT NULL DEFAULT 0,
+    restored_from INT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (post_id, number)
+);
+
+-- revisions saved while a post was not published are pruned once they expire
+CREATE INDEX IF NOT EXISTS post_revisions_draft_idx ON post_revisions(created_at) WHERE status <> 'published';
+
+-- existing posts start their history with their current content
+INSERT INTO post_revisions(post_id, number, author_address, status, title, teaser, tags, body, body_format, access_policy, body_ciphertext, created_at)
+SELECT id, 1, author_address, status, title, teaser, tags, body, body_format, access_policy, body_ciphertext, updated_at FROM posts
+ON CONFLICT (post_id, number) DO NOTHING;
@@ -0,0 +1 @@
+-- bodies left out of the index are indexed again the next time their post is saved
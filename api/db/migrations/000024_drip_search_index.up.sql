@@ -0,0 +1,8 @@
+-- dripped posts are indexed without their body, like gated ones, so it can not be
+-- probed through search before it unlocks
+INSERT INTO post_search(post_id, search_vector)
+SELECT p.id, setweight(to_tsvector('english', p.title), 'A')
+    || setweight(to_tsvector('simple', array_to_string(p.tags, ' ')), 'B')
+    || setweight(to_tsvector('english', p.teaser), 'C')
+FROM posts p JOIN post_drips d ON d.post_id = p.id
+ON CONFLICT (post_id) DO UPDATE SET search_vector = EXCLUDED.search_vector;
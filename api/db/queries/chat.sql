@@ -0,0 +1,46 @@
+-- name: CreateChatMessage :one
+INSERT INTO chat_messages(community_id, author_address, body)
+VALUES($1, $2, $3)
+RETURNING id, community_id, author_address, body, created_at;
+
+-- name: GetChatMessage :one
+SELECT m.id, m.community_id, m.author_address, m.body, m.created_at, h.handle FROM chat_messages m
+LEFT JOIN handles h ON h.eth_address = m.author_address AND h.retired_at IS NULL
+WHERE m.id = $1;
+
+-- name: ListChatMessages :many
+SELECT m.id, m.community_id, m.author_address, m.body, m.created_at, h.handle FROM chat_messages m
+LEFT JOIN handles h ON h.eth_address = m.author_address AND h.retired_at IS NULL
+WHERE m.community_id = sqlc.arg(community_id)
+    AND (m.created_at, m.id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY m.created_at DESC, m.id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: NotifyChat :exec
+SELECT pg_notify('chat_events', sqlc.arg(payload)::text);
+
+-- name: CreateChatPresence :one
+INSERT INTO chat_presence(community_id, member_address)
+VALUES($1, $2)
+RETURNING session_id;
+
+-- name: DeleteChatPresence :exec
+DELETE FROM chat_presence
+WHERE session_id = $1;
+
+-- name: CountChatSessions :one
+SELECT COUNT(*) FROM chat_presence
+WHERE community_id = $1 AND member_address = $2 AND seen_at > $3;
+
+-- name: ListChatPresence :many
+SELECT DISTINCT member_address FROM chat_presence
+WHERE community_id = $1 AND seen_at > $2
+ORDER BY member_address;
+
+-- name: TouchChatPresence :exec
+UPDATE chat_presence SET seen_at = CURRENT_TIMESTAMP
+WHERE session_id = ANY(sqlc.arg(session_ids)::uuid[]);
+
+-- name: PurgeChatPresence :execrows
+DELETE FROM chat_presence
+WHERE seen_at < $1;
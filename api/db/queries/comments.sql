@@ -0,0 +1,58 @@
+-- name: CreateComment :one
+INSERT INTO post_comments(post_id, parent_id, depth, author_address, body, mentions)
+VALUES($1, $2, $3, $4, $5, $6)
+RETURNING id, post_id, parent_id, depth, author_address, body, mentions, reply_count, created_at, edited_at, deleted_at, deleted_by;
+
+-- name: IncrementCommentReplies :exec
+UPDATE post_comments SET reply_count = reply_count + 1
+WHERE id = $1;
+
+-- name: GetComment :one
+SELECT c.id, c.post_id, c.parent_id, c.depth, c.author_address, c.body, c.mentions, c.reply_count, c.created_at, c.edited_at, c.deleted_at, c.deleted_by, h.handle FROM post_comments c
+LEFT JOIN handles h ON h.eth_address = c.author_address AND h.retired_at IS NULL
+WHERE c.id = $1;
+
+-- name: ListTopLevelComments :many
+SELECT c.id, c.post_id, c.parent_id, c.depth, c.author_address, c.body, c.mentions, c.reply_count, c.created_at, c.edited_at, c.deleted_at, c.deleted_by, h.handle FROM post_comments c
+LEFT JOIN handles h ON h.eth_address = c.author_address AND h.retired_at IS NULL
+WHERE c.post_id = sqlc.arg(post_id) AND c.parent_id IS NULL
+    AND (c.deleted_at IS NULL OR c.reply_count > 0)
+    AND (c.created_at, c.id) > (sqlc.arg(after_created_at)::timestamp, sqlc.arg(after_id)::uuid)
+    AND NOT EXISTS (
+        SELECT 1 FROM account_restrictions r
+        WHERE (r.actor_address = sqlc.arg(viewer_address) AND r.target_address = c.author_address)
+            OR (r.kind = 'block' AND r.actor_address = c.author_address AND r.target_address = sqlc.arg(viewer_address))
+    )
+ORDER BY c.created_at, c.id
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListCommentReplies :many
+SELECT c.id, c.post_id, c.parent_id, c.depth, c.author_address, c.body, c.mentions, c.reply_count, c.created_at, c.edited_at, c.deleted_at, c.deleted_by, h.handle FROM post_comments c
+LEFT JOIN handles h ON h.eth_address = c.author_address AND h.retired_at IS NULL
+WHERE c.parent_id = sqlc.arg(parent_id)
+    AND (c.deleted_at IS NULL OR c.reply_count > 0)
+    AND (c.created_at, c.id) > (sqlc.arg(after_created_at)::timestamp, sqlc.arg(after_id)::uuid)
+    AND NOT EXISTS (
+        SELECT 1 FROM account_restrictions r
+        WHERE (r.actor_address = sqlc.arg(viewer_address) AND r.target_address = c.author_address)
+            OR (r.kind = 'block' AND r.actor_address = c.author_address AND r.target_address = sqlc.arg(viewer_address))
+    )
+ORDER BY c.created_at, c.id
+LIMIT sqlc.arg(row_limit);
+
+-- name: UpdateCommentBody :one
+UPDATE post_comments SET body = $2, mentions = $3, edited_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, post_id, parent_id, depth, author_address, body, mentions, reply_count, created_at, edited_at, deleted_at, deleted_by;
+
+-- name: SoftDeleteComment :one
+UPDATE post_comments SET deleted_at = CURRENT_TIMESTAMP, deleted_by = sqlc.arg(deleted_by)::varchar,
+    body = CASE WHEN author_address = sqlc.arg(deleted_by)::varchar THEN '' ELSE body END,
+    mentions = CASE WHEN author_address = sqlc.arg(deleted_by)::varchar THEN '{}' ELSE mentions END
+WHERE id = sqlc.arg(id) AND deleted_at IS NULL
+RETURNING id, post_id, parent_id, depth, author_address, body, mentions, reply_count, created_at, edited_at, deleted_at, deleted_by;
+
+-- name: RestoreComment :one
+UPDATE post_comments SET deleted_at = NULL, deleted_by = NULL
+WHERE id = $1 AND deleted_at IS NOT NULL AND deleted_by <> author_address
+RETURNING id, post_id, parent_id, depth, author_address, body, mentions, reply_count, created_at, edited_at, deleted_at, deleted_by;
@@ -0,0 +1,60 @@
+-- name: CreateCommunity :one
+INSERT INTO communities(slug, name, description, owner_address, join_policy, access_policy, price_chain_id, price_token, price_amount)
+VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, slug, name, description, owner_address, join_policy, access_policy, price_chain_id, price_token, price_amount, member_count, created_at, updated_at;
+
+-- name: GetCommunity :one
+SELECT id, slug, name, description, owner_address, join_policy, access_policy, price_chain_id, price_token, price_amount, member_count, created_at, updated_at FROM communities
+WHERE id = $1;
+
+-- name: GetCommunityBySlug :one
+SELECT id, slug, name, description, owner_address, join_policy, access_policy, price_chain_id, price_token, price_amount, member_count, created_at, updated_at FROM communities
+WHERE slug = $1;
+
+-- name: UpdateCommunity :one
+UPDATE communities SET slug = $2, name = $3, description = $4, join_policy = $5, access_policy = $6, price_chain_id = $7, price_token = $8, price_amount = $9, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING id, slug, name, description, owner_address, join_policy, access_policy, price_chain_id, price_token, price_amount, member_count, created_at, updated_at;
+
+-- name: DeleteCommunity :execrows
+DELETE FROM communities WHERE id = $1;
+
+-- name: AdjustCommunityMemberCount :exec
+UPDATE communities SET member_count = member_count + $2
+WHERE id = $1;
+
+-- name: ListMemberCommunities :many
+SELECT c.id, c.slug, c.name, c.description, c.owner_address, c.join_policy, c.access_policy, c.price_chain_id, c.price_token, c.price_amount, c.member_count, c.created_at, c.updated_at FROM communities c
+JOIN community_members m ON m.community_id = c.id
+WHERE m.member_address = $1
+ORDER BY m.joined_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CreateCommunityMember :one
+INSERT INTO community_members(community_id, member_address, role, granted_via, granted_policy, granted_blocks, payment_tx, invited_by, invite_id)
+VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING community_id, member_address, role, granted_via, granted_policy, granted_blocks, payment_tx, invited_by, joined_at, invite_id;
+
+-- name: GetCommunityMember :one
+SELECT community_id, member_address, role, granted_via, granted_policy, granted_blocks, payment_tx, invited_by, joined_at, invite_id FROM community_members
+WHERE community_id = $1 AND member_address = $2;
+
+-- name: UpdateCommunityMemberRole :one
+UPDATE community_members SET role = $3
+WHERE community_id = $1 AND member_address = $2
+RETURNING community_id, member_address, role, granted_via, granted_policy, granted_blocks, payment_tx, invited_by, joined_at, invite_id;
+
+-- name: DeleteCommunityMember :execrows
+DELETE FROM community_members
+WHERE community_id = $1 AND member_address = $2;
+
+-- name: ListCommunityMembers :many
+SELECT m.community_id, m.member_address, m.role, m.granted_via, m.granted_policy, m.granted_blocks, m.payment_tx, m.invited_by, m.joined_at, m.invite_id, h.handle FROM community_members m
+LEFT JOIN handles h ON h.eth_address = m.member_address AND h.retired_at IS NULL
+WHERE m.community_id = sqlc.arg(community_id) AND (sqlc.arg(role)::text = '' OR m.role = sqlc.arg(role))
+ORDER BY CASE m.role WHEN 'owner' THEN 0 WHEN 'admin' THEN 1 WHEN 'moderator' THEN 2 ELSE 3 END, m.joined_at, m.member_address
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: ListGatedMemberships :many
+SELECT community_id, member_address, role, granted_via, granted_policy, granted_blocks, payment_tx, invited_by, joined_at, invite_id FROM community_members
+WHERE member_address = ANY(sqlc.arg(member_addresses)::varchar[]) AND granted_policy IS NOT NULL AND role IN ('member', 'moderator');
@@ -0,0 +1,36 @@
+-- name: CreateCommunityInvite :one
+INSERT INTO community_invites(community_id, code, inviter_address, max_uses, expires_at, access_policy)
+VALUES($1, $2, $3, $4, $5, $6)
+RETURNING id, community_id, code, inviter_address, max_uses, uses, expires_at, access_policy, clicks, sign_ins, revoked_at, created_at;
+
+-- name: GetCommunityInvite :one
+SELECT id, community_id, code, inviter_address, max_uses, uses, expires_at, access_policy, clicks, sign_ins, revoked_at, created_at FROM community_invites
+WHERE id = $1;
+
+-- name: GetCommunityInviteByCode :one
+SELECT id, community_id, code, inviter_address, max_uses, uses, expires_at, access_policy, clicks, sign_ins, revoked_at, created_at FROM community_invites
+WHERE code = $1;
+
+-- name: ListCommunityInvites :many
+SELECT id, community_id, code, inviter_address, max_uses, uses, expires_at, access_policy, clicks, sign_ins, revoked_at, created_at FROM community_invites
+WHERE community_id = sqlc.arg(community_id) AND (sqlc.arg(inviter_address)::text = '' OR inviter_address = sqlc.arg(inviter_address))
+ORDER BY created_at DESC
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: RevokeCommunityInvite :execrows
+UPDATE community_invites SET revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND revoked_at IS NULL;
+
+-- name: CountInviteClick :exec
+UPDATE community_invites SET clicks = clicks + 1
+WHERE id = $1;
+
+-- name: CountInviteSignIn :exec
+UPDATE community_invites SET sign_ins = sign_ins + 1
+WHERE id = $1;
+
+-- name: ClaimInviteUse :execrows
+UPDATE community_invites SET uses = uses + 1
+WHERE id = $1 AND revoked_at IS NULL
+  AND (max_uses IS NULL OR uses < max_uses)
+  AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP);
@@ -0,0 +1,82 @@
+-- name: GetAccountEmail :one
+SELECT account_address, email, verified_at, verify_token_hash, verify_expires_at, digest, digest_sent_at, created_at, updated_at FROM account_emails
+WHERE account_address = $1;
+
+-- name: UpsertAccountEmail :one
+INSERT INTO account_emails(account_address, email, verify_token_hash, verify_expires_at)
+VALUES($1, $2, $3, $4)
+ON CONFLICT (account_address) DO UPDATE SET email = EXCLUDED.email, verified_at = NULL, verify_token_hash = EXCLUDED.verify_token_hash, verify_expires_at = EXCLUDED.verify_expires_at, updated_at = CURRENT_TIMESTAMP
+RETURNING account_address, email, verified_at, verify_token_hash, verify_expires_at, digest, digest_sent_at, created_at, updated_at;
+
+-- name: VerifyAccountEmail :one
+UPDATE account_emails SET verified_at = CURRENT_TIMESTAMP, verify_token_hash = NULL, verify_expires_at = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE verify_token_hash = $1 AND verify_expires_at > CURRENT_TIMESTAMP
+RETURNING account_address, email, verified_at, verify_token_hash, verify_expires_at, digest, digest_sent_at, created_at, updated_at;
+
+-- name: DeleteAccountEmail :execrows
+DELETE FROM account_emails
+WHERE account_address = $1;
+
+-- name: SetEmailDigest :one
+UPDATE account_emails SET digest = $2, digest_sent_at = CASE WHEN digest = 'off' THEN CURRENT_TIMESTAMP ELSE digest_sent_at END, updated_at = CURRENT_TIMESTAMP
+WHERE account_address = $1
+RETURNING account_address, email, verified_at, verify_token_hash, verify_expires_at, digest, digest_sent_at, created_at, updated_at;
+
+-- name: ClaimDueDigests :many
+WITH due AS (
+    SELECT account_address, COALESCE(digest_sent_at, verified_at)::timestamp AS since FROM account_emails
+    WHERE verified_at IS NOT NULL
+    AND ((digest = 'daily' AND COALESCE(digest_sent_at, verified_at) <= sqlc.arg(daily_before)) OR (digest = 'weekly' AND COALESCE(digest_sent_at, verified_at) <= sqlc.arg(weekly_before)))
+    ORDER BY COALESCE(digest_sent_at, verified_at)
+    LIMIT sqlc.arg(row_limit)
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE account_emails e SET digest_sent_at = CURRENT_TIMESTAMP
+FROM due WHERE e.account_address = due.account_address
+RETURNING e.account_address, e.email, e.digest, due.since;
+
+-- name: ResetEmailDigest :exec
+UPDATE account_emails SET digest_sent_at = $2
+WHERE account_address = $1;
+
+-- name: CountNotificationsSince :many
+SELECT kind, COUNT(*)::int AS count FROM notifications
+WHERE recipient_address = $1 AND created_at > $2
+GROUP BY kind;
+
+-- name: ListNotificationsSince :many
+SELECT id, recipient_address, kind, actor_address, subject_id, data, group_key, read_at, created_at FROM notifications
+WHERE recipient_address = $1 AND created_at > $2
+ORDER BY id DESC
+LIMIT $3;
+
+-- name: EnqueueEmailDeliveries :execrows
+INSERT INTO email_deliveries(notification_id)
+SELECT n.id FROM notifications n
+JOIN account_emails e ON e.account_address = n.recipient_address AND e.verified_at IS NOT NULL
+JOIN notification_preferences p ON p.account_address = n.recipient_address AND p.channel = 'email' AND p.kind = n.kind AND p.enabled
+WHERE n.id = ANY(sqlc.arg(notification_ids)::bigint[]);
+
+-- name: ClaimEmailDeliveries :many
+WITH due AS (
+    SELECT id FROM email_deliveries
+    WHERE next_attempt_at <= CURRENT_TIMESTAMP
+    ORDER BY next_attempt_at, id
+    LIMIT sqlc.arg(row_limit)
+    FOR UPDATE SKIP LOCKED
+), claimed AS (
+    UPDATE email_deliveries d SET attempts = d.attempts + 1, next_attempt_at = sqlc.arg(lease_until)
+    FROM due WHERE d.id = due.id
+    RETURNING d.id, d.notification_id, d.attempts
+)
+SELECT c.id, c.attempts, e.email, n.id AS notification_id, n.recipient_address, n.kind, n.actor_address, n.subject_id, n.data, n.read_at, n.created_at FROM claimed c
+JOIN notifications n ON n.id = c.notification_id
+LEFT JOIN account_emails e ON e.account_address = n.recipient_address AND e.verified_at IS NOT NULL;
+
+-- name: DeleteEmailDelivery :exec
+DELETE FROM email_deliveries
+WHERE id = $1;
+
+-- name: RescheduleEmailDelivery :exec
+UPDATE email_deliveries SET next_attempt_at = $2, last_error = $3
+WHERE id = $1;
@@ -0,0 +1,30 @@
+-- name: ListFeed :many
+WITH sources AS (
+    SELECT followee_address AS author_address FROM follows
+    WHERE follower_address = sqlc.arg(viewer_address)
+    UNION
+    SELECT c.owner_address FROM communities c
+    JOIN community_members m ON m.community_id = c.id
+    WHERE m.member_address = sqlc.arg(viewer_address) AND c.owner_address <> sqlc.arg(viewer_address)
+), ranked AS (
+    SELECT p.id, p.author_address, p.title, p.body, p.body_format, p.status, p.access_policy, p.created_at, p.updated_at, p.published_at, p.body_ciphertext, p.data_key, p.key_id, p.teaser, p.tags, p.publish_at,
+        e.reaction_count, e.comment_count,
+        extract(epoch FROM p.published_at)::float8 + sqlc.arg(boost_seconds)::float8 * ln(1 + e.reaction_count + e.comment_count) AS score
+    FROM posts p
+    JOIN sources s ON s.author_address = p.author_address
+    CROSS JOIN LATERAL (
+        SELECT
+            (SELECT count(*) FROM post_reactions r WHERE r.post_id = p.id AND r.created_at <= sqlc.arg(as_of)) AS reaction_count,
+            (SELECT count(*) FROM post_comments pc WHERE pc.post_id = p.id AND pc.created_at <= sqlc.arg(as_of) AND (pc.deleted_at IS NULL OR pc.deleted_at > sqlc.arg(as_of))) AS comment_count
+    ) e
+    WHERE p.status = 'published' AND p.published_at <= sqlc.arg(as_of) AND p.published_at > sqlc.arg(since)
+        AND NOT EXISTS (
+            SELECT 1 FROM account_restrictions r
+            WHERE (r.actor_address = sqlc.arg(viewer_address) AND r.target_address = p.author_address)
+                OR (r.kind = 'block' AND r.actor_address = p.author_address AND r.target_address = sqlc.arg(viewer_address))
+        )
+)
+SELECT id, author_address, title, body, body_format, status, access_policy, created_at, updated_at, published_at, body_ciphertext, data_key, key_id, teaser, tags, publish_at, reaction_count, comment_count, score::float8 AS score FROM ranked
+WHERE (score, id) < (sqlc.arg(after_score)::float8, sqlc.arg(after_id)::uuid)
+ORDER BY score DESC, id DESC
+LIMIT sqlc.arg(row_limit);
@@ -0,0 +1,98 @@
+-- name: FollowAccount :execrows
+INSERT INTO follows(follower_address, followee_address)
+SELECT sqlc.arg(follower_address)::varchar, sqlc.arg(followee_address)::varchar
+WHERE NOT EXISTS (
+    SELECT 1 FROM account_restrictions r
+    WHERE r.kind = 'block' AND ((r.actor_address = sqlc.arg(follower_address)::varchar AND r.target_address = sqlc.arg(followee_address)::varchar)
+        OR (r.actor_address = sqlc.arg(followee_address)::varchar AND r.target_address = sqlc.arg(follower_address)::varchar))
+)
+ON CONFLICT (follower_address, followee_address) DO NOTHING;
+
+-- name: UnfollowAccount :execrows
+DELETE FROM follows
+WHERE follower_address = $1 AND followee_address = $2;
+
+-- name: ImportFollows :many
+INSERT INTO follows(follower_address, followee_address)
+SELECT sqlc.arg(follower_address)::varchar, t.followee_address FROM unnest(sqlc.arg(followee_addresses)::varchar[]) AS t(followee_address)
+WHERE t.followee_address <> sqlc.arg(follower_address)::varchar
+    AND NOT EXISTS (
+        SELECT 1 FROM account_restrictions r
+        WHERE r.kind = 'block' AND ((r.actor_address = sqlc.arg(follower_address)::varchar AND r.target_address = t.followee_address)
+            OR (r.actor_address = t.followee_address AND r.target_address = sqlc.arg(follower_address)::varchar))
+    )
+ON CONFLICT (follower_address, followee_address) DO NOTHING
+RETURNING followee_address;
+
+-- name: AdjustFollowerCount :exec
+INSERT INTO follow_counts(account_address, follower_count)
+VALUES($1, $2)
+ON CONFLICT (account_address) DO UPDATE SET follower_count = follow_counts.follower_count + EXCLUDED.follower_count;
+
+-- name: AdjustFollowingCount :exec
+INSERT INTO follow_counts(account_address, following_count)
+VALUES($1, $2)
+ON CONFLICT (account_address) DO UPDATE SET following_count = follow_counts.following_count + EXCLUDED.following_count;
+
+-- name: IncrementFollowerCounts :exec
+INSERT INTO follow_counts(account_address, follower_count)
+SELECT unnest(sqlc.arg(addresses)::varchar[]), 1
+ON CONFLICT (account_address) DO UPDATE SET follower_count = follow_counts.follower_count + 1;
+
+-- name: GetFollowCounts :one
+SELECT account_address, follower_count, following_count FROM follow_counts
+WHERE account_address = $1;
+
+-- name: GetRelationship :one
+SELECT
+    EXISTS(SELECT 1 FROM follows WHERE follower_address = sqlc.arg(viewer_address) AND followee_address = sqlc.arg(account_address)) AS following,
+    EXISTS(SELECT 1 FROM follows WHERE follower_address = sqlc.arg(account_address) AND followee_address = sqlc.arg(viewer_address)) AS followed_by,
+    EXISTS(SELECT 1 FROM account_restrictions WHERE actor_address = sqlc.arg(viewer_address) AND kind = 'block' AND target_address = sqlc.arg(account_address)) AS blocking,
+    EXISTS(SELECT 1 FROM account_restrictions WHERE actor_address = sqlc.arg(viewer_address) AND kind = 'mute' AND target_address = sqlc.arg(account_address)) AS muting,
+    EXISTS(SELECT 1 FROM account_restrictions WHERE actor_address = sqlc.arg(account_address) AND kind = 'block' AND target_address = sqlc.arg(viewer_address)) AS blocked_by;
+
+-- name: ListFollowers :many
+SELECT f.follower_address AS address, h.handle, f.created_at FROM follows f
+LEFT JOIN handles h ON h.eth_address = f.follower_address AND h.retired_at IS NULL
+WHERE f.followee_address = sqlc.arg(account_address)
+    AND (sqlc.arg(before_address)::varchar = '' OR (f.created_at, f.follower_address) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_address)::varchar))
+ORDER BY f.created_at DESC, f.follower_address DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListFollowing :many
+SELECT f.followee_address AS address, h.handle, f.created_at FROM follows f
+LEFT JOIN handles h ON h.eth_address = f.followee_address AND h.retired_at IS NULL
+WHERE f.follower_address = sqlc.arg(account_address)
+    AND (sqlc.arg(before_address)::varchar = '' OR (f.created_at, f.followee_address) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_address)::varchar))
+ORDER BY f.created_at DESC, f.followee_address DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: CreateRestriction :execrows
+INSERT INTO account_restrictions(actor_address, target_address, kind)
+VALUES($1, $2, $3)
+ON CONFLICT (actor_address, kind, target_address) DO NOTHING;
+
+-- name: DeleteRestriction :execrows
+DELETE FROM account_restrictions
+WHERE actor_address = $1 AND target_address = $2 AND kind = $3;
+
+-- name: ListRestrictions :many
+SELECT r.target_address AS address, h.handle, r.created_at FROM account_restrictions r
+LEFT JOIN handles h ON h.eth_address = r.target_address AND h.retired_at IS NULL
+WHERE r.actor_address = sqlc.arg(actor_address) AND r.kind = sqlc.arg(kind)
+    AND (sqlc.arg(before_address)::varchar = '' OR (r.created_at, r.target_address) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_address)::varchar))
+ORDER BY r.created_at DESC, r.target_address DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: LockAccountPairs :exec
+SELECT pg_advisory_xact_lock(k.pair_key) FROM (
+    SELECT DISTINCT hashtextextended(LEAST(sqlc.arg(account_address)::varchar, t.other_address) || ':' || GREATEST(sqlc.arg(account_address)::varchar, t.other_address), 0) AS pair_key
+    FROM unnest(sqlc.arg(other_addresses)::varchar[]) AS t(other_address)
+) k
+ORDER BY k.pair_key;
+
+-- name: IsBlockedBetween :one
+SELECT EXISTS(
+    SELECT 1 FROM account_restrictions
+    WHERE kind = 'block' AND ((actor_address = $1 AND target_address = $2) OR (actor_address = $2 AND target_address = $1))
+);
@@ -0,0 +1,23 @@
+-- name: CreateHandle :one
+INSERT INTO handles(handle, skeleton, eth_address)
+VALUES($1, $2, $3) RETURNING id, handle, skeleton, eth_address, created_at, retired_at;
+
+-- name: GetHandleBySkeleton :one
+SELECT id, handle, skeleton, eth_address, created_at, retired_at FROM handles
+WHERE skeleton = $1;
+
+-- name: GetActiveHandleByAddress :one
+SELECT id, handle, skeleton, eth_address, created_at, retired_at FROM handles
+WHERE eth_address = $1 AND retired_at IS NULL;
+
+-- name: ListHandlesByAddress :many
+SELECT id, handle, skeleton, eth_address, created_at, retired_at FROM handles
+WHERE eth_address = $1 ORDER BY created_at DESC;
+
+-- name: RetireActiveHandle :execrows
+UPDATE handles SET retired_at = CURRENT_TIMESTAMP
+WHERE eth_address = $1 AND retired_at IS NULL;
+
+-- name: ReleaseRetiredHandle :execrows
+DELETE FROM handles
+WHERE skeleton = $1 AND retired_at IS NOT NULL AND retired_at < $2;
@@ -0,0 +1,113 @@
+-- name: UpsertIndexedContract :exec
+INSERT INTO indexed_contracts(chain_id, address, token_kind, backfilled_to)
+VALUES($1, $2, $3, $4)
+ON CONFLICT (chain_id, address) DO NOTHING;
+
+-- name: ListIndexedContracts :many
+SELECT chain_id, address, token_kind, backfilled_to, created_at FROM indexed_contracts
+WHERE chain_id = $1 ORDER BY address;
+
+-- name: GetIndexedContract :one
+SELECT chain_id, address, token_kind, backfilled_to, created_at FROM indexed_contracts
+WHERE chain_id = $1 AND address = $2;
+
+-- name: UpdateContractBackfill :exec
+UPDATE indexed_contracts SET backfilled_to = $3
+WHERE chain_id = $1 AND address = $2;
+
+-- name: AdvanceContractBackfill :exec
+UPDATE indexed_contracts SET backfilled_to = sqlc.arg(synced_to)
+WHERE chain_id = sqlc.arg(chain_id) AND backfilled_to >= sqlc.arg(previous_block);
+
+-- name: ClampContractBackfill :exec
+UPDATE indexed_contracts SET backfilled_to = $2
+WHERE chain_id = $1 AND backfilled_to > $2;
+
+-- name: GetIndexerCheckpoint :one
+SELECT chain_id, block_number, block_hash, updated_at FROM indexer_checkpoints
+WHERE chain_id = $1;
+
+-- name: UpsertIndexerCheckpoint :exec
+INSERT INTO indexer_checkpoints(chain_id, block_number, block_hash)
+VALUES($1, $2, $3)
+ON CONFLICT (chain_id) DO UPDATE SET block_number = EXCLUDED.block_number, block_hash = EXCLUDED.block_hash, updated_at = CURRENT_TIMESTAMP;
+
+-- name: UpsertIndexedBlock :exec
+INSERT INTO indexed_blocks(chain_id, block_number, block_hash)
+VALUES($1, $2, $3)
+ON CONFLICT (chain_id, block_number) DO UPDATE SET block_hash = EXCLUDED.block_hash;
+
+-- name: ListIndexedBlocks :many
+SELECT chain_id, block_number, block_hash FROM indexed_blocks
+WHERE chain_id = $1
+ORDER BY block_number DESC
+LIMIT $2;
+
+-- name: PruneIndexedBlocks :exec
+DELETE FROM indexed_blocks
+WHERE chain_id = $1 AND block_number < $2;
+
+-- name: DeleteIndexedBlocksAfter :exec
+DELETE FROM indexed_blocks
+WHERE chain_id = $1 AND block_number > $2;
+
+-- name: CreateTokenTransfer :execrows
+INSERT INTO token_transfers(chain_id, contract, token_kind, token_id, from_address, to_address, amount, block_number, block_hash, tx_hash, log_index, batch_index, block_time)
+VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+ON CONFLICT (chain_id, tx_hash, log_index, batch_index) DO NOTHING;
+
+-- name: ListTokenTransfersAfter :many
+SELECT id, chain_id, contract, token_kind, token_id, from_address, to_address, amount, block_number, block_hash, tx_hash, log_index, batch_index, created_at, block_time FROM token_transfers
+WHERE chain_id = $1 AND block_number > $2
+ORDER BY block_number DESC, log_index DESC, batch_index DESC;
+
+-- name: ListTransactionTransfers :many
+SELECT id, chain_id, contract, token_kind, token_id, from_address, to_address, amount, block_number, block_hash, tx_hash, log_index, batch_index, created_at, block_time FROM token_transfers
+WHERE chain_id = $1 AND tx_hash = $2
+ORDER BY log_index, batch_index;
+
+-- name: DeleteTokenTransfersAfter :execrows
+DELETE FROM token_transfers
+WHERE chain_id = $1 AND block_number > $2;
+
+-- name: AddTokenHoldingBalance :exec
+INSERT INTO token_holdings(chain_id, contract, token_kind, token_id, holder, balance, updated_block)
+VALUES($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (chain_id, contract, token_id, holder) DO UPDATE
+SET balance = token_holdings.balance + EXCLUDED.balance, updated_block = EXCLUDED.updated_block, updated_at = CURRENT_TIMESTAMP;
+
+-- name: DeleteEmptyTokenHoldings :exec
+DELETE FROM token_holdings
+WHERE chain_id = $1 AND balance <= 0;
+
+-- name: ListHolderTransfers :many
+SELECT id, chain_id, contract, token_kind, token_id, from_address, to_address, amount, block_number, block_hash, tx_hash, log_index, batch_index, created_at, block_time FROM token_transfers
+WHERE chain_id = sqlc.arg(chain_id) AND contract = sqlc.arg(contract)
+  AND (from_address = sqlc.arg(holder) OR to_address = sqlc.arg(holder))
+  AND (NOT sqlc.arg(match_token)::boolean OR token_id = sqlc.arg(token_id))
+ORDER BY block_number, log_index, batch_index;
+
+-- name: GetHolderBalanceAt :one
+SELECT (
+    COALESCE(SUM(amount) FILTER (WHERE to_address = sqlc.arg(holder)), 0) -
+    COALESCE(SUM(amount) FILTER (WHERE from_address = sqlc.arg(holder)), 0)
+)::numeric AS balance
+FROM token_transfers
+WHERE chain_id = sqlc.arg(chain_id) AND contract = sqlc.arg(contract) AND block_number <= sqlc.arg(block_number)
+  AND (from_address = sqlc.arg(holder) OR to_address = sqlc.arg(holder))
+  AND (NOT sqlc.arg(match_token)::boolean OR token_id = sqlc.arg(token_id));
+
+-- name: ListHoldersAt :many
+SELECT holder, SUM(delta)::numeric AS balance FROM (
+    SELECT to_address AS holder, amount AS delta FROM token_transfers
+    WHERE chain_id = sqlc.arg(chain_id) AND contract = sqlc.arg(contract) AND block_number <= sqlc.arg(block_number)
+      AND (NOT sqlc.arg(match_token)::boolean OR token_id = sqlc.arg(token_id))
+    UNION ALL
+    SELECT from_address AS holder, -amount AS delta FROM token_transfers
+    WHERE chain_id = sqlc.arg(chain_id) AND contract = sqlc.arg(contract) AND block_number <= sqlc.arg(block_number)
+      AND (NOT sqlc.arg(match_token)::boolean OR token_id = sqlc.arg(token_id))
+) moves
+WHERE holder <> '0x0000000000000000000000000000000000000000'
+GROUP BY holder
+HAVING SUM(delta) > 0
+ORDER BY holder;
@@ -0,0 +1,75 @@
+-- name: CreateMediaFile :one
+INSERT INTO media_files(owner_address, post_id, storage_key, filename, content_type, size_bytes, sha256, data_key, key_id, processing_status)
+VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING id, owner_address, post_id, storage_key, filename, content_type, size_bytes, sha256, created_at, data_key, key_id, processing_status, processing_attempts, processing_started_at, width, height, blurhash;
+
+-- name: GetMediaFile :one
+SELECT id, owner_address, post_id, storage_key, filename, content_type, size_bytes, sha256, created_at, data_key, key_id, processing_status, processing_attempts, processing_started_at, width, height, blurhash FROM media_files
+WHERE id = $1;
+
+-- name: ListPostMedia :many
+SELECT id, owner_address, post_id, storage_key, filename, content_type, size_bytes, sha256, created_at, data_key, key_id, processing_status, processing_attempts, processing_started_at, width, height, blurhash FROM media_files
+WHERE post_id = $1
+ORDER BY created_at;
+
+-- name: DeleteMediaFile :execrows
+DELETE FROM media_files WHERE id = $1 AND owner_address = $2;
+
+-- name: ListMediaToEncrypt :many
+SELECT id, owner_address, post_id, storage_key, filename, content_type, size_bytes, sha256, created_at, data_key, key_id, processing_status, processing_attempts, processing_started_at, width, height, blurhash FROM media_files
+WHERE (key_id IS NULL OR key_id <> sqlc.arg(active_key_id)) AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg(row_limit);
+
+-- name: SealMediaFile :exec
+UPDATE media_files SET storage_key = $2, data_key = $3, key_id = $4,
+    processing_status = CASE WHEN processing_status = 'none' THEN 'none' ELSE 'pending' END,
+    processing_attempts = 0
+WHERE id = $1;
+
+-- name: RewrapMediaKey :exec
+UPDATE media_files SET data_key = $2, key_id = $3
+WHERE id = $1;
+
+-- name: ClaimMediaToProcess :many
+UPDATE media_files SET processing_status = 'processing', processing_started_at = NOW(), processing_attempts = processing_attempts + 1
+WHERE id IN (
+    SELECT m.id FROM media_files m
+    WHERE (m.processing_status = 'pending' OR (m.processing_status = 'processing' AND m.processing_started_at < sqlc.arg(stale_before)))
+        AND m.processing_attempts < sqlc.arg(max_attempts) AND m.content_type = ANY(sqlc.arg(content_types)::text[])
+    ORDER BY m.created_at
+    LIMIT sqlc.arg(row_limit)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, owner_address, post_id, storage_key, filename, content_type, size_bytes, sha256, created_at, data_key, key_id, processing_status, processing_attempts, processing_started_at, width, height, blurhash;
+
+-- name: FailStaleMediaProcessing :execrows
+UPDATE media_files SET processing_status = 'failed'
+WHERE processing_status = 'processing' AND processing_started_at < sqlc.arg(stale_before)
+    AND processing_attempts >= sqlc.arg(max_attempts) AND content_type = ANY(sqlc.arg(content_types)::text[]);
+
+-- name: FailMediaProcessing :exec
+UPDATE media_files SET processing_status = CASE WHEN processing_attempts >= sqlc.arg(max_attempts) THEN 'failed' ELSE 'pending' END
+WHERE id = sqlc.arg(id) AND processing_status = 'processing';
+
+-- name: FinishMediaProcessing :execrows
+UPDATE media_files SET processing_status = 'ready', storage_key = sqlc.arg(storage_key), size_bytes = sqlc.arg(size_bytes),
+    sha256 = sqlc.arg(sha256), width = sqlc.arg(width), height = sqlc.arg(height), blurhash = sqlc.arg(blurhash)
+WHERE id = sqlc.arg(id) AND processing_status = 'processing' AND storage_key = sqlc.arg(processed_key);
+
+-- name: FinishStreamProcessing :execrows
+UPDATE media_files SET processing_status = 'ready'
+WHERE id = sqlc.arg(id) AND processing_status = 'processing' AND storage_key = sqlc.arg(processed_key);
+
+-- name: DeleteMediaVariants :many
+DELETE FROM media_variants WHERE media_id = $1
+RETURNING storage_key;
+
+-- name: CreateMediaVariant :exec
+INSERT INTO media_variants(media_id, name, storage_key, content_type, width, height, size_bytes)
+VALUES($1, $2, $3, $4, $5, $6, $7);
+
+-- name: ListMediaVariants :many
+SELECT media_id, name, storage_key, content_type, width, height, size_bytes, created_at FROM media_variants
+WHERE media_id = ANY(sqlc.arg(media_ids)::uuid[])
+ORDER BY media_id, width;
@@ -0,0 +1,19 @@
+-- name: GetMediaStream :one
+SELECT media_id, storage_prefix, segment_key, duration_ms, created_at FROM media_streams
+WHERE media_id = $1;
+
+-- name: ListStreamRenditions :many
+SELECT media_id, name, width, height, bandwidth, playlist FROM media_stream_renditions
+WHERE media_id = $1
+ORDER BY height;
+
+-- name: DeleteMediaStream :exec
+DELETE FROM media_streams WHERE media_id = $1;
+
+-- name: CreateMediaStream :exec
+INSERT INTO media_streams(media_id, storage_prefix, segment_key, duration_ms)
+VALUES($1, $2, $3, $4);
+
+-- name: CreateStreamRendition :exec
+INSERT INTO media_stream_renditions(media_id, name, width, height, bandwidth, playlist)
+VALUES($1, $2, $3, $4, $5, $6);
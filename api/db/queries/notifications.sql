@@ -0,0 +1,86 @@
+-- name: CreateNotification :one
+INSERT INTO notifications(recipient_address, kind, actor_address, subject_id, data, group_key)
+SELECT sqlc.arg(recipient_address)::varchar, sqlc.arg(kind)::varchar, sqlc.arg(actor_address)::varchar, sqlc.arg(subject_id)::varchar, sqlc.arg(data)::jsonb, sqlc.arg(group_key)::varchar
+WHERE sqlc.arg(group_key)::varchar IS NULL OR NOT EXISTS (
+    SELECT 1 FROM notifications n
+    WHERE n.recipient_address = sqlc.arg(recipient_address) AND n.group_key = sqlc.arg(group_key) AND n.read_at IS NULL
+)
+RETURNING id, recipient_address, kind, actor_address, subject_id, data, group_key, read_at, created_at;
+
+-- name: CreateMemberNotifications :many
+INSERT INTO notifications(recipient_address, kind, actor_address, subject_id, data)
+SELECT DISTINCT m.member_address, sqlc.arg(kind)::varchar, sqlc.arg(owner_address)::varchar, sqlc.arg(subject_id)::varchar, sqlc.arg(data)::jsonb FROM community_members m
+JOIN communities c ON c.id = m.community_id
+WHERE c.owner_address = sqlc.arg(owner_address) AND m.member_address <> sqlc.arg(owner_address)
+RETURNING id, recipient_address, kind, actor_address, subject_id, data, group_key, read_at, created_at;
+
+-- name: ReleaseDrips :many
+WITH due AS (
+    SELECT d.post_id, m.member_address FROM post_drips d
+    JOIN posts p ON p.id = d.post_id
+    JOIN community_members m ON m.community_id = d.community_id
+    WHERE p.status = 'published' AND m.member_address <> p.author_address
+        AND m.joined_at + make_interval(secs => d.delay_seconds) <= CURRENT_TIMESTAMP
+        AND m.joined_at + make_interval(secs => d.delay_seconds) > GREATEST(d.created_at, p.published_at)
+        AND NOT EXISTS (
+            SELECT 1 FROM drip_releases r
+            WHERE r.post_id = d.post_id AND r.member_address = m.member_address
+        )
+    LIMIT sqlc.arg(row_limit)
+), released AS (
+    INSERT INTO drip_releases(post_id, member_address)
+    SELECT post_id, member_address FROM due
+    ON CONFLICT DO NOTHING
+    RETURNING post_id, member_address
+)
+INSERT INTO notifications(recipient_address, kind, actor_address, subject_id, data)
+SELECT r.member_address, 'content_unlocked', p.author_address, p.id::text, jsonb_build_object('post_id', p.id::text, 'title', p.title) FROM released r
+JOIN posts p ON p.id = r.post_id
+RETURNING id, recipient_address, kind, actor_address, subject_id, data, group_key, read_at, created_at;
+
+-- name: ListNotifications :many
+SELECT id, recipient_address, kind, actor_address, subject_id, data, group_key, read_at, created_at FROM notifications
+WHERE recipient_address = sqlc.arg(recipient_address) AND id < sqlc.arg(before_id)
+    AND (NOT sqlc.arg(unread_only)::boolean OR read_at IS NULL)
+ORDER BY id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListNotificationsAfter :many
+SELECT id, recipient_address, kind, actor_address, subject_id, data, group_key, read_at, created_at FROM notifications
+WHERE recipient_address = $1 AND id > $2
+ORDER BY id
+LIMIT $3;
+
+-- name: GetLatestNotificationID :one
+SELECT COALESCE(MAX(id), 0)::bigint FROM notifications
+WHERE recipient_address = $1;
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE recipient_address = $1 AND read_at IS NULL;
+
+-- name: MarkNotificationRead :one
+UPDATE notifications SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP)
+WHERE id = $1 AND recipient_address = $2
+RETURNING id, recipient_address, kind, actor_address, subject_id, data, group_key, read_at, created_at;
+
+-- name: MarkNotificationUnread :one
+UPDATE notifications SET read_at = NULL
+WHERE id = $1 AND recipient_address = $2
+RETURNING id, recipient_address, kind, actor_address, subject_id, data, group_key, read_at, created_at;
+
+-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications SET read_at = CURRENT_TIMESTAMP
+WHERE recipient_address = $1 AND read_at IS NULL AND id <= $2;
+
+-- name: NotifyNotifications :exec
+SELECT pg_notify('notification_events', sqlc.arg(payload)::text);
+
+-- name: ListNotificationPreferences :many
+SELECT account_address, channel, kind, enabled, updated_at FROM notification_preferences
+WHERE account_address = $1;
+
+-- name: UpsertNotificationPreference :exec
+INSERT INTO notification_preferences(account_address, channel, kind, enabled)
+VALUES($1, $2, $3, $4)
+ON CONFLICT (account_address, channel, kind) DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = CURRENT_TIMESTAMP;
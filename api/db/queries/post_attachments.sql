@@ -0,0 +1,15 @@
+-- name: CreatePostAttachment :one
+INSERT INTO post_attachments(post_id, name, url, content_type, size_bytes, position, name_ciphertext, url_ciphertext)
+VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, post_id, name, url, content_type, size_bytes, position, created_at, name_ciphertext, url_ciphertext;
+
+-- name: ListPostAttachments :many
+SELECT id, post_id, name, url, content_type, size_bytes, position, created_at, name_ciphertext, url_ciphertext FROM post_attachments
+WHERE post_id = $1 ORDER BY position, created_at;
+
+-- name: DeletePostAttachment :execrows
+DELETE FROM post_attachments WHERE id = $1 AND post_id = $2;
+
+-- name: SealPostAttachment :exec
+UPDATE post_attachments SET name = '', url = '', name_ciphertext = $2, url_ciphertext = $3
+WHERE id = $1;
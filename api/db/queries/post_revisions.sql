@@ -0,0 +1,34 @@
+-- name: CreatePostRevision :exec
+INSERT INTO post_revisions(post_id, number, author_address, status, title, teaser, tags, body, body_format, access_policy, body_ciphertext, changed_fields, lines_added, lines_removed, restored_from)
+SELECT p.id, COALESCE((SELECT MAX(r.number) FROM post_revisions r WHERE r.post_id = p.id), 0) + 1, p.author_address, p.status, p.title, p.teaser, p.tags, p.body, p.body_format, p.access_policy, p.body_ciphertext,
+    sqlc.arg(changed_fields)::text[], sqlc.arg(lines_added)::int, sqlc.arg(lines_removed)::int, NULLIF(sqlc.arg(restored_from)::int, 0)
+FROM posts p
+WHERE p.id = sqlc.arg(post_id);
+
+-- name: GetPostRevision :one
+SELECT post_id, number, author_address, status, title, teaser, tags, body, body_format, access_policy, body_ciphertext, changed_fields, lines_added, lines_removed, restored_from, created_at FROM post_revisions
+WHERE post_id = $1 AND number = $2;
+
+-- name: ListPostRevisions :many
+SELECT post_id, number, author_address, status, title, teaser, tags, body, body_format, access_policy, body_ciphertext, changed_fields, lines_added, lines_removed, restored_from, created_at FROM post_revisions
+WHERE post_id = $1
+ORDER BY number DESC
+LIMIT $2 OFFSET $3;
+
+-- name: ListPlaintextRevisions :many
+SELECT post_id, number, author_address, status, title, teaser, tags, body, body_format, access_policy, body_ciphertext, changed_fields, lines_added, lines_removed, restored_from, created_at FROM post_revisions
+WHERE post_id = $1 AND body_ciphertext IS NULL AND body <> ''
+ORDER BY number;
+
+-- name: SealPostRevision :exec
+UPDATE post_revisions SET body = '', body_ciphertext = $3
+WHERE post_id = $1 AND number = $2;
+
+-- name: PruneDraftRevisions :execrows
+DELETE FROM post_revisions
+WHERE (post_id, number) IN (
+    SELECT o.post_id, o.number FROM post_revisions o
+    WHERE o.status <> 'published' AND o.created_at < sqlc.arg(cutoff)
+        AND o.number < (SELECT MAX(l.number) FROM post_revisions l WHERE l.post_id = o.post_id)
+    LIMIT sqlc.arg(row_limit)
+);
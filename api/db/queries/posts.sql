@@ -0,0 +1,112 @@
+-- name: CreatePost :one
+INSERT INTO posts(author_address, title, body, body_format, access_policy, body_ciphertext, data_key, key_id, teaser, tags)
+VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING id, author_address, title, body, body_format, status, access_policy, created_at, updated_at, published_at, body_ciphertext, data_key, key_id, teaser, tags, publish_at;
+
+-- name: GetPost :one
+SELECT id, author_address, title, body, body_format, status, access_policy, created_at, updated_at, published_at, body_ciphertext, data_key, key_id, teaser, tags, publish_at FROM posts
+WHERE id = $1;
+
+-- name: UpdatePost :one
+UPDATE posts SET title = $2, body = $3, body_format = $4, access_policy = $5, body_ciphertext = $6, data_key = $7, key_id = $8, teaser = $9, tags = $10, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING id, author_address, title, body, body_format, status, access_policy, created_at, updated_at, published_at, body_ciphertext, data_key, key_id, teaser, tags, publish_at;
+
+-- name: PublishPost :one
+UPDATE posts SET status = 'published', published_at = COALESCE(published_at, CURRENT_TIMESTAMP), publish_at = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING id, author_address, title, body, body_format, status, access_policy, created_at, updated_at, published_at, body_ciphertext, data_key, key_id, teaser, tags, publish_at;
+
+-- name: UnpublishPost :one
+UPDATE posts SET status = 'draft', publish_at = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING id, author_address, title, body, body_format, status, access_policy, created_at, updated_at, published_at, body_ciphertext, data_key, key_id, teaser, tags, publish_at;
+
+-- name: SchedulePost :one
+UPDATE posts SET status = 'scheduled', publish_at = $2, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING id, author_address, title, body, body_format, status, access_policy, created_at, updated_at, published_at, body_ciphertext, data_key, key_id, teaser, tags, publish_at;
+
+-- name: PublishDuePosts :many
+WITH due AS (
+    SELECT id, published_at IS NULL AS first_publish FROM posts
+    WHERE status = 'scheduled' AND publish_at <= CURRENT_TIMESTAMP
+    ORDER BY publish_at
+    LIMIT sqlc.arg(row_limit)
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE posts p SET status = 'published', published_at = COALESCE(p.published_at, CURRENT_TIMESTAMP), publish_at = NULL, updated_at = CURRENT_TIMESTAMP
+FROM due
+WHERE p.id = due.id
+RETURNING p.id, p.author_address, p.title, p.body, p.body_format, p.status, p.access_policy, p.created_at, p.updated_at, p.published_at, p.body_ciphertext, p.data_key, p.key_id, p.teaser, p.tags, p.publish_at, due.first_publish;
+
+-- name: DeletePost :execrows
+DELETE FROM posts WHERE id = $1;
+
+-- name: ListPostsByAuthor :many
+SELECT id, author_address, title, body, body_format, status, access_policy, created_at, updated_at, published_at, body_ciphertext, data_key, key_id, teaser, tags, publish_at FROM posts
+WHERE author_address = $1 AND status = $2
+ORDER BY created_at DESC
+LIMIT $3 OFFSET $4;
+
+-- name: ListAccessPolicies :many
+SELECT access_policy FROM posts
+WHERE access_policy IS NOT NULL
+UNION
+SELECT access_policy FROM communities
+WHERE access_policy IS NOT NULL
+UNION
+SELECT jsonb_build_object('type', 'erc20_balance', 'chain_id', price_chain_id, 'contract', price_token) FROM communities
+WHERE price_token IS NOT NULL;
+
+-- name: ListPostsToEncrypt :many
+SELECT id, author_address, title, body, body_format, status, access_policy, created_at, updated_at, published_at, body_ciphertext, data_key, key_id, teaser, tags, publish_at FROM posts
+WHERE (key_id IS NULL OR key_id <> sqlc.arg(active_key_id) OR EXISTS (
+        SELECT 1 FROM post_revisions r
+        WHERE r.post_id = posts.id AND r.body_ciphertext IS NULL AND r.body <> ''
+    ))
+    AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg(row_limit);
+
+-- name: SealPost :exec
+UPDATE posts SET body = '', body_ciphertext = $2, data_key = $3, key_id = $4
+WHERE id = $1;
+
+-- name: RewrapPostKey :exec
+UPDATE posts SET data_key = $2, key_id = $3
+WHERE id = $1;
+
+-- name: IndexPost :exec
+INSERT INTO post_search(post_id, search_vector)
+VALUES(sqlc.arg(post_id), setweight(to_tsvector('english', sqlc.arg(title)::text), 'A')
+    || setweight(to_tsvector('simple', array_to_string(sqlc.arg(tags)::text[], ' ')), 'B')
+    || setweight(to_tsvector('english', sqlc.arg(teaser)::text), 'C')
+    || setweight(to_tsvector('english', sqlc.arg(search_body)::text), 'D'))
+ON CONFLICT (post_id) DO UPDATE SET search_vector = EXCLUDED.search_vector;
+
+-- name: ReindexPost :exec
+INSERT INTO post_search(post_id, search_vector)
+SELECT id, setweight(to_tsvector('english', title), 'A')
+    || setweight(to_tsvector('simple', array_to_string(tags, ' ')), 'B')
+    || setweight(to_tsvector('english', teaser), 'C')
+    || setweight(to_tsvector('english', sqlc.arg(search_body)::text), 'D')
+FROM posts WHERE id = sqlc.arg(post_id)
+ON CONFLICT (post_id) DO UPDATE SET search_vector = EXCLUDED.search_vector;
+
+-- name: UpsertPostDrip :one
+INSERT INTO post_drips(post_id, community_id, delay_seconds)
+VALUES($1, $2, $3)
+ON CONFLICT (post_id) DO UPDATE SET community_id = EXCLUDED.community_id, delay_seconds = EXCLUDED.delay_seconds, created_at = CURRENT_TIMESTAMP
+RETURNING post_id, community_id, delay_seconds, created_at;
+
+-- name: DeletePostDrip :execrows
+DELETE FROM post_drips WHERE post_id = $1;
+
+-- name: DeleteDripReleases :exec
+DELETE FROM drip_releases WHERE post_id = $1;
+
+-- name: ListPostDrips :many
+SELECT d.post_id, d.community_id, d.delay_seconds, m.joined_at FROM post_drips d
+LEFT JOIN community_members m ON m.community_id = d.community_id AND m.member_address = sqlc.arg(member_address)
+WHERE d.post_id = ANY(sqlc.arg(post_ids)::uuid[]);
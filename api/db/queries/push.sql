@@ -0,0 +1,53 @@
+-- name: UpsertPushSubscription :one
+INSERT INTO push_subscriptions(account_address, endpoint, p256dh, auth, user_agent)
+VALUES($1, $2, $3, $4, $5)
+ON CONFLICT (endpoint) DO UPDATE SET account_address = EXCLUDED.account_address, p256dh = EXCLUDED.p256dh, auth = EXCLUDED.auth, user_agent = EXCLUDED.user_agent
+RETURNING id, account_address, endpoint, p256dh, auth, user_agent, created_at, last_used_at;
+
+-- name: ListPushSubscriptions :many
+SELECT id, account_address, endpoint, p256dh, auth, user_agent, created_at, last_used_at FROM push_subscriptions
+WHERE account_address = $1
+ORDER BY created_at DESC;
+
+-- name: DeleteAccountPushSubscription :execrows
+DELETE FROM push_subscriptions
+WHERE id = $1 AND account_address = $2;
+
+-- name: DeletePushSubscription :exec
+DELETE FROM push_subscriptions
+WHERE id = $1;
+
+-- name: TouchPushSubscription :exec
+UPDATE push_subscriptions SET last_used_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: EnqueuePushDeliveries :execrows
+INSERT INTO push_deliveries(subscription_id, notification_id)
+SELECT s.id, n.id FROM notifications n
+JOIN push_subscriptions s ON s.account_address = n.recipient_address
+LEFT JOIN notification_preferences p ON p.account_address = n.recipient_address AND p.channel = 'push' AND p.kind = n.kind
+WHERE n.id = ANY(sqlc.arg(notification_ids)::bigint[]) AND COALESCE(p.enabled, TRUE);
+
+-- name: ClaimPushDeliveries :many
+WITH due AS (
+    SELECT id FROM push_deliveries
+    WHERE next_attempt_at <= CURRENT_TIMESTAMP
+    ORDER BY next_attempt_at, id
+    LIMIT sqlc.arg(row_limit)
+    FOR UPDATE SKIP LOCKED
+), claimed AS (
+    UPDATE push_deliveries d SET attempts = d.attempts + 1, next_attempt_at = sqlc.arg(lease_until)
+    FROM due WHERE d.id = due.id
+    RETURNING d.id, d.subscription_id, d.notification_id, d.attempts
+)
+SELECT c.id, c.attempts, s.id AS subscription_id, s.account_address, s.endpoint, s.p256dh, s.auth, n.id AS notification_id, n.kind, n.actor_address, n.subject_id, n.data, n.read_at, n.created_at FROM claimed c
+JOIN push_subscriptions s ON s.id = c.subscription_id
+JOIN notifications n ON n.id = c.notification_id;
+
+-- name: DeletePushDelivery :exec
+DELETE FROM push_deliveries
+WHERE id = $1;
+
+-- name: ReschedulePushDelivery :exec
+UPDATE push_deliveries SET next_attempt_at = $2, last_error = $3
+WHERE id = $1;
@@ -0,0 +1,37 @@
+-- name: AddPostReaction :exec
+INSERT INTO post_reactions(post_id, reactor_address, emoji)
+VALUES($1, $2, $3)
+ON CONFLICT (post_id, reactor_address, emoji) DO NOTHING;
+
+-- name: RemovePostReaction :execrows
+DELETE FROM post_reactions
+WHERE post_id = $1 AND reactor_address = $2 AND emoji = $3;
+
+-- name: CountPostReactionKinds :one
+SELECT COUNT(*) FROM post_reactions
+WHERE post_id = $1 AND reactor_address = $2 AND emoji <> $3;
+
+-- name: ListPostReactionCounts :many
+SELECT emoji, COUNT(*) AS count, bool_or(reactor_address = sqlc.arg(viewer)::varchar) AS reacted FROM post_reactions
+WHERE post_id = sqlc.arg(post_id)
+GROUP BY emoji
+ORDER BY count DESC, MIN(created_at);
+
+-- name: AddCommentReaction :exec
+INSERT INTO comment_reactions(comment_id, reactor_address, emoji)
+VALUES($1, $2, $3)
+ON CONFLICT (comment_id, reactor_address, emoji) DO NOTHING;
+
+-- name: RemoveCommentReaction :execrows
+DELETE FROM comment_reactions
+WHERE comment_id = $1 AND reactor_address = $2 AND emoji = $3;
+
+-- name: CountCommentReactionKinds :one
+SELECT COUNT(*) FROM comment_reactions
+WHERE comment_id = $1 AND reactor_address = $2 AND emoji <> $3;
+
+-- name: ListCommentReactionCounts :many
+SELECT comment_id, emoji, COUNT(*) AS count, bool_or(reactor_address = sqlc.arg(viewer)::varchar) AS reacted FROM comment_reactions
+WHERE comment_id = ANY(sqlc.arg(comment_ids)::uuid[])
+GROUP BY comment_id, emoji
+ORDER BY comment_id, count DESC, MIN(created_at);
@@ -0,0 +1,30 @@
+-- name: SearchPosts :many
+SELECT p.id, p.author_address, p.title, p.body, p.body_format, p.status, p.access_policy, p.created_at, p.updated_at, p.published_at, p.body_ciphertext, p.data_key, p.key_id, p.teaser, p.tags, p.publish_at,
+    ts_rank_cd(s.search_vector, q.query) AS rank,
+    ts_headline('english', p.title, q.query, sqlc.arg(headline_options)::text) AS title_headline,
+    ts_headline('english', p.teaser, q.query, sqlc.arg(headline_options)::text) AS teaser_headline
+FROM posts p
+JOIN post_search s ON s.post_id = p.id
+CROSS JOIN (SELECT websearch_to_tsquery('english', sqlc.arg(query)::text) || websearch_to_tsquery('simple', sqlc.arg(query)::text) AS query) q
+WHERE p.status = 'published' AND s.search_vector @@ q.query
+ORDER BY rank DESC, p.published_at DESC, p.id
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: SearchHandles :many
+SELECT h.handle, h.eth_address, similarity(h.handle, sqlc.arg(query)::text) AS score
+FROM handles h
+WHERE h.retired_at IS NULL AND (h.handle % sqlc.arg(query)::text OR h.handle ILIKE sqlc.arg(prefix)::text)
+ORDER BY h.handle ILIKE sqlc.arg(prefix)::text DESC, score DESC, h.handle
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: SearchCommunities :many
+SELECT c.id, c.slug, c.name, c.description, c.owner_address, c.join_policy, c.access_policy, c.price_chain_id, c.price_token, c.price_amount, c.member_count, c.created_at, c.updated_at,
+    ts_rank_cd(s.search_vector, q.query) AS rank,
+    ts_headline('english', c.name, q.query, sqlc.arg(headline_options)::text) AS name_headline,
+    ts_headline('english', c.description, q.query, sqlc.arg(headline_options)::text) AS description_headline
+FROM communities c
+JOIN community_search s ON s.community_id = c.id
+CROSS JOIN (SELECT websearch_to_tsquery('english', sqlc.arg(query)::text) || websearch_to_tsquery('simple', sqlc.arg(query)::text) AS query) q
+WHERE s.search_vector @@ q.query
+ORDER BY rank DESC, c.member_count DESC, c.id
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
@@ -0,0 +1,70 @@
+-- name: CreateUpload :one
+INSERT INTO uploads(owner_address, upload_length, metadata, filename, post_id, expires_at, data_key, key_id)
+VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, owner_address, upload_length, upload_offset, metadata, filename, post_id, media_id, completed_at, expires_at, created_at, updated_at, data_key, key_id;
+
+-- name: GetUpload :one
+SELECT id, owner_address, upload_length, upload_offset, metadata, filename, post_id, media_id, completed_at, expires_at, created_at, updated_at, data_key, key_id FROM uploads
+WHERE id = $1;
+
+-- name: AdvanceUploadOffset :execrows
+UPDATE uploads SET upload_offset = sqlc.arg(new_offset), expires_at = sqlc.arg(expires_at), updated_at = NOW()
+WHERE id = sqlc.arg(id) AND upload_offset = sqlc.arg(old_offset) AND completed_at IS NULL;
+
+-- name: CompleteUpload :execrows
+UPDATE uploads SET media_id = $2, completed_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND completed_at IS NULL;
+
+-- name: DeleteUpload :exec
+DELETE FROM uploads WHERE id = $1;
+
+-- name: ListExpiredUploads :many
+SELECT id, owner_address, upload_length, upload_offset, metadata, filename, post_id, media_id, completed_at, expires_at, created_at, updated_at, data_key, key_id FROM uploads
+WHERE expires_at < NOW() AND completed_at IS NULL
+ORDER BY expires_at
+LIMIT $1;
+
+-- name: DeleteExpiredCompletedUploads :execrows
+DELETE FROM uploads WHERE id IN (
+    SELECT id FROM uploads
+    WHERE expires_at < NOW() AND completed_at IS NOT NULL
+    ORDER BY expires_at
+    LIMIT $1
+);
+
+-- name: ListUploadsToRewrap :many
+SELECT id, owner_address, upload_length, upload_offset, metadata, filename, post_id, media_id, completed_at, expires_at, created_at, updated_at, data_key, key_id FROM uploads
+WHERE key_id IS NOT NULL AND key_id <> sqlc.arg(active_key_id) AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg(row_limit);
+
+-- name: RewrapUploadKey :exec
+UPDATE uploads SET data_key = $2, key_id = $3
+WHERE id = $1;
+
+-- name: LockUploadOwner :exec
+SELECT pg_advisory_xact_lock(hashtext($1));
+
+-- name: LockUpload :exec
+SELECT pg_advisory_lock(hashtext($1));
+
+-- name: UnlockUpload :exec
+SELECT pg_advisory_unlock(hashtext($1));
+
+-- name: CreateUploadChunk :exec
+INSERT INTO upload_chunks(upload_id, chunk_offset, storage_key, size_bytes)
+VALUES($1, $2, $3, $4);
+
+-- name: ListUploadChunks :many
+SELECT upload_id, chunk_offset, storage_key, size_bytes FROM upload_chunks
+WHERE upload_id = $1
+ORDER BY chunk_offset;
+
+-- name: DeleteUploadChunks :exec
+DELETE FROM upload_chunks WHERE upload_id = $1;
+
+-- name: GetStorageUsage :one
+SELECT (
+    COALESCE((SELECT SUM(media_files.size_bytes) FROM media_files WHERE media_files.owner_address = $1), 0) +
+    COALESCE((SELECT SUM(uploads.upload_length) FROM uploads WHERE uploads.owner_address = $1 AND uploads.completed_at IS NULL AND uploads.expires_at > NOW()), 0)
+)::BIGINT AS used;
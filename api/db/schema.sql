@@ -45,4 +45,642 @@ CREATE TABLE IF NOT EXISTS refresh_tokens(
     user_agent TEXT,
     device_name VARCHAR(255),  -- e.g., "Chrome on Windows"
     family_id UUID                      -- optional: for rotation detection
-);
\ No newline at end of file
+);
+-- handles table :- it holds @handles claimed by accounts. Retired handles are kept
+-- so that old share links keep redirecting to the account's current handle
+CREATE TABLE IF NOT EXISTS handles(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    handle VARCHAR(30) NOT NULL,
+    skeleton VARCHAR(30) NOT NULL,   -- confusable-folded form used for uniqueness
+    eth_address VARCHAR(42) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    retired_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS handles_skeleton_idx ON handles(skeleton);
+CREATE UNIQUE INDEX IF NOT EXISTS handles_active_address_idx ON handles(eth_address) WHERE retired_at IS NULL;
+
+-- posts table :- it holds content written by creators. access_policy decides who can
+-- read the body, a NULL policy makes the post public
+CREATE TABLE IF NOT EXISTS posts(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    author_address VARCHAR(42) NOT NULL,
+    title VARCHAR(200) NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    body_format VARCHAR(16) NOT NULL DEFAULT 'markdown' CHECK (body_format IN ('markdown', 'richtext')),
+    status VARCHAR(16) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'published')),
+    access_policy JSONB,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS posts_author_status_idx ON posts(author_address, status, created_at DESC);
+
+-- post_attachments table :- files linked to a post, readable under the post's access policy
+CREATE TABLE IF NOT EXISTS post_attachments(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    url TEXT NOT NULL,
+    content_type VARCHAR(127) NOT NULL,
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS post_attachments_post_idx ON post_attachments(post_id, position);
+
+-- indexed_contracts table :- token contracts referenced by gating rules which the indexer
+-- follows. backfilled_to is the last block whose logs were applied for the contract
+CREATE TABLE IF NOT EXISTS indexed_contracts(
+    chain_id BIGINT NOT NULL,
+    address VARCHAR(42) NOT NULL,
+    token_kind VARCHAR(8) NOT NULL CHECK (token_kind IN ('erc20', 'erc721', 'erc1155')),
+    backfilled_to BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (chain_id, address)
+);
+
+-- token_transfers table :- every Transfer, TransferSingle and TransferBatch log seen by the
+-- indexer. It is the source of token_holdings and is rolled back on reorgs
+CREATE TABLE IF NOT EXISTS token_transfers(
+    id BIGSERIAL PRIMARY KEY,
+    chain_id BIGINT NOT NULL,
+    contract VARCHAR(42) NOT NULL,
+    token_kind VARCHAR(8) NOT NULL,
+    token_id NUMERIC(78, 0) NOT NULL DEFAULT 0,   -- 0 for erc20
+    from_address VARCHAR(42) NOT NULL,
+    to_address VARCHAR(42) NOT NULL,
+    amount NUMERIC(78, 0) NOT NULL,
+    block_number BIGINT NOT NULL,
+    block_hash VARCHAR(66) NOT NULL,
+    tx_hash VARCHAR(66) NOT NULL,
+    log_index INT NOT NULL,
+    batch_index INT NOT NULL DEFAULT 0,           -- position inside a TransferBatch
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (chain_id, tx_hash, log_index, batch_index)
+);
+
+CREATE INDEX IF NOT EXISTS token_transfers_block_idx ON token_transfers(chain_id, block_number);
+CREATE INDEX IF NOT EXISTS token_transfers_token_idx ON token_transfers(chain_id, contract, token_id, block_number);
+
+-- token_holdings table :- current balance of every holder of an indexed contract
+CREATE TABLE IF NOT EXISTS token_holdings(
+    chain_id BIGINT NOT NULL,
+    contract VARCHAR(42) NOT NULL,
+    token_kind VARCHAR(8) NOT NULL,
+    token_id NUMERIC(78, 0) NOT NULL DEFAULT 0,
+    holder VARCHAR(42) NOT NULL,
+    balance NUMERIC(78, 0) NOT NULL DEFAULT 0,
+    updated_block BIGINT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (chain_id, contract, token_id, holder)
+);
+
+CREATE INDEX IF NOT EXISTS token_holdings_holder_idx ON token_holdings(chain_id, holder);
+
+-- indexer_checkpoints table :- last block fully indexed per chain
+CREATE TABLE IF NOT EXISTS indexer_checkpoints(
+    chain_id BIGINT PRIMARY KEY,
+    block_number BIGINT NOT NULL,
+    block_hash VARCHAR(66) NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- indexed_blocks table :- hashes of recently indexed blocks, used to find the last
+-- common ancestor when the chain reorganizes
+CREATE TABLE IF NOT EXISTS indexed_blocks(
+    chain_id BIGINT NOT NULL,
+    block_number BIGINT NOT NULL,
+    block_hash VARCHAR(66) NOT NULL,
+    PRIMARY KEY (chain_id, block_number)
+);
+
+-- token_transfers.block_time :- timestamp of the block a transfer was mined in, used by
+-- held_for_days conditions. Transfers indexed before this column existed have none
+ALTER TABLE token_transfers ADD COLUMN IF NOT EXISTS block_time TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS token_transfers_from_idx ON token_transfers(chain_id, contract, from_address, block_number);
+CREATE INDEX IF NOT EXISTS token_transfers_to_idx ON token_transfers(chain_id, contract, to_address, block_number);
+
+-- media_files table :- files uploaded by creators. A file linked to a post can be
+-- downloaded under the post's access policy, other files only by their owner
+CREATE TABLE IF NOT EXISTS media_files(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    owner_address VARCHAR(42) NOT NULL,
+    post_id UUID REFERENCES posts(id) ON DELETE SET NULL,
+    storage_key TEXT NOT NULL UNIQUE,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(127) NOT NULL,   -- sniffed from the content, not the client's claim
+    size_bytes BIGINT NOT NULL,
+    sha256 VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS media_files_owner_idx ON media_files(owner_address, created_at DESC);
+CREATE INDEX IF NOT EXISTS media_files_post_idx ON media_files(post_id);
+
+-- uploads table :- resumable tus uploads in progress. Received bytes are kept as chunk
+-- objects in media storage until the upload completes and is handed over as a media file
+CREATE TABLE IF NOT EXISTS uploads(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    owner_address VARCHAR(42) NOT NULL,
+    upload_length BIGINT NOT NULL,
+    upload_offset BIGINT NOT NULL DEFAULT 0,
+    metadata TEXT NOT NULL DEFAULT '',     -- raw Upload-Metadata header, echoed back on HEAD
+    filename VARCHAR(255) NOT NULL DEFAULT '',
+    post_id UUID REFERENCES posts(id) ON DELETE SET NULL,
+    media_id UUID REFERENCES media_files(id) ON DELETE SET NULL,  -- set once completed
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS uploads_owner_idx ON uploads(owner_address) WHERE completed_at IS NULL;
+CREATE INDEX IF NOT EXISTS uploads_expires_idx ON uploads(expires_at);
+
+-- upload_chunks table :- the bytes received by one PATCH request of an upload
+CREATE TABLE IF NOT EXISTS upload_chunks(
+    upload_id UUID NOT NULL REFERENCES uploads(id) ON DELETE CASCADE,
+    chunk_offset BIGINT NOT NULL,
+    storage_key TEXT NOT NULL UNIQUE,
+    size_bytes BIGINT NOT NULL,
+    PRIMARY KEY (upload_id, chunk_offset)
+);
+
+-- content encryption :- every post has a random data key, stored wrapped by the master
+-- key named in key_id. The body and attachment names and urls are kept encrypted under
+-- it, the plaintext columns stay empty once a row is encrypted
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS body_ciphertext BYTEA;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS data_key BYTEA;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS key_id VARCHAR(64);
+
+ALTER TABLE post_attachments ADD COLUMN IF NOT EXISTS name_ciphertext BYTEA;
+ALTER TABLE post_attachments ADD COLUMN IF NOT EXISTS url_ciphertext BYTEA;
+
+-- media objects and upload chunks are encrypted as streams under their own data key
+ALTER TABLE media_files ADD COLUMN IF NOT EXISTS data_key BYTEA;
+ALTER TABLE media_files ADD COLUMN IF NOT EXISTS key_id VARCHAR(64);
+
+ALTER TABLE uploads ADD COLUMN IF NOT EXISTS data_key BYTEA;
+ALTER TABLE uploads ADD COLUMN IF NOT EXISTS key_id VARCHAR(64);
+
+-- image processing :- uploaded images wait in processing_status 'pending' until the
+-- image worker stripped their metadata and rendered the variants. Other files stay 'none'
+ALTER TABLE media_files ADD COLUMN IF NOT EXISTS processing_status VARCHAR(16) NOT NULL DEFAULT 'none';
+ALTER TABLE media_files ADD COLUMN IF NOT EXISTS processing_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE media_files ADD COLUMN IF NOT EXISTS processing_started_at TIMESTAMP;
+ALTER TABLE media_files ADD COLUMN IF NOT EXISTS width INT;
+ALTER TABLE media_files ADD COLUMN IF NOT EXISTS height INT;
+ALTER TABLE media_files ADD COLUMN IF NOT EXISTS blurhash VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS media_files_processing_idx ON media_files(created_at)
+    WHERE processing_status IN ('pending', 'processing');
+
+-- media variants :- resized renditions and the blurred preview of an image, encrypted
+-- under the data key of their media file
+CREATE TABLE IF NOT EXISTS media_variants(
+    media_id UUID NOT NULL REFERENCES media_files(id) ON DELETE CASCADE,
+    name VARCHAR(16) NOT NULL,
+    storage_key TEXT NOT NULL UNIQUE,
+    content_type VARCHAR(127) NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (media_id, name)
+);
+
+-- media_streams table :- HLS packages of uploaded videos. Segments are encrypted with
+-- AES-128 under segment_key, which is kept sealed by the data key of the media file and
+-- only handed to players passing the post's access policy
+CREATE TABLE IF NOT EXISTS media_streams(
+    media_id UUID PRIMARY KEY REFERENCES media_files(id) ON DELETE CASCADE,
+    storage_prefix TEXT NOT NULL UNIQUE,   -- segments live at {prefix}/{rendition}/{segment}
+    segment_key BYTEA NOT NULL,
+    duration_ms BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- media_stream_renditions table :- one quality of a stream, its media playlist lists
+-- the segment names relative to the rendition
+CREATE TABLE IF NOT EXISTS media_stream_renditions(
+    media_id UUID NOT NULL REFERENCES media_streams(media_id) ON DELETE CASCADE,
+    name VARCHAR(16) NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    bandwidth INT NOT NULL,
+    playlist TEXT NOT NULL,
+    PRIMARY KEY (media_id, name)
+);
+
+-- communities table :- spaces owned by a creator. join_policy decides how accounts become
+-- members: open to anyone, token_gated by access_policy, paid with an ERC-20 transfer of
+-- price_amount to the owner, or invite_only
+CREATE TABLE IF NOT EXISTS communities(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    slug VARCHAR(40) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    owner_address VARCHAR(42) NOT NULL,
+    join_policy VARCHAR(16) NOT NULL CHECK (join_policy IN ('open', 'token_gated', 'paid', 'invite_only')),
+    access_policy JSONB,
+    price_chain_id BIGINT,
+    price_token VARCHAR(42),
+    price_amount NUMERIC(78, 0),
+    member_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS communities_slug_idx ON communities(slug);
+CREATE INDEX IF NOT EXISTS communities_owner_idx ON communities(owner_address, created_at DESC);
+
+-- community_members table :- accounts belonging to a community. granted_via, granted_policy
+-- and granted_blocks record the policy a membership was granted under and the chain heads
+-- it was evaluated at, payment_tx the transfer which paid for it
+CREATE TABLE IF NOT EXISTS community_members(
+    community_id UUID NOT NULL REFERENCES communities(id) ON DELETE CASCADE,
+    member_address VARCHAR(42) NOT NULL,
+    role VARCHAR(16) NOT NULL DEFAULT 'member' CHECK (role IN ('owner', 'admin', 'moderator', 'member')),
+    granted_via VARCHAR(16) NOT NULL CHECK (granted_via IN ('owner', 'open', 'token_gated', 'paid', 'invite')),
+    granted_policy JSONB,
+    granted_blocks JSONB,
+    payment_tx VARCHAR(66),
+    invited_by VARCHAR(42),
+    joined_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (community_id, member_address)
+);
+
+CREATE INDEX IF NOT EXISTS community_members_address_idx ON community_members(member_address, joined_at DESC);
+-- a payment buys a single membership
+CREATE UNIQUE INDEX IF NOT EXISTS community_members_payment_idx ON community_members(payment_tx) WHERE payment_tx IS NOT NULL;
+
+-- paid joins look up the transfers of a transaction
+CREATE INDEX IF NOT EXISTS token_transfers_tx_idx ON token_transfers(chain_id, tx_hash);
+
+-- community_invites table :- shareable links into a community. Links created by admins
+-- admit on their own, others are referrals subject to the join policy. access_policy is
+-- a gating policy the redeemer must satisfy on top. clicks, sign_ins and uses count
+-- opened links, sign-ins through the link and joins
+CREATE TABLE IF NOT EXISTS community_invites(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    community_id UUID NOT NULL REFERENCES communities(id) ON DELETE CASCADE,
+    code VARCHAR(32) NOT NULL,
+    inviter_address VARCHAR(42) NOT NULL,
+    max_uses INT CHECK (max_uses > 0),
+    uses INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP,
+    access_policy JSONB,
+    clicks INT NOT NULL DEFAULT 0,
+    sign_ins INT NOT NULL DEFAULT 0,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS community_invites_code_idx ON community_invites(code);
+CREATE INDEX IF NOT EXISTS community_invites_community_idx ON community_invites(community_id, inviter_address, created_at DESC);
+
+-- invite_id attributes a membership to the link it was redeemed through
+ALTER TABLE community_members ADD COLUMN IF NOT EXISTS invite_id UUID REFERENCES community_invites(id) ON DELETE SET NULL;
+
+-- post_comments table :- threaded discussion under posts, parent_id is NULL for top
+-- level comments. Deleted comments keep their row so their replies stay threaded,
+-- deleted_by tells an author's own deletion from moderation. mentions holds the
+-- addresses of accounts mentioned in the body
+CREATE TABLE IF NOT EXISTS post_comments(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    parent_id UUID REFERENCES post_comments(id) ON DELETE CASCADE,
+    depth INT NOT NULL DEFAULT 0,
+    author_address VARCHAR(42) NOT NULL,
+    body TEXT NOT NULL,
+    mentions VARCHAR(42)[] NOT NULL DEFAULT '{}',
+    reply_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    edited_at TIMESTAMP,
+    deleted_at TIMESTAMP,
+    deleted_by VARCHAR(42)
+);
+
+CREATE INDEX IF NOT EXISTS post_comments_top_level_idx ON post_comments(post_id, created_at, id) WHERE parent_id IS NULL;
+CREATE INDEX IF NOT EXISTS post_comments_parent_idx ON post_comments(parent_id, created_at, id);
+CREATE INDEX IF NOT EXISTS post_comments_mentions_idx ON post_comments USING GIN(mentions);
+
+-- post_reactions table :- emoji reactions on posts, an account adds each emoji once
+CREATE TABLE IF NOT EXISTS post_reactions(
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    reactor_address VARCHAR(42) NOT NULL,
+    emoji VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (post_id, reactor_address, emoji)
+);
+
+-- comment_reactions table :- emoji reactions on comments, an account adds each emoji once
+CREATE TABLE IF NOT EXISTS comment_reactions(
+    comment_id UUID NOT NULL REFERENCES post_comments(id) ON DELETE CASCADE,
+    reactor_address VARCHAR(42) NOT NULL,
+    emoji VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (comment_id, reactor_address, emoji)
+);
+
+-- chat_messages table :- history of the chat channel every community has
+CREATE TABLE IF NOT EXISTS chat_messages(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    community_id UUID NOT NULL REFERENCES communities(id) ON DELETE CASCADE,
+    author_address VARCHAR(42) NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS chat_messages_community_idx ON chat_messages(community_id, created_at DESC, id DESC);
+
+-- chat_presence table :- open chat connections across api replicas, one row per
+-- connection. Replicas refresh seen_at of their connections, rows of a replica which
+-- went away stop counting once seen_at is stale
+CREATE UNLOGGED TABLE IF NOT EXISTS chat_presence(
+    session_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    community_id UUID NOT NULL REFERENCES communities(id) ON DELETE CASCADE,
+    member_address VARCHAR(42) NOT NULL,
+    connected_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    seen_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS chat_presence_community_idx ON chat_presence(community_id, member_address);
+CREATE INDEX IF NOT EXISTS chat_presence_seen_idx ON chat_presence(seen_at);
+
+-- notifications table :- in-app notifications of an account. id grows with every insert
+-- and doubles as the event id of the live stream, so clients resume after the last id
+-- they saw. subject_id is the post, comment or community the notification is about and
+-- group_key folds repeats, a recipient has one unread notification per group_key
+CREATE TABLE IF NOT EXISTS notifications(
+    id BIGSERIAL PRIMARY KEY,
+    recipient_address VARCHAR(42) NOT NULL,
+    kind VARCHAR(32) NOT NULL CHECK (kind IN ('new_post', 'reply', 'mention', 'payment_received', 'membership_expiring')),
+    actor_address VARCHAR(42),
+    subject_id VARCHAR(66) NOT NULL,
+    data JSONB NOT NULL DEFAULT '{}',
+    group_key VARCHAR(128),
+    read_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS notifications_recipient_idx ON notifications(recipient_address, id DESC);
+CREATE INDEX IF NOT EXISTS notifications_unread_idx ON notifications(recipient_address) WHERE read_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS notifications_group_idx ON notifications(recipient_address, group_key) WHERE read_at IS NULL AND group_key IS NOT NULL;
+
+-- push_subscriptions table :- browser push subscriptions of accounts. endpoint is the
+-- push service url handed out by the browser, p256dh and auth are the keys messages
+-- are encrypted for
+CREATE TABLE IF NOT EXISTS push_subscriptions(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    account_address VARCHAR(42) NOT NULL,
+    endpoint TEXT NOT NULL,
+    p256dh VARCHAR(128) NOT NULL,
+    auth VARCHAR(64) NOT NULL,
+    user_agent VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS push_subscriptions_endpoint_idx ON push_subscriptions(endpoint);
+CREATE INDEX IF NOT EXISTS push_subscriptions_account_idx ON push_subscriptions(account_address);
+
+-- notification_preferences table :- delivery channels an account switched per kind of
+-- notification, kinds without a row keep the channel default
+CREATE TABLE IF NOT EXISTS notification_preferences(
+    account_address VARCHAR(42) NOT NULL,
+    channel VARCHAR(16) NOT NULL CHECK (channel IN ('push')),
+    kind VARCHAR(32) NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (account_address, channel, kind)
+);
+
+-- push_deliveries table :- outbox of push messages, one row per notification and
+-- subscription. next_attempt_at schedules retries and leases claimed rows, so sends
+-- cut short by a crash are retried
+CREATE TABLE IF NOT EXISTS push_deliveries(
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id UUID NOT NULL REFERENCES push_subscriptions(id) ON DELETE CASCADE,
+    notification_id BIGINT NOT NULL REFERENCES notifications(id) ON DELETE CASCADE,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS push_deliveries_due_idx ON push_deliveries(next_attempt_at);
+
+-- account_emails table :- email addresses accounts are mailed at. Nothing is sent
+-- before verified_at is set by the double opt-in, verify_token_hash is the sha256 of
+-- the token mailed for it. digest is how often a summary is sent, off, daily or weekly
+CREATE TABLE IF NOT EXISTS account_emails(
+    account_address VARCHAR(42) PRIMARY KEY,
+    email VARCHAR(254) NOT NULL,
+    verified_at TIMESTAMP,
+    verify_token_hash VARCHAR(64),
+    verify_expires_at TIMESTAMP,
+    digest VARCHAR(8) NOT NULL DEFAULT 'off' CHECK (digest IN ('off', 'daily', 'weekly')),
+    digest_sent_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS account_emails_verify_token_idx ON account_emails(verify_token_hash) WHERE verify_token_hash IS NOT NULL;
+CREATE INDEX IF NOT EXISTS account_emails_digest_idx ON account_emails(digest, digest_sent_at) WHERE verified_at IS NOT NULL AND digest <> 'off';
+
+ALTER TABLE notification_preferences DROP CONSTRAINT IF EXISTS notification_preferences_channel_check;
+ALTER TABLE notification_preferences ADD CONSTRAINT notification_preferences_channel_check CHECK (channel IN ('push', 'email'));
+
+-- email_deliveries table :- outbox of notification emails, leased and retried like
+-- push_deliveries. The address is read when the email is sent
+CREATE TABLE IF NOT EXISTS email_deliveries(
+    id BIGSERIAL PRIMARY KEY,
+    notification_id BIGINT NOT NULL REFERENCES notifications(id) ON DELETE CASCADE,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS email_deliveries_due_idx ON email_deliveries(next_attempt_at);
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- teaser is the public summary of a post, shown to readers the post is locked for. tags
+-- label posts for search
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS teaser VARCHAR(300) NOT NULL DEFAULT '';
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS tags VARCHAR(32)[] NOT NULL DEFAULT '{}';
+
+-- post_search table :- full-text index of posts, weighted title, tags, teaser and body.
+-- It is written by the post service along with the post since bodies may be stored
+-- encrypted. Bodies of gated posts are left out so their content can not be probed
+-- through search
+CREATE TABLE IF NOT EXISTS post_search(
+    post_id UUID PRIMARY KEY REFERENCES posts(id) ON DELETE CASCADE,
+    search_vector TSVECTOR NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS post_search_vector_idx ON post_search USING GIN(search_vector);
+
+-- encrypted bodies read as empty, those posts are indexed by title until saved again
+INSERT INTO post_search(post_id, search_vector)
+SELECT id, setweight(to_tsvector('english', title), 'A') || setweight(to_tsvector('english', CASE WHEN access_policy IS NULL THEN body ELSE '' END), 'D') FROM posts
+ON CONFLICT (post_id) DO NOTHING;
+
+-- community_search table :- full-text index of communities, kept current by a trigger
+-- as communities are stored in plaintext
+CREATE TABLE IF NOT EXISTS community_search(
+    community_id UUID PRIMARY KEY REFERENCES communities(id) ON DELETE CASCADE,
+    search_vector TSVECTOR NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS community_search_vector_idx ON community_search USING GIN(search_vector);
+
+CREATE OR REPLACE FUNCTION index_community_search() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO community_search(community_id, search_vector)
+    VALUES(NEW.id, setweight(to_tsvector('english', NEW.name), 'A')
+        || setweight(to_tsvector('simple', replace(NEW.slug, '-', ' ')), 'A')
+        || setweight(to_tsvector('english', NEW.description), 'C'))
+    ON CONFLICT (community_id) DO UPDATE SET search_vector = EXCLUDED.search_vector;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS communities_search_trigger ON communities;
+CREATE TRIGGER communities_search_trigger AFTER INSERT OR UPDATE OF name, slug, description ON communities
+FOR EACH ROW EXECUTE FUNCTION index_community_search();
+
+INSERT INTO community_search(community_id, search_vector)
+SELECT id, setweight(to_tsvector('english', name), 'A') || setweight(to_tsvector('simple', replace(slug, '-', ' ')), 'A') || setweight(to_tsvector('english', description), 'C') FROM communities
+ON CONFLICT (community_id) DO NOTHING;
+
+-- creators are found by fuzzy matches on their handle
+CREATE INDEX IF NOT EXISTS handles_handle_trgm_idx ON handles USING GIN(handle gin_trgm_ops) WHERE retired_at IS NULL;
+
+-- follows table :- creators an account follows, their posts show up in the follower's
+-- home feed next to those of the communities the follower belongs to
+CREATE TABLE IF NOT EXISTS follows(
+    follower_address VARCHAR(42) NOT NULL,
+    followee_address VARCHAR(42) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (follower_address, followee_address),
+    CHECK (follower_address <> followee_address)
+);
+
+CREATE INDEX IF NOT EXISTS follows_followee_idx ON follows(followee_address, created_at DESC);
+
+-- the feed reads the published posts of a set of authors newest first
+CREATE INDEX IF NOT EXISTS posts_author_published_idx ON posts(author_address, published_at DESC) WHERE status = 'published';
+
+-- follow_counts table :- follower and following counts of accounts, adjusted along with
+-- the follows they count so profiles do not count rows
+CREATE TABLE IF NOT EXISTS follow_counts(
+    account_address VARCHAR(42) PRIMARY KEY,
+    follower_count INT NOT NULL DEFAULT 0 CHECK (follower_count >= 0),
+    following_count INT NOT NULL DEFAULT 0 CHECK (following_count >= 0)
+);
+
+INSERT INTO follow_counts(account_address, follower_count, following_count)
+SELECT account_address, sum(follower_count), sum(following_count) FROM (
+    SELECT followee_address AS account_address, count(*) AS follower_count, 0 AS following_count FROM follows GROUP BY followee_address
+    UNION ALL
+    SELECT follower_address, 0, count(*) FROM follows GROUP BY follower_address
+) counts
+GROUP BY account_address
+ON CONFLICT (account_address) DO NOTHING;
+
+-- account_restrictions table :- blocks and mutes between accounts. A block ends follows
+-- both ways and hides the two accounts from each other, a mute only hides the target
+-- from the actor
+CREATE TABLE IF NOT EXISTS account_restrictions(
+    actor_address VARCHAR(42) NOT NULL,
+    target_address VARCHAR(42) NOT NULL,
+    kind VARCHAR(8) NOT NULL CHECK (kind IN ('block', 'mute')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (actor_address, kind, target_address),
+    CHECK (actor_address <> target_address)
+);
+
+CREATE INDEX IF NOT EXISTS account_restrictions_target_idx ON account_restrictions(target_address, kind);
+CREATE INDEX IF NOT EXISTS account_restrictions_actor_idx ON account_restrictions(actor_address, kind, created_at DESC);
+
+-- following lists page through the follows of an account newest first
+CREATE INDEX IF NOT EXISTS follows_follower_idx ON follows(follower_address, created_at DESC);
+
+-- scheduled posts are published by the scheduler once publish_at is reached
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_status_check;
+ALTER TABLE posts ADD CONSTRAINT posts_status_check CHECK (status IN ('draft', 'scheduled', 'published'));
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS publish_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS posts_publish_at_idx ON posts(publish_at) WHERE status = 'scheduled';
+
+-- post_drips table :- posts unlocked for the members of a community delay_seconds after
+-- each of them joined. Authors only drip posts to communities they own
+CREATE TABLE IF NOT EXISTS post_drips(
+    post_id UUID PRIMARY KEY REFERENCES posts(id) ON DELETE CASCADE,
+    community_id UUID NOT NULL REFERENCES communities(id) ON DELETE CASCADE,
+    delay_seconds INT NOT NULL CHECK (delay_seconds >= 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS post_drips_community_idx ON post_drips(community_id);
+
+-- drip_releases table :- members told a dripped post unlocked for them, the scheduler
+-- notifies every member once
+CREATE TABLE IF NOT EXISTS drip_releases(
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    member_address VARCHAR(42) NOT NULL,
+    released_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (post_id, member_address)
+);
+
+ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_kind_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_kind_check CHECK (kind IN ('new_post', 'reply', 'mention', 'payment_received', 'membership_expiring', 'content_unlocked'));
+
+-- post_revisions table :- immutable snapshots of a post, one per save. The body is stored
+-- like the post's, sealed under the data key of the post when it is encrypted. status is
+-- the post's when saved, changed_fields, lines_added and lines_removed compare a revision
+-- to the one before it and restored_from is the revision a restore copied
+CREATE TABLE IF NOT EXISTS post_revisions(
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    number INT NOT NULL CHECK (number > 0),
+    author_address VARCHAR(42) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    title VARCHAR(200) NOT NULL,
+    teaser VARCHAR(300) NOT NULL DEFAULT '',
+    tags VARCHAR(32)[] NOT NULL DEFAULT '{}',
+    body TEXT NOT NULL DEFAULT '',
+    body_format VARCHAR(16) NOT NULL,
+    access_policy JSONB,
+    body_ciphertext BYTEA,
+    changed_fields TEXT[] NOT NULL DEFAULT '{}',
+    lines_added INT NOT NULL DEFAULT 0,
+    lines_removed INT NOT NULL DEFAULT 0,
+    restored_from INT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (post_id, number)
+);
+
+-- revisions saved while a post was not published are pruned once they expire
+CREATE INDEX IF NOT EXISTS post_revisions_draft_idx ON post_revisions(created_at) WHERE status <> 'published';
+
+-- existing posts start their history with their current content
+INSERT INTO post_revisions(post_id, number, author_address, status, title, teaser, tags, body, body_format, access_policy, body_ciphertext, created_at)
+SELECT id, 1, author_address, status, title, teaser, tags, body, body_format, access_policy, body_ciphertext, updated_at FROM posts
+ON CONFLICT (post_id, number) DO NOTHING;
+
+-- dripped posts are indexed without their body, like gated ones, so it can not be
+-- probed through search before it unlocks
+INSERT INTO post_search(post_id, search_vector)
+SELECT p.id, setweight(to_tsvector('english', p.title), 'A')
+    || setweight(to_tsvector('simple', array_to_string(p.tags, ' ')), 'B')
+    || setweight(to_tsvector('english', p.teaser), 'C')
+FROM posts p JOIN post_drips d ON d.post_id = p.id
+ON CONFLICT (post_id) DO UPDATE SET search_vector = EXCLUDED.search_vector;
@@ -0,0 +1,14 @@
+package dto
+
+import "encoding/json"
+
+type PolicyPreviewDTO struct {
+	AccessPolicy json.RawMessage `json:"access_policy" validate:"required"`
+	Block        uint64          `json:"block" validate:"required,gt=0"`
+}
+
+type PolicyPreviewResponseDTO struct {
+	Block     uint64   `json:"block"`
+	Total     int      `json:"total"`
+	Addresses []string `json:"addresses"`
+}
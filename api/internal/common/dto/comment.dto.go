@@ -0,0 +1,11 @@
+package dto
+
+type CommentDTO struct {
+	Body string `json:"body" validate:"required,max=5000"`
+	// ParentID is the comment replied to, empty for top level comments
+	ParentID string `json:"parent_id" validate:"omitempty,uuid"`
+}
+
+type UpdateCommentDTO struct {
+	Body string `json:"body" validate:"required,max=5000"`
+}
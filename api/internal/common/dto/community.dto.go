@@ -0,0 +1,40 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type CommunityDTO struct {
+	Slug         string             `json:"slug" validate:"required,min=3,max=40"`
+	Name         string             `json:"name" validate:"required,max=100"`
+	Description  string             `json:"description" validate:"max=2000"`
+	JoinPolicy   string             `json:"join_policy" validate:"required,oneof=open token_gated paid invite_only"`
+	AccessPolicy json.RawMessage    `json:"access_policy"`
+	Price        *CommunityPriceDTO `json:"price"`
+}
+
+type CommunityPriceDTO struct {
+	ChainID uint64 `json:"chain_id" validate:"required,gt=0"`
+	Token   string `json:"token" validate:"required,eth_addr"`
+	Amount  string `json:"amount" validate:"required,numeric"`
+}
+
+type JoinCommunityDTO struct {
+	PaymentTx string `json:"payment_tx" validate:"omitempty,len=66"`
+}
+
+type CommunityMemberDTO struct {
+	// Member is an address or @handle
+	Member string `json:"member" validate:"required,max=64"`
+}
+
+type CommunityRoleDTO struct {
+	Role string `json:"role" validate:"required,oneof=admin moderator member"`
+}
+
+type InviteDTO struct {
+	MaxUses      *int            `json:"max_uses" validate:"omitempty,gt=0"`
+	ExpiresAt    *time.Time      `json:"expires_at"`
+	AccessPolicy json.RawMessage `json:"access_policy"`
+}
@@ -0,0 +1,13 @@
+package dto
+
+type EmailDTO struct {
+	Email string `json:"email" validate:"required,email,max=254"`
+}
+
+type VerifyEmailDTO struct {
+	Token string `json:"token" validate:"required,max=64"`
+}
+
+type EmailDigestDTO struct {
+	Frequency string `json:"frequency" validate:"required,oneof=off daily weekly"`
+}
@@ -0,0 +1,6 @@
+package dto
+
+// FollowImportDTO lists the addresses a bulk import follows
+type FollowImportDTO struct {
+	Addresses []string `json:"addresses" validate:"required,min=1,max=1000"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+type ClaimHandleDTO struct {
+	Handle string `json:"handle" validate:"required,max=64"`
+}
+
+type HandleAvailabilityResponseDTO struct {
+	Handle    string `json:"handle"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"`
+}
@@ -0,0 +1,12 @@
+package dto
+
+type MarkNotificationsReadDTO struct {
+	// UpToID is the newest notification marked read, 0 marks every notification
+	UpToID int64 `json:"up_to_id" validate:"omitempty,min=1"`
+}
+
+type NotificationPreferencesDTO struct {
+	// Preferences maps channels to the kinds switched on or off, kinds left out keep
+	// their setting
+	Preferences map[string]map[string]bool `json:"preferences" validate:"required,min=1"`
+}
@@ -0,0 +1,32 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type PostDTO struct {
+	Title        string          `json:"title" validate:"required,max=200"`
+	Teaser       string          `json:"teaser" validate:"max=300"`
+	Tags         []string        `json:"tags" validate:"max=10,dive,min=1,max=32"`
+	Body         string          `json:"body"`
+	BodyFormat   string          `json:"body_format" validate:"omitempty,oneof=markdown richtext"`
+	AccessPolicy json.RawMessage `json:"access_policy"`
+}
+
+type PostAttachmentDTO struct {
+	Name        string `json:"name" validate:"required,max=255"`
+	URL         string `json:"url" validate:"required,url"`
+	ContentType string `json:"content_type" validate:"required,max=127"`
+	SizeBytes   int64  `json:"size_bytes" validate:"gte=0"`
+	Position    int    `json:"position" validate:"gte=0"`
+}
+
+type SchedulePostDTO struct {
+	PublishAt time.Time `json:"publish_at" validate:"required"`
+}
+
+type PostDripDTO struct {
+	CommunityID  string `json:"community_id" validate:"required,max=64"`
+	DelaySeconds int    `json:"delay_seconds" validate:"gte=0"`
+}
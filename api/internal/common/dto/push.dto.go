@@ -0,0 +1,12 @@
+package dto
+
+// PushSubscriptionDTO is the json of a browser PushSubscription
+type PushSubscriptionDTO struct {
+	Endpoint string               `json:"endpoint" validate:"required,url,max=2048"`
+	Keys     PushSubscriptionKeys `json:"keys" validate:"required"`
+}
+
+type PushSubscriptionKeys struct {
+	P256dh string `json:"p256dh" validate:"required,max=128"`
+	Auth   string `json:"auth" validate:"required,max=32"`
+}
@@ -16,6 +16,328 @@ type AccessToken struct {
 	RevokedAt  pgtype.Timestamp
 }
 
+type AccountEmail struct {
+	AccountAddress  string
+	Email           string
+	VerifiedAt      pgtype.Timestamp
+	VerifyTokenHash pgtype.Text
+	VerifyExpiresAt pgtype.Timestamp
+	Digest          string
+	DigestSentAt    pgtype.Timestamp
+	CreatedAt       pgtype.Timestamp
+	UpdatedAt       pgtype.Timestamp
+}
+
+type AccountRestriction struct {
+	ActorAddress  string
+	TargetAddress string
+	Kind          string
+	CreatedAt     pgtype.Timestamp
+}
+
+type ChatMessage struct {
+	ID            pgtype.UUID
+	CommunityID   pgtype.UUID
+	AuthorAddress string
+	Body          string
+	CreatedAt     pgtype.Timestamp
+}
+
+type ChatPresence struct {
+	SessionID     pgtype.UUID
+	CommunityID   pgtype.UUID
+	MemberAddress string
+	ConnectedAt   pgtype.Timestamp
+	SeenAt        pgtype.Timestamp
+}
+
+type CommentReaction struct {
+	CommentID      pgtype.UUID
+	ReactorAddress string
+	Emoji          string
+	CreatedAt      pgtype.Timestamp
+}
+
+type Community struct {
+	ID           pgtype.UUID
+	Slug         string
+	Name         string
+	Description  string
+	OwnerAddress string
+	JoinPolicy   string
+	AccessPolicy []byte
+	PriceChainID pgtype.Int8
+	PriceToken   pgtype.Text
+	PriceAmount  pgtype.Numeric
+	MemberCount  int32
+	CreatedAt    pgtype.Timestamp
+	UpdatedAt    pgtype.Timestamp
+}
+
+type CommunityInvite struct {
+	ID             pgtype.UUID
+	CommunityID    pgtype.UUID
+	Code           string
+	InviterAddress string
+	MaxUses        pgtype.Int4
+	Uses           int32
+	ExpiresAt      pgtype.Timestamp
+	AccessPolicy   []byte
+	Clicks         int32
+	SignIns        int32
+	RevokedAt      pgtype.Timestamp
+	CreatedAt      pgtype.Timestamp
+}
+
+type CommunityMember struct {
+	CommunityID   pgtype.UUID
+	MemberAddress string
+	Role          string
+	GrantedVia    string
+	GrantedPolicy []byte
+	GrantedBlocks []byte
+	PaymentTx     pgtype.Text
+	InvitedBy     pgtype.Text
+	JoinedAt      pgtype.Timestamp
+	InviteID      pgtype.UUID
+}
+
+type CommunitySearch struct {
+	CommunityID  pgtype.UUID
+	SearchVector interface{}
+}
+
+type DripRelease struct {
+	PostID        pgtype.UUID
+	MemberAddress string
+	ReleasedAt    pgtype.Timestamp
+}
+
+type EmailDelivery struct {
+	ID             int64
+	NotificationID int64
+	Attempts       int32
+	NextAttemptAt  pgtype.Timestamp
+	LastError      pgtype.Text
+	CreatedAt      pgtype.Timestamp
+}
+
+type Follow struct {
+	FollowerAddress string
+	FolloweeAddress string
+	CreatedAt       pgtype.Timestamp
+}
+
+type FollowCount struct {
+	AccountAddress string
+	FollowerCount  int32
+	FollowingCount int32
+}
+
+type Handle struct {
+	ID         pgtype.UUID
+	Handle     string
+	Skeleton   string
+	EthAddress string
+	CreatedAt  pgtype.Timestamp
+	RetiredAt  pgtype.Timestamp
+}
+
+type IndexedBlock struct {
+	ChainID     int64
+	BlockNumber int64
+	BlockHash   string
+}
+
+type IndexedContract struct {
+	ChainID      int64
+	Address      string
+	TokenKind    string
+	BackfilledTo int64
+	CreatedAt    pgtype.Timestamp
+}
+
+type IndexerCheckpoint struct {
+	ChainID     int64
+	BlockNumber int64
+	BlockHash   string
+	UpdatedAt   pgtype.Timestamp
+}
+
+type MediaFile struct {
+	ID                  pgtype.UUID
+	OwnerAddress        string
+	PostID              pgtype.UUID
+	StorageKey          string
+	Filename            string
+	ContentType         string
+	SizeBytes           int64
+	Sha256              string
+	CreatedAt           pgtype.Timestamp
+	DataKey             []byte
+	KeyID               pgtype.Text
+	ProcessingStatus    string
+	ProcessingAttempts  int32
+	ProcessingStartedAt pgtype.Timestamp
+	Width               pgtype.Int4
+	Height              pgtype.Int4
+	Blurhash            pgtype.Text
+}
+
+type MediaStream struct {
+	MediaID       pgtype.UUID
+	StoragePrefix string
+	SegmentKey    []byte
+	DurationMs    int64
+	CreatedAt     pgtype.Timestamp
+}
+
+type MediaStreamRendition struct {
+	MediaID   pgtype.UUID
+	Name      string
+	Width     int32
+	Height    int32
+	Bandwidth int32
+	Playlist  string
+}
+
+type MediaVariant struct {
+	MediaID     pgtype.UUID
+	Name        string
+	StorageKey  string
+	ContentType string
+	Width       int32
+	Height      int32
+	SizeBytes   int64
+	CreatedAt   pgtype.Timestamp
+}
+
+type Notification struct {
+	ID               int64
+	RecipientAddress string
+	Kind             string
+	ActorAddress     pgtype.Text
+	SubjectID        string
+	Data             []byte
+	GroupKey         pgtype.Text
+	ReadAt           pgtype.Timestamp
+	CreatedAt        pgtype.Timestamp
+}
+
+type NotificationPreference struct {
+	AccountAddress string
+	Channel        string
+	Kind           string
+	Enabled        bool
+	UpdatedAt      pgtype.Timestamp
+}
+
+type Post struct {
+	ID             pgtype.UUID
+	AuthorAddress  string
+	Title          string
+	Body           string
+	BodyFormat     string
+	Status         string
+	AccessPolicy   []byte
+	CreatedAt      pgtype.Timestamp
+	UpdatedAt      pgtype.Timestamp
+	PublishedAt    pgtype.Timestamp
+	BodyCiphertext []byte
+	DataKey        []byte
+	KeyID          pgtype.Text
+	Teaser         string
+	Tags           []string
+	PublishAt      pgtype.Timestamp
+}
+
+type PostAttachment struct {
+	ID             pgtype.UUID
+	PostID         pgtype.UUID
+	Name           string
+	Url            string
+	ContentType    string
+	SizeBytes      int64
+	Position       int32
+	CreatedAt      pgtype.Timestamp
+	NameCiphertext []byte
+	UrlCiphertext  []byte
+}
+
+type PostComment struct {
+	ID            pgtype.UUID
+	PostID        pgtype.UUID
+	ParentID      pgtype.UUID
+	Depth         int32
+	AuthorAddress string
+	Body          string
+	Mentions      []string
+	ReplyCount    int32
+	CreatedAt     pgtype.Timestamp
+	EditedAt      pgtype.Timestamp
+	DeletedAt     pgtype.Timestamp
+	DeletedBy     pgtype.Text
+}
+
+type PostDrip struct {
+	PostID       pgtype.UUID
+	CommunityID  pgtype.UUID
+	DelaySeconds int32
+	CreatedAt    pgtype.Timestamp
+}
+
+type PostReaction struct {
+	PostID         pgtype.UUID
+	ReactorAddress string
+	Emoji          string
+	CreatedAt      pgtype.Timestamp
+}
+
+type PostRevision struct {
+	PostID         pgtype.UUID
+	Number         int32
+	AuthorAddress  string
+	Status         string
+	Title          string
+	Teaser         string
+	Tags           []string
+	Body           string
+	BodyFormat     string
+	AccessPolicy   []byte
+	BodyCiphertext []byte
+	ChangedFields  []string
+	LinesAdded     int32
+	LinesRemoved   int32
+	RestoredFrom   pgtype.Int4
+	CreatedAt      pgtype.Timestamp
+}
+
+type PostSearch struct {
+	PostID       pgtype.UUID
+	SearchVector interface{}
+}
+
+type PushDelivery struct {
+	ID             int64
+	SubscriptionID pgtype.UUID
+	NotificationID int64
+	Attempts       int32
+	NextAttemptAt  pgtype.Timestamp
+	LastError      pgtype.Text
+	CreatedAt      pgtype.Timestamp
+}
+
+type PushSubscription struct {
+	ID             pgtype.UUID
+	AccountAddress string
+	Endpoint       string
+	P256dh         string
+	Auth           string
+	UserAgent      pgtype.Text
+	CreatedAt      pgtype.Timestamp
+	LastUsedAt     pgtype.Timestamp
+}
+
 type RefreshToken struct {
 	ID         pgtype.UUID
 	EthAddress string
@@ -41,3 +363,56 @@ type SiweNonce struct {
 	CreatedAt  pgtype.Timestamp
 	Used       pgtype.Bool
 }
+
+type TokenHolding struct {
+	ChainID      int64
+	Contract     string
+	TokenKind    string
+	TokenID      pgtype.Numeric
+	Holder       string
+	Balance      pgtype.Numeric
+	UpdatedBlock int64
+	UpdatedAt    pgtype.Timestamp
+}
+
+type TokenTransfer struct {
+	ID          int64
+	ChainID     int64
+	Contract    string
+	TokenKind   string
+	TokenID     pgtype.Numeric
+	FromAddress string
+	ToAddress   string
+	Amount      pgtype.Numeric
+	BlockNumber int64
+	BlockHash   string
+	TxHash      string
+	LogIndex    int32
+	BatchIndex  int32
+	CreatedAt   pgtype.Timestamp
+	BlockTime   pgtype.Timestamp
+}
+
+type Upload struct {
+	ID           pgtype.UUID
+	OwnerAddress string
+	UploadLength int64
+	UploadOffset int64
+	Metadata     string
+	Filename     string
+	PostID       pgtype.UUID
+	MediaID      pgtype.UUID
+	CompletedAt  pgtype.Timestamp
+	ExpiresAt    pgtype.Timestamp
+	CreatedAt    pgtype.Timestamp
+	UpdatedAt    pgtype.Timestamp
+	DataKey      []byte
+	KeyID        pgtype.Text
+}
+
+type UploadChunk struct {
+	UploadID    pgtype.UUID
+	ChunkOffset int64
+	StorageKey  string
+	SizeBytes   int64
+}
@@ -4,13 +4,28 @@ package container
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/Xebec19/jibe/api/internal/common/schema"
 	"github.com/Xebec19/jibe/api/internal/db"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
 	"github.com/Xebec19/jibe/api/internal/layers/repositories"
 	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/internal/templates"
+	"github.com/Xebec19/jibe/api/internal/utils"
+	"github.com/Xebec19/jibe/api/internal/workers"
+	"github.com/Xebec19/jibe/api/pkg/chain"
 	"github.com/Xebec19/jibe/api/pkg/config"
+	"github.com/Xebec19/jibe/api/pkg/envelope"
+	"github.com/Xebec19/jibe/api/pkg/eventbus"
+	"github.com/Xebec19/jibe/api/pkg/gating"
 	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/Xebec19/jibe/api/pkg/mailer"
+	"github.com/Xebec19/jibe/api/pkg/pgnotify"
+	"github.com/Xebec19/jibe/api/pkg/storage"
+	"github.com/Xebec19/jibe/api/pkg/transcode"
+	"github.com/Xebec19/jibe/api/pkg/webpush"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -25,6 +40,7 @@ func NewContainer(ctx context.Context, cfg *config.Config, logger logger.Logger,
 		Dbpool:    dbpool,
 		Queries:   q,
 		Validator: validator,
+		Events:    eventbus.New(1024),
 	}
 }
 
@@ -36,11 +52,219 @@ type Container struct {
 	Queries   *db.Queries
 	Validator schema.RequestValidator
 
+	// Events carries domain events from the services producing them to their consumers
+	Events *eventbus.Bus
+
+	// ChainReader reads balances and ownership from the configured chains
+	ChainReader chain.Reader
+
+	// Storage keeps uploaded media
+	Storage storage.Storage
+
+	// Keyring holds the master keys content is encrypted under, nil when none are set
+	Keyring *envelope.Keyring
+
+	// Transcoder packages videos into HLS, nil when packaging is disabled
+	Transcoder transcode.Transcoder
+
+	// Push sends browser push messages, nil when no VAPID keys are set
+	Push *webpush.Client
+
+	// Mailer sends email, nil when no SMTP server is set. EmailTemplates are the
+	// messages it sends
+	Mailer         mailer.Sender
+	EmailTemplates *mailer.Templates
+
 	// Repositories
-	AuthRepository repositories.AuthRepository
+	AuthRepository         repositories.AuthRepository
+	HandleRepository       repositories.HandleRepository
+	PostRepository         repositories.PostRepository
+	IndexerRepository      repositories.IndexerRepository
+	MediaRepository        repositories.MediaRepository
+	UploadRepository       repositories.UploadRepository
+	CommunityRepository    repositories.CommunityRepository
+	InviteRepository       repositories.InviteRepository
+	CommentRepository      repositories.CommentRepository
+	ChatRepository         repositories.ChatRepository
+	NotificationRepository repositories.NotificationRepository
+	PushRepository         repositories.PushRepository
+	EmailRepository        repositories.EmailRepository
+	SearchRepository       repositories.SearchRepository
+	FeedRepository         repositories.FeedRepository
+	FollowRepository       repositories.FollowRepository
 
 	// Services
-	AuthService services.AuthService
+	AuthService         services.AuthService
+	HandleService       services.HandleService
+	AccessService       services.AccessService
+	PostService         services.PostService
+	TokenHistory        services.TokenHistoryService
+	MediaService        services.MediaService
+	UploadService       services.UploadService
+	Encryption          services.EncryptionService
+	ImageService        services.ImageService
+	StreamService       services.StreamService
+	CommunityService    services.CommunityService
+	InviteService       services.InviteService
+	CommentService      services.CommentService
+	ChatService         services.ChatService
+	NotificationService services.NotificationService
+	PushService         services.PushService
+	EmailService        services.EmailService
+	SearchService       services.SearchService
+	FeedService         services.FeedService
+	FollowService       services.FollowService
+
+	// Workers
+	IndexerWorker workers.IndexerWorker
+
+	// Notifications delivers postgres notifications published by every replica
+	Notifications *pgnotify.Listener
+}
+
+// connect to the rpc endpoints of every configured chain
+func (c *Container) SetupChainReader() error {
+
+	reader, err := chain.Dial(c.Ctx, c.Cfg.ChainRPCURLs, chain.Options{
+		Timeout:   c.Cfg.ChainRPCTimeout,
+		CacheTTL:  c.Cfg.ChainCacheTTL,
+		BatchSize: c.Cfg.MulticallBatchSize,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.ChainReader = reader
+	return nil
+}
+
+// open the media storage backend
+func (c *Container) SetupStorage() error {
+
+	store, err := storage.New(c.Ctx, storage.Options{
+		Driver:      c.Cfg.StorageDriver,
+		LocalDir:    c.Cfg.StorageLocalDir,
+		S3Endpoint:  c.Cfg.S3Endpoint,
+		S3Region:    c.Cfg.S3Region,
+		S3Bucket:    c.Cfg.S3Bucket,
+		S3AccessKey: c.Cfg.S3AccessKey,
+		S3SecretKey: c.Cfg.S3SecretKey,
+		S3UseSSL:    c.Cfg.S3UseSSL,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Storage = store
+	return nil
+}
+
+// check the secret links are signed with. Development setups may sign them with the
+// JWT secret, production requires a secret of its own
+func (c *Container) SetupLinkSigning() error {
+
+	if c.Cfg.LinkSigningSecret != "" {
+		return nil
+	}
+
+	if utils.IsProductionEnv(c.Cfg.Env) {
+		return errors.New("LINK_SIGNING_SECRET must be set in production")
+	}
+
+	c.Logger.Warn("No link signing secret configured, links are signed with the JWT secret")
+	c.Cfg.LinkSigningSecret = c.Cfg.JwtSecret
+	return nil
+}
+
+// load the master keys of content encryption
+func (c *Container) SetupKeyring() error {
+
+	if len(c.Cfg.EncryptionKeys) == 0 {
+		c.Logger.Warn("No encryption keys configured, content is stored in plaintext")
+		return nil
+	}
+
+	keyring, err := envelope.NewKeyring(c.Cfg.EncryptionKeys, c.Cfg.EncryptionKeyID)
+	if err != nil {
+		return err
+	}
+
+	c.Keyring = keyring
+	return nil
+}
+
+// load the VAPID keys push messages are signed with, push is disabled without them
+func (c *Container) SetupPush() error {
+
+	if c.Cfg.VAPIDPublicKey == "" || c.Cfg.VAPIDPrivateKey == "" {
+		c.Logger.Warn("No VAPID keys configured, push notifications are disabled")
+		return nil
+	}
+
+	vapid, err := webpush.ParseVAPID(c.Cfg.VAPIDPublicKey, c.Cfg.VAPIDPrivateKey, c.Cfg.VAPIDSubject)
+	if err != nil {
+		return err
+	}
+
+	if c.Cfg.PushEndpoint != "" {
+		c.Logger.Warn("Push messages are sent to the configured endpoint instead of the browsers", "endpoint", c.Cfg.PushEndpoint)
+	}
+
+	c.Push = webpush.NewClient(vapid, webpush.ClientOptions{Endpoint: c.Cfg.PushEndpoint})
+	return nil
+}
+
+// parse the email templates and connect the mailer, email is disabled without an SMTP
+// server
+func (c *Container) SetupMailer() error {
+
+	emailTemplates, err := mailer.ParseTemplates(templates.Email, "email")
+	if err != nil {
+		return err
+	}
+	c.EmailTemplates = emailTemplates
+
+	if c.Cfg.SMTPHost == "" {
+		c.Logger.Warn("No SMTP server configured, email notifications are disabled")
+		return nil
+	}
+
+	sender, err := mailer.NewSMTP(mailer.Options{
+		Host:     c.Cfg.SMTPHost,
+		Port:     c.Cfg.SMTPPort,
+		Username: c.Cfg.SMTPUsername,
+		Password: c.Cfg.SMTPPassword,
+		From:     c.Cfg.SMTPFrom,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Mailer = sender
+	return nil
+}
+
+// pick the video transcoder, ffmpeg is used when it is installed and none is configured
+func (c *Container) SetupTranscoder() error {
+
+	driver := c.Cfg.Transcoder
+	if driver == "" {
+		driver = transcode.Detect()
+	}
+
+	transcoder, err := transcode.New(driver)
+	if err != nil {
+		return err
+	}
+
+	if transcoder == nil {
+		c.Logger.Warn("No video transcoder available, videos are not packaged for streaming")
+	}
+
+	// services read the resolved driver
+	c.Cfg.Transcoder = driver
+	c.Transcoder = transcoder
+	return nil
 }
 
 // initialize all repositories and save them in container
@@ -48,6 +272,51 @@ func (c *Container) SetupRepositories() {
 
 	authRepo := repositories.NewAuthRepository(c.Ctx, &c.Logger, c.Queries)
 	c.AuthRepository = authRepo
+
+	handleRepo := repositories.NewHandleRepository(c.Ctx, &c.Logger, c.Dbpool, c.Queries)
+	c.HandleRepository = handleRepo
+
+	postRepo := repositories.NewPostRepository(c.Ctx, &c.Logger, c.Dbpool, c.Queries)
+	c.PostRepository = postRepo
+
+	indexerRepo := repositories.NewIndexerRepository(c.Ctx, &c.Logger, c.Dbpool, c.Queries)
+	c.IndexerRepository = indexerRepo
+
+	mediaRepo := repositories.NewMediaRepository(c.Ctx, &c.Logger, c.Dbpool, c.Queries)
+	c.MediaRepository = mediaRepo
+
+	uploadRepo := repositories.NewUploadRepository(c.Ctx, &c.Logger, c.Dbpool, c.Queries)
+	c.UploadRepository = uploadRepo
+
+	communityRepo := repositories.NewCommunityRepository(c.Ctx, &c.Logger, c.Dbpool, c.Queries)
+	c.CommunityRepository = communityRepo
+
+	inviteRepo := repositories.NewInviteRepository(c.Ctx, &c.Logger, c.Queries)
+	c.InviteRepository = inviteRepo
+
+	commentRepo := repositories.NewCommentRepository(c.Ctx, &c.Logger, c.Dbpool, c.Queries)
+	c.CommentRepository = commentRepo
+
+	chatRepo := repositories.NewChatRepository(c.Ctx, &c.Logger, c.Queries)
+	c.ChatRepository = chatRepo
+
+	notificationRepo := repositories.NewNotificationRepository(c.Ctx, &c.Logger, c.Queries)
+	c.NotificationRepository = notificationRepo
+
+	pushRepo := repositories.NewPushRepository(c.Ctx, &c.Logger, c.Queries)
+	c.PushRepository = pushRepo
+
+	emailRepo := repositories.NewEmailRepository(c.Ctx, &c.Logger, c.Queries)
+	c.EmailRepository = emailRepo
+
+	searchRepo := repositories.NewSearchRepository(c.Ctx, &c.Logger, c.Queries)
+	c.SearchRepository = searchRepo
+
+	feedRepo := repositories.NewFeedRepository(c.Ctx, &c.Logger, c.Queries)
+	c.FeedRepository = feedRepo
+
+	followRepo := repositories.NewFollowRepository(c.Ctx, &c.Logger, c.Dbpool, c.Queries)
+	c.FollowRepository = followRepo
 }
 
 // initialize all services and save them in services
@@ -55,4 +324,158 @@ func (c *Container) SetupServices() {
 
 	authSvc := services.NewAuthService(c.Logger, &c.Cfg, c.AuthRepository)
 	c.AuthService = authSvc
+
+	handleSvc := services.NewHandleService(c.Logger, &c.Cfg, c.HandleRepository)
+	c.HandleService = handleSvc
+
+	tokenHistory := services.NewTokenHistoryService(c.Logger, c.IndexerRepository)
+	c.TokenHistory = tokenHistory
+
+	evaluator := gating.NewEvaluator(c.ChainReader, tokenHistory)
+	accessSvc := services.NewAccessService(c.Ctx, c.Logger, evaluator, gating.NewDecisionCache(c.Cfg.AccessCacheTTL))
+	c.AccessService = accessSvc
+
+	encryptionSvc := services.NewEncryptionService(c.Ctx, c.Logger, c.Keyring, c.Storage, c.PostRepository, c.MediaRepository, c.UploadRepository)
+	c.Encryption = encryptionSvc
+
+	postSvc := services.NewPostService(c.Logger, c.PostRepository, c.CommunityRepository, c.AccessService, c.Encryption, c.Events)
+	c.PostService = postSvc
+
+	mediaSvc := services.NewMediaService(c.Ctx, c.Logger, &c.Cfg, c.Storage, c.MediaRepository, c.PostRepository, c.PostService, c.Encryption)
+	c.MediaService = mediaSvc
+
+	imageSvc := services.NewImageService(c.Ctx, c.Logger, &c.Cfg, c.Storage, c.MediaRepository, c.Encryption)
+	c.ImageService = imageSvc
+
+	streamSvc := services.NewStreamService(c.Ctx, c.Logger, &c.Cfg, c.Storage, c.Transcoder, c.MediaRepository, c.MediaService, c.Encryption)
+	c.StreamService = streamSvc
+
+	uploadSvc := services.NewUploadService(c.Ctx, c.Logger, &c.Cfg, c.Storage, c.UploadRepository, c.MediaService, c.Encryption)
+	c.UploadService = uploadSvc
+
+	communitySvc := services.NewCommunityService(c.Ctx, c.Logger, &c.Cfg, c.CommunityRepository, c.IndexerRepository, c.AccessService, c.ChainReader, c.Events)
+	c.CommunityService = communitySvc
+
+	inviteSvc := services.NewInviteService(c.Logger, &c.Cfg, c.InviteRepository, c.CommunityRepository, c.HandleRepository, c.CommunityService, c.AccessService)
+	c.InviteService = inviteSvc
+
+	commentSvc := services.NewCommentService(c.Logger, c.CommentRepository, c.PostService, c.HandleService, c.Events)
+	c.CommentService = commentSvc
+
+	chatSvc := services.NewChatService(c.Logger, c.ChatRepository, c.CommunityService)
+	c.ChatService = chatSvc
+
+	notificationSvc := services.NewNotificationService(c.Logger, c.NotificationRepository, c.PostService, c.Events)
+	c.NotificationService = notificationSvc
+
+	pushSvc := services.NewPushService(c.Ctx, c.Logger, &c.Cfg, c.Push, c.PushRepository)
+	c.PushService = pushSvc
+
+	emailSvc := services.NewEmailService(c.Ctx, c.Logger, &c.Cfg, c.Mailer, c.EmailTemplates, c.EmailRepository, c.NotificationRepository)
+	c.EmailService = emailSvc
+
+	searchSvc := services.NewSearchService(c.Logger, c.SearchRepository, c.PostService)
+	c.SearchService = searchSvc
+
+	feedSvc := services.NewFeedService(c.Logger, &c.Cfg, c.FeedRepository, c.PostService)
+	c.FeedService = feedSvc
+
+	followSvc := services.NewFollowService(c.Logger, c.FollowRepository, c.HandleService)
+	c.FollowService = followSvc
+}
+
+// initialize background workers, they are started by the server
+func (c *Container) SetupWorkers() []workers.Worker {
+
+	indexer := workers.NewIndexerWorker(c.Logger, c.ChainReader, c.IndexerRepository, workers.IndexerOptions{
+		StartBlocks:  c.Cfg.IndexerStartBlocks,
+		PollInterval: c.Cfg.IndexerPollInterval,
+		BlockRange:   c.Cfg.IndexerBlockRange,
+	})
+	c.IndexerWorker = indexer
+
+	// holders who move a token lose their cached access right away
+	indexer.Subscribe(c.AccessService.InvalidateTransfers)
+
+	// senders may have dropped below the policy of a gated membership
+	indexer.Subscribe(func(chainID uint64, transfers []domain.TokenTransfer) {
+		senders := make([]string, 0, len(transfers))
+		for _, t := range transfers {
+			senders = append(senders, t.From)
+		}
+		c.Events.Publish(domain.TransfersIndexed{ChainID: chainID, Senders: senders})
+	})
+
+	eventbus.On(c.Events, c.CommunityService.CheckTransfers)
+	eventbus.On(c.Events, c.NotificationService.OnPostPublished)
+	eventbus.On(c.Events, c.NotificationService.OnCommentCreated)
+	eventbus.On(c.Events, c.NotificationService.OnPaymentReceived)
+	eventbus.On(c.Events, c.NotificationService.OnMembershipExpiring)
+	eventbus.On(c.Events, c.PushService.OnNotificationsCreated)
+	eventbus.On(c.Events, c.EmailService.OnNotificationsCreated)
+	c.Events.OnError(func(event eventbus.Event, err error) {
+		c.Logger.Warn("Event handling failed", "topic", event.Topic(), "error", err)
+	})
+
+	uploadCleanup := workers.NewUploadCleanupWorker(c.Logger, c.UploadService, 10*time.Minute)
+
+	// uploaded media is processed right away instead of on the next poll
+	images := workers.NewMediaWorker(c.Logger, "image processing", c.ImageService, workers.MediaWorkerOptions{
+		ContentTypes: domain.ProcessedImageTypes,
+		Batch:        10,
+		Interval:     30 * time.Second,
+	})
+	c.MediaService.Subscribe(images.Notify)
+
+	// chat events and new notifications reach the connections of every replica
+	// through postgres
+	notifications := pgnotify.NewListener(c.Dbpool)
+	notifications.Handle(domain.ChatNotifyChannel, c.ChatService.Dispatch)
+	notifications.Handle(domain.NotificationNotifyChannel, c.NotificationService.Dispatch)
+	notifications.OnReconnect(c.ChatService.Resync)
+	notifications.OnReconnect(c.NotificationService.Resync)
+	notifications.OnError(func(err error) {
+		c.Logger.Warn("Postgres notification listener failed", "error", err)
+	})
+	c.Notifications = notifications
+
+	chatPresence := workers.NewChatPresenceWorker(c.Logger, c.ChatService, services.ChatHeartbeatInterval)
+
+	scheduler := workers.NewSchedulerWorker(c.Logger, c.PostService, c.NotificationService, 50, 30*time.Second)
+
+	revisionCleanup := workers.NewRevisionCleanupWorker(c.Logger, c.PostService, c.Cfg.RevisionDraftRetention, time.Hour)
+
+	jobs := []workers.Worker{indexer, uploadCleanup, images, c.Events, notifications, chatPresence, scheduler, revisionCleanup}
+
+	if c.Transcoder != nil {
+		// transcoding is heavy, videos are packaged one at a time
+		videos := workers.NewMediaWorker(c.Logger, "video packaging", c.StreamService, workers.MediaWorkerOptions{
+			ContentTypes: domain.StreamedVideoTypes,
+			Batch:        1,
+			Interval:     time.Minute,
+		})
+		c.MediaService.Subscribe(videos.Notify)
+
+		jobs = append(jobs, videos)
+	}
+
+	if c.Push != nil {
+		// queued deliveries are sent right away instead of on the next poll
+		push := workers.NewPushWorker(c.Logger, c.PushService, 50, 15*time.Second)
+		c.PushService.OnEnqueued(push.Notify)
+
+		jobs = append(jobs, push)
+	}
+
+	if c.Mailer != nil {
+		// queued emails are sent right away instead of on the next poll
+		emails := workers.NewEmailWorker(c.Logger, c.EmailService, 20, 30*time.Second)
+		c.EmailService.OnEnqueued(emails.Notify)
+
+		digests := workers.NewDigestWorker(c.Logger, c.EmailService, 50, 15*time.Minute)
+
+		jobs = append(jobs, emails, digests)
+	}
+
+	return jobs
 }
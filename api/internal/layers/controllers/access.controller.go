@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/common/dto"
+	"github.com/Xebec19/jibe/api/internal/common/schema"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+)
+
+type AccessController interface {
+	// PreviewPolicy lists the addresses which satisfied an access policy at a past block
+	PreviewPolicy(w http.ResponseWriter, r *http.Request)
+}
+
+func NewAccessController(logger *logger.Logger, validator schema.RequestValidator, accessService services.AccessService) AccessController {
+	return accessController{
+		logger:        *logger,
+		validator:     validator,
+		accessService: accessService,
+	}
+}
+
+type accessController struct {
+	logger        logger.Logger
+	validator     schema.RequestValidator
+	accessService services.AccessService
+}
+
+func (a accessController) PreviewPolicy(w http.ResponseWriter, r *http.Request) {
+
+	var req dto.PolicyPreviewDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.logger.Error("request body parsing failed for policy preview", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	if err := a.validator.Validate(req); err != nil {
+		a.logger.Error("invalid req body for policy preview", "error", a.validator.FormatErrors(err))
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	addresses, err := a.accessService.Preview(req.AccessPolicy, req.Block)
+	if err != nil {
+		a.respondAccessError(w, err, "policy preview failed")
+		return
+	}
+
+	limit, offset := parsePagination(r)
+	page := addresses[min(offset, len(addresses)):min(offset+limit, len(addresses))]
+
+	respondJSON(w, http.StatusOK, "policy preview", dto.PolicyPreviewResponseDTO{
+		Block:     req.Block,
+		Total:     len(addresses),
+		Addresses: page,
+	})
+}
+
+func (a accessController) respondAccessError(w http.ResponseWriter, err error, msg string) {
+
+	switch {
+	case errors.Is(err, domain.ErrInvalidPolicy), errors.Is(err, domain.ErrPreviewUnsupported):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrHistoryUnavailable):
+		respondError(w, http.StatusConflict, domain.ErrHistoryUnavailable.Error())
+	default:
+		a.logger.Error(msg, "error", err)
+		respondError(w, http.StatusInternalServerError, SOMETHING_WENT_WRONG_MSG)
+	}
+}
@@ -17,24 +17,27 @@ import (
 type AuthController interface {
 	// GenerateNonce returns a random nonce and also save it in db with expiry
 	GenerateNonce(w http.ResponseWriter, r *http.Request)
-	// VerifyHandler verifies the message and signature generated during SIWE
+	// VerifyHandler verifies the message and signature generated during SIWE. An invite
+	// token sent along is redeemed for the account which signed in
 	VerifyHandler(w http.ResponseWriter, r *http.Request)
 }
 
-func NewAuthController(logger *logger.Logger, cfg *config.Config, validator schema.RequestValidator, authService services.AuthService) AuthController {
+func NewAuthController(logger *logger.Logger, cfg *config.Config, validator schema.RequestValidator, authService services.AuthService, inviteService services.InviteService) AuthController {
 	return authController{
-		logger:      *logger,
-		validator:   validator,
-		cfg:         cfg,
-		authService: authService,
+		logger:        *logger,
+		validator:     validator,
+		cfg:           cfg,
+		authService:   authService,
+		inviteService: inviteService,
 	}
 }
 
 type authController struct {
-	logger      logger.Logger
-	validator   schema.RequestValidator
-	authService services.AuthService
-	cfg         *config.Config
+	logger        logger.Logger
+	validator     schema.RequestValidator
+	authService   services.AuthService
+	inviteService services.InviteService
+	cfg           *config.Config
 }
 
 func (a authController) GenerateNonce(w http.ResponseWriter, r *http.Request) {
@@ -134,7 +137,14 @@ func (a authController) VerifyHandler(w http.ResponseWriter, r *http.Request) {
 		MaxAge:   a.cfg.RefreshTokenExpiry,
 	})
 
-	respondJSON(w, http.StatusOK, "Message is verified", domain.VerifyResponse{
+	res := domain.VerifyResponse{
 		Valid: true,
-	})
+	}
+
+	if req.Invite != "" {
+		redemption := a.inviteService.RedeemOnSignIn(domain.Viewer{Address: addr}, req.Invite)
+		res.Invite = &redemption
+	}
+
+	respondJSON(w, http.StatusOK, "Message is verified", res)
 }
@@ -0,0 +1,221 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/pkg/config"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// chatWriteWait bounds a single write to the client
+	chatWriteWait = 10 * time.Second
+	// chatPongWait is how long a client may stay silent, pings go out well before it
+	chatPongWait   = 60 * time.Second
+	chatPingPeriod = chatPongWait * 9 / 10
+	// chatMaxFrame caps commands read from the client
+	chatMaxFrame = 16 * 1024
+)
+
+type ChatController interface {
+	// Connect upgrades to a websocket carrying the chat of a community. Clients send
+	// message and typing commands and receive the events of the channel
+	Connect(w http.ResponseWriter, r *http.Request)
+	// History returns past messages of a community, newest first, paginated by the
+	// cursor query param
+	History(w http.ResponseWriter, r *http.Request)
+}
+
+func NewChatController(logger *logger.Logger, cfg *config.Config, chatService services.ChatService) ChatController {
+
+	c := chatController{
+		logger:      *logger,
+		cfg:         cfg,
+		chatService: chatService,
+	}
+
+	c.upgrader = &websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     c.checkOrigin,
+	}
+
+	return c
+}
+
+type chatController struct {
+	logger      logger.Logger
+	cfg         *config.Config
+	chatService services.ChatService
+	upgrader    *websocket.Upgrader
+}
+
+func (c chatController) Connect(w http.ResponseWriter, r *http.Request) {
+
+	// membership is checked before the upgrade so failures are plain http errors
+	session, err := c.chatService.Connect(viewerFrom(r), mux.Vars(r)["id"])
+	if err != nil {
+		c.respondChatError(w, err, "chat connection failed")
+		return
+	}
+
+	conn, err := c.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader already replied with an error
+		c.chatService.Disconnect(session)
+		return
+	}
+
+	go c.readCommands(conn, session)
+	c.writeEvents(conn, session)
+}
+
+func (c chatController) History(w http.ResponseWriter, r *http.Request) {
+
+	limit, cursor := parseCursorPagination(r)
+
+	page, err := c.chatService.History(viewerFrom(r), mux.Vars(r)["id"], cursor, limit)
+	if err != nil {
+		c.respondChatError(w, err, "chat history failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "messages found", page)
+}
+
+// readCommands runs the commands of the client until the connection closes, which
+// ends the session
+func (c chatController) readCommands(conn *websocket.Conn, session *services.ChatSession) {
+
+	defer c.chatService.Disconnect(session)
+
+	conn.SetReadLimit(chatMaxFrame)
+	conn.SetReadDeadline(time.Now().Add(chatPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(chatPongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		// malformed commands are reported by the service as unknown ones
+		var command domain.ChatCommand
+		json.Unmarshal(data, &command)
+
+		if err := c.chatService.Handle(session, command); err != nil {
+			c.logger.Error("chat command failed", "session", session.ID, "error", err)
+		}
+	}
+}
+
+// writeEvents is the only writer of the connection. It relays the events of the
+// session, keeps the connection alive and rechecks the membership behind it
+func (c chatController) writeEvents(conn *websocket.Conn, session *services.ChatSession) {
+
+	ping := time.NewTicker(chatPingPeriod)
+	recheck := time.NewTicker(c.cfg.ChatRecheckInterval)
+
+	defer func() {
+		ping.Stop()
+		recheck.Stop()
+		conn.Close()
+	}()
+
+	for {
+		select {
+		case event := <-session.Events():
+			conn.SetWriteDeadline(time.Now().Add(chatWriteWait))
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+
+		case <-session.Done():
+			c.close(conn, session)
+			return
+
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(chatWriteWait)); err != nil {
+				return
+			}
+
+		case <-recheck.C:
+			if err := c.chatService.Recheck(session); err != nil {
+				c.logger.Warn("chat membership recheck failed", "session", session.ID, "error", err)
+			}
+		}
+	}
+}
+
+// close tells the client why its session ended
+func (c chatController) close(conn *websocket.Conn, session *services.ChatSession) {
+
+	err := session.Err()
+
+	code := websocket.CloseNormalClosure
+	switch {
+	case errors.Is(err, domain.ErrChatTooSlow):
+		code = websocket.CloseTryAgainLater
+	case errors.Is(err, domain.ErrMembershipRevoked), errors.Is(err, domain.ErrCommunityForbidden),
+		errors.Is(err, domain.ErrCommunityNotFound):
+		code = websocket.ClosePolicyViolation
+	}
+
+	reason := ""
+	if err != nil {
+		reason = err.Error()
+	}
+
+	deadline := time.Now().Add(chatWriteWait)
+
+	conn.SetWriteDeadline(deadline)
+	conn.WriteJSON(domain.ChatEvent{Type: domain.ChatEventClosed, CommunityID: session.CommunityID, Reason: reason})
+
+	// close frames carry at most 123 bytes of reason
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+}
+
+// checkOrigin accepts browsers on the configured domain or the host serving the api,
+// the access token cookie would otherwise be usable from any site
+func (c chatController) checkOrigin(r *http.Request) bool {
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return u.Host == r.Host || (c.cfg.Domain != "" && (u.Host == c.cfg.Domain || u.Hostname() == c.cfg.Domain))
+}
+
+func (c chatController) respondChatError(w http.ResponseWriter, err error, msg string) {
+
+	switch {
+	case errors.Is(err, domain.ErrCursorInvalid):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrCommunityForbidden), errors.Is(err, domain.ErrMembershipRevoked):
+		respondError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, domain.ErrCommunityNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+	default:
+		c.logger.Error(msg, "error", err)
+		respondError(w, http.StatusInternalServerError, SOMETHING_WENT_WRONG_MSG)
+	}
+}
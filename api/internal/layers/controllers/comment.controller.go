@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/common/dto"
+	"github.com/Xebec19/jibe/api/internal/common/schema"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/gorilla/mux"
+)
+
+type CommentController interface {
+	// ListComments returns top level comments of a post, paginated by the cursor query param
+	ListComments(w http.ResponseWriter, r *http.Request)
+	// ListReplies returns direct replies to a comment, paginated by the cursor query param
+	ListReplies(w http.ResponseWriter, r *http.Request)
+	// CreateComment comments on a post or replies to a comment of it
+	CreateComment(w http.ResponseWriter, r *http.Request)
+	UpdateComment(w http.ResponseWriter, r *http.Request)
+	// DeleteComment soft deletes a comment, for its author and the author of the post
+	DeleteComment(w http.ResponseWriter, r *http.Request)
+	// RestoreComment undoes the moderation of a comment
+	RestoreComment(w http.ResponseWriter, r *http.Request)
+	ListPostReactions(w http.ResponseWriter, r *http.Request)
+	// AddPostReaction and RemovePostReaction toggle the emoji in the path on a post
+	AddPostReaction(w http.ResponseWriter, r *http.Request)
+	RemovePostReaction(w http.ResponseWriter, r *http.Request)
+	// AddCommentReaction and RemoveCommentReaction toggle the emoji in the path on a comment
+	AddCommentReaction(w http.ResponseWriter, r *http.Request)
+	RemoveCommentReaction(w http.ResponseWriter, r *http.Request)
+}
+
+func NewCommentController(logger *logger.Logger, validator schema.RequestValidator, commentService services.CommentService) CommentController {
+	return commentController{
+		logger:         *logger,
+		validator:      validator,
+		commentService: commentService,
+	}
+}
+
+type commentController struct {
+	logger         logger.Logger
+	validator      schema.RequestValidator
+	commentService services.CommentService
+}
+
+func (c commentController) ListComments(w http.ResponseWriter, r *http.Request) {
+
+	limit, cursor := parseCursorPagination(r)
+
+	page, err := c.commentService.ListComments(viewerFrom(r), mux.Vars(r)["id"], cursor, limit)
+	if err != nil {
+		c.respondCommentError(w, err, "comment listing failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "comments found", page)
+}
+
+func (c commentController) ListReplies(w http.ResponseWriter, r *http.Request) {
+
+	limit, cursor := parseCursorPagination(r)
+
+	page, err := c.commentService.ListReplies(viewerFrom(r), mux.Vars(r)["id"], cursor, limit)
+	if err != nil {
+		c.respondCommentError(w, err, "reply listing failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "replies found", page)
+}
+
+func (c commentController) CreateComment(w http.ResponseWriter, r *http.Request) {
+
+	var req dto.CommentDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		c.logger.Error("request body parsing failed for creating comment", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	if err := c.validator.Validate(req); err != nil {
+		c.logger.Error("invalid req body for creating comment", "error", c.validator.FormatErrors(err))
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	comment, err := c.commentService.CreateComment(viewerFrom(r), mux.Vars(r)["id"], req.ParentID, req.Body)
+	if err != nil {
+		c.respondCommentError(w, err, "comment creation failed")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, RESOURCE_CREATED_MSG, comment)
+}
+
+func (c commentController) UpdateComment(w http.ResponseWriter, r *http.Request) {
+
+	var req dto.UpdateCommentDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		c.logger.Error("request body parsing failed for updating comment", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	if err := c.validator.Validate(req); err != nil {
+		c.logger.Error("invalid req body for updating comment", "error", c.validator.FormatErrors(err))
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	comment, err := c.commentService.UpdateComment(viewerFrom(r), mux.Vars(r)["id"], req.Body)
+	if err != nil {
+		c.respondCommentError(w, err, "comment update failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "comment updated", comment)
+}
+
+func (c commentController) DeleteComment(w http.ResponseWriter, r *http.Request) {
+
+	comment, err := c.commentService.DeleteComment(viewerFrom(r), mux.Vars(r)["id"])
+	if err != nil {
+		c.respondCommentError(w, err, "comment deletion failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "comment deleted", comment)
+}
+
+func (c commentController) RestoreComment(w http.ResponseWriter, r *http.Request) {
+
+	comment, err := c.commentService.RestoreComment(viewerFrom(r), mux.Vars(r)["id"])
+	if err != nil {
+		c.respondCommentError(w, err, "comment restore failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "comment restored", comment)
+}
+
+func (c commentController) ListPostReactions(w http.ResponseWriter, r *http.Request) {
+
+	reactions, err := c.commentService.ListPostReactions(viewerFrom(r), mux.Vars(r)["id"])
+	if err != nil {
+		c.respondCommentError(w, err, "reaction listing failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "reactions found", reactions)
+}
+
+func (c commentController) AddPostReaction(w http.ResponseWriter, r *http.Request) {
+	c.reactToPost(w, r, false)
+}
+
+func (c commentController) RemovePostReaction(w http.ResponseWriter, r *http.Request) {
+	c.reactToPost(w, r, true)
+}
+
+func (c commentController) AddCommentReaction(w http.ResponseWriter, r *http.Request) {
+	c.reactToComment(w, r, false)
+}
+
+func (c commentController) RemoveCommentReaction(w http.ResponseWriter, r *http.Request) {
+	c.reactToComment(w, r, true)
+}
+
+func (c commentController) reactToPost(w http.ResponseWriter, r *http.Request, remove bool) {
+
+	vars := mux.Vars(r)
+
+	reactions, err := c.commentService.ReactToPost(viewerFrom(r), vars["id"], vars["emoji"], remove)
+	if err != nil {
+		c.respondCommentError(w, err, "post reaction failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "reactions updated", reactions)
+}
+
+func (c commentController) reactToComment(w http.ResponseWriter, r *http.Request, remove bool) {
+
+	vars := mux.Vars(r)
+
+	reactions, err := c.commentService.ReactToComment(viewerFrom(r), vars["id"], vars["emoji"], remove)
+	if err != nil {
+		c.respondCommentError(w, err, "comment reaction failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "reactions updated", reactions)
+}
+
+func (c commentController) respondCommentError(w http.ResponseWriter, err error, msg string) {
+
+	switch {
+	case errors.Is(err, domain.ErrCommentInvalid), errors.Is(err, domain.ErrReactionInvalid),
+		errors.Is(err, domain.ErrCursorInvalid):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrPostLocked), errors.Is(err, domain.ErrCommentForbidden):
+		respondError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, domain.ErrPostNotFound), errors.Is(err, domain.ErrCommentNotFound),
+		errors.Is(err, domain.ErrReactionNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrCommentDeleted), errors.Is(err, domain.ErrReactionLimit):
+		respondError(w, http.StatusConflict, err.Error())
+	default:
+		c.logger.Error(msg, "error", err)
+		respondError(w, http.StatusInternalServerError, SOMETHING_WENT_WRONG_MSG)
+	}
+}
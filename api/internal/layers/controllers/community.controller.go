@@ -0,0 +1,310 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/common/dto"
+	"github.com/Xebec19/jibe/api/internal/common/schema"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/internal/middleware"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/gorilla/mux"
+)
+
+type CommunityController interface {
+	// CreateCommunity creates a community owned by the caller
+	CreateCommunity(w http.ResponseWriter, r *http.Request)
+	// UpdateCommunity replaces name, description and join policy of a community
+	UpdateCommunity(w http.ResponseWriter, r *http.Request)
+	DeleteCommunity(w http.ResponseWriter, r *http.Request)
+	// GetCommunity returns a community by id or slug along with the caller's membership
+	GetCommunity(w http.ResponseWriter, r *http.Request)
+	// ListCommunities lists the communities the caller belongs to
+	ListCommunities(w http.ResponseWriter, r *http.Request)
+	// Join makes the caller a member if they satisfy the join policy
+	Join(w http.ResponseWriter, r *http.Request)
+	Leave(w http.ResponseWriter, r *http.Request)
+	// ListMembers is the member directory, filtered by the role query param
+	ListMembers(w http.ResponseWriter, r *http.Request)
+	// AddMember adds an account given by address or handle, for admins
+	AddMember(w http.ResponseWriter, r *http.Request)
+	// SetRole changes the role of a member
+	SetRole(w http.ResponseWriter, r *http.Request)
+	RemoveMember(w http.ResponseWriter, r *http.Request)
+}
+
+func NewCommunityController(logger *logger.Logger, validator schema.RequestValidator, communityService services.CommunityService, handleService services.HandleService) CommunityController {
+	return communityController{
+		logger:           *logger,
+		validator:        validator,
+		communityService: communityService,
+		handleService:    handleService,
+	}
+}
+
+type communityController struct {
+	logger           logger.Logger
+	validator        schema.RequestValidator
+	communityService services.CommunityService
+	handleService    services.HandleService
+}
+
+func (c communityController) CreateCommunity(w http.ResponseWriter, r *http.Request) {
+
+	input, ok := c.decodeCommunityInput(w, r)
+	if !ok {
+		return
+	}
+
+	community, err := c.communityService.CreateCommunity(middleware.GetEthAddress(r.Context()), input)
+	if err != nil {
+		c.respondCommunityError(w, err, "community creation failed")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, RESOURCE_CREATED_MSG, community)
+}
+
+func (c communityController) UpdateCommunity(w http.ResponseWriter, r *http.Request) {
+
+	input, ok := c.decodeCommunityInput(w, r)
+	if !ok {
+		return
+	}
+
+	community, err := c.communityService.UpdateCommunity(middleware.GetEthAddress(r.Context()), mux.Vars(r)["id"], input)
+	if err != nil {
+		c.respondCommunityError(w, err, "community update failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "community updated", community)
+}
+
+func (c communityController) DeleteCommunity(w http.ResponseWriter, r *http.Request) {
+
+	err := c.communityService.DeleteCommunity(middleware.GetEthAddress(r.Context()), mux.Vars(r)["id"])
+	if err != nil {
+		c.respondCommunityError(w, err, "community deletion failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "community deleted", nil)
+}
+
+func (c communityController) GetCommunity(w http.ResponseWriter, r *http.Request) {
+
+	community, err := c.communityService.GetCommunity(viewerFrom(r), mux.Vars(r)["id"])
+	if err != nil {
+		c.respondCommunityError(w, err, "community lookup failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "community found", community)
+}
+
+func (c communityController) ListCommunities(w http.ResponseWriter, r *http.Request) {
+
+	limit, offset := parsePagination(r)
+
+	communities, err := c.communityService.ListJoined(middleware.GetEthAddress(r.Context()), limit, offset)
+	if err != nil {
+		c.respondCommunityError(w, err, "community listing failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "communities found", communities)
+}
+
+func (c communityController) Join(w http.ResponseWriter, r *http.Request) {
+
+	// the body is optional, only paid communities need one
+	var req dto.JoinCommunityDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.logger.Error("request body parsing failed for joining community", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	if err := c.validator.Validate(req); err != nil {
+		c.logger.Error("invalid req body for joining community", "error", c.validator.FormatErrors(err))
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	member, err := c.communityService.Join(viewerFrom(r), mux.Vars(r)["id"], req.PaymentTx)
+	if err != nil {
+		c.respondCommunityError(w, err, "community join failed")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, "community joined", member)
+}
+
+func (c communityController) Leave(w http.ResponseWriter, r *http.Request) {
+
+	err := c.communityService.Leave(middleware.GetEthAddress(r.Context()), mux.Vars(r)["id"])
+	if err != nil {
+		c.respondCommunityError(w, err, "community leave failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "community left", nil)
+}
+
+func (c communityController) ListMembers(w http.ResponseWriter, r *http.Request) {
+
+	limit, offset := parsePagination(r)
+
+	members, err := c.communityService.ListMembers(viewerFrom(r), mux.Vars(r)["id"], r.URL.Query().Get("role"), limit, offset)
+	if err != nil {
+		c.respondCommunityError(w, err, "member listing failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "members found", members)
+}
+
+func (c communityController) AddMember(w http.ResponseWriter, r *http.Request) {
+
+	var req dto.CommunityMemberDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		c.logger.Error("request body parsing failed for adding member", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	if err := c.validator.Validate(req); err != nil {
+		c.logger.Error("invalid req body for adding member", "error", c.validator.FormatErrors(err))
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	addr, err := c.handleService.ResolveAddress(req.Member)
+	if err != nil {
+		c.respondCommunityError(w, err, "member resolution failed")
+		return
+	}
+
+	member, err := c.communityService.AddMember(middleware.GetEthAddress(r.Context()), mux.Vars(r)["id"], addr)
+	if err != nil {
+		c.respondCommunityError(w, err, "member creation failed")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, RESOURCE_CREATED_MSG, member)
+}
+
+func (c communityController) SetRole(w http.ResponseWriter, r *http.Request) {
+
+	var req dto.CommunityRoleDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		c.logger.Error("request body parsing failed for setting role", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	if err := c.validator.Validate(req); err != nil {
+		c.logger.Error("invalid req body for setting role", "error", c.validator.FormatErrors(err))
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	addr, err := c.handleService.ResolveAddress(vars["member"])
+	if err != nil {
+		c.respondCommunityError(w, err, "member resolution failed")
+		return
+	}
+
+	member, err := c.communityService.SetRole(middleware.GetEthAddress(r.Context()), vars["id"], addr, req.Role)
+	if err != nil {
+		c.respondCommunityError(w, err, "role update failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "role updated", member)
+}
+
+func (c communityController) RemoveMember(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+
+	addr, err := c.handleService.ResolveAddress(vars["member"])
+	if err != nil {
+		c.respondCommunityError(w, err, "member resolution failed")
+		return
+	}
+
+	err = c.communityService.RemoveMember(middleware.GetEthAddress(r.Context()), vars["id"], addr)
+	if err != nil {
+		c.respondCommunityError(w, err, "member removal failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "member removed", nil)
+}
+
+func (c communityController) decodeCommunityInput(w http.ResponseWriter, r *http.Request) (domain.CommunityInput, bool) {
+
+	var req dto.CommunityDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		c.logger.Error("request body parsing failed for community", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return domain.CommunityInput{}, false
+	}
+
+	if err := c.validator.Validate(req); err != nil {
+		c.logger.Error("invalid req body for community", "error", c.validator.FormatErrors(err))
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return domain.CommunityInput{}, false
+	}
+
+	input := domain.CommunityInput{
+		Slug:         req.Slug,
+		Name:         req.Name,
+		Description:  req.Description,
+		JoinPolicy:   req.JoinPolicy,
+		AccessPolicy: req.AccessPolicy,
+	}
+
+	if req.Price != nil {
+		input.Price = &domain.CommunityPrice{
+			ChainID: req.Price.ChainID,
+			Token:   req.Price.Token,
+			Amount:  req.Price.Amount,
+		}
+	}
+
+	return input, true
+}
+
+func (c communityController) respondCommunityError(w http.ResponseWriter, err error, msg string) {
+
+	switch {
+	case errors.Is(err, domain.ErrCommunityInvalid), errors.Is(err, domain.ErrInvalidPolicy),
+		errors.Is(err, domain.ErrPaymentInvalid):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrCommunityForbidden), errors.Is(err, domain.ErrJoinDenied),
+		errors.Is(err, domain.ErrInviteOnly):
+		respondError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, domain.ErrCommunityNotFound), errors.Is(err, domain.ErrMemberNotFound),
+		errors.Is(err, domain.ErrHandleNotFound), errors.Is(err, domain.ErrPaymentNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrCommunitySlugTaken), errors.Is(err, domain.ErrAlreadyMember),
+		errors.Is(err, domain.ErrOwnerCannotLeave):
+		respondError(w, http.StatusConflict, err.Error())
+	default:
+		c.logger.Error(msg, "error", err)
+		respondError(w, http.StatusInternalServerError, SOMETHING_WENT_WRONG_MSG)
+	}
+}
@@ -1,8 +1,12 @@
 package controllers
 
 import (
-	"encoding/json"
 	"net/http"
+	"strconv"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/middleware"
+	"github.com/Xebec19/jibe/api/internal/response"
 )
 
 const (
@@ -12,37 +16,53 @@ const (
 	// error messages
 	INVALID_REQUEST_MSG      string = "invalid request"
 	SOMETHING_WENT_WRONG_MSG string = "something went wrong"
+
+	// pagination
+	DEFAULT_PAGE_LIMIT int = 20
+	MAX_PAGE_LIMIT     int = 100
 )
 
-type Response struct {
-	Status  bool
-	Message string
-	Data    interface{}
+func respondJSON(w http.ResponseWriter, status int, msg string, payload interface{}) {
+	response.JSON(w, status, msg, payload)
 }
 
-func respondJSON(w http.ResponseWriter, status int, msg string, payload interface{}) {
+func respondError(w http.ResponseWriter, status int, msg string) {
+	response.Error(w, status, msg)
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
+// parsePagination reads limit and offset query params, falling back to defaults
+// for missing or invalid values
+func parsePagination(r *http.Request) (int, int) {
 
-	data := &Response{
-		Status:  true,
-		Message: msg,
-		Data:    payload,
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = DEFAULT_PAGE_LIMIT
+	}
+	if limit > MAX_PAGE_LIMIT {
+		limit = MAX_PAGE_LIMIT
 	}
 
-	json.NewEncoder(w).Encode(data)
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	return limit, offset
 }
 
-func respondError(w http.ResponseWriter, status int, msg string) {
+// parseCursorPagination reads the limit and cursor query params of lists paginated by
+// cursor, the cursor is opaque and checked by the service
+func parseCursorPagination(r *http.Request) (int, string) {
 
-	w.WriteHeader(status)
+	limit, _ := parsePagination(r)
 
-	data := &Response{
-		Status:  false,
-		Message: msg,
-		Data:    nil,
-	}
+	return limit, r.URL.Query().Get("cursor")
+}
 
-	json.NewEncoder(w).Encode(data)
+// viewerFrom returns the reader of the request access policies are evaluated for
+func viewerFrom(r *http.Request) domain.Viewer {
+	return domain.Viewer{
+		Address:     middleware.GetEthAddress(r.Context()),
+		BypassCache: middleware.BypassAccessCache(r.Context()),
+	}
 }
@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/common/dto"
+	"github.com/Xebec19/jibe/api/internal/common/schema"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+)
+
+type EmailController interface {
+	GetEmail(w http.ResponseWriter, r *http.Request)
+	// SetEmail stores the viewer's address and mails it a verification link
+	SetEmail(w http.ResponseWriter, r *http.Request)
+	RemoveEmail(w http.ResponseWriter, r *http.Request)
+	// VerifyEmail confirms an address with the token of its verification link
+	VerifyEmail(w http.ResponseWriter, r *http.Request)
+	SetDigest(w http.ResponseWriter, r *http.Request)
+	// Unsubscribe is the one-click unsubscribe of RFC 8058, the token comes in the query
+	Unsubscribe(w http.ResponseWriter, r *http.Request)
+}
+
+func NewEmailController(logger *logger.Logger, validator schema.RequestValidator, emailService services.EmailService) EmailController {
+	return emailController{
+		logger:       *logger,
+		validator:    validator,
+		emailService: emailService,
+	}
+}
+
+type emailController struct {
+	logger       logger.Logger
+	validator    schema.RequestValidator
+	emailService services.EmailService
+}
+
+func (c emailController) GetEmail(w http.ResponseWriter, r *http.Request) {
+
+	email, err := c.emailService.GetEmail(viewerFrom(r).Address)
+	if err != nil {
+		c.respondEmailError(w, err, "email lookup failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "email found", email)
+}
+
+func (c emailController) SetEmail(w http.ResponseWriter, r *http.Request) {
+
+	var req dto.EmailDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		c.logger.Error("request body parsing failed for setting email", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	if err := c.validator.Validate(req); err != nil {
+		c.logger.Error("invalid req body for setting email", "error", c.validator.FormatErrors(err))
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	email, err := c.emailService.SetEmail(viewerFrom(r).Address, req.Email)
+	if err != nil {
+		c.respondEmailError(w, err, "setting email failed")
+		return
+	}
+
+	if email.IsVerified() {
+		respondJSON(w, http.StatusOK, "email already verified", email)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "verification email sent", email)
+}
+
+func (c emailController) RemoveEmail(w http.ResponseWriter, r *http.Request) {
+
+	if err := c.emailService.RemoveEmail(viewerFrom(r).Address); err != nil {
+		c.respondEmailError(w, err, "email removal failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c emailController) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+
+	var req dto.VerifyEmailDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		c.logger.Error("request body parsing failed for verifying email", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	if err := c.validator.Validate(req); err != nil {
+		c.logger.Error("invalid req body for verifying email", "error", c.validator.FormatErrors(err))
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	email, err := c.emailService.VerifyEmail(req.Token)
+	if err != nil {
+		c.respondEmailError(w, err, "email verification failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "email verified", email)
+}
+
+func (c emailController) SetDigest(w http.ResponseWriter, r *http.Request) {
+
+	var req dto.EmailDigestDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		c.logger.Error("request body parsing failed for setting digest", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	if err := c.validator.Validate(req); err != nil {
+		c.logger.Error("invalid req body for setting digest", "error", c.validator.FormatErrors(err))
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	email, err := c.emailService.SetDigest(viewerFrom(r).Address, req.Frequency)
+	if err != nil {
+		c.respondEmailError(w, err, "setting digest failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "digest updated", email)
+}
+
+func (c emailController) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+
+	if err := c.emailService.Unsubscribe(r.URL.Query().Get("token")); err != nil {
+		c.respondEmailError(w, err, "unsubscribe failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "unsubscribed", nil)
+}
+
+func (c emailController) respondEmailError(w http.ResponseWriter, err error, msg string) {
+
+	switch {
+	case errors.Is(err, domain.ErrEmailDisabled):
+		respondError(w, http.StatusServiceUnavailable, err.Error())
+	case errors.Is(err, domain.ErrEmailInvalid), errors.Is(err, domain.ErrDigestInvalid),
+		errors.Is(err, domain.ErrEmailTokenInvalid), errors.Is(err, domain.ErrUnsubscribeTokenInvalid):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrEmailNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+	default:
+		c.logger.Error(msg, "error", err)
+		respondError(w, http.StatusInternalServerError, SOMETHING_WENT_WRONG_MSG)
+	}
+}
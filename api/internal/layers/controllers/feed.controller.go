@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+)
+
+type FeedController interface {
+	// GetFeed returns a page of the home feed, the ranking query param picks between
+	// boosted and recent ordering
+	GetFeed(w http.ResponseWriter, r *http.Request)
+}
+
+func NewFeedController(logger *logger.Logger, feedService services.FeedService) FeedController {
+	return feedController{
+		logger:      *logger,
+		feedService: feedService,
+	}
+}
+
+type feedController struct {
+	logger      logger.Logger
+	feedService services.FeedService
+}
+
+func (c feedController) GetFeed(w http.ResponseWriter, r *http.Request) {
+
+	limit, cursor := parseCursorPagination(r)
+
+	page, err := c.feedService.GetFeed(viewerFrom(r), r.URL.Query().Get("ranking"), cursor, limit)
+	if err != nil {
+		c.respondFeedError(w, err, "feed listing failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "feed found", page)
+}
+
+func (c feedController) respondFeedError(w http.ResponseWriter, err error, msg string) {
+
+	switch {
+	case errors.Is(err, domain.ErrCursorInvalid),
+		errors.Is(err, domain.ErrFeedRankingInvalid):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		c.logger.Error(msg, "error", err)
+		respondError(w, http.StatusInternalServerError, SOMETHING_WENT_WRONG_MSG)
+	}
+}
@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/common/dto"
+	"github.com/Xebec19/jibe/api/internal/common/schema"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/gorilla/mux"
+)
+
+type FollowController interface {
+	// GetStats returns the follow counts of the account and how the viewer relates to it
+	GetStats(w http.ResponseWriter, r *http.Request)
+	ListFollowers(w http.ResponseWriter, r *http.Request)
+	ListFollowing(w http.ResponseWriter, r *http.Request)
+	Follow(w http.ResponseWriter, r *http.Request)
+	Unfollow(w http.ResponseWriter, r *http.Request)
+	// Import follows every address of the request body
+	Import(w http.ResponseWriter, r *http.Request)
+	// Restrict blocks or mutes the account, as named by the kind path var
+	Restrict(w http.ResponseWriter, r *http.Request)
+	Unrestrict(w http.ResponseWriter, r *http.Request)
+	ListBlocks(w http.ResponseWriter, r *http.Request)
+	ListMutes(w http.ResponseWriter, r *http.Request)
+}
+
+func NewFollowController(logger *logger.Logger, validator schema.RequestValidator, followService services.FollowService) FollowController {
+	return followController{
+		logger:        *logger,
+		validator:     validator,
+		followService: followService,
+	}
+}
+
+type followController struct {
+	logger        logger.Logger
+	validator     schema.RequestValidator
+	followService services.FollowService
+}
+
+func (c followController) GetStats(w http.ResponseWriter, r *http.Request) {
+
+	stats, err := c.followService.GetStats(viewerFrom(r), mux.Vars(r)["account"])
+	if err != nil {
+		c.respondFollowError(w, err, "follow stats lookup failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "follow stats found", stats)
+}
+
+func (c followController) ListFollowers(w http.ResponseWriter, r *http.Request) {
+
+	limit, cursor := parseCursorPagination(r)
+
+	page, err := c.followService.ListFollowers(mux.Vars(r)["account"], cursor, limit)
+	if err != nil {
+		c.respondFollowError(w, err, "follower listing failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "followers found", page)
+}
+
+func (c followController) ListFollowing(w http.ResponseWriter, r *http.Request) {
+
+	limit, cursor := parseCursorPagination(r)
+
+	page, err := c.followService.ListFollowing(mux.Vars(r)["account"], cursor, limit)
+	if err != nil {
+		c.respondFollowError(w, err, "following listing failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "following found", page)
+}
+
+func (c followController) Follow(w http.ResponseWriter, r *http.Request) {
+
+	if err := c.followService.Follow(viewerFrom(r).Address, mux.Vars(r)["account"]); err != nil {
+		c.respondFollowError(w, err, "follow failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c followController) Unfollow(w http.ResponseWriter, r *http.Request) {
+
+	if err := c.followService.Unfollow(viewerFrom(r).Address, mux.Vars(r)["account"]); err != nil {
+		c.respondFollowError(w, err, "unfollow failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c followController) Import(w http.ResponseWriter, r *http.Request) {
+
+	var req dto.FollowImportDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		c.logger.Error("request body parsing failed for follow import", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	if err := c.validator.Validate(req); err != nil {
+		c.logger.Error("invalid req body for follow import", "error", c.validator.FormatErrors(err))
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	result, err := c.followService.Import(viewerFrom(r).Address, req.Addresses)
+	if err != nil {
+		c.respondFollowError(w, err, "follow import failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "follows imported", result)
+}
+
+func (c followController) Restrict(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+
+	if err := c.followService.Restrict(viewerFrom(r).Address, vars["account"], vars["kind"]); err != nil {
+		c.respondFollowError(w, err, "account restriction failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c followController) Unrestrict(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+
+	if err := c.followService.Unrestrict(viewerFrom(r).Address, vars["account"], vars["kind"]); err != nil {
+		c.respondFollowError(w, err, "account restriction removal failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c followController) ListBlocks(w http.ResponseWriter, r *http.Request) {
+	c.listRestrictions(w, r, domain.RestrictionBlock)
+}
+
+func (c followController) ListMutes(w http.ResponseWriter, r *http.Request) {
+	c.listRestrictions(w, r, domain.RestrictionMute)
+}
+
+func (c followController) listRestrictions(w http.ResponseWriter, r *http.Request, kind string) {
+
+	limit, cursor := parseCursorPagination(r)
+
+	page, err := c.followService.ListRestrictions(viewerFrom(r).Address, kind, cursor, limit)
+	if err != nil {
+		c.respondFollowError(w, err, "account restriction listing failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "restricted accounts found", page)
+}
+
+func (c followController) respondFollowError(w http.ResponseWriter, err error, msg string) {
+
+	switch {
+	case errors.Is(err, domain.ErrHandleNotFound),
+		errors.Is(err, domain.ErrFollowNotFound),
+		errors.Is(err, domain.ErrRestrictionMissing):
+		respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrFollowBlocked):
+		respondError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, domain.ErrRestrictionExists):
+		respondError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, domain.ErrFollowSelf),
+		errors.Is(err, domain.ErrRestrictionSelf),
+		errors.Is(err, domain.ErrRestrictionInvalid),
+		errors.Is(err, domain.ErrFollowImportEmpty),
+		errors.Is(err, domain.ErrFollowImportSize),
+		errors.Is(err, domain.ErrFollowImportFormat),
+		errors.Is(err, domain.ErrCursorInvalid):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		c.logger.Error(msg, "error", err)
+		respondError(w, http.StatusInternalServerError, SOMETHING_WENT_WRONG_MSG)
+	}
+}
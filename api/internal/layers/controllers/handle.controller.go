@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/common/dto"
+	"github.com/Xebec19/jibe/api/internal/common/schema"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/internal/middleware"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/gorilla/mux"
+)
+
+type HandleController interface {
+	// ClaimHandle assigns a handle to the authenticated account, retiring its previous one
+	ClaimHandle(w http.ResponseWriter, r *http.Request)
+	// CheckAvailability tells if a handle can be claimed by the caller
+	CheckAvailability(w http.ResponseWriter, r *http.Request)
+	// ResolveHandle maps a handle or address to the account, redirecting retired handles
+	ResolveHandle(w http.ResponseWriter, r *http.Request)
+	// GetHistory lists every handle used by an account
+	GetHistory(w http.ResponseWriter, r *http.Request)
+}
+
+func NewHandleController(logger *logger.Logger, validator schema.RequestValidator, handleService services.HandleService) HandleController {
+	return handleController{
+		logger:        *logger,
+		validator:     validator,
+		handleService: handleService,
+	}
+}
+
+type handleController struct {
+	logger        logger.Logger
+	validator     schema.RequestValidator
+	handleService services.HandleService
+}
+
+func (h handleController) ClaimHandle(w http.ResponseWriter, r *http.Request) {
+
+	var req dto.ClaimHandleDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("request body parsing failed for claiming handle", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		h.logger.Error("invalid req body for claiming handle", "error", h.validator.FormatErrors(err))
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	handle, err := h.handleService.ClaimHandle(middleware.GetEthAddress(r.Context()), req.Handle)
+	if err != nil {
+		h.respondHandleError(w, err, "handle claim failed")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, RESOURCE_CREATED_MSG, handle)
+}
+
+func (h handleController) CheckAvailability(w http.ResponseWriter, r *http.Request) {
+
+	raw := mux.Vars(r)["handle"]
+
+	payload := dto.HandleAvailabilityResponseDTO{Handle: raw}
+
+	if normalized, err := domain.NormalizeHandle(raw); err == nil {
+		payload.Handle = normalized
+	}
+
+	err := h.handleService.CheckAvailability(middleware.GetEthAddress(r.Context()), raw)
+	switch {
+	case err == nil:
+		payload.Available = true
+	case errors.Is(err, domain.ErrHandleInvalid), errors.Is(err, domain.ErrHandleReserved),
+		errors.Is(err, domain.ErrHandleTaken), errors.Is(err, domain.ErrHandleUnchanged):
+		payload.Reason = err.Error()
+	default:
+		h.logger.Error("handle availability check failed", "error", err)
+		respondError(w, http.StatusInternalServerError, SOMETHING_WENT_WRONG_MSG)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "availability checked", payload)
+}
+
+func (h handleController) ResolveHandle(w http.ResponseWriter, r *http.Request) {
+
+	res, err := h.handleService.Resolve(mux.Vars(r)["handle"])
+	if err != nil {
+		h.respondHandleError(w, err, "handle resolution failed")
+		return
+	}
+
+	if res.RedirectTo != "" {
+		w.Header().Set("Location", "/v1/handles/"+res.RedirectTo)
+		respondJSON(w, http.StatusPermanentRedirect, "handle has moved", res)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "handle resolved", res)
+}
+
+func (h handleController) GetHistory(w http.ResponseWriter, r *http.Request) {
+
+	handles, err := h.handleService.History(mux.Vars(r)["handle"])
+	if err != nil {
+		h.respondHandleError(w, err, "handle history lookup failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "handle history", handles)
+}
+
+func (h handleController) respondHandleError(w http.ResponseWriter, err error, msg string) {
+
+	switch {
+	case errors.Is(err, domain.ErrHandleInvalid), errors.Is(err, domain.ErrHandleReserved):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrHandleTaken), errors.Is(err, domain.ErrHandleUnchanged):
+		respondError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, domain.ErrHandleNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+	default:
+		h.logger.Error(msg, "error", err)
+		respondError(w, http.StatusInternalServerError, SOMETHING_WENT_WRONG_MSG)
+	}
+}
@@ -3,23 +3,44 @@ package controllers
 import (
 	"net/http"
 
+	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/pkg/chain"
 	"github.com/Xebec19/jibe/api/pkg/logger"
 )
 
 type HealthController interface {
 	GetHealthCheckpoint(w http.ResponseWriter, r *http.Request)
+	// GetMetrics reports access cache and chain read counters
+	GetMetrics(w http.ResponseWriter, r *http.Request)
 }
 
-func NewHealthController(logger *logger.Logger) HealthController {
+func NewHealthController(logger *logger.Logger, accessService services.AccessService, chainReader chain.Reader) HealthController {
 	return healthController{
-		logger: logger,
+		logger:        logger,
+		accessService: accessService,
+		chainReader:   chainReader,
 	}
 }
 
 type healthController struct {
-	logger *logger.Logger
+	logger        *logger.Logger
+	accessService services.AccessService
+	chainReader   chain.Reader
 }
 
 func (h healthController) GetHealthCheckpoint(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, "alive", nil)
 }
+
+func (h healthController) GetMetrics(w http.ResponseWriter, r *http.Request) {
+
+	reads := h.chainReader.Stats()
+
+	respondJSON(w, http.StatusOK, "metrics", map[string]any{
+		"access_cache": h.accessService.CacheStats(),
+		"chain_reads": map[string]uint64{
+			"reads":       reads.Reads,
+			"round_trips": reads.RoundTrips,
+		},
+	})
+}
@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/common/dto"
+	"github.com/Xebec19/jibe/api/internal/common/schema"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/internal/middleware"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/gorilla/mux"
+)
+
+type InviteController interface {
+	// CreateInvite issues a shareable link into a community
+	CreateInvite(w http.ResponseWriter, r *http.Request)
+	// ListInvites lists the links of a community with their click, sign-in and join stats
+	ListInvites(w http.ResponseWriter, r *http.Request)
+	RevokeInvite(w http.ResponseWriter, r *http.Request)
+	// PreviewInvite shows the recipient of a link what it leads to
+	PreviewInvite(w http.ResponseWriter, r *http.Request)
+	// RedeemInvite joins the caller through a link
+	RedeemInvite(w http.ResponseWriter, r *http.Request)
+}
+
+func NewInviteController(logger *logger.Logger, validator schema.RequestValidator, inviteService services.InviteService) InviteController {
+	return inviteController{
+		logger:        *logger,
+		validator:     validator,
+		inviteService: inviteService,
+	}
+}
+
+type inviteController struct {
+	logger        logger.Logger
+	validator     schema.RequestValidator
+	inviteService services.InviteService
+}
+
+func (c inviteController) CreateInvite(w http.ResponseWriter, r *http.Request) {
+
+	// every setting is optional, an empty body creates an unlimited link
+	var req dto.InviteDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.logger.Error("request body parsing failed for creating invite", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	if err := c.validator.Validate(req); err != nil {
+		c.logger.Error("invalid req body for creating invite", "error", c.validator.FormatErrors(err))
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	invite, err := c.inviteService.CreateInvite(middleware.GetEthAddress(r.Context()), mux.Vars(r)["id"], domain.InviteInput{
+		MaxUses:      req.MaxUses,
+		ExpiresAt:    req.ExpiresAt,
+		AccessPolicy: req.AccessPolicy,
+	})
+	if err != nil {
+		c.respondInviteError(w, err, "invite creation failed")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, RESOURCE_CREATED_MSG, invite)
+}
+
+func (c inviteController) ListInvites(w http.ResponseWriter, r *http.Request) {
+
+	limit, offset := parsePagination(r)
+
+	invites, err := c.inviteService.ListInvites(middleware.GetEthAddress(r.Context()), mux.Vars(r)["id"], limit, offset)
+	if err != nil {
+		c.respondInviteError(w, err, "invite listing failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "invites found", invites)
+}
+
+func (c inviteController) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+
+	err := c.inviteService.RevokeInvite(middleware.GetEthAddress(r.Context()), vars["id"], vars["inviteId"])
+	if err != nil {
+		c.respondInviteError(w, err, "invite revocation failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "invite revoked", nil)
+}
+
+func (c inviteController) PreviewInvite(w http.ResponseWriter, r *http.Request) {
+
+	preview, err := c.inviteService.Preview(mux.Vars(r)["token"])
+	if err != nil {
+		c.respondInviteError(w, err, "invite lookup failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "invite found", preview)
+}
+
+func (c inviteController) RedeemInvite(w http.ResponseWriter, r *http.Request) {
+
+	// the body is optional, only referrals into paid communities need one
+	var req dto.JoinCommunityDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.logger.Error("request body parsing failed for redeeming invite", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	if err := c.validator.Validate(req); err != nil {
+		c.logger.Error("invalid req body for redeeming invite", "error", c.validator.FormatErrors(err))
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	member, err := c.inviteService.Redeem(viewerFrom(r), mux.Vars(r)["token"], req.PaymentTx)
+	if err != nil {
+		c.respondInviteError(w, err, "invite redemption failed")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, "community joined", member)
+}
+
+func (c inviteController) respondInviteError(w http.ResponseWriter, err error, msg string) {
+
+	switch {
+	case errors.Is(err, domain.ErrCommunityInvalid), errors.Is(err, domain.ErrInvalidPolicy),
+		errors.Is(err, domain.ErrPaymentInvalid):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrCommunityForbidden), errors.Is(err, domain.ErrJoinDenied),
+		errors.Is(err, domain.ErrInviteOnly):
+		respondError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, domain.ErrInviteNotFound), errors.Is(err, domain.ErrCommunityNotFound),
+		errors.Is(err, domain.ErrPaymentNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrAlreadyMember):
+		respondError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, domain.ErrInviteExpired):
+		respondError(w, http.StatusGone, err.Error())
+	default:
+		c.logger.Error(msg, "error", err)
+		respondError(w, http.StatusInternalServerError, SOMETHING_WENT_WRONG_MSG)
+	}
+}
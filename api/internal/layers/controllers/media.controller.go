@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/internal/middleware"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/gorilla/mux"
+)
+
+// transferTimeout bounds a single upload or download, the server wide timeouts are
+// sized for small JSON bodies
+const transferTimeout = 30 * time.Minute
+
+type MediaController interface {
+	// UploadMultipart stores the "file" part of a multipart form. A post_id field must
+	// come before the file part, or be passed as query param
+	UploadMultipart(w http.ResponseWriter, r *http.Request)
+	// UploadStream stores the raw request body, filename and post_id are query params
+	UploadStream(w http.ResponseWriter, r *http.Request)
+	GetMedia(w http.ResponseWriter, r *http.Request)
+	// SignURL issues an expiring download url bound to the caller, the variant query
+	// param selects a resized variant or the blurred preview of an image
+	SignURL(w http.ResponseWriter, r *http.Request)
+	// Download serves a file through a signed url
+	Download(w http.ResponseWriter, r *http.Request)
+	DeleteMedia(w http.ResponseWriter, r *http.Request)
+}
+
+func NewMediaController(logger *logger.Logger, mediaService services.MediaService) MediaController {
+	return mediaController{
+		logger:       *logger,
+		mediaService: mediaService,
+	}
+}
+
+type mediaController struct {
+	logger       logger.Logger
+	mediaService services.MediaService
+}
+
+func (m mediaController) UploadMultipart(w http.ResponseWriter, r *http.Request) {
+
+	extendDeadlines(w)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		m.logger.Error("request body parsing failed for media upload", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	upload := domain.MediaUpload{PostID: r.URL.Query().Get("post_id")}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			m.respondMediaError(w, err, "request body parsing failed for media upload")
+			return
+		}
+
+		switch part.FormName() {
+		case "post_id":
+			value, err := io.ReadAll(io.LimitReader(part, 64))
+			if err != nil {
+				m.respondMediaError(w, err, "request body parsing failed for media upload")
+				return
+			}
+			upload.PostID = string(value)
+
+		case "file":
+			upload.Filename = part.FileName()
+
+			media, err := m.mediaService.Upload(middleware.GetEthAddress(r.Context()), upload, part)
+			if err != nil {
+				m.respondMediaError(w, err, "media upload failed")
+				return
+			}
+
+			respondJSON(w, http.StatusCreated, RESOURCE_CREATED_MSG, media)
+			return
+		}
+	}
+
+	respondError(w, http.StatusBadRequest, domain.ErrMediaEmpty.Error())
+}
+
+func (m mediaController) UploadStream(w http.ResponseWriter, r *http.Request) {
+
+	extendDeadlines(w)
+
+	upload := domain.MediaUpload{
+		Filename: r.URL.Query().Get("filename"),
+		PostID:   r.URL.Query().Get("post_id"),
+	}
+
+	media, err := m.mediaService.Upload(middleware.GetEthAddress(r.Context()), upload, r.Body)
+	if err != nil {
+		m.respondMediaError(w, err, "media upload failed")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, RESOURCE_CREATED_MSG, media)
+}
+
+func (m mediaController) GetMedia(w http.ResponseWriter, r *http.Request) {
+
+	media, err := m.mediaService.GetMedia(viewerFrom(r), mux.Vars(r)["id"])
+	if err != nil {
+		m.respondMediaError(w, err, "media lookup failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "media found", media)
+}
+
+func (m mediaController) SignURL(w http.ResponseWriter, r *http.Request) {
+
+	signed, err := m.mediaService.SignURL(viewerFrom(r), mux.Vars(r)["id"], r.URL.Query().Get("variant"))
+	if err != nil {
+		m.respondMediaError(w, err, "media url signing failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "media url signed", signed)
+}
+
+func (m mediaController) Download(w http.ResponseWriter, r *http.Request) {
+
+	extendDeadlines(w)
+
+	query := r.URL.Query()
+
+	file, media, err := m.mediaService.Open(middleware.GetEthAddress(r.Context()), mux.Vars(r)["id"], query.Get("variant"), query.Get("expires"), query.Get("sig"))
+	if err != nil {
+		m.respondMediaError(w, err, "media download failed")
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", media.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": media.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, no-store")
+
+	if seeker, ok := file.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", media.CreatedAt, seeker)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, file); err != nil {
+		m.logger.Warn("media download interrupted", "media", media.ID, "error", err)
+	}
+}
+
+func (m mediaController) DeleteMedia(w http.ResponseWriter, r *http.Request) {
+
+	err := m.mediaService.DeleteMedia(middleware.GetEthAddress(r.Context()), mux.Vars(r)["id"])
+	if err != nil {
+		m.respondMediaError(w, err, "media deletion failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "media deleted", nil)
+}
+
+func (m mediaController) respondMediaError(w http.ResponseWriter, err error, msg string) {
+
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.Is(err, domain.ErrMediaEmpty):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrUnsupportedMediaType):
+		respondError(w, http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, domain.ErrMediaTooLarge), errors.As(err, &maxBytesErr):
+		respondError(w, http.StatusRequestEntityTooLarge, domain.ErrMediaTooLarge.Error())
+	case errors.Is(err, domain.ErrStorageQuotaExceeded):
+		respondError(w, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, domain.ErrMediaForbidden), errors.Is(err, domain.ErrPostForbidden),
+		errors.Is(err, domain.ErrMediaLocked), errors.Is(err, domain.ErrMediaURLInvalid):
+		respondError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, domain.ErrMediaNotFound), errors.Is(err, domain.ErrPostNotFound),
+		errors.Is(err, domain.ErrVariantNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrMediaProcessing):
+		respondError(w, http.StatusConflict, err.Error())
+	default:
+		m.logger.Error(msg, "error", err)
+		respondError(w, http.StatusInternalServerError, SOMETHING_WENT_WRONG_MSG)
+	}
+}
+
+// extendDeadlines lifts the server read and write timeouts for a file transfer
+func extendDeadlines(w http.ResponseWriter) {
+
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(transferTimeout)
+
+	_ = rc.SetReadDeadline(deadline)
+	_ = rc.SetWriteDeadline(deadline)
+}
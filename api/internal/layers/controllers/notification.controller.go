@@ -0,0 +1,269 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/common/dto"
+	"github.com/Xebec19/jibe/api/internal/common/schema"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// notificationWriteWait bounds a single write to a stream
+	notificationWriteWait = 10 * time.Second
+	// notificationKeepAlive keeps proxies from closing idle streams
+	notificationKeepAlive = 25 * time.Second
+	// notificationRetry is how long browsers wait before reconnecting, in milliseconds
+	notificationRetry = 5000
+	// notificationBatch is how many notifications are read from the stream at once
+	notificationBatch = 100
+)
+
+type NotificationController interface {
+	// ListNotifications returns the viewer's notifications, paginated by the cursor query
+	// param. unread=true leaves read ones out
+	ListNotifications(w http.ResponseWriter, r *http.Request)
+	CountUnread(w http.ResponseWriter, r *http.Request)
+	MarkRead(w http.ResponseWriter, r *http.Request)
+	MarkUnread(w http.ResponseWriter, r *http.Request)
+	// MarkAllRead marks every notification up to the optional up_to_id as read
+	MarkAllRead(w http.ResponseWriter, r *http.Request)
+	// GetPreferences returns which kinds of notifications every delivery channel sends
+	GetPreferences(w http.ResponseWriter, r *http.Request)
+	UpdatePreferences(w http.ResponseWriter, r *http.Request)
+	// Stream delivers new notifications as server-sent events. The Last-Event-ID header,
+	// or the last_event_id query param on first connect, resumes after a given event
+	Stream(w http.ResponseWriter, r *http.Request)
+}
+
+func NewNotificationController(logger *logger.Logger, validator schema.RequestValidator, notificationService services.NotificationService) NotificationController {
+	return notificationController{
+		logger:              *logger,
+		validator:           validator,
+		notificationService: notificationService,
+	}
+}
+
+type notificationController struct {
+	logger              logger.Logger
+	validator           schema.RequestValidator
+	notificationService services.NotificationService
+}
+
+func (c notificationController) ListNotifications(w http.ResponseWriter, r *http.Request) {
+
+	limit, cursor := parseCursorPagination(r)
+	unreadOnly, _ := strconv.ParseBool(r.URL.Query().Get("unread"))
+
+	page, err := c.notificationService.ListNotifications(viewerFrom(r).Address, cursor, unreadOnly, limit)
+	if err != nil {
+		c.respondNotificationError(w, err, "notification listing failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "notifications found", page)
+}
+
+func (c notificationController) CountUnread(w http.ResponseWriter, r *http.Request) {
+
+	count, err := c.notificationService.CountUnread(viewerFrom(r).Address)
+	if err != nil {
+		c.respondNotificationError(w, err, "unread notification count failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "unread notifications counted", map[string]int{"count": count})
+}
+
+func (c notificationController) MarkRead(w http.ResponseWriter, r *http.Request) {
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondError(w, http.StatusNotFound, domain.ErrNotificationNotFound.Error())
+		return
+	}
+
+	notification, err := c.notificationService.MarkRead(viewerFrom(r).Address, id)
+	if err != nil {
+		c.respondNotificationError(w, err, "marking notification read failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "notification marked read", notification)
+}
+
+func (c notificationController) MarkUnread(w http.ResponseWriter, r *http.Request) {
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondError(w, http.StatusNotFound, domain.ErrNotificationNotFound.Error())
+		return
+	}
+
+	notification, err := c.notificationService.MarkUnread(viewerFrom(r).Address, id)
+	if err != nil {
+		c.respondNotificationError(w, err, "marking notification unread failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "notification marked unread", notification)
+}
+
+func (c notificationController) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+
+	var req dto.MarkNotificationsReadDTO
+
+	// the body is optional
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.logger.Error("request body parsing failed for marking notifications read", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	if err := c.validator.Validate(req); err != nil {
+		c.logger.Error("invalid req body for marking notifications read", "error", c.validator.FormatErrors(err))
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	marked, err := c.notificationService.MarkAllRead(viewerFrom(r).Address, req.UpToID)
+	if err != nil {
+		c.respondNotificationError(w, err, "marking notifications read failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "notifications marked read", map[string]int64{"marked": marked})
+}
+
+func (c notificationController) GetPreferences(w http.ResponseWriter, r *http.Request) {
+
+	preferences, err := c.notificationService.GetPreferences(viewerFrom(r).Address)
+	if err != nil {
+		c.respondNotificationError(w, err, "notification preferences lookup failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "notification preferences found", preferences)
+}
+
+func (c notificationController) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+
+	var req dto.NotificationPreferencesDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		c.logger.Error("request body parsing failed for notification preferences", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	if err := c.validator.Validate(req); err != nil {
+		c.logger.Error("invalid req body for notification preferences", "error", c.validator.FormatErrors(err))
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	preferences, err := c.notificationService.UpdatePreferences(viewerFrom(r).Address, req.Preferences)
+	if err != nil {
+		c.respondNotificationError(w, err, "notification preferences update failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "notification preferences updated", preferences)
+}
+
+func (c notificationController) Stream(w http.ResponseWriter, r *http.Request) {
+
+	// EventSource can not set headers, only reconnects carry Last-Event-ID
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+
+	stream, err := c.notificationService.Subscribe(viewerFrom(r).Address, lastEventID)
+	if err != nil {
+		c.respondNotificationError(w, err, "notification stream failed")
+		return
+	}
+	defer c.notificationService.Unsubscribe(stream)
+
+	rc := http.NewResponseController(w)
+
+	// write pushes a chunk out, the deadline set by the server only covers the headers
+	write := func(chunk string) bool {
+		rc.SetWriteDeadline(time.Now().Add(notificationWriteWait))
+		if _, err := io.WriteString(w, chunk); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if !write(fmt.Sprintf("retry: %d\n\n", notificationRetry)) {
+		return
+	}
+
+	keepAlive := time.NewTicker(notificationKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-keepAlive.C:
+			if !write(": keep-alive\n\n") {
+				return
+			}
+
+		case <-stream.Wake():
+			for {
+				notifications, err := c.notificationService.Next(stream, notificationBatch)
+				if err != nil {
+					// the stream is woken again by the next announcement
+					c.logger.Warn("notification stream read failed", "address", stream.Address, "error", err)
+					break
+				}
+
+				for _, notification := range notifications {
+					data, _ := json.Marshal(notification)
+					if !write(fmt.Sprintf("id: %d\nevent: notification\ndata: %s\n\n", notification.ID, data)) {
+						return
+					}
+				}
+
+				if len(notifications) < notificationBatch {
+					break
+				}
+			}
+		}
+	}
+}
+
+func (c notificationController) respondNotificationError(w http.ResponseWriter, err error, msg string) {
+
+	switch {
+	case errors.Is(err, domain.ErrCursorInvalid):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrNotificationNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrPreferenceInvalid):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		c.logger.Error(msg, "error", err)
+		respondError(w, http.StatusInternalServerError, SOMETHING_WENT_WRONG_MSG)
+	}
+}
@@ -0,0 +1,421 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Xebec19/jibe/api/internal/common/dto"
+	"github.com/Xebec19/jibe/api/internal/common/schema"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/internal/middleware"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/gorilla/mux"
+)
+
+type PostController interface {
+	// CreatePost stores a new draft for the authenticated author
+	CreatePost(w http.ResponseWriter, r *http.Request)
+	// UpdatePost replaces title, body and access policy of a post
+	UpdatePost(w http.ResponseWriter, r *http.Request)
+	// PublishPost makes a draft visible to readers
+	PublishPost(w http.ResponseWriter, r *http.Request)
+	// UnpublishPost moves a published or scheduled post back to drafts
+	UnpublishPost(w http.ResponseWriter, r *http.Request)
+	// SchedulePost publishes a draft at a later time
+	SchedulePost(w http.ResponseWriter, r *http.Request)
+	// SetDrip unlocks a post for community members some time after each of them joined
+	SetDrip(w http.ResponseWriter, r *http.Request)
+	RemoveDrip(w http.ResponseWriter, r *http.Request)
+	DeletePost(w http.ResponseWriter, r *http.Request)
+	// GetPost returns a post, locking its content if the caller fails the access policy.
+	// Images of a locked post are only shown as blurred previews
+	GetPost(w http.ResponseWriter, r *http.Request)
+	// ListPosts lists posts of an author given by address or handle
+	ListPosts(w http.ResponseWriter, r *http.Request)
+	// ListRevisions lists the revisions of a post for its author, newest first
+	ListRevisions(w http.ResponseWriter, r *http.Request)
+	GetRevision(w http.ResponseWriter, r *http.Request)
+	// DiffRevisions compares the revisions given by the from and to query params
+	DiffRevisions(w http.ResponseWriter, r *http.Request)
+	// RestoreRevision saves the content of a revision as the post's
+	RestoreRevision(w http.ResponseWriter, r *http.Request)
+	AddAttachment(w http.ResponseWriter, r *http.Request)
+	RemoveAttachment(w http.ResponseWriter, r *http.Request)
+}
+
+func NewPostController(logger *logger.Logger, validator schema.RequestValidator, postService services.PostService, handleService services.HandleService, mediaService services.MediaService) PostController {
+	return postController{
+		logger:        *logger,
+		validator:     validator,
+		postService:   postService,
+		handleService: handleService,
+		mediaService:  mediaService,
+	}
+}
+
+type postController struct {
+	logger        logger.Logger
+	validator     schema.RequestValidator
+	postService   services.PostService
+	handleService services.HandleService
+	mediaService  services.MediaService
+}
+
+func (p postController) CreatePost(w http.ResponseWriter, r *http.Request) {
+
+	input, ok := p.decodePostInput(w, r)
+	if !ok {
+		return
+	}
+
+	post, err := p.postService.CreatePost(middleware.GetEthAddress(r.Context()), input)
+	if err != nil {
+		p.respondPostError(w, err, "post creation failed")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, RESOURCE_CREATED_MSG, post)
+}
+
+func (p postController) UpdatePost(w http.ResponseWriter, r *http.Request) {
+
+	input, ok := p.decodePostInput(w, r)
+	if !ok {
+		return
+	}
+
+	post, err := p.postService.UpdatePost(middleware.GetEthAddress(r.Context()), mux.Vars(r)["id"], input)
+	if err != nil {
+		p.respondPostError(w, err, "post update failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "post updated", post)
+}
+
+func (p postController) PublishPost(w http.ResponseWriter, r *http.Request) {
+
+	post, err := p.postService.PublishPost(middleware.GetEthAddress(r.Context()), mux.Vars(r)["id"])
+	if err != nil {
+		p.respondPostError(w, err, "post publishing failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "post published", post)
+}
+
+func (p postController) UnpublishPost(w http.ResponseWriter, r *http.Request) {
+
+	post, err := p.postService.UnpublishPost(middleware.GetEthAddress(r.Context()), mux.Vars(r)["id"])
+	if err != nil {
+		p.respondPostError(w, err, "post unpublishing failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "post unpublished", post)
+}
+
+func (p postController) SchedulePost(w http.ResponseWriter, r *http.Request) {
+
+	var req dto.SchedulePostDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		p.logger.Error("request body parsing failed for post schedule", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	if err := p.validator.Validate(req); err != nil {
+		p.logger.Error("invalid req body for post schedule", "error", p.validator.FormatErrors(err))
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	post, err := p.postService.SchedulePost(middleware.GetEthAddress(r.Context()), mux.Vars(r)["id"], req.PublishAt)
+	if err != nil {
+		p.respondPostError(w, err, "post scheduling failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "post scheduled", post)
+}
+
+func (p postController) SetDrip(w http.ResponseWriter, r *http.Request) {
+
+	var req dto.PostDripDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		p.logger.Error("request body parsing failed for post drip", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	if err := p.validator.Validate(req); err != nil {
+		p.logger.Error("invalid req body for post drip", "error", p.validator.FormatErrors(err))
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	drip, err := p.postService.SetDrip(middleware.GetEthAddress(r.Context()), mux.Vars(r)["id"], domain.PostDrip{
+		CommunityID:  req.CommunityID,
+		DelaySeconds: req.DelaySeconds,
+	})
+	if err != nil {
+		p.respondPostError(w, err, "post drip update failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "post drip updated", drip)
+}
+
+func (p postController) RemoveDrip(w http.ResponseWriter, r *http.Request) {
+
+	err := p.postService.RemoveDrip(middleware.GetEthAddress(r.Context()), mux.Vars(r)["id"])
+	if err != nil {
+		p.respondPostError(w, err, "post drip removal failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "post drip removed", nil)
+}
+
+func (p postController) DeletePost(w http.ResponseWriter, r *http.Request) {
+
+	err := p.postService.DeletePost(middleware.GetEthAddress(r.Context()), mux.Vars(r)["id"])
+	if err != nil {
+		p.respondPostError(w, err, "post deletion failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "post deleted", nil)
+}
+
+func (p postController) GetPost(w http.ResponseWriter, r *http.Request) {
+
+	viewer := viewerFrom(r)
+
+	post, err := p.postService.GetPost(viewer, mux.Vars(r)["id"])
+	if err != nil {
+		p.respondPostError(w, err, "post lookup failed")
+		return
+	}
+
+	post.Images, err = p.mediaService.PostImages(viewer, post)
+	if err != nil {
+		p.respondPostError(w, err, "post images lookup failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "post found", post)
+}
+
+func (p postController) ListPosts(w http.ResponseWriter, r *http.Request) {
+
+	viewer := viewerFrom(r)
+
+	author := viewer.Address
+	if identifier := r.URL.Query().Get("author"); identifier != "" {
+		addr, err := p.handleService.ResolveAddress(identifier)
+		if errors.Is(err, domain.ErrHandleNotFound) {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			p.logger.Error("author resolution failed", "error", err)
+			respondError(w, http.StatusInternalServerError, SOMETHING_WENT_WRONG_MSG)
+			return
+		}
+		author = addr
+	}
+
+	if author == "" {
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = domain.PostStatusPublished
+	}
+	if status != domain.PostStatusPublished && status != domain.PostStatusDraft && status != domain.PostStatusScheduled {
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	limit, offset := parsePagination(r)
+
+	posts, err := p.postService.ListPosts(viewer, author, status, limit, offset)
+	if err != nil {
+		p.respondPostError(w, err, "post listing failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "posts found", posts)
+}
+
+func (p postController) ListRevisions(w http.ResponseWriter, r *http.Request) {
+
+	limit, offset := parsePagination(r)
+
+	revisions, err := p.postService.ListRevisions(middleware.GetEthAddress(r.Context()), mux.Vars(r)["id"], limit, offset)
+	if err != nil {
+		p.respondPostError(w, err, "revision listing failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "revisions found", revisions)
+}
+
+func (p postController) GetRevision(w http.ResponseWriter, r *http.Request) {
+
+	number, err := strconv.Atoi(mux.Vars(r)["number"])
+	if err != nil {
+		respondError(w, http.StatusNotFound, domain.ErrRevisionNotFound.Error())
+		return
+	}
+
+	revision, err := p.postService.GetRevision(middleware.GetEthAddress(r.Context()), mux.Vars(r)["id"], number)
+	if err != nil {
+		p.respondPostError(w, err, "revision lookup failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "revision found", revision)
+}
+
+func (p postController) DiffRevisions(w http.ResponseWriter, r *http.Request) {
+
+	from, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil || from <= 0 {
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	to, err := strconv.Atoi(r.URL.Query().Get("to"))
+	if err != nil || to <= 0 {
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	diff, err := p.postService.DiffRevisions(middleware.GetEthAddress(r.Context()), mux.Vars(r)["id"], from, to)
+	if err != nil {
+		p.respondPostError(w, err, "revision diff failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "revisions compared", diff)
+}
+
+func (p postController) RestoreRevision(w http.ResponseWriter, r *http.Request) {
+
+	number, err := strconv.Atoi(mux.Vars(r)["number"])
+	if err != nil {
+		respondError(w, http.StatusNotFound, domain.ErrRevisionNotFound.Error())
+		return
+	}
+
+	post, err := p.postService.RestoreRevision(middleware.GetEthAddress(r.Context()), mux.Vars(r)["id"], number)
+	if err != nil {
+		p.respondPostError(w, err, "revision restore failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "revision restored", post)
+}
+
+func (p postController) AddAttachment(w http.ResponseWriter, r *http.Request) {
+
+	var req dto.PostAttachmentDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		p.logger.Error("request body parsing failed for adding attachment", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	if err := p.validator.Validate(req); err != nil {
+		p.logger.Error("invalid req body for adding attachment", "error", p.validator.FormatErrors(err))
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	attachment, err := p.postService.AddAttachment(middleware.GetEthAddress(r.Context()), mux.Vars(r)["id"], domain.PostAttachment{
+		Name:        req.Name,
+		URL:         req.URL,
+		ContentType: req.ContentType,
+		SizeBytes:   req.SizeBytes,
+		Position:    req.Position,
+	})
+	if err != nil {
+		p.respondPostError(w, err, "attachment creation failed")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, RESOURCE_CREATED_MSG, attachment)
+}
+
+func (p postController) RemoveAttachment(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+
+	err := p.postService.RemoveAttachment(middleware.GetEthAddress(r.Context()), vars["id"], vars["attachmentId"])
+	if err != nil {
+		p.respondPostError(w, err, "attachment deletion failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "attachment deleted", nil)
+}
+
+func (p postController) decodePostInput(w http.ResponseWriter, r *http.Request) (domain.PostInput, bool) {
+
+	var req dto.PostDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		p.logger.Error("request body parsing failed for post", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return domain.PostInput{}, false
+	}
+
+	if err := p.validator.Validate(req); err != nil {
+		p.logger.Error("invalid req body for post", "error", p.validator.FormatErrors(err))
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return domain.PostInput{}, false
+	}
+
+	if req.BodyFormat == "" {
+		req.BodyFormat = domain.BodyFormatMarkdown
+	}
+
+	return domain.PostInput{
+		Title:        req.Title,
+		Teaser:       req.Teaser,
+		Tags:         req.Tags,
+		Body:         req.Body,
+		BodyFormat:   req.BodyFormat,
+		AccessPolicy: req.AccessPolicy,
+	}, true
+}
+
+func (p postController) respondPostError(w http.ResponseWriter, err error, msg string) {
+
+	switch {
+	case errors.Is(err, domain.ErrInvalidPolicy), errors.Is(err, domain.ErrPublishAtInvalid),
+		errors.Is(err, domain.ErrDripDelayInvalid):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrPostForbidden), errors.Is(err, domain.ErrDripCommunityNotOwned):
+		respondError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, domain.ErrPostNotFound), errors.Is(err, domain.ErrAttachmentNotFound),
+		errors.Is(err, domain.ErrDripNotFound), errors.Is(err, domain.ErrCommunityNotFound),
+		errors.Is(err, domain.ErrRevisionNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrPostAlreadyPublished), errors.Is(err, domain.ErrRevisionCurrent):
+		respondError(w, http.StatusConflict, err.Error())
+	default:
+		p.logger.Error(msg, "error", err)
+		respondError(w, http.StatusInternalServerError, SOMETHING_WENT_WRONG_MSG)
+	}
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/common/dto"
+	"github.com/Xebec19/jibe/api/internal/common/schema"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/gorilla/mux"
+)
+
+type PushController interface {
+	// PublicKey returns the VAPID key browsers pass as applicationServerKey
+	PublicKey(w http.ResponseWriter, r *http.Request)
+	ListSubscriptions(w http.ResponseWriter, r *http.Request)
+	// Subscribe registers the PushSubscription of a browser, registering a known
+	// endpoint again updates its keys
+	Subscribe(w http.ResponseWriter, r *http.Request)
+	Unsubscribe(w http.ResponseWriter, r *http.Request)
+}
+
+func NewPushController(logger *logger.Logger, validator schema.RequestValidator, pushService services.PushService) PushController {
+	return pushController{
+		logger:      *logger,
+		validator:   validator,
+		pushService: pushService,
+	}
+}
+
+type pushController struct {
+	logger      logger.Logger
+	validator   schema.RequestValidator
+	pushService services.PushService
+}
+
+func (c pushController) PublicKey(w http.ResponseWriter, r *http.Request) {
+
+	key, err := c.pushService.PublicKey()
+	if err != nil {
+		c.respondPushError(w, err, "vapid public key lookup failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "vapid public key found", map[string]string{"public_key": key})
+}
+
+func (c pushController) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+
+	subscriptions, err := c.pushService.ListSubscriptions(viewerFrom(r).Address)
+	if err != nil {
+		c.respondPushError(w, err, "push subscription listing failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "push subscriptions found", subscriptions)
+}
+
+func (c pushController) Subscribe(w http.ResponseWriter, r *http.Request) {
+
+	var req dto.PushSubscriptionDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		c.logger.Error("request body parsing failed for push subscription", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	if err := c.validator.Validate(req); err != nil {
+		c.logger.Error("invalid req body for push subscription", "error", c.validator.FormatErrors(err))
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	subscription, err := c.pushService.Subscribe(viewerFrom(r).Address, domain.PushSubscription{
+		Endpoint:  req.Endpoint,
+		P256dh:    req.Keys.P256dh,
+		Auth:      req.Keys.Auth,
+		UserAgent: r.UserAgent(),
+	})
+	if err != nil {
+		c.respondPushError(w, err, "push subscription failed")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, RESOURCE_CREATED_MSG, subscription)
+}
+
+func (c pushController) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+
+	if err := c.pushService.Unsubscribe(viewerFrom(r).Address, mux.Vars(r)["id"]); err != nil {
+		c.respondPushError(w, err, "push subscription removal failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c pushController) respondPushError(w http.ResponseWriter, err error, msg string) {
+
+	switch {
+	case errors.Is(err, domain.ErrPushDisabled):
+		respondError(w, http.StatusServiceUnavailable, err.Error())
+	case errors.Is(err, domain.ErrPushSubscriptionInvalid):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrPushSubscriptionNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+	default:
+		c.logger.Error(msg, "error", err)
+		respondError(w, http.StatusInternalServerError, SOMETHING_WENT_WRONG_MSG)
+	}
+}
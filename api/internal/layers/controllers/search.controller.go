@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+)
+
+type SearchController interface {
+	// Search looks up the q query param among posts, creators and communities, the type
+	// param narrows it to one of them
+	Search(w http.ResponseWriter, r *http.Request)
+}
+
+func NewSearchController(logger *logger.Logger, searchService services.SearchService) SearchController {
+	return searchController{
+		logger:        *logger,
+		searchService: searchService,
+	}
+}
+
+type searchController struct {
+	logger        logger.Logger
+	searchService services.SearchService
+}
+
+func (c searchController) Search(w http.ResponseWriter, r *http.Request) {
+
+	limit, offset := parsePagination(r)
+
+	query := r.URL.Query()
+
+	results, err := c.searchService.Search(viewerFrom(r), query.Get("q"), query.Get("type"), limit, offset)
+	if err != nil {
+		c.respondSearchError(w, err, "search failed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "search results found", results)
+}
+
+func (c searchController) respondSearchError(w http.ResponseWriter, err error, msg string) {
+
+	switch {
+	case errors.Is(err, domain.ErrSearchQueryInvalid):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		c.logger.Error(msg, "error", err)
+		respondError(w, http.StatusInternalServerError, SOMETHING_WENT_WRONG_MSG)
+	}
+}
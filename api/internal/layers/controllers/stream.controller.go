@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/internal/middleware"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/gorilla/mux"
+)
+
+const playlistContentType = "application/vnd.apple.mpegurl"
+
+type StreamController interface {
+	// MasterPlaylist starts a playback session of a video for a caller passing the
+	// post's access policy
+	MasterPlaylist(w http.ResponseWriter, r *http.Request)
+	// MediaPlaylist serves a rendition playlist through a signed url
+	MediaPlaylist(w http.ResponseWriter, r *http.Request)
+	// Segment serves an encrypted segment through a signed url
+	Segment(w http.ResponseWriter, r *http.Request)
+	// Key serves the AES-128 segment key after checking the access policy again
+	Key(w http.ResponseWriter, r *http.Request)
+}
+
+func NewStreamController(logger *logger.Logger, streamService services.StreamService) StreamController {
+	return streamController{
+		logger:        *logger,
+		streamService: streamService,
+	}
+}
+
+type streamController struct {
+	logger        logger.Logger
+	streamService services.StreamService
+}
+
+func (s streamController) MasterPlaylist(w http.ResponseWriter, r *http.Request) {
+
+	playlist, err := s.streamService.MasterPlaylist(viewerFrom(r), mux.Vars(r)["id"])
+	if err != nil {
+		s.respondStreamError(w, err, "master playlist lookup failed")
+		return
+	}
+
+	s.respondPlaylist(w, playlist)
+}
+
+func (s streamController) MediaPlaylist(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+
+	playlist, err := s.streamService.MediaPlaylist(middleware.GetEthAddress(r.Context()), vars["id"], vars["rendition"], query.Get("session"), query.Get("expires"), query.Get("sig"))
+	if err != nil {
+		s.respondStreamError(w, err, "media playlist lookup failed")
+		return
+	}
+
+	s.respondPlaylist(w, playlist)
+}
+
+func (s streamController) Segment(w http.ResponseWriter, r *http.Request) {
+
+	extendDeadlines(w)
+
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+
+	file, err := s.streamService.OpenSegment(middleware.GetEthAddress(r.Context()), vars["id"], vars["rendition"], vars["segment"], query.Get("session"), query.Get("expires"), query.Get("sig"))
+	if err != nil {
+		s.respondStreamError(w, err, "segment download failed")
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "video/mp2t")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, no-store")
+
+	if seeker, ok := file.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", time.Time{}, seeker)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, file); err != nil {
+		s.logger.Warn("segment download interrupted", "media", vars["id"], "error", err)
+	}
+}
+
+func (s streamController) Key(w http.ResponseWriter, r *http.Request) {
+
+	query := r.URL.Query()
+
+	key, err := s.streamService.SegmentKey(viewerFrom(r), mux.Vars(r)["id"], query.Get("session"), query.Get("expires"), query.Get("sig"))
+	if err != nil {
+		s.respondStreamError(w, err, "segment key lookup failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(key)
+}
+
+func (s streamController) respondPlaylist(w http.ResponseWriter, playlist string) {
+
+	w.Header().Set("Content-Type", playlistContentType)
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, playlist)
+}
+
+func (s streamController) respondStreamError(w http.ResponseWriter, err error, msg string) {
+
+	switch {
+	case errors.Is(err, domain.ErrMediaForbidden), errors.Is(err, domain.ErrPostForbidden),
+		errors.Is(err, domain.ErrMediaLocked), errors.Is(err, domain.ErrMediaURLInvalid):
+		respondError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, domain.ErrMediaNotFound), errors.Is(err, domain.ErrPostNotFound),
+		errors.Is(err, domain.ErrStreamNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrMediaProcessing):
+		respondError(w, http.StatusConflict, err.Error())
+	default:
+		s.logger.Error(msg, "error", err)
+		respondError(w, http.StatusInternalServerError, SOMETHING_WENT_WRONG_MSG)
+	}
+}
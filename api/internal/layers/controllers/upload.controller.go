@@ -0,0 +1,252 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/internal/middleware"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// tusContentType is required on PATCH requests carrying upload bytes
+	tusContentType = "application/offset+octet-stream"
+
+	// statusChecksumMismatch is the tus status for a chunk failing its Upload-Checksum
+	statusChecksumMismatch = 460
+
+	// mediaIDHeader names the media file a completed upload was stored as
+	mediaIDHeader = "X-Media-Id"
+)
+
+// UploadController implements the tus 1.0 core protocol with the creation,
+// expiration, checksum and termination extensions
+type UploadController interface {
+	// Options advertises the protocol version, extensions and limits
+	Options(w http.ResponseWriter, r *http.Request)
+	// CreateUpload starts an upload of the length given in Upload-Length
+	CreateUpload(w http.ResponseWriter, r *http.Request)
+	// HeadUpload reports how many bytes were received so far
+	HeadUpload(w http.ResponseWriter, r *http.Request)
+	// PatchUpload appends the request body at Upload-Offset
+	PatchUpload(w http.ResponseWriter, r *http.Request)
+	// TerminateUpload drops an unfinished upload
+	TerminateUpload(w http.ResponseWriter, r *http.Request)
+}
+
+func NewUploadController(logger *logger.Logger, uploadService services.UploadService) UploadController {
+	return uploadController{
+		logger:        *logger,
+		uploadService: uploadService,
+	}
+}
+
+type uploadController struct {
+	logger        logger.Logger
+	uploadService services.UploadService
+}
+
+func (u uploadController) Options(w http.ResponseWriter, r *http.Request) {
+
+	w.Header().Set("Tus-Version", middleware.TusVersion)
+	w.Header().Set("Tus-Extension", "creation,expiration,checksum,termination")
+	w.Header().Set("Tus-Checksum-Algorithm", strings.Join(services.ChecksumAlgorithms, ","))
+	if maxSize := u.uploadService.MaxSize(); maxSize > 0 {
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(maxSize, 10))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (u uploadController) CreateUpload(w http.ResponseWriter, r *http.Request) {
+
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		u.logger.Error("request header parsing failed for upload creation", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	raw := r.Header.Get("Upload-Metadata")
+	metadata, err := parseUploadMetadata(raw)
+	if err != nil {
+		u.logger.Error("request header parsing failed for upload creation", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	filename := metadata["filename"]
+	if filename == "" {
+		filename = metadata["name"]
+	}
+
+	upload, err := u.uploadService.CreateUpload(middleware.GetEthAddress(r.Context()), domain.UploadInput{
+		Length:   length,
+		Metadata: raw,
+		Filename: filename,
+		PostID:   metadata["post_id"],
+	})
+	if err != nil {
+		u.respondUploadError(w, err, "upload creation failed")
+		return
+	}
+
+	w.Header().Set("Location", "/v1/uploads/"+upload.ID)
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (u uploadController) HeadUpload(w http.ResponseWriter, r *http.Request) {
+
+	upload, err := u.uploadService.GetUpload(middleware.GetEthAddress(r.Context()), mux.Vars(r)["id"])
+	if err != nil {
+		u.respondUploadError(w, err, "upload lookup failed")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	if upload.Metadata != "" {
+		w.Header().Set("Upload-Metadata", upload.Metadata)
+	}
+	u.setProgress(w, upload)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (u uploadController) PatchUpload(w http.ResponseWriter, r *http.Request) {
+
+	extendDeadlines(w)
+
+	if r.Header.Get("Content-Type") != tusContentType {
+		respondError(w, http.StatusUnsupportedMediaType, INVALID_REQUEST_MSG)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		u.logger.Error("request header parsing failed for upload chunk", "error", err)
+		respondError(w, http.StatusBadRequest, INVALID_REQUEST_MSG)
+		return
+	}
+
+	checksum, err := parseUploadChecksum(r.Header.Get("Upload-Checksum"))
+	if err != nil {
+		u.respondUploadError(w, err, "request header parsing failed for upload chunk")
+		return
+	}
+
+	upload, err := u.uploadService.WriteChunk(middleware.GetEthAddress(r.Context()), mux.Vars(r)["id"], offset, checksum, r.Body)
+	if err != nil {
+		u.respondUploadError(w, err, "upload chunk failed")
+		return
+	}
+
+	u.setProgress(w, upload)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (u uploadController) TerminateUpload(w http.ResponseWriter, r *http.Request) {
+
+	err := u.uploadService.TerminateUpload(middleware.GetEthAddress(r.Context()), mux.Vars(r)["id"])
+	if err != nil {
+		u.respondUploadError(w, err, "upload termination failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setProgress writes the offset, and either the expiry of an unfinished upload or the
+// media file a completed one became
+func (u uploadController) setProgress(w http.ResponseWriter, upload *domain.Upload) {
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+
+	if upload.MediaID != nil {
+		w.Header().Set(mediaIDHeader, *upload.MediaID)
+		return
+	}
+
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.Format(http.TimeFormat))
+}
+
+func (u uploadController) respondUploadError(w http.ResponseWriter, err error, msg string) {
+
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.Is(err, domain.ErrMediaEmpty), errors.Is(err, domain.ErrUnsupportedChecksum),
+		errors.Is(err, domain.ErrUploadExceedsLength):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrUnsupportedMediaType):
+		respondError(w, http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, domain.ErrUploadTooLarge), errors.Is(err, domain.ErrStorageQuotaExceeded),
+		errors.As(err, &maxBytesErr):
+		respondError(w, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, domain.ErrUploadForbidden), errors.Is(err, domain.ErrPostForbidden):
+		respondError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, domain.ErrUploadNotFound), errors.Is(err, domain.ErrPostNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrUploadOffsetMismatch):
+		respondError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, domain.ErrUploadExpired):
+		respondError(w, http.StatusGone, err.Error())
+	case errors.Is(err, domain.ErrUploadChecksumMismatch):
+		respondError(w, statusChecksumMismatch, err.Error())
+	default:
+		u.logger.Error(msg, "error", err)
+		respondError(w, http.StatusInternalServerError, SOMETHING_WENT_WRONG_MSG)
+	}
+}
+
+// parseUploadMetadata reads the comma separated "key base64value" pairs of an
+// Upload-Metadata header, values may be omitted
+func parseUploadMetadata(raw string) (map[string]string, error) {
+
+	metadata := make(map[string]string)
+
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, err
+		}
+
+		metadata[key] = string(value)
+	}
+
+	return metadata, nil
+}
+
+// parseUploadChecksum reads an "algorithm base64digest" Upload-Checksum header, nil
+// when the header is absent
+func parseUploadChecksum(raw string) (*domain.UploadChecksum, error) {
+
+	if raw == "" {
+		return nil, nil
+	}
+
+	algorithm, encoded, ok := strings.Cut(raw, " ")
+	if !ok {
+		return nil, domain.ErrUnsupportedChecksum
+	}
+
+	sum, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, domain.ErrUploadChecksumMismatch
+	}
+
+	return &domain.UploadChecksum{Algorithm: algorithm, Sum: sum}, nil
+}
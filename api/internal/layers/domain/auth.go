@@ -21,11 +21,15 @@ type CreateNonceDTO struct {
 type VerifyRequest struct {
 	Message   string `json:"message"`
 	Signature string `json:"signature"`
+	// Invite is the token of an invite link to redeem once signed in
+	Invite string `json:"invite,omitempty"`
 }
 
 type VerifyResponse struct {
 	Valid bool   `json:"valid"`
 	Error string `json:"error,omitempty"`
+	// Invite is the outcome of redeeming the invite sent along
+	Invite *InviteRedemption `json:"invite,omitempty"`
 }
 
 // ParseSIWEMessage parses a SIWE message string
@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	ChatMessageMaxLength = 2000
+
+	// ChatNotifyChannel is the postgres channel chat events are fanned out on, the
+	// NotifyChat query publishes to it
+	ChatNotifyChannel = "chat_events"
+
+	// chat events sent to clients
+	ChatEventMessage  = "message"
+	ChatEventTyping   = "typing"
+	ChatEventPresence = "presence"
+	// ChatEventPresenceState lists who is online, it is the first event of a session
+	ChatEventPresenceState = "presence_state"
+	// ChatEventResync tells clients events may have been missed and history should be
+	// reloaded
+	ChatEventResync = "resync"
+	ChatEventError  = "error"
+	// ChatEventClosed is the last event of a session ended by the server
+	ChatEventClosed = "closed"
+
+	// commands sent by clients
+	ChatCommandMessage = "message"
+	ChatCommandTyping  = "typing"
+)
+
+var (
+	ErrChatMessageInvalid = errors.New("chat message is invalid")
+	// ErrChatRateLimited is returned to clients sending faster than the channel allows
+	ErrChatRateLimited = errors.New("sending too fast")
+	// ErrChatTooSlow ends sessions whose client does not keep up with the channel
+	ErrChatTooSlow = errors.New("connection is too slow to keep up")
+	ErrChatClosed  = errors.New("chat session is closed")
+)
+
+type ChatMessage struct {
+	ID            string    `json:"id"`
+	CommunityID   string    `json:"community_id"`
+	AuthorAddress string    `json:"author_address"`
+	AuthorHandle  string    `json:"author_handle,omitempty"`
+	Body          string    `json:"body"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ChatEvent is a frame sent to chat clients, and the payload replicas fan out to each
+// other. Fields are set by event type
+type ChatEvent struct {
+	Type        string       `json:"type"`
+	CommunityID string       `json:"community_id,omitempty"`
+	Message     *ChatMessage `json:"message,omitempty"`
+	// Address is who is typing, or whose presence changed
+	Address string `json:"address,omitempty"`
+	Online  bool   `json:"online,omitempty"`
+	// Members are the addresses online in a presence_state event
+	Members []string `json:"members,omitempty"`
+	// Reason explains error and closed events
+	Reason string `json:"reason,omitempty"`
+
+	// MessageID replaces Message between replicas when the message is too large for a
+	// notification, receivers load it from the database
+	MessageID string `json:"message_id,omitempty"`
+}
+
+// Droppable reports if the event can be skipped for a client falling behind, missing
+// one of the others would leave the client out of sync
+func (e ChatEvent) Droppable() bool {
+	return e.Type == ChatEventTyping || e.Type == ChatEventPresence
+}
+
+// ChatCommand is a frame received from chat clients
+type ChatCommand struct {
+	Type string `json:"type"`
+	Body string `json:"body,omitempty"`
+}
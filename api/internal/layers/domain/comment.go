@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	CommentMaxLength = 5000
+
+	// CommentMaxDepth bounds how deep replies nest, top level comments have depth 0
+	CommentMaxDepth = 8
+
+	// CommentMaxMentions bounds how many accounts a single comment can mention
+	CommentMaxMentions = 20
+
+	// ReactionMaxKinds bounds how many different emoji an account adds to one post or
+	// comment
+	ReactionMaxKinds = 5
+)
+
+var (
+	ErrCommentNotFound  = errors.New("comment not found")
+	ErrCommentForbidden = errors.New("comment belongs to another account")
+	ErrCommentInvalid   = errors.New("comment is invalid")
+	ErrCommentDeleted   = errors.New("comment was deleted")
+	// ErrPostLocked is returned for discussion of gated posts the account can not read
+	ErrPostLocked       = errors.New("post is locked for this account")
+	ErrReactionInvalid  = errors.New("reaction must be a single emoji")
+	ErrReactionLimit    = errors.New("too many different reactions")
+	ErrReactionNotFound = errors.New("reaction not found")
+
+	mentionRegex = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z0-9_]{3,30})\b`)
+)
+
+// Comment is a comment under a post. Body is blank for deleted comments, except for
+// moderated comments shown to the moderator who can restore them
+type Comment struct {
+	ID            string          `json:"id"`
+	PostID        string          `json:"post_id"`
+	ParentID      string          `json:"parent_id,omitempty"`
+	Depth         int             `json:"depth"`
+	AuthorAddress string          `json:"author_address"`
+	AuthorHandle  string          `json:"author_handle,omitempty"`
+	Body          string          `json:"body"`
+	Mentions      []string        `json:"mentions,omitempty"`
+	ReplyCount    int             `json:"reply_count"`
+	Reactions     []ReactionCount `json:"reactions,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	EditedAt      *time.Time      `json:"edited_at,omitempty"`
+	DeletedAt     *time.Time      `json:"deleted_at,omitempty"`
+	// Moderated tells a comment removed by a moderator from one its author deleted
+	Moderated bool `json:"moderated,omitempty"`
+}
+
+func (c Comment) IsDeleted() bool {
+	return c.DeletedAt != nil
+}
+
+// ReactionCount is how many accounts added an emoji and whether the viewer is one of them
+type ReactionCount struct {
+	Emoji   string `json:"emoji"`
+	Count   int    `json:"count"`
+	Reacted bool   `json:"reacted"`
+}
+
+// ParseMentions returns the handles mentioned in a body as "@handle", normalized and
+// without duplicates, in order of appearance
+func ParseMentions(body string) []string {
+
+	var handles []string
+	seen := make(map[string]struct{})
+
+	for _, match := range mentionRegex.FindAllStringSubmatch(body, -1) {
+		handle, err := NormalizeHandle(match[1])
+		if err != nil {
+			continue
+		}
+		if _, ok := seen[handle]; ok {
+			continue
+		}
+		seen[handle] = struct{}{}
+		handles = append(handles, handle)
+	}
+
+	return handles
+}
+
+// IsEmoji reports if s is a single emoji, including sequences joined by zero width
+// joiners, skin tone modifiers and flags
+func IsEmoji(s string) bool {
+
+	if s == "" || len(s) > 32 || !utf8.ValidString(s) {
+		return false
+	}
+
+	pictographs := 0
+	for _, r := range s {
+		switch {
+		case r == '\u200d', r == '\ufe0f', r >= 0x1f3fb && r <= 0x1f3ff:
+			// joiner, presentation selector and skin tones only modify a pictograph
+		case r >= 0x1f1e6 && r <= 0x1f1ff:
+			// regional indicators pair up into flags
+			pictographs++
+		case unicode.Is(unicode.So, r), r >= 0x1f000 && r <= 0x1faff:
+			pictographs++
+		default:
+			return false
+		}
+	}
+
+	// a flag is two regional indicators, sequences join several pictographs
+	return pictographs > 0 && (pictographs == 1 || strings.ContainsRune(s, '\u200d') || isFlag(s))
+}
+
+func isFlag(s string) bool {
+
+	runes := []rune(s)
+	return len(runes) == 2 && runes[0] >= 0x1f1e6 && runes[0] <= 0x1f1ff && runes[1] >= 0x1f1e6 && runes[1] <= 0x1f1ff
+}
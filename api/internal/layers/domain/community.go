@@ -0,0 +1,136 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	JoinPolicyOpen       = "open"
+	JoinPolicyTokenGated = "token_gated"
+	JoinPolicyPaid       = "paid"
+	JoinPolicyInviteOnly = "invite_only"
+
+	// GrantedViaOwner and GrantedViaInvite complete the join policies as the ways a
+	// membership can be granted
+	GrantedViaOwner  = "owner"
+	GrantedViaInvite = "invite"
+
+	RoleOwner     = "owner"
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+	RoleMember    = "member"
+
+	CommunitySlugMinLength = 3
+	CommunitySlugMaxLength = 40
+)
+
+var (
+	ErrCommunityNotFound  = errors.New("community not found")
+	ErrCommunityForbidden = errors.New("community action is not allowed for this role")
+	ErrCommunitySlugTaken = errors.New("community slug is already taken")
+	ErrCommunityInvalid   = errors.New("community is invalid")
+	ErrAlreadyMember      = errors.New("account is already a member")
+	ErrMemberNotFound     = errors.New("member not found")
+	ErrJoinDenied         = errors.New("join policy is not satisfied")
+	ErrInviteOnly         = errors.New("community can only be joined with an invite")
+	// ErrPaymentNotFound is returned for payments which are not indexed yet, the join can
+	// be retried once the indexer caught up
+	ErrPaymentNotFound  = errors.New("payment transfer not found")
+	ErrPaymentInvalid   = errors.New("payment does not match the community price")
+	ErrOwnerCannotLeave = errors.New("the owner can not leave the community")
+	// ErrMembershipRevoked is returned once a member no longer satisfies the access
+	// policy their membership was granted under, the membership is removed
+	ErrMembershipRevoked = errors.New("membership was revoked, the access policy is no longer satisfied")
+
+	communitySlugRegex = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+)
+
+// roleRanks orders roles by the permissions they hold
+var roleRanks = map[string]int{
+	RoleOwner:     4,
+	RoleAdmin:     3,
+	RoleModerator: 2,
+	RoleMember:    1,
+}
+
+// IsRole reports if role is one of the community roles
+func IsRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// RoleOutranks reports if role holds more permissions than other
+func RoleOutranks(role, other string) bool {
+	return roleRanks[role] > roleRanks[other]
+}
+
+// NormalizeCommunitySlug lowercases a slug and checks it is made of words joined by
+// single dashes
+func NormalizeCommunitySlug(slug string) (string, bool) {
+
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	if len(slug) < CommunitySlugMinLength || len(slug) > CommunitySlugMaxLength {
+		return "", false
+	}
+
+	return slug, communitySlugRegex.MatchString(slug)
+}
+
+type Community struct {
+	ID           string          `json:"id"`
+	Slug         string          `json:"slug"`
+	Name         string          `json:"name"`
+	Description  string          `json:"description"`
+	OwnerAddress string          `json:"owner_address"`
+	JoinPolicy   string          `json:"join_policy"`
+	AccessPolicy json.RawMessage `json:"access_policy,omitempty"`
+	Price        *CommunityPrice `json:"price,omitempty"`
+	MemberCount  int             `json:"member_count"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+
+	// Membership is the viewer's membership, nil for anonymous viewers and non members
+	Membership *CommunityMember `json:"membership,omitempty"`
+}
+
+// CommunityPrice is what a paid community costs to join, an ERC-20 transfer of Amount
+// base units of Token to the owner
+type CommunityPrice struct {
+	ChainID uint64 `json:"chain_id"`
+	Token   string `json:"token"`
+	Amount  string `json:"amount"`
+}
+
+// CommunityInput holds the editable fields of a community
+type CommunityInput struct {
+	Slug         string
+	Name         string
+	Description  string
+	JoinPolicy   string
+	AccessPolicy json.RawMessage
+	Price        *CommunityPrice
+}
+
+// CommunityMember is an account's membership. GrantedVia, GrantedBlocks and PaymentTx
+// describe the chain state the membership was granted under
+type CommunityMember struct {
+	CommunityID string `json:"community_id"`
+	Address     string `json:"address"`
+	Handle      string `json:"handle,omitempty"`
+	Role        string `json:"role"`
+	GrantedVia  string `json:"granted_via"`
+	// GrantedBlocks maps the chains of the join policy to their head block when it was
+	// evaluated
+	GrantedBlocks map[uint64]uint64 `json:"granted_blocks,omitempty"`
+	PaymentTx     string            `json:"payment_tx,omitempty"`
+	InvitedBy     string            `json:"invited_by,omitempty"`
+	InviteID      string            `json:"invite_id,omitempty"`
+	JoinedAt      time.Time         `json:"joined_at"`
+
+	// GrantedPolicy is the access policy evaluated on join
+	GrantedPolicy json.RawMessage `json:"-"`
+}
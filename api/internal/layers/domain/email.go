@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrEmailDisabled           = errors.New("email notifications are not configured")
+	ErrEmailNotFound           = errors.New("no email address is set")
+	ErrEmailInvalid            = errors.New("email address is invalid")
+	ErrEmailTokenInvalid       = errors.New("email verification token is invalid or expired")
+	ErrDigestInvalid           = errors.New("digest frequency is invalid")
+	ErrUnsubscribeTokenInvalid = errors.New("unsubscribe token is invalid")
+)
+
+// How often an account is sent a summary of its notifications
+const (
+	EmailDigestOff    = "off"
+	EmailDigestDaily  = "daily"
+	EmailDigestWeekly = "weekly"
+)
+
+// EmailUnsubscribeDigest and EmailUnsubscribeAll are the scopes of unsubscribe links
+// besides the notification kinds, which switch off the emails of one kind
+const (
+	EmailUnsubscribeDigest = "digest"
+	EmailUnsubscribeAll    = "all"
+)
+
+// AccountEmail is the address an account is mailed at, nothing is sent before it was
+// verified through the link mailed to it
+type AccountEmail struct {
+	Address      string     `json:"-"`
+	Email        string     `json:"email"`
+	VerifiedAt   *time.Time `json:"verified_at"`
+	Digest       string     `json:"digest"`
+	DigestSentAt *time.Time `json:"digest_sent_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (e AccountEmail) IsVerified() bool {
+	return e.VerifiedAt != nil
+}
+
+// EmailDelivery is a notification waiting to be mailed. Email is empty when the
+// address was removed since it was queued
+type EmailDelivery struct {
+	ID           int64
+	Attempts     int
+	Email        string
+	Notification Notification
+}
+
+// EmailDigest is a summary due for an account, it covers the notifications since
+// Since
+type EmailDigest struct {
+	Address   string
+	Email     string
+	Frequency string
+	Since     time.Time
+}
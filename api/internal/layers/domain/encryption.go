@@ -0,0 +1,24 @@
+package domain
+
+import "errors"
+
+// ErrContentSealed is returned when encrypted content is read without the master key
+// its data key was wrapped by
+var ErrContentSealed = errors.New("content is encrypted with an unavailable key")
+
+// WrappedKey is the data key of a record, encrypted by the master key KeyID
+type WrappedKey struct {
+	KeyID string
+	Key   []byte
+}
+
+// ReencryptReport counts what a re-encryption pass changed
+type ReencryptReport struct {
+	PostsSealed      int `json:"posts_sealed"`
+	PostsRewrapped   int `json:"posts_rewrapped"`
+	RevisionsSealed  int `json:"revisions_sealed"`
+	MediaSealed      int `json:"media_sealed"`
+	MediaRewrapped   int `json:"media_rewrapped"`
+	UploadsRewrapped int `json:"uploads_rewrapped"`
+	Failed           int `json:"failed"`
+}
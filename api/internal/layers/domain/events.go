@@ -0,0 +1,57 @@
+package domain
+
+// Events are published on the event bus by the services producing them, consumers
+// subscribe by type
+
+// NotificationsCreated is published once notifications were stored, delivery channels
+// other than the in-app stream pick them up from there
+type NotificationsCreated struct {
+	Notifications []Notification
+}
+
+func (NotificationsCreated) Topic() string { return "notifications.created" }
+
+type PostPublished struct {
+	Post Post
+}
+
+func (PostPublished) Topic() string { return "post.published" }
+
+// CommentCreated carries the authors replied to along with the comment, ParentAuthor
+// is empty for top level comments
+type CommentCreated struct {
+	Comment      Comment
+	PostAuthor   string
+	PostTitle    string
+	ParentAuthor string
+}
+
+func (CommentCreated) Topic() string { return "comment.created" }
+
+// PaymentReceived is published once a payment bought a membership of a paid community
+type PaymentReceived struct {
+	Community Community
+	Payer     string
+	PaymentTx string
+}
+
+func (PaymentReceived) Topic() string { return "payment.received" }
+
+// TransfersIndexed lists the accounts which sent tokens in a range applied by the
+// indexer
+type TransfersIndexed struct {
+	ChainID uint64
+	Senders []string
+}
+
+func (TransfersIndexed) Topic() string { return "transfers.indexed" }
+
+// MembershipExpiring is published for members who stopped satisfying the policy their
+// membership was granted under
+type MembershipExpiring struct {
+	Community Community
+	Member    CommunityMember
+	Reason    string
+}
+
+func (MembershipExpiring) Topic() string { return "membership.expiring" }
@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// FeedRankingBoosted moves posts with reactions and comments ahead of newer ones,
+	// FeedRankingRecent orders by publish time alone
+	FeedRankingBoosted = "boosted"
+	FeedRankingRecent  = "recent"
+)
+
+var ErrFeedRankingInvalid = errors.New("feed ranking is invalid")
+
+// FeedItem is a post of the home feed as seen by its reader. The engagement counts are
+// those the ranking used
+type FeedItem struct {
+	PostView
+	ReactionCount int `json:"reaction_count"`
+	CommentCount  int `json:"comment_count"`
+}
+
+// FeedEntry is a post of the home feed as stored, along with its ranking
+type FeedEntry struct {
+	Post          Post
+	ReactionCount int
+	CommentCount  int
+	Score         float64
+}
+
+// FeedCursor points past the last item of a feed page. Engagement is counted as of
+// AsOf and weighted by BoostSeconds for every page, so scores hold still while a reader
+// pages through the feed
+type FeedCursor struct {
+	Score        float64
+	ID           string
+	AsOf         time.Time
+	BoostSeconds float64
+}
+
+// Encode returns the opaque form of the cursor handed to clients
+func (c FeedCursor) Encode() string {
+	raw := strings.Join([]string{
+		strconv.FormatFloat(c.Score, 'g', -1, 64),
+		c.ID,
+		c.AsOf.UTC().Format(time.RFC3339Nano),
+		strconv.FormatFloat(c.BoostSeconds, 'g', -1, 64),
+	}, "|")
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeFeedCursor parses a cursor handed out by Encode
+func DecodeFeedCursor(s string) (FeedCursor, error) {
+
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return FeedCursor{}, ErrCursorInvalid
+	}
+
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 4 {
+		return FeedCursor{}, ErrCursorInvalid
+	}
+
+	score, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return FeedCursor{}, ErrCursorInvalid
+	}
+
+	asOf, err := time.Parse(time.RFC3339Nano, parts[2])
+	if err != nil {
+		return FeedCursor{}, ErrCursorInvalid
+	}
+
+	boost, err := strconv.ParseFloat(parts[3], 64)
+	if err != nil || boost < 0 {
+		return FeedCursor{}, ErrCursorInvalid
+	}
+
+	return FeedCursor{Score: score, ID: parts[1], AsOf: asOf, BoostSeconds: boost}, nil
+}
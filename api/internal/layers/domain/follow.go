@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	RestrictionBlock = "block"
+	RestrictionMute  = "mute"
+
+	// FollowImportMax caps the addresses of a single bulk import
+	FollowImportMax = 1000
+)
+
+var (
+	ErrFollowSelf         = errors.New("accounts can not follow themselves")
+	ErrFollowBlocked      = errors.New("follow is not possible while either account blocks the other")
+	ErrFollowNotFound     = errors.New("follow not found")
+	ErrRestrictionSelf    = errors.New("accounts can not block or mute themselves")
+	ErrRestrictionInvalid = errors.New("restriction kind is invalid")
+	ErrRestrictionExists  = errors.New("account is already restricted")
+	ErrRestrictionMissing = errors.New("restriction not found")
+	ErrFollowImportEmpty  = errors.New("follow import has no addresses")
+	ErrFollowImportSize   = errors.New("follow import has too many addresses")
+	ErrFollowImportFormat = errors.New("follow import holds an invalid address")
+)
+
+// AccountEntry is an account in a follower, following, block or mute list. Since is
+// when the relationship started
+type AccountEntry struct {
+	Address string    `json:"address"`
+	Handle  string    `json:"handle,omitempty"`
+	Since   time.Time `json:"since"`
+}
+
+// Relationship is how the viewer relates to another account
+type Relationship struct {
+	Following  bool `json:"following"`
+	FollowedBy bool `json:"followed_by"`
+	Blocking   bool `json:"blocking"`
+	Muting     bool `json:"muting"`
+	BlockedBy  bool `json:"blocked_by"`
+}
+
+// FollowStats are the counts of an account, Relationship is nil for anonymous viewers
+// and the account itself
+type FollowStats struct {
+	Address        string        `json:"address"`
+	FollowerCount  int           `json:"follower_count"`
+	FollowingCount int           `json:"following_count"`
+	Relationship   *Relationship `json:"relationship,omitempty"`
+}
+
+// FollowImport reports a bulk import. Skipped counts addresses already followed,
+// blocked either way or the importer's own
+type FollowImport struct {
+	Followed int `json:"followed"`
+	Skipped  int `json:"skipped"`
+}
+
+// IsRestriction reports if kind is RestrictionBlock or RestrictionMute
+func IsRestriction(kind string) bool {
+	return kind == RestrictionBlock || kind == RestrictionMute
+}
@@ -0,0 +1,152 @@
+package domain
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	HandleMinLength = 3
+	HandleMaxLength = 30
+
+	// HandleReleaseAfter is how long a retired handle keeps redirecting to its
+	// owner before anyone else can claim it
+	HandleReleaseAfter = 90 * 24 * time.Hour
+)
+
+var (
+	ErrHandleInvalid   = errors.New("handle is invalid")
+	ErrHandleReserved  = errors.New("handle is reserved")
+	ErrHandleTaken     = errors.New("handle is already taken")
+	ErrHandleNotFound  = errors.New("handle not found")
+	ErrHandleUnchanged = errors.New("handle is already in use by this account")
+
+	handleRegex = regexp.MustCompile(`^[a-z0-9_]+$`)
+)
+
+// reservedHandles are words that could be mistaken for product pages or staff accounts.
+// They are stored as skeletons so that look-alikes such as "adm1n" are rejected too
+var reservedHandles = map[string]struct{}{}
+
+func init() {
+	for _, word := range []string{
+		"about", "account", "admin", "administrator", "api", "app", "auth", "billing",
+		"blog", "communities", "community", "create", "creator", "dashboard", "docs",
+		"explore", "feed", "help", "home", "jibe", "login", "logout", "me", "mod",
+		"moderator", "notifications", "official", "payments", "posts", "privacy",
+		"profile", "root", "search", "security", "settings", "share", "signin",
+		"signout", "signup", "staff", "status", "support", "system", "team", "terms",
+		"uploads", "user", "verify", "wallet", "www",
+	} {
+		reservedHandles[HandleSkeleton(word)] = struct{}{}
+	}
+}
+
+// confusables folds characters that render like ascii letters onto those letters, so
+// a handle typed with a cyrillic "а" is treated the same as one typed with a latin "a"
+var confusables = map[rune]rune{
+	// cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'һ': 'h', 'і': 'i', 'ї': 'i', 'ј': 'j',
+	'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p', 'с': 'c', 'т': 't', 'у': 'y',
+	'х': 'x', 'ѕ': 's', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w', 'ɡ': 'g',
+	// greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p',
+	'τ': 't', 'υ': 'u', 'χ': 'x', 'ω': 'w',
+	// latin look-alikes
+	'ı': 'i', 'ł': 'l', 'ø': 'o', 'ß': 's', 'ſ': 's',
+	// separators people commonly type instead of an underscore
+	'-': '_', '.': '_',
+}
+
+// NormalizeHandle turns user input such as "@Alice" into the canonical stored form
+// "alice", folding confusable characters and validating length and charset
+func NormalizeHandle(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	raw = strings.TrimPrefix(raw, "@")
+
+	var b strings.Builder
+	for _, r := range raw {
+		r = unicode.ToLower(r)
+		if folded, ok := confusables[r]; ok {
+			r = folded
+		}
+		// fullwidth forms, eg. "ａ", map onto ascii by a fixed offset
+		if r >= 0xFF01 && r <= 0xFF5E {
+			r = r - 0xFF01 + 0x21
+		}
+		b.WriteRune(r)
+	}
+
+	handle := b.String()
+
+	if len(handle) < HandleMinLength || len(handle) > HandleMaxLength {
+		return "", ErrHandleInvalid
+	}
+
+	if !handleRegex.MatchString(handle) || strings.HasPrefix(handle, "0x") {
+		return "", ErrHandleInvalid
+	}
+
+	if IsReservedHandle(handle) {
+		return "", ErrHandleReserved
+	}
+
+	return handle, nil
+}
+
+// HandleSkeleton reduces a normalized handle to the form used for uniqueness so that
+// "alice_1", "a1ice1" and "alicel" can not be held by different accounts
+func HandleSkeleton(handle string) string {
+	handle = strings.ReplaceAll(handle, "_", "")
+	handle = strings.ReplaceAll(handle, "rn", "m")
+	handle = strings.ReplaceAll(handle, "vv", "w")
+
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '0':
+			return 'o'
+		case '1', 'i':
+			return 'l'
+		case '5':
+			return 's'
+		}
+		return r
+	}, handle)
+}
+
+// IsReservedHandle reports if the handle, or a look-alike of it, is reserved
+func IsReservedHandle(handle string) bool {
+	_, ok := reservedHandles[HandleSkeleton(handle)]
+	return ok
+}
+
+type Handle struct {
+	Handle     string     `json:"handle"`
+	EthAddress string     `json:"eth_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RetiredAt  *time.Time `json:"retired_at,omitempty"`
+}
+
+func (h Handle) IsActive() bool {
+	return h.RetiredAt == nil
+}
+
+// HandleResolution is the result of looking up a handle or an address
+type HandleResolution struct {
+	EthAddress string `json:"eth_address"`
+	Handle     string `json:"handle,omitempty"`
+	// RedirectTo is set when the looked up handle was retired by its owner
+	RedirectTo string `json:"redirect_to,omitempty"`
+	ShareURL   string `json:"share_url"`
+}
+
+// ShareURL builds the public profile link of an account, preferring its handle
+func ShareURL(domain, handle, ethAddress string) string {
+	if handle != "" {
+		return "https://" + domain + "/@" + handle
+	}
+	return "https://" + domain + "/" + ethAddress
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"errors"
+	"math/big"
+	"time"
+)
+
+const (
+	TokenKindERC20   = "erc20"
+	TokenKindERC721  = "erc721"
+	TokenKindERC1155 = "erc1155"
+)
+
+// ZeroAddress is the sender of mints and the receiver of burns
+const ZeroAddress = "0x0000000000000000000000000000000000000000"
+
+var (
+	ErrCheckpointNotFound      = errors.New("indexer checkpoint not found")
+	ErrIndexedContractNotFound = errors.New("contract is not indexed")
+	// ErrHistoryUnavailable is returned when the indexer has not covered the blocks a
+	// historical condition needs
+	ErrHistoryUnavailable = errors.New("token history is not indexed for the requested blocks")
+)
+
+// BlockRef identifies a block by number and hash
+type BlockRef struct {
+	Number uint64
+	Hash   string
+}
+
+// IndexedContract is a token contract followed by the indexer. BackfilledTo is the
+// last block whose logs were applied for it
+type IndexedContract struct {
+	ChainID      uint64
+	Address      string
+	TokenKind    string
+	BackfilledTo uint64
+}
+
+// TokenTransfer is a single balance movement decoded from a transfer log. A
+// TransferBatch log yields one transfer per id, told apart by BatchIndex
+type TokenTransfer struct {
+	ChainID     uint64
+	Contract    string
+	TokenKind   string
+	TokenID     *big.Int
+	From        string
+	To          string
+	Amount      *big.Int
+	BlockNumber uint64
+	BlockHash   string
+	BlockTime   *time.Time
+	TxHash      string
+	LogIndex    uint
+	BatchIndex  int
+}
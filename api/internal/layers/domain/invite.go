@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInviteNotFound = errors.New("invite not found")
+	// ErrInviteExpired is returned for links which were revoked, used up, expired or
+	// whose inviter left the community
+	ErrInviteExpired = errors.New("invite is no longer valid")
+)
+
+// CommunityInvite is a shareable link into a community. Invites created by admins admit
+// regardless of the join policy, those of other members are referrals which only
+// attribute joins. AccessPolicy is checked for the redeemer on top. Uses is the count
+// redemptions claim against MaxUses
+type CommunityInvite struct {
+	ID             string          `json:"id"`
+	CommunityID    string          `json:"community_id"`
+	InviterAddress string          `json:"inviter_address"`
+	URL            string          `json:"url,omitempty"`
+	MaxUses        *int            `json:"max_uses,omitempty"`
+	Uses           int             `json:"uses"`
+	ExpiresAt      *time.Time      `json:"expires_at,omitempty"`
+	AccessPolicy   json.RawMessage `json:"access_policy,omitempty"`
+	Stats          InviteStats     `json:"stats"`
+	RevokedAt      *time.Time      `json:"revoked_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+
+	// Code identifies the invite in its link, which carries it along with a signature
+	Code string `json:"-"`
+}
+
+// IsUsable reports if the invite can still be redeemed at now
+func (i CommunityInvite) IsUsable(now time.Time) bool {
+
+	if i.RevokedAt != nil {
+		return false
+	}
+	if i.ExpiresAt != nil && !now.Before(*i.ExpiresAt) {
+		return false
+	}
+
+	return i.MaxUses == nil || i.Uses < *i.MaxUses
+}
+
+// InviteStats counts what happened through a link. Joins is also the number of uses
+type InviteStats struct {
+	Clicks  int `json:"clicks"`
+	SignIns int `json:"sign_ins"`
+	Joins   int `json:"joins"`
+}
+
+// InviteInput holds the settings of a new invite, all of them optional
+type InviteInput struct {
+	MaxUses      *int
+	ExpiresAt    *time.Time
+	AccessPolicy json.RawMessage
+}
+
+// InvitePreview is what the recipient of a link sees before redeeming it
+type InvitePreview struct {
+	Community      Community  `json:"community"`
+	InviterAddress string     `json:"inviter_address"`
+	InviterHandle  string     `json:"inviter_handle,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	// RemainingUses is nil for links without a limit
+	RemainingUses *int `json:"remaining_uses,omitempty"`
+	// Admits tells if the link skips the join policy of the community
+	Admits bool `json:"admits"`
+	Usable bool `json:"usable"`
+}
+
+// InviteRedemption is the outcome of redeeming an invite while signing in, a failed
+// redemption does not fail the sign-in
+type InviteRedemption struct {
+	Member *CommunityMember `json:"member,omitempty"`
+	Error  string           `json:"error,omitempty"`
+}
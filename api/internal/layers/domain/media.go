@@ -0,0 +1,204 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrMediaNotFound        = errors.New("media not found")
+	ErrMediaForbidden       = errors.New("media belongs to another account")
+	ErrMediaLocked          = errors.New("media is locked by the post's access policy")
+	ErrMediaTooLarge        = errors.New("media exceeds the upload size limit")
+	ErrUnsupportedMediaType = errors.New("media type is not supported")
+	ErrMediaURLInvalid      = errors.New("media url is invalid or expired")
+	ErrMediaEmpty           = errors.New("media upload is empty")
+	ErrStorageQuotaExceeded = errors.New("storage quota exceeded")
+	ErrMediaProcessing      = errors.New("media is still being processed")
+	ErrVariantNotFound      = errors.New("media variant not found")
+	ErrStreamNotFound       = errors.New("media has no stream")
+)
+
+// Processing states of a media file. Images are pending until their metadata was
+// stripped and variants rendered, other files are never processed
+const (
+	MediaProcessingNone       = "none"
+	MediaProcessingPending    = "pending"
+	MediaProcessingProcessing = "processing"
+	MediaProcessingReady      = "ready"
+	MediaProcessingFailed     = "failed"
+)
+
+// StreamedVideoTypes are the uploads packaged into HLS streams when a transcoder is
+// configured
+var StreamedVideoTypes = map[string]bool{
+	"video/mp4":       true,
+	"video/webm":      true,
+	"video/quicktime": true,
+}
+
+// VariantPreview names the strongly blurred rendition shown to readers a post is
+// locked for
+const VariantPreview = "preview"
+
+// ProcessedImageTypes are the uploads handed to the image pipeline
+var ProcessedImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// AllowedMediaTypes are the content types accepted for upload, detected from the
+// file content rather than trusted from the client
+var AllowedMediaTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+	"audio/mpeg":      true,
+	"audio/ogg":       true,
+	"audio/wav":       true,
+	"audio/mp4":       true,
+	"audio/x-m4a":     true,
+	"audio/flac":      true,
+	"video/mp4":       true,
+	"video/webm":      true,
+	"video/quicktime": true,
+	"application/zip": true,
+}
+
+// MediaFile is an uploaded file. Files linked to a post follow its access policy
+type MediaFile struct {
+	ID           string    `json:"id"`
+	OwnerAddress string    `json:"owner_address"`
+	PostID       *string   `json:"post_id,omitempty"`
+	StorageKey   string    `json:"-"`
+	Filename     string    `json:"filename"`
+	ContentType  string    `json:"content_type"`
+	SizeBytes    int64     `json:"size_bytes"`
+	SHA256       string    `json:"sha256"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	ProcessingStatus string         `json:"processing_status"`
+	Width            int            `json:"width,omitempty"`
+	Height           int            `json:"height,omitempty"`
+	BlurHash         string         `json:"blurhash,omitempty"`
+	Variants         []MediaVariant `json:"variants,omitempty"`
+
+	// StreamURL is the HLS master playlist of a packaged video
+	StreamURL string `json:"stream_url,omitempty"`
+
+	// DataKey is set when the stored object is encrypted, variants share it
+	DataKey *WrappedKey `json:"-"`
+
+	// ProcessingAttempts counts how often the image pipeline picked the file up
+	ProcessingAttempts int `json:"-"`
+}
+
+// IsServable reports if the original may be downloaded by others than its owner,
+// images are held back until their metadata is stripped
+func (m MediaFile) IsServable() bool {
+
+	if !ProcessedImageTypes[m.ContentType] {
+		return true
+	}
+
+	return m.ProcessingStatus == MediaProcessingNone || m.ProcessingStatus == MediaProcessingReady
+}
+
+// Variant returns the named variant of an image
+func (m MediaFile) Variant(name string) (*MediaVariant, bool) {
+
+	for i := range m.Variants {
+		if m.Variants[i].Name == name {
+			return &m.Variants[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// MediaVariant is a rendition of an image, resized or blurred. It is stored encrypted
+// under the data key of its media file
+type MediaVariant struct {
+	Name        string `json:"name"`
+	StorageKey  string `json:"-"`
+	ContentType string `json:"content_type"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	SizeBytes   int64  `json:"size_bytes"`
+}
+
+// ProcessedImage is the outcome of the image pipeline for a media file. StorageKey,
+// SizeBytes and SHA256 describe the original after its metadata was stripped
+type ProcessedImage struct {
+	StorageKey string
+	SizeBytes  int64
+	SHA256     string
+	Width      int
+	Height     int
+	BlurHash   string
+	Variants   []MediaVariant
+}
+
+// MediaStream is the HLS package of a video. Its segments are stored below
+// StoragePrefix, encrypted with AES-128 under the segment key
+type MediaStream struct {
+	MediaID       string
+	StoragePrefix string
+	Duration      time.Duration
+	Renditions    []StreamRendition
+
+	// SealedKey is the segment key, sealed by the data key of the media file when it
+	// is encrypted
+	SealedKey []byte
+}
+
+// Rendition returns the named rendition of the stream
+func (s MediaStream) Rendition(name string) (*StreamRendition, bool) {
+
+	for i := range s.Renditions {
+		if s.Renditions[i].Name == name {
+			return &s.Renditions[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// StreamRendition is one quality of a stream. Playlist is its media playlist as
+// written by the transcoder, segment uris are relative to the rendition
+type StreamRendition struct {
+	Name      string
+	Width     int
+	Height    int
+	Bandwidth int
+	Playlist  string
+}
+
+// PostImage is an image of a post as served with it. Readers the post is locked for
+// only get the blurred preview, the others the variants and the original
+type PostImage struct {
+	MediaID     string            `json:"media_id"`
+	Width       int               `json:"width"`
+	Height      int               `json:"height"`
+	BlurHash    string            `json:"blurhash"`
+	PreviewURL  string            `json:"preview_url,omitempty"`
+	URL         string            `json:"url,omitempty"`
+	VariantURLs map[string]string `json:"variant_urls,omitempty"`
+	ExpiresAt   time.Time         `json:"expires_at"`
+}
+
+// MediaUpload describes a file being uploaded
+type MediaUpload struct {
+	Filename string
+	PostID   string
+}
+
+// SignedURL is a download link bound to the address it was issued for
+type SignedURL struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
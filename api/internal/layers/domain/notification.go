@@ -0,0 +1,145 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+)
+
+const (
+	// NotificationNewPost goes to the members of communities owned by the author
+	NotificationNewPost = "new_post"
+	// NotificationReply goes to the author of the post or comment replied to
+	NotificationReply   = "reply"
+	NotificationMention = "mention"
+	// NotificationPaymentReceived goes to the owner of a paid community on every join
+	NotificationPaymentReceived = "payment_received"
+	// NotificationMembershipExpiring warns members who no longer satisfy the policy of
+	// their membership, it ends on the next check unless the tokens come back
+	NotificationMembershipExpiring = "membership_expiring"
+	// NotificationContentUnlocked goes to members once a dripped post unlocks for them
+	NotificationContentUnlocked = "content_unlocked"
+
+	// NotificationChannelPush delivers notifications as browser push messages, the
+	// in-app list and stream always carry every notification
+	NotificationChannelPush = "push"
+	// NotificationChannelEmail mails notifications to the verified address of an
+	// account
+	NotificationChannelEmail = "email"
+
+	// NotificationNotifyChannel is the postgres channel new notifications are announced on, the
+	// NotifyNotifications query publishes to it
+	NotificationNotifyChannel = "notification_events"
+)
+
+var (
+	ErrNotificationNotFound = errors.New("notification not found")
+	ErrPreferenceInvalid    = errors.New("notification preference is invalid")
+
+	NotificationKinds = []string{
+		NotificationNewPost,
+		NotificationReply,
+		NotificationMention,
+		NotificationPaymentReceived,
+		NotificationMembershipExpiring,
+		NotificationContentUnlocked,
+	}
+
+	// notificationChannelDefaults tells if a channel delivers the kinds an account did
+	// not switch
+	notificationChannelDefaults = map[string]bool{
+		NotificationChannelPush: true,
+		// emails are opt-in
+		NotificationChannelEmail: false,
+	}
+)
+
+func IsNotificationKind(kind string) bool {
+	return slices.Contains(NotificationKinds, kind)
+}
+
+func IsNotificationChannel(channel string) bool {
+	_, ok := notificationChannelDefaults[channel]
+	return ok
+}
+
+// NotificationPreferences maps delivery channels to the kinds of notifications they
+// deliver
+type NotificationPreferences map[string]map[string]bool
+
+func (p NotificationPreferences) Set(channel, kind string, enabled bool) {
+	if p[channel] == nil {
+		p[channel] = make(map[string]bool)
+	}
+	p[channel][kind] = enabled
+}
+
+// Enabled reports if the channel delivers the kind, falling back to the channel default
+func (p NotificationPreferences) Enabled(channel, kind string) bool {
+	if enabled, ok := p[channel][kind]; ok {
+		return enabled
+	}
+	return notificationChannelDefaults[channel]
+}
+
+// Complete lists every channel and kind along with the defaults in effect
+func (p NotificationPreferences) Complete() NotificationPreferences {
+	complete := make(NotificationPreferences)
+	for channel := range notificationChannelDefaults {
+		for _, kind := range NotificationKinds {
+			complete.Set(channel, kind, p.Enabled(channel, kind))
+		}
+	}
+	return complete
+}
+
+type Notification struct {
+	ID               int64           `json:"id"`
+	RecipientAddress string          `json:"recipient_address"`
+	Kind             string          `json:"kind"`
+	ActorAddress     string          `json:"actor_address,omitempty"`
+	SubjectID        string          `json:"subject_id"`
+	Data             json.RawMessage `json:"data"`
+	ReadAt           *time.Time      `json:"read_at,omitempty"`
+	CreatedAt        time.Time       `json:"created_at"`
+
+	// GroupKey folds repeated notifications, a recipient has at most one unread
+	// notification per key
+	GroupKey string `json:"-"`
+}
+
+func (n Notification) IsRead() bool {
+	return n.ReadAt != nil
+}
+
+// NotificationSummary is the readable form of a notification shown outside the app.
+// Path is the page it links to
+type NotificationSummary struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	Path  string `json:"path"`
+}
+
+func (n Notification) Summarize() NotificationSummary {
+
+	var data map[string]string
+	json.Unmarshal(n.Data, &data)
+
+	switch n.Kind {
+	case NotificationNewPost:
+		return NotificationSummary{Title: "New post", Body: data["title"], Path: "/posts/" + data["post_id"]}
+	case NotificationReply:
+		return NotificationSummary{Title: "New reply", Body: data["excerpt"], Path: "/posts/" + data["post_id"] + "#comment-" + data["comment_id"]}
+	case NotificationMention:
+		return NotificationSummary{Title: "You were mentioned", Body: data["excerpt"], Path: "/posts/" + data["post_id"] + "#comment-" + data["comment_id"]}
+	case NotificationPaymentReceived:
+		return NotificationSummary{Title: "Payment received", Body: "A member paid to join " + data["community_name"], Path: "/communities/" + data["community_id"]}
+	case NotificationMembershipExpiring:
+		return NotificationSummary{Title: "Membership expiring", Body: "Your membership of " + data["community_name"] + " ends soon: " + data["reason"], Path: "/communities/" + data["community_slug"]}
+	case NotificationContentUnlocked:
+		return NotificationSummary{Title: "New content unlocked", Body: data["title"], Path: "/posts/" + data["post_id"]}
+	default:
+		return NotificationSummary{Title: "New notification", Path: "/notifications"}
+	}
+}
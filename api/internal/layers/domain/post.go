@@ -0,0 +1,160 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	PostStatusDraft = "draft"
+	// PostStatusScheduled posts are published by the scheduler once PublishAt is reached
+	PostStatusScheduled = "scheduled"
+	PostStatusPublished = "published"
+
+	// DripMaxDelay caps how long after joining a dripped post unlocks
+	DripMaxDelay = 365 * 24 * time.Hour
+
+	BodyFormatMarkdown = "markdown"
+	BodyFormatRichText = "richtext"
+)
+
+var (
+	ErrPostNotFound       = errors.New("post not found")
+	ErrPostForbidden      = errors.New("post belongs to another account")
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	ErrInvalidPolicy      = errors.New("access policy is invalid")
+	// ErrPreviewUnsupported is returned for policies whose qualifying addresses can not
+	// be listed, eg. native balance conditions
+	ErrPreviewUnsupported = errors.New("access policy can not be previewed")
+
+	ErrPublishAtInvalid      = errors.New("publish time must be in the future")
+	ErrPostAlreadyPublished  = errors.New("post is already published, unpublish it first")
+	ErrDripNotFound          = errors.New("post is not dripped")
+	ErrDripDelayInvalid      = errors.New("drip delay is invalid")
+	ErrDripCommunityNotOwned = errors.New("posts can only be dripped to communities owned by their author")
+)
+
+type Post struct {
+	ID            string           `json:"id"`
+	AuthorAddress string           `json:"author_address"`
+	Title         string           `json:"title"`
+	Teaser        string           `json:"teaser,omitempty"`
+	Tags          []string         `json:"tags,omitempty"`
+	Body          string           `json:"body,omitempty"`
+	BodyFormat    string           `json:"body_format"`
+	Status        string           `json:"status"`
+	AccessPolicy  json.RawMessage  `json:"access_policy,omitempty"`
+	Attachments   []PostAttachment `json:"attachments,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+	PublishedAt   *time.Time       `json:"published_at,omitempty"`
+	// PublishAt is when a scheduled post gets published
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+	// Drip is set on posts unlocking for community members some time after they joined,
+	// it is filled in when the post is viewed
+	Drip *PostDrip `json:"drip,omitempty"`
+
+	// DataKey and SealedBody hold the encrypted body as stored, Body is only filled in
+	// once the reader passed the access policy
+	DataKey    *WrappedKey `json:"-"`
+	SealedBody []byte      `json:"-"`
+}
+
+func (p Post) IsPublished() bool {
+	return p.Status == PostStatusPublished
+}
+
+// IsGated reports if the post has an access policy, posts without one are public
+func (p Post) IsGated() bool {
+	return len(p.AccessPolicy) > 0 && string(p.AccessPolicy) != "null"
+}
+
+// PostDrip unlocks a post for every member of a community DelaySeconds after they
+// joined. The access policy of the post still applies on top
+type PostDrip struct {
+	CommunityID  string `json:"community_id"`
+	DelaySeconds int    `json:"delay_seconds"`
+}
+
+// DripAccess is the drip of a post along with when the viewer joined its community,
+// JoinedAt is nil for viewers outside of it
+type DripAccess struct {
+	PostDrip
+	JoinedAt *time.Time
+}
+
+// UnlocksAt is when the post unlocks for the viewer, nil for non-members
+func (d DripAccess) UnlocksAt() *time.Time {
+	if d.JoinedAt == nil {
+		return nil
+	}
+	at := d.JoinedAt.Add(time.Duration(d.DelaySeconds) * time.Second)
+	return &at
+}
+
+type PostAttachment struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	URL         string    `json:"url"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	Position    int       `json:"position"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// SealedName and SealedURL are Name and URL encrypted under the post's data key
+	SealedName []byte `json:"-"`
+	SealedURL  []byte `json:"-"`
+}
+
+// PostInput holds the editable fields of a post
+type PostInput struct {
+	Title        string
+	Teaser       string
+	Tags         []string
+	Body         string
+	BodyFormat   string
+	AccessPolicy json.RawMessage
+
+	// SearchBody is the plaintext indexed for search, empty for gated posts so their
+	// content is not exposed through matches
+	SearchBody string
+
+	// Change describes the save against the previous revision, RestoredFrom is the
+	// revision a restore copies
+	Change       RevisionChange
+	RestoredFrom int
+
+	// DataKey and SealedBody replace Body when the post is stored encrypted
+	DataKey    *WrappedKey
+	SealedBody []byte
+}
+
+// PostView is a post as seen by a particular reader. Body and attachments are
+// stripped when the reader does not satisfy the access policy
+type PostView struct {
+	Post
+	Locked     bool   `json:"locked"`
+	LockReason string `json:"lock_reason,omitempty"`
+	// UnlocksAt is when a dripped post unlocks for the reader
+	UnlocksAt *time.Time `json:"unlocks_at,omitempty"`
+
+	// Images are the processed images uploaded to the post
+	Images []PostImage `json:"images,omitempty"`
+}
+
+// AccessDecision is the outcome of checking an address against an access policy
+type AccessDecision struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+
+	// UnlocksAt is set when access is only a matter of time, eg. a drip not due yet
+	UnlocksAt *time.Time `json:"unlocks_at,omitempty"`
+}
+
+// Viewer is the reader access policies are evaluated for. Address is empty for
+// anonymous readers, BypassCache forces a fresh evaluation while debugging a policy
+type Viewer struct {
+	Address     string
+	BypassCache bool
+}
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrPushDisabled             = errors.New("push notifications are not configured")
+	ErrPushSubscriptionInvalid  = errors.New("push subscription is invalid")
+	ErrPushSubscriptionNotFound = errors.New("push subscription not found")
+)
+
+// PushSubscription is a browser registered to receive the push messages of an
+// account. P256dh and Auth are the keys messages are encrypted for
+type PushSubscription struct {
+	ID         string     `json:"id"`
+	Address    string     `json:"-"`
+	Endpoint   string     `json:"endpoint"`
+	P256dh     string     `json:"-"`
+	Auth       string     `json:"-"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// PushDelivery is a notification waiting to be pushed to one subscription. Attempts
+// counts the tries including the current one
+type PushDelivery struct {
+	ID           int64
+	Attempts     int
+	Subscription PushSubscription
+	Notification Notification
+}
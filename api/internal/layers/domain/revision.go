@@ -0,0 +1,134 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+)
+
+const (
+	RevisionFieldTitle        = "title"
+	RevisionFieldTeaser       = "teaser"
+	RevisionFieldTags         = "tags"
+	RevisionFieldBody         = "body"
+	RevisionFieldBodyFormat   = "body_format"
+	RevisionFieldAccessPolicy = "access_policy"
+
+	// RevisionDiffContext is how many unchanged lines surround the changes of a hunk
+	RevisionDiffContext = 3
+)
+
+var (
+	ErrRevisionNotFound = errors.New("revision not found")
+	ErrRevisionCurrent  = errors.New("revision is the current content of the post")
+)
+
+// RevisionContent is the part of a post a revision keeps
+type RevisionContent struct {
+	Title        string          `json:"title"`
+	Teaser       string          `json:"teaser,omitempty"`
+	Tags         []string        `json:"tags,omitempty"`
+	Body         string          `json:"body,omitempty"`
+	BodyFormat   string          `json:"body_format"`
+	AccessPolicy json.RawMessage `json:"access_policy,omitempty"`
+}
+
+// ChangedFields lists the fields differing between prev and c
+func (c RevisionContent) ChangedFields(prev RevisionContent) []string {
+
+	fields := []string{}
+	if c.Title != prev.Title {
+		fields = append(fields, RevisionFieldTitle)
+	}
+	if c.Teaser != prev.Teaser {
+		fields = append(fields, RevisionFieldTeaser)
+	}
+	if !slices.Equal(c.Tags, prev.Tags) {
+		fields = append(fields, RevisionFieldTags)
+	}
+	if c.Body != prev.Body {
+		fields = append(fields, RevisionFieldBody)
+	}
+	if c.BodyFormat != prev.BodyFormat {
+		fields = append(fields, RevisionFieldBodyFormat)
+	}
+	if !samePolicy(c.AccessPolicy, prev.AccessPolicy) {
+		fields = append(fields, RevisionFieldAccessPolicy)
+	}
+
+	return fields
+}
+
+// samePolicy compares policies regardless of formatting, null is no policy
+func samePolicy(a, b json.RawMessage) bool {
+
+	compact := func(policy json.RawMessage) []byte {
+		var buf bytes.Buffer
+		if json.Compact(&buf, policy) != nil || buf.String() == "null" {
+			return nil
+		}
+		return buf.Bytes()
+	}
+
+	return bytes.Equal(compact(a), compact(b))
+}
+
+// RevisionChange describes a revision against the one before it, lines count the lines
+// of the body
+type RevisionChange struct {
+	Fields       []string `json:"fields"`
+	LinesAdded   int      `json:"lines_added"`
+	LinesRemoved int      `json:"lines_removed"`
+}
+
+// PostRevision is an immutable snapshot of a post taken on every save. Status is the
+// status of the post when it was saved, RestoredFrom the revision a restore copied. The
+// body is left out of revision lists
+type PostRevision struct {
+	PostID        string `json:"post_id"`
+	Number        int    `json:"number"`
+	AuthorAddress string `json:"author_address"`
+	Status        string `json:"status"`
+	RevisionContent
+	Change       RevisionChange `json:"change"`
+	RestoredFrom int            `json:"restored_from,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+
+	// SealedBody holds the body encrypted under the data key of the post
+	SealedBody []byte `json:"-"`
+}
+
+// FieldChange is a field other than the body compared between two revisions
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// DiffLine is a line of a body diff, Op is equal, insert or delete
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// DiffHunk is a run of body changes with the unchanged lines around them, lines count
+// from 1
+type DiffHunk struct {
+	FromLine  int        `json:"from_line"`
+	FromCount int        `json:"from_count"`
+	ToLine    int        `json:"to_line"`
+	ToCount   int        `json:"to_count"`
+	Lines     []DiffLine `json:"lines"`
+}
+
+// RevisionDiff compares revision From of a post to revision To
+type RevisionDiff struct {
+	PostID string         `json:"post_id"`
+	From   int            `json:"from"`
+	To     int            `json:"to"`
+	Change RevisionChange `json:"change"`
+	Fields []FieldChange  `json:"fields"`
+	Body   []DiffHunk     `json:"body"`
+}
@@ -0,0 +1,60 @@
+package domain
+
+import "errors"
+
+const (
+	SearchTypeAll         = "all"
+	SearchTypePosts       = "posts"
+	SearchTypeCreators    = "creators"
+	SearchTypeCommunities = "communities"
+
+	SearchQueryMaxLength = 200
+)
+
+var ErrSearchQueryInvalid = errors.New("search query is invalid")
+
+// SearchHighlight holds fragments of a result with the matched terms wrapped in <mark>.
+// Everything else is HTML escaped, so fragments can be rendered as they are
+type SearchHighlight struct {
+	Title       string `json:"title,omitempty"`
+	Teaser      string `json:"teaser,omitempty"`
+	Body        string `json:"body,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// PostSearchResult is a published post matching a search. The body is never included,
+// readers passing the access policy get a highlighted snippet of it instead
+type PostSearchResult struct {
+	PostView
+	Rank      float32         `json:"rank"`
+	Highlight SearchHighlight `json:"highlight"`
+}
+
+type CreatorSearchResult struct {
+	Handle  string  `json:"handle"`
+	Address string  `json:"address"`
+	Score   float32 `json:"score"`
+}
+
+type CommunitySearchResult struct {
+	Community
+	Rank      float32         `json:"rank"`
+	Highlight SearchHighlight `json:"highlight"`
+}
+
+// SearchResults groups matches by kind, kinds not searched for are left out
+type SearchResults struct {
+	Posts       []PostSearchResult      `json:"posts,omitempty"`
+	Creators    []CreatorSearchResult   `json:"creators,omitempty"`
+	Communities []CommunitySearchResult `json:"communities,omitempty"`
+}
+
+// IsSearchType reports if t is one of the SearchType constants
+func IsSearchType(t string) bool {
+	switch t {
+	case SearchTypeAll, SearchTypePosts, SearchTypeCreators, SearchTypeCommunities:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrUploadNotFound         = errors.New("upload not found")
+	ErrUploadForbidden        = errors.New("upload belongs to another account")
+	ErrUploadExpired          = errors.New("upload has expired")
+	ErrUploadOffsetMismatch   = errors.New("upload offset does not match the received bytes")
+	ErrUploadExceedsLength    = errors.New("upload exceeds its declared length")
+	ErrUploadTooLarge         = errors.New("upload exceeds the maximum size")
+	ErrUploadChecksumMismatch = errors.New("upload checksum does not match")
+	ErrUnsupportedChecksum    = errors.New("checksum algorithm is not supported")
+)
+
+// Upload is a resumable upload following the tus protocol. Once all bytes arrived it
+// is handed over to media storage and MediaID is set
+type Upload struct {
+	ID           string
+	OwnerAddress string
+	Length       int64
+	Offset       int64
+	// Metadata is the raw Upload-Metadata header given on creation
+	Metadata    string
+	Filename    string
+	PostID      *string
+	MediaID     *string
+	CompletedAt *time.Time
+	ExpiresAt   time.Time
+	CreatedAt   time.Time
+	// DataKey is set when chunks are stored encrypted
+	DataKey *WrappedKey
+}
+
+func (u Upload) IsComplete() bool {
+	return u.CompletedAt != nil
+}
+
+// UploadInput describes an upload being created
+type UploadInput struct {
+	Length   int64
+	Metadata string
+	Filename string
+	PostID   string
+	DataKey  *WrappedKey
+}
+
+// UploadChunk is the part of an upload received by one request
+type UploadChunk struct {
+	Offset     int64
+	StorageKey string
+	SizeBytes  int64
+}
+
+// UploadChecksum is the digest a client sent along with a chunk
+type UploadChecksum struct {
+	Algorithm string
+	Sum       []byte
+}
@@ -0,0 +1,237 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/db"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type ChatRepository interface {
+	CreateMessage(communityID, authorAddr, body string) (*domain.ChatMessage, error)
+
+	// GetMessage returns a message along with its author's handle
+	GetMessage(id string) (*domain.ChatMessage, error)
+
+	// ListMessages returns the messages of a community newest first, before the cursor
+	// when one is given
+	ListMessages(communityID string, before domain.Cursor, limit int) ([]domain.ChatMessage, error)
+
+	// Notify sends a payload to the chat listeners of every replica
+	Notify(payload string) error
+
+	// AddSession records an open connection and returns its session id
+	AddSession(communityID, addr string) (string, error)
+
+	RemoveSession(sessionID string) error
+
+	// CountSessions counts the live connections of an account to a community
+	CountSessions(communityID, addr string, liveSince time.Time) (int, error)
+
+	// ListPresent returns the accounts with a live connection to a community
+	ListPresent(communityID string, liveSince time.Time) ([]string, error)
+
+	// TouchSessions marks connections as live
+	TouchSessions(sessionIDs []string) error
+
+	// PurgeSessions removes connections which were not marked live since before, they
+	// belonged to replicas which went away
+	PurgeSessions(before time.Time) (int64, error)
+}
+
+func NewChatRepository(ctx context.Context, logger *logger.Logger, q *db.Queries) ChatRepository {
+
+	return &chatRepository{
+		ctx:    ctx,
+		logger: *logger,
+		q:      q,
+	}
+}
+
+type chatRepository struct {
+	ctx    context.Context
+	logger logger.Logger
+	q      *db.Queries
+}
+
+func (repo *chatRepository) CreateMessage(communityID, authorAddr, body string) (*domain.ChatMessage, error) {
+
+	uuid, ok := parseUUID(communityID)
+	if !ok {
+		return nil, domain.ErrCommunityNotFound
+	}
+
+	row, err := repo.q.CreateChatMessage(repo.ctx, db.CreateChatMessageParams{
+		CommunityID:   uuid,
+		AuthorAddress: authorAddr,
+		Body:          body,
+	})
+	if isForeignKeyViolation(err) {
+		return nil, domain.ErrCommunityNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	message := toDomainChatMessage(db.GetChatMessageRow{
+		ID:            row.ID,
+		CommunityID:   row.CommunityID,
+		AuthorAddress: row.AuthorAddress,
+		Body:          row.Body,
+		CreatedAt:     row.CreatedAt,
+	})
+	return &message, nil
+}
+
+func (repo *chatRepository) GetMessage(id string) (*domain.ChatMessage, error) {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return nil, domain.ErrChatMessageInvalid
+	}
+
+	row, err := repo.q.GetChatMessage(repo.ctx, uuid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrChatMessageInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	message := toDomainChatMessage(row)
+	return &message, nil
+}
+
+func (repo *chatRepository) ListMessages(communityID string, before domain.Cursor, limit int) ([]domain.ChatMessage, error) {
+
+	uuid, ok := parseUUID(communityID)
+	if !ok {
+		return nil, domain.ErrCommunityNotFound
+	}
+
+	// the first page starts past every row
+	beforeCreatedAt := pgtype.Timestamp{InfinityModifier: pgtype.Infinity, Valid: true}
+	beforeID := pgtype.UUID{Valid: true}
+
+	if before.ID != "" {
+		if beforeID, ok = parseUUID(before.ID); !ok {
+			return nil, domain.ErrCursorInvalid
+		}
+		beforeCreatedAt = toTimestamp(before.CreatedAt)
+	}
+
+	rows, err := repo.q.ListChatMessages(repo.ctx, db.ListChatMessagesParams{
+		CommunityID:     uuid,
+		BeforeCreatedAt: beforeCreatedAt,
+		BeforeID:        beforeID,
+		RowLimit:        int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]domain.ChatMessage, 0, len(rows))
+	for _, row := range rows {
+		messages = append(messages, toDomainChatMessage(db.GetChatMessageRow(row)))
+	}
+
+	return messages, nil
+}
+
+func (repo *chatRepository) Notify(payload string) error {
+
+	return repo.q.NotifyChat(repo.ctx, payload)
+}
+
+func (repo *chatRepository) AddSession(communityID, addr string) (string, error) {
+
+	uuid, ok := parseUUID(communityID)
+	if !ok {
+		return "", domain.ErrCommunityNotFound
+	}
+
+	sessionID, err := repo.q.CreateChatPresence(repo.ctx, db.CreateChatPresenceParams{
+		CommunityID:   uuid,
+		MemberAddress: addr,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return sessionID.String(), nil
+}
+
+func (repo *chatRepository) RemoveSession(sessionID string) error {
+
+	uuid, ok := parseUUID(sessionID)
+	if !ok {
+		return nil
+	}
+
+	return repo.q.DeleteChatPresence(repo.ctx, uuid)
+}
+
+func (repo *chatRepository) CountSessions(communityID, addr string, liveSince time.Time) (int, error) {
+
+	uuid, ok := parseUUID(communityID)
+	if !ok {
+		return 0, domain.ErrCommunityNotFound
+	}
+
+	count, err := repo.q.CountChatSessions(repo.ctx, db.CountChatSessionsParams{
+		CommunityID:   uuid,
+		MemberAddress: addr,
+		SeenAt:        toTimestamp(liveSince),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(count), nil
+}
+
+func (repo *chatRepository) ListPresent(communityID string, liveSince time.Time) ([]string, error) {
+
+	uuid, ok := parseUUID(communityID)
+	if !ok {
+		return nil, domain.ErrCommunityNotFound
+	}
+
+	return repo.q.ListChatPresence(repo.ctx, db.ListChatPresenceParams{
+		CommunityID: uuid,
+		SeenAt:      toTimestamp(liveSince),
+	})
+}
+
+func (repo *chatRepository) TouchSessions(sessionIDs []string) error {
+
+	uuids := make([]pgtype.UUID, 0, len(sessionIDs))
+	for _, id := range sessionIDs {
+		if uuid, ok := parseUUID(id); ok {
+			uuids = append(uuids, uuid)
+		}
+	}
+
+	return repo.q.TouchChatPresence(repo.ctx, uuids)
+}
+
+func (repo *chatRepository) PurgeSessions(before time.Time) (int64, error) {
+
+	return repo.q.PurgeChatPresence(repo.ctx, toTimestamp(before))
+}
+
+func toDomainChatMessage(row db.GetChatMessageRow) domain.ChatMessage {
+	return domain.ChatMessage{
+		ID:            row.ID.String(),
+		CommunityID:   row.CommunityID.String(),
+		AuthorAddress: row.AuthorAddress,
+		AuthorHandle:  row.Handle.String,
+		Body:          row.Body,
+		CreatedAt:     row.CreatedAt.Time,
+	}
+}
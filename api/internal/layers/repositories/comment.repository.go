@@ -0,0 +1,486 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Xebec19/jibe/api/internal/db"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type CommentRepository interface {
+	// CreateComment stores a comment and counts it as a reply on its parent
+	CreateComment(comment domain.Comment) (*domain.Comment, error)
+
+	GetComment(id string) (*domain.Comment, error)
+
+	// ListComments returns the top level comments of a post, or the replies of parentID
+	// when one is given, oldest first after the cursor. Deleted comments are only
+	// listed while they have replies, comments of accounts viewer blocked or muted and
+	// of accounts blocking viewer are left out
+	ListComments(postID, parentID, viewer string, after domain.Cursor, limit int) ([]domain.Comment, error)
+
+	// UpdateComment replaces the body of a comment which was not deleted
+	UpdateComment(id, body string, mentions []string) (*domain.Comment, error)
+
+	// DeleteComment soft deletes a comment. The body is erased when its author deletes
+	// it and kept for moderated comments so they can be restored
+	DeleteComment(id, deletedBy string) (*domain.Comment, error)
+
+	// RestoreComment undoes the moderation of a comment
+	RestoreComment(id string) (*domain.Comment, error)
+
+	// AddPostReaction stores a reaction, adding one twice is a no-op. ErrReactionLimit
+	// is returned once the account used ReactionMaxKinds other emoji on the post
+	AddPostReaction(postID, addr, emoji string) error
+
+	RemovePostReaction(postID, addr, emoji string) error
+
+	// ListPostReactions returns the reaction counts of a post, most used first
+	ListPostReactions(postID, viewer string) ([]domain.ReactionCount, error)
+
+	// AddCommentReaction is AddPostReaction for comments
+	AddCommentReaction(commentID, addr, emoji string) error
+
+	RemoveCommentReaction(commentID, addr, emoji string) error
+
+	// ListCommentReactions returns the reaction counts of several comments keyed by
+	// comment id
+	ListCommentReactions(commentIDs []string, viewer string) (map[string][]domain.ReactionCount, error)
+}
+
+func NewCommentRepository(ctx context.Context, logger *logger.Logger, pool *pgxpool.Pool, q *db.Queries) CommentRepository {
+
+	return &commentRepository{
+		ctx:    ctx,
+		logger: *logger,
+		pool:   pool,
+		q:      q,
+	}
+}
+
+type commentRepository struct {
+	ctx    context.Context
+	logger logger.Logger
+	pool   *pgxpool.Pool
+	q      *db.Queries
+}
+
+func (repo *commentRepository) CreateComment(comment domain.Comment) (*domain.Comment, error) {
+
+	postID, ok := parseUUID(comment.PostID)
+	if !ok {
+		return nil, domain.ErrPostNotFound
+	}
+
+	var parentID pgtype.UUID
+	if comment.ParentID != "" {
+		if parentID, ok = parseUUID(comment.ParentID); !ok {
+			return nil, domain.ErrCommentNotFound
+		}
+	}
+
+	tx, err := repo.pool.Begin(repo.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(repo.ctx)
+
+	qtx := repo.q.WithTx(tx)
+
+	mentions := comment.Mentions
+	if mentions == nil {
+		mentions = []string{}
+	}
+
+	row, err := qtx.CreateComment(repo.ctx, db.CreateCommentParams{
+		PostID:        postID,
+		ParentID:      parentID,
+		Depth:         int32(comment.Depth),
+		AuthorAddress: comment.AuthorAddress,
+		Body:          comment.Body,
+		Mentions:      mentions,
+	})
+	if isForeignKeyViolation(err) {
+		return nil, domain.ErrPostNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID.Valid {
+		if err := qtx.IncrementCommentReplies(repo.ctx, parentID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(repo.ctx); err != nil {
+		return nil, fmt.Errorf("transaction commit failed %w", err)
+	}
+
+	created := toDomainComment(row)
+	return &created, nil
+}
+
+func (repo *commentRepository) GetComment(id string) (*domain.Comment, error) {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return nil, domain.ErrCommentNotFound
+	}
+
+	row, err := repo.q.GetComment(repo.ctx, uuid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	comment := commentWithHandle(row)
+	return &comment, nil
+}
+
+func (repo *commentRepository) ListComments(postID, parentID, viewer string, after domain.Cursor, limit int) ([]domain.Comment, error) {
+
+	afterID := pgtype.UUID{Valid: true}
+	if after.ID != "" {
+		var ok bool
+		if afterID, ok = parseUUID(after.ID); !ok {
+			return nil, domain.ErrCursorInvalid
+		}
+	}
+
+	var rows []db.GetCommentRow
+
+	if parentID != "" {
+		uuid, ok := parseUUID(parentID)
+		if !ok {
+			return nil, domain.ErrCommentNotFound
+		}
+
+		replies, err := repo.q.ListCommentReplies(repo.ctx, db.ListCommentRepliesParams{
+			ParentID:       uuid,
+			AfterCreatedAt: toTimestamp(after.CreatedAt),
+			AfterID:        afterID,
+			ViewerAddress:  viewer,
+			RowLimit:       int32(limit),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, reply := range replies {
+			rows = append(rows, db.GetCommentRow(reply))
+		}
+	} else {
+		uuid, ok := parseUUID(postID)
+		if !ok {
+			return nil, domain.ErrPostNotFound
+		}
+
+		comments, err := repo.q.ListTopLevelComments(repo.ctx, db.ListTopLevelCommentsParams{
+			PostID:         uuid,
+			AfterCreatedAt: toTimestamp(after.CreatedAt),
+			AfterID:        afterID,
+			ViewerAddress:  viewer,
+			RowLimit:       int32(limit),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, comment := range comments {
+			rows = append(rows, db.GetCommentRow(comment))
+		}
+	}
+
+	comments := make([]domain.Comment, 0, len(rows))
+	for _, row := range rows {
+		comments = append(comments, commentWithHandle(row))
+	}
+
+	return comments, nil
+}
+
+func (repo *commentRepository) UpdateComment(id, body string, mentions []string) (*domain.Comment, error) {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return nil, domain.ErrCommentNotFound
+	}
+
+	if mentions == nil {
+		mentions = []string{}
+	}
+
+	row, err := repo.q.UpdateCommentBody(repo.ctx, db.UpdateCommentBodyParams{
+		ID:       uuid,
+		Body:     body,
+		Mentions: mentions,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrCommentDeleted
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	comment := toDomainComment(row)
+	return &comment, nil
+}
+
+func (repo *commentRepository) DeleteComment(id, deletedBy string) (*domain.Comment, error) {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return nil, domain.ErrCommentNotFound
+	}
+
+	row, err := repo.q.SoftDeleteComment(repo.ctx, db.SoftDeleteCommentParams{
+		DeletedBy: deletedBy,
+		ID:        uuid,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrCommentDeleted
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	comment := toDomainComment(row)
+	return &comment, nil
+}
+
+func (repo *commentRepository) RestoreComment(id string) (*domain.Comment, error) {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return nil, domain.ErrCommentNotFound
+	}
+
+	row, err := repo.q.RestoreComment(repo.ctx, uuid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	comment := toDomainComment(row)
+	return &comment, nil
+}
+
+func (repo *commentRepository) AddPostReaction(postID, addr, emoji string) error {
+
+	uuid, ok := parseUUID(postID)
+	if !ok {
+		return domain.ErrPostNotFound
+	}
+
+	kinds, err := repo.q.CountPostReactionKinds(repo.ctx, db.CountPostReactionKindsParams{
+		PostID:         uuid,
+		ReactorAddress: addr,
+		Emoji:          emoji,
+	})
+	if err != nil {
+		return err
+	}
+	if kinds >= domain.ReactionMaxKinds {
+		return domain.ErrReactionLimit
+	}
+
+	err = repo.q.AddPostReaction(repo.ctx, db.AddPostReactionParams{
+		PostID:         uuid,
+		ReactorAddress: addr,
+		Emoji:          emoji,
+	})
+	if isForeignKeyViolation(err) {
+		return domain.ErrPostNotFound
+	}
+
+	return err
+}
+
+func (repo *commentRepository) RemovePostReaction(postID, addr, emoji string) error {
+
+	uuid, ok := parseUUID(postID)
+	if !ok {
+		return domain.ErrPostNotFound
+	}
+
+	rows, err := repo.q.RemovePostReaction(repo.ctx, db.RemovePostReactionParams{
+		PostID:         uuid,
+		ReactorAddress: addr,
+		Emoji:          emoji,
+	})
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return domain.ErrReactionNotFound
+	}
+
+	return nil
+}
+
+func (repo *commentRepository) ListPostReactions(postID, viewer string) ([]domain.ReactionCount, error) {
+
+	uuid, ok := parseUUID(postID)
+	if !ok {
+		return nil, domain.ErrPostNotFound
+	}
+
+	rows, err := repo.q.ListPostReactionCounts(repo.ctx, db.ListPostReactionCountsParams{
+		Viewer: viewer,
+		PostID: uuid,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reactions := make([]domain.ReactionCount, 0, len(rows))
+	for _, row := range rows {
+		reactions = append(reactions, domain.ReactionCount{
+			Emoji:   row.Emoji,
+			Count:   int(row.Count),
+			Reacted: row.Reacted,
+		})
+	}
+
+	return reactions, nil
+}
+
+func (repo *commentRepository) AddCommentReaction(commentID, addr, emoji string) error {
+
+	uuid, ok := parseUUID(commentID)
+	if !ok {
+		return domain.ErrCommentNotFound
+	}
+
+	kinds, err := repo.q.CountCommentReactionKinds(repo.ctx, db.CountCommentReactionKindsParams{
+		CommentID:      uuid,
+		ReactorAddress: addr,
+		Emoji:          emoji,
+	})
+	if err != nil {
+		return err
+	}
+	if kinds >= domain.ReactionMaxKinds {
+		return domain.ErrReactionLimit
+	}
+
+	err = repo.q.AddCommentReaction(repo.ctx, db.AddCommentReactionParams{
+		CommentID:      uuid,
+		ReactorAddress: addr,
+		Emoji:          emoji,
+	})
+	if isForeignKeyViolation(err) {
+		return domain.ErrCommentNotFound
+	}
+
+	return err
+}
+
+func (repo *commentRepository) RemoveCommentReaction(commentID, addr, emoji string) error {
+
+	uuid, ok := parseUUID(commentID)
+	if !ok {
+		return domain.ErrCommentNotFound
+	}
+
+	rows, err := repo.q.RemoveCommentReaction(repo.ctx, db.RemoveCommentReactionParams{
+		CommentID:      uuid,
+		ReactorAddress: addr,
+		Emoji:          emoji,
+	})
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return domain.ErrReactionNotFound
+	}
+
+	return nil
+}
+
+func (repo *commentRepository) ListCommentReactions(commentIDs []string, viewer string) (map[string][]domain.ReactionCount, error) {
+
+	uuids := make([]pgtype.UUID, 0, len(commentIDs))
+	for _, id := range commentIDs {
+		if uuid, ok := parseUUID(id); ok {
+			uuids = append(uuids, uuid)
+		}
+	}
+
+	rows, err := repo.q.ListCommentReactionCounts(repo.ctx, db.ListCommentReactionCountsParams{
+		Viewer:     viewer,
+		CommentIds: uuids,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reactions := make(map[string][]domain.ReactionCount)
+	for _, row := range rows {
+		id := row.CommentID.String()
+		reactions[id] = append(reactions[id], domain.ReactionCount{
+			Emoji:   row.Emoji,
+			Count:   int(row.Count),
+			Reacted: row.Reacted,
+		})
+	}
+
+	return reactions, nil
+}
+
+func toDomainComment(row db.PostComment) domain.Comment {
+
+	comment := domain.Comment{
+		ID:            row.ID.String(),
+		PostID:        row.PostID.String(),
+		Depth:         int(row.Depth),
+		AuthorAddress: row.AuthorAddress,
+		Body:          row.Body,
+		Mentions:      row.Mentions,
+		ReplyCount:    int(row.ReplyCount),
+		CreatedAt:     row.CreatedAt.Time,
+		EditedAt:      fromTimestamp(row.EditedAt),
+		DeletedAt:     fromTimestamp(row.DeletedAt),
+	}
+
+	if row.ParentID.Valid {
+		comment.ParentID = row.ParentID.String()
+	}
+
+	comment.Moderated = row.DeletedBy.Valid && row.DeletedBy.String != row.AuthorAddress
+
+	return comment
+}
+
+// commentWithHandle converts a comment read along with its author's handle
+func commentWithHandle(row db.GetCommentRow) domain.Comment {
+
+	comment := toDomainComment(db.PostComment{
+		ID:            row.ID,
+		PostID:        row.PostID,
+		ParentID:      row.ParentID,
+		Depth:         row.Depth,
+		AuthorAddress: row.AuthorAddress,
+		Body:          row.Body,
+		Mentions:      row.Mentions,
+		ReplyCount:    row.ReplyCount,
+		CreatedAt:     row.CreatedAt,
+		EditedAt:      row.EditedAt,
+		DeletedAt:     row.DeletedAt,
+		DeletedBy:     row.DeletedBy,
+	})
+	comment.AuthorHandle = row.Handle.String
+
+	return comment
+}
@@ -0,0 +1,490 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/Xebec19/jibe/api/internal/db"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type CommunityRepository interface {
+	// CreateCommunity stores a community along with the owner's membership
+	CreateCommunity(ownerAddr string, input domain.CommunityInput) (*domain.Community, error)
+
+	// GetCommunity looks a community up by id or slug
+	GetCommunity(idOrSlug string) (*domain.Community, error)
+
+	UpdateCommunity(id string, input domain.CommunityInput) (*domain.Community, error)
+
+	DeleteCommunity(id string) error
+
+	// ListMemberCommunities returns the communities the address belongs to, most
+	// recently joined first
+	ListMemberCommunities(addr string, limit, offset int) ([]domain.Community, error)
+
+	GetMember(communityID, addr string) (*domain.CommunityMember, error)
+
+	// AddMember stores a membership and counts it on the community. A membership
+	// redeemed through an invite claims one of its uses, ErrInviteExpired is returned
+	// when none is left
+	AddMember(member domain.CommunityMember) (*domain.CommunityMember, error)
+
+	SetMemberRole(communityID, addr, role string) (*domain.CommunityMember, error)
+
+	// RemoveMember deletes a membership and uncounts it on the community
+	RemoveMember(communityID, addr string) error
+
+	// ListMembers returns members by rank then join date, along with their handles.
+	// An empty role lists every member
+	ListMembers(communityID, role string, limit, offset int) ([]domain.CommunityMember, error)
+
+	// ListGatedMemberships returns the memberships of the addresses which were granted
+	// under an access policy and are re-evaluated, admins and owners are left out
+	ListGatedMemberships(addrs []string) ([]domain.CommunityMember, error)
+}
+
+func NewCommunityRepository(ctx context.Context, logger *logger.Logger, pool *pgxpool.Pool, q *db.Queries) CommunityRepository {
+
+	return &communityRepository{
+		ctx:    ctx,
+		logger: *logger,
+		pool:   pool,
+		q:      q,
+	}
+}
+
+type communityRepository struct {
+	ctx    context.Context
+	logger logger.Logger
+	pool   *pgxpool.Pool
+	q      *db.Queries
+}
+
+func (repo *communityRepository) CreateCommunity(ownerAddr string, input domain.CommunityInput) (*domain.Community, error) {
+
+	chainID, token, amount := fromCommunityPrice(input.Price)
+
+	tx, err := repo.pool.Begin(repo.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(repo.ctx)
+
+	qtx := repo.q.WithTx(tx)
+
+	row, err := qtx.CreateCommunity(repo.ctx, db.CreateCommunityParams{
+		Slug:         input.Slug,
+		Name:         input.Name,
+		Description:  input.Description,
+		OwnerAddress: ownerAddr,
+		JoinPolicy:   input.JoinPolicy,
+		AccessPolicy: input.AccessPolicy,
+		PriceChainID: chainID,
+		PriceToken:   token,
+		PriceAmount:  amount,
+	})
+	if isUniqueViolation(err) {
+		return nil, domain.ErrCommunitySlugTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("community creation failed %w", err)
+	}
+
+	member, err := qtx.CreateCommunityMember(repo.ctx, db.CreateCommunityMemberParams{
+		CommunityID:   row.ID,
+		MemberAddress: ownerAddr,
+		Role:          domain.RoleOwner,
+		GrantedVia:    domain.GrantedViaOwner,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("owner membership creation failed %w", err)
+	}
+
+	if err := qtx.AdjustCommunityMemberCount(repo.ctx, db.AdjustCommunityMemberCountParams{ID: row.ID, MemberCount: 1}); err != nil {
+		return nil, fmt.Errorf("member count update failed %w", err)
+	}
+
+	if err := tx.Commit(repo.ctx); err != nil {
+		return nil, fmt.Errorf("transaction commit failed %w", err)
+	}
+
+	community := toDomainCommunity(row)
+	community.MemberCount = 1
+	community.Membership = toDomainMember(member)
+	return &community, nil
+}
+
+func (repo *communityRepository) GetCommunity(idOrSlug string) (*domain.Community, error) {
+
+	var row db.Community
+	var err error
+
+	if uuid, ok := parseUUID(idOrSlug); ok {
+		row, err = repo.q.GetCommunity(repo.ctx, uuid)
+	} else {
+		row, err = repo.q.GetCommunityBySlug(repo.ctx, idOrSlug)
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrCommunityNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	community := toDomainCommunity(row)
+	return &community, nil
+}
+
+func (repo *communityRepository) UpdateCommunity(id string, input domain.CommunityInput) (*domain.Community, error) {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return nil, domain.ErrCommunityNotFound
+	}
+
+	chainID, token, amount := fromCommunityPrice(input.Price)
+
+	row, err := repo.q.UpdateCommunity(repo.ctx, db.UpdateCommunityParams{
+		ID:           uuid,
+		Slug:         input.Slug,
+		Name:         input.Name,
+		Description:  input.Description,
+		JoinPolicy:   input.JoinPolicy,
+		AccessPolicy: input.AccessPolicy,
+		PriceChainID: chainID,
+		PriceToken:   token,
+		PriceAmount:  amount,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrCommunityNotFound
+	}
+	if isUniqueViolation(err) {
+		return nil, domain.ErrCommunitySlugTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	community := toDomainCommunity(row)
+	return &community, nil
+}
+
+func (repo *communityRepository) DeleteCommunity(id string) error {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return domain.ErrCommunityNotFound
+	}
+
+	rows, err := repo.q.DeleteCommunity(repo.ctx, uuid)
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return domain.ErrCommunityNotFound
+	}
+
+	return nil
+}
+
+func (repo *communityRepository) ListMemberCommunities(addr string, limit, offset int) ([]domain.Community, error) {
+
+	rows, err := repo.q.ListMemberCommunities(repo.ctx, db.ListMemberCommunitiesParams{
+		MemberAddress: addr,
+		Limit:         int32(limit),
+		Offset:        int32(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	communities := make([]domain.Community, 0, len(rows))
+	for _, row := range rows {
+		communities = append(communities, toDomainCommunity(row))
+	}
+
+	return communities, nil
+}
+
+func (repo *communityRepository) GetMember(communityID, addr string) (*domain.CommunityMember, error) {
+
+	uuid, ok := parseUUID(communityID)
+	if !ok {
+		return nil, domain.ErrCommunityNotFound
+	}
+
+	row, err := repo.q.GetCommunityMember(repo.ctx, db.GetCommunityMemberParams{
+		CommunityID:   uuid,
+		MemberAddress: addr,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrMemberNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainMember(row), nil
+}
+
+func (repo *communityRepository) AddMember(member domain.CommunityMember) (*domain.CommunityMember, error) {
+
+	uuid, ok := parseUUID(member.CommunityID)
+	if !ok {
+		return nil, domain.ErrCommunityNotFound
+	}
+
+	var blocks []byte
+	if len(member.GrantedBlocks) > 0 {
+		var err error
+		if blocks, err = json.Marshal(member.GrantedBlocks); err != nil {
+			return nil, err
+		}
+	}
+
+	var inviteID pgtype.UUID
+	if member.InviteID != "" {
+		if inviteID, ok = parseUUID(member.InviteID); !ok {
+			return nil, domain.ErrInviteNotFound
+		}
+	}
+
+	tx, err := repo.pool.Begin(repo.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(repo.ctx)
+
+	qtx := repo.q.WithTx(tx)
+
+	row, err := qtx.CreateCommunityMember(repo.ctx, db.CreateCommunityMemberParams{
+		CommunityID:   uuid,
+		MemberAddress: member.Address,
+		Role:          member.Role,
+		GrantedVia:    member.GrantedVia,
+		GrantedPolicy: member.GrantedPolicy,
+		GrantedBlocks: blocks,
+		PaymentTx:     toText(member.PaymentTx),
+		InvitedBy:     toText(member.InvitedBy),
+		InviteID:      inviteID,
+	})
+	if isUniqueViolation(err) && constraintName(err) == "community_members_payment_idx" {
+		return nil, fmt.Errorf("%w: the payment was already used", domain.ErrPaymentInvalid)
+	}
+	if isUniqueViolation(err) {
+		return nil, domain.ErrAlreadyMember
+	}
+	if isForeignKeyViolation(err) {
+		return nil, domain.ErrCommunityNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("membership creation failed %w", err)
+	}
+
+	if inviteID.Valid {
+		claimed, err := qtx.ClaimInviteUse(repo.ctx, inviteID)
+		if err != nil {
+			return nil, fmt.Errorf("invite use claim failed %w", err)
+		}
+		if claimed == 0 {
+			return nil, domain.ErrInviteExpired
+		}
+	}
+
+	if err := qtx.AdjustCommunityMemberCount(repo.ctx, db.AdjustCommunityMemberCountParams{ID: uuid, MemberCount: 1}); err != nil {
+		return nil, fmt.Errorf("member count update failed %w", err)
+	}
+
+	if err := tx.Commit(repo.ctx); err != nil {
+		return nil, fmt.Errorf("transaction commit failed %w", err)
+	}
+
+	return toDomainMember(row), nil
+}
+
+func (repo *communityRepository) SetMemberRole(communityID, addr, role string) (*domain.CommunityMember, error) {
+
+	uuid, ok := parseUUID(communityID)
+	if !ok {
+		return nil, domain.ErrCommunityNotFound
+	}
+
+	row, err := repo.q.UpdateCommunityMemberRole(repo.ctx, db.UpdateCommunityMemberRoleParams{
+		CommunityID:   uuid,
+		MemberAddress: addr,
+		Role:          role,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrMemberNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainMember(row), nil
+}
+
+func (repo *communityRepository) RemoveMember(communityID, addr string) error {
+
+	uuid, ok := parseUUID(communityID)
+	if !ok {
+		return domain.ErrCommunityNotFound
+	}
+
+	tx, err := repo.pool.Begin(repo.ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(repo.ctx)
+
+	qtx := repo.q.WithTx(tx)
+
+	rows, err := qtx.DeleteCommunityMember(repo.ctx, db.DeleteCommunityMemberParams{
+		CommunityID:   uuid,
+		MemberAddress: addr,
+	})
+	if err != nil {
+		return fmt.Errorf("membership deletion failed %w", err)
+	}
+
+	if rows == 0 {
+		return domain.ErrMemberNotFound
+	}
+
+	if err := qtx.AdjustCommunityMemberCount(repo.ctx, db.AdjustCommunityMemberCountParams{ID: uuid, MemberCount: -1}); err != nil {
+		return fmt.Errorf("member count update failed %w", err)
+	}
+
+	if err := tx.Commit(repo.ctx); err != nil {
+		return fmt.Errorf("transaction commit failed %w", err)
+	}
+
+	return nil
+}
+
+func (repo *communityRepository) ListMembers(communityID, role string, limit, offset int) ([]domain.CommunityMember, error) {
+
+	uuid, ok := parseUUID(communityID)
+	if !ok {
+		return nil, domain.ErrCommunityNotFound
+	}
+
+	rows, err := repo.q.ListCommunityMembers(repo.ctx, db.ListCommunityMembersParams{
+		CommunityID: uuid,
+		Role:        role,
+		RowLimit:    int32(limit),
+		RowOffset:   int32(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]domain.CommunityMember, 0, len(rows))
+	for _, row := range rows {
+		member := toDomainMember(db.CommunityMember{
+			CommunityID:   row.CommunityID,
+			MemberAddress: row.MemberAddress,
+			Role:          row.Role,
+			GrantedVia:    row.GrantedVia,
+			GrantedPolicy: row.GrantedPolicy,
+			GrantedBlocks: row.GrantedBlocks,
+			PaymentTx:     row.PaymentTx,
+			InvitedBy:     row.InvitedBy,
+			JoinedAt:      row.JoinedAt,
+			InviteID:      row.InviteID,
+		})
+		member.Handle = row.Handle.String
+		members = append(members, *member)
+	}
+
+	return members, nil
+}
+
+func (repo *communityRepository) ListGatedMemberships(addrs []string) ([]domain.CommunityMember, error) {
+
+	rows, err := repo.q.ListGatedMemberships(repo.ctx, addrs)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]domain.CommunityMember, 0, len(rows))
+	for _, row := range rows {
+		members = append(members, *toDomainMember(row))
+	}
+
+	return members, nil
+}
+
+func fromCommunityPrice(price *domain.CommunityPrice) (pgtype.Int8, pgtype.Text, pgtype.Numeric) {
+
+	if price == nil {
+		return pgtype.Int8{}, pgtype.Text{}, pgtype.Numeric{}
+	}
+
+	amount, _ := new(big.Int).SetString(price.Amount, 10)
+
+	return pgtype.Int8{Int64: int64(price.ChainID), Valid: true},
+		pgtype.Text{String: price.Token, Valid: true},
+		toNumeric(amount)
+}
+
+func toDomainCommunity(row db.Community) domain.Community {
+
+	community := domain.Community{
+		ID:           row.ID.String(),
+		Slug:         row.Slug,
+		Name:         row.Name,
+		Description:  row.Description,
+		OwnerAddress: row.OwnerAddress,
+		JoinPolicy:   row.JoinPolicy,
+		AccessPolicy: row.AccessPolicy,
+		MemberCount:  int(row.MemberCount),
+		CreatedAt:    row.CreatedAt.Time,
+		UpdatedAt:    row.UpdatedAt.Time,
+	}
+
+	if row.PriceToken.Valid {
+		community.Price = &domain.CommunityPrice{
+			ChainID: uint64(row.PriceChainID.Int64),
+			Token:   row.PriceToken.String,
+			Amount:  fromNumeric(row.PriceAmount).String(),
+		}
+	}
+
+	return community
+}
+
+func toDomainMember(row db.CommunityMember) *domain.CommunityMember {
+
+	member := &domain.CommunityMember{
+		CommunityID:   row.CommunityID.String(),
+		Address:       row.MemberAddress,
+		Role:          row.Role,
+		GrantedVia:    row.GrantedVia,
+		GrantedPolicy: row.GrantedPolicy,
+		PaymentTx:     row.PaymentTx.String,
+		InvitedBy:     row.InvitedBy.String,
+		JoinedAt:      row.JoinedAt.Time,
+	}
+
+	if row.InviteID.Valid {
+		member.InviteID = row.InviteID.String()
+	}
+
+	if len(row.GrantedBlocks) > 0 {
+		// written by AddMember, a malformed value only loses the snapshot
+		_ = json.Unmarshal(row.GrantedBlocks, &member.GrantedBlocks)
+	}
+
+	return member
+}
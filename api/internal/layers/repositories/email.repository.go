@@ -0,0 +1,264 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/db"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/jackc/pgx/v5"
+)
+
+type EmailRepository interface {
+	GetEmail(addr string) (*domain.AccountEmail, error)
+
+	// SetEmail stores the address of an account as unverified, awaiting the token
+	// whose hash is given
+	SetEmail(addr, email, tokenHash string, expiresAt time.Time) (*domain.AccountEmail, error)
+
+	// VerifyEmail marks the address holding the token as verified
+	VerifyEmail(tokenHash string) (*domain.AccountEmail, error)
+
+	RemoveEmail(addr string) error
+
+	SetDigest(addr, frequency string) (*domain.AccountEmail, error)
+
+	// ClaimDigests takes up to limit accounts whose digest is due, their digest is
+	// recorded as sent
+	ClaimDigests(dailyBefore, weeklyBefore time.Time, limit int) ([]domain.EmailDigest, error)
+
+	// ResetDigest records a digest as unsent so it is claimed again
+	ResetDigest(addr string, sentAt time.Time) error
+
+	// CountSince counts the notifications of an account stored after since by kind
+	CountSince(addr string, since time.Time) (map[string]int, error)
+
+	// ListSince returns the newest notifications of an account stored after since
+	ListSince(addr string, since time.Time, limit int) ([]domain.Notification, error)
+
+	// Enqueue queues the notifications whose recipients have a verified address and
+	// switched emails of their kind on, it returns how many were queued
+	Enqueue(notificationIDs []int64) (int64, error)
+
+	// ClaimDeliveries takes up to limit due deliveries. They are due again at
+	// leaseUntil unless completed or rescheduled before
+	ClaimDeliveries(limit int, leaseUntil time.Time) ([]domain.EmailDelivery, error)
+
+	CompleteDelivery(id int64) error
+
+	RescheduleDelivery(id int64, next time.Time, lastError string) error
+}
+
+func NewEmailRepository(ctx context.Context, logger *logger.Logger, q *db.Queries) EmailRepository {
+
+	return &emailRepository{
+		ctx:    ctx,
+		logger: *logger,
+		q:      q,
+	}
+}
+
+type emailRepository struct {
+	ctx    context.Context
+	logger logger.Logger
+	q      *db.Queries
+}
+
+func (repo *emailRepository) GetEmail(addr string) (*domain.AccountEmail, error) {
+
+	row, err := repo.q.GetAccountEmail(repo.ctx, addr)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrEmailNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainAccountEmail(row), nil
+}
+
+func (repo *emailRepository) SetEmail(addr, email, tokenHash string, expiresAt time.Time) (*domain.AccountEmail, error) {
+
+	row, err := repo.q.UpsertAccountEmail(repo.ctx, db.UpsertAccountEmailParams{
+		AccountAddress:  addr,
+		Email:           email,
+		VerifyTokenHash: toText(tokenHash),
+		VerifyExpiresAt: toTimestamp(expiresAt),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainAccountEmail(row), nil
+}
+
+func (repo *emailRepository) VerifyEmail(tokenHash string) (*domain.AccountEmail, error) {
+
+	row, err := repo.q.VerifyAccountEmail(repo.ctx, toText(tokenHash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrEmailTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainAccountEmail(row), nil
+}
+
+func (repo *emailRepository) RemoveEmail(addr string) error {
+
+	deleted, err := repo.q.DeleteAccountEmail(repo.ctx, addr)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return domain.ErrEmailNotFound
+	}
+
+	return nil
+}
+
+func (repo *emailRepository) SetDigest(addr, frequency string) (*domain.AccountEmail, error) {
+
+	row, err := repo.q.SetEmailDigest(repo.ctx, db.SetEmailDigestParams{
+		AccountAddress: addr,
+		Digest:         frequency,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrEmailNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainAccountEmail(row), nil
+}
+
+func (repo *emailRepository) ClaimDigests(dailyBefore, weeklyBefore time.Time, limit int) ([]domain.EmailDigest, error) {
+
+	rows, err := repo.q.ClaimDueDigests(repo.ctx, db.ClaimDueDigestsParams{
+		DailyBefore:  toTimestamp(dailyBefore),
+		WeeklyBefore: toTimestamp(weeklyBefore),
+		RowLimit:     int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	digests := make([]domain.EmailDigest, 0, len(rows))
+	for _, row := range rows {
+		digests = append(digests, domain.EmailDigest{
+			Address:   row.AccountAddress,
+			Email:     row.Email,
+			Frequency: row.Digest,
+			Since:     row.Since.Time,
+		})
+	}
+
+	return digests, nil
+}
+
+func (repo *emailRepository) ResetDigest(addr string, sentAt time.Time) error {
+
+	return repo.q.ResetEmailDigest(repo.ctx, db.ResetEmailDigestParams{
+		AccountAddress: addr,
+		DigestSentAt:   toTimestamp(sentAt),
+	})
+}
+
+func (repo *emailRepository) CountSince(addr string, since time.Time) (map[string]int, error) {
+
+	rows, err := repo.q.CountNotificationsSince(repo.ctx, db.CountNotificationsSinceParams{
+		RecipientAddress: addr,
+		CreatedAt:        toTimestamp(since),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Kind] = int(row.Count)
+	}
+
+	return counts, nil
+}
+
+func (repo *emailRepository) ListSince(addr string, since time.Time, limit int) ([]domain.Notification, error) {
+
+	rows, err := repo.q.ListNotificationsSince(repo.ctx, db.ListNotificationsSinceParams{
+		RecipientAddress: addr,
+		CreatedAt:        toTimestamp(since),
+		Limit:            int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainNotifications(rows), nil
+}
+
+func (repo *emailRepository) Enqueue(notificationIDs []int64) (int64, error) {
+
+	return repo.q.EnqueueEmailDeliveries(repo.ctx, notificationIDs)
+}
+
+func (repo *emailRepository) ClaimDeliveries(limit int, leaseUntil time.Time) ([]domain.EmailDelivery, error) {
+
+	rows, err := repo.q.ClaimEmailDeliveries(repo.ctx, db.ClaimEmailDeliveriesParams{
+		RowLimit:   int32(limit),
+		LeaseUntil: toTimestamp(leaseUntil),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]domain.EmailDelivery, 0, len(rows))
+	for _, row := range rows {
+		deliveries = append(deliveries, domain.EmailDelivery{
+			ID:       row.ID,
+			Attempts: int(row.Attempts),
+			Email:    row.Email.String,
+			Notification: domain.Notification{
+				ID:               row.NotificationID,
+				RecipientAddress: row.RecipientAddress,
+				Kind:             row.Kind,
+				ActorAddress:     row.ActorAddress.String,
+				SubjectID:        row.SubjectID,
+				Data:             row.Data,
+				ReadAt:           fromTimestamp(row.ReadAt),
+				CreatedAt:        row.CreatedAt.Time,
+			},
+		})
+	}
+
+	return deliveries, nil
+}
+
+func (repo *emailRepository) CompleteDelivery(id int64) error {
+
+	return repo.q.DeleteEmailDelivery(repo.ctx, id)
+}
+
+func (repo *emailRepository) RescheduleDelivery(id int64, next time.Time, lastError string) error {
+
+	return repo.q.RescheduleEmailDelivery(repo.ctx, db.RescheduleEmailDeliveryParams{
+		ID:            id,
+		NextAttemptAt: toTimestamp(next),
+		LastError:     toText(lastError),
+	})
+}
+
+func toDomainAccountEmail(row db.AccountEmail) *domain.AccountEmail {
+	return &domain.AccountEmail{
+		Address:      row.AccountAddress,
+		Email:        row.Email,
+		VerifiedAt:   fromTimestamp(row.VerifiedAt),
+		Digest:       row.Digest,
+		DigestSentAt: fromTimestamp(row.DigestSentAt),
+		CreatedAt:    row.CreatedAt.Time,
+		UpdatedAt:    row.UpdatedAt.Time,
+	}
+}
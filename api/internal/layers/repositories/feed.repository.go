@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/db"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+)
+
+type FeedRepository interface {
+	// ListFeed ranks the published posts of the creators addr follows and of the owners
+	// of the communities addr belongs to. Posts come after the cursor, a cursor without
+	// an ID starts the feed. since bounds how old posts may be. Authors addr blocked or
+	// muted and authors blocking addr are left out
+	ListFeed(addr string, after domain.FeedCursor, since time.Time, limit int) ([]domain.FeedEntry, error)
+}
+
+func NewFeedRepository(ctx context.Context, logger *logger.Logger, q *db.Queries) FeedRepository {
+
+	return &feedRepository{
+		ctx:    ctx,
+		logger: *logger,
+		q:      q,
+	}
+}
+
+type feedRepository struct {
+	ctx    context.Context
+	logger logger.Logger
+	q      *db.Queries
+}
+
+func (repo *feedRepository) ListFeed(addr string, after domain.FeedCursor, since time.Time, limit int) ([]domain.FeedEntry, error) {
+
+	// the first page starts past every row
+	if after.ID == "" {
+		after.Score = math.MaxFloat64
+		after.ID = "ffffffff-ffff-ffff-ffff-ffffffffffff"
+	}
+
+	afterID, ok := parseUUID(after.ID)
+	if !ok {
+		return nil, domain.ErrCursorInvalid
+	}
+
+	rows, err := repo.q.ListFeed(repo.ctx, db.ListFeedParams{
+		ViewerAddress: addr,
+		BoostSeconds:  after.BoostSeconds,
+		AsOf:          toTimestamp(after.AsOf),
+		Since:         toTimestamp(since),
+		AfterScore:    after.Score,
+		AfterID:       afterID,
+		RowLimit:      int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]domain.FeedEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, domain.FeedEntry{
+			Post: toDomainPost(db.Post{
+				ID:             row.ID,
+				AuthorAddress:  row.AuthorAddress,
+				Title:          row.Title,
+				Body:           row.Body,
+				BodyFormat:     row.BodyFormat,
+				Status:         row.Status,
+				AccessPolicy:   row.AccessPolicy,
+				CreatedAt:      row.CreatedAt,
+				UpdatedAt:      row.UpdatedAt,
+				PublishedAt:    row.PublishedAt,
+				BodyCiphertext: row.BodyCiphertext,
+				DataKey:        row.DataKey,
+				KeyID:          row.KeyID,
+				Teaser:         row.Teaser,
+				Tags:           row.Tags,
+				PublishAt:      row.PublishAt,
+			}),
+			ReactionCount: int(row.ReactionCount),
+			CommentCount:  int(row.CommentCount),
+			Score:         row.Score,
+		})
+	}
+
+	return entries, nil
+}
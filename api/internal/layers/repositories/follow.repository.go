@@ -0,0 +1,372 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Xebec19/jibe/api/internal/db"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type FollowRepository interface {
+	// Follow stores the follow and counts it, following an account twice is a no-op. It
+	// fails with ErrFollowBlocked while either account blocks the other
+	Follow(follower, followee string) error
+
+	Unfollow(follower, followee string) error
+
+	// Import follows every followee at once. Followees already followed or blocked
+	// either way are skipped, it returns how many were followed
+	Import(follower string, followees []string) (int, error)
+
+	GetStats(addr string) (*domain.FollowStats, error)
+
+	GetRelationship(viewer, addr string) (*domain.Relationship, error)
+
+	// ListFollowers returns the followers of addr newest first, starting before the
+	// cursor
+	ListFollowers(addr string, before domain.Cursor, limit int) ([]domain.AccountEntry, error)
+
+	ListFollowing(addr string, before domain.Cursor, limit int) ([]domain.AccountEntry, error)
+
+	// Restrict blocks or mutes target for actor. A block also ends the follows between
+	// the two accounts
+	Restrict(actor, target, kind string) error
+
+	Unrestrict(actor, target, kind string) error
+
+	ListRestrictions(actor, kind string, before domain.Cursor, limit int) ([]domain.AccountEntry, error)
+}
+
+func NewFollowRepository(ctx context.Context, logger *logger.Logger, pool *pgxpool.Pool, q *db.Queries) FollowRepository {
+
+	return &followRepository{
+		ctx:    ctx,
+		logger: *logger,
+		pool:   pool,
+		q:      q,
+	}
+}
+
+type followRepository struct {
+	ctx    context.Context
+	logger logger.Logger
+	pool   *pgxpool.Pool
+	q      *db.Queries
+}
+
+func (repo *followRepository) Follow(follower, followee string) error {
+
+	tx, err := repo.pool.Begin(repo.ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(repo.ctx)
+
+	qtx := repo.q.WithTx(tx)
+
+	if err := lockAccountPairs(repo.ctx, qtx, follower, followee); err != nil {
+		return err
+	}
+
+	created, err := qtx.FollowAccount(repo.ctx, db.FollowAccountParams{
+		FollowerAddress: follower,
+		FolloweeAddress: followee,
+	})
+	if err != nil {
+		return err
+	}
+
+	if created > 0 {
+		if err := adjustFollowCounts(repo.ctx, qtx, follower, followee, 1); err != nil {
+			return err
+		}
+		return tx.Commit(repo.ctx)
+	}
+
+	// nothing was inserted, either the follow exists or a block stopped it
+	blocked, err := qtx.IsBlockedBetween(repo.ctx, db.IsBlockedBetweenParams{
+		ActorAddress:  follower,
+		TargetAddress: followee,
+	})
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return domain.ErrFollowBlocked
+	}
+
+	return tx.Commit(repo.ctx)
+}
+
+func (repo *followRepository) Unfollow(follower, followee string) error {
+
+	tx, err := repo.pool.Begin(repo.ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(repo.ctx)
+
+	qtx := repo.q.WithTx(tx)
+
+	removed, err := unfollow(repo.ctx, qtx, follower, followee)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return domain.ErrFollowNotFound
+	}
+
+	return tx.Commit(repo.ctx)
+}
+
+func (repo *followRepository) Import(follower string, followees []string) (int, error) {
+
+	tx, err := repo.pool.Begin(repo.ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(repo.ctx)
+
+	qtx := repo.q.WithTx(tx)
+
+	if err := lockAccountPairs(repo.ctx, qtx, follower, followees...); err != nil {
+		return 0, err
+	}
+
+	followed, err := qtx.ImportFollows(repo.ctx, db.ImportFollowsParams{
+		FollowerAddress:   follower,
+		FolloweeAddresses: followees,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if len(followed) > 0 {
+		if err := qtx.IncrementFollowerCounts(repo.ctx, followed); err != nil {
+			return 0, err
+		}
+
+		err := qtx.AdjustFollowingCount(repo.ctx, db.AdjustFollowingCountParams{
+			AccountAddress: follower,
+			FollowingCount: int32(len(followed)),
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(repo.ctx); err != nil {
+		return 0, err
+	}
+
+	return len(followed), nil
+}
+
+func (repo *followRepository) GetStats(addr string) (*domain.FollowStats, error) {
+
+	stats := &domain.FollowStats{Address: addr}
+
+	row, err := repo.q.GetFollowCounts(repo.ctx, addr)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// accounts nobody followed yet have no counts stored
+		return stats, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stats.FollowerCount = int(row.FollowerCount)
+	stats.FollowingCount = int(row.FollowingCount)
+
+	return stats, nil
+}
+
+func (repo *followRepository) GetRelationship(viewer, addr string) (*domain.Relationship, error) {
+
+	row, err := repo.q.GetRelationship(repo.ctx, db.GetRelationshipParams{
+		ViewerAddress:  viewer,
+		AccountAddress: addr,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.Relationship{
+		Following:  row.Following,
+		FollowedBy: row.FollowedBy,
+		Blocking:   row.Blocking,
+		Muting:     row.Muting,
+		BlockedBy:  row.BlockedBy,
+	}, nil
+}
+
+func (repo *followRepository) ListFollowers(addr string, before domain.Cursor, limit int) ([]domain.AccountEntry, error) {
+
+	rows, err := repo.q.ListFollowers(repo.ctx, db.ListFollowersParams{
+		AccountAddress:  addr,
+		BeforeAddress:   before.ID,
+		BeforeCreatedAt: toTimestamp(before.CreatedAt),
+		RowLimit:        int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]domain.AccountEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, toDomainAccountEntry(db.ListRestrictionsRow(row)))
+	}
+
+	return entries, nil
+}
+
+func (repo *followRepository) ListFollowing(addr string, before domain.Cursor, limit int) ([]domain.AccountEntry, error) {
+
+	rows, err := repo.q.ListFollowing(repo.ctx, db.ListFollowingParams{
+		AccountAddress:  addr,
+		BeforeAddress:   before.ID,
+		BeforeCreatedAt: toTimestamp(before.CreatedAt),
+		RowLimit:        int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]domain.AccountEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, toDomainAccountEntry(db.ListRestrictionsRow(row)))
+	}
+
+	return entries, nil
+}
+
+func (repo *followRepository) Restrict(actor, target, kind string) error {
+
+	tx, err := repo.pool.Begin(repo.ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(repo.ctx)
+
+	qtx := repo.q.WithTx(tx)
+
+	if kind == domain.RestrictionBlock {
+		if err := lockAccountPairs(repo.ctx, qtx, actor, target); err != nil {
+			return err
+		}
+	}
+
+	created, err := qtx.CreateRestriction(repo.ctx, db.CreateRestrictionParams{
+		ActorAddress:  actor,
+		TargetAddress: target,
+		Kind:          kind,
+	})
+	if err != nil {
+		return err
+	}
+	if created == 0 {
+		return domain.ErrRestrictionExists
+	}
+
+	if kind == domain.RestrictionBlock {
+		if _, err := unfollow(repo.ctx, qtx, actor, target); err != nil {
+			return err
+		}
+		if _, err := unfollow(repo.ctx, qtx, target, actor); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(repo.ctx)
+}
+
+func (repo *followRepository) Unrestrict(actor, target, kind string) error {
+
+	deleted, err := repo.q.DeleteRestriction(repo.ctx, db.DeleteRestrictionParams{
+		ActorAddress:  actor,
+		TargetAddress: target,
+		Kind:          kind,
+	})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return domain.ErrRestrictionMissing
+	}
+
+	return nil
+}
+
+func (repo *followRepository) ListRestrictions(actor, kind string, before domain.Cursor, limit int) ([]domain.AccountEntry, error) {
+
+	rows, err := repo.q.ListRestrictions(repo.ctx, db.ListRestrictionsParams{
+		ActorAddress:    actor,
+		Kind:            kind,
+		BeforeAddress:   before.ID,
+		BeforeCreatedAt: toTimestamp(before.CreatedAt),
+		RowLimit:        int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]domain.AccountEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, toDomainAccountEntry(row))
+	}
+
+	return entries, nil
+}
+
+// unfollow removes a follow inside tx and uncounts it, it reports if there was one
+func unfollow(ctx context.Context, q *db.Queries, follower, followee string) (bool, error) {
+
+	removed, err := q.UnfollowAccount(ctx, db.UnfollowAccountParams{
+		FollowerAddress: follower,
+		FolloweeAddress: followee,
+	})
+	if err != nil || removed == 0 {
+		return false, err
+	}
+
+	return true, adjustFollowCounts(ctx, q, follower, followee, -1)
+}
+
+func adjustFollowCounts(ctx context.Context, q *db.Queries, follower, followee string, delta int32) error {
+
+	err := q.AdjustFollowingCount(ctx, db.AdjustFollowingCountParams{
+		AccountAddress: follower,
+		FollowingCount: delta,
+	})
+	if err != nil {
+		return err
+	}
+
+	return q.AdjustFollowerCount(ctx, db.AdjustFollowerCountParams{
+		AccountAddress: followee,
+		FollowerCount:  delta,
+	})
+}
+
+// lockAccountPairs serializes follows and blocks between addr and each of others until
+// the tx ends. Without it a follow checked against blocks before a concurrent block
+// commits would survive the block ending follows between the two accounts
+func lockAccountPairs(ctx context.Context, q *db.Queries, addr string, others ...string) error {
+
+	return q.LockAccountPairs(ctx, db.LockAccountPairsParams{
+		AccountAddress: addr,
+		OtherAddresses: others,
+	})
+}
+
+func toDomainAccountEntry(row db.ListRestrictionsRow) domain.AccountEntry {
+	return domain.AccountEntry{
+		Address: row.Address,
+		Handle:  row.Handle.String,
+		Since:   row.CreatedAt.Time,
+	}
+}
@@ -0,0 +1,144 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/db"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type HandleRepository interface {
+	// ClaimHandle retires the current handle of the address, if any, and assigns the new
+	// one in a single transaction. Retired handles past their release window are freed first
+	ClaimHandle(handle, skeleton, ethAddr string, releaseBefore time.Time) (*domain.Handle, error)
+
+	// GetBySkeleton returns the handle, active or retired, matching the skeleton
+	GetBySkeleton(skeleton string) (*domain.Handle, error)
+
+	// GetActiveByAddress returns the handle currently used by the address
+	GetActiveByAddress(ethAddr string) (*domain.Handle, error)
+
+	// ListByAddress returns every handle the address has used, newest first
+	ListByAddress(ethAddr string) ([]domain.Handle, error)
+}
+
+func NewHandleRepository(ctx context.Context, logger *logger.Logger, pool *pgxpool.Pool, q *db.Queries) HandleRepository {
+
+	return &handleRepository{
+		ctx:    ctx,
+		logger: *logger,
+		pool:   pool,
+		q:      q,
+	}
+}
+
+type handleRepository struct {
+	ctx    context.Context
+	logger logger.Logger
+	pool   *pgxpool.Pool
+	q      *db.Queries
+}
+
+func (repo *handleRepository) ClaimHandle(handle, skeleton, ethAddr string, releaseBefore time.Time) (*domain.Handle, error) {
+
+	tx, err := repo.pool.Begin(repo.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("transaction begin failed %w", err)
+	}
+	defer tx.Rollback(repo.ctx)
+
+	qtx := repo.q.WithTx(tx)
+
+	_, err = qtx.ReleaseRetiredHandle(repo.ctx, db.ReleaseRetiredHandleParams{
+		Skeleton:  skeleton,
+		RetiredAt: toTimestamp(releaseBefore),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("retired handle release failed %w", err)
+	}
+
+	_, err = qtx.RetireActiveHandle(repo.ctx, ethAddr)
+	if err != nil {
+		return nil, fmt.Errorf("active handle retirement failed %w", err)
+	}
+
+	row, err := qtx.CreateHandle(repo.ctx, db.CreateHandleParams{
+		Handle:     handle,
+		Skeleton:   skeleton,
+		EthAddress: ethAddr,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, domain.ErrHandleTaken
+		}
+		return nil, fmt.Errorf("handle creation failed %w", err)
+	}
+
+	if err := tx.Commit(repo.ctx); err != nil {
+		return nil, fmt.Errorf("transaction commit failed %w", err)
+	}
+
+	h := toDomainHandle(row)
+	return &h, nil
+}
+
+func (repo *handleRepository) GetBySkeleton(skeleton string) (*domain.Handle, error) {
+
+	row, err := repo.q.GetHandleBySkeleton(repo.ctx, skeleton)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrHandleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	h := toDomainHandle(row)
+	return &h, nil
+}
+
+func (repo *handleRepository) GetActiveByAddress(ethAddr string) (*domain.Handle, error) {
+
+	row, err := repo.q.GetActiveHandleByAddress(repo.ctx, ethAddr)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrHandleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	h := toDomainHandle(row)
+	return &h, nil
+}
+
+func (repo *handleRepository) ListByAddress(ethAddr string) ([]domain.Handle, error) {
+
+	rows, err := repo.q.ListHandlesByAddress(repo.ctx, ethAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	handles := make([]domain.Handle, 0, len(rows))
+	for _, row := range rows {
+		handles = append(handles, toDomainHandle(row))
+	}
+
+	return handles, nil
+}
+
+func toDomainHandle(row db.Handle) domain.Handle {
+
+	return domain.Handle{
+		Handle:     row.Handle,
+		EthAddress: row.EthAddress,
+		CreatedAt:  row.CreatedAt.Time,
+		RetiredAt:  fromTimestamp(row.RetiredAt),
+	}
+}
@@ -0,0 +1,481 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/Xebec19/jibe/api/internal/db"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IndexerRepository interface {
+	// ListAccessPolicies returns every distinct access policy stored on posts and
+	// communities, the tokens paid communities are priced in are returned as
+	// erc20_balance policies
+	ListAccessPolicies() ([][]byte, error)
+
+	// TrackContract starts following a contract. New contracts are backfilled from
+	// startBlock, contracts already tracked are left untouched
+	TrackContract(chainID uint64, address, tokenKind string, startBlock uint64) error
+
+	ListContracts(chainID uint64) ([]domain.IndexedContract, error)
+
+	GetContract(chainID uint64, address string) (*domain.IndexedContract, error)
+
+	// GetCheckpoint returns the last block fully indexed on the chain
+	GetCheckpoint(chainID uint64) (*domain.BlockRef, error)
+
+	// ListRecentBlocks returns hashes of the latest indexed blocks, newest first
+	ListRecentBlocks(chainID uint64, limit int) ([]domain.BlockRef, error)
+
+	// ApplyRange stores the transfers of a block range, updates holdings and moves the
+	// checkpoint and every caught up contract to head in a single transaction
+	ApplyRange(chainID uint64, transfers []domain.TokenTransfer, from uint64, head domain.BlockRef, keepBlocks int) error
+
+	// ApplyBackfill stores transfers of a contract which joined after the chain's
+	// checkpoint, up to block to
+	ApplyBackfill(chainID uint64, contract string, transfers []domain.TokenTransfer, to uint64) error
+
+	// RollbackTo undoes every transfer after the ancestor block and resets the checkpoint
+	// to it. It returns the transfers which were undone
+	RollbackTo(chainID uint64, ancestor domain.BlockRef) ([]domain.TokenTransfer, error)
+
+	// ListHolderTransfers returns every transfer in or out of holder, oldest first. A nil
+	// tokenID matches every token of the contract
+	ListHolderTransfers(chainID uint64, contract string, tokenID *big.Int, holder string) ([]domain.TokenTransfer, error)
+
+	// ListTransactionTransfers returns the indexed transfers of a transaction
+	ListTransactionTransfers(chainID uint64, txHash string) ([]domain.TokenTransfer, error)
+
+	// HolderBalanceAt sums the transfers of holder up to and including block
+	HolderBalanceAt(chainID uint64, contract string, tokenID *big.Int, holder string, block uint64) (*big.Int, error)
+
+	// HoldersAt returns the balance of every address holding the token at the end of block
+	HoldersAt(chainID uint64, contract string, tokenID *big.Int, block uint64) (map[string]*big.Int, error)
+}
+
+func NewIndexerRepository(ctx context.Context, logger *logger.Logger, pool *pgxpool.Pool, q *db.Queries) IndexerRepository {
+
+	return &indexerRepository{
+		ctx:    ctx,
+		logger: *logger,
+		pool:   pool,
+		q:      q,
+	}
+}
+
+type indexerRepository struct {
+	ctx    context.Context
+	logger logger.Logger
+	pool   *pgxpool.Pool
+	q      *db.Queries
+}
+
+func (repo *indexerRepository) ListAccessPolicies() ([][]byte, error) {
+
+	return repo.q.ListAccessPolicies(repo.ctx)
+}
+
+func (repo *indexerRepository) TrackContract(chainID uint64, address, tokenKind string, startBlock uint64) error {
+
+	return repo.q.UpsertIndexedContract(repo.ctx, db.UpsertIndexedContractParams{
+		ChainID:      int64(chainID),
+		Address:      address,
+		TokenKind:    tokenKind,
+		BackfilledTo: int64(startBlock) - 1,
+	})
+}
+
+func (repo *indexerRepository) ListContracts(chainID uint64) ([]domain.IndexedContract, error) {
+
+	rows, err := repo.q.ListIndexedContracts(repo.ctx, int64(chainID))
+	if err != nil {
+		return nil, err
+	}
+
+	contracts := make([]domain.IndexedContract, 0, len(rows))
+	for _, row := range rows {
+		contracts = append(contracts, toDomainContract(row))
+	}
+
+	return contracts, nil
+}
+
+func (repo *indexerRepository) GetContract(chainID uint64, address string) (*domain.IndexedContract, error) {
+
+	row, err := repo.q.GetIndexedContract(repo.ctx, db.GetIndexedContractParams{
+		ChainID: int64(chainID),
+		Address: address,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrIndexedContractNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	contract := toDomainContract(row)
+	return &contract, nil
+}
+
+func (repo *indexerRepository) GetCheckpoint(chainID uint64) (*domain.BlockRef, error) {
+
+	row, err := repo.q.GetIndexerCheckpoint(repo.ctx, int64(chainID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrCheckpointNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.BlockRef{Number: uint64(row.BlockNumber), Hash: row.BlockHash}, nil
+}
+
+func (repo *indexerRepository) ListRecentBlocks(chainID uint64, limit int) ([]domain.BlockRef, error) {
+
+	rows, err := repo.q.ListIndexedBlocks(repo.ctx, db.ListIndexedBlocksParams{
+		ChainID: int64(chainID),
+		Limit:   int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make([]domain.BlockRef, 0, len(rows))
+	for _, row := range rows {
+		blocks = append(blocks, domain.BlockRef{Number: uint64(row.BlockNumber), Hash: row.BlockHash})
+	}
+
+	return blocks, nil
+}
+
+func (repo *indexerRepository) ApplyRange(chainID uint64, transfers []domain.TokenTransfer, from uint64, head domain.BlockRef, keepBlocks int) error {
+
+	tx, err := repo.pool.Begin(repo.ctx)
+	if err != nil {
+		return fmt.Errorf("transaction begin failed %w", err)
+	}
+	defer tx.Rollback(repo.ctx)
+
+	qtx := repo.q.WithTx(tx)
+
+	if err := repo.applyTransfers(qtx, chainID, transfers); err != nil {
+		return err
+	}
+
+	err = qtx.AdvanceContractBackfill(repo.ctx, db.AdvanceContractBackfillParams{
+		SyncedTo:      int64(head.Number),
+		ChainID:       int64(chainID),
+		PreviousBlock: int64(from) - 1,
+	})
+	if err != nil {
+		return fmt.Errorf("contract progress update failed %w", err)
+	}
+
+	err = qtx.UpsertIndexerCheckpoint(repo.ctx, db.UpsertIndexerCheckpointParams{
+		ChainID:     int64(chainID),
+		BlockNumber: int64(head.Number),
+		BlockHash:   head.Hash,
+	})
+	if err != nil {
+		return fmt.Errorf("checkpoint update failed %w", err)
+	}
+
+	err = qtx.UpsertIndexedBlock(repo.ctx, db.UpsertIndexedBlockParams{
+		ChainID:     int64(chainID),
+		BlockNumber: int64(head.Number),
+		BlockHash:   head.Hash,
+	})
+	if err != nil {
+		return fmt.Errorf("block hash insertion failed %w", err)
+	}
+
+	err = qtx.PruneIndexedBlocks(repo.ctx, db.PruneIndexedBlocksParams{
+		ChainID:     int64(chainID),
+		BlockNumber: int64(head.Number) - int64(keepBlocks),
+	})
+	if err != nil {
+		return fmt.Errorf("block hash pruning failed %w", err)
+	}
+
+	return tx.Commit(repo.ctx)
+}
+
+func (repo *indexerRepository) ApplyBackfill(chainID uint64, contract string, transfers []domain.TokenTransfer, to uint64) error {
+
+	tx, err := repo.pool.Begin(repo.ctx)
+	if err != nil {
+		return fmt.Errorf("transaction begin failed %w", err)
+	}
+	defer tx.Rollback(repo.ctx)
+
+	qtx := repo.q.WithTx(tx)
+
+	if err := repo.applyTransfers(qtx, chainID, transfers); err != nil {
+		return err
+	}
+
+	err = qtx.UpdateContractBackfill(repo.ctx, db.UpdateContractBackfillParams{
+		ChainID:      int64(chainID),
+		Address:      contract,
+		BackfilledTo: int64(to),
+	})
+	if err != nil {
+		return fmt.Errorf("contract progress update failed %w", err)
+	}
+
+	return tx.Commit(repo.ctx)
+}
+
+func (repo *indexerRepository) RollbackTo(chainID uint64, ancestor domain.BlockRef) ([]domain.TokenTransfer, error) {
+
+	tx, err := repo.pool.Begin(repo.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("transaction begin failed %w", err)
+	}
+	defer tx.Rollback(repo.ctx)
+
+	qtx := repo.q.WithTx(tx)
+
+	rows, err := qtx.ListTokenTransfersAfter(repo.ctx, db.ListTokenTransfersAfterParams{
+		ChainID:     int64(chainID),
+		BlockNumber: int64(ancestor.Number),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("orphaned transfer lookup failed %w", err)
+	}
+
+	undone := make([]domain.TokenTransfer, 0, len(rows))
+	for _, row := range rows {
+		t := toDomainTransfer(row)
+		// replaying the transfer backwards restores the balances before it
+		if err := repo.moveBalance(qtx, t, t.To, t.From); err != nil {
+			return nil, err
+		}
+		undone = append(undone, t)
+	}
+
+	if _, err := qtx.DeleteTokenTransfersAfter(repo.ctx, db.DeleteTokenTransfersAfterParams{
+		ChainID:     int64(chainID),
+		BlockNumber: int64(ancestor.Number),
+	}); err != nil {
+		return nil, fmt.Errorf("orphaned transfer deletion failed %w", err)
+	}
+
+	if err := qtx.DeleteEmptyTokenHoldings(repo.ctx, int64(chainID)); err != nil {
+		return nil, fmt.Errorf("empty holdings cleanup failed %w", err)
+	}
+
+	if err := qtx.DeleteIndexedBlocksAfter(repo.ctx, db.DeleteIndexedBlocksAfterParams{
+		ChainID:     int64(chainID),
+		BlockNumber: int64(ancestor.Number),
+	}); err != nil {
+		return nil, fmt.Errorf("orphaned block deletion failed %w", err)
+	}
+
+	if err := qtx.ClampContractBackfill(repo.ctx, db.ClampContractBackfillParams{
+		ChainID:      int64(chainID),
+		BackfilledTo: int64(ancestor.Number),
+	}); err != nil {
+		return nil, fmt.Errorf("contract progress rollback failed %w", err)
+	}
+
+	if err := qtx.UpsertIndexerCheckpoint(repo.ctx, db.UpsertIndexerCheckpointParams{
+		ChainID:     int64(chainID),
+		BlockNumber: int64(ancestor.Number),
+		BlockHash:   ancestor.Hash,
+	}); err != nil {
+		return nil, fmt.Errorf("checkpoint rollback failed %w", err)
+	}
+
+	if err := tx.Commit(repo.ctx); err != nil {
+		return nil, fmt.Errorf("transaction commit failed %w", err)
+	}
+
+	return undone, nil
+}
+
+func (repo *indexerRepository) ListHolderTransfers(chainID uint64, contract string, tokenID *big.Int, holder string) ([]domain.TokenTransfer, error) {
+
+	rows, err := repo.q.ListHolderTransfers(repo.ctx, db.ListHolderTransfersParams{
+		ChainID:    int64(chainID),
+		Contract:   contract,
+		Holder:     holder,
+		MatchToken: tokenID != nil,
+		TokenID:    toNumeric(tokenID),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	transfers := make([]domain.TokenTransfer, 0, len(rows))
+	for _, row := range rows {
+		transfers = append(transfers, toDomainTransfer(row))
+	}
+
+	return transfers, nil
+}
+
+func (repo *indexerRepository) ListTransactionTransfers(chainID uint64, txHash string) ([]domain.TokenTransfer, error) {
+
+	rows, err := repo.q.ListTransactionTransfers(repo.ctx, db.ListTransactionTransfersParams{
+		ChainID: int64(chainID),
+		TxHash:  txHash,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	transfers := make([]domain.TokenTransfer, 0, len(rows))
+	for _, row := range rows {
+		transfers = append(transfers, toDomainTransfer(row))
+	}
+
+	return transfers, nil
+}
+
+func (repo *indexerRepository) HolderBalanceAt(chainID uint64, contract string, tokenID *big.Int, holder string, block uint64) (*big.Int, error) {
+
+	balance, err := repo.q.GetHolderBalanceAt(repo.ctx, db.GetHolderBalanceAtParams{
+		Holder:      holder,
+		ChainID:     int64(chainID),
+		Contract:    contract,
+		BlockNumber: int64(block),
+		MatchToken:  tokenID != nil,
+		TokenID:     toNumeric(tokenID),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return fromNumeric(balance), nil
+}
+
+func (repo *indexerRepository) HoldersAt(chainID uint64, contract string, tokenID *big.Int, block uint64) (map[string]*big.Int, error) {
+
+	rows, err := repo.q.ListHoldersAt(repo.ctx, db.ListHoldersAtParams{
+		ChainID:     int64(chainID),
+		Contract:    contract,
+		BlockNumber: int64(block),
+		MatchToken:  tokenID != nil,
+		TokenID:     toNumeric(tokenID),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	holders := make(map[string]*big.Int, len(rows))
+	for _, row := range rows {
+		holders[row.Holder] = fromNumeric(row.Balance)
+	}
+
+	return holders, nil
+}
+
+// applyTransfers inserts the transfers and moves balances for the ones not seen
+// before, so a range can safely be applied twice
+func (repo *indexerRepository) applyTransfers(qtx *db.Queries, chainID uint64, transfers []domain.TokenTransfer) error {
+
+	for _, t := range transfers {
+		inserted, err := qtx.CreateTokenTransfer(repo.ctx, db.CreateTokenTransferParams{
+			ChainID:     int64(chainID),
+			Contract:    t.Contract,
+			TokenKind:   t.TokenKind,
+			TokenID:     toNumeric(t.TokenID),
+			FromAddress: t.From,
+			ToAddress:   t.To,
+			Amount:      toNumeric(t.Amount),
+			BlockNumber: int64(t.BlockNumber),
+			BlockHash:   t.BlockHash,
+			TxHash:      t.TxHash,
+			LogIndex:    int32(t.LogIndex),
+			BatchIndex:  int32(t.BatchIndex),
+			BlockTime:   toNullTimestamp(t.BlockTime),
+		})
+		if err != nil {
+			return fmt.Errorf("transfer insertion failed %w", err)
+		}
+
+		if inserted == 0 {
+			continue
+		}
+
+		if err := repo.moveBalance(qtx, t, t.From, t.To); err != nil {
+			return err
+		}
+	}
+
+	if len(transfers) > 0 {
+		if err := qtx.DeleteEmptyTokenHoldings(repo.ctx, int64(chainID)); err != nil {
+			return fmt.Errorf("empty holdings cleanup failed %w", err)
+		}
+	}
+
+	return nil
+}
+
+// moveBalance debits the amount of the transfer from one holder and credits the
+// other. The zero address stands for mints and burns and holds nothing
+func (repo *indexerRepository) moveBalance(qtx *db.Queries, t domain.TokenTransfer, from, to string) error {
+
+	for _, leg := range []struct {
+		holder string
+		amount *big.Int
+	}{
+		{from, new(big.Int).Neg(t.Amount)},
+		{to, t.Amount},
+	} {
+		if leg.holder == domain.ZeroAddress {
+			continue
+		}
+
+		err := qtx.AddTokenHoldingBalance(repo.ctx, db.AddTokenHoldingBalanceParams{
+			ChainID:      int64(t.ChainID),
+			Contract:     t.Contract,
+			TokenKind:    t.TokenKind,
+			TokenID:      toNumeric(t.TokenID),
+			Holder:       leg.holder,
+			Balance:      toNumeric(leg.amount),
+			UpdatedBlock: int64(t.BlockNumber),
+		})
+		if err != nil {
+			return fmt.Errorf("holding update failed %w", err)
+		}
+	}
+
+	return nil
+}
+
+func toDomainTransfer(row db.TokenTransfer) domain.TokenTransfer {
+
+	return domain.TokenTransfer{
+		ChainID:     uint64(row.ChainID),
+		Contract:    row.Contract,
+		TokenKind:   row.TokenKind,
+		TokenID:     fromNumeric(row.TokenID),
+		From:        row.FromAddress,
+		To:          row.ToAddress,
+		Amount:      fromNumeric(row.Amount),
+		BlockNumber: uint64(row.BlockNumber),
+		BlockHash:   row.BlockHash,
+		BlockTime:   fromTimestamp(row.BlockTime),
+		TxHash:      row.TxHash,
+		LogIndex:    uint(row.LogIndex),
+		BatchIndex:  int(row.BatchIndex),
+	}
+}
+
+func toDomainContract(row db.IndexedContract) domain.IndexedContract {
+
+	return domain.IndexedContract{
+		ChainID:      uint64(row.ChainID),
+		Address:      row.Address,
+		TokenKind:    row.TokenKind,
+		BackfilledTo: uint64(max(row.BackfilledTo, 0)),
+	}
+}
@@ -0,0 +1,201 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Xebec19/jibe/api/internal/db"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type InviteRepository interface {
+	CreateInvite(communityID, inviterAddr, code string, input domain.InviteInput) (*domain.CommunityInvite, error)
+
+	GetInvite(id string) (*domain.CommunityInvite, error)
+
+	GetInviteByCode(code string) (*domain.CommunityInvite, error)
+
+	// ListInvites returns invites of a community, newest first. An empty inviter lists
+	// the invites of every member
+	ListInvites(communityID, inviterAddr string, limit, offset int) ([]domain.CommunityInvite, error)
+
+	// RevokeInvite stops an invite from being redeemed, revoked invites keep their stats
+	RevokeInvite(id string) error
+
+	CountClick(id string) error
+
+	CountSignIn(id string) error
+}
+
+func NewInviteRepository(ctx context.Context, logger *logger.Logger, q *db.Queries) InviteRepository {
+
+	return &inviteRepository{
+		ctx:    ctx,
+		logger: *logger,
+		q:      q,
+	}
+}
+
+type inviteRepository struct {
+	ctx    context.Context
+	logger logger.Logger
+	q      *db.Queries
+}
+
+func (repo *inviteRepository) CreateInvite(communityID, inviterAddr, code string, input domain.InviteInput) (*domain.CommunityInvite, error) {
+
+	uuid, ok := parseUUID(communityID)
+	if !ok {
+		return nil, domain.ErrCommunityNotFound
+	}
+
+	var maxUses pgtype.Int4
+	if input.MaxUses != nil {
+		maxUses = pgtype.Int4{Int32: int32(*input.MaxUses), Valid: true}
+	}
+
+	row, err := repo.q.CreateCommunityInvite(repo.ctx, db.CreateCommunityInviteParams{
+		CommunityID:    uuid,
+		Code:           code,
+		InviterAddress: inviterAddr,
+		MaxUses:        maxUses,
+		ExpiresAt:      toNullTimestamp(input.ExpiresAt),
+		AccessPolicy:   input.AccessPolicy,
+	})
+	if isForeignKeyViolation(err) {
+		return nil, domain.ErrCommunityNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	invite := toDomainInvite(row)
+	return &invite, nil
+}
+
+func (repo *inviteRepository) GetInvite(id string) (*domain.CommunityInvite, error) {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return nil, domain.ErrInviteNotFound
+	}
+
+	row, err := repo.q.GetCommunityInvite(repo.ctx, uuid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrInviteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	invite := toDomainInvite(row)
+	return &invite, nil
+}
+
+func (repo *inviteRepository) GetInviteByCode(code string) (*domain.CommunityInvite, error) {
+
+	row, err := repo.q.GetCommunityInviteByCode(repo.ctx, code)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrInviteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	invite := toDomainInvite(row)
+	return &invite, nil
+}
+
+func (repo *inviteRepository) ListInvites(communityID, inviterAddr string, limit, offset int) ([]domain.CommunityInvite, error) {
+
+	uuid, ok := parseUUID(communityID)
+	if !ok {
+		return nil, domain.ErrCommunityNotFound
+	}
+
+	rows, err := repo.q.ListCommunityInvites(repo.ctx, db.ListCommunityInvitesParams{
+		CommunityID:    uuid,
+		InviterAddress: inviterAddr,
+		RowLimit:       int32(limit),
+		RowOffset:      int32(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	invites := make([]domain.CommunityInvite, 0, len(rows))
+	for _, row := range rows {
+		invites = append(invites, toDomainInvite(row))
+	}
+
+	return invites, nil
+}
+
+func (repo *inviteRepository) RevokeInvite(id string) error {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return domain.ErrInviteNotFound
+	}
+
+	rows, err := repo.q.RevokeCommunityInvite(repo.ctx, uuid)
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return domain.ErrInviteExpired
+	}
+
+	return nil
+}
+
+func (repo *inviteRepository) CountClick(id string) error {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return domain.ErrInviteNotFound
+	}
+
+	return repo.q.CountInviteClick(repo.ctx, uuid)
+}
+
+func (repo *inviteRepository) CountSignIn(id string) error {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return domain.ErrInviteNotFound
+	}
+
+	return repo.q.CountInviteSignIn(repo.ctx, uuid)
+}
+
+func toDomainInvite(row db.CommunityInvite) domain.CommunityInvite {
+
+	invite := domain.CommunityInvite{
+		ID:             row.ID.String(),
+		CommunityID:    row.CommunityID.String(),
+		InviterAddress: row.InviterAddress,
+		Uses:           int(row.Uses),
+		ExpiresAt:      fromTimestamp(row.ExpiresAt),
+		AccessPolicy:   row.AccessPolicy,
+		Stats: domain.InviteStats{
+			Clicks:  int(row.Clicks),
+			SignIns: int(row.SignIns),
+			Joins:   int(row.Uses),
+		},
+		RevokedAt: fromTimestamp(row.RevokedAt),
+		CreatedAt: row.CreatedAt.Time,
+		Code:      row.Code,
+	}
+
+	if row.MaxUses.Valid {
+		maxUses := int(row.MaxUses.Int32)
+		invite.MaxUses = &maxUses
+	}
+
+	return invite
+}
@@ -0,0 +1,561 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/db"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type MediaRepository interface {
+	// CreateMedia records a stored file. A PostID naming a missing post is rejected
+	// with ErrPostNotFound. With a positive quota the file must fit the owner's free
+	// space, checked under the lock uploads reserve space with, or it fails with
+	// ErrStorageQuotaExceeded
+	CreateMedia(media domain.MediaFile, quota int64) (*domain.MediaFile, error)
+
+	// GetMedia returns a file with its variants
+	GetMedia(id string) (*domain.MediaFile, error)
+
+	// ListPostMedia returns the files linked to a post with their variants
+	ListPostMedia(postID string) ([]domain.MediaFile, error)
+
+	// DeleteMedia removes the record of a file owned by the owner
+	DeleteMedia(owner, id string) error
+
+	// StorageUsage sums the owner's files and the space reserved by unfinished uploads
+	StorageUsage(owner string) (int64, error)
+
+	// ListMediaToEncrypt pages by id through files stored in plaintext or under a master
+	// key other than activeKeyID
+	ListMediaToEncrypt(activeKeyID, afterID string, limit int) ([]domain.MediaFile, error)
+
+	// SealMedia points a file at its encrypted object, processed images are queued to
+	// render their variants again
+	SealMedia(id, storageKey string, key domain.WrappedKey) error
+
+	// RewrapMediaKey replaces the wrapped data key of a file
+	RewrapMediaKey(id string, key domain.WrappedKey) error
+
+	// ClaimMediaToProcess marks up to limit pending files of contentTypes as processing.
+	// Claims older than staleBefore were abandoned by a crashed worker and are taken
+	// over, as long as the file was tried less than maxAttempts times
+	ClaimMediaToProcess(contentTypes []string, staleBefore time.Time, maxAttempts, limit int) ([]domain.MediaFile, error)
+
+	// FailStaleProcessing gives up on abandoned claims which used their last attempt
+	FailStaleProcessing(contentTypes []string, staleBefore time.Time, maxAttempts int) (int64, error)
+
+	// FailProcessing returns a claimed image to pending, or fails it for good once it
+	// used maxAttempts. A maxAttempts of 0 fails it right away
+	FailProcessing(id string, maxAttempts int) error
+
+	// FinishProcessing records the processed image and replaces its variants, returning
+	// the storage keys of the replaced ones. It fails with ErrMediaNotFound when the file
+	// was deleted, or its object replaced, while processedKey was being processed
+	FinishProcessing(id, processedKey string, image domain.ProcessedImage) ([]string, error)
+
+	// GetStream returns the HLS package of a video with its renditions
+	GetStream(mediaID string) (*domain.MediaStream, error)
+
+	// FinishStream records the HLS package of a video and marks it ready, returning the
+	// package it replaced, if any. It fails like FinishProcessing
+	FinishStream(processedKey string, stream domain.MediaStream) (*domain.MediaStream, error)
+}
+
+func NewMediaRepository(ctx context.Context, logger *logger.Logger, pool *pgxpool.Pool, q *db.Queries) MediaRepository {
+
+	return &mediaRepository{
+		ctx:    ctx,
+		logger: *logger,
+		pool:   pool,
+		q:      q,
+	}
+}
+
+type mediaRepository struct {
+	ctx    context.Context
+	logger logger.Logger
+	pool   *pgxpool.Pool
+	q      *db.Queries
+}
+
+func (repo *mediaRepository) CreateMedia(media domain.MediaFile, quota int64) (*domain.MediaFile, error) {
+
+	var postID pgtype.UUID
+	if media.PostID != nil {
+		var ok bool
+		if postID, ok = parseUUID(*media.PostID); !ok {
+			return nil, domain.ErrPostNotFound
+		}
+	}
+
+	dataKey, keyID := fromWrappedKey(media.DataKey)
+
+	status := media.ProcessingStatus
+	if status == "" {
+		status = domain.MediaProcessingNone
+	}
+
+	tx, err := repo.pool.Begin(repo.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(repo.ctx)
+
+	qtx := repo.q.WithTx(tx)
+
+	if quota > 0 {
+		// concurrent uploads of one owner would otherwise all fit the same free space
+		if err := qtx.LockUploadOwner(repo.ctx, media.OwnerAddress); err != nil {
+			return nil, err
+		}
+
+		used, err := qtx.GetStorageUsage(repo.ctx, media.OwnerAddress)
+		if err != nil {
+			return nil, err
+		}
+
+		if used+media.SizeBytes > quota {
+			return nil, domain.ErrStorageQuotaExceeded
+		}
+	}
+
+	row, err := qtx.CreateMediaFile(repo.ctx, db.CreateMediaFileParams{
+		OwnerAddress:     media.OwnerAddress,
+		PostID:           postID,
+		StorageKey:       media.StorageKey,
+		Filename:         media.Filename,
+		ContentType:      media.ContentType,
+		SizeBytes:        media.SizeBytes,
+		Sha256:           media.SHA256,
+		DataKey:          dataKey,
+		KeyID:            keyID,
+		ProcessingStatus: status,
+	})
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return nil, domain.ErrPostNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(repo.ctx); err != nil {
+		return nil, err
+	}
+
+	created := toDomainMedia(row)
+	return &created, nil
+}
+
+func (repo *mediaRepository) GetMedia(id string) (*domain.MediaFile, error) {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return nil, domain.ErrMediaNotFound
+	}
+
+	row, err := repo.q.GetMediaFile(repo.ctx, uuid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrMediaNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := repo.withVariants([]db.MediaFile{row})
+	if err != nil {
+		return nil, err
+	}
+
+	return &files[0], nil
+}
+
+func (repo *mediaRepository) ListPostMedia(postID string) ([]domain.MediaFile, error) {
+
+	uuid, ok := parseUUID(postID)
+	if !ok {
+		return nil, domain.ErrPostNotFound
+	}
+
+	rows, err := repo.q.ListPostMedia(repo.ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.withVariants(rows)
+}
+
+func (repo *mediaRepository) DeleteMedia(owner, id string) error {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return domain.ErrMediaNotFound
+	}
+
+	rows, err := repo.q.DeleteMediaFile(repo.ctx, db.DeleteMediaFileParams{
+		ID:           uuid,
+		OwnerAddress: owner,
+	})
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return domain.ErrMediaNotFound
+	}
+
+	return nil
+}
+
+func (repo *mediaRepository) StorageUsage(owner string) (int64, error) {
+	return repo.q.GetStorageUsage(repo.ctx, owner)
+}
+
+func (repo *mediaRepository) ListMediaToEncrypt(activeKeyID, afterID string, limit int) ([]domain.MediaFile, error) {
+
+	after, ok := parseUUID(afterID)
+	if !ok {
+		return nil, domain.ErrMediaNotFound
+	}
+
+	rows, err := repo.q.ListMediaToEncrypt(repo.ctx, db.ListMediaToEncryptParams{
+		ActiveKeyID: pgtype.Text{String: activeKeyID, Valid: true},
+		AfterID:     after,
+		RowLimit:    int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]domain.MediaFile, 0, len(rows))
+	for _, row := range rows {
+		files = append(files, toDomainMedia(row))
+	}
+
+	return files, nil
+}
+
+func (repo *mediaRepository) SealMedia(id, storageKey string, key domain.WrappedKey) error {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return domain.ErrMediaNotFound
+	}
+
+	dataKey, keyID := fromWrappedKey(&key)
+
+	return repo.q.SealMediaFile(repo.ctx, db.SealMediaFileParams{
+		ID:         uuid,
+		StorageKey: storageKey,
+		DataKey:    dataKey,
+		KeyID:      keyID,
+	})
+}
+
+func (repo *mediaRepository) RewrapMediaKey(id string, key domain.WrappedKey) error {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return domain.ErrMediaNotFound
+	}
+
+	dataKey, keyID := fromWrappedKey(&key)
+
+	return repo.q.RewrapMediaKey(repo.ctx, db.RewrapMediaKeyParams{
+		ID:      uuid,
+		DataKey: dataKey,
+		KeyID:   keyID,
+	})
+}
+
+func (repo *mediaRepository) ClaimMediaToProcess(contentTypes []string, staleBefore time.Time, maxAttempts, limit int) ([]domain.MediaFile, error) {
+
+	rows, err := repo.q.ClaimMediaToProcess(repo.ctx, db.ClaimMediaToProcessParams{
+		StaleBefore:  toTimestamp(staleBefore),
+		MaxAttempts:  int32(maxAttempts),
+		ContentTypes: contentTypes,
+		RowLimit:     int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]domain.MediaFile, 0, len(rows))
+	for _, row := range rows {
+		files = append(files, toDomainMedia(row))
+	}
+
+	return files, nil
+}
+
+func (repo *mediaRepository) FailStaleProcessing(contentTypes []string, staleBefore time.Time, maxAttempts int) (int64, error) {
+
+	return repo.q.FailStaleMediaProcessing(repo.ctx, db.FailStaleMediaProcessingParams{
+		StaleBefore:  toTimestamp(staleBefore),
+		MaxAttempts:  int32(maxAttempts),
+		ContentTypes: contentTypes,
+	})
+}
+
+func (repo *mediaRepository) FailProcessing(id string, maxAttempts int) error {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return domain.ErrMediaNotFound
+	}
+
+	return repo.q.FailMediaProcessing(repo.ctx, db.FailMediaProcessingParams{
+		MaxAttempts: int32(maxAttempts),
+		ID:          uuid,
+	})
+}
+
+func (repo *mediaRepository) FinishProcessing(id, processedKey string, image domain.ProcessedImage) ([]string, error) {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return nil, domain.ErrMediaNotFound
+	}
+
+	tx, err := repo.pool.Begin(repo.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(repo.ctx)
+
+	qtx := repo.q.WithTx(tx)
+
+	rows, err := qtx.FinishMediaProcessing(repo.ctx, db.FinishMediaProcessingParams{
+		StorageKey:   image.StorageKey,
+		SizeBytes:    image.SizeBytes,
+		Sha256:       image.SHA256,
+		Width:        pgtype.Int4{Int32: int32(image.Width), Valid: true},
+		Height:       pgtype.Int4{Int32: int32(image.Height), Valid: true},
+		Blurhash:     pgtype.Text{String: image.BlurHash, Valid: image.BlurHash != ""},
+		ID:           uuid,
+		ProcessedKey: processedKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if rows == 0 {
+		return nil, domain.ErrMediaNotFound
+	}
+
+	replaced, err := qtx.DeleteMediaVariants(repo.ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, variant := range image.Variants {
+		err := qtx.CreateMediaVariant(repo.ctx, db.CreateMediaVariantParams{
+			MediaID:     uuid,
+			Name:        variant.Name,
+			StorageKey:  variant.StorageKey,
+			ContentType: variant.ContentType,
+			Width:       int32(variant.Width),
+			Height:      int32(variant.Height),
+			SizeBytes:   variant.SizeBytes,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(repo.ctx); err != nil {
+		return nil, err
+	}
+
+	return replaced, nil
+}
+
+func (repo *mediaRepository) GetStream(mediaID string) (*domain.MediaStream, error) {
+
+	uuid, ok := parseUUID(mediaID)
+	if !ok {
+		return nil, domain.ErrStreamNotFound
+	}
+
+	return repo.getStream(repo.q, uuid)
+}
+
+func (repo *mediaRepository) FinishStream(processedKey string, stream domain.MediaStream) (*domain.MediaStream, error) {
+
+	uuid, ok := parseUUID(stream.MediaID)
+	if !ok {
+		return nil, domain.ErrMediaNotFound
+	}
+
+	tx, err := repo.pool.Begin(repo.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(repo.ctx)
+
+	qtx := repo.q.WithTx(tx)
+
+	rows, err := qtx.FinishStreamProcessing(repo.ctx, db.FinishStreamProcessingParams{
+		ID:           uuid,
+		ProcessedKey: processedKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if rows == 0 {
+		return nil, domain.ErrMediaNotFound
+	}
+
+	replaced, err := repo.getStream(qtx, uuid)
+	if err != nil && !errors.Is(err, domain.ErrStreamNotFound) {
+		return nil, err
+	}
+
+	if err := qtx.DeleteMediaStream(repo.ctx, uuid); err != nil {
+		return nil, err
+	}
+
+	err = qtx.CreateMediaStream(repo.ctx, db.CreateMediaStreamParams{
+		MediaID:       uuid,
+		StoragePrefix: stream.StoragePrefix,
+		SegmentKey:    stream.SealedKey,
+		DurationMs:    stream.Duration.Milliseconds(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rendition := range stream.Renditions {
+		err := qtx.CreateStreamRendition(repo.ctx, db.CreateStreamRenditionParams{
+			MediaID:   uuid,
+			Name:      rendition.Name,
+			Width:     int32(rendition.Width),
+			Height:    int32(rendition.Height),
+			Bandwidth: int32(rendition.Bandwidth),
+			Playlist:  rendition.Playlist,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(repo.ctx); err != nil {
+		return nil, err
+	}
+
+	return replaced, nil
+}
+
+func (repo *mediaRepository) getStream(q *db.Queries, mediaID pgtype.UUID) (*domain.MediaStream, error) {
+
+	row, err := q.GetMediaStream(repo.ctx, mediaID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrStreamNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	renditions, err := q.ListStreamRenditions(repo.ctx, mediaID)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := &domain.MediaStream{
+		MediaID:       row.MediaID.String(),
+		StoragePrefix: row.StoragePrefix,
+		Duration:      time.Duration(row.DurationMs) * time.Millisecond,
+		SealedKey:     row.SegmentKey,
+	}
+
+	for _, rendition := range renditions {
+		stream.Renditions = append(stream.Renditions, domain.StreamRendition{
+			Name:      rendition.Name,
+			Width:     int(rendition.Width),
+			Height:    int(rendition.Height),
+			Bandwidth: int(rendition.Bandwidth),
+			Playlist:  rendition.Playlist,
+		})
+	}
+
+	return stream, nil
+}
+
+// withVariants converts rows and loads the variants of all of them in one query
+func (repo *mediaRepository) withVariants(rows []db.MediaFile) ([]domain.MediaFile, error) {
+
+	files := make([]domain.MediaFile, 0, len(rows))
+	ids := make([]pgtype.UUID, 0, len(rows))
+	index := make(map[string]int, len(rows))
+
+	for _, row := range rows {
+		media := toDomainMedia(row)
+		index[media.ID] = len(files)
+		files = append(files, media)
+
+		if media.ProcessingStatus != domain.MediaProcessingNone {
+			ids = append(ids, row.ID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return files, nil
+	}
+
+	variants, err := repo.q.ListMediaVariants(repo.ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, variant := range variants {
+		media := &files[index[variant.MediaID.String()]]
+		media.Variants = append(media.Variants, domain.MediaVariant{
+			Name:        variant.Name,
+			StorageKey:  variant.StorageKey,
+			ContentType: variant.ContentType,
+			Width:       int(variant.Width),
+			Height:      int(variant.Height),
+			SizeBytes:   variant.SizeBytes,
+		})
+	}
+
+	return files, nil
+}
+
+func toDomainMedia(row db.MediaFile) domain.MediaFile {
+
+	media := domain.MediaFile{
+		ID:           row.ID.String(),
+		OwnerAddress: row.OwnerAddress,
+		StorageKey:   row.StorageKey,
+		Filename:     row.Filename,
+		ContentType:  row.ContentType,
+		SizeBytes:    row.SizeBytes,
+		SHA256:       row.Sha256,
+		CreatedAt:    row.CreatedAt.Time,
+		DataKey:      toWrappedKey(row.KeyID, row.DataKey),
+
+		ProcessingStatus:   row.ProcessingStatus,
+		ProcessingAttempts: int(row.ProcessingAttempts),
+		Width:              int(row.Width.Int32),
+		Height:             int(row.Height.Int32),
+		BlurHash:           row.Blurhash.String,
+	}
+
+	if row.PostID.Valid {
+		postID := row.PostID.String()
+		media.PostID = &postID
+	}
+
+	return media
+}
@@ -0,0 +1,269 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+
+	"github.com/Xebec19/jibe/api/internal/db"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/jackc/pgx/v5"
+)
+
+type NotificationRepository interface {
+	// CreateNotification stores a notification. A notification folded into an unread one
+	// of the same group is not stored, nil is returned then
+	CreateNotification(notification domain.Notification) (*domain.Notification, error)
+
+	// NotifyMembers stores a notification for every member of the communities owned by
+	// owner
+	NotifyMembers(kind, owner, subjectID string, data json.RawMessage) ([]domain.Notification, error)
+
+	// ReleaseDrips notifies up to limit members of dripped posts which unlocked for them
+	// since they were dripped or published. Releases are recorded along with their
+	// notification, so every member is told once even across instances and restarts
+	ReleaseDrips(limit int) ([]domain.Notification, error)
+
+	// ListNotifications returns the notifications of an account newest first, before
+	// the given id when it is not 0
+	ListNotifications(addr string, beforeID int64, unreadOnly bool, limit int) ([]domain.Notification, error)
+
+	// ListAfter returns the notifications of an account stored after the given id,
+	// oldest first
+	ListAfter(addr string, afterID int64, limit int) ([]domain.Notification, error)
+
+	// LatestID returns the id of the newest notification of an account, 0 without any
+	LatestID(addr string) (int64, error)
+
+	CountUnread(addr string) (int, error)
+
+	MarkRead(addr string, id int64) (*domain.Notification, error)
+
+	MarkUnread(addr string, id int64) (*domain.Notification, error)
+
+	// MarkAllRead marks the notifications of an account up to the given id as read
+	MarkAllRead(addr string, upToID int64) (int64, error)
+
+	// Notify announces new notifications to the listeners of every replica
+	Notify(payload string) error
+
+	// ListPreferences returns the channels an account switched, kinds left at the
+	// channel default are missing
+	ListPreferences(addr string) (domain.NotificationPreferences, error)
+
+	SetPreference(addr, channel, kind string, enabled bool) error
+}
+
+func NewNotificationRepository(ctx context.Context, logger *logger.Logger, q *db.Queries) NotificationRepository {
+
+	return &notificationRepository{
+		ctx:    ctx,
+		logger: *logger,
+		q:      q,
+	}
+}
+
+type notificationRepository struct {
+	ctx    context.Context
+	logger logger.Logger
+	q      *db.Queries
+}
+
+func (repo *notificationRepository) CreateNotification(notification domain.Notification) (*domain.Notification, error) {
+
+	data := notification.Data
+	if len(data) == 0 {
+		data = json.RawMessage("{}")
+	}
+
+	row, err := repo.q.CreateNotification(repo.ctx, db.CreateNotificationParams{
+		RecipientAddress: notification.RecipientAddress,
+		Kind:             notification.Kind,
+		ActorAddress:     toText(notification.ActorAddress),
+		SubjectID:        notification.SubjectID,
+		Data:             data,
+		GroupKey:         toText(notification.GroupKey),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainNotification(row), nil
+}
+
+func (repo *notificationRepository) NotifyMembers(kind, owner, subjectID string, data json.RawMessage) ([]domain.Notification, error) {
+
+	rows, err := repo.q.CreateMemberNotifications(repo.ctx, db.CreateMemberNotificationsParams{
+		Kind:         kind,
+		OwnerAddress: owner,
+		SubjectID:    subjectID,
+		Data:         data,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainNotifications(rows), nil
+}
+
+func (repo *notificationRepository) ReleaseDrips(limit int) ([]domain.Notification, error) {
+
+	rows, err := repo.q.ReleaseDrips(repo.ctx, int32(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainNotifications(rows), nil
+}
+
+func (repo *notificationRepository) ListNotifications(addr string, beforeID int64, unreadOnly bool, limit int) ([]domain.Notification, error) {
+
+	// the first page starts past every row
+	if beforeID == 0 {
+		beforeID = math.MaxInt64
+	}
+
+	rows, err := repo.q.ListNotifications(repo.ctx, db.ListNotificationsParams{
+		RecipientAddress: addr,
+		BeforeID:         beforeID,
+		UnreadOnly:       unreadOnly,
+		RowLimit:         int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainNotifications(rows), nil
+}
+
+func (repo *notificationRepository) ListAfter(addr string, afterID int64, limit int) ([]domain.Notification, error) {
+
+	rows, err := repo.q.ListNotificationsAfter(repo.ctx, db.ListNotificationsAfterParams{
+		RecipientAddress: addr,
+		ID:               afterID,
+		Limit:            int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainNotifications(rows), nil
+}
+
+func (repo *notificationRepository) LatestID(addr string) (int64, error) {
+
+	return repo.q.GetLatestNotificationID(repo.ctx, addr)
+}
+
+func (repo *notificationRepository) CountUnread(addr string) (int, error) {
+
+	count, err := repo.q.CountUnreadNotifications(repo.ctx, addr)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(count), nil
+}
+
+func (repo *notificationRepository) MarkRead(addr string, id int64) (*domain.Notification, error) {
+
+	row, err := repo.q.MarkNotificationRead(repo.ctx, db.MarkNotificationReadParams{
+		ID:               id,
+		RecipientAddress: addr,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotificationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainNotification(row), nil
+}
+
+func (repo *notificationRepository) MarkUnread(addr string, id int64) (*domain.Notification, error) {
+
+	row, err := repo.q.MarkNotificationUnread(repo.ctx, db.MarkNotificationUnreadParams{
+		ID:               id,
+		RecipientAddress: addr,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotificationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainNotification(row), nil
+}
+
+func (repo *notificationRepository) MarkAllRead(addr string, upToID int64) (int64, error) {
+
+	if upToID == 0 {
+		upToID = math.MaxInt64
+	}
+
+	return repo.q.MarkAllNotificationsRead(repo.ctx, db.MarkAllNotificationsReadParams{
+		RecipientAddress: addr,
+		ID:               upToID,
+	})
+}
+
+func (repo *notificationRepository) Notify(payload string) error {
+
+	return repo.q.NotifyNotifications(repo.ctx, payload)
+}
+
+func (repo *notificationRepository) ListPreferences(addr string) (domain.NotificationPreferences, error) {
+
+	rows, err := repo.q.ListNotificationPreferences(repo.ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	preferences := make(domain.NotificationPreferences)
+	for _, row := range rows {
+		preferences.Set(row.Channel, row.Kind, row.Enabled)
+	}
+
+	return preferences, nil
+}
+
+func (repo *notificationRepository) SetPreference(addr, channel, kind string, enabled bool) error {
+
+	return repo.q.UpsertNotificationPreference(repo.ctx, db.UpsertNotificationPreferenceParams{
+		AccountAddress: addr,
+		Channel:        channel,
+		Kind:           kind,
+		Enabled:        enabled,
+	})
+}
+
+func toDomainNotifications(rows []db.Notification) []domain.Notification {
+
+	notifications := make([]domain.Notification, 0, len(rows))
+	for _, row := range rows {
+		notifications = append(notifications, *toDomainNotification(row))
+	}
+
+	return notifications
+}
+
+func toDomainNotification(row db.Notification) *domain.Notification {
+	return &domain.Notification{
+		ID:               row.ID,
+		RecipientAddress: row.RecipientAddress,
+		Kind:             row.Kind,
+		ActorAddress:     row.ActorAddress.String,
+		SubjectID:        row.SubjectID,
+		Data:             row.Data,
+		ReadAt:           fromTimestamp(row.ReadAt),
+		CreatedAt:        row.CreatedAt.Time,
+		GroupKey:         row.GroupKey.String,
+	}
+}
@@ -0,0 +1,809 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/db"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PostRepository interface {
+	// CreatePost stores the post and its first revision, and indexes it for search with
+	// input.SearchBody
+	CreatePost(authorAddr string, input domain.PostInput) (*domain.Post, error)
+
+	// GetPost returns the post along with its attachments
+	GetPost(id string) (*domain.Post, error)
+
+	// UpdatePost replaces the editable fields of the post and its search index, the
+	// result is kept as a new revision described by input.Change
+	UpdatePost(id string, input domain.PostInput) (*domain.Post, error)
+
+	// SetStatus moves a post between draft and published. published_at is only set the
+	// first time a post is published, a pending schedule is dropped either way
+	SetStatus(id, status string) (*domain.Post, error)
+
+	// Schedule moves a post to scheduled, it is published once publishAt is reached
+	Schedule(id string, publishAt time.Time) (*domain.Post, error)
+
+	// PublishDue publishes up to limit scheduled posts whose time came. Posts are claimed
+	// so instances never publish the same one. It returns how many were published and
+	// those published for the first time
+	PublishDue(limit int) (int, []domain.Post, error)
+
+	DeletePost(id string) error
+
+	// ListByAuthor returns posts of the author in the given status, newest first.
+	// Attachments are not loaded
+	ListByAuthor(authorAddr, status string, limit, offset int) ([]domain.Post, error)
+
+	CreateAttachment(postID string, attachment domain.PostAttachment) (*domain.PostAttachment, error)
+
+	DeleteAttachment(postID, attachmentID string) error
+
+	// ListPostsToEncrypt pages by id through posts stored in plaintext, under a master
+	// key other than activeKeyID or with revisions stored in plaintext. Attachments are
+	// not loaded
+	ListPostsToEncrypt(activeKeyID, afterID string, limit int) ([]domain.Post, error)
+
+	// SealPost stores the encrypted body and attachments of a plaintext post
+	SealPost(post domain.Post) error
+
+	// RewrapPostKey replaces the wrapped data key of a post
+	RewrapPostKey(id string, key domain.WrappedKey) error
+
+	GetRevision(postID string, number int) (*domain.PostRevision, error)
+
+	// ListRevisions returns the revisions of a post, newest first
+	ListRevisions(postID string, limit, offset int) ([]domain.PostRevision, error)
+
+	// ListPlaintextRevisions returns the revisions of a post whose body is not encrypted,
+	// they were saved before the post was
+	ListPlaintextRevisions(postID string) ([]domain.PostRevision, error)
+
+	// SealRevisions stores the encrypted bodies of revisions
+	SealRevisions(postID string, revisions []domain.PostRevision) error
+
+	// PruneDraftRevisions deletes up to limit revisions saved before cutoff while their
+	// post was not published. The latest revision of a post is kept
+	PruneDraftRevisions(cutoff time.Time, limit int) (int, error)
+
+	// SetDrip drips a post or replaces its drip. Members are told again when the post
+	// unlocks for them under the new drip. The body is dropped from the search index of
+	// the post so it can not be probed before it unlocks
+	SetDrip(postID string, drip domain.PostDrip) (*domain.PostDrip, error)
+
+	// DeleteDrip removes the drip of a post and indexes it with searchBody again
+	DeleteDrip(postID, searchBody string) error
+
+	// ListDrips returns the drips of the posts by post id, along with when member joined
+	// the communities they are dripped to
+	ListDrips(postIDs []string, member string) (map[string]domain.DripAccess, error)
+}
+
+func NewPostRepository(ctx context.Context, logger *logger.Logger, pool *pgxpool.Pool, q *db.Queries) PostRepository {
+
+	return &postRepository{
+		ctx:    ctx,
+		logger: *logger,
+		pool:   pool,
+		q:      q,
+	}
+}
+
+type postRepository struct {
+	ctx    context.Context
+	logger logger.Logger
+	pool   *pgxpool.Pool
+	q      *db.Queries
+}
+
+func (repo *postRepository) CreatePost(authorAddr string, input domain.PostInput) (*domain.Post, error) {
+
+	tx, err := repo.pool.Begin(repo.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(repo.ctx)
+
+	qtx := repo.q.WithTx(tx)
+
+	dataKey, keyID := fromWrappedKey(input.DataKey)
+
+	row, err := qtx.CreatePost(repo.ctx, db.CreatePostParams{
+		AuthorAddress:  authorAddr,
+		Title:          input.Title,
+		Body:           input.Body,
+		BodyFormat:     input.BodyFormat,
+		AccessPolicy:   input.AccessPolicy,
+		BodyCiphertext: input.SealedBody,
+		DataKey:        dataKey,
+		KeyID:          keyID,
+		Teaser:         input.Teaser,
+		Tags:           input.Tags,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := indexPost(repo.ctx, qtx, row.ID, input); err != nil {
+		return nil, err
+	}
+
+	if err := recordRevision(repo.ctx, qtx, row.ID, input); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(repo.ctx); err != nil {
+		return nil, err
+	}
+
+	post := toDomainPost(row)
+	return &post, nil
+}
+
+func (repo *postRepository) GetPost(id string) (*domain.Post, error) {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return nil, domain.ErrPostNotFound
+	}
+
+	row, err := repo.q.GetPost(repo.ctx, uuid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrPostNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	attachments, err := repo.q.ListPostAttachments(repo.ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	post := toDomainPost(row)
+	for _, a := range attachments {
+		post.Attachments = append(post.Attachments, toDomainAttachment(a))
+	}
+
+	return &post, nil
+}
+
+func (repo *postRepository) UpdatePost(id string, input domain.PostInput) (*domain.Post, error) {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return nil, domain.ErrPostNotFound
+	}
+
+	tx, err := repo.pool.Begin(repo.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(repo.ctx)
+
+	qtx := repo.q.WithTx(tx)
+
+	dataKey, keyID := fromWrappedKey(input.DataKey)
+
+	row, err := qtx.UpdatePost(repo.ctx, db.UpdatePostParams{
+		ID:             uuid,
+		Title:          input.Title,
+		Body:           input.Body,
+		BodyFormat:     input.BodyFormat,
+		AccessPolicy:   input.AccessPolicy,
+		BodyCiphertext: input.SealedBody,
+		DataKey:        dataKey,
+		KeyID:          keyID,
+		Teaser:         input.Teaser,
+		Tags:           input.Tags,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrPostNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := indexPost(repo.ctx, qtx, uuid, input); err != nil {
+		return nil, err
+	}
+
+	if err := recordRevision(repo.ctx, qtx, uuid, input); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(repo.ctx); err != nil {
+		return nil, err
+	}
+
+	post := toDomainPost(row)
+	return &post, nil
+}
+
+func (repo *postRepository) SetStatus(id, status string) (*domain.Post, error) {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return nil, domain.ErrPostNotFound
+	}
+
+	var row db.Post
+	var err error
+
+	if status == domain.PostStatusPublished {
+		row, err = repo.q.PublishPost(repo.ctx, uuid)
+	} else {
+		row, err = repo.q.UnpublishPost(repo.ctx, uuid)
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrPostNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	post := toDomainPost(row)
+	return &post, nil
+}
+
+func (repo *postRepository) Schedule(id string, publishAt time.Time) (*domain.Post, error) {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return nil, domain.ErrPostNotFound
+	}
+
+	row, err := repo.q.SchedulePost(repo.ctx, db.SchedulePostParams{
+		ID:        uuid,
+		PublishAt: toTimestamp(publishAt),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrPostNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	post := toDomainPost(row)
+	return &post, nil
+}
+
+func (repo *postRepository) PublishDue(limit int) (int, []domain.Post, error) {
+
+	rows, err := repo.q.PublishDuePosts(repo.ctx, int32(limit))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var first []domain.Post
+	for _, row := range rows {
+		if !row.FirstPublish {
+			continue
+		}
+		first = append(first, toDomainPost(db.Post{
+			ID:             row.ID,
+			AuthorAddress:  row.AuthorAddress,
+			Title:          row.Title,
+			Body:           row.Body,
+			BodyFormat:     row.BodyFormat,
+			Status:         row.Status,
+			AccessPolicy:   row.AccessPolicy,
+			CreatedAt:      row.CreatedAt,
+			UpdatedAt:      row.UpdatedAt,
+			PublishedAt:    row.PublishedAt,
+			BodyCiphertext: row.BodyCiphertext,
+			DataKey:        row.DataKey,
+			KeyID:          row.KeyID,
+			Teaser:         row.Teaser,
+			Tags:           row.Tags,
+			PublishAt:      row.PublishAt,
+		}))
+	}
+
+	return len(rows), first, nil
+}
+
+func (repo *postRepository) DeletePost(id string) error {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return domain.ErrPostNotFound
+	}
+
+	rows, err := repo.q.DeletePost(repo.ctx, uuid)
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return domain.ErrPostNotFound
+	}
+
+	return nil
+}
+
+func (repo *postRepository) ListByAuthor(authorAddr, status string, limit, offset int) ([]domain.Post, error) {
+
+	rows, err := repo.q.ListPostsByAuthor(repo.ctx, db.ListPostsByAuthorParams{
+		AuthorAddress: authorAddr,
+		Status:        status,
+		Limit:         int32(limit),
+		Offset:        int32(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	posts := make([]domain.Post, 0, len(rows))
+	for _, row := range rows {
+		posts = append(posts, toDomainPost(row))
+	}
+
+	return posts, nil
+}
+
+func (repo *postRepository) CreateAttachment(postID string, attachment domain.PostAttachment) (*domain.PostAttachment, error) {
+
+	uuid, ok := parseUUID(postID)
+	if !ok {
+		return nil, domain.ErrPostNotFound
+	}
+
+	row, err := repo.q.CreatePostAttachment(repo.ctx, db.CreatePostAttachmentParams{
+		PostID:         uuid,
+		Name:           attachment.Name,
+		Url:            attachment.URL,
+		ContentType:    attachment.ContentType,
+		SizeBytes:      attachment.SizeBytes,
+		Position:       int32(attachment.Position),
+		NameCiphertext: attachment.SealedName,
+		UrlCiphertext:  attachment.SealedURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	a := toDomainAttachment(row)
+	return &a, nil
+}
+
+func (repo *postRepository) DeleteAttachment(postID, attachmentID string) error {
+
+	postUUID, ok := parseUUID(postID)
+	if !ok {
+		return domain.ErrPostNotFound
+	}
+
+	attachmentUUID, ok := parseUUID(attachmentID)
+	if !ok {
+		return domain.ErrAttachmentNotFound
+	}
+
+	rows, err := repo.q.DeletePostAttachment(repo.ctx, db.DeletePostAttachmentParams{
+		ID:     attachmentUUID,
+		PostID: postUUID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return domain.ErrAttachmentNotFound
+	}
+
+	return nil
+}
+
+func (repo *postRepository) ListPostsToEncrypt(activeKeyID, afterID string, limit int) ([]domain.Post, error) {
+
+	after, ok := parseUUID(afterID)
+	if !ok {
+		return nil, domain.ErrPostNotFound
+	}
+
+	rows, err := repo.q.ListPostsToEncrypt(repo.ctx, db.ListPostsToEncryptParams{
+		ActiveKeyID: pgtype.Text{String: activeKeyID, Valid: true},
+		AfterID:     after,
+		RowLimit:    int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	posts := make([]domain.Post, 0, len(rows))
+	for _, row := range rows {
+		posts = append(posts, toDomainPost(row))
+	}
+
+	return posts, nil
+}
+
+func (repo *postRepository) SealPost(post domain.Post) error {
+
+	uuid, ok := parseUUID(post.ID)
+	if !ok {
+		return domain.ErrPostNotFound
+	}
+
+	tx, err := repo.pool.Begin(repo.ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(repo.ctx)
+
+	qtx := repo.q.WithTx(tx)
+
+	dataKey, keyID := fromWrappedKey(post.DataKey)
+
+	err = qtx.SealPost(repo.ctx, db.SealPostParams{
+		ID:             uuid,
+		BodyCiphertext: post.SealedBody,
+		DataKey:        dataKey,
+		KeyID:          keyID,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, attachment := range post.Attachments {
+		attachmentUUID, ok := parseUUID(attachment.ID)
+		if !ok {
+			return domain.ErrAttachmentNotFound
+		}
+
+		err := qtx.SealPostAttachment(repo.ctx, db.SealPostAttachmentParams{
+			ID:             attachmentUUID,
+			NameCiphertext: attachment.SealedName,
+			UrlCiphertext:  attachment.SealedURL,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(repo.ctx)
+}
+
+func (repo *postRepository) RewrapPostKey(id string, key domain.WrappedKey) error {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return domain.ErrPostNotFound
+	}
+
+	dataKey, keyID := fromWrappedKey(&key)
+
+	return repo.q.RewrapPostKey(repo.ctx, db.RewrapPostKeyParams{
+		ID:      uuid,
+		DataKey: dataKey,
+		KeyID:   keyID,
+	})
+}
+
+func (repo *postRepository) GetRevision(postID string, number int) (*domain.PostRevision, error) {
+
+	uuid, ok := parseUUID(postID)
+	if !ok {
+		return nil, domain.ErrPostNotFound
+	}
+
+	row, err := repo.q.GetPostRevision(repo.ctx, db.GetPostRevisionParams{
+		PostID: uuid,
+		Number: int32(number),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	revision := toDomainRevision(row)
+	return &revision, nil
+}
+
+func (repo *postRepository) ListRevisions(postID string, limit, offset int) ([]domain.PostRevision, error) {
+
+	uuid, ok := parseUUID(postID)
+	if !ok {
+		return nil, domain.ErrPostNotFound
+	}
+
+	rows, err := repo.q.ListPostRevisions(repo.ctx, db.ListPostRevisionsParams{
+		PostID: uuid,
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]domain.PostRevision, 0, len(rows))
+	for _, row := range rows {
+		revisions = append(revisions, toDomainRevision(row))
+	}
+
+	return revisions, nil
+}
+
+func (repo *postRepository) ListPlaintextRevisions(postID string) ([]domain.PostRevision, error) {
+
+	uuid, ok := parseUUID(postID)
+	if !ok {
+		return nil, domain.ErrPostNotFound
+	}
+
+	rows, err := repo.q.ListPlaintextRevisions(repo.ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]domain.PostRevision, 0, len(rows))
+	for _, row := range rows {
+		revisions = append(revisions, toDomainRevision(row))
+	}
+
+	return revisions, nil
+}
+
+func (repo *postRepository) SealRevisions(postID string, revisions []domain.PostRevision) error {
+
+	uuid, ok := parseUUID(postID)
+	if !ok {
+		return domain.ErrPostNotFound
+	}
+
+	tx, err := repo.pool.Begin(repo.ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(repo.ctx)
+
+	qtx := repo.q.WithTx(tx)
+
+	for _, revision := range revisions {
+		err := qtx.SealPostRevision(repo.ctx, db.SealPostRevisionParams{
+			PostID:         uuid,
+			Number:         int32(revision.Number),
+			BodyCiphertext: revision.SealedBody,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(repo.ctx)
+}
+
+func (repo *postRepository) PruneDraftRevisions(cutoff time.Time, limit int) (int, error) {
+
+	rows, err := repo.q.PruneDraftRevisions(repo.ctx, db.PruneDraftRevisionsParams{
+		Cutoff:   toTimestamp(cutoff),
+		RowLimit: int32(limit),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rows), nil
+}
+
+func (repo *postRepository) SetDrip(postID string, drip domain.PostDrip) (*domain.PostDrip, error) {
+
+	postUUID, ok := parseUUID(postID)
+	if !ok {
+		return nil, domain.ErrPostNotFound
+	}
+
+	communityUUID, ok := parseUUID(drip.CommunityID)
+	if !ok {
+		return nil, domain.ErrCommunityNotFound
+	}
+
+	tx, err := repo.pool.Begin(repo.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(repo.ctx)
+
+	qtx := repo.q.WithTx(tx)
+
+	row, err := qtx.UpsertPostDrip(repo.ctx, db.UpsertPostDripParams{
+		PostID:       postUUID,
+		CommunityID:  communityUUID,
+		DelaySeconds: int32(drip.DelaySeconds),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := qtx.DeleteDripReleases(repo.ctx, postUUID); err != nil {
+		return nil, err
+	}
+
+	if err := qtx.ReindexPost(repo.ctx, db.ReindexPostParams{PostID: postUUID}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(repo.ctx); err != nil {
+		return nil, err
+	}
+
+	return &domain.PostDrip{
+		CommunityID:  row.CommunityID.String(),
+		DelaySeconds: int(row.DelaySeconds),
+	}, nil
+}
+
+func (repo *postRepository) DeleteDrip(postID, searchBody string) error {
+
+	uuid, ok := parseUUID(postID)
+	if !ok {
+		return domain.ErrPostNotFound
+	}
+
+	tx, err := repo.pool.Begin(repo.ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(repo.ctx)
+
+	qtx := repo.q.WithTx(tx)
+
+	rows, err := qtx.DeletePostDrip(repo.ctx, uuid)
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return domain.ErrDripNotFound
+	}
+
+	if err := qtx.ReindexPost(repo.ctx, db.ReindexPostParams{PostID: uuid, SearchBody: searchBody}); err != nil {
+		return err
+	}
+
+	return tx.Commit(repo.ctx)
+}
+
+func (repo *postRepository) ListDrips(postIDs []string, member string) (map[string]domain.DripAccess, error) {
+
+	uuids := make([]pgtype.UUID, 0, len(postIDs))
+	for _, id := range postIDs {
+		if uuid, ok := parseUUID(id); ok {
+			uuids = append(uuids, uuid)
+		}
+	}
+
+	drips := make(map[string]domain.DripAccess)
+	if len(uuids) == 0 {
+		return drips, nil
+	}
+
+	rows, err := repo.q.ListPostDrips(repo.ctx, db.ListPostDripsParams{
+		MemberAddress: member,
+		PostIds:       uuids,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		drips[row.PostID.String()] = domain.DripAccess{
+			PostDrip: domain.PostDrip{
+				CommunityID:  row.CommunityID.String(),
+				DelaySeconds: int(row.DelaySeconds),
+			},
+			JoinedAt: fromTimestamp(row.JoinedAt),
+		}
+	}
+
+	return drips, nil
+}
+
+// indexPost writes the search vector of a post, the body comes from input.SearchBody as
+// the stored one may be encrypted
+func indexPost(ctx context.Context, q *db.Queries, id pgtype.UUID, input domain.PostInput) error {
+
+	tags := input.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return q.IndexPost(ctx, db.IndexPostParams{
+		PostID:     id,
+		Title:      input.Title,
+		Tags:       tags,
+		Teaser:     input.Teaser,
+		SearchBody: input.SearchBody,
+	})
+}
+
+// recordRevision snapshots the post as just written by the tx, the content is read back
+// so the revision holds the body exactly as stored
+func recordRevision(ctx context.Context, q *db.Queries, id pgtype.UUID, input domain.PostInput) error {
+
+	fields := input.Change.Fields
+	if fields == nil {
+		fields = []string{}
+	}
+
+	return q.CreatePostRevision(ctx, db.CreatePostRevisionParams{
+		ChangedFields: fields,
+		LinesAdded:    int32(input.Change.LinesAdded),
+		LinesRemoved:  int32(input.Change.LinesRemoved),
+		RestoredFrom:  int32(input.RestoredFrom),
+		PostID:        id,
+	})
+}
+
+func toDomainPost(row db.Post) domain.Post {
+
+	return domain.Post{
+		ID:            row.ID.String(),
+		AuthorAddress: row.AuthorAddress,
+		Title:         row.Title,
+		Body:          row.Body,
+		BodyFormat:    row.BodyFormat,
+		Status:        row.Status,
+		AccessPolicy:  row.AccessPolicy,
+		CreatedAt:     row.CreatedAt.Time,
+		UpdatedAt:     row.UpdatedAt.Time,
+		PublishedAt:   fromTimestamp(row.PublishedAt),
+		PublishAt:     fromTimestamp(row.PublishAt),
+		DataKey:       toWrappedKey(row.KeyID, row.DataKey),
+		SealedBody:    row.BodyCiphertext,
+		Teaser:        row.Teaser,
+		Tags:          row.Tags,
+	}
+}
+
+func toDomainAttachment(row db.PostAttachment) domain.PostAttachment {
+
+	return domain.PostAttachment{
+		ID:          row.ID.String(),
+		Name:        row.Name,
+		URL:         row.Url,
+		ContentType: row.ContentType,
+		SizeBytes:   row.SizeBytes,
+		Position:    int(row.Position),
+		CreatedAt:   row.CreatedAt.Time,
+		SealedName:  row.NameCiphertext,
+		SealedURL:   row.UrlCiphertext,
+	}
+}
+
+func toDomainRevision(row db.PostRevision) domain.PostRevision {
+
+	return domain.PostRevision{
+		PostID:        row.PostID.String(),
+		Number:        int(row.Number),
+		AuthorAddress: row.AuthorAddress,
+		Status:        row.Status,
+		RevisionContent: domain.RevisionContent{
+			Title:        row.Title,
+			Teaser:       row.Teaser,
+			Tags:         row.Tags,
+			Body:         row.Body,
+			BodyFormat:   row.BodyFormat,
+			AccessPolicy: row.AccessPolicy,
+		},
+		Change: domain.RevisionChange{
+			Fields:       row.ChangedFields,
+			LinesAdded:   int(row.LinesAdded),
+			LinesRemoved: int(row.LinesRemoved),
+		},
+		RestoredFrom: int(row.RestoredFrom.Int32),
+		CreatedAt:    row.CreatedAt.Time,
+		SealedBody:   row.BodyCiphertext,
+	}
+}
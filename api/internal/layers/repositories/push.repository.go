@@ -0,0 +1,198 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/db"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+)
+
+type PushRepository interface {
+	// Subscribe stores a subscription, a known endpoint is moved to the account and
+	// takes the new keys
+	Subscribe(subscription domain.PushSubscription) (*domain.PushSubscription, error)
+
+	ListSubscriptions(addr string) ([]domain.PushSubscription, error)
+
+	// RemoveSubscription deletes a subscription of the account
+	RemoveSubscription(addr, id string) error
+
+	// DropSubscription deletes a subscription the push service no longer accepts along
+	// with its pending deliveries
+	DropSubscription(id string) error
+
+	// TouchSubscription records a successful delivery
+	TouchSubscription(id string) error
+
+	// Enqueue queues the notifications for every subscription of their recipients
+	// whose push preferences allow them, it returns how many deliveries were queued
+	Enqueue(notificationIDs []int64) (int64, error)
+
+	// ClaimDeliveries takes up to limit due deliveries. They are due again at
+	// leaseUntil unless completed or rescheduled before
+	ClaimDeliveries(limit int, leaseUntil time.Time) ([]domain.PushDelivery, error)
+
+	CompleteDelivery(id int64) error
+
+	RescheduleDelivery(id int64, next time.Time, lastError string) error
+}
+
+func NewPushRepository(ctx context.Context, logger *logger.Logger, q *db.Queries) PushRepository {
+
+	return &pushRepository{
+		ctx:    ctx,
+		logger: *logger,
+		q:      q,
+	}
+}
+
+type pushRepository struct {
+	ctx    context.Context
+	logger logger.Logger
+	q      *db.Queries
+}
+
+func (repo *pushRepository) Subscribe(subscription domain.PushSubscription) (*domain.PushSubscription, error) {
+
+	row, err := repo.q.UpsertPushSubscription(repo.ctx, db.UpsertPushSubscriptionParams{
+		AccountAddress: subscription.Address,
+		Endpoint:       subscription.Endpoint,
+		P256dh:         subscription.P256dh,
+		Auth:           subscription.Auth,
+		UserAgent:      toText(subscription.UserAgent),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stored := toDomainPushSubscription(row)
+	return &stored, nil
+}
+
+func (repo *pushRepository) ListSubscriptions(addr string) ([]domain.PushSubscription, error) {
+
+	rows, err := repo.q.ListPushSubscriptions(repo.ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions := make([]domain.PushSubscription, 0, len(rows))
+	for _, row := range rows {
+		subscriptions = append(subscriptions, toDomainPushSubscription(row))
+	}
+
+	return subscriptions, nil
+}
+
+func (repo *pushRepository) RemoveSubscription(addr, id string) error {
+
+	uid, ok := parseUUID(id)
+	if !ok {
+		return domain.ErrPushSubscriptionNotFound
+	}
+
+	deleted, err := repo.q.DeleteAccountPushSubscription(repo.ctx, db.DeleteAccountPushSubscriptionParams{
+		ID:             uid,
+		AccountAddress: addr,
+	})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return domain.ErrPushSubscriptionNotFound
+	}
+
+	return nil
+}
+
+func (repo *pushRepository) DropSubscription(id string) error {
+
+	uid, ok := parseUUID(id)
+	if !ok {
+		return domain.ErrPushSubscriptionNotFound
+	}
+
+	return repo.q.DeletePushSubscription(repo.ctx, uid)
+}
+
+func (repo *pushRepository) TouchSubscription(id string) error {
+
+	uid, ok := parseUUID(id)
+	if !ok {
+		return domain.ErrPushSubscriptionNotFound
+	}
+
+	return repo.q.TouchPushSubscription(repo.ctx, uid)
+}
+
+func (repo *pushRepository) Enqueue(notificationIDs []int64) (int64, error) {
+
+	return repo.q.EnqueuePushDeliveries(repo.ctx, notificationIDs)
+}
+
+func (repo *pushRepository) ClaimDeliveries(limit int, leaseUntil time.Time) ([]domain.PushDelivery, error) {
+
+	rows, err := repo.q.ClaimPushDeliveries(repo.ctx, db.ClaimPushDeliveriesParams{
+		RowLimit:   int32(limit),
+		LeaseUntil: toTimestamp(leaseUntil),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]domain.PushDelivery, 0, len(rows))
+	for _, row := range rows {
+		deliveries = append(deliveries, domain.PushDelivery{
+			ID:       row.ID,
+			Attempts: int(row.Attempts),
+			Subscription: domain.PushSubscription{
+				ID:       row.SubscriptionID.String(),
+				Address:  row.AccountAddress,
+				Endpoint: row.Endpoint,
+				P256dh:   row.P256dh,
+				Auth:     row.Auth,
+			},
+			Notification: domain.Notification{
+				ID:               row.NotificationID,
+				RecipientAddress: row.AccountAddress,
+				Kind:             row.Kind,
+				ActorAddress:     row.ActorAddress.String,
+				SubjectID:        row.SubjectID,
+				Data:             row.Data,
+				ReadAt:           fromTimestamp(row.ReadAt),
+				CreatedAt:        row.CreatedAt.Time,
+			},
+		})
+	}
+
+	return deliveries, nil
+}
+
+func (repo *pushRepository) CompleteDelivery(id int64) error {
+
+	return repo.q.DeletePushDelivery(repo.ctx, id)
+}
+
+func (repo *pushRepository) RescheduleDelivery(id int64, next time.Time, lastError string) error {
+
+	return repo.q.ReschedulePushDelivery(repo.ctx, db.ReschedulePushDeliveryParams{
+		ID:            id,
+		NextAttemptAt: toTimestamp(next),
+		LastError:     toText(lastError),
+	})
+}
+
+func toDomainPushSubscription(row db.PushSubscription) domain.PushSubscription {
+	return domain.PushSubscription{
+		ID:         row.ID.String(),
+		Address:    row.AccountAddress,
+		Endpoint:   row.Endpoint,
+		P256dh:     row.P256dh,
+		Auth:       row.Auth,
+		UserAgent:  row.UserAgent.String,
+		CreatedAt:  row.CreatedAt.Time,
+		LastUsedAt: fromTimestamp(row.LastUsedAt),
+	}
+}
@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// parseUUID converts an id received from a client into a pgtype.UUID, ok is false
+// when the id is malformed so callers can treat it as not found
+func parseUUID(id string) (pgtype.UUID, bool) {
+	var uuid pgtype.UUID
+	if err := uuid.Scan(id); err != nil {
+		return uuid, false
+	}
+	return uuid, true
+}
+
+func toTimestamp(t time.Time) pgtype.Timestamp {
+	return pgtype.Timestamp{Time: t, Valid: true}
+}
+
+func toNullTimestamp(t *time.Time) pgtype.Timestamp {
+	if t == nil {
+		return pgtype.Timestamp{}
+	}
+	return toTimestamp(*t)
+}
+
+func fromTimestamp(ts pgtype.Timestamp) *time.Time {
+	if !ts.Valid {
+		return nil
+	}
+	t := ts.Time
+	return &t
+}
+
+func toNumeric(n *big.Int) pgtype.Numeric {
+	if n == nil {
+		n = new(big.Int)
+	}
+	return pgtype.Numeric{Int: new(big.Int).Set(n), Valid: true}
+}
+
+// fromNumeric converts an integral numeric column to a big.Int. Postgres may return
+// trailing zeros as a positive exponent, eg. 1000 as 1e3
+func fromNumeric(n pgtype.Numeric) *big.Int {
+	if !n.Valid || n.Int == nil {
+		return new(big.Int)
+	}
+
+	out := new(big.Int).Set(n.Int)
+	if n.Exp > 0 {
+		out.Mul(out, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n.Exp)), nil))
+	} else if n.Exp < 0 {
+		out.Quo(out, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-n.Exp)), nil))
+	}
+
+	return out
+}
+
+// toWrappedKey reads a data key stored next to the master key id, nil for plaintext rows
+func toWrappedKey(keyID pgtype.Text, key []byte) *domain.WrappedKey {
+	if !keyID.Valid {
+		return nil
+	}
+	return &domain.WrappedKey{KeyID: keyID.String, Key: key}
+}
+
+func fromWrappedKey(key *domain.WrappedKey) ([]byte, pgtype.Text) {
+	if key == nil {
+		return nil, pgtype.Text{}
+	}
+	return key.Key, pgtype.Text{String: key.KeyID, Valid: true}
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// constraintName names the constraint a statement violated, empty for other errors
+func constraintName(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.ConstraintName
+	}
+	return ""
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
+
+// toText stores empty strings as NULL
+func toText(s string) pgtype.Text {
+	return pgtype.Text{String: s, Valid: s != ""}
+}
@@ -0,0 +1,155 @@
+package repositories
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Xebec19/jibe/api/internal/db"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+)
+
+type SearchRepository interface {
+	// SearchPosts ranks published posts against query, a websearch style query. The
+	// highlight holds ts_headline fragments of title and teaser built with
+	// headlineOptions, posts are returned as stored
+	SearchPosts(query, headlineOptions string, limit, offset int) ([]domain.PostSearchResult, error)
+
+	// SearchCreators fuzzy matches active handles, handles starting with query first
+	SearchCreators(query string, limit, offset int) ([]domain.CreatorSearchResult, error)
+
+	// SearchCommunities ranks communities against query like SearchPosts does
+	SearchCommunities(query, headlineOptions string, limit, offset int) ([]domain.CommunitySearchResult, error)
+}
+
+func NewSearchRepository(ctx context.Context, logger *logger.Logger, q *db.Queries) SearchRepository {
+
+	return &searchRepository{
+		ctx:    ctx,
+		logger: *logger,
+		q:      q,
+	}
+}
+
+type searchRepository struct {
+	ctx    context.Context
+	logger logger.Logger
+	q      *db.Queries
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (repo *searchRepository) SearchPosts(query, headlineOptions string, limit, offset int) ([]domain.PostSearchResult, error) {
+
+	rows, err := repo.q.SearchPosts(repo.ctx, db.SearchPostsParams{
+		HeadlineOptions: headlineOptions,
+		Query:           query,
+		RowLimit:        int32(limit),
+		RowOffset:       int32(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]domain.PostSearchResult, 0, len(rows))
+	for _, row := range rows {
+		post := toDomainPost(db.Post{
+			ID:             row.ID,
+			AuthorAddress:  row.AuthorAddress,
+			Title:          row.Title,
+			Body:           row.Body,
+			BodyFormat:     row.BodyFormat,
+			Status:         row.Status,
+			AccessPolicy:   row.AccessPolicy,
+			CreatedAt:      row.CreatedAt,
+			UpdatedAt:      row.UpdatedAt,
+			PublishedAt:    row.PublishedAt,
+			BodyCiphertext: row.BodyCiphertext,
+			DataKey:        row.DataKey,
+			KeyID:          row.KeyID,
+			Teaser:         row.Teaser,
+			Tags:           row.Tags,
+			PublishAt:      row.PublishAt,
+		})
+
+		results = append(results, domain.PostSearchResult{
+			PostView: domain.PostView{Post: post},
+			Rank:     row.Rank,
+			Highlight: domain.SearchHighlight{
+				Title:  row.TitleHeadline,
+				Teaser: row.TeaserHeadline,
+			},
+		})
+	}
+
+	return results, nil
+}
+
+func (repo *searchRepository) SearchCreators(query string, limit, offset int) ([]domain.CreatorSearchResult, error) {
+
+	query = strings.ToLower(query)
+
+	rows, err := repo.q.SearchHandles(repo.ctx, db.SearchHandlesParams{
+		Query:     query,
+		Prefix:    likeEscaper.Replace(query) + "%",
+		RowLimit:  int32(limit),
+		RowOffset: int32(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]domain.CreatorSearchResult, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, domain.CreatorSearchResult{
+			Handle:  row.Handle,
+			Address: row.EthAddress,
+			Score:   row.Score,
+		})
+	}
+
+	return results, nil
+}
+
+func (repo *searchRepository) SearchCommunities(query, headlineOptions string, limit, offset int) ([]domain.CommunitySearchResult, error) {
+
+	rows, err := repo.q.SearchCommunities(repo.ctx, db.SearchCommunitiesParams{
+		HeadlineOptions: headlineOptions,
+		Query:           query,
+		RowLimit:        int32(limit),
+		RowOffset:       int32(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]domain.CommunitySearchResult, 0, len(rows))
+	for _, row := range rows {
+		community := toDomainCommunity(db.Community{
+			ID:           row.ID,
+			Slug:         row.Slug,
+			Name:         row.Name,
+			Description:  row.Description,
+			OwnerAddress: row.OwnerAddress,
+			JoinPolicy:   row.JoinPolicy,
+			AccessPolicy: row.AccessPolicy,
+			PriceChainID: row.PriceChainID,
+			PriceToken:   row.PriceToken,
+			PriceAmount:  row.PriceAmount,
+			MemberCount:  row.MemberCount,
+			CreatedAt:    row.CreatedAt,
+			UpdatedAt:    row.UpdatedAt,
+		})
+
+		results = append(results, domain.CommunitySearchResult{
+			Community: community,
+			Rank:      row.Rank,
+			Highlight: domain.SearchHighlight{
+				Name:        row.NameHeadline,
+				Description: row.DescriptionHeadline,
+			},
+		})
+	}
+
+	return results, nil
+}
@@ -0,0 +1,383 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/db"
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type UploadRepository interface {
+	// CreateUpload reserves the declared length against the owner's quota and records
+	// the upload. It fails with ErrStorageQuotaExceeded when the reservation does not fit
+	CreateUpload(owner string, input domain.UploadInput, quota int64, expiresAt time.Time) (*domain.Upload, error)
+
+	GetUpload(id string) (*domain.Upload, error)
+
+	// AppendChunk records a chunk and moves the upload offset past it. It fails with
+	// ErrUploadOffsetMismatch when another chunk was appended at the same offset first
+	AppendChunk(id string, chunk domain.UploadChunk, expiresAt time.Time) error
+
+	// ListChunks returns the chunks of an upload in offset order
+	ListChunks(id string) ([]domain.UploadChunk, error)
+
+	// LockUpload holds the lock of an upload until unlock is called, so one request at a
+	// time completes it. A lock is also freed when its connection drops
+	LockUpload(id string) (unlock func(), err error)
+
+	// CompleteUpload links the media file built from the upload and forgets its chunks.
+	// It fails with ErrUploadNotFound when the upload is gone or was completed already
+	CompleteUpload(id, mediaID string) error
+
+	DeleteUpload(id string) error
+
+	// ListExpiredUploads returns incomplete uploads the client gave up on
+	ListExpiredUploads(limit int) ([]domain.Upload, error)
+
+	// DeleteExpiredCompletedUploads forgets up to limit completed uploads past their
+	// expiry, the media files they were imported as are kept
+	DeleteExpiredCompletedUploads(limit int) (int, error)
+
+	// ListUploadsToRewrap pages by id through uploads whose data key is wrapped by a
+	// master key other than activeKeyID
+	ListUploadsToRewrap(activeKeyID, afterID string, limit int) ([]domain.Upload, error)
+
+	RewrapUploadKey(id string, key domain.WrappedKey) error
+}
+
+func NewUploadRepository(ctx context.Context, logger *logger.Logger, pool *pgxpool.Pool, q *db.Queries) UploadRepository {
+
+	return &uploadRepository{
+		ctx:    ctx,
+		logger: *logger,
+		pool:   pool,
+		q:      q,
+	}
+}
+
+type uploadRepository struct {
+	ctx    context.Context
+	logger logger.Logger
+	pool   *pgxpool.Pool
+	q      *db.Queries
+}
+
+func (repo *uploadRepository) CreateUpload(owner string, input domain.UploadInput, quota int64, expiresAt time.Time) (*domain.Upload, error) {
+
+	var postID pgtype.UUID
+	if input.PostID != "" {
+		var ok bool
+		if postID, ok = parseUUID(input.PostID); !ok {
+			return nil, domain.ErrPostNotFound
+		}
+	}
+
+	tx, err := repo.pool.Begin(repo.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(repo.ctx)
+
+	qtx := repo.q.WithTx(tx)
+
+	// concurrent creations of one owner would otherwise all fit the same free space
+	if err := qtx.LockUploadOwner(repo.ctx, owner); err != nil {
+		return nil, err
+	}
+
+	used, err := qtx.GetStorageUsage(repo.ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	if quota > 0 && used+input.Length > quota {
+		return nil, domain.ErrStorageQuotaExceeded
+	}
+
+	dataKey, keyID := fromWrappedKey(input.DataKey)
+
+	row, err := qtx.CreateUpload(repo.ctx, db.CreateUploadParams{
+		OwnerAddress: owner,
+		UploadLength: input.Length,
+		Metadata:     input.Metadata,
+		Filename:     input.Filename,
+		PostID:       postID,
+		ExpiresAt:    toTimestamp(expiresAt),
+		DataKey:      dataKey,
+		KeyID:        keyID,
+	})
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return nil, domain.ErrPostNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(repo.ctx); err != nil {
+		return nil, err
+	}
+
+	upload := toDomainUpload(row)
+	return &upload, nil
+}
+
+func (repo *uploadRepository) GetUpload(id string) (*domain.Upload, error) {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return nil, domain.ErrUploadNotFound
+	}
+
+	row, err := repo.q.GetUpload(repo.ctx, uuid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	upload := toDomainUpload(row)
+	return &upload, nil
+}
+
+func (repo *uploadRepository) AppendChunk(id string, chunk domain.UploadChunk, expiresAt time.Time) error {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return domain.ErrUploadNotFound
+	}
+
+	tx, err := repo.pool.Begin(repo.ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(repo.ctx)
+
+	qtx := repo.q.WithTx(tx)
+
+	rows, err := qtx.AdvanceUploadOffset(repo.ctx, db.AdvanceUploadOffsetParams{
+		NewOffset: chunk.Offset + chunk.SizeBytes,
+		ExpiresAt: toTimestamp(expiresAt),
+		ID:        uuid,
+		OldOffset: chunk.Offset,
+	})
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return domain.ErrUploadOffsetMismatch
+	}
+
+	err = qtx.CreateUploadChunk(repo.ctx, db.CreateUploadChunkParams{
+		UploadID:    uuid,
+		ChunkOffset: chunk.Offset,
+		StorageKey:  chunk.StorageKey,
+		SizeBytes:   chunk.SizeBytes,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(repo.ctx)
+}
+
+func (repo *uploadRepository) ListChunks(id string) ([]domain.UploadChunk, error) {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return nil, domain.ErrUploadNotFound
+	}
+
+	rows, err := repo.q.ListUploadChunks(repo.ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make([]domain.UploadChunk, 0, len(rows))
+	for _, row := range rows {
+		chunks = append(chunks, domain.UploadChunk{
+			Offset:     row.ChunkOffset,
+			StorageKey: row.StorageKey,
+			SizeBytes:  row.SizeBytes,
+		})
+	}
+
+	return chunks, nil
+}
+
+func (repo *uploadRepository) LockUpload(id string) (func(), error) {
+
+	// the lock is held by a session, so the connection is kept out of the pool for as
+	// long as the upload is completed
+	conn, err := repo.pool.Acquire(repo.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	q := db.New(conn)
+
+	if err := q.LockUpload(repo.ctx, id); err != nil {
+		conn.Release()
+		return nil, err
+	}
+
+	unlock := func() {
+		if err := q.UnlockUpload(context.Background(), id); err != nil {
+			// a connection still holding the lock must not go back to the pool
+			repo.logger.Warn("upload unlock failed", "upload", id, "error", err)
+			conn.Conn().Close(context.Background())
+		}
+		conn.Release()
+	}
+
+	return unlock, nil
+}
+
+func (repo *uploadRepository) CompleteUpload(id, mediaID string) error {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return domain.ErrUploadNotFound
+	}
+
+	mediaUUID, ok := parseUUID(mediaID)
+	if !ok {
+		return domain.ErrMediaNotFound
+	}
+
+	tx, err := repo.pool.Begin(repo.ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(repo.ctx)
+
+	qtx := repo.q.WithTx(tx)
+
+	completed, err := qtx.CompleteUpload(repo.ctx, db.CompleteUploadParams{ID: uuid, MediaID: mediaUUID})
+	if err != nil {
+		return err
+	}
+
+	if completed == 0 {
+		return domain.ErrUploadNotFound
+	}
+
+	if err := qtx.DeleteUploadChunks(repo.ctx, uuid); err != nil {
+		return err
+	}
+
+	return tx.Commit(repo.ctx)
+}
+
+func (repo *uploadRepository) DeleteUpload(id string) error {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return domain.ErrUploadNotFound
+	}
+
+	return repo.q.DeleteUpload(repo.ctx, uuid)
+}
+
+func (repo *uploadRepository) ListExpiredUploads(limit int) ([]domain.Upload, error) {
+
+	rows, err := repo.q.ListExpiredUploads(repo.ctx, int32(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	uploads := make([]domain.Upload, 0, len(rows))
+	for _, row := range rows {
+		uploads = append(uploads, toDomainUpload(row))
+	}
+
+	return uploads, nil
+}
+
+func (repo *uploadRepository) DeleteExpiredCompletedUploads(limit int) (int, error) {
+
+	rows, err := repo.q.DeleteExpiredCompletedUploads(repo.ctx, int32(limit))
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rows), nil
+}
+
+func (repo *uploadRepository) ListUploadsToRewrap(activeKeyID, afterID string, limit int) ([]domain.Upload, error) {
+
+	after, ok := parseUUID(afterID)
+	if !ok {
+		return nil, domain.ErrUploadNotFound
+	}
+
+	rows, err := repo.q.ListUploadsToRewrap(repo.ctx, db.ListUploadsToRewrapParams{
+		ActiveKeyID: pgtype.Text{String: activeKeyID, Valid: true},
+		AfterID:     after,
+		RowLimit:    int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uploads := make([]domain.Upload, 0, len(rows))
+	for _, row := range rows {
+		uploads = append(uploads, toDomainUpload(row))
+	}
+
+	return uploads, nil
+}
+
+func (repo *uploadRepository) RewrapUploadKey(id string, key domain.WrappedKey) error {
+
+	uuid, ok := parseUUID(id)
+	if !ok {
+		return domain.ErrUploadNotFound
+	}
+
+	dataKey, keyID := fromWrappedKey(&key)
+
+	return repo.q.RewrapUploadKey(repo.ctx, db.RewrapUploadKeyParams{
+		ID:      uuid,
+		DataKey: dataKey,
+		KeyID:   keyID,
+	})
+}
+
+func toDomainUpload(row db.Upload) domain.Upload {
+
+	upload := domain.Upload{
+		ID:           row.ID.String(),
+		OwnerAddress: row.OwnerAddress,
+		Length:       row.UploadLength,
+		Offset:       row.UploadOffset,
+		Metadata:     row.Metadata,
+		Filename:     row.Filename,
+		CompletedAt:  fromTimestamp(row.CompletedAt),
+		ExpiresAt:    row.ExpiresAt.Time,
+		CreatedAt:    row.CreatedAt.Time,
+		DataKey:      toWrappedKey(row.KeyID, row.DataKey),
+	}
+
+	if row.PostID.Valid {
+		postID := row.PostID.String()
+		upload.PostID = &postID
+	}
+
+	if row.MediaID.Valid {
+		mediaID := row.MediaID.String()
+		upload.MediaID = &mediaID
+	}
+
+	return upload
+}
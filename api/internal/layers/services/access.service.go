@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/pkg/gating"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type AccessService interface {
+	// ValidatePolicy checks that an access policy is well formed before it is stored
+	ValidatePolicy(policy json.RawMessage) error
+
+	// Evaluate decides if the viewer satisfies the access policy. Anonymous viewers only
+	// satisfy public policies. Decisions are cached briefly unless the viewer bypasses
+	// the cache
+	Evaluate(viewer domain.Viewer, policy json.RawMessage) (domain.AccessDecision, error)
+
+	// EvaluateAll decides a list of policies for the viewer in one go. Each distinct
+	// policy is evaluated once and all of them concurrently, so their chain reads share
+	// a multicall batch. Decisions and errors line up with policies
+	EvaluateAll(viewer domain.Viewer, policies []json.RawMessage) ([]domain.AccessDecision, []error)
+
+	// Preview lists the addresses which satisfy the policy at the end of block, answered
+	// from indexed transfer history
+	Preview(policy json.RawMessage, block uint64) ([]string, error)
+
+	// InvalidateTransfers drops cached decisions of the senders and receivers of the
+	// transfers, it is subscribed to the token indexer
+	InvalidateTransfers(chainID uint64, transfers []domain.TokenTransfer)
+
+	CacheStats() gating.CacheStats
+}
+
+func NewAccessService(ctx context.Context, logger logger.Logger, evaluator *gating.Evaluator, cache *gating.DecisionCache) AccessService {
+
+	return &accessService{
+		ctx:       ctx,
+		logger:    logger,
+		evaluator: evaluator,
+		cache:     cache,
+	}
+}
+
+type accessService struct {
+	ctx       context.Context
+	logger    logger.Logger
+	evaluator *gating.Evaluator
+	cache     *gating.DecisionCache
+}
+
+func (svc *accessService) ValidatePolicy(policy json.RawMessage) error {
+
+	if isPublicPolicy(policy) {
+		return nil
+	}
+
+	if _, err := gating.Parse(policy); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidPolicy, err)
+	}
+
+	return nil
+}
+
+func (svc *accessService) Evaluate(viewer domain.Viewer, policy json.RawMessage) (domain.AccessDecision, error) {
+
+	if isPublicPolicy(policy) {
+		return domain.AccessDecision{Allowed: true}, nil
+	}
+
+	if viewer.Address == "" {
+		return domain.AccessDecision{Reason: "sign in to check access"}, nil
+	}
+
+	addr := common.HexToAddress(viewer.Address)
+	policyKey := gating.PolicyKey(policy)
+
+	if !viewer.BypassCache {
+		if decision, ok := svc.cache.Get(addr, policyKey); ok {
+			return domain.AccessDecision{Allowed: decision.Allowed, Reason: decision.Explanation}, nil
+		}
+	}
+
+	rule, err := gating.Parse(policy)
+	if err != nil {
+		return domain.AccessDecision{}, fmt.Errorf("%w: %v", domain.ErrInvalidPolicy, err)
+	}
+
+	epoch := svc.cache.Epoch()
+
+	decision, err := svc.evaluator.Evaluate(svc.ctx, *rule, addr)
+	if err != nil {
+		return domain.AccessDecision{}, fmt.Errorf("policy evaluation failed %w", err)
+	}
+
+	svc.cache.Set(addr, policyKey, *rule, decision, epoch)
+
+	return domain.AccessDecision{
+		Allowed: decision.Allowed,
+		Reason:  decision.Explanation,
+	}, nil
+}
+
+func (svc *accessService) EvaluateAll(viewer domain.Viewer, policies []json.RawMessage) ([]domain.AccessDecision, []error) {
+
+	type outcome struct {
+		decision domain.AccessDecision
+		err      error
+	}
+
+	outcomes := make(map[string]*outcome, len(policies))
+
+	var wg sync.WaitGroup
+	for _, policy := range policies {
+		key := gating.PolicyKey(policy)
+		if _, ok := outcomes[key]; ok {
+			continue
+		}
+
+		o := &outcome{}
+		outcomes[key] = o
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			o.decision, o.err = svc.Evaluate(viewer, policy)
+		}()
+	}
+	wg.Wait()
+
+	decisions := make([]domain.AccessDecision, len(policies))
+	errs := make([]error, len(policies))
+	for i, policy := range policies {
+		o := outcomes[gating.PolicyKey(policy)]
+		decisions[i], errs[i] = o.decision, o.err
+	}
+
+	return decisions, errs
+}
+
+func (svc *accessService) Preview(policy json.RawMessage, block uint64) ([]string, error) {
+
+	if isPublicPolicy(policy) {
+		return nil, fmt.Errorf("%w: a public policy admits every address", domain.ErrPreviewUnsupported)
+	}
+
+	rule, err := gating.Parse(policy)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidPolicy, err)
+	}
+
+	addrs, err := svc.evaluator.Qualifying(svc.ctx, *rule, block)
+	if errors.Is(err, gating.ErrNotPreviewable) || errors.Is(err, gating.ErrTooManyCandidates) {
+		return nil, fmt.Errorf("%w: %v", domain.ErrPreviewUnsupported, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("policy preview failed %w", err)
+	}
+
+	qualifying := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		qualifying = append(qualifying, addr.Hex())
+	}
+
+	return qualifying, nil
+}
+
+func (svc *accessService) InvalidateTransfers(chainID uint64, transfers []domain.TokenTransfer) {
+
+	for _, t := range transfers {
+		svc.cache.Invalidate(chainID, common.HexToAddress(t.Contract), common.HexToAddress(t.From), common.HexToAddress(t.To))
+	}
+}
+
+func (svc *accessService) CacheStats() gating.CacheStats {
+	return svc.cache.Stats()
+}
+
+func isPublicPolicy(policy json.RawMessage) bool {
+	return len(policy) == 0 || string(policy) == "null"
+}
@@ -0,0 +1,466 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/repositories"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/Xebec19/jibe/api/pkg/pgnotify"
+)
+
+const (
+	// chatSessionBuffer is how many events a session queues for a slow client before
+	// it starts dropping typing and presence events, and then ends the session
+	chatSessionBuffer = 64
+
+	// ChatHeartbeatInterval is how often replicas mark their connections live, a
+	// connection stops counting as present after chatPresenceTTL without one
+	ChatHeartbeatInterval = 30 * time.Second
+	chatPresenceTTL       = 3 * ChatHeartbeatInterval
+
+	// chatMessageBurst messages can be sent at once, then one per chatMessageEvery
+	chatMessageBurst = 5
+	chatMessageEvery = time.Second
+
+	// chatTypingEvery throttles the typing events of a session
+	chatTypingEvery = 3 * time.Second
+)
+
+// ChatSession is a connection to the chat channel of a community. Events delivers what
+// happens in the channel until Done is closed, Err then tells why the session ended
+type ChatSession struct {
+	ID          string
+	CommunityID string
+	Address     string
+
+	events chan domain.ChatEvent
+	done   chan struct{}
+	once   sync.Once
+
+	mu         sync.Mutex
+	err        error
+	tokens     float64
+	refilledAt time.Time
+	typedAt    time.Time
+}
+
+func newChatSession(id, communityID, addr string) *ChatSession {
+	return &ChatSession{
+		ID:          id,
+		CommunityID: communityID,
+		Address:     addr,
+		events:      make(chan domain.ChatEvent, chatSessionBuffer),
+		done:        make(chan struct{}),
+		tokens:      chatMessageBurst,
+		refilledAt:  time.Now(),
+	}
+}
+
+func (s *ChatSession) Events() <-chan domain.ChatEvent {
+	return s.events
+}
+
+func (s *ChatSession) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *ChatSession) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+func (s *ChatSession) end(err error) {
+	s.once.Do(func() {
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+		close(s.done)
+	})
+}
+
+// deliver queues an event without blocking the fan-out. A client which stopped
+// reading loses typing and presence events first, then its session
+func (s *ChatSession) deliver(event domain.ChatEvent) {
+
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+
+	select {
+	case s.events <- event:
+	default:
+		if !event.Droppable() {
+			s.end(domain.ErrChatTooSlow)
+		}
+	}
+}
+
+// allowMessage takes a token from the session's bucket
+func (s *ChatSession) allowMessage(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens = min(chatMessageBurst, s.tokens+now.Sub(s.refilledAt).Seconds()/chatMessageEvery.Seconds())
+	s.refilledAt = now
+
+	if s.tokens < 1 {
+		return false
+	}
+	s.tokens--
+	return true
+}
+
+func (s *ChatSession) allowTyping(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.typedAt) < chatTypingEvery {
+		return false
+	}
+	s.typedAt = now
+	return true
+}
+
+type ChatService interface {
+	// Connect opens a session on the chat of a community. Membership and the gating it
+	// was granted under are checked against fresh chain state. The session starts with
+	// a presence_state event listing who is online
+	Connect(viewer domain.Viewer, idOrSlug string) (*ChatSession, error)
+
+	// Disconnect ends a session, the member is announced offline once their last
+	// connection is gone. It is safe to call more than once
+	Disconnect(session *ChatSession)
+
+	// Handle runs a command sent by the client of a session. Problems with the command
+	// are reported to the client as error events, only internal failures are returned
+	Handle(session *ChatSession, command domain.ChatCommand) error
+
+	// Recheck re-evaluates the membership behind a session, so members who no longer
+	// satisfy the gating are removed. The session ends when the membership is gone
+	Recheck(session *ChatSession) error
+
+	// History returns a page of messages of a community, newest first
+	History(viewer domain.Viewer, idOrSlug, cursor string, limit int) (*domain.Page[domain.ChatMessage], error)
+
+	// Heartbeat marks the sessions of this replica live and purges the sessions of
+	// replicas which went away, it runs every ChatHeartbeatInterval
+	Heartbeat() error
+
+	// Dispatch delivers an event published by any replica to the local sessions of its
+	// channel, it is fed by the chat notification listener
+	Dispatch(payload string)
+
+	// Resync tells every local session events may have been missed, after the
+	// notification listener reconnected
+	Resync()
+}
+
+func NewChatService(logger logger.Logger, chatRepo repositories.ChatRepository, communityService CommunityService) ChatService {
+
+	return &chatService{
+		logger:           logger,
+		chatRepo:         chatRepo,
+		communityService: communityService,
+		rooms:            make(map[string]map[*ChatSession]struct{}),
+	}
+}
+
+type chatService struct {
+	logger           logger.Logger
+	chatRepo         repositories.ChatRepository
+	communityService CommunityService
+
+	// rooms holds the sessions connected to this replica by community id
+	mu    sync.RWMutex
+	rooms map[string]map[*ChatSession]struct{}
+}
+
+func (svc *chatService) Connect(viewer domain.Viewer, idOrSlug string) (*ChatSession, error) {
+
+	// joining a channel is checked on fresh chain state, later rechecks may use the cache
+	community, _, err := svc.communityService.VerifyMembership(domain.Viewer{Address: viewer.Address, BypassCache: true}, idOrSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID, err := svc.chatRepo.AddSession(community.ID, viewer.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	session := newChatSession(sessionID, community.ID, viewer.Address)
+
+	svc.mu.Lock()
+	room, ok := svc.rooms[community.ID]
+	if !ok {
+		room = make(map[*ChatSession]struct{})
+		svc.rooms[community.ID] = room
+	}
+	room[session] = struct{}{}
+	svc.mu.Unlock()
+
+	liveSince := time.Now().Add(-chatPresenceTTL)
+
+	present, err := svc.chatRepo.ListPresent(community.ID, liveSince)
+	if err != nil {
+		svc.Disconnect(session)
+		return nil, err
+	}
+	session.deliver(domain.ChatEvent{Type: domain.ChatEventPresenceState, CommunityID: community.ID, Members: present})
+
+	sessions, err := svc.chatRepo.CountSessions(community.ID, viewer.Address, liveSince)
+	if err != nil {
+		svc.Disconnect(session)
+		return nil, err
+	}
+	if sessions == 1 {
+		svc.publish(domain.ChatEvent{Type: domain.ChatEventPresence, CommunityID: community.ID, Address: viewer.Address, Online: true})
+	}
+
+	return session, nil
+}
+
+func (svc *chatService) Disconnect(session *ChatSession) {
+
+	session.end(domain.ErrChatClosed)
+
+	svc.mu.Lock()
+	room, ok := svc.rooms[session.CommunityID]
+	if ok {
+		if _, ok = room[session]; ok {
+			delete(room, session)
+		}
+		if len(room) == 0 {
+			delete(svc.rooms, session.CommunityID)
+		}
+	}
+	svc.mu.Unlock()
+
+	// the session was already disconnected
+	if !ok {
+		return
+	}
+
+	if err := svc.chatRepo.RemoveSession(session.ID); err != nil {
+		svc.logger.Warn("chat session removal failed", "session", session.ID, "error", err)
+		return
+	}
+
+	sessions, err := svc.chatRepo.CountSessions(session.CommunityID, session.Address, time.Now().Add(-chatPresenceTTL))
+	if err != nil {
+		svc.logger.Warn("chat session count failed", "session", session.ID, "error", err)
+		return
+	}
+	if sessions == 0 {
+		svc.publish(domain.ChatEvent{Type: domain.ChatEventPresence, CommunityID: session.CommunityID, Address: session.Address})
+	}
+}
+
+func (svc *chatService) Handle(session *ChatSession, command domain.ChatCommand) error {
+
+	var err error
+
+	switch command.Type {
+	case domain.ChatCommandMessage:
+		err = svc.send(session, command.Body)
+	case domain.ChatCommandTyping:
+		if session.allowTyping(time.Now()) {
+			err = svc.publish(domain.ChatEvent{Type: domain.ChatEventTyping, CommunityID: session.CommunityID, Address: session.Address})
+		}
+	default:
+		err = fmt.Errorf("%w: unknown command %q", domain.ErrChatMessageInvalid, command.Type)
+	}
+
+	if errors.Is(err, domain.ErrChatMessageInvalid) || errors.Is(err, domain.ErrChatRateLimited) {
+		session.deliver(domain.ChatEvent{Type: domain.ChatEventError, CommunityID: session.CommunityID, Reason: err.Error()})
+		return nil
+	}
+
+	if err != nil {
+		session.deliver(domain.ChatEvent{Type: domain.ChatEventError, CommunityID: session.CommunityID, Reason: "message could not be sent"})
+	}
+
+	return err
+}
+
+func (svc *chatService) Recheck(session *ChatSession) error {
+
+	_, _, err := svc.communityService.VerifyMembership(domain.Viewer{Address: session.Address}, session.CommunityID)
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, domain.ErrMembershipRevoked), errors.Is(err, domain.ErrCommunityForbidden),
+		errors.Is(err, domain.ErrCommunityNotFound):
+		session.end(err)
+		return nil
+	default:
+		// a flaky rpc keeps the session, the next recheck decides
+		return err
+	}
+}
+
+func (svc *chatService) History(viewer domain.Viewer, idOrSlug, cursor string, limit int) (*domain.Page[domain.ChatMessage], error) {
+
+	community, _, err := svc.communityService.VerifyMembership(viewer, idOrSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := domain.DecodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := svc.chatRepo.ListMessages(community.ID, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.Page[domain.ChatMessage]{}
+	if len(messages) > limit {
+		messages = messages[:limit]
+		last := messages[limit-1]
+		page.NextCursor = domain.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	page.Items = messages
+
+	return page, nil
+}
+
+func (svc *chatService) Heartbeat() error {
+
+	svc.mu.RLock()
+	var ids []string
+	for _, room := range svc.rooms {
+		for session := range room {
+			ids = append(ids, session.ID)
+		}
+	}
+	svc.mu.RUnlock()
+
+	if len(ids) > 0 {
+		if err := svc.chatRepo.TouchSessions(ids); err != nil {
+			return err
+		}
+	}
+
+	purged, err := svc.chatRepo.PurgeSessions(time.Now().Add(-chatPresenceTTL))
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		svc.logger.Info("Stale chat sessions purged", "count", purged)
+	}
+
+	return nil
+}
+
+func (svc *chatService) Dispatch(payload string) {
+
+	var event domain.ChatEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		svc.logger.Warn("chat notification is malformed", "error", err)
+		return
+	}
+
+	svc.mu.RLock()
+	room := svc.rooms[event.CommunityID]
+	sessions := make([]*ChatSession, 0, len(room))
+	for session := range room {
+		sessions = append(sessions, session)
+	}
+	svc.mu.RUnlock()
+
+	if len(sessions) == 0 {
+		return
+	}
+
+	if event.MessageID != "" && event.Message == nil {
+		message, err := svc.chatRepo.GetMessage(event.MessageID)
+		if err != nil {
+			svc.logger.Warn("chat message lookup failed", "message", event.MessageID, "error", err)
+			return
+		}
+		event.Message = message
+		event.MessageID = ""
+	}
+
+	for _, session := range sessions {
+		session.deliver(event)
+	}
+}
+
+func (svc *chatService) Resync() {
+
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	for communityID, room := range svc.rooms {
+		for session := range room {
+			session.deliver(domain.ChatEvent{Type: domain.ChatEventResync, CommunityID: communityID})
+		}
+	}
+}
+
+func (svc *chatService) send(session *ChatSession, body string) error {
+
+	body = strings.TrimSpace(body)
+	if body == "" || utf8.RuneCountInString(body) > domain.ChatMessageMaxLength {
+		return fmt.Errorf("%w: body must be 1 to %d characters", domain.ErrChatMessageInvalid, domain.ChatMessageMaxLength)
+	}
+
+	if !session.allowMessage(time.Now()) {
+		return domain.ErrChatRateLimited
+	}
+
+	created, err := svc.chatRepo.CreateMessage(session.CommunityID, session.Address, body)
+	if err != nil {
+		return err
+	}
+
+	// read back with the author's handle
+	message, err := svc.chatRepo.GetMessage(created.ID)
+	if err != nil {
+		return err
+	}
+
+	return svc.publish(domain.ChatEvent{Type: domain.ChatEventMessage, CommunityID: session.CommunityID, Message: message})
+}
+
+// publish fans an event out to every replica, this one included. Messages too large
+// for a notification are sent by id
+func (svc *chatService) publish(event domain.ChatEvent) error {
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if len(payload) > pgnotify.MaxPayload && event.Message != nil {
+		event.MessageID = event.Message.ID
+		event.Message = nil
+		if payload, err = json.Marshal(event); err != nil {
+			return err
+		}
+	}
+
+	if err := svc.chatRepo.Notify(string(payload)); err != nil {
+		svc.logger.Warn("chat event fan-out failed", "type", event.Type, "community", event.CommunityID, "error", err)
+		return err
+	}
+
+	return nil
+}
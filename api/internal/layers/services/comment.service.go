@@ -0,0 +1,353 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/repositories"
+	"github.com/Xebec19/jibe/api/pkg/eventbus"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+)
+
+type CommentService interface {
+	// ListComments returns a page of top level comments of a post, oldest first
+	ListComments(viewer domain.Viewer, postID, cursor string, limit int) (*domain.Page[domain.Comment], error)
+
+	// ListReplies returns a page of direct replies to a comment, oldest first
+	ListReplies(viewer domain.Viewer, commentID, cursor string, limit int) (*domain.Page[domain.Comment], error)
+
+	// CreateComment comments on a post, or replies to parentID when one is given.
+	// Handles mentioned as @handle are resolved to the accounts holding them
+	CreateComment(viewer domain.Viewer, postID, parentID, body string) (*domain.Comment, error)
+
+	// UpdateComment replaces the body of a comment, allowed for its author
+	UpdateComment(viewer domain.Viewer, id, body string) (*domain.Comment, error)
+
+	// DeleteComment soft deletes a comment. Authors delete their own comments, the
+	// author of the post moderates every comment under it
+	DeleteComment(viewer domain.Viewer, id string) (*domain.Comment, error)
+
+	// RestoreComment brings back a moderated comment, allowed for the author of the post
+	RestoreComment(viewer domain.Viewer, id string) (*domain.Comment, error)
+
+	// ListPostReactions returns the reaction counts of a post
+	ListPostReactions(viewer domain.Viewer, postID string) ([]domain.ReactionCount, error)
+
+	// ReactToPost adds or, when remove is set, takes back an emoji reaction on a post
+	// and returns its updated counts
+	ReactToPost(viewer domain.Viewer, postID, emoji string, remove bool) ([]domain.ReactionCount, error)
+
+	// ReactToComment is ReactToPost for comments
+	ReactToComment(viewer domain.Viewer, commentID, emoji string, remove bool) ([]domain.ReactionCount, error)
+}
+
+func NewCommentService(logger logger.Logger, commentRepo repositories.CommentRepository, postService PostService, handleService HandleService, bus *eventbus.Bus) CommentService {
+
+	return &commentService{
+		logger:        logger,
+		commentRepo:   commentRepo,
+		postService:   postService,
+		handleService: handleService,
+		bus:           bus,
+	}
+}
+
+type commentService struct {
+	logger        logger.Logger
+	commentRepo   repositories.CommentRepository
+	postService   PostService
+	handleService HandleService
+	bus           *eventbus.Bus
+}
+
+func (svc *commentService) ListComments(viewer domain.Viewer, postID, cursor string, limit int) (*domain.Page[domain.Comment], error) {
+
+	post, err := svc.postService.Authorize(viewer, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.page(viewer, post, "", cursor, limit)
+}
+
+func (svc *commentService) ListReplies(viewer domain.Viewer, commentID, cursor string, limit int) (*domain.Page[domain.Comment], error) {
+
+	comment, post, err := svc.authorized(viewer, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.page(viewer, post, comment.ID, cursor, limit)
+}
+
+func (svc *commentService) CreateComment(viewer domain.Viewer, postID, parentID, body string) (*domain.Comment, error) {
+
+	post, err := svc.postService.Authorize(viewer, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	body, mentions, err := svc.parseBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := domain.Comment{
+		PostID:        post.ID,
+		AuthorAddress: viewer.Address,
+		Body:          body,
+		Mentions:      mentions,
+	}
+
+	created := domain.CommentCreated{
+		PostAuthor: post.AuthorAddress,
+		PostTitle:  post.Title,
+	}
+
+	if parentID != "" {
+		parent, err := svc.commentRepo.GetComment(parentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.PostID != post.ID {
+			return nil, domain.ErrCommentNotFound
+		}
+		if parent.IsDeleted() {
+			return nil, domain.ErrCommentDeleted
+		}
+		if parent.Depth >= domain.CommentMaxDepth {
+			return nil, fmt.Errorf("%w: replies nest at most %d levels deep", domain.ErrCommentInvalid, domain.CommentMaxDepth)
+		}
+
+		comment.ParentID = parent.ID
+		comment.Depth = parent.Depth + 1
+		created.ParentAuthor = parent.AuthorAddress
+	}
+
+	stored, err := svc.commentRepo.CreateComment(comment)
+	if err != nil {
+		return nil, err
+	}
+
+	created.Comment = *stored
+	if err := svc.bus.Publish(created); err != nil {
+		svc.logger.Warn("comment created event dropped", "comment", stored.ID, "error", err)
+	}
+
+	return stored, nil
+}
+
+func (svc *commentService) UpdateComment(viewer domain.Viewer, id, body string) (*domain.Comment, error) {
+
+	comment, _, err := svc.authorized(viewer, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if comment.AuthorAddress != viewer.Address {
+		return nil, domain.ErrCommentForbidden
+	}
+	if comment.IsDeleted() {
+		return nil, domain.ErrCommentDeleted
+	}
+
+	body, mentions, err := svc.parseBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.commentRepo.UpdateComment(comment.ID, body, mentions)
+}
+
+func (svc *commentService) DeleteComment(viewer domain.Viewer, id string) (*domain.Comment, error) {
+
+	comment, post, err := svc.authorized(viewer, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if comment.AuthorAddress != viewer.Address && post.AuthorAddress != viewer.Address {
+		return nil, domain.ErrCommentForbidden
+	}
+
+	return svc.commentRepo.DeleteComment(comment.ID, viewer.Address)
+}
+
+func (svc *commentService) RestoreComment(viewer domain.Viewer, id string) (*domain.Comment, error) {
+
+	comment, post, err := svc.authorized(viewer, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if post.AuthorAddress != viewer.Address {
+		return nil, domain.ErrCommentForbidden
+	}
+	if !comment.Moderated {
+		return nil, domain.ErrCommentNotFound
+	}
+
+	return svc.commentRepo.RestoreComment(comment.ID)
+}
+
+func (svc *commentService) ListPostReactions(viewer domain.Viewer, postID string) ([]domain.ReactionCount, error) {
+
+	post, err := svc.postService.Authorize(viewer, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.commentRepo.ListPostReactions(post.ID, viewer.Address)
+}
+
+func (svc *commentService) ReactToPost(viewer domain.Viewer, postID, emoji string, remove bool) ([]domain.ReactionCount, error) {
+
+	if !domain.IsEmoji(emoji) {
+		return nil, domain.ErrReactionInvalid
+	}
+
+	post, err := svc.postService.Authorize(viewer, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	if remove {
+		err = svc.commentRepo.RemovePostReaction(post.ID, viewer.Address, emoji)
+	} else {
+		err = svc.commentRepo.AddPostReaction(post.ID, viewer.Address, emoji)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.commentRepo.ListPostReactions(post.ID, viewer.Address)
+}
+
+func (svc *commentService) ReactToComment(viewer domain.Viewer, commentID, emoji string, remove bool) ([]domain.ReactionCount, error) {
+
+	if !domain.IsEmoji(emoji) {
+		return nil, domain.ErrReactionInvalid
+	}
+
+	comment, _, err := svc.authorized(viewer, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if remove {
+		err = svc.commentRepo.RemoveCommentReaction(comment.ID, viewer.Address, emoji)
+	} else if comment.IsDeleted() {
+		return nil, domain.ErrCommentDeleted
+	} else {
+		err = svc.commentRepo.AddCommentReaction(comment.ID, viewer.Address, emoji)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reactions, err := svc.commentRepo.ListCommentReactions([]string{comment.ID}, viewer.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	return reactions[comment.ID], nil
+}
+
+// authorized loads a comment along with its post, as long as the viewer can read the post
+func (svc *commentService) authorized(viewer domain.Viewer, id string) (*domain.Comment, *domain.Post, error) {
+
+	comment, err := svc.commentRepo.GetComment(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	post, err := svc.postService.Authorize(viewer, comment.PostID)
+	if errors.Is(err, domain.ErrPostNotFound) {
+		return nil, nil, domain.ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return comment, post, nil
+}
+
+// page reads a page of comments with their reactions, one row past the limit tells if
+// there is a next page
+func (svc *commentService) page(viewer domain.Viewer, post *domain.Post, parentID, cursor string, limit int) (*domain.Page[domain.Comment], error) {
+
+	after, err := domain.DecodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	comments, err := svc.commentRepo.ListComments(post.ID, parentID, viewer.Address, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.Page[domain.Comment]{}
+	if len(comments) > limit {
+		comments = comments[:limit]
+		last := comments[limit-1]
+		page.NextCursor = domain.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+
+	ids := make([]string, 0, len(comments))
+	for _, comment := range comments {
+		ids = append(ids, comment.ID)
+	}
+
+	reactions, err := svc.commentRepo.ListCommentReactions(ids, viewer.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range comments {
+		comments[i].Reactions = reactions[comments[i].ID]
+
+		// moderators keep seeing what they removed so they can restore it
+		if comments[i].IsDeleted() && !(comments[i].Moderated && post.AuthorAddress == viewer.Address) {
+			comments[i].Body = ""
+			comments[i].Mentions = nil
+		}
+	}
+
+	page.Items = comments
+	return page, nil
+}
+
+// parseBody trims and checks a comment body, and resolves the accounts it mentions.
+// Handles which do not resolve are left as plain text
+func (svc *commentService) parseBody(body string) (string, []string, error) {
+
+	body = strings.TrimSpace(body)
+	if body == "" || utf8.RuneCountInString(body) > domain.CommentMaxLength {
+		return "", nil, fmt.Errorf("%w: body must be 1 to %d characters", domain.ErrCommentInvalid, domain.CommentMaxLength)
+	}
+
+	handles := domain.ParseMentions(body)
+	if len(handles) > domain.CommentMaxMentions {
+		return "", nil, fmt.Errorf("%w: at most %d accounts can be mentioned", domain.ErrCommentInvalid, domain.CommentMaxMentions)
+	}
+
+	var mentions []string
+	for _, handle := range handles {
+		addr, err := svc.handleService.ResolveAddress(handle)
+		if errors.Is(err, domain.ErrHandleNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		if !slices.Contains(mentions, addr) {
+			mentions = append(mentions, addr)
+		}
+	}
+
+	return body, mentions, nil
+}
@@ -0,0 +1,609 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"regexp"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/repositories"
+	"github.com/Xebec19/jibe/api/pkg/chain"
+	"github.com/Xebec19/jibe/api/pkg/config"
+	"github.com/Xebec19/jibe/api/pkg/eventbus"
+	"github.com/Xebec19/jibe/api/pkg/gating"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var txHashRegex = regexp.MustCompile("^0x[0-9a-fA-F]{64}$")
+
+type CommunityService interface {
+	// CreateCommunity stores a community owned by owner, who becomes its first member
+	CreateCommunity(owner string, input domain.CommunityInput) (*domain.Community, error)
+
+	// UpdateCommunity replaces the editable fields of a community, allowed for admins.
+	// Memberships granted under a previous join policy are kept
+	UpdateCommunity(actor, idOrSlug string, input domain.CommunityInput) (*domain.Community, error)
+
+	// DeleteCommunity removes a community along with its memberships, allowed for the
+	// owner only
+	DeleteCommunity(actor, idOrSlug string) error
+
+	// GetCommunity returns a community by id or slug with the viewer's membership
+	GetCommunity(viewer domain.Viewer, idOrSlug string) (*domain.Community, error)
+
+	// ListJoined returns the communities the address belongs to
+	ListJoined(addr string, limit, offset int) ([]domain.Community, error)
+
+	// Join evaluates the join policy for the viewer and records the membership with the
+	// chain state it was granted under. Paid communities need the hash of the transfer
+	// paying the price, invite only communities can not be joined
+	Join(viewer domain.Viewer, idOrSlug, paymentTx string) (*domain.CommunityMember, error)
+
+	// JoinWithInvite joins the viewer through an invite. Invites of admins admit the
+	// viewer regardless of the join policy, those of other members attribute a regular
+	// join. The access policy bound to the invite is checked either way
+	JoinWithInvite(viewer domain.Viewer, invite domain.CommunityInvite, paymentTx string) (*domain.CommunityMember, error)
+
+	// VerifyMembership checks that the viewer is still a member of the community and
+	// re-evaluates the access policy the membership was granted under. Members failing
+	// it are removed and ErrMembershipRevoked is returned. Admins and the owner are not
+	// re-checked
+	VerifyMembership(viewer domain.Viewer, idOrSlug string) (*domain.Community, *domain.CommunityMember, error)
+
+	// CheckTransfers re-evaluates the gated memberships of accounts which sent tokens.
+	// Members who no longer satisfy their policy are warned with a MembershipExpiring
+	// event, the membership itself ends on its next verification
+	CheckTransfers(event domain.TransfersIndexed)
+
+	// Leave ends the address's membership, the owner can not leave
+	Leave(addr, idOrSlug string) error
+
+	// AddMember lets admins add an account directly, regardless of the join policy
+	AddMember(actor, idOrSlug, addr string) (*domain.CommunityMember, error)
+
+	// SetRole changes the role of a member. The actor must outrank both the member's
+	// current and new role, so admins appoint moderators and only the owner appoints
+	// admins
+	SetRole(actor, idOrSlug, addr, role string) (*domain.CommunityMember, error)
+
+	// RemoveMember removes a member outranked by the actor
+	RemoveMember(actor, idOrSlug, addr string) error
+
+	// ListMembers is the member directory, filtered by role when one is given. Open
+	// communities list their members to anyone, others to their members only
+	ListMembers(viewer domain.Viewer, idOrSlug, role string, limit, offset int) ([]domain.CommunityMember, error)
+}
+
+func NewCommunityService(ctx context.Context, logger logger.Logger, cfg *config.Config, communityRepo repositories.CommunityRepository, indexerRepo repositories.IndexerRepository, accessService AccessService, reader chain.Reader, bus *eventbus.Bus) CommunityService {
+
+	return &communityService{
+		ctx:           ctx,
+		logger:        logger,
+		cfg:           cfg,
+		communityRepo: communityRepo,
+		indexerRepo:   indexerRepo,
+		accessService: accessService,
+		reader:        reader,
+		bus:           bus,
+	}
+}
+
+type communityService struct {
+	ctx           context.Context
+	logger        logger.Logger
+	cfg           *config.Config
+	communityRepo repositories.CommunityRepository
+	indexerRepo   repositories.IndexerRepository
+	accessService AccessService
+	reader        chain.Reader
+	bus           *eventbus.Bus
+}
+
+func (svc *communityService) CreateCommunity(owner string, input domain.CommunityInput) (*domain.Community, error) {
+
+	if err := svc.validate(&input); err != nil {
+		return nil, err
+	}
+
+	return svc.communityRepo.CreateCommunity(owner, input)
+}
+
+func (svc *communityService) UpdateCommunity(actor, idOrSlug string, input domain.CommunityInput) (*domain.Community, error) {
+
+	community, _, err := svc.withRole(actor, idOrSlug, domain.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := svc.validate(&input); err != nil {
+		return nil, err
+	}
+
+	return svc.communityRepo.UpdateCommunity(community.ID, input)
+}
+
+func (svc *communityService) DeleteCommunity(actor, idOrSlug string) error {
+
+	community, _, err := svc.withRole(actor, idOrSlug, domain.RoleOwner)
+	if err != nil {
+		return err
+	}
+
+	return svc.communityRepo.DeleteCommunity(community.ID)
+}
+
+func (svc *communityService) GetCommunity(viewer domain.Viewer, idOrSlug string) (*domain.Community, error) {
+
+	community, err := svc.communityRepo.GetCommunity(idOrSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	if viewer.Address == "" {
+		return community, nil
+	}
+
+	member, err := svc.communityRepo.GetMember(community.ID, viewer.Address)
+	if err != nil && !errors.Is(err, domain.ErrMemberNotFound) {
+		return nil, err
+	}
+	community.Membership = member
+
+	return community, nil
+}
+
+func (svc *communityService) ListJoined(addr string, limit, offset int) ([]domain.Community, error) {
+
+	return svc.communityRepo.ListMemberCommunities(addr, limit, offset)
+}
+
+func (svc *communityService) Join(viewer domain.Viewer, idOrSlug, paymentTx string) (*domain.CommunityMember, error) {
+
+	community, err := svc.communityRepo.GetCommunity(idOrSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.join(viewer, community, nil, paymentTx)
+}
+
+func (svc *communityService) JoinWithInvite(viewer domain.Viewer, invite domain.CommunityInvite, paymentTx string) (*domain.CommunityMember, error) {
+
+	community, err := svc.communityRepo.GetCommunity(invite.CommunityID)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.join(viewer, community, &invite, paymentTx)
+}
+
+// join records the viewer's membership, through invite when one is given
+func (svc *communityService) join(viewer domain.Viewer, community *domain.Community, invite *domain.CommunityInvite, paymentTx string) (*domain.CommunityMember, error) {
+
+	_, err := svc.communityRepo.GetMember(community.ID, viewer.Address)
+	if err == nil {
+		return nil, domain.ErrAlreadyMember
+	}
+	if !errors.Is(err, domain.ErrMemberNotFound) {
+		return nil, err
+	}
+
+	member := domain.CommunityMember{
+		CommunityID:   community.ID,
+		Address:       viewer.Address,
+		Role:          domain.RoleMember,
+		GrantedVia:    community.JoinPolicy,
+		GrantedBlocks: make(map[uint64]uint64),
+	}
+
+	if invite != nil {
+		inviter, err := svc.communityRepo.GetMember(community.ID, invite.InviterAddress)
+		if errors.Is(err, domain.ErrMemberNotFound) {
+			return nil, domain.ErrInviteExpired
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		member.InvitedBy = invite.InviterAddress
+		member.InviteID = invite.ID
+
+		if !isPublicPolicy(invite.AccessPolicy) {
+			blocks, err := svc.evaluate(viewer.Address, invite.AccessPolicy)
+			if err != nil {
+				return nil, err
+			}
+			maps.Copy(member.GrantedBlocks, blocks)
+			member.GrantedPolicy = invite.AccessPolicy
+		}
+
+		// the invites of admins stand in for the join policy
+		if domain.RoleOutranks(inviter.Role, domain.RoleModerator) {
+			member.GrantedVia = domain.GrantedViaInvite
+			return svc.communityRepo.AddMember(member)
+		}
+	}
+
+	switch community.JoinPolicy {
+	case domain.JoinPolicyOpen:
+
+	case domain.JoinPolicyTokenGated:
+		blocks, err := svc.evaluate(viewer.Address, community.AccessPolicy)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(member.GrantedBlocks, blocks)
+		member.GrantedPolicy = allOf(community.AccessPolicy, member.GrantedPolicy)
+
+	case domain.JoinPolicyPaid:
+		block, hash, err := svc.verifyPayment(viewer.Address, community, paymentTx)
+		if err != nil {
+			return nil, err
+		}
+		member.GrantedBlocks[community.Price.ChainID] = max(member.GrantedBlocks[community.Price.ChainID], block)
+		member.PaymentTx = hash
+
+	case domain.JoinPolicyInviteOnly:
+		return nil, domain.ErrInviteOnly
+
+	default:
+		return nil, fmt.Errorf("unknown join policy %q", community.JoinPolicy)
+	}
+
+	joined, err := svc.communityRepo.AddMember(member)
+	if err != nil {
+		return nil, err
+	}
+
+	if joined.PaymentTx != "" {
+		if err := svc.bus.Publish(domain.PaymentReceived{Community: *community, Payer: joined.Address, PaymentTx: joined.PaymentTx}); err != nil {
+			svc.logger.Warn("payment received event dropped", "community", community.ID, "payment", joined.PaymentTx, "error", err)
+		}
+	}
+
+	return joined, nil
+}
+
+func (svc *communityService) VerifyMembership(viewer domain.Viewer, idOrSlug string) (*domain.Community, *domain.CommunityMember, error) {
+
+	community, err := svc.communityRepo.GetCommunity(idOrSlug)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if viewer.Address == "" {
+		return nil, nil, domain.ErrCommunityForbidden
+	}
+
+	member, err := svc.communityRepo.GetMember(community.ID, viewer.Address)
+	if errors.Is(err, domain.ErrMemberNotFound) {
+		return nil, nil, domain.ErrCommunityForbidden
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if isPublicPolicy(member.GrantedPolicy) || domain.RoleOutranks(member.Role, domain.RoleModerator) {
+		return community, member, nil
+	}
+
+	decision, err := svc.accessService.Evaluate(viewer, member.GrantedPolicy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !decision.Allowed {
+		if err := svc.communityRepo.RemoveMember(community.ID, member.Address); err != nil && !errors.Is(err, domain.ErrMemberNotFound) {
+			return nil, nil, err
+		}
+		svc.logger.Info("Community membership revoked", "community", community.ID, "member", member.Address, "reason", decision.Reason)
+		return nil, nil, fmt.Errorf("%w: %s", domain.ErrMembershipRevoked, decision.Reason)
+	}
+
+	return community, member, nil
+}
+
+func (svc *communityService) CheckTransfers(event domain.TransfersIndexed) {
+
+	members, err := svc.communityRepo.ListGatedMemberships(event.Senders)
+	if err != nil {
+		svc.logger.Warn("gated membership lookup failed", "chain", event.ChainID, "error", err)
+		return
+	}
+
+	for _, member := range members {
+		decision, err := svc.accessService.Evaluate(domain.Viewer{Address: member.Address, BypassCache: true}, member.GrantedPolicy)
+		if err != nil {
+			svc.logger.Warn("membership check failed", "community", member.CommunityID, "member", member.Address, "error", err)
+			continue
+		}
+		if decision.Allowed {
+			continue
+		}
+
+		community, err := svc.communityRepo.GetCommunity(member.CommunityID)
+		if err != nil {
+			svc.logger.Warn("community lookup failed", "community", member.CommunityID, "error", err)
+			continue
+		}
+
+		if err := svc.bus.Publish(domain.MembershipExpiring{Community: *community, Member: member, Reason: decision.Reason}); err != nil {
+			svc.logger.Warn("membership expiring event dropped", "community", community.ID, "member", member.Address, "error", err)
+		}
+	}
+}
+
+func (svc *communityService) Leave(addr, idOrSlug string) error {
+
+	community, err := svc.communityRepo.GetCommunity(idOrSlug)
+	if err != nil {
+		return err
+	}
+
+	if community.OwnerAddress == addr {
+		return domain.ErrOwnerCannotLeave
+	}
+
+	return svc.communityRepo.RemoveMember(community.ID, addr)
+}
+
+func (svc *communityService) AddMember(actor, idOrSlug, addr string) (*domain.CommunityMember, error) {
+
+	community, _, err := svc.withRole(actor, idOrSlug, domain.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.communityRepo.AddMember(domain.CommunityMember{
+		CommunityID: community.ID,
+		Address:     addr,
+		Role:        domain.RoleMember,
+		GrantedVia:  domain.GrantedViaInvite,
+		InvitedBy:   actor,
+	})
+}
+
+func (svc *communityService) SetRole(actor, idOrSlug, addr, role string) (*domain.CommunityMember, error) {
+
+	if !domain.IsRole(role) || role == domain.RoleOwner {
+		return nil, fmt.Errorf("%w: unknown role %q", domain.ErrCommunityInvalid, role)
+	}
+
+	community, actorRole, err := svc.withRole(actor, idOrSlug, domain.RoleModerator)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := svc.communityRepo.GetMember(community.ID, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if !domain.RoleOutranks(actorRole, member.Role) || !domain.RoleOutranks(actorRole, role) {
+		return nil, domain.ErrCommunityForbidden
+	}
+
+	return svc.communityRepo.SetMemberRole(community.ID, addr, role)
+}
+
+func (svc *communityService) RemoveMember(actor, idOrSlug, addr string) error {
+
+	community, actorRole, err := svc.withRole(actor, idOrSlug, domain.RoleModerator)
+	if err != nil {
+		return err
+	}
+
+	member, err := svc.communityRepo.GetMember(community.ID, addr)
+	if err != nil {
+		return err
+	}
+
+	if !domain.RoleOutranks(actorRole, member.Role) {
+		return domain.ErrCommunityForbidden
+	}
+
+	return svc.communityRepo.RemoveMember(community.ID, addr)
+}
+
+func (svc *communityService) ListMembers(viewer domain.Viewer, idOrSlug, role string, limit, offset int) ([]domain.CommunityMember, error) {
+
+	if role != "" && !domain.IsRole(role) {
+		return nil, fmt.Errorf("%w: unknown role %q", domain.ErrCommunityInvalid, role)
+	}
+
+	community, err := svc.communityRepo.GetCommunity(idOrSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	if community.JoinPolicy != domain.JoinPolicyOpen {
+		if _, _, err := svc.withRole(viewer.Address, community.ID, domain.RoleMember); err != nil {
+			return nil, err
+		}
+	}
+
+	return svc.communityRepo.ListMembers(community.ID, role, limit, offset)
+}
+
+// withRole loads a community and makes sure addr holds at least minRole in it. It
+// returns the role addr holds
+func (svc *communityService) withRole(addr, idOrSlug, minRole string) (*domain.Community, string, error) {
+
+	community, err := svc.communityRepo.GetCommunity(idOrSlug)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if addr == "" {
+		return nil, "", domain.ErrCommunityForbidden
+	}
+
+	member, err := svc.communityRepo.GetMember(community.ID, addr)
+	if errors.Is(err, domain.ErrMemberNotFound) {
+		return nil, "", domain.ErrCommunityForbidden
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	if member.Role != minRole && !domain.RoleOutranks(member.Role, minRole) {
+		return nil, "", domain.ErrCommunityForbidden
+	}
+
+	return community, member.Role, nil
+}
+
+// evaluate checks an access policy guarding membership for addr. The heads of the
+// chains it reads are taken before evaluating, so the recorded blocks never postdate
+// the state access was granted on
+func (svc *communityService) evaluate(addr string, policy json.RawMessage) (map[uint64]uint64, error) {
+
+	rule, err := gating.Parse(policy)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidPolicy, err)
+	}
+
+	blocks := make(map[uint64]uint64)
+	for _, chainID := range rule.Chains() {
+		head, err := svc.reader.BlockNumber(svc.ctx, chainID)
+		if err != nil {
+			return nil, fmt.Errorf("head of chain %d unavailable %w", chainID, err)
+		}
+		blocks[chainID] = head
+	}
+
+	// a membership outlives the cache, so it is granted on fresh chain state only
+	decision, err := svc.accessService.Evaluate(domain.Viewer{Address: addr, BypassCache: true}, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	if !decision.Allowed {
+		return nil, fmt.Errorf("%w: %s", domain.ErrJoinDenied, decision.Reason)
+	}
+
+	return blocks, nil
+}
+
+// allOf combines the policies a membership was granted under, either may be empty
+func allOf(policy, other json.RawMessage) json.RawMessage {
+
+	if isPublicPolicy(other) {
+		return policy
+	}
+	if isPublicPolicy(policy) {
+		return other
+	}
+
+	combined, _ := json.Marshal(map[string]any{
+		"op":    gating.OpAnd,
+		"rules": []json.RawMessage{policy, other},
+	})
+	return combined
+}
+
+// verifyPayment looks for transfers of the price token from addr to the owner in the
+// indexed transaction. It returns the block of the payment and the normalized hash
+func (svc *communityService) verifyPayment(addr string, community *domain.Community, paymentTx string) (uint64, string, error) {
+
+	if !txHashRegex.MatchString(paymentTx) {
+		return 0, "", fmt.Errorf("%w: payment_tx must be a transaction hash", domain.ErrPaymentInvalid)
+	}
+
+	price := community.Price
+	hash := common.HexToHash(paymentTx).Hex()
+
+	transfers, err := svc.indexerRepo.ListTransactionTransfers(price.ChainID, hash)
+	if err != nil {
+		return 0, "", err
+	}
+
+	token := common.HexToAddress(price.Token)
+	payer := common.HexToAddress(addr)
+	payee := common.HexToAddress(community.OwnerAddress)
+
+	paid := new(big.Int)
+	var block uint64
+
+	for _, t := range transfers {
+		if common.HexToAddress(t.Contract) != token || common.HexToAddress(t.From) != payer || common.HexToAddress(t.To) != payee {
+			continue
+		}
+		paid.Add(paid, t.Amount)
+		block = t.BlockNumber
+	}
+
+	if block == 0 {
+		if len(transfers) == 0 {
+			return 0, "", domain.ErrPaymentNotFound
+		}
+		return 0, "", fmt.Errorf("%w: the transaction pays no %s from %s to the owner", domain.ErrPaymentInvalid, price.Token, addr)
+	}
+
+	amount, _ := new(big.Int).SetString(price.Amount, 10)
+	if paid.Cmp(amount) < 0 {
+		return 0, "", fmt.Errorf("%w: paid %s of %s", domain.ErrPaymentInvalid, paid, amount)
+	}
+
+	return block, hash, nil
+}
+
+// validate normalizes the input and checks that the settings of the join policy are
+// given, and only those
+func (svc *communityService) validate(input *domain.CommunityInput) error {
+
+	slug, ok := domain.NormalizeCommunitySlug(input.Slug)
+	if !ok {
+		return fmt.Errorf("%w: slug must be %d to %d lowercase letters, digits and dashes", domain.ErrCommunityInvalid, domain.CommunitySlugMinLength, domain.CommunitySlugMaxLength)
+	}
+	input.Slug = slug
+
+	if input.JoinPolicy != domain.JoinPolicyTokenGated && !isPublicPolicy(input.AccessPolicy) {
+		return fmt.Errorf("%w: access_policy is only used by token_gated communities", domain.ErrCommunityInvalid)
+	}
+	if input.JoinPolicy != domain.JoinPolicyPaid && input.Price != nil {
+		return fmt.Errorf("%w: price is only used by paid communities", domain.ErrCommunityInvalid)
+	}
+
+	switch input.JoinPolicy {
+	case domain.JoinPolicyOpen, domain.JoinPolicyInviteOnly:
+		input.AccessPolicy = nil
+
+	case domain.JoinPolicyTokenGated:
+		if isPublicPolicy(input.AccessPolicy) {
+			return fmt.Errorf("%w: token_gated communities need an access_policy", domain.ErrCommunityInvalid)
+		}
+		if err := svc.accessService.ValidatePolicy(input.AccessPolicy); err != nil {
+			return err
+		}
+
+	case domain.JoinPolicyPaid:
+		price := input.Price
+		if price == nil {
+			return fmt.Errorf("%w: paid communities need a price", domain.ErrCommunityInvalid)
+		}
+		if !common.IsHexAddress(price.Token) {
+			return fmt.Errorf("%w: price token %q is not an address", domain.ErrCommunityInvalid, price.Token)
+		}
+		// payments are verified from indexed transfers
+		if _, ok := svc.cfg.IndexerStartBlocks[price.ChainID]; !ok {
+			return fmt.Errorf("%w: transfers on chain %d are not indexed", domain.ErrCommunityInvalid, price.ChainID)
+		}
+		amount, ok := new(big.Int).SetString(price.Amount, 10)
+		if !ok || amount.Sign() <= 0 {
+			return fmt.Errorf("%w: price amount %q is not a positive integer", domain.ErrCommunityInvalid, price.Amount)
+		}
+		price.Token = common.HexToAddress(price.Token).Hex()
+		price.Amount = amount.String()
+
+	default:
+		return fmt.Errorf("%w: unknown join policy %q", domain.ErrCommunityInvalid, input.JoinPolicy)
+	}
+
+	return nil
+}
@@ -0,0 +1,504 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/mail"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/repositories"
+	"github.com/Xebec19/jibe/api/pkg/config"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/Xebec19/jibe/api/pkg/mailer"
+)
+
+const (
+	// emailVerifyTTL is how long the double opt-in link stays valid
+	emailVerifyTTL = 24 * time.Hour
+	// emailTTL is how old a notification may get before it is no longer mailed
+	emailTTL = 24 * time.Hour
+	// emailLease is how long a claimed delivery is left to its sender before it is due
+	// again
+	emailLease = 2 * time.Minute
+	// emailMaxAttempts caps the tries of a delivery the server keeps refusing
+	emailMaxAttempts = 6
+	// emailBackoff is the wait before the second try, it doubles up to emailMaxBackoff
+	emailBackoff    = time.Minute
+	emailMaxBackoff = time.Hour
+	// emailDigestItems is how many notifications a digest lists, the others are counted
+	emailDigestItems = 10
+	// emailDigestSlack lets digests go out a little early so they do not drift by the
+	// interval of the digest worker every period
+	emailDigestSlack = time.Hour
+)
+
+// emailKindLabels name the kinds of notifications in digests
+var emailKindLabels = map[string]string{
+	domain.NotificationNewPost:            "New posts",
+	domain.NotificationReply:              "Replies",
+	domain.NotificationMention:            "Mentions",
+	domain.NotificationPaymentReceived:    "Payments received",
+	domain.NotificationMembershipExpiring: "Membership warnings",
+	domain.NotificationContentUnlocked:    "Unlocked content",
+}
+
+type EmailService interface {
+	GetEmail(addr string) (*domain.AccountEmail, error)
+
+	// SetEmail stores the address of an account and mails it a verification link,
+	// nothing else is sent before it was opened. Setting an unverified address again
+	// sends a new link
+	SetEmail(addr, email string) (*domain.AccountEmail, error)
+
+	// VerifyEmail confirms the address the token was mailed to
+	VerifyEmail(token string) (*domain.AccountEmail, error)
+
+	RemoveEmail(addr string) error
+
+	// SetDigest sets how often a summary of the account's notifications is mailed
+	SetDigest(addr, frequency string) (*domain.AccountEmail, error)
+
+	// Unsubscribe switches off the emails named by the token of an unsubscribe link,
+	// it needs no session so mail clients can do it in one click
+	Unsubscribe(token string) error
+
+	// OnNotificationsCreated queues emails of new notifications, it is subscribed to
+	// the event bus
+	OnNotificationsCreated(event domain.NotificationsCreated)
+
+	// OnEnqueued registers a handler called after emails were queued
+	OnEnqueued(handler func())
+
+	// DeliverPending sends up to limit due emails and returns how many it claimed
+	DeliverPending(limit int) (int, error)
+
+	// SendDigests mails up to limit due digests and returns how many it claimed
+	SendDigests(limit int) (int, error)
+}
+
+// NewEmailService returns the email service, sender is nil when no SMTP server is
+// configured and email is disabled
+func NewEmailService(ctx context.Context, logger logger.Logger, cfg *config.Config, sender mailer.Sender, templates *mailer.Templates, emailRepo repositories.EmailRepository, notificationRepo repositories.NotificationRepository) EmailService {
+
+	return &emailService{
+		ctx:              ctx,
+		logger:           logger,
+		cfg:              cfg,
+		sender:           sender,
+		templates:        templates,
+		emailRepo:        emailRepo,
+		notificationRepo: notificationRepo,
+		secret:           []byte(cfg.LinkSigningSecret),
+	}
+}
+
+type emailService struct {
+	ctx              context.Context
+	logger           logger.Logger
+	cfg              *config.Config
+	sender           mailer.Sender
+	templates        *mailer.Templates
+	emailRepo        repositories.EmailRepository
+	notificationRepo repositories.NotificationRepository
+	secret           []byte
+
+	mu       sync.Mutex
+	handlers []func()
+}
+
+func (svc *emailService) GetEmail(addr string) (*domain.AccountEmail, error) {
+
+	return svc.emailRepo.GetEmail(addr)
+}
+
+func (svc *emailService) SetEmail(addr, email string) (*domain.AccountEmail, error) {
+
+	if svc.sender == nil {
+		return nil, domain.ErrEmailDisabled
+	}
+
+	parsed, err := mail.ParseAddress(email)
+	if err != nil || parsed.Address != email {
+		return nil, domain.ErrEmailInvalid
+	}
+
+	current, err := svc.emailRepo.GetEmail(addr)
+	if err != nil && !errors.Is(err, domain.ErrEmailNotFound) {
+		return nil, err
+	}
+	if current != nil && current.IsVerified() && strings.EqualFold(current.Email, email) {
+		return current, nil
+	}
+
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(token)
+
+	stored, err := svc.emailRepo.SetEmail(addr, email, hashEmailToken(encoded), time.Now().Add(emailVerifyTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := svc.templates.Render("verify", struct {
+		Email          string
+		VerifyURL      string
+		ExpiresIn      string
+		UnsubscribeURL string
+	}{
+		Email:     email,
+		VerifyURL: "https://" + svc.cfg.Domain + "/email/verify?token=" + encoded,
+		ExpiresIn: "24 hours",
+	})
+	if err != nil {
+		return nil, err
+	}
+	msg.To = email
+
+	if err := svc.sender.Send(svc.ctx, msg); err != nil {
+		return nil, err
+	}
+
+	return stored, nil
+}
+
+func (svc *emailService) VerifyEmail(token string) (*domain.AccountEmail, error) {
+
+	if token == "" {
+		return nil, domain.ErrEmailTokenInvalid
+	}
+
+	return svc.emailRepo.VerifyEmail(hashEmailToken(token))
+}
+
+func (svc *emailService) RemoveEmail(addr string) error {
+
+	return svc.emailRepo.RemoveEmail(addr)
+}
+
+func (svc *emailService) SetDigest(addr, frequency string) (*domain.AccountEmail, error) {
+
+	switch frequency {
+	case domain.EmailDigestOff, domain.EmailDigestDaily, domain.EmailDigestWeekly:
+	default:
+		return nil, domain.ErrDigestInvalid
+	}
+
+	return svc.emailRepo.SetDigest(addr, frequency)
+}
+
+func (svc *emailService) Unsubscribe(token string) error {
+
+	addr, scope, err := svc.parseUnsubscribeToken(token)
+	if err != nil {
+		return err
+	}
+
+	if scope == domain.EmailUnsubscribeDigest || scope == domain.EmailUnsubscribeAll {
+		if _, err := svc.emailRepo.SetDigest(addr, domain.EmailDigestOff); err != nil && !errors.Is(err, domain.ErrEmailNotFound) {
+			return err
+		}
+	}
+
+	kinds := []string{scope}
+	if scope == domain.EmailUnsubscribeAll {
+		kinds = domain.NotificationKinds
+	}
+
+	for _, kind := range kinds {
+		if !domain.IsNotificationKind(kind) {
+			continue
+		}
+		if err := svc.notificationRepo.SetPreference(addr, domain.NotificationChannelEmail, kind, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (svc *emailService) OnNotificationsCreated(event domain.NotificationsCreated) {
+
+	if svc.sender == nil {
+		return
+	}
+
+	ids := make([]int64, 0, len(event.Notifications))
+	for _, notification := range event.Notifications {
+		ids = append(ids, notification.ID)
+	}
+
+	queued, err := svc.emailRepo.Enqueue(ids)
+	if err != nil {
+		svc.logger.Error("email deliveries could not be queued", "notifications", len(ids), "error", err)
+		return
+	}
+
+	if queued == 0 {
+		return
+	}
+
+	svc.mu.Lock()
+	handlers := svc.handlers
+	svc.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler()
+	}
+}
+
+func (svc *emailService) OnEnqueued(handler func()) {
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.handlers = append(svc.handlers, handler)
+}
+
+func (svc *emailService) DeliverPending(limit int) (int, error) {
+
+	if svc.sender == nil {
+		return 0, nil
+	}
+
+	deliveries, err := svc.emailRepo.ClaimDeliveries(limit, time.Now().Add(emailLease))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, delivery := range deliveries {
+		svc.deliver(delivery)
+	}
+
+	return len(deliveries), nil
+}
+
+// deliver mails one notification and settles the delivery by the answer of the server
+func (svc *emailService) deliver(delivery domain.EmailDelivery) {
+
+	notification := delivery.Notification
+
+	// the address is gone, or the notification was read elsewhere or is too old
+	if delivery.Email == "" || notification.IsRead() || time.Since(notification.CreatedAt) > emailTTL {
+		svc.complete(delivery)
+		return
+	}
+
+	summary := notification.Summarize()
+	unsubscribe := svc.unsubscribeToken(notification.RecipientAddress, notification.Kind)
+
+	msg, err := svc.templates.Render("notification", struct {
+		Summary        domain.NotificationSummary
+		URL            string
+		UnsubscribeURL string
+	}{
+		Summary:        summary,
+		URL:            "https://" + svc.cfg.Domain + summary.Path,
+		UnsubscribeURL: svc.unsubscribePage(unsubscribe),
+	})
+	if err != nil {
+		svc.logger.Error("notification email rendering failed", "notification", notification.ID, "error", err)
+		svc.complete(delivery)
+		return
+	}
+
+	err = svc.send(delivery.Email, msg, unsubscribe)
+
+	switch {
+	case err == nil:
+		svc.complete(delivery)
+
+	case mailer.IsPermanent(err):
+		svc.logger.Warn("notification email refused", "delivery", delivery.ID, "error", err)
+		svc.complete(delivery)
+
+	case delivery.Attempts >= emailMaxAttempts:
+		svc.logger.Warn("notification email given up", "delivery", delivery.ID, "attempts", delivery.Attempts, "error", err)
+		svc.complete(delivery)
+
+	default:
+		wait := min(emailBackoff<<(delivery.Attempts-1), emailMaxBackoff)
+		if err := svc.emailRepo.RescheduleDelivery(delivery.ID, time.Now().Add(wait), err.Error()); err != nil {
+			svc.logger.Error("email delivery reschedule failed", "delivery", delivery.ID, "error", err)
+		}
+	}
+}
+
+func (svc *emailService) complete(delivery domain.EmailDelivery) {
+
+	if err := svc.emailRepo.CompleteDelivery(delivery.ID); err != nil {
+		svc.logger.Error("email delivery completion failed", "delivery", delivery.ID, "error", err)
+	}
+}
+
+func (svc *emailService) SendDigests(limit int) (int, error) {
+
+	if svc.sender == nil {
+		return 0, nil
+	}
+
+	now := time.Now()
+
+	digests, err := svc.emailRepo.ClaimDigests(now.Add(-24*time.Hour+emailDigestSlack), now.Add(-7*24*time.Hour+emailDigestSlack), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, digest := range digests {
+		if err := svc.sendDigest(digest); err != nil {
+			svc.logger.Warn("digest email failed", "address", digest.Address, "error", err)
+
+			// refused addresses are not retried every pass
+			if mailer.IsPermanent(err) {
+				continue
+			}
+			if err := svc.emailRepo.ResetDigest(digest.Address, digest.Since); err != nil {
+				svc.logger.Error("digest reset failed", "address", digest.Address, "error", err)
+			}
+		}
+	}
+
+	return len(digests), nil
+}
+
+type digestCount struct {
+	Label string
+	Count int
+}
+
+type digestItem struct {
+	Title string
+	Body  string
+	URL   string
+}
+
+func (svc *emailService) sendDigest(digest domain.EmailDigest) error {
+
+	counts, err := svc.emailRepo.CountSince(digest.Address, digest.Since)
+	if err != nil {
+		return err
+	}
+
+	data := struct {
+		Frequency        string
+		Total            int
+		Counts           []digestCount
+		Items            []digestItem
+		More             int
+		NotificationsURL string
+		UnsubscribeURL   string
+	}{
+		Frequency:        digest.Frequency,
+		NotificationsURL: "https://" + svc.cfg.Domain + "/notifications",
+	}
+
+	for _, kind := range domain.NotificationKinds {
+		if counts[kind] > 0 {
+			data.Total += counts[kind]
+			data.Counts = append(data.Counts, digestCount{Label: emailKindLabels[kind], Count: counts[kind]})
+		}
+	}
+
+	// quiet periods send nothing
+	if data.Total == 0 {
+		return nil
+	}
+
+	notifications, err := svc.emailRepo.ListSince(digest.Address, digest.Since, emailDigestItems)
+	if err != nil {
+		return err
+	}
+
+	for _, notification := range notifications {
+		summary := notification.Summarize()
+		data.Items = append(data.Items, digestItem{
+			Title: summary.Title,
+			Body:  excerpt(summary.Body, notificationExcerptLength),
+			URL:   "https://" + svc.cfg.Domain + summary.Path,
+		})
+	}
+	data.More = data.Total - len(data.Items)
+
+	unsubscribe := svc.unsubscribeToken(digest.Address, domain.EmailUnsubscribeDigest)
+	data.UnsubscribeURL = svc.unsubscribePage(unsubscribe)
+
+	msg, err := svc.templates.Render("digest", data)
+	if err != nil {
+		return err
+	}
+
+	return svc.send(digest.Email, msg, unsubscribe)
+}
+
+// send mails msg with the one-click unsubscribe headers of RFC 8058
+func (svc *emailService) send(to string, msg mailer.Message, unsubscribeToken string) error {
+
+	msg.To = to
+	msg.Headers = map[string]string{
+		"List-Unsubscribe":      "<https://" + svc.cfg.Domain + "/v1/email/unsubscribe?token=" + url.QueryEscape(unsubscribeToken) + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+
+	return svc.sender.Send(svc.ctx, msg)
+}
+
+// unsubscribePage is the link in the footer of emails, it opens a page confirming the
+// unsubscribe since links in bodies are followed by scanners
+func (svc *emailService) unsubscribePage(token string) string {
+	return "https://" + svc.cfg.Domain + "/email/unsubscribe?token=" + url.QueryEscape(token)
+}
+
+// unsubscribeToken names an account and what to switch off, signed so links can not be
+// forged. They do not expire, old emails keep working
+func (svc *emailService) unsubscribeToken(addr, scope string) string {
+
+	payload := base64.RawURLEncoding.EncodeToString([]byte(addr + "\n" + scope))
+
+	return payload + "." + svc.signUnsubscribe(payload)
+}
+
+func (svc *emailService) parseUnsubscribeToken(token string) (addr, scope string, err error) {
+
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(svc.signUnsubscribe(payload))) {
+		return "", "", domain.ErrUnsubscribeTokenInvalid
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", "", domain.ErrUnsubscribeTokenInvalid
+	}
+
+	addr, scope, ok = strings.Cut(string(raw), "\n")
+	if !ok {
+		return "", "", domain.ErrUnsubscribeTokenInvalid
+	}
+
+	return addr, scope, nil
+}
+
+func (svc *emailService) signUnsubscribe(payload string) string {
+
+	mac := hmac.New(sha256.New, svc.secret)
+	mac.Write([]byte("unsubscribe\n" + payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// hashEmailToken is what is stored of a verification token, a leaked table can not
+// verify addresses
+func hashEmailToken(token string) string {
+
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
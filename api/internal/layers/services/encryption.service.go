@@ -0,0 +1,525 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/repositories"
+	"github.com/Xebec19/jibe/api/pkg/envelope"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/Xebec19/jibe/api/pkg/storage"
+)
+
+// associated data binding each ciphertext to the field it was written to
+var (
+	aadPostBody       = []byte("post.body")
+	aadAttachmentName = []byte("post.attachment.name")
+	aadAttachmentURL  = []byte("post.attachment.url")
+	aadSegmentKey     = []byte("media.stream.key")
+)
+
+// firstID sorts before every uuid, re-encryption pages start after it
+const firstID = "00000000-0000-0000-0000-000000000000"
+
+type EncryptionService interface {
+	// Enabled reports if a master key is configured, new content is stored in plaintext
+	// without one
+	Enabled() bool
+
+	// NewDataKey creates a data key for a record, nil when encryption is disabled
+	NewDataKey() (*domain.WrappedKey, error)
+
+	// SealPostInput encrypts the body of input under key, a new data key is created
+	// when key is nil. Keys wrapped by a retired master key are rewrapped on the way
+	SealPostInput(input *domain.PostInput, key *domain.WrappedKey) error
+
+	// SealAttachment encrypts name and url of an attachment under the post's data key,
+	// attachments of plaintext posts are left as they are
+	SealAttachment(key *domain.WrappedKey, attachment *domain.PostAttachment) error
+
+	// OpenPost decrypts the body and attachments of a post in place. Callers only open
+	// posts the reader was granted access to
+	OpenPost(post *domain.Post) error
+
+	// Seal encrypts a small value under key bound to aad, it is returned as is when key
+	// is nil
+	Seal(key *domain.WrappedKey, plaintext, aad []byte) ([]byte, error)
+
+	// Open reverses Seal
+	Open(key *domain.WrappedKey, ciphertext, aad []byte) ([]byte, error)
+
+	// EncryptStream encrypts r under key, r is returned as is when key is nil
+	EncryptStream(key *domain.WrappedKey, r io.Reader) (io.Reader, error)
+
+	// DecryptStream decrypts an object holding size bytes of plaintext. The returned
+	// reader can seek when src can
+	DecryptStream(key *domain.WrappedKey, src io.ReadCloser, size int64) (io.ReadCloser, error)
+
+	// Reencrypt encrypts posts and media files stored in plaintext and rewraps the data
+	// keys of records still wrapped by a retired master key
+	Reencrypt(batchSize int, dryRun bool) (*domain.ReencryptReport, error)
+}
+
+// NewEncryptionService encrypts content under keyring, which is nil when no master
+// key is configured
+func NewEncryptionService(ctx context.Context, logger logger.Logger, keyring *envelope.Keyring, store storage.Storage, postRepo repositories.PostRepository, mediaRepo repositories.MediaRepository, uploadRepo repositories.UploadRepository) EncryptionService {
+
+	return &encryptionService{
+		ctx:        ctx,
+		logger:     logger,
+		keyring:    keyring,
+		store:      store,
+		postRepo:   postRepo,
+		mediaRepo:  mediaRepo,
+		uploadRepo: uploadRepo,
+	}
+}
+
+type encryptionService struct {
+	ctx        context.Context
+	logger     logger.Logger
+	keyring    *envelope.Keyring
+	store      storage.Storage
+	postRepo   repositories.PostRepository
+	mediaRepo  repositories.MediaRepository
+	uploadRepo repositories.UploadRepository
+}
+
+func (svc *encryptionService) Enabled() bool {
+	return svc.keyring != nil
+}
+
+func (svc *encryptionService) NewDataKey() (*domain.WrappedKey, error) {
+
+	if svc.keyring == nil {
+		return nil, nil
+	}
+
+	_, wrapped, err := svc.keyring.NewDataKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.WrappedKey{KeyID: svc.keyring.ActiveKeyID(), Key: wrapped}, nil
+}
+
+func (svc *encryptionService) SealPostInput(input *domain.PostInput, key *domain.WrappedKey) error {
+
+	if svc.keyring == nil {
+		if key != nil {
+			return domain.ErrContentSealed
+		}
+		return nil
+	}
+
+	var err error
+	if key == nil {
+		if key, err = svc.NewDataKey(); err != nil {
+			return err
+		}
+	} else if key.KeyID != svc.keyring.ActiveKeyID() {
+		if key, err = svc.rewrap(*key); err != nil {
+			return err
+		}
+	}
+
+	dataKey, err := svc.unwrap(key)
+	if err != nil {
+		return err
+	}
+
+	sealed, err := dataKey.Seal([]byte(input.Body), aadPostBody)
+	if err != nil {
+		return err
+	}
+
+	input.Body = ""
+	input.SealedBody = sealed
+	input.DataKey = key
+
+	return nil
+}
+
+func (svc *encryptionService) SealAttachment(key *domain.WrappedKey, attachment *domain.PostAttachment) error {
+
+	if key == nil {
+		return nil
+	}
+
+	dataKey, err := svc.unwrap(key)
+	if err != nil {
+		return err
+	}
+
+	if attachment.SealedName, err = dataKey.Seal([]byte(attachment.Name), aadAttachmentName); err != nil {
+		return err
+	}
+	if attachment.SealedURL, err = dataKey.Seal([]byte(attachment.URL), aadAttachmentURL); err != nil {
+		return err
+	}
+
+	attachment.Name = ""
+	attachment.URL = ""
+
+	return nil
+}
+
+func (svc *encryptionService) OpenPost(post *domain.Post) error {
+
+	if post.DataKey == nil {
+		return nil
+	}
+
+	dataKey, err := svc.unwrap(post.DataKey)
+	if err != nil {
+		return err
+	}
+
+	body, err := dataKey.Open(post.SealedBody, aadPostBody)
+	if err != nil {
+		return fmt.Errorf("post %s body %w", post.ID, err)
+	}
+	post.Body = string(body)
+
+	for i := range post.Attachments {
+		attachment := &post.Attachments[i]
+		if attachment.SealedName == nil {
+			continue
+		}
+
+		name, err := dataKey.Open(attachment.SealedName, aadAttachmentName)
+		if err != nil {
+			return fmt.Errorf("attachment %s name %w", attachment.ID, err)
+		}
+
+		url, err := dataKey.Open(attachment.SealedURL, aadAttachmentURL)
+		if err != nil {
+			return fmt.Errorf("attachment %s url %w", attachment.ID, err)
+		}
+
+		attachment.Name = string(name)
+		attachment.URL = string(url)
+	}
+
+	return nil
+}
+
+func (svc *encryptionService) Seal(key *domain.WrappedKey, plaintext, aad []byte) ([]byte, error) {
+
+	if key == nil {
+		return plaintext, nil
+	}
+
+	dataKey, err := svc.unwrap(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return dataKey.Seal(plaintext, aad)
+}
+
+func (svc *encryptionService) Open(key *domain.WrappedKey, ciphertext, aad []byte) ([]byte, error) {
+
+	if key == nil {
+		return ciphertext, nil
+	}
+
+	dataKey, err := svc.unwrap(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return dataKey.Open(ciphertext, aad)
+}
+
+func (svc *encryptionService) EncryptStream(key *domain.WrappedKey, r io.Reader) (io.Reader, error) {
+
+	if key == nil {
+		return r, nil
+	}
+
+	dataKey, err := svc.unwrap(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return dataKey.EncryptStream(r), nil
+}
+
+func (svc *encryptionService) DecryptStream(key *domain.WrappedKey, src io.ReadCloser, size int64) (io.ReadCloser, error) {
+
+	if key == nil {
+		return src, nil
+	}
+
+	dataKey, err := svc.unwrap(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return dataKey.DecryptStream(src, size), nil
+}
+
+func (svc *encryptionService) Reencrypt(batchSize int, dryRun bool) (*domain.ReencryptReport, error) {
+
+	if svc.keyring == nil {
+		return nil, errors.New("no master key configured")
+	}
+
+	report := &domain.ReencryptReport{}
+	active := svc.keyring.ActiveKeyID()
+
+	for after := firstID; ; {
+		posts, err := svc.postRepo.ListPostsToEncrypt(active, after, batchSize)
+		if err != nil {
+			return report, err
+		}
+		if len(posts) == 0 {
+			break
+		}
+
+		for _, post := range posts {
+			after = post.ID
+			if err := svc.reencryptPost(post, dryRun, report); err != nil {
+				svc.logger.Warn("post re-encryption failed", "post", post.ID, "error", err)
+				report.Failed++
+			}
+		}
+	}
+
+	for after := firstID; ; {
+		files, err := svc.mediaRepo.ListMediaToEncrypt(active, after, batchSize)
+		if err != nil {
+			return report, err
+		}
+		if len(files) == 0 {
+			break
+		}
+
+		for _, media := range files {
+			after = media.ID
+			if err := svc.reencryptMedia(media, dryRun, report); err != nil {
+				svc.logger.Warn("media re-encryption failed", "media", media.ID, "error", err)
+				report.Failed++
+			}
+		}
+	}
+
+	// chunks of uploads started before encryption stay in plaintext until they expire
+	for after := firstID; ; {
+		uploads, err := svc.uploadRepo.ListUploadsToRewrap(active, after, batchSize)
+		if err != nil {
+			return report, err
+		}
+		if len(uploads) == 0 {
+			break
+		}
+
+		for _, upload := range uploads {
+			after = upload.ID
+			if err := svc.rewrapUpload(upload, dryRun, report); err != nil {
+				svc.logger.Warn("upload key rewrap failed", "upload", upload.ID, "error", err)
+				report.Failed++
+			}
+		}
+	}
+
+	return report, nil
+}
+
+func (svc *encryptionService) reencryptPost(post domain.Post, dryRun bool, report *domain.ReencryptReport) error {
+
+	if post.DataKey != nil {
+		// posts encrypted by an edit may only be listed for their older revisions
+		if post.DataKey.KeyID != svc.keyring.ActiveKeyID() {
+			key, err := svc.rewrap(*post.DataKey)
+			if err != nil {
+				return err
+			}
+
+			if !dryRun {
+				if err := svc.postRepo.RewrapPostKey(post.ID, *key); err != nil {
+					return err
+				}
+			}
+
+			report.PostsRewrapped++
+		}
+
+		return svc.sealRevisions(post, dryRun, report)
+	}
+
+	// plaintext posts are reloaded for their attachments
+	full, err := svc.postRepo.GetPost(post.ID)
+	if err != nil {
+		return err
+	}
+
+	input := domain.PostInput{Body: full.Body}
+	if err := svc.SealPostInput(&input, nil); err != nil {
+		return err
+	}
+
+	full.Body = ""
+	full.SealedBody = input.SealedBody
+	full.DataKey = input.DataKey
+
+	for i := range full.Attachments {
+		if err := svc.SealAttachment(full.DataKey, &full.Attachments[i]); err != nil {
+			return err
+		}
+	}
+
+	if !dryRun {
+		if err := svc.postRepo.SealPost(*full); err != nil {
+			return err
+		}
+	}
+
+	report.PostsSealed++
+
+	return svc.sealRevisions(*full, dryRun, report)
+}
+
+// sealRevisions encrypts the revisions of a post saved while it was stored in plaintext
+// under the data key of the post
+func (svc *encryptionService) sealRevisions(post domain.Post, dryRun bool, report *domain.ReencryptReport) error {
+
+	revisions, err := svc.postRepo.ListPlaintextRevisions(post.ID)
+	if err != nil || len(revisions) == 0 {
+		return err
+	}
+
+	for i := range revisions {
+		sealed, err := svc.Seal(post.DataKey, []byte(revisions[i].Body), aadPostBody)
+		if err != nil {
+			return err
+		}
+		revisions[i].Body = ""
+		revisions[i].SealedBody = sealed
+	}
+
+	if !dryRun {
+		if err := svc.postRepo.SealRevisions(post.ID, revisions); err != nil {
+			return err
+		}
+	}
+
+	report.RevisionsSealed += len(revisions)
+	return nil
+}
+
+func (svc *encryptionService) reencryptMedia(media domain.MediaFile, dryRun bool, report *domain.ReencryptReport) error {
+
+	if media.DataKey != nil {
+		key, err := svc.rewrap(*media.DataKey)
+		if err != nil {
+			return err
+		}
+
+		if !dryRun {
+			if err := svc.mediaRepo.RewrapMediaKey(media.ID, *key); err != nil {
+				return err
+			}
+		}
+
+		report.MediaRewrapped++
+		return nil
+	}
+
+	if dryRun {
+		report.MediaSealed++
+		return nil
+	}
+
+	// the plaintext object is copied to a new key, so readers keep working until the
+	// record points at the encrypted copy
+	file, _, err := svc.store.Get(svc.ctx, media.StorageKey)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	key, err := svc.NewDataKey()
+	if err != nil {
+		return err
+	}
+
+	body, err := svc.EncryptStream(key, file)
+	if err != nil {
+		return err
+	}
+
+	storageKey, err := mediaKey(media.OwnerAddress)
+	if err != nil {
+		return err
+	}
+
+	if err := svc.store.Put(svc.ctx, storageKey, body, -1, media.ContentType); err != nil {
+		svc.discard(storageKey)
+		return err
+	}
+
+	// processed images go back to pending, their variants are rendered again under the
+	// new data key
+	if err := svc.mediaRepo.SealMedia(media.ID, storageKey, *key); err != nil {
+		svc.discard(storageKey)
+		return err
+	}
+
+	svc.discard(media.StorageKey)
+
+	report.MediaSealed++
+	return nil
+}
+
+func (svc *encryptionService) rewrapUpload(upload domain.Upload, dryRun bool, report *domain.ReencryptReport) error {
+
+	key, err := svc.rewrap(*upload.DataKey)
+	if err != nil {
+		return err
+	}
+
+	if !dryRun {
+		if err := svc.uploadRepo.RewrapUploadKey(upload.ID, *key); err != nil {
+			return err
+		}
+	}
+
+	report.UploadsRewrapped++
+	return nil
+}
+
+func (svc *encryptionService) unwrap(key *domain.WrappedKey) (*envelope.DataKey, error) {
+
+	if svc.keyring == nil {
+		return nil, domain.ErrContentSealed
+	}
+
+	dataKey, err := svc.keyring.Unwrap(key.KeyID, key.Key)
+	if errors.Is(err, envelope.ErrUnknownKey) {
+		return nil, fmt.Errorf("%w: %w", domain.ErrContentSealed, err)
+	}
+
+	return dataKey, err
+}
+
+func (svc *encryptionService) rewrap(key domain.WrappedKey) (*domain.WrappedKey, error) {
+
+	wrapped, err := svc.keyring.Rewrap(key.KeyID, key.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.WrappedKey{KeyID: svc.keyring.ActiveKeyID(), Key: wrapped}, nil
+}
+
+func (svc *encryptionService) discard(key string) {
+
+	if err := svc.store.Delete(svc.ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		svc.logger.Warn("media object deletion failed", "key", key, "error", err)
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/repositories"
+	"github.com/Xebec19/jibe/api/pkg/config"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+)
+
+type FeedService interface {
+	// GetFeed returns a page of the viewer's home feed, posts of the creators they follow
+	// and of the communities they belong to. The ranking is picked on the first page,
+	// later pages follow the one their cursor was handed out for
+	GetFeed(viewer domain.Viewer, ranking, cursor string, limit int) (*domain.Page[domain.FeedItem], error)
+}
+
+func NewFeedService(logger logger.Logger, cfg *config.Config, feedRepo repositories.FeedRepository, postService PostService) FeedService {
+
+	return &feedService{
+		logger:      logger,
+		cfg:         cfg,
+		feedRepo:    feedRepo,
+		postService: postService,
+	}
+}
+
+type feedService struct {
+	logger      logger.Logger
+	cfg         *config.Config
+	feedRepo    repositories.FeedRepository
+	postService PostService
+}
+
+func (svc *feedService) GetFeed(viewer domain.Viewer, ranking, cursor string, limit int) (*domain.Page[domain.FeedItem], error) {
+
+	var after domain.FeedCursor
+
+	if cursor != "" {
+		var err error
+		if after, err = domain.DecodeFeedCursor(cursor); err != nil {
+			return nil, err
+		}
+	} else {
+		after.AsOf = time.Now().UTC()
+
+		switch ranking {
+		case "", domain.FeedRankingBoosted:
+			after.BoostSeconds = svc.cfg.FeedEngagementBoost.Seconds()
+		case domain.FeedRankingRecent:
+		default:
+			return nil, domain.ErrFeedRankingInvalid
+		}
+	}
+
+	// one row past the limit tells if there is a next page
+	entries, err := svc.feedRepo.ListFeed(viewer.Address, after, after.AsOf.Add(-svc.cfg.FeedWindow), limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.Page[domain.FeedItem]{}
+	if len(entries) > limit {
+		entries = entries[:limit]
+		last := entries[limit-1]
+		page.NextCursor = domain.FeedCursor{
+			Score:        last.Score,
+			ID:           last.Post.ID,
+			AsOf:         after.AsOf,
+			BoostSeconds: after.BoostSeconds,
+		}.Encode()
+	}
+
+	posts := make([]domain.Post, len(entries))
+	for i, entry := range entries {
+		posts[i] = entry.Post
+	}
+
+	// the whole page goes through gating at once
+	views, err := svc.postService.ViewPosts(viewer, posts)
+	if err != nil {
+		return nil, err
+	}
+
+	page.Items = make([]domain.FeedItem, 0, len(entries))
+	for i, entry := range entries {
+		page.Items = append(page.Items, domain.FeedItem{
+			PostView:      views[i],
+			ReactionCount: entry.ReactionCount,
+			CommentCount:  entry.CommentCount,
+		})
+	}
+
+	return page, nil
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/repositories"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type FollowService interface {
+	// Follow makes addr follow the account behind identifier, an address or a handle.
+	// Following is free, it is refused while either account blocks the other
+	Follow(addr, identifier string) error
+
+	Unfollow(addr, identifier string) error
+
+	// Import follows a list of addresses at once, see FollowImport for what is skipped
+	Import(addr string, addresses []string) (*domain.FollowImport, error)
+
+	// GetStats returns the counts of an account along with how the viewer relates to it
+	GetStats(viewer domain.Viewer, identifier string) (*domain.FollowStats, error)
+
+	ListFollowers(identifier, cursor string, limit int) (*domain.Page[domain.AccountEntry], error)
+
+	ListFollowing(identifier, cursor string, limit int) (*domain.Page[domain.AccountEntry], error)
+
+	// Restrict blocks or mutes an account for addr. Blocked and muted accounts are
+	// filtered from the feed and comments of addr, blocks hide addr from them as well
+	Restrict(addr, identifier, kind string) error
+
+	Unrestrict(addr, identifier, kind string) error
+
+	// ListRestrictions returns the accounts addr blocked or muted, newest first
+	ListRestrictions(addr, kind, cursor string, limit int) (*domain.Page[domain.AccountEntry], error)
+}
+
+func NewFollowService(logger logger.Logger, followRepo repositories.FollowRepository, handleService HandleService) FollowService {
+
+	return &followService{
+		logger:        logger,
+		followRepo:    followRepo,
+		handleService: handleService,
+	}
+}
+
+type followService struct {
+	logger        logger.Logger
+	followRepo    repositories.FollowRepository
+	handleService HandleService
+}
+
+func (svc *followService) Follow(addr, identifier string) error {
+
+	target, err := svc.handleService.ResolveAddress(identifier)
+	if err != nil {
+		return err
+	}
+
+	if target == addr {
+		return domain.ErrFollowSelf
+	}
+
+	return svc.followRepo.Follow(addr, target)
+}
+
+func (svc *followService) Unfollow(addr, identifier string) error {
+
+	target, err := svc.handleService.ResolveAddress(identifier)
+	if err != nil {
+		return err
+	}
+
+	return svc.followRepo.Unfollow(addr, target)
+}
+
+func (svc *followService) Import(addr string, addresses []string) (*domain.FollowImport, error) {
+
+	if len(addresses) == 0 {
+		return nil, domain.ErrFollowImportEmpty
+	}
+	if len(addresses) > domain.FollowImportMax {
+		return nil, domain.ErrFollowImportSize
+	}
+
+	followees := make([]string, 0, len(addresses))
+	seen := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		if !common.IsHexAddress(address) {
+			return nil, domain.ErrFollowImportFormat
+		}
+
+		followee := common.HexToAddress(address).Hex()
+		if seen[followee] {
+			continue
+		}
+		seen[followee] = true
+		followees = append(followees, followee)
+	}
+
+	followed, err := svc.followRepo.Import(addr, followees)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.FollowImport{
+		Followed: followed,
+		Skipped:  len(addresses) - followed,
+	}, nil
+}
+
+func (svc *followService) GetStats(viewer domain.Viewer, identifier string) (*domain.FollowStats, error) {
+
+	target, err := svc.handleService.ResolveAddress(identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := svc.followRepo.GetStats(target)
+	if err != nil {
+		return nil, err
+	}
+
+	if viewer.Address != "" && viewer.Address != target {
+		if stats.Relationship, err = svc.followRepo.GetRelationship(viewer.Address, target); err != nil {
+			return nil, err
+		}
+	}
+
+	return stats, nil
+}
+
+func (svc *followService) ListFollowers(identifier, cursor string, limit int) (*domain.Page[domain.AccountEntry], error) {
+
+	target, err := svc.handleService.ResolveAddress(identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	return accountPage(cursor, limit, func(before domain.Cursor, limit int) ([]domain.AccountEntry, error) {
+		return svc.followRepo.ListFollowers(target, before, limit)
+	})
+}
+
+func (svc *followService) ListFollowing(identifier, cursor string, limit int) (*domain.Page[domain.AccountEntry], error) {
+
+	target, err := svc.handleService.ResolveAddress(identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	return accountPage(cursor, limit, func(before domain.Cursor, limit int) ([]domain.AccountEntry, error) {
+		return svc.followRepo.ListFollowing(target, before, limit)
+	})
+}
+
+func (svc *followService) Restrict(addr, identifier, kind string) error {
+
+	if !domain.IsRestriction(kind) {
+		return domain.ErrRestrictionInvalid
+	}
+
+	target, err := svc.handleService.ResolveAddress(identifier)
+	if err != nil {
+		return err
+	}
+
+	if target == addr {
+		return domain.ErrRestrictionSelf
+	}
+
+	return svc.followRepo.Restrict(addr, target, kind)
+}
+
+func (svc *followService) Unrestrict(addr, identifier, kind string) error {
+
+	if !domain.IsRestriction(kind) {
+		return domain.ErrRestrictionInvalid
+	}
+
+	target, err := svc.handleService.ResolveAddress(identifier)
+	if err != nil {
+		return err
+	}
+
+	return svc.followRepo.Unrestrict(addr, target, kind)
+}
+
+func (svc *followService) ListRestrictions(addr, kind, cursor string, limit int) (*domain.Page[domain.AccountEntry], error) {
+
+	if !domain.IsRestriction(kind) {
+		return nil, domain.ErrRestrictionInvalid
+	}
+
+	return accountPage(cursor, limit, func(before domain.Cursor, limit int) ([]domain.AccountEntry, error) {
+		return svc.followRepo.ListRestrictions(addr, kind, before, limit)
+	})
+}
+
+// accountPage reads a page of an account list with list, one row past the limit tells
+// if there is a next page
+func accountPage(cursor string, limit int, list func(before domain.Cursor, limit int) ([]domain.AccountEntry, error)) (*domain.Page[domain.AccountEntry], error) {
+
+	before, err := domain.DecodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := list(before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.Page[domain.AccountEntry]{}
+	if len(entries) > limit {
+		entries = entries[:limit]
+		last := entries[limit-1]
+		page.NextCursor = domain.Cursor{CreatedAt: last.Since, ID: last.Address}.Encode()
+	}
+	page.Items = entries
+
+	return page, nil
+}
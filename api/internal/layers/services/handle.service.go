@@ -0,0 +1,176 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/repositories"
+	"github.com/Xebec19/jibe/api/pkg/config"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type HandleService interface {
+	// ClaimHandle normalizes the requested handle and assigns it to the address. The
+	// previous handle of the address is retired and keeps redirecting to the new one
+	ClaimHandle(addr, handle string) (*domain.Handle, error)
+
+	// CheckAvailability reports if a handle can be claimed by the address
+	CheckAvailability(addr, handle string) error
+
+	// Resolve accepts an address, a handle or an @handle and returns the account it
+	// points to along with its current handle and share link
+	Resolve(identifier string) (*domain.HandleResolution, error)
+
+	// ResolveAddress is a shortcut of Resolve for routes which only need the address
+	ResolveAddress(identifier string) (string, error)
+
+	// History returns every handle used by the account, newest first
+	History(identifier string) ([]domain.Handle, error)
+}
+
+func NewHandleService(logger logger.Logger, cfg *config.Config, handleRepo repositories.HandleRepository) HandleService {
+
+	return &handleService{
+		logger:     logger,
+		cfg:        cfg,
+		handleRepo: handleRepo,
+	}
+}
+
+type handleService struct {
+	logger     logger.Logger
+	cfg        *config.Config
+	handleRepo repositories.HandleRepository
+}
+
+func (svc *handleService) ClaimHandle(addr, handle string) (*domain.Handle, error) {
+
+	normalized, err := domain.NormalizeHandle(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	skeleton := domain.HandleSkeleton(normalized)
+	releaseBefore := time.Now().Add(-domain.HandleReleaseAfter)
+
+	existing, err := svc.handleRepo.GetBySkeleton(skeleton)
+	switch {
+	case errors.Is(err, domain.ErrHandleNotFound):
+	case err != nil:
+		return nil, err
+	case existing.EthAddress == addr && existing.IsActive():
+		return nil, domain.ErrHandleUnchanged
+	case existing.EthAddress == addr:
+		// owners can take back their own retired handles at any time
+		releaseBefore = time.Now()
+	case existing.IsActive() || existing.RetiredAt.After(releaseBefore):
+		return nil, domain.ErrHandleTaken
+	}
+
+	return svc.handleRepo.ClaimHandle(normalized, skeleton, addr, releaseBefore)
+}
+
+func (svc *handleService) CheckAvailability(addr, handle string) error {
+
+	normalized, err := domain.NormalizeHandle(handle)
+	if err != nil {
+		return err
+	}
+
+	existing, err := svc.handleRepo.GetBySkeleton(domain.HandleSkeleton(normalized))
+	if errors.Is(err, domain.ErrHandleNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if existing.EthAddress == addr {
+		if existing.IsActive() {
+			return domain.ErrHandleUnchanged
+		}
+		return nil
+	}
+
+	if existing.IsActive() || existing.RetiredAt.After(time.Now().Add(-domain.HandleReleaseAfter)) {
+		return domain.ErrHandleTaken
+	}
+
+	return nil
+}
+
+func (svc *handleService) Resolve(identifier string) (*domain.HandleResolution, error) {
+
+	if common.IsHexAddress(identifier) {
+		addr := common.HexToAddress(identifier).Hex()
+
+		res := &domain.HandleResolution{EthAddress: addr}
+
+		current, err := svc.handleRepo.GetActiveByAddress(addr)
+		if err != nil && !errors.Is(err, domain.ErrHandleNotFound) {
+			return nil, err
+		}
+		if current != nil {
+			res.Handle = current.Handle
+		}
+
+		res.ShareURL = domain.ShareURL(svc.cfg.Domain, res.Handle, addr)
+		return res, nil
+	}
+
+	normalized, err := domain.NormalizeHandle(identifier)
+	if err != nil {
+		return nil, domain.ErrHandleNotFound
+	}
+
+	found, err := svc.handleRepo.GetBySkeleton(domain.HandleSkeleton(normalized))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &domain.HandleResolution{
+		EthAddress: found.EthAddress,
+		Handle:     found.Handle,
+	}
+
+	if !found.IsActive() {
+		current, err := svc.handleRepo.GetActiveByAddress(found.EthAddress)
+		if err != nil && !errors.Is(err, domain.ErrHandleNotFound) {
+			return nil, err
+		}
+
+		res.Handle = ""
+		if current != nil {
+			res.Handle = current.Handle
+			res.RedirectTo = current.Handle
+		}
+	} else if found.Handle != normalized {
+		// look-alike of an existing handle, send the caller to the canonical spelling
+		res.RedirectTo = found.Handle
+	}
+
+	res.ShareURL = domain.ShareURL(svc.cfg.Domain, res.Handle, res.EthAddress)
+	return res, nil
+}
+
+func (svc *handleService) ResolveAddress(identifier string) (string, error) {
+
+	res, err := svc.Resolve(identifier)
+	if err != nil {
+		return "", err
+	}
+
+	return res.EthAddress, nil
+}
+
+func (svc *handleService) History(identifier string) ([]domain.Handle, error) {
+
+	addr, err := svc.ResolveAddress(identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.handleRepo.ListByAddress(addr)
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/repositories"
+	"github.com/Xebec19/jibe/api/pkg/gating"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// TokenHistoryService answers historical gating conditions from the transfers stored
+// by the indexer. It refuses to answer for blocks the indexer has not covered so
+// callers deny access instead of trusting partial history
+type TokenHistoryService interface {
+	gating.HistoryReader
+}
+
+func NewTokenHistoryService(logger logger.Logger, indexerRepo repositories.IndexerRepository) TokenHistoryService {
+
+	return &tokenHistoryService{
+		logger:      logger,
+		indexerRepo: indexerRepo,
+	}
+}
+
+type tokenHistoryService struct {
+	logger      logger.Logger
+	indexerRepo repositories.IndexerRepository
+}
+
+func (svc *tokenHistoryService) BalanceAt(ctx context.Context, chainID uint64, token common.Address, tokenID *big.Int, owner common.Address, block uint64) (*big.Int, error) {
+
+	contract, err := svc.contract(chainID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if block > contract.BackfilledTo {
+		return nil, domain.ErrHistoryUnavailable
+	}
+
+	return svc.indexerRepo.HolderBalanceAt(chainID, contract.Address, tokenID, owner.Hex(), block)
+}
+
+func (svc *tokenHistoryService) HeldSince(ctx context.Context, chainID uint64, token common.Address, tokenID *big.Int, owner common.Address, min *big.Int) (time.Time, bool, error) {
+
+	contract, err := svc.contract(chainID, token)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	// a contract still being backfilled has not seen the transfers which put the
+	// tokens in the holder's wallet yet
+	checkpoint, err := svc.indexerRepo.GetCheckpoint(chainID)
+	if errors.Is(err, domain.ErrCheckpointNotFound) || err == nil && contract.BackfilledTo < checkpoint.Number {
+		return time.Time{}, false, domain.ErrHistoryUnavailable
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	transfers, err := svc.indexerRepo.ListHolderTransfers(chainID, contract.Address, tokenID, owner.Hex())
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	holder := owner.Hex()
+	balance := new(big.Int)
+	var since *time.Time
+
+	for _, t := range transfers {
+		before := balance.Cmp(min) >= 0
+
+		if t.To == holder {
+			balance.Add(balance, t.Amount)
+		}
+		if t.From == holder {
+			balance.Sub(balance, t.Amount)
+		}
+
+		after := balance.Cmp(min) >= 0
+		switch {
+		case !before && after:
+			if t.BlockTime == nil {
+				return time.Time{}, false, domain.ErrHistoryUnavailable
+			}
+			since = t.BlockTime
+		case !after:
+			since = nil
+		}
+	}
+
+	if since == nil {
+		return time.Time{}, false, nil
+	}
+
+	return *since, true, nil
+}
+
+func (svc *tokenHistoryService) HoldersAt(ctx context.Context, chainID uint64, token common.Address, tokenID *big.Int, block uint64) (map[common.Address]*big.Int, error) {
+
+	contract, err := svc.contract(chainID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if block > contract.BackfilledTo {
+		return nil, domain.ErrHistoryUnavailable
+	}
+
+	rows, err := svc.indexerRepo.HoldersAt(chainID, contract.Address, tokenID, block)
+	if err != nil {
+		return nil, err
+	}
+
+	holders := make(map[common.Address]*big.Int, len(rows))
+	for addr, balance := range rows {
+		holders[common.HexToAddress(addr)] = balance
+	}
+
+	return holders, nil
+}
+
+// contract returns the indexing progress of the token, tokens the indexer does not
+// follow have no history
+func (svc *tokenHistoryService) contract(chainID uint64, token common.Address) (*domain.IndexedContract, error) {
+
+	contract, err := svc.indexerRepo.GetContract(chainID, token.Hex())
+	if errors.Is(err, domain.ErrIndexedContractNotFound) {
+		return nil, domain.ErrHistoryUnavailable
+	}
+
+	return contract, err
+}
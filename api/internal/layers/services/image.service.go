@@ -0,0 +1,283 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/repositories"
+	"github.com/Xebec19/jibe/api/pkg/config"
+	"github.com/Xebec19/jibe/api/pkg/imaging"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/Xebec19/jibe/api/pkg/storage"
+)
+
+const (
+	// imageMaxAttempts is how often an image is tried before it is failed for good
+	imageMaxAttempts = 3
+
+	// imageClaimTimeout is after how long a claim is considered abandoned by a worker
+	// which crashed while processing
+	imageClaimTimeout = 10 * time.Minute
+
+	// imageMaxBytes caps the originals read into memory for processing
+	imageMaxBytes = 64 * 1024 * 1024
+
+	variantQuality = 82
+	previewQuality = 60
+)
+
+// errUnprocessable marks files which fail the same way on every attempt
+var errUnprocessable = errors.New("media can not be processed")
+
+// imageTypes are the content types claimed by the image pipeline
+var imageTypes = slices.Sorted(maps.Keys(domain.ProcessedImageTypes))
+
+type ImageService interface {
+	// ProcessPending claims up to limit pending images and processes them one by one.
+	// It returns how many were claimed
+	ProcessPending(limit int) (int, error)
+}
+
+// NewImageService strips the metadata of uploaded images and renders their resized
+// variants, blurred preview and BlurHash
+func NewImageService(ctx context.Context, logger logger.Logger, cfg *config.Config, store storage.Storage, mediaRepo repositories.MediaRepository, encryption EncryptionService) ImageService {
+
+	return &imageService{
+		ctx:          ctx,
+		logger:       logger,
+		store:        store,
+		mediaRepo:    mediaRepo,
+		encryption:   encryption,
+		widths:       cfg.ImageVariantWidths,
+		previewWidth: cfg.ImagePreviewWidth,
+		maxPixels:    cfg.ImageMaxPixels,
+	}
+}
+
+type imageService struct {
+	ctx          context.Context
+	logger       logger.Logger
+	store        storage.Storage
+	mediaRepo    repositories.MediaRepository
+	encryption   EncryptionService
+	widths       []int
+	previewWidth int
+	maxPixels    int
+}
+
+func (svc *imageService) ProcessPending(limit int) (int, error) {
+
+	staleBefore := time.Now().Add(-imageClaimTimeout)
+
+	failed, err := svc.mediaRepo.FailStaleProcessing(imageTypes, staleBefore, imageMaxAttempts)
+	if err != nil {
+		return 0, err
+	}
+	if failed > 0 {
+		svc.logger.Warn("Abandoned image processing failed for good", "count", failed)
+	}
+
+	files, err := svc.mediaRepo.ClaimMediaToProcess(imageTypes, staleBefore, imageMaxAttempts, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, media := range files {
+		if err := svc.process(media); err != nil {
+			svc.logger.Warn("Image processing failed", "media", media.ID, "attempt", media.ProcessingAttempts, "error", err)
+
+			// broken or oversized images are not retried
+			attempts := imageMaxAttempts
+			if errors.Is(err, errUnprocessable) {
+				attempts = 0
+			}
+
+			if err := svc.mediaRepo.FailProcessing(media.ID, attempts); err != nil {
+				return len(files), err
+			}
+		}
+	}
+
+	return len(files), nil
+}
+
+// process replaces the original by a copy without metadata and stores the variants.
+// New objects are only referenced once the record is updated, they are removed again
+// on any failure before
+func (svc *imageService) process(media domain.MediaFile) error {
+
+	if media.SizeBytes > imageMaxBytes {
+		return fmt.Errorf("%w: %d bytes exceed the limit", errUnprocessable, media.SizeBytes)
+	}
+
+	original, err := svc.read(media)
+	if err != nil {
+		return err
+	}
+
+	stripped, err := imaging.StripMetadata(original)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errUnprocessable, err)
+	}
+
+	img, err := imaging.Decode(stripped, svc.maxPixels)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errUnprocessable, err)
+	}
+
+	blurHash, err := imaging.BlurHash(img)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errUnprocessable, err)
+	}
+
+	bounds := img.Bounds()
+	result := domain.ProcessedImage{
+		StorageKey: media.StorageKey,
+		SizeBytes:  media.SizeBytes,
+		SHA256:     media.SHA256,
+		Width:      bounds.Dx(),
+		Height:     bounds.Dy(),
+		BlurHash:   blurHash,
+	}
+
+	var created []string
+	discardCreated := func() {
+		for _, key := range created {
+			svc.discard(key)
+		}
+	}
+
+	if !bytes.Equal(stripped, original) {
+		key, err := svc.write(media, stripped, media.ContentType)
+		if err != nil {
+			return err
+		}
+		created = append(created, key)
+
+		digest := sha256.Sum256(stripped)
+		result.StorageKey = key
+		result.SizeBytes = int64(len(stripped))
+		result.SHA256 = hex.EncodeToString(digest[:])
+	}
+
+	for _, width := range svc.widths {
+		if width >= bounds.Dx() {
+			break
+		}
+
+		variant, err := svc.variant(media, fmt.Sprintf("w%d", width), imaging.Resize(img, width), variantQuality)
+		if err != nil {
+			discardCreated()
+			return err
+		}
+		created = append(created, variant.StorageKey)
+		result.Variants = append(result.Variants, *variant)
+	}
+
+	preview, err := svc.variant(media, domain.VariantPreview, imaging.Blur(img, svc.previewWidth), previewQuality)
+	if err != nil {
+		discardCreated()
+		return err
+	}
+	created = append(created, preview.StorageKey)
+	result.Variants = append(result.Variants, *preview)
+
+	replaced, err := svc.mediaRepo.FinishProcessing(media.ID, media.StorageKey, result)
+	if errors.Is(err, domain.ErrMediaNotFound) {
+		// deleted or re-encrypted meanwhile, a new claim processes the current object
+		discardCreated()
+		return nil
+	}
+	if err != nil {
+		discardCreated()
+		return err
+	}
+
+	if result.StorageKey != media.StorageKey {
+		svc.discard(media.StorageKey)
+	}
+	for _, key := range replaced {
+		svc.discard(key)
+	}
+
+	return nil
+}
+
+func (svc *imageService) variant(media domain.MediaFile, name string, img image.Image, quality int) (*domain.MediaVariant, error) {
+
+	data, contentType, err := imaging.Encode(img, quality)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := svc.write(media, data, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	return &domain.MediaVariant{
+		Name:        name,
+		StorageKey:  key,
+		ContentType: contentType,
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+		SizeBytes:   int64(len(data)),
+	}, nil
+}
+
+// read loads and decrypts the original of a media file
+func (svc *imageService) read(media domain.MediaFile) ([]byte, error) {
+
+	file, _, err := svc.store.Get(svc.ctx, media.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := svc.encryption.DecryptStream(media.DataKey, file, media.SizeBytes)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	defer content.Close()
+
+	return io.ReadAll(content)
+}
+
+// write stores data as a new object encrypted under the data key of the media file
+func (svc *imageService) write(media domain.MediaFile, data []byte, contentType string) (string, error) {
+
+	key, err := mediaKey(media.OwnerAddress)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := svc.encryption.EncryptStream(media.DataKey, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+
+	if err := svc.store.Put(svc.ctx, key, sealed, -1, contentType); err != nil {
+		svc.discard(key)
+		return "", err
+	}
+
+	return key, nil
+}
+
+func (svc *imageService) discard(key string) {
+
+	if err := svc.store.Delete(svc.ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		svc.logger.Warn("media object deletion failed", "key", key, "error", err)
+	}
+}
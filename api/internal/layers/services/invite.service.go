@@ -0,0 +1,316 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/repositories"
+	"github.com/Xebec19/jibe/api/pkg/config"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+)
+
+// inviteCodeBytes and inviteSigBytes size the two halves of an invite token
+const (
+	inviteCodeBytes = 9
+	inviteSigBytes  = 12
+)
+
+type InviteService interface {
+	// CreateInvite issues a link into a community. Any member can create referral links,
+	// moderators and up can invite into invite only communities, links of admins admit
+	// regardless of the join policy
+	CreateInvite(actor, idOrSlug string, input domain.InviteInput) (*domain.CommunityInvite, error)
+
+	// ListInvites returns the invites of a community along with their stats. Admins see
+	// every invite, other members their own
+	ListInvites(actor, idOrSlug string, limit, offset int) ([]domain.CommunityInvite, error)
+
+	// RevokeInvite stops a link from working, allowed for its inviter and admins
+	RevokeInvite(actor, idOrSlug, inviteID string) error
+
+	// Preview resolves a link for its recipient and counts the click
+	Preview(token string) (*domain.InvitePreview, error)
+
+	// Redeem joins the viewer to the community of a link
+	Redeem(viewer domain.Viewer, token, paymentTx string) (*domain.CommunityMember, error)
+
+	// RedeemOnSignIn counts a sign-in through a link and redeems it for the account which
+	// signed in. Failures are reported in the result rather than returned, a sign-in
+	// never fails on its invite
+	RedeemOnSignIn(viewer domain.Viewer, token string) domain.InviteRedemption
+}
+
+func NewInviteService(logger logger.Logger, cfg *config.Config, inviteRepo repositories.InviteRepository, communityRepo repositories.CommunityRepository, handleRepo repositories.HandleRepository, communityService CommunityService, accessService AccessService) InviteService {
+
+	return &inviteService{
+		logger:           logger,
+		cfg:              cfg,
+		secret:           []byte(cfg.LinkSigningSecret),
+		inviteRepo:       inviteRepo,
+		communityRepo:    communityRepo,
+		handleRepo:       handleRepo,
+		communityService: communityService,
+		accessService:    accessService,
+	}
+}
+
+type inviteService struct {
+	logger           logger.Logger
+	cfg              *config.Config
+	secret           []byte
+	inviteRepo       repositories.InviteRepository
+	communityRepo    repositories.CommunityRepository
+	handleRepo       repositories.HandleRepository
+	communityService CommunityService
+	accessService    AccessService
+}
+
+func (svc *inviteService) CreateInvite(actor, idOrSlug string, input domain.InviteInput) (*domain.CommunityInvite, error) {
+
+	community, member, err := svc.membership(actor, idOrSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	// a referral into an invite only community would admit nobody
+	if community.JoinPolicy == domain.JoinPolicyInviteOnly && !domain.RoleOutranks(member.Role, domain.RoleMember) {
+		return nil, domain.ErrCommunityForbidden
+	}
+
+	if input.MaxUses != nil && *input.MaxUses <= 0 {
+		return nil, fmt.Errorf("%w: max_uses must be positive", domain.ErrCommunityInvalid)
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", domain.ErrCommunityInvalid)
+	}
+
+	if isPublicPolicy(input.AccessPolicy) {
+		input.AccessPolicy = nil
+	} else if err := svc.accessService.ValidatePolicy(input.AccessPolicy); err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, inviteCodeBytes)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	invite, err := svc.inviteRepo.CreateInvite(community.ID, actor, base64.RawURLEncoding.EncodeToString(b), input)
+	if err != nil {
+		return nil, err
+	}
+
+	invite.URL = svc.url(invite.Code)
+	return invite, nil
+}
+
+func (svc *inviteService) ListInvites(actor, idOrSlug string, limit, offset int) ([]domain.CommunityInvite, error) {
+
+	community, member, err := svc.membership(actor, idOrSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	inviter := actor
+	if domain.RoleOutranks(member.Role, domain.RoleModerator) {
+		inviter = ""
+	}
+
+	invites, err := svc.inviteRepo.ListInvites(community.ID, inviter, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range invites {
+		invites[i].URL = svc.url(invites[i].Code)
+	}
+
+	return invites, nil
+}
+
+func (svc *inviteService) RevokeInvite(actor, idOrSlug, inviteID string) error {
+
+	community, member, err := svc.membership(actor, idOrSlug)
+	if err != nil {
+		return err
+	}
+
+	invite, err := svc.inviteRepo.GetInvite(inviteID)
+	if err != nil {
+		return err
+	}
+	if invite.CommunityID != community.ID {
+		return domain.ErrInviteNotFound
+	}
+
+	if invite.InviterAddress != actor && !domain.RoleOutranks(member.Role, domain.RoleModerator) {
+		return domain.ErrCommunityForbidden
+	}
+
+	return svc.inviteRepo.RevokeInvite(invite.ID)
+}
+
+func (svc *inviteService) Preview(token string) (*domain.InvitePreview, error) {
+
+	invite, err := svc.resolve(token)
+	if err != nil {
+		return nil, err
+	}
+
+	community, err := svc.communityRepo.GetCommunity(invite.CommunityID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := svc.inviteRepo.CountClick(invite.ID); err != nil {
+		svc.logger.Error("counting invite click failed", "invite", invite.ID, "error", err)
+	}
+
+	preview := domain.InvitePreview{
+		Community:      *community,
+		InviterAddress: invite.InviterAddress,
+		ExpiresAt:      invite.ExpiresAt,
+		Usable:         invite.IsUsable(time.Now()),
+	}
+
+	if invite.MaxUses != nil {
+		remaining := max(*invite.MaxUses-invite.Uses, 0)
+		preview.RemainingUses = &remaining
+	}
+
+	// links stop working once their inviter leaves
+	inviter, err := svc.communityRepo.GetMember(community.ID, invite.InviterAddress)
+	switch {
+	case errors.Is(err, domain.ErrMemberNotFound):
+		preview.Usable = false
+	case err != nil:
+		return nil, err
+	default:
+		preview.Admits = domain.RoleOutranks(inviter.Role, domain.RoleModerator)
+	}
+
+	handle, err := svc.handleRepo.GetActiveByAddress(invite.InviterAddress)
+	if err != nil && !errors.Is(err, domain.ErrHandleNotFound) {
+		return nil, err
+	}
+	if handle != nil {
+		preview.InviterHandle = handle.Handle
+	}
+
+	return &preview, nil
+}
+
+func (svc *inviteService) Redeem(viewer domain.Viewer, token, paymentTx string) (*domain.CommunityMember, error) {
+
+	invite, err := svc.resolve(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.redeem(viewer, invite, paymentTx)
+}
+
+func (svc *inviteService) RedeemOnSignIn(viewer domain.Viewer, token string) domain.InviteRedemption {
+
+	invite, err := svc.resolve(token)
+	if err != nil {
+		return domain.InviteRedemption{Error: err.Error()}
+	}
+
+	if err := svc.inviteRepo.CountSignIn(invite.ID); err != nil {
+		svc.logger.Error("counting invite sign-in failed", "invite", invite.ID, "error", err)
+	}
+
+	// paid communities are joined once the payment is made, through the redeem route
+	member, err := svc.redeem(viewer, invite, "")
+	if err != nil {
+		if !isInviteError(err) {
+			svc.logger.Error("invite redemption on sign-in failed", "invite", invite.ID, "error", err)
+			return domain.InviteRedemption{Error: domain.ErrInviteExpired.Error()}
+		}
+		return domain.InviteRedemption{Error: err.Error()}
+	}
+
+	return domain.InviteRedemption{Member: member}
+}
+
+func (svc *inviteService) redeem(viewer domain.Viewer, invite *domain.CommunityInvite, paymentTx string) (*domain.CommunityMember, error) {
+
+	if viewer.Address == "" {
+		return nil, domain.ErrCommunityForbidden
+	}
+
+	if !invite.IsUsable(time.Now()) {
+		return nil, domain.ErrInviteExpired
+	}
+
+	return svc.communityService.JoinWithInvite(viewer, *invite, paymentTx)
+}
+
+// membership loads a community along with the membership of addr, non members are
+// forbidden
+func (svc *inviteService) membership(addr, idOrSlug string) (*domain.Community, *domain.CommunityMember, error) {
+
+	community, err := svc.communityRepo.GetCommunity(idOrSlug)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	member, err := svc.communityRepo.GetMember(community.ID, addr)
+	if errors.Is(err, domain.ErrMemberNotFound) {
+		return nil, nil, domain.ErrCommunityForbidden
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return community, member, nil
+}
+
+// resolve checks the signature of a token and loads the invite it names
+func (svc *inviteService) resolve(token string) (*domain.CommunityInvite, error) {
+
+	code, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(svc.sign(code))) {
+		return nil, domain.ErrInviteNotFound
+	}
+
+	return svc.inviteRepo.GetInviteByCode(code)
+}
+
+// url is the shareable link of an invite, its token is the code and a signature so
+// codes can not be guessed into existence
+func (svc *inviteService) url(code string) string {
+	return "https://" + svc.cfg.Domain + "/invite/" + code + "." + svc.sign(code)
+}
+
+func (svc *inviteService) sign(code string) string {
+
+	mac := hmac.New(sha256.New, svc.secret)
+	mac.Write([]byte("invite\n" + code))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:inviteSigBytes])
+}
+
+// isInviteError reports if err is an outcome of redeeming worth showing to the user
+func isInviteError(err error) bool {
+
+	for _, target := range []error{
+		domain.ErrInviteNotFound, domain.ErrInviteExpired, domain.ErrInviteOnly,
+		domain.ErrAlreadyMember, domain.ErrJoinDenied, domain.ErrPaymentInvalid,
+		domain.ErrPaymentNotFound, domain.ErrCommunityNotFound, domain.ErrInvalidPolicy,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,529 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/repositories"
+	"github.com/Xebec19/jibe/api/pkg/config"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/Xebec19/jibe/api/pkg/storage"
+	"github.com/Xebec19/jibe/api/pkg/transcode"
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// sniffLen is how much of an upload is read to detect its content type
+const sniffLen = 3072
+
+// MediaHandler is called with every stored file
+type MediaHandler func(media domain.MediaFile)
+
+type MediaService interface {
+	// Upload streams a file of the owner into storage. The content type is sniffed
+	// from the first bytes and must be one of domain.AllowedMediaTypes
+	Upload(owner string, upload domain.MediaUpload, r io.Reader) (*domain.MediaFile, error)
+
+	// Import stores a file assembled by a resumable upload. Its size was checked and
+	// reserved against the quota when the upload was created
+	Import(owner string, upload domain.MediaUpload, r io.Reader) (*domain.MediaFile, error)
+
+	GetMedia(viewer domain.Viewer, id string) (*domain.MediaFile, error)
+
+	// SignURL issues a download url of the file, or of its named variant, for the viewer
+	// which expires after the configured ttl. Files linked to a gated post are only
+	// signed for viewers passing its policy, except for the blurred preview
+	SignURL(viewer domain.Viewer, id, variant string) (*domain.SignedURL, error)
+
+	// Open verifies a signed url for the address requesting it and opens the file or
+	// variant. The returned file describes what is served
+	Open(addr, id, variant, expires, signature string) (io.ReadCloser, *domain.MediaFile, error)
+
+	// PostImages lists the processed images of a post the viewer was shown. Only the
+	// blurred previews are signed when the post is locked for the viewer
+	PostImages(viewer domain.Viewer, post *domain.PostView) ([]domain.PostImage, error)
+
+	DeleteMedia(owner, id string) error
+
+	// Subscribe registers a handler called after a file was stored
+	Subscribe(handler MediaHandler)
+}
+
+func NewMediaService(ctx context.Context, logger logger.Logger, cfg *config.Config, store storage.Storage, mediaRepo repositories.MediaRepository, postRepo repositories.PostRepository, postService PostService, encryption EncryptionService) MediaService {
+
+	return &mediaService{
+		ctx:         ctx,
+		logger:      logger,
+		store:       store,
+		mediaRepo:   mediaRepo,
+		postRepo:    postRepo,
+		postService: postService,
+		encryption:  encryption,
+		maxSize:     cfg.MediaMaxUploadSize,
+		quota:       cfg.StorageQuota,
+		signer:      newURLSigner(cfg, cfg.MediaURLTTL),
+		streaming:   cfg.Transcoder != transcode.DriverNone,
+	}
+}
+
+type mediaService struct {
+	ctx         context.Context
+	logger      logger.Logger
+	store       storage.Storage
+	mediaRepo   repositories.MediaRepository
+	postRepo    repositories.PostRepository
+	postService PostService
+	encryption  EncryptionService
+	maxSize     int64
+	quota       int64
+	signer      urlSigner
+	streaming   bool
+
+	mu       sync.RWMutex
+	handlers []MediaHandler
+}
+
+func (svc *mediaService) Upload(owner string, upload domain.MediaUpload, r io.Reader) (*domain.MediaFile, error) {
+
+	limit := svc.maxSize
+	if svc.quota > 0 {
+		used, err := svc.mediaRepo.StorageUsage(owner)
+		if err != nil {
+			return nil, err
+		}
+		if used >= svc.quota {
+			return nil, domain.ErrStorageQuotaExceeded
+		}
+		limit = min(limit, svc.quota-used)
+	}
+
+	media, err := svc.save(owner, upload, r, limit, svc.quota)
+	if errors.Is(err, domain.ErrMediaTooLarge) && limit < svc.maxSize {
+		return nil, domain.ErrStorageQuotaExceeded
+	}
+
+	return media, err
+}
+
+func (svc *mediaService) Import(owner string, upload domain.MediaUpload, r io.Reader) (*domain.MediaFile, error) {
+	return svc.save(owner, upload, r, 0, 0)
+}
+
+// save streams the file into storage and records it, limit caps its size when positive.
+// Files are only recorded when they fit quota, the free space checked before streaming
+// may have been taken by concurrent uploads meanwhile
+func (svc *mediaService) save(owner string, upload domain.MediaUpload, r io.Reader, limit, quota int64) (*domain.MediaFile, error) {
+
+	var postID *string
+	if upload.PostID != "" {
+		post, err := svc.postRepo.GetPost(upload.PostID)
+		if err != nil {
+			return nil, err
+		}
+		if post.AuthorAddress != owner {
+			return nil, domain.ErrPostForbidden
+		}
+		postID = &post.ID
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, domain.ErrMediaEmpty
+	}
+	head = head[:n]
+
+	contentType, _, _ := strings.Cut(mimetype.Detect(head).String(), ";")
+	if !domain.AllowedMediaTypes[contentType] {
+		return nil, fmt.Errorf("%w: %s", domain.ErrUnsupportedMediaType, contentType)
+	}
+
+	body := &countingReader{
+		r:     io.MultiReader(bytes.NewReader(head), r),
+		hash:  sha256.New(),
+		limit: limit,
+	}
+
+	key, err := mediaKey(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := svc.encryption.NewDataKey()
+	if err != nil {
+		return nil, err
+	}
+
+	// size and digest are taken of the plaintext, before encryption
+	sealed, err := svc.encryption.EncryptStream(dataKey, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := svc.store.Put(svc.ctx, key, sealed, -1, contentType); err != nil {
+		svc.discard(key)
+		if body.exceeded {
+			return nil, domain.ErrMediaTooLarge
+		}
+		return nil, fmt.Errorf("media storage failed %w", err)
+	}
+
+	// images are held back until the image pipeline stripped their metadata, videos
+	// are queued for HLS packaging
+	status := domain.MediaProcessingNone
+	if domain.ProcessedImageTypes[contentType] || (svc.streaming && domain.StreamedVideoTypes[contentType]) {
+		status = domain.MediaProcessingPending
+	}
+
+	media, err := svc.mediaRepo.CreateMedia(domain.MediaFile{
+		ProcessingStatus: status,
+		OwnerAddress:     owner,
+		PostID:           postID,
+		StorageKey:       key,
+		Filename:         cleanFilename(upload.Filename),
+		ContentType:      contentType,
+		SizeBytes:        body.n,
+		SHA256:           hex.EncodeToString(body.hash.Sum(nil)),
+		DataKey:          dataKey,
+	}, quota)
+	if err != nil {
+		svc.discard(key)
+		return nil, err
+	}
+
+	svc.mu.RLock()
+	for _, handler := range svc.handlers {
+		handler(*media)
+	}
+	svc.mu.RUnlock()
+
+	return media, nil
+}
+
+func (svc *mediaService) GetMedia(viewer domain.Viewer, id string) (*domain.MediaFile, error) {
+
+	media, err := svc.mediaRepo.GetMedia(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := svc.authorize(viewer, media, ""); err != nil {
+		return nil, err
+	}
+
+	if domain.StreamedVideoTypes[media.ContentType] && media.ProcessingStatus == domain.MediaProcessingReady {
+		media.StreamURL = "/v1/media/" + media.ID + "/hls/master.m3u8"
+	}
+
+	return media, nil
+}
+
+func (svc *mediaService) SignURL(viewer domain.Viewer, id, variant string) (*domain.SignedURL, error) {
+
+	media, err := svc.mediaRepo.GetMedia(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := svc.authorize(viewer, media, variant); err != nil {
+		return nil, err
+	}
+
+	if variant != "" {
+		if _, ok := media.Variant(variant); !ok {
+			return nil, domain.ErrVariantNotFound
+		}
+	} else if !media.IsServable() && media.OwnerAddress != viewer.Address {
+		// originals may still carry EXIF and GPS metadata until they were processed
+		return nil, domain.ErrMediaProcessing
+	}
+
+	expiresAt := svc.signer.expiry()
+	return &domain.SignedURL{
+		URL:       svc.signedURL(media.ID, variant, viewer.Address, expiresAt),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (svc *mediaService) Open(addr, id, variant, expires, signature string) (io.ReadCloser, *domain.MediaFile, error) {
+
+	if err := svc.signer.verify(subject(id, variant), addr, expires, signature); err != nil {
+		return nil, nil, err
+	}
+
+	media, err := svc.mediaRepo.GetMedia(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	served := *media
+	if variant != "" {
+		v, ok := media.Variant(variant)
+		if !ok {
+			return nil, nil, domain.ErrVariantNotFound
+		}
+
+		served.StorageKey = v.StorageKey
+		served.ContentType = v.ContentType
+		served.SizeBytes = v.SizeBytes
+		served.Filename = variantFilename(media.Filename, v)
+	}
+
+	file, _, err := svc.store.Get(svc.ctx, served.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, domain.ErrMediaNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, err := svc.encryption.DecryptStream(media.DataKey, file, served.SizeBytes)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	return content, &served, nil
+}
+
+func (svc *mediaService) PostImages(viewer domain.Viewer, post *domain.PostView) ([]domain.PostImage, error) {
+
+	files, err := svc.mediaRepo.ListPostMedia(post.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := svc.signer.expiry()
+	images := []domain.PostImage{}
+
+	for _, media := range files {
+		if media.ProcessingStatus != domain.MediaProcessingReady {
+			continue
+		}
+
+		image := domain.PostImage{
+			MediaID:   media.ID,
+			Width:     media.Width,
+			Height:    media.Height,
+			BlurHash:  media.BlurHash,
+			ExpiresAt: expiresAt,
+		}
+
+		if _, ok := media.Variant(domain.VariantPreview); ok {
+			image.PreviewURL = svc.signedURL(media.ID, domain.VariantPreview, viewer.Address, expiresAt)
+		}
+
+		if !post.Locked {
+			image.URL = svc.signedURL(media.ID, "", viewer.Address, expiresAt)
+			image.VariantURLs = make(map[string]string, len(media.Variants))
+
+			for _, variant := range media.Variants {
+				if variant.Name != domain.VariantPreview {
+					image.VariantURLs[variant.Name] = svc.signedURL(media.ID, variant.Name, viewer.Address, expiresAt)
+				}
+			}
+		}
+
+		images = append(images, image)
+	}
+
+	return images, nil
+}
+
+func (svc *mediaService) DeleteMedia(owner, id string) error {
+
+	media, err := svc.mediaRepo.GetMedia(id)
+	if err != nil {
+		return err
+	}
+
+	if media.OwnerAddress != owner {
+		return domain.ErrMediaForbidden
+	}
+
+	// the stream rows go with the file, their segment objects are removed after it
+	var stream *domain.MediaStream
+	if domain.StreamedVideoTypes[media.ContentType] {
+		stream, err = svc.mediaRepo.GetStream(id)
+		if err != nil && !errors.Is(err, domain.ErrStreamNotFound) {
+			return err
+		}
+	}
+
+	if err := svc.mediaRepo.DeleteMedia(owner, id); err != nil {
+		return err
+	}
+
+	svc.discard(media.StorageKey)
+	for _, variant := range media.Variants {
+		svc.discard(variant.StorageKey)
+	}
+	if stream != nil {
+		for _, key := range streamKeys(stream) {
+			svc.discard(key)
+		}
+	}
+
+	return nil
+}
+
+func (svc *mediaService) Subscribe(handler MediaHandler) {
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.handlers = append(svc.handlers, handler)
+}
+
+// authorize lets the owner see any of their files, and others only files linked to a
+// post they can read. The blurred preview is shown to readers the post is locked for
+func (svc *mediaService) authorize(viewer domain.Viewer, media *domain.MediaFile, variant string) error {
+
+	if media.OwnerAddress == viewer.Address {
+		return nil
+	}
+
+	if media.PostID == nil {
+		return domain.ErrMediaNotFound
+	}
+
+	post, err := svc.postService.GetPost(viewer, *media.PostID)
+	if errors.Is(err, domain.ErrPostNotFound) {
+		return domain.ErrMediaNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if post.Locked && variant != domain.VariantPreview {
+		return fmt.Errorf("%w: %s", domain.ErrMediaLocked, post.LockReason)
+	}
+
+	return nil
+}
+
+// signedURL builds the download url of a file or variant for addr
+func (svc *mediaService) signedURL(id, variant, addr string, expiresAt time.Time) string {
+
+	query := svc.signer.query(subject(id, variant), addr, expiresAt)
+	if variant != "" {
+		query.Set("variant", variant)
+	}
+
+	return "/v1/media/" + id + "/download?" + query.Encode()
+}
+
+// subject names what a url downloads, the original keeps the bare id
+func subject(id, variant string) string {
+
+	if variant == "" {
+		return id
+	}
+
+	return id + "/" + variant
+}
+
+// variantFilename names a variant after its original, "photo.png" becomes
+// "photo-w640.jpg" when the variant was encoded as jpeg
+func variantFilename(filename string, variant *domain.MediaVariant) string {
+
+	base := strings.TrimSuffix(filename, path.Ext(filename))
+
+	ext := ".jpg"
+	if variant.ContentType == "image/png" {
+		ext = ".png"
+	}
+
+	return base + "-" + variant.Name + ext
+}
+
+// discard removes an object which is not, or no longer, referenced by a record
+func (svc *mediaService) discard(key string) {
+
+	if err := svc.store.Delete(svc.ctx, key); err != nil {
+		svc.logger.Warn("media object deletion failed", "key", key, "error", err)
+	}
+}
+
+func mediaKey(owner string) (string, error) {
+
+	name, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+
+	return "media/" + strings.ToLower(owner) + "/" + name, nil
+}
+
+func randomHex(n int) (string, error) {
+
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// cleanFilename keeps the base name of a client supplied filename, it is only shown
+// back in Content-Disposition
+func cleanFilename(name string) string {
+
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(name))
+
+	if len(name) > 255 {
+		name = strings.ToValidUTF8(name[:255], "")
+	}
+	if name == "" {
+		name = "file"
+	}
+
+	return name
+}
+
+// countingReader hashes and counts what passes through it and fails once more than
+// limit bytes were read
+type countingReader struct {
+	r        io.Reader
+	hash     hash.Hash
+	n        int64
+	limit    int64
+	exceeded bool
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	c.hash.Write(p[:n])
+
+	if c.limit > 0 && c.n > c.limit {
+		c.exceeded = true
+		return n, domain.ErrMediaTooLarge
+	}
+
+	return n, err
+}
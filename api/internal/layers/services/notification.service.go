@@ -0,0 +1,504 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/repositories"
+	"github.com/Xebec19/jibe/api/pkg/eventbus"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/Xebec19/jibe/api/pkg/pgnotify"
+)
+
+const (
+	// notificationExcerptLength caps the part of a comment quoted in its notifications
+	notificationExcerptLength = 140
+
+	// notificationAnnounceBatch is how many recipients one announcement names, larger
+	// fan-outs wake every stream instead
+	notificationAnnounceBatch = 150
+)
+
+// NotificationStream follows the notifications of an account. Wake fires when new
+// ones may be stored, Next reads them
+type NotificationStream struct {
+	Address string
+
+	mu     sync.Mutex
+	lastID int64
+	wake   chan struct{}
+}
+
+func (s *NotificationStream) Wake() <-chan struct{} {
+	return s.wake
+}
+
+// LastID is the id of the last notification read from the stream
+func (s *NotificationStream) LastID() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lastID
+}
+
+func (s *NotificationStream) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+type NotificationService interface {
+	// ListNotifications returns a page of the viewer's notifications, newest first
+	ListNotifications(addr, cursor string, unreadOnly bool, limit int) (*domain.Page[domain.Notification], error)
+
+	CountUnread(addr string) (int, error)
+
+	MarkRead(addr string, id int64) (*domain.Notification, error)
+
+	MarkUnread(addr string, id int64) (*domain.Notification, error)
+
+	// MarkAllRead marks every notification up to upToID as read, 0 marks them all
+	MarkAllRead(addr string, upToID int64) (int64, error)
+
+	// Subscribe opens a stream of the account's notifications. It starts after
+	// lastEventID when one is given so clients resume where they left off, otherwise
+	// only notifications stored from now on are streamed
+	Subscribe(addr, lastEventID string) (*NotificationStream, error)
+
+	// Next reads up to limit notifications of a stream stored since the last read
+	Next(stream *NotificationStream, limit int) ([]domain.Notification, error)
+
+	Unsubscribe(stream *NotificationStream)
+
+	// Dispatch wakes the local streams of the recipients named in an announcement of
+	// any replica, it is fed by the notification listener
+	Dispatch(payload string)
+
+	// Resync wakes every local stream after the notification listener reconnected
+	Resync()
+
+	// GetPreferences returns which kinds of notifications every channel delivers to the
+	// account
+	GetPreferences(addr string) (domain.NotificationPreferences, error)
+
+	// UpdatePreferences switches the given channels and kinds, the others keep their
+	// setting
+	UpdatePreferences(addr string, preferences domain.NotificationPreferences) (domain.NotificationPreferences, error)
+
+	// ReleaseDrips notifies up to limit members of dripped posts which unlocked for
+	// them, it returns how many were notified
+	ReleaseDrips(limit int) (int, error)
+
+	// the handlers below turn domain events of the event bus into notifications
+
+	OnPostPublished(event domain.PostPublished)
+
+	OnCommentCreated(event domain.CommentCreated)
+
+	OnPaymentReceived(event domain.PaymentReceived)
+
+	OnMembershipExpiring(event domain.MembershipExpiring)
+}
+
+func NewNotificationService(logger logger.Logger, notificationRepo repositories.NotificationRepository, postService PostService, bus *eventbus.Bus) NotificationService {
+
+	return &notificationService{
+		logger:           logger,
+		notificationRepo: notificationRepo,
+		postService:      postService,
+		bus:              bus,
+		streams:          make(map[string]map[*NotificationStream]struct{}),
+	}
+}
+
+type notificationService struct {
+	logger           logger.Logger
+	notificationRepo repositories.NotificationRepository
+	postService      PostService
+	bus              *eventbus.Bus
+
+	// streams holds the streams open on this replica by address
+	mu      sync.RWMutex
+	streams map[string]map[*NotificationStream]struct{}
+}
+
+func (svc *notificationService) ListNotifications(addr, cursor string, unreadOnly bool, limit int) (*domain.Page[domain.Notification], error) {
+
+	before, err := domain.DecodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	var beforeID int64
+	if before.ID != "" {
+		if beforeID, err = strconv.ParseInt(before.ID, 10, 64); err != nil {
+			return nil, domain.ErrCursorInvalid
+		}
+	}
+
+	notifications, err := svc.notificationRepo.ListNotifications(addr, beforeID, unreadOnly, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.Page[domain.Notification]{}
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+		last := notifications[limit-1]
+		page.NextCursor = domain.Cursor{CreatedAt: last.CreatedAt, ID: strconv.FormatInt(last.ID, 10)}.Encode()
+	}
+	page.Items = notifications
+
+	return page, nil
+}
+
+func (svc *notificationService) CountUnread(addr string) (int, error) {
+
+	return svc.notificationRepo.CountUnread(addr)
+}
+
+func (svc *notificationService) MarkRead(addr string, id int64) (*domain.Notification, error) {
+
+	return svc.notificationRepo.MarkRead(addr, id)
+}
+
+func (svc *notificationService) MarkUnread(addr string, id int64) (*domain.Notification, error) {
+
+	return svc.notificationRepo.MarkUnread(addr, id)
+}
+
+func (svc *notificationService) MarkAllRead(addr string, upToID int64) (int64, error) {
+
+	return svc.notificationRepo.MarkAllRead(addr, upToID)
+}
+
+func (svc *notificationService) Subscribe(addr, lastEventID string) (*NotificationStream, error) {
+
+	stream := &NotificationStream{
+		Address: addr,
+		wake:    make(chan struct{}, 1),
+	}
+
+	if lastEventID != "" {
+		id, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || id < 0 {
+			return nil, domain.ErrCursorInvalid
+		}
+		stream.lastID = id
+
+		// catch up on what was missed while disconnected
+		stream.signal()
+	} else {
+		id, err := svc.notificationRepo.LatestID(addr)
+		if err != nil {
+			return nil, err
+		}
+		stream.lastID = id
+	}
+
+	svc.mu.Lock()
+	streams, ok := svc.streams[addr]
+	if !ok {
+		streams = make(map[*NotificationStream]struct{})
+		svc.streams[addr] = streams
+	}
+	streams[stream] = struct{}{}
+	svc.mu.Unlock()
+
+	return stream, nil
+}
+
+func (svc *notificationService) Next(stream *NotificationStream, limit int) ([]domain.Notification, error) {
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	notifications, err := svc.notificationRepo.ListAfter(stream.Address, stream.lastID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(notifications) > 0 {
+		stream.lastID = notifications[len(notifications)-1].ID
+	}
+
+	return notifications, nil
+}
+
+func (svc *notificationService) Unsubscribe(stream *NotificationStream) {
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	streams := svc.streams[stream.Address]
+	delete(streams, stream)
+	if len(streams) == 0 {
+		delete(svc.streams, stream.Address)
+	}
+}
+
+func (svc *notificationService) Dispatch(payload string) {
+
+	var announcement struct {
+		Recipients []string `json:"recipients"`
+	}
+	if err := json.Unmarshal([]byte(payload), &announcement); err != nil {
+		svc.logger.Warn("notification announcement is malformed", "error", err)
+		return
+	}
+
+	// announcements without recipients concern everyone
+	if len(announcement.Recipients) == 0 {
+		svc.Resync()
+		return
+	}
+
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	for _, addr := range announcement.Recipients {
+		for stream := range svc.streams[addr] {
+			stream.signal()
+		}
+	}
+}
+
+func (svc *notificationService) Resync() {
+
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	for _, streams := range svc.streams {
+		for stream := range streams {
+			stream.signal()
+		}
+	}
+}
+
+func (svc *notificationService) GetPreferences(addr string) (domain.NotificationPreferences, error) {
+
+	preferences, err := svc.notificationRepo.ListPreferences(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return preferences.Complete(), nil
+}
+
+func (svc *notificationService) UpdatePreferences(addr string, preferences domain.NotificationPreferences) (domain.NotificationPreferences, error) {
+
+	for channel, kinds := range preferences {
+		if !domain.IsNotificationChannel(channel) {
+			return nil, domain.ErrPreferenceInvalid
+		}
+		for kind := range kinds {
+			if !domain.IsNotificationKind(kind) {
+				return nil, domain.ErrPreferenceInvalid
+			}
+		}
+	}
+
+	for channel, kinds := range preferences {
+		for kind, enabled := range kinds {
+			if err := svc.notificationRepo.SetPreference(addr, channel, kind, enabled); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return svc.GetPreferences(addr)
+}
+
+func (svc *notificationService) ReleaseDrips(limit int) (int, error) {
+
+	notifications, err := svc.notificationRepo.ReleaseDrips(limit)
+	if err != nil {
+		return 0, err
+	}
+
+	svc.announce(notifications)
+
+	return len(notifications), nil
+}
+
+func (svc *notificationService) OnPostPublished(event domain.PostPublished) {
+
+	data, _ := json.Marshal(map[string]string{
+		"post_id": event.Post.ID,
+		"title":   event.Post.Title,
+	})
+
+	notifications, err := svc.notificationRepo.NotifyMembers(domain.NotificationNewPost, event.Post.AuthorAddress, event.Post.ID, data)
+	if err != nil {
+		svc.logger.Error("new post notification failed", "post", event.Post.ID, "error", err)
+		return
+	}
+
+	svc.announce(notifications)
+}
+
+func (svc *notificationService) OnCommentCreated(event domain.CommentCreated) {
+
+	comment := event.Comment
+
+	data, _ := json.Marshal(map[string]string{
+		"post_id":    comment.PostID,
+		"post_title": event.PostTitle,
+		"comment_id": comment.ID,
+		"parent_id":  comment.ParentID,
+		"excerpt":    excerpt(comment.Body, notificationExcerptLength),
+	})
+
+	repliedTo := event.PostAuthor
+	if event.ParentAuthor != "" {
+		repliedTo = event.ParentAuthor
+	}
+
+	// everyone is notified once, a reply outweighs a mention
+	notified := map[string]bool{comment.AuthorAddress: true}
+
+	var created []domain.Notification
+
+	notify := func(addr, kind string) {
+		if notified[addr] {
+			return
+		}
+		notified[addr] = true
+
+		// the comment is only visible to those who can read the post
+		if _, err := svc.postService.Authorize(domain.Viewer{Address: addr}, comment.PostID); err != nil {
+			if !errors.Is(err, domain.ErrPostLocked) && !errors.Is(err, domain.ErrPostNotFound) {
+				svc.logger.Warn("comment notification access check failed", "comment", comment.ID, "recipient", addr, "error", err)
+			}
+			return
+		}
+
+		notification, err := svc.notificationRepo.CreateNotification(domain.Notification{
+			RecipientAddress: addr,
+			Kind:             kind,
+			ActorAddress:     comment.AuthorAddress,
+			SubjectID:        comment.ID,
+			Data:             data,
+		})
+		if err != nil {
+			svc.logger.Error("comment notification failed", "comment", comment.ID, "recipient", addr, "error", err)
+			return
+		}
+		if notification != nil {
+			created = append(created, *notification)
+		}
+	}
+
+	notify(repliedTo, domain.NotificationReply)
+	for _, addr := range comment.Mentions {
+		notify(addr, domain.NotificationMention)
+	}
+
+	svc.announce(created)
+}
+
+func (svc *notificationService) OnPaymentReceived(event domain.PaymentReceived) {
+
+	community := event.Community
+
+	payload := map[string]any{
+		"community_id":   community.ID,
+		"community_name": community.Name,
+		"payment_tx":     event.PaymentTx,
+	}
+	if community.Price != nil {
+		payload["chain_id"] = community.Price.ChainID
+		payload["token"] = community.Price.Token
+		payload["amount"] = community.Price.Amount
+	}
+	data, _ := json.Marshal(payload)
+
+	svc.create(domain.Notification{
+		RecipientAddress: community.OwnerAddress,
+		Kind:             domain.NotificationPaymentReceived,
+		ActorAddress:     event.Payer,
+		SubjectID:        community.ID,
+		Data:             data,
+	})
+}
+
+func (svc *notificationService) OnMembershipExpiring(event domain.MembershipExpiring) {
+
+	community := event.Community
+
+	data, _ := json.Marshal(map[string]string{
+		"community_id":   community.ID,
+		"community_name": community.Name,
+		"community_slug": community.Slug,
+		"reason":         event.Reason,
+	})
+
+	svc.create(domain.Notification{
+		RecipientAddress: event.Member.Address,
+		Kind:             domain.NotificationMembershipExpiring,
+		SubjectID:        community.ID,
+		Data:             data,
+		// every transfer out re-checks the membership, members are warned once
+		GroupKey: domain.NotificationMembershipExpiring + ":" + community.ID,
+	})
+}
+
+func (svc *notificationService) create(notification domain.Notification) {
+
+	created, err := svc.notificationRepo.CreateNotification(notification)
+	if err != nil {
+		svc.logger.Error("notification failed", "kind", notification.Kind, "recipient", notification.RecipientAddress, "error", err)
+		return
+	}
+
+	if created != nil {
+		svc.announce([]domain.Notification{*created})
+	}
+}
+
+// announce wakes the streams of the recipients on every replica and hands the
+// notifications to the other delivery channels. Large fan-outs are announced to
+// everyone, streams of accounts without news read nothing
+func (svc *notificationService) announce(notifications []domain.Notification) {
+
+	if len(notifications) == 0 {
+		return
+	}
+
+	if err := svc.bus.Publish(domain.NotificationsCreated{Notifications: notifications}); err != nil {
+		svc.logger.Warn("notifications created event dropped", "count", len(notifications), "error", err)
+	}
+
+	recipients := make([]string, 0, len(notifications))
+	for _, notification := range notifications {
+		recipients = append(recipients, notification.RecipientAddress)
+	}
+
+	if len(recipients) > notificationAnnounceBatch {
+		recipients = nil
+	}
+
+	payload, _ := json.Marshal(map[string][]string{"recipients": recipients})
+	if len(payload) > pgnotify.MaxPayload {
+		payload = []byte(`{"recipients":[]}`)
+	}
+
+	if err := svc.notificationRepo.Notify(string(payload)); err != nil {
+		svc.logger.Warn("notification announcement failed", "error", err)
+	}
+}
+
+// excerpt cuts s to at most n characters
+func excerpt(s string, n int) string {
+
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+
+	return string(runes[:n]) + "…"
+}
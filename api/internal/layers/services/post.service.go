@@ -0,0 +1,755 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/repositories"
+	"github.com/Xebec19/jibe/api/pkg/eventbus"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/Xebec19/jibe/api/pkg/textdiff"
+)
+
+type PostService interface {
+	// CreatePost stores a new draft written by the author
+	CreatePost(author string, input domain.PostInput) (*domain.Post, error)
+
+	// UpdatePost replaces the editable fields of a post owned by the author. Every save
+	// keeps a revision of the post, attachments are not part of it
+	UpdatePost(author, id string, input domain.PostInput) (*domain.Post, error)
+
+	// PublishPost makes a draft visible to readers allowed by its access policy
+	PublishPost(author, id string) (*domain.Post, error)
+
+	// UnpublishPost moves a published or scheduled post back to drafts
+	UnpublishPost(author, id string) (*domain.Post, error)
+
+	// SchedulePost publishes a draft at publishAt, rescheduling a scheduled post moves
+	// its time. Readers are notified once it is published
+	SchedulePost(author, id string, publishAt time.Time) (*domain.Post, error)
+
+	// PublishDue publishes up to limit scheduled posts whose time came, it returns how
+	// many were published
+	PublishDue(limit int) (int, error)
+
+	// SetDrip unlocks a post for members of a community owned by the author some time
+	// after each of them joined
+	SetDrip(author, id string, drip domain.PostDrip) (*domain.PostDrip, error)
+
+	RemoveDrip(author, id string) error
+
+	DeletePost(author, id string) error
+
+	// GetPost returns the post as seen by the viewer. Drafts are only visible to their
+	// author, dripped posts are locked until they unlock for the viewer and gated posts
+	// are locked for viewers failing the access policy
+	GetPost(viewer domain.Viewer, id string) (*domain.PostView, error)
+
+	// Authorize returns the post if the viewer can read it, which is what taking part in
+	// its discussion requires. Viewers failing the access policy of a gated post get
+	// ErrPostLocked. The post is returned as stored, its content is not decrypted
+	Authorize(viewer domain.Viewer, id string) (*domain.Post, error)
+
+	// ListPosts returns posts of the author. Drafts and scheduled posts are only listed
+	// for the author
+	ListPosts(viewer domain.Viewer, author, status string, limit, offset int) ([]domain.PostView, error)
+
+	// ViewPosts applies drips and access policies to posts loaded elsewhere, eg. search
+	// results. Posts the viewer can not read yet are locked
+	ViewPosts(viewer domain.Viewer, posts []domain.Post) ([]domain.PostView, error)
+
+	// ListRevisions returns the revisions of a post owned by the author newest first,
+	// without their body
+	ListRevisions(author, postID string, limit, offset int) ([]domain.PostRevision, error)
+
+	GetRevision(author, postID string, number int) (*domain.PostRevision, error)
+
+	// DiffRevisions compares revision from of a post owned by the author to revision to
+	DiffRevisions(author, postID string, from, to int) (*domain.RevisionDiff, error)
+
+	// RestoreRevision saves the content of a revision as the post's, which keeps it as a
+	// new revision. The status of the post does not change
+	RestoreRevision(author, postID string, number int) (*domain.Post, error)
+
+	// PruneRevisions deletes up to limit revisions saved before cutoff while their post
+	// was not published, it returns how many were deleted
+	PruneRevisions(cutoff time.Time, limit int) (int, error)
+
+	AddAttachment(author, postID string, attachment domain.PostAttachment) (*domain.PostAttachment, error)
+
+	RemoveAttachment(author, postID, attachmentID string) error
+}
+
+func NewPostService(logger logger.Logger, postRepo repositories.PostRepository, communityRepo repositories.CommunityRepository, accessService AccessService, encryption EncryptionService, bus *eventbus.Bus) PostService {
+
+	return &postService{
+		logger:        logger,
+		postRepo:      postRepo,
+		communityRepo: communityRepo,
+		accessService: accessService,
+		encryption:    encryption,
+		bus:           bus,
+	}
+}
+
+type postService struct {
+	logger        logger.Logger
+	postRepo      repositories.PostRepository
+	communityRepo repositories.CommunityRepository
+	accessService AccessService
+	encryption    EncryptionService
+	bus           *eventbus.Bus
+}
+
+func (svc *postService) CreatePost(author string, input domain.PostInput) (*domain.Post, error) {
+
+	if err := svc.accessService.ValidatePolicy(input.AccessPolicy); err != nil {
+		return nil, err
+	}
+
+	// a new post has no drip yet
+	prepareInput(&input, false)
+	input.Change = revisionChange(domain.RevisionContent{}, input)
+
+	if err := svc.encryption.SealPostInput(&input, nil); err != nil {
+		return nil, err
+	}
+
+	return svc.opened(svc.postRepo.CreatePost(author, input))
+}
+
+func (svc *postService) UpdatePost(author, id string, input domain.PostInput) (*domain.Post, error) {
+
+	post, err := svc.ownedPost(author, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.save(post, input)
+}
+
+func (svc *postService) PublishPost(author, id string) (*domain.Post, error) {
+
+	post, err := svc.ownedPost(author, id)
+	if err != nil {
+		return nil, err
+	}
+
+	published, err := svc.opened(svc.postRepo.SetStatus(id, domain.PostStatusPublished))
+	if err != nil {
+		return nil, err
+	}
+
+	// readers hear of a post once, not again when it is republished
+	if post.PublishedAt == nil {
+		svc.announce(*published)
+	}
+
+	return published, nil
+}
+
+func (svc *postService) UnpublishPost(author, id string) (*domain.Post, error) {
+
+	if _, err := svc.ownedPost(author, id); err != nil {
+		return nil, err
+	}
+
+	return svc.opened(svc.postRepo.SetStatus(id, domain.PostStatusDraft))
+}
+
+func (svc *postService) SchedulePost(author, id string, publishAt time.Time) (*domain.Post, error) {
+
+	post, err := svc.ownedPost(author, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if post.IsPublished() {
+		return nil, domain.ErrPostAlreadyPublished
+	}
+
+	if !publishAt.After(time.Now()) {
+		return nil, domain.ErrPublishAtInvalid
+	}
+
+	return svc.opened(svc.postRepo.Schedule(id, publishAt.UTC()))
+}
+
+func (svc *postService) PublishDue(limit int) (int, error) {
+
+	published, first, err := svc.postRepo.PublishDue(limit)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, post := range first {
+		svc.announce(post)
+	}
+
+	return published, nil
+}
+
+func (svc *postService) SetDrip(author, id string, drip domain.PostDrip) (*domain.PostDrip, error) {
+
+	if _, err := svc.ownedPost(author, id); err != nil {
+		return nil, err
+	}
+
+	if drip.DelaySeconds < 0 || time.Duration(drip.DelaySeconds)*time.Second > domain.DripMaxDelay {
+		return nil, domain.ErrDripDelayInvalid
+	}
+
+	community, err := svc.communityRepo.GetCommunity(drip.CommunityID)
+	if err != nil {
+		return nil, err
+	}
+
+	if community.OwnerAddress != author {
+		return nil, domain.ErrDripCommunityNotOwned
+	}
+
+	drip.CommunityID = community.ID
+	return svc.postRepo.SetDrip(id, drip)
+}
+
+func (svc *postService) RemoveDrip(author, id string) error {
+
+	post, err := svc.ownedPost(author, id)
+	if err != nil {
+		return err
+	}
+
+	if err := svc.encryption.OpenPost(post); err != nil {
+		return err
+	}
+
+	return svc.postRepo.DeleteDrip(id, searchBody(*post, false))
+}
+
+func (svc *postService) DeletePost(author, id string) error {
+
+	if _, err := svc.ownedPost(author, id); err != nil {
+		return err
+	}
+
+	return svc.postRepo.DeletePost(id)
+}
+
+func (svc *postService) GetPost(viewer domain.Viewer, id string) (*domain.PostView, error) {
+
+	post, err := svc.postRepo.GetPost(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !post.IsPublished() && post.AuthorAddress != viewer.Address {
+		return nil, domain.ErrPostNotFound
+	}
+
+	return svc.view(viewer, *post)
+}
+
+func (svc *postService) Authorize(viewer domain.Viewer, id string) (*domain.Post, error) {
+
+	post, err := svc.postRepo.GetPost(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !post.IsPublished() && post.AuthorAddress != viewer.Address {
+		return nil, domain.ErrPostNotFound
+	}
+
+	decisions, err := svc.decideAll(viewer, []domain.Post{*post})
+	if err != nil {
+		return nil, err
+	}
+
+	if decision := decisions[0]; !decision.Allowed {
+		return nil, fmt.Errorf("%w: %s", domain.ErrPostLocked, decision.Reason)
+	}
+
+	return post, nil
+}
+
+func (svc *postService) ListPosts(viewer domain.Viewer, author, status string, limit, offset int) ([]domain.PostView, error) {
+
+	if status != domain.PostStatusPublished && viewer.Address != author {
+		return nil, domain.ErrPostForbidden
+	}
+
+	posts, err := svc.postRepo.ListByAuthor(author, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.viewAll(viewer, posts)
+}
+
+func (svc *postService) ViewPosts(viewer domain.Viewer, posts []domain.Post) ([]domain.PostView, error) {
+
+	return svc.viewAll(viewer, posts)
+}
+
+func (svc *postService) ListRevisions(author, postID string, limit, offset int) ([]domain.PostRevision, error) {
+
+	if _, err := svc.ownedPost(author, postID); err != nil {
+		return nil, err
+	}
+
+	revisions, err := svc.postRepo.ListRevisions(postID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range revisions {
+		revisions[i].Body = ""
+	}
+
+	return revisions, nil
+}
+
+func (svc *postService) GetRevision(author, postID string, number int) (*domain.PostRevision, error) {
+
+	post, err := svc.ownedPost(author, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.openedRevision(post, number)
+}
+
+func (svc *postService) DiffRevisions(author, postID string, from, to int) (*domain.RevisionDiff, error) {
+
+	post, err := svc.ownedPost(author, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	older, err := svc.openedRevision(post, from)
+	if err != nil {
+		return nil, err
+	}
+
+	newer, err := svc.openedRevision(post, to)
+	if err != nil {
+		return nil, err
+	}
+
+	script := textdiff.Lines(older.Body, newer.Body)
+	added, removed := textdiff.Count(script)
+
+	diff := &domain.RevisionDiff{
+		PostID: postID,
+		From:   from,
+		To:     to,
+		Change: domain.RevisionChange{
+			Fields:       newer.ChangedFields(older.RevisionContent),
+			LinesAdded:   added,
+			LinesRemoved: removed,
+		},
+		Fields: []domain.FieldChange{},
+		Body:   []domain.DiffHunk{},
+	}
+
+	for _, field := range diff.Change.Fields {
+		if change, ok := fieldChange(field, older.RevisionContent, newer.RevisionContent); ok {
+			diff.Fields = append(diff.Fields, change)
+		}
+	}
+
+	for _, hunk := range textdiff.Hunks(script, domain.RevisionDiffContext) {
+		lines := make([]domain.DiffLine, len(hunk.Lines))
+		for i, line := range hunk.Lines {
+			lines[i] = domain.DiffLine{Op: string(line.Op), Text: line.Text}
+		}
+
+		diff.Body = append(diff.Body, domain.DiffHunk{
+			FromLine:  hunk.FromLine,
+			FromCount: hunk.FromCount,
+			ToLine:    hunk.ToLine,
+			ToCount:   hunk.ToCount,
+			Lines:     lines,
+		})
+	}
+
+	return diff, nil
+}
+
+func (svc *postService) RestoreRevision(author, postID string, number int) (*domain.Post, error) {
+
+	post, err := svc.ownedPost(author, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	revision, err := svc.openedRevision(post, number)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.save(post, domain.PostInput{
+		Title:        revision.Title,
+		Teaser:       revision.Teaser,
+		Tags:         revision.Tags,
+		Body:         revision.Body,
+		BodyFormat:   revision.BodyFormat,
+		AccessPolicy: revision.AccessPolicy,
+		RestoredFrom: revision.Number,
+	})
+}
+
+func (svc *postService) PruneRevisions(cutoff time.Time, limit int) (int, error) {
+
+	return svc.postRepo.PruneDraftRevisions(cutoff, limit)
+}
+
+func (svc *postService) AddAttachment(author, postID string, attachment domain.PostAttachment) (*domain.PostAttachment, error) {
+
+	post, err := svc.ownedPost(author, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	plain := attachment
+	if err := svc.encryption.SealAttachment(post.DataKey, &attachment); err != nil {
+		return nil, err
+	}
+
+	created, err := svc.postRepo.CreateAttachment(postID, attachment)
+	if err != nil {
+		return nil, err
+	}
+
+	created.Name = plain.Name
+	created.URL = plain.URL
+	return created, nil
+}
+
+func (svc *postService) RemoveAttachment(author, postID, attachmentID string) error {
+
+	if _, err := svc.ownedPost(author, postID); err != nil {
+		return err
+	}
+
+	return svc.postRepo.DeleteAttachment(postID, attachmentID)
+}
+
+// ownedPost loads a post and makes sure it was written by the author
+func (svc *postService) ownedPost(author, id string) (*domain.Post, error) {
+
+	post, err := svc.postRepo.GetPost(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if post.AuthorAddress != author {
+		return nil, domain.ErrPostForbidden
+	}
+
+	return post, nil
+}
+
+// save replaces the content of a post with input and keeps it as a revision described
+// against the content it replaces
+func (svc *postService) save(post *domain.Post, input domain.PostInput) (*domain.Post, error) {
+
+	if err := svc.accessService.ValidatePolicy(input.AccessPolicy); err != nil {
+		return nil, err
+	}
+
+	drips, err := svc.postRepo.ListDrips([]string{post.ID}, "")
+	if err != nil {
+		return nil, err
+	}
+
+	_, dripped := drips[post.ID]
+	prepareInput(&input, dripped)
+
+	if err := svc.encryption.OpenPost(post); err != nil {
+		return nil, err
+	}
+
+	input.Change = revisionChange(revisionContent(*post), input)
+	if input.RestoredFrom != 0 && len(input.Change.Fields) == 0 {
+		return nil, domain.ErrRevisionCurrent
+	}
+
+	// the post keeps its data key, so attachments stay readable
+	if err := svc.encryption.SealPostInput(&input, post.DataKey); err != nil {
+		return nil, err
+	}
+
+	return svc.opened(svc.postRepo.UpdatePost(post.ID, input))
+}
+
+// openedRevision loads a revision of the post and decrypts its body. Revisions saved
+// before the post was encrypted hold their body in plaintext
+func (svc *postService) openedRevision(post *domain.Post, number int) (*domain.PostRevision, error) {
+
+	revision, err := svc.postRepo.GetRevision(post.ID, number)
+	if err != nil {
+		return nil, err
+	}
+
+	if revision.SealedBody == nil {
+		return revision, nil
+	}
+
+	if post.DataKey == nil {
+		return nil, domain.ErrContentSealed
+	}
+
+	body, err := svc.encryption.Open(post.DataKey, revision.SealedBody, aadPostBody)
+	if err != nil {
+		return nil, fmt.Errorf("post %s revision %d body %w", post.ID, number, err)
+	}
+	revision.Body = string(body)
+
+	return revision, nil
+}
+
+// announce tells readers a post was published for the first time
+func (svc *postService) announce(post domain.Post) {
+
+	if err := svc.bus.Publish(domain.PostPublished{Post: post}); err != nil {
+		svc.logger.Warn("post published event dropped", "post", post.ID, "error", err)
+	}
+}
+
+// opened decrypts a post returned to its author by a write
+func (svc *postService) opened(post *domain.Post, err error) (*domain.Post, error) {
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := svc.encryption.OpenPost(post); err != nil {
+		return nil, err
+	}
+
+	return post, nil
+}
+
+// prepareInput normalizes tags and picks the text indexed for search. It runs before the
+// body is sealed
+func prepareInput(input *domain.PostInput, dripped bool) {
+
+	tags := make([]string, 0, len(input.Tags))
+	seen := make(map[string]bool, len(input.Tags))
+	for _, tag := range input.Tags {
+		tag = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tag), "#")))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	input.Tags = tags
+
+	input.SearchBody = searchBody(domain.Post{Body: input.Body, AccessPolicy: input.AccessPolicy}, dripped)
+}
+
+// searchBody is the text of the post indexed for search. Bodies of gated and dripped
+// posts are not indexed so matches can not reveal them
+func searchBody(post domain.Post, dripped bool) string {
+
+	if dripped || post.IsGated() {
+		return ""
+	}
+
+	return post.Body
+}
+
+func revisionContent(post domain.Post) domain.RevisionContent {
+
+	return domain.RevisionContent{
+		Title:        post.Title,
+		Teaser:       post.Teaser,
+		Tags:         post.Tags,
+		Body:         post.Body,
+		BodyFormat:   post.BodyFormat,
+		AccessPolicy: post.AccessPolicy,
+	}
+}
+
+// revisionChange describes the save of input over prev. It runs on the plaintext, before
+// the body is sealed
+func revisionChange(prev domain.RevisionContent, input domain.PostInput) domain.RevisionChange {
+
+	next := domain.RevisionContent{
+		Title:        input.Title,
+		Teaser:       input.Teaser,
+		Tags:         input.Tags,
+		Body:         input.Body,
+		BodyFormat:   input.BodyFormat,
+		AccessPolicy: input.AccessPolicy,
+	}
+
+	change := domain.RevisionChange{Fields: next.ChangedFields(prev)}
+	if next.Body != prev.Body {
+		change.LinesAdded, change.LinesRemoved = textdiff.Count(textdiff.Lines(prev.Body, next.Body))
+	}
+
+	return change
+}
+
+// fieldChange reports the values of a field other than the body in two revisions
+func fieldChange(field string, from, to domain.RevisionContent) (domain.FieldChange, bool) {
+
+	change := domain.FieldChange{Field: field}
+
+	switch field {
+	case domain.RevisionFieldTitle:
+		change.From, change.To = from.Title, to.Title
+	case domain.RevisionFieldTeaser:
+		change.From, change.To = from.Teaser, to.Teaser
+	case domain.RevisionFieldTags:
+		change.From, change.To = from.Tags, to.Tags
+	case domain.RevisionFieldBodyFormat:
+		change.From, change.To = from.BodyFormat, to.BodyFormat
+	case domain.RevisionFieldAccessPolicy:
+		change.From, change.To = from.AccessPolicy, to.AccessPolicy
+	default:
+		return change, false
+	}
+
+	return change, true
+}
+
+// view applies the drip and access policy of the post for the viewer. Content is only
+// decrypted once the viewer was granted access
+func (svc *postService) view(viewer domain.Viewer, post domain.Post) (*domain.PostView, error) {
+
+	views, err := svc.viewAll(viewer, []domain.Post{post})
+	if err != nil {
+		return nil, err
+	}
+
+	return &views[0], nil
+}
+
+// present builds the view of a post for an access decision, locked views lose their
+// content
+func (svc *postService) present(post domain.Post, decision domain.AccessDecision) (*domain.PostView, error) {
+
+	view := &domain.PostView{Post: post}
+
+	if !decision.Allowed {
+		view.Locked = true
+		view.LockReason = decision.Reason
+		view.UnlocksAt = decision.UnlocksAt
+		view.Body = ""
+		view.Attachments = nil
+		return view, nil
+	}
+
+	return svc.openView(view)
+}
+
+// decideAll decides if the viewer can read each of the posts. Authors always pass, the
+// drip of a post is checked before its access policy. Drips are read at once and the
+// policies of gated posts are decided together, so the chain reads behind them are
+// batched. The drips found are set on the posts
+func (svc *postService) decideAll(viewer domain.Viewer, posts []domain.Post) ([]domain.AccessDecision, error) {
+
+	ids := make([]string, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+
+	drips, err := svc.postRepo.ListDrips(ids, viewer.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	decisions := make([]domain.AccessDecision, len(posts))
+
+	var gated []int
+	var policies []json.RawMessage
+	for i := range posts {
+		post := &posts[i]
+
+		drip, dripped := drips[post.ID]
+		if dripped {
+			post.Drip = &drip.PostDrip
+		}
+
+		if post.AuthorAddress == viewer.Address {
+			decisions[i] = domain.AccessDecision{Allowed: true}
+			continue
+		}
+
+		if dripped {
+			unlocksAt := drip.UnlocksAt()
+			if unlocksAt == nil {
+				decisions[i] = domain.AccessDecision{Reason: "join the community to unlock this post"}
+				continue
+			}
+			if unlocksAt.After(now) {
+				decisions[i] = domain.AccessDecision{
+					Reason:    "unlocks on " + unlocksAt.UTC().Format(time.RFC3339),
+					UnlocksAt: unlocksAt,
+				}
+				continue
+			}
+		}
+
+		if !post.IsGated() {
+			decisions[i] = domain.AccessDecision{Allowed: true}
+			continue
+		}
+		gated = append(gated, i)
+		policies = append(policies, post.AccessPolicy)
+	}
+
+	if len(policies) > 0 {
+		evaluated, errs := svc.accessService.EvaluateAll(viewer, policies)
+		for n, i := range gated {
+			decisions[i] = evaluated[n]
+			if errs[n] != nil {
+				// fail closed, a flaky rpc must not leak gated content
+				svc.logger.Warn("post access evaluation failed", "post", posts[i].ID, "error", errs[n])
+				decisions[i] = domain.AccessDecision{Reason: "access could not be verified, try again shortly"}
+			}
+		}
+	}
+
+	return decisions, nil
+}
+
+func (svc *postService) openView(view *domain.PostView) (*domain.PostView, error) {
+
+	if err := svc.encryption.OpenPost(&view.Post); err != nil {
+		return nil, err
+	}
+
+	return view, nil
+}
+
+// viewAll applies drips and access policies to a page of posts
+func (svc *postService) viewAll(viewer domain.Viewer, posts []domain.Post) ([]domain.PostView, error) {
+
+	// decideAll sets drips on the posts, the caller's slice is left alone
+	posts = append([]domain.Post(nil), posts...)
+
+	decisions, err := svc.decideAll(viewer, posts)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]domain.PostView, len(posts))
+	for i, post := range posts {
+		view, err := svc.present(post, decisions[i])
+		if err != nil {
+			return nil, err
+		}
+		views[i] = *view
+	}
+
+	return views, nil
+}
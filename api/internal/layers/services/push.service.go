@@ -0,0 +1,352 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/repositories"
+	"github.com/Xebec19/jibe/api/pkg/config"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/Xebec19/jibe/api/pkg/webpush"
+)
+
+const (
+	// pushTTL is how long push services keep a message for an offline browser, older
+	// notifications are not pushed at all
+	pushTTL = 24 * time.Hour
+	// pushLease is how long a claimed delivery is left to its sender before it is due
+	// again
+	pushLease = 2 * time.Minute
+	// pushMaxAttempts caps the tries of a delivery the push service keeps refusing
+	pushMaxAttempts = 8
+	// pushBackoff is the wait before the second try, it doubles up to pushMaxBackoff
+	pushBackoff    = 30 * time.Second
+	pushMaxBackoff = time.Hour
+	// pushConcurrency is how many messages are sent at once
+	pushConcurrency = 8
+	// pushEndpointLength caps the endpoints accepted from browsers
+	pushEndpointLength = 2048
+)
+
+// pushServiceHosts are the push services of the major browsers, a leading dot matches
+// any subdomain. Endpoints elsewhere are refused so deliveries can not be aimed at
+// internal hosts
+var pushServiceHosts = []string{
+	"fcm.googleapis.com",
+	"android.googleapis.com",
+	"updates.push.services.mozilla.com",
+	"web.push.apple.com",
+	".notify.windows.com",
+}
+
+type PushService interface {
+	// PublicKey is the VAPID key browsers subscribe with
+	PublicKey() (string, error)
+
+	// Subscribe registers a browser subscription of the account, its endpoint must be
+	// on one of the known push services
+	Subscribe(addr string, subscription domain.PushSubscription) (*domain.PushSubscription, error)
+
+	ListSubscriptions(addr string) ([]domain.PushSubscription, error)
+
+	Unsubscribe(addr, id string) error
+
+	// OnNotificationsCreated queues push deliveries of new notifications, it is
+	// subscribed to the event bus
+	OnNotificationsCreated(event domain.NotificationsCreated)
+
+	// OnEnqueued registers a handler called after deliveries were queued
+	OnEnqueued(handler func())
+
+	// DeliverPending sends up to limit due deliveries and returns how many it claimed.
+	// Refused deliveries are retried with backoff, subscriptions the push service
+	// dropped are removed
+	DeliverPending(limit int) (int, error)
+}
+
+// NewPushService returns the push service, client is nil when no VAPID keys are
+// configured and push is disabled
+func NewPushService(ctx context.Context, logger logger.Logger, cfg *config.Config, client *webpush.Client, pushRepo repositories.PushRepository) PushService {
+
+	return &pushService{
+		ctx:      ctx,
+		logger:   logger,
+		cfg:      cfg,
+		client:   client,
+		pushRepo: pushRepo,
+	}
+}
+
+type pushService struct {
+	ctx      context.Context
+	logger   logger.Logger
+	cfg      *config.Config
+	client   *webpush.Client
+	pushRepo repositories.PushRepository
+
+	mu       sync.Mutex
+	handlers []func()
+}
+
+func (svc *pushService) PublicKey() (string, error) {
+
+	if svc.client == nil {
+		return "", domain.ErrPushDisabled
+	}
+
+	return svc.cfg.VAPIDPublicKey, nil
+}
+
+func (svc *pushService) Subscribe(addr string, subscription domain.PushSubscription) (*domain.PushSubscription, error) {
+
+	if svc.client == nil {
+		return nil, domain.ErrPushDisabled
+	}
+
+	if len(subscription.Endpoint) > pushEndpointLength {
+		return nil, domain.ErrPushSubscriptionInvalid
+	}
+
+	if !svc.allowedEndpoint(subscription.Endpoint) {
+		return nil, domain.ErrPushSubscriptionInvalid
+	}
+
+	// a P-256 point in uncompressed form and a 16 byte auth secret
+	if key := decodeKey(subscription.P256dh); len(key) != 65 || key[0] != 0x04 {
+		return nil, domain.ErrPushSubscriptionInvalid
+	}
+	if len(decodeKey(subscription.Auth)) != 16 {
+		return nil, domain.ErrPushSubscriptionInvalid
+	}
+
+	subscription.Address = addr
+
+	return svc.pushRepo.Subscribe(subscription)
+}
+
+func (svc *pushService) ListSubscriptions(addr string) ([]domain.PushSubscription, error) {
+
+	return svc.pushRepo.ListSubscriptions(addr)
+}
+
+func (svc *pushService) Unsubscribe(addr, id string) error {
+
+	return svc.pushRepo.RemoveSubscription(addr, id)
+}
+
+func (svc *pushService) OnNotificationsCreated(event domain.NotificationsCreated) {
+
+	if svc.client == nil {
+		return
+	}
+
+	ids := make([]int64, 0, len(event.Notifications))
+	for _, notification := range event.Notifications {
+		ids = append(ids, notification.ID)
+	}
+
+	queued, err := svc.pushRepo.Enqueue(ids)
+	if err != nil {
+		svc.logger.Error("push deliveries could not be queued", "notifications", len(ids), "error", err)
+		return
+	}
+
+	if queued == 0 {
+		return
+	}
+
+	svc.mu.Lock()
+	handlers := svc.handlers
+	svc.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler()
+	}
+}
+
+func (svc *pushService) OnEnqueued(handler func()) {
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.handlers = append(svc.handlers, handler)
+}
+
+func (svc *pushService) DeliverPending(limit int) (int, error) {
+
+	if svc.client == nil {
+		return 0, nil
+	}
+
+	deliveries, err := svc.pushRepo.ClaimDeliveries(limit, time.Now().Add(pushLease))
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, pushConcurrency)
+
+	for _, delivery := range deliveries {
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			svc.deliver(delivery)
+		}()
+	}
+
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+// deliver sends one delivery and settles it by the answer of the push service
+func (svc *pushService) deliver(delivery domain.PushDelivery) {
+
+	notification := delivery.Notification
+
+	// read elsewhere or too old to be news
+	if notification.IsRead() || time.Since(notification.CreatedAt) > pushTTL {
+		svc.complete(delivery)
+		return
+	}
+
+	// subscriptions stored before their push service was refused are not sent to
+	if !svc.allowedEndpoint(delivery.Subscription.Endpoint) {
+		svc.logger.Warn("push subscription removed", "subscription", delivery.Subscription.ID, "reason", "endpoint is not a known push service")
+		if err := svc.pushRepo.DropSubscription(delivery.Subscription.ID); err != nil {
+			svc.logger.Error("push subscription removal failed", "subscription", delivery.Subscription.ID, "error", err)
+		}
+		return
+	}
+
+	payload, err := svc.payload(notification)
+	if err != nil {
+		svc.logger.Error("push payload failed", "notification", notification.ID, "error", err)
+		svc.complete(delivery)
+		return
+	}
+
+	sub := webpush.Subscription{
+		Endpoint: delivery.Subscription.Endpoint,
+		P256dh:   delivery.Subscription.P256dh,
+		Auth:     delivery.Subscription.Auth,
+	}
+
+	err = svc.client.Send(svc.ctx, sub, payload, webpush.Options{
+		TTL:     pushTTL - time.Since(notification.CreatedAt),
+		Urgency: "normal",
+	})
+
+	var statusErr *webpush.StatusError
+
+	switch {
+	case err == nil:
+		svc.complete(delivery)
+		if err := svc.pushRepo.TouchSubscription(delivery.Subscription.ID); err != nil {
+			svc.logger.Warn("push subscription touch failed", "subscription", delivery.Subscription.ID, "error", err)
+		}
+
+	case errors.Is(err, webpush.ErrGone), errors.Is(err, webpush.ErrKeysInvalid):
+		svc.logger.Info("push subscription removed", "subscription", delivery.Subscription.ID, "reason", err)
+		if err := svc.pushRepo.DropSubscription(delivery.Subscription.ID); err != nil {
+			svc.logger.Error("push subscription removal failed", "subscription", delivery.Subscription.ID, "error", err)
+		}
+
+	case errors.As(err, &statusErr) && !statusErr.Temporary():
+		svc.logger.Warn("push message refused", "delivery", delivery.ID, "error", err)
+		svc.complete(delivery)
+
+	default:
+		// temporary refusals and network errors are retried
+		if delivery.Attempts >= pushMaxAttempts {
+			svc.logger.Warn("push delivery given up", "delivery", delivery.ID, "attempts", delivery.Attempts, "error", err)
+			svc.complete(delivery)
+			return
+		}
+
+		wait := pushBackoff << (delivery.Attempts - 1)
+		if wait > pushMaxBackoff {
+			wait = pushMaxBackoff
+		}
+		if statusErr != nil && statusErr.RetryAfter > wait {
+			wait = statusErr.RetryAfter
+		}
+
+		if err := svc.pushRepo.RescheduleDelivery(delivery.ID, time.Now().Add(wait), err.Error()); err != nil {
+			svc.logger.Error("push delivery reschedule failed", "delivery", delivery.ID, "error", err)
+		}
+	}
+}
+
+func (svc *pushService) complete(delivery domain.PushDelivery) {
+
+	if err := svc.pushRepo.CompleteDelivery(delivery.ID); err != nil {
+		svc.logger.Error("push delivery completion failed", "delivery", delivery.ID, "error", err)
+	}
+}
+
+// allowedEndpoint reports if messages may be sent to the endpoint, an https url of one
+// of the pushServiceHosts. Any https url passes when PUSH_ENDPOINT is set, messages then
+// go to it instead
+func (svc *pushService) allowedEndpoint(raw string) bool {
+
+	endpoint, err := url.Parse(raw)
+	if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" || endpoint.User != nil {
+		return false
+	}
+
+	if svc.cfg.PushEndpoint != "" {
+		return true
+	}
+
+	if endpoint.Port() != "" && endpoint.Port() != "443" {
+		return false
+	}
+
+	host := strings.ToLower(endpoint.Hostname())
+	for _, allowed := range pushServiceHosts {
+		if host == allowed || (strings.HasPrefix(allowed, ".") && strings.HasSuffix(host, allowed)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// payload is the message handed to the service worker of the browser
+func (svc *pushService) payload(notification domain.Notification) ([]byte, error) {
+
+	summary := notification.Summarize()
+
+	message := map[string]any{
+		"id":    notification.ID,
+		"kind":  notification.Kind,
+		"title": summary.Title,
+		"body":  excerpt(summary.Body, notificationExcerptLength),
+		"url":   "https://" + svc.cfg.Domain + summary.Path,
+	}
+
+	return json.Marshal(message)
+}
+
+// decodeKey reads a base64url key as browsers send it, with or without padding
+func decodeKey(s string) []byte {
+
+	key, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil
+	}
+
+	return key
+}
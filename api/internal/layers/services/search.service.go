@@ -0,0 +1,245 @@
+package services
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/repositories"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+)
+
+const (
+	// markStart and markStop delimit matches in ts_headline output. They are private use
+	// runes so they survive HTML escaping and are unlikely to appear in posts
+	markStart = "\uE000"
+	markStop  = "\uE001"
+
+	// searchSnippetBefore and searchSnippetAfter are how many runes of the body around
+	// the first match make up a snippet
+	searchSnippetBefore = 60
+	searchSnippetAfter  = 180
+)
+
+var (
+	headlineOptions = `StartSel="` + markStart + `", StopSel="` + markStop + `", MaxWords=35, MinWords=15`
+
+	highlighter = strings.NewReplacer(markStart, "<mark>", markStop, "</mark>")
+)
+
+type SearchService interface {
+	// Search looks up query among the kind of results asked for, SearchTypeAll searches
+	// every kind with the same page. Only published posts are searched and posts the
+	// viewer can not read are returned locked, with their teaser
+	Search(viewer domain.Viewer, query, kind string, limit, offset int) (*domain.SearchResults, error)
+}
+
+func NewSearchService(logger logger.Logger, searchRepo repositories.SearchRepository, postService PostService) SearchService {
+
+	return &searchService{
+		logger:      logger,
+		searchRepo:  searchRepo,
+		postService: postService,
+	}
+}
+
+type searchService struct {
+	logger      logger.Logger
+	searchRepo  repositories.SearchRepository
+	postService PostService
+}
+
+func (svc *searchService) Search(viewer domain.Viewer, query, kind string, limit, offset int) (*domain.SearchResults, error) {
+
+	query = strings.TrimSpace(query)
+	if query == "" || utf8.RuneCountInString(query) > domain.SearchQueryMaxLength {
+		return nil, domain.ErrSearchQueryInvalid
+	}
+
+	if kind == "" {
+		kind = domain.SearchTypeAll
+	}
+	if !domain.IsSearchType(kind) {
+		return nil, domain.ErrSearchQueryInvalid
+	}
+
+	results := &domain.SearchResults{}
+
+	if kind == domain.SearchTypeAll || kind == domain.SearchTypePosts {
+		posts, err := svc.searchPosts(viewer, query, limit, offset)
+		if err != nil {
+			return nil, err
+		}
+		results.Posts = posts
+	}
+
+	if kind == domain.SearchTypeAll || kind == domain.SearchTypeCreators {
+		creators, err := svc.searchRepo.SearchCreators(query, limit, offset)
+		if err != nil {
+			return nil, err
+		}
+		results.Creators = creators
+	}
+
+	if kind == domain.SearchTypeAll || kind == domain.SearchTypeCommunities {
+		communities, err := svc.searchRepo.SearchCommunities(query, headlineOptions, limit, offset)
+		if err != nil {
+			return nil, err
+		}
+		for i := range communities {
+			h := &communities[i].Highlight
+			h.Name = toHighlight(h.Name)
+			h.Description = toHighlight(h.Description)
+		}
+		results.Communities = communities
+	}
+
+	return results, nil
+}
+
+// searchPosts applies access policies to the matching posts. Readers passing them get a
+// snippet of the body around the match, the body itself is always dropped
+func (svc *searchService) searchPosts(viewer domain.Viewer, query string, limit, offset int) ([]domain.PostSearchResult, error) {
+
+	results, err := svc.searchRepo.SearchPosts(query, headlineOptions, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	posts := make([]domain.Post, len(results))
+	for i, result := range results {
+		posts[i] = result.Post
+	}
+
+	views, err := svc.postService.ViewPosts(viewer, posts)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := searchTerms(query)
+
+	for i := range results {
+		result := &results[i]
+		result.PostView = views[i]
+
+		result.Highlight.Title = toHighlight(result.Highlight.Title)
+		result.Highlight.Teaser = toHighlight(result.Highlight.Teaser)
+		if !result.Locked {
+			result.Highlight.Body = bodySnippet(result.Body, terms)
+		}
+
+		result.Body = ""
+		result.Attachments = nil
+	}
+
+	return results, nil
+}
+
+// toHighlight escapes a ts_headline fragment and turns its selectors into marks
+func toHighlight(headline string) string {
+	return highlighter.Replace(html.EscapeString(headline))
+}
+
+// searchTerms picks the words of a websearch query worth marking in a snippet, excluded
+// words and operators are dropped. Longer words are cut to a stem so inflected forms the
+// full-text match accepted are marked too
+func searchTerms(query string) [][]rune {
+
+	var terms [][]rune
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if strings.HasPrefix(word, "-") || word == "or" {
+			continue
+		}
+
+		term := []rune(strings.TrimFunc(word, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}))
+		if len(term) == 0 {
+			continue
+		}
+		if len(term) > 4 {
+			term = term[:max(4, len(term)-2)]
+		}
+
+		terms = append(terms, term)
+	}
+
+	return terms
+}
+
+// bodySnippet cuts the body around the first word starting with one of the terms and
+// marks every such word in the cut. Bodies without a match give no snippet
+func bodySnippet(body string, terms [][]rune) string {
+
+	text := []rune(strings.Join(strings.Fields(body), " "))
+
+	// lowering rune by rune keeps indexes of text and lower aligned
+	lower := make([]rune, len(text))
+	for i, r := range text {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	isWord := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+
+	matchAt := func(i int) bool {
+		if i > 0 && isWord(lower[i-1]) {
+			return false
+		}
+		for _, term := range terms {
+			if len(lower)-i >= len(term) && string(lower[i:i+len(term)]) == string(term) {
+				return true
+			}
+		}
+		return false
+	}
+
+	first := -1
+	for i := range lower {
+		if matchAt(i) {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return ""
+	}
+
+	start := max(0, first-searchSnippetBefore)
+	for start > 0 && start < first && text[start-1] != ' ' {
+		start++
+	}
+	end := min(len(text), first+searchSnippetAfter)
+	for end < len(text) && end > first && text[end] != ' ' {
+		end--
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+
+	for i := start; i < end; {
+		if !matchAt(i) {
+			b.WriteString(html.EscapeString(string(text[i])))
+			i++
+			continue
+		}
+
+		j := i
+		for j < end && isWord(text[j]) {
+			j++
+		}
+		b.WriteString("<mark>" + html.EscapeString(string(text[i:j])) + "</mark>")
+		i = max(j, i+1)
+	}
+
+	if end < len(text) {
+		b.WriteString("…")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/pkg/config"
+)
+
+// urlSigner issues download urls bound to what they download, the address they were
+// issued for and their expiry
+type urlSigner struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// newURLSigner signs with MediaURLSecret, or LinkSigningSecret when it is empty
+func newURLSigner(cfg *config.Config, ttl time.Duration) urlSigner {
+
+	secret := cfg.MediaURLSecret
+	if secret == "" {
+		secret = cfg.LinkSigningSecret
+	}
+
+	return urlSigner{secret: []byte(secret), ttl: ttl}
+}
+
+func (s urlSigner) expiry() time.Time {
+	return time.Now().Add(s.ttl).Truncate(time.Second)
+}
+
+// query holds the expires and sig params of a url for subject
+func (s urlSigner) query(subject, addr string, expiresAt time.Time) url.Values {
+
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("sig", s.sign(subject, addr, expires))
+
+	return query
+}
+
+// verify checks the expires and sig params of a url for subject
+func (s urlSigner) verify(subject, addr, expires, signature string) error {
+
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return domain.ErrMediaURLInvalid
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(s.sign(subject, addr, expires))) {
+		return domain.ErrMediaURLInvalid
+	}
+
+	return nil
+}
+
+func (s urlSigner) sign(subject, addr, expires string) string {
+
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(subject + "\n" + addr + "\n" + expires))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
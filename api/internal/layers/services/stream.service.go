@@ -0,0 +1,492 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/repositories"
+	"github.com/Xebec19/jibe/api/pkg/config"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/Xebec19/jibe/api/pkg/storage"
+	"github.com/Xebec19/jibe/api/pkg/transcode"
+)
+
+const (
+	// streamMaxAttempts is how often a video is packaged before it is failed for good
+	streamMaxAttempts = 3
+
+	// streamClaimTimeout is after how long a packaging claim is considered abandoned,
+	// long videos take a while to transcode
+	streamClaimTimeout = 2 * time.Hour
+
+	// streamAudioBitrate is the aac bitrate of every rendition in kbit/s
+	streamAudioBitrate = 128
+)
+
+// segmentPattern matches the segment names written by the transcoders, anything else
+// in a playlist is not served
+var segmentPattern = regexp.MustCompile(`^seg_[0-9]{5}\.ts$`)
+
+// streamTypes are the content types claimed for packaging
+var streamTypes = slices.Sorted(maps.Keys(domain.StreamedVideoTypes))
+
+type StreamService interface {
+	// ProcessPending claims up to limit pending videos and packages them one by one.
+	// It returns how many were claimed
+	ProcessPending(limit int) (int, error)
+
+	// MasterPlaylist lists the renditions of a video for a new playback session. The
+	// rendition playlists are signed for the viewer, who must pass the post's policy
+	MasterPlaylist(viewer domain.Viewer, id string) (string, error)
+
+	// MediaPlaylist verifies a signed rendition url and returns its playlist, with the
+	// segments and the key signed for the same session
+	MediaPlaylist(addr, id, rendition, session, expires, signature string) (string, error)
+
+	// OpenSegment verifies a signed segment url and opens the segment, which stays
+	// encrypted with the video's AES-128 key. The key is shared by every viewer, what
+	// binds a segment to a session is its signed url
+	OpenSegment(addr, id, rendition, segment, session, expires, signature string) (io.ReadCloser, error)
+
+	// SegmentKey verifies a signed key url and checks the post's policy again before the
+	// AES-128 key is handed out, so revoked access stops playback on the next key fetch
+	SegmentKey(viewer domain.Viewer, id, session, expires, signature string) ([]byte, error)
+}
+
+// NewStreamService packages videos with transcoder, which is nil when packaging is
+// disabled
+func NewStreamService(ctx context.Context, logger logger.Logger, cfg *config.Config, store storage.Storage, transcoder transcode.Transcoder, mediaRepo repositories.MediaRepository, mediaService MediaService, encryption EncryptionService) StreamService {
+
+	renditions := make([]transcode.Rendition, 0, len(cfg.HLSRenditions))
+	for _, height := range slices.Sorted(maps.Keys(cfg.HLSRenditions)) {
+		renditions = append(renditions, transcode.Rendition{
+			Name:         strconv.Itoa(height) + "p",
+			Height:       height,
+			VideoBitrate: cfg.HLSRenditions[height],
+			AudioBitrate: streamAudioBitrate,
+		})
+	}
+
+	return &streamService{
+		ctx:          ctx,
+		logger:       logger,
+		store:        store,
+		transcoder:   transcoder,
+		mediaRepo:    mediaRepo,
+		mediaService: mediaService,
+		encryption:   encryption,
+		renditions:   renditions,
+		segment:      cfg.HLSSegmentDuration,
+		signer:       newURLSigner(cfg, cfg.HLSSessionTTL),
+	}
+}
+
+type streamService struct {
+	ctx          context.Context
+	logger       logger.Logger
+	store        storage.Storage
+	transcoder   transcode.Transcoder
+	mediaRepo    repositories.MediaRepository
+	mediaService MediaService
+	encryption   EncryptionService
+	renditions   []transcode.Rendition
+	segment      time.Duration
+	signer       urlSigner
+}
+
+func (svc *streamService) ProcessPending(limit int) (int, error) {
+
+	if svc.transcoder == nil {
+		return 0, nil
+	}
+
+	staleBefore := time.Now().Add(-streamClaimTimeout)
+
+	failed, err := svc.mediaRepo.FailStaleProcessing(streamTypes, staleBefore, streamMaxAttempts)
+	if err != nil {
+		return 0, err
+	}
+	if failed > 0 {
+		svc.logger.Warn("Abandoned video packaging failed for good", "count", failed)
+	}
+
+	files, err := svc.mediaRepo.ClaimMediaToProcess(streamTypes, staleBefore, streamMaxAttempts, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, media := range files {
+		if err := svc.process(media); err != nil {
+			svc.logger.Warn("Video packaging failed", "media", media.ID, "attempt", media.ProcessingAttempts, "error", err)
+
+			// files without a video stream are not retried
+			attempts := streamMaxAttempts
+			if errors.Is(err, errUnprocessable) {
+				attempts = 0
+			}
+
+			if err := svc.mediaRepo.FailProcessing(media.ID, attempts); err != nil {
+				return len(files), err
+			}
+		}
+	}
+
+	return len(files), nil
+}
+
+// process transcodes the video in a scratch directory and uploads the segments, which
+// are only referenced once the stream is recorded
+func (svc *streamService) process(media domain.MediaFile) error {
+
+	dir, err := os.MkdirTemp("", "jibe-hls-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "source")
+	if err := svc.download(media, input); err != nil {
+		return err
+	}
+
+	probe, err := svc.transcoder.Probe(svc.ctx, input)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errUnprocessable, err)
+	}
+
+	key := make([]byte, transcode.KeySize)
+	iv := make([]byte, transcode.KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return err
+	}
+
+	outputs, err := svc.transcoder.Package(svc.ctx, input, filepath.Join(dir, "out"), transcode.Options{
+		Renditions:      svc.pickRenditions(probe),
+		SegmentDuration: svc.segment,
+		Key:             key,
+		IV:              iv,
+		// replaced by a signed key url whenever the playlist is served
+		KeyURI: "key",
+	})
+	if err != nil {
+		return err
+	}
+
+	prefix, err := mediaKey(media.OwnerAddress)
+	if err != nil {
+		return err
+	}
+
+	stream := domain.MediaStream{
+		MediaID:       media.ID,
+		StoragePrefix: prefix + "-hls",
+		Duration:      probe.Duration,
+	}
+
+	var created []string
+	discardCreated := func() {
+		for _, key := range created {
+			svc.discard(key)
+		}
+	}
+
+	for _, output := range outputs {
+		playlist, err := os.ReadFile(filepath.Join(output.Dir, transcode.PlaylistName))
+		if err != nil {
+			discardCreated()
+			return err
+		}
+
+		for _, segment := range transcode.Segments(string(playlist)) {
+			if !segmentPattern.MatchString(segment) {
+				discardCreated()
+				return fmt.Errorf("unexpected segment %q in rendition %s", segment, output.Name)
+			}
+
+			key := segmentKey(stream.StoragePrefix, output.Name, segment)
+			if err := svc.upload(filepath.Join(output.Dir, segment), key); err != nil {
+				discardCreated()
+				return err
+			}
+			created = append(created, key)
+		}
+
+		stream.Renditions = append(stream.Renditions, domain.StreamRendition{
+			Name:      output.Name,
+			Width:     output.Width,
+			Height:    output.Height,
+			Bandwidth: output.Bandwidth(),
+			Playlist:  string(playlist),
+		})
+	}
+
+	if stream.SealedKey, err = svc.encryption.Seal(media.DataKey, key, aadSegmentKey); err != nil {
+		discardCreated()
+		return err
+	}
+
+	replaced, err := svc.mediaRepo.FinishStream(media.StorageKey, stream)
+	if errors.Is(err, domain.ErrMediaNotFound) {
+		// deleted or re-encrypted meanwhile, a new claim packages the current object
+		discardCreated()
+		return nil
+	}
+	if err != nil {
+		discardCreated()
+		return err
+	}
+
+	if replaced != nil {
+		for _, key := range streamKeys(replaced) {
+			svc.discard(key)
+		}
+	}
+
+	return nil
+}
+
+// pickRenditions skips renditions taller than the source, videos smaller than every
+// rendition get the lowest one at their own height
+func (svc *streamService) pickRenditions(probe *transcode.Probe) []transcode.Rendition {
+
+	var picked []transcode.Rendition
+	for _, rendition := range svc.renditions {
+		if rendition.Height <= probe.Height {
+			picked = append(picked, rendition)
+		}
+	}
+
+	if len(picked) == 0 && len(svc.renditions) > 0 {
+		lowest := svc.renditions[0]
+		lowest.Height = max(2, probe.Height-probe.Height%2)
+		lowest.Name = strconv.Itoa(lowest.Height) + "p"
+		picked = append(picked, lowest)
+	}
+
+	return picked
+}
+
+func (svc *streamService) MasterPlaylist(viewer domain.Viewer, id string) (string, error) {
+
+	media, err := svc.mediaService.GetMedia(viewer, id)
+	if err != nil {
+		return "", err
+	}
+
+	stream, err := svc.readyStream(media)
+	if err != nil {
+		return "", err
+	}
+
+	session, err := randomHex(8)
+	if err != nil {
+		return "", err
+	}
+
+	expiresAt := svc.signer.expiry()
+
+	var playlist strings.Builder
+	playlist.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+
+	for _, rendition := range stream.Renditions {
+		fmt.Fprintf(&playlist, "#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d\n", rendition.Bandwidth, rendition.Width, rendition.Height)
+		playlist.WriteString(svc.streamURL(media.ID, rendition.Name+"/"+transcode.PlaylistName, session, viewer.Address, expiresAt))
+		playlist.WriteByte('\n')
+	}
+
+	return playlist.String(), nil
+}
+
+func (svc *streamService) MediaPlaylist(addr, id, rendition, session, expires, signature string) (string, error) {
+
+	if err := svc.signer.verify(streamSubject(id, rendition+"/"+transcode.PlaylistName, session), addr, expires, signature); err != nil {
+		return "", err
+	}
+
+	stream, err := svc.mediaRepo.GetStream(id)
+	if err != nil {
+		return "", err
+	}
+
+	r, ok := stream.Rendition(rendition)
+	if !ok {
+		return "", domain.ErrStreamNotFound
+	}
+
+	// segments expire with the playlist they were listed in
+	unix, _ := strconv.ParseInt(expires, 10, 64)
+	expiresAt := time.Unix(unix, 0)
+
+	keyURL := svc.streamURL(id, "key", session, addr, expiresAt)
+
+	return transcode.Rewrite(r.Playlist, keyURL, func(segment string) string {
+		return svc.streamURL(id, rendition+"/"+segment, session, addr, expiresAt)
+	}), nil
+}
+
+func (svc *streamService) OpenSegment(addr, id, rendition, segment, session, expires, signature string) (io.ReadCloser, error) {
+
+	if err := svc.signer.verify(streamSubject(id, rendition+"/"+segment, session), addr, expires, signature); err != nil {
+		return nil, err
+	}
+
+	if !segmentPattern.MatchString(segment) {
+		return nil, domain.ErrStreamNotFound
+	}
+
+	stream, err := svc.mediaRepo.GetStream(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := stream.Rendition(rendition); !ok {
+		return nil, domain.ErrStreamNotFound
+	}
+
+	file, _, err := svc.store.Get(svc.ctx, segmentKey(stream.StoragePrefix, rendition, segment))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, domain.ErrStreamNotFound
+	}
+
+	return file, err
+}
+
+func (svc *streamService) SegmentKey(viewer domain.Viewer, id, session, expires, signature string) ([]byte, error) {
+
+	if err := svc.signer.verify(streamSubject(id, "key", session), viewer.Address, expires, signature); err != nil {
+		return nil, err
+	}
+
+	media, err := svc.mediaService.GetMedia(viewer, id)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := svc.readyStream(media)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.encryption.Open(media.DataKey, stream.SealedKey, aadSegmentKey)
+}
+
+// readyStream returns the stream of a video which finished packaging
+func (svc *streamService) readyStream(media *domain.MediaFile) (*domain.MediaStream, error) {
+
+	if !domain.StreamedVideoTypes[media.ContentType] {
+		return nil, domain.ErrStreamNotFound
+	}
+
+	switch media.ProcessingStatus {
+	case domain.MediaProcessingReady:
+	case domain.MediaProcessingPending, domain.MediaProcessingProcessing:
+		return nil, domain.ErrMediaProcessing
+	default:
+		return nil, domain.ErrStreamNotFound
+	}
+
+	return svc.mediaRepo.GetStream(media.ID)
+}
+
+// streamURL signs a file of the stream, path is relative to its hls directory
+func (svc *streamService) streamURL(id, path, session, addr string, expiresAt time.Time) string {
+
+	query := svc.signer.query(streamSubject(id, path, session), addr, expiresAt)
+	query.Set("session", session)
+
+	return "/v1/media/" + id + "/hls/" + path + "?" + query.Encode()
+}
+
+// download decrypts the original of a video into a scratch file for the transcoder
+func (svc *streamService) download(media domain.MediaFile, path string) error {
+
+	file, _, err := svc.store.Get(svc.ctx, media.StorageKey)
+	if err != nil {
+		return err
+	}
+
+	content, err := svc.encryption.DecryptStream(media.DataKey, file, media.SizeBytes)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	defer content.Close()
+
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, content); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// upload stores a segment as written by the transcoder, it already is encrypted
+func (svc *streamService) upload(path, key string) error {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := svc.store.Put(svc.ctx, key, file, info.Size(), "video/mp2t"); err != nil {
+		svc.discard(key)
+		return err
+	}
+
+	return nil
+}
+
+func (svc *streamService) discard(key string) {
+
+	if err := svc.store.Delete(svc.ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		svc.logger.Warn("media object deletion failed", "key", key, "error", err)
+	}
+}
+
+// streamSubject binds a signed stream url to the file it serves and its session
+func streamSubject(id, path, session string) string {
+	return id + "/hls/" + path + "/" + session
+}
+
+func segmentKey(prefix, rendition, segment string) string {
+	return prefix + "/" + rendition + "/" + segment
+}
+
+// streamKeys lists the segment objects of a stream
+func streamKeys(stream *domain.MediaStream) []string {
+
+	var keys []string
+	for _, rendition := range stream.Renditions {
+		for _, segment := range transcode.Segments(rendition.Playlist) {
+			keys = append(keys, segmentKey(stream.StoragePrefix, rendition.Name, segment))
+		}
+	}
+
+	return keys
+}
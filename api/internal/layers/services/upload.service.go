@@ -0,0 +1,459 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/repositories"
+	"github.com/Xebec19/jibe/api/pkg/config"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/Xebec19/jibe/api/pkg/storage"
+)
+
+// ChecksumAlgorithms are the Upload-Checksum algorithms accepted on chunks
+var ChecksumAlgorithms = []string{"sha1", "sha256"}
+
+type UploadService interface {
+	// CreateUpload starts a resumable upload of the declared length for the owner
+	CreateUpload(owner string, input domain.UploadInput) (*domain.Upload, error)
+
+	GetUpload(owner, id string) (*domain.Upload, error)
+
+	// WriteChunk appends the bytes of r at offset, which must match the bytes received
+	// so far. A chunk failing its checksum is dropped. Once the last byte arrived the
+	// upload is handed over to media storage
+	WriteChunk(owner, id string, offset int64, checksum *domain.UploadChecksum, r io.Reader) (*domain.Upload, error)
+
+	// TerminateUpload drops an upload and the bytes received for it
+	TerminateUpload(owner, id string) error
+
+	// PurgeExpired removes up to limit expired uploads along with their chunks, and
+	// forgets up to limit expired completed ones. It returns how many were removed
+	PurgeExpired(limit int) (int, error)
+
+	// MaxSize is the largest upload length accepted
+	MaxSize() int64
+}
+
+func NewUploadService(ctx context.Context, logger logger.Logger, cfg *config.Config, store storage.Storage, uploadRepo repositories.UploadRepository, mediaService MediaService, encryption EncryptionService) UploadService {
+
+	return &uploadService{
+		ctx:          ctx,
+		logger:       logger,
+		store:        store,
+		uploadRepo:   uploadRepo,
+		mediaService: mediaService,
+		encryption:   encryption,
+		maxSize:      cfg.UploadMaxSize,
+		quota:        cfg.StorageQuota,
+		expiry:       cfg.UploadExpiry,
+	}
+}
+
+type uploadService struct {
+	ctx          context.Context
+	logger       logger.Logger
+	store        storage.Storage
+	uploadRepo   repositories.UploadRepository
+	mediaService MediaService
+	encryption   EncryptionService
+	maxSize      int64
+	quota        int64
+	expiry       time.Duration
+}
+
+func (svc *uploadService) CreateUpload(owner string, input domain.UploadInput) (*domain.Upload, error) {
+
+	if input.Length <= 0 {
+		return nil, domain.ErrMediaEmpty
+	}
+
+	if svc.maxSize > 0 && input.Length > svc.maxSize {
+		return nil, domain.ErrUploadTooLarge
+	}
+
+	input.Filename = cleanFilename(input.Filename)
+
+	dataKey, err := svc.encryption.NewDataKey()
+	if err != nil {
+		return nil, err
+	}
+	input.DataKey = dataKey
+
+	return svc.uploadRepo.CreateUpload(owner, input, svc.quota, svc.expiresAt())
+}
+
+func (svc *uploadService) GetUpload(owner, id string) (*domain.Upload, error) {
+
+	upload, err := svc.uploadRepo.GetUpload(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if upload.OwnerAddress != owner {
+		return nil, domain.ErrUploadForbidden
+	}
+
+	if !upload.IsComplete() && time.Now().UTC().After(upload.ExpiresAt) {
+		return nil, domain.ErrUploadExpired
+	}
+
+	return upload, nil
+}
+
+func (svc *uploadService) WriteChunk(owner, id string, offset int64, checksum *domain.UploadChecksum, r io.Reader) (*domain.Upload, error) {
+
+	upload, err := svc.GetUpload(owner, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if offset != upload.Offset {
+		return nil, domain.ErrUploadOffsetMismatch
+	}
+
+	if upload.IsComplete() {
+		return upload, nil
+	}
+
+	if offset < upload.Length {
+		if err := svc.appendChunk(upload, checksum, r); err != nil {
+			return nil, err
+		}
+	}
+
+	// a retried final request completes an upload whose handover failed before
+	if upload.Offset == upload.Length {
+		if err := svc.complete(upload); err != nil {
+			return nil, err
+		}
+	}
+
+	return upload, nil
+}
+
+func (svc *uploadService) TerminateUpload(owner, id string) error {
+
+	upload, err := svc.uploadRepo.GetUpload(id)
+	if err != nil {
+		return err
+	}
+
+	if upload.OwnerAddress != owner {
+		return domain.ErrUploadForbidden
+	}
+
+	// an upload being completed keeps its chunks until the import is done
+	unlock, err := svc.uploadRepo.LockUpload(upload.ID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return svc.remove(upload.ID)
+}
+
+func (svc *uploadService) PurgeExpired(limit int) (int, error) {
+
+	uploads, err := svc.uploadRepo.ListExpiredUploads(limit)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, upload := range uploads {
+		if err := svc.remove(upload.ID); err != nil {
+			return i, err
+		}
+	}
+
+	// completed uploads only keep the record, their chunks went with the import
+	completed, err := svc.uploadRepo.DeleteExpiredCompletedUploads(limit)
+	if err != nil {
+		return len(uploads), err
+	}
+
+	return len(uploads) + completed, nil
+}
+
+func (svc *uploadService) MaxSize() int64 {
+	return svc.maxSize
+}
+
+// appendChunk stores the bytes of one request as a chunk object and advances the
+// upload past them. When the client goes away mid request the bytes received so far are
+// kept, so it can resume from there
+func (svc *uploadService) appendChunk(upload *domain.Upload, checksum *domain.UploadChecksum, r io.Reader) error {
+
+	var digest hash.Hash
+	if checksum != nil {
+		var err error
+		if digest, err = newChecksumHash(checksum.Algorithm); err != nil {
+			return err
+		}
+	}
+
+	body := &chunkReader{
+		r: r,
+		// one byte past the remaining length tells an overlong chunk apart
+		remaining: upload.Length - upload.Offset + 1,
+		digest:    digest,
+	}
+
+	suffix, err := randomHex(8)
+	if err != nil {
+		return err
+	}
+	// racing requests at the same offset must not overwrite each other's chunk
+	key := "uploads/" + upload.ID + "/" + strconv.FormatInt(upload.Offset, 10) + "-" + suffix
+
+	sealed, err := svc.encryption.EncryptStream(upload.DataKey, body)
+	if err != nil {
+		return err
+	}
+
+	if err := svc.store.Put(svc.ctx, key, sealed, -1, "application/octet-stream"); err != nil {
+		svc.discard(key)
+		return fmt.Errorf("upload chunk storage failed %w", err)
+	}
+
+	switch {
+	case body.n > upload.Length-upload.Offset:
+		svc.discard(key)
+		return domain.ErrUploadExceedsLength
+	case checksum != nil && (body.interrupted != nil || !bytes.Equal(digest.Sum(nil), checksum.Sum)):
+		svc.discard(key)
+		return domain.ErrUploadChecksumMismatch
+	case body.n == 0:
+		svc.discard(key)
+		return body.interrupted
+	}
+
+	err = svc.uploadRepo.AppendChunk(upload.ID, domain.UploadChunk{
+		Offset:     upload.Offset,
+		StorageKey: key,
+		SizeBytes:  body.n,
+	}, svc.expiresAt())
+	if err != nil {
+		svc.discard(key)
+		return err
+	}
+
+	upload.Offset += body.n
+
+	if body.interrupted != nil {
+		svc.logger.Info("upload chunk interrupted", "upload", upload.ID, "offset", upload.Offset, "error", body.interrupted)
+	}
+
+	return nil
+}
+
+// complete hands the assembled chunks to media storage. Content which media storage
+// rejects ends the upload, other failures leave it for the client to retry. Racing or
+// retried final requests wait for each other, the later ones find the upload completed
+func (svc *uploadService) complete(upload *domain.Upload) error {
+
+	unlock, err := svc.uploadRepo.LockUpload(upload.ID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	stored, err := svc.uploadRepo.GetUpload(upload.ID)
+	if err != nil {
+		return err
+	}
+
+	if stored.IsComplete() {
+		*upload = *stored
+		return nil
+	}
+
+	chunks, err := svc.uploadRepo.ListChunks(upload.ID)
+	if err != nil {
+		return err
+	}
+
+	postID := ""
+	if upload.PostID != nil {
+		postID = *upload.PostID
+	}
+
+	content := &chunkSequence{ctx: svc.ctx, store: svc.store, encryption: svc.encryption, dataKey: upload.DataKey, chunks: chunks}
+	defer content.Close()
+
+	media, err := svc.mediaService.Import(upload.OwnerAddress, domain.MediaUpload{
+		Filename: upload.Filename,
+		PostID:   postID,
+	}, content)
+
+	if errors.Is(err, domain.ErrUnsupportedMediaType) || errors.Is(err, domain.ErrMediaEmpty) || errors.Is(err, domain.ErrPostNotFound) || errors.Is(err, domain.ErrPostForbidden) {
+		if rmErr := svc.remove(upload.ID); rmErr != nil {
+			svc.logger.Warn("rejected upload removal failed", "upload", upload.ID, "error", rmErr)
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := svc.uploadRepo.CompleteUpload(upload.ID, media.ID); err != nil {
+		// the upload was terminated meanwhile, its media file would be left unreferenced
+		if errors.Is(err, domain.ErrUploadNotFound) {
+			if rmErr := svc.mediaService.DeleteMedia(upload.OwnerAddress, media.ID); rmErr != nil {
+				svc.logger.Warn("orphaned upload media removal failed", "upload", upload.ID, "media", media.ID, "error", rmErr)
+			}
+		}
+		return err
+	}
+
+	for _, chunk := range chunks {
+		svc.discard(chunk.StorageKey)
+	}
+
+	now := time.Now().UTC()
+	upload.MediaID = &media.ID
+	upload.CompletedAt = &now
+
+	return nil
+}
+
+// remove deletes the chunk objects of an upload and then its record
+func (svc *uploadService) remove(id string) error {
+
+	chunks, err := svc.uploadRepo.ListChunks(id)
+	if err != nil {
+		return err
+	}
+
+	for _, chunk := range chunks {
+		svc.discard(chunk.StorageKey)
+	}
+
+	return svc.uploadRepo.DeleteUpload(id)
+}
+
+func (svc *uploadService) discard(key string) {
+
+	if err := svc.store.Delete(svc.ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		svc.logger.Warn("upload chunk deletion failed", "key", key, "error", err)
+	}
+}
+
+func (svc *uploadService) expiresAt() time.Time {
+	return time.Now().UTC().Add(svc.expiry)
+}
+
+func newChecksumHash(algorithm string) (hash.Hash, error) {
+
+	switch algorithm {
+	case "sha1":
+		return sha1.New(), nil
+	case "sha256":
+		return sha256.New(), nil
+	default:
+		return nil, domain.ErrUnsupportedChecksum
+	}
+}
+
+// chunkReader reads at most remaining bytes of a request body. A failing body ends the
+// chunk instead of failing it, the error is kept in interrupted
+type chunkReader struct {
+	r           io.Reader
+	remaining   int64
+	digest      hash.Hash
+	n           int64
+	interrupted error
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+
+	if c.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	c.remaining -= int64(n)
+	if c.digest != nil {
+		c.digest.Write(p[:n])
+	}
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		c.interrupted = err
+		return n, io.EOF
+	}
+
+	return n, err
+}
+
+// chunkSequence reads the chunk objects of an upload back to back, opening each one
+// only when the previous one is drained
+type chunkSequence struct {
+	ctx        context.Context
+	store      storage.Storage
+	encryption EncryptionService
+	dataKey    *domain.WrappedKey
+	chunks     []domain.UploadChunk
+	current    io.ReadCloser
+}
+
+func (s *chunkSequence) Read(p []byte) (int, error) {
+
+	for {
+		if s.current == nil {
+			if len(s.chunks) == 0 {
+				return 0, io.EOF
+			}
+
+			chunk := s.chunks[0]
+			s.chunks = s.chunks[1:]
+
+			file, _, err := s.store.Get(s.ctx, chunk.StorageKey)
+			if err != nil {
+				return 0, fmt.Errorf("upload chunk %d unreadable %w", chunk.Offset, err)
+			}
+
+			content, err := s.encryption.DecryptStream(s.dataKey, file, chunk.SizeBytes)
+			if err != nil {
+				file.Close()
+				return 0, err
+			}
+			s.current = content
+		}
+
+		n, err := s.current.Read(p)
+		if errors.Is(err, io.EOF) {
+			s.current.Close()
+			s.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+
+		return n, err
+	}
+}
+
+func (s *chunkSequence) Close() error {
+
+	if s.current == nil {
+		return nil
+	}
+
+	err := s.current.Close()
+	s.current = nil
+	return err
+}
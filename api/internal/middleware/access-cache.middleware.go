@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// AccessCacheHeader set to "bypass" makes access policies evaluate against fresh chain
+// state for the request, useful when debugging why a reader is locked out
+const AccessCacheHeader = "X-Access-Cache"
+
+const bypassAccessCacheKey contextKey = "bypass_access_cache"
+
+// AccessCacheBypass honours the AccessCacheHeader when enabled, otherwise the header
+// is ignored
+func AccessCacheBypass(enabled bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if enabled && strings.EqualFold(r.Header.Get(AccessCacheHeader), "bypass") {
+				r = r.WithContext(context.WithValue(r.Context(), bypassAccessCacheKey, true))
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// BypassAccessCache reports if cached access decisions must be skipped for the request
+func BypassAccessCache(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassAccessCacheKey).(bool)
+	return bypass
+}
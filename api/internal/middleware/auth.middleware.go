@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/response"
+	"github.com/Xebec19/jibe/api/pkg/jwt"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type contextKey string
+
+const ethAddressKey contextKey = "eth_address"
+
+// Authenticate reads the access_token cookie set during SIWE verification, validates it
+// and stores the checksummed address of the caller in the request context
+func Authenticate(secret string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			addr, ok := addressFromRequest(r, secret)
+			if !ok {
+				response.Error(w, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ethAddressKey, addr)))
+		})
+	}
+}
+
+// OptionalAuthenticate behaves like Authenticate but lets anonymous requests through,
+// useful for public endpoints which show more data to signed in users
+func OptionalAuthenticate(secret string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if addr, ok := addressFromRequest(r, secret); ok {
+				r = r.WithContext(context.WithValue(r.Context(), ethAddressKey, addr))
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GetEthAddress returns the address of the authenticated caller, or an empty string
+// for anonymous requests
+func GetEthAddress(ctx context.Context) string {
+	addr, _ := ctx.Value(ethAddressKey).(string)
+	return addr
+}
+
+func addressFromRequest(r *http.Request, secret string) (string, bool) {
+	cookie, err := r.Cookie("access_token")
+	if err != nil || cookie.Value == "" {
+		return "", false
+	}
+
+	claims, err := jwt.ValidateToken(cookie.Value, []byte(secret))
+	if err != nil {
+		return "", false
+	}
+
+	sub, err := claims.GetSubject()
+	if err != nil || !common.IsHexAddress(sub) {
+		return "", false
+	}
+
+	return common.HexToAddress(sub).Hex(), true
+}
@@ -1,6 +1,9 @@
 package middleware
 
 import (
+	"bufio"
+	"errors"
+	"net"
 	"net/http"
 	"time"
 
@@ -37,3 +40,21 @@ func HttpLogger(logger logger.Logger) func(next http.Handler) http.Handler {
 		})
 	}
 }
+
+// Unwrap exposes the wrapped writer to http.ResponseController, streaming handlers
+// flush and extend their write deadline through it
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Hijack hands the connection over to websocket handlers
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+
+	hijacker, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+
+	rw.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
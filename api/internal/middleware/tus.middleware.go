@@ -0,0 +1,22 @@
+package middleware
+
+import "net/http"
+
+// TusVersion is the tus protocol version spoken by the upload endpoints
+const TusVersion = "1.0.0"
+
+// TusResumable tags every response with the protocol version and rejects requests
+// speaking another one. OPTIONS requests are exempt so clients can discover versions
+func TusResumable(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Tus-Resumable", TusVersion)
+
+		if r.Method != http.MethodOptions && r.Header.Get("Tus-Resumable") != TusVersion {
+			w.Header().Set("Tus-Version", TusVersion)
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,40 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Response is the envelope every json endpoint answers with
+type Response struct {
+	Status  bool
+	Message string
+	Data    interface{}
+}
+
+// JSON writes a successful response carrying payload
+func JSON(w http.ResponseWriter, status int, msg string, payload interface{}) {
+	write(w, status, &Response{
+		Status:  true,
+		Message: msg,
+		Data:    payload,
+	})
+}
+
+// Error writes a failed response, handlers and middlewares share it so clients parse
+// a single shape
+func Error(w http.ResponseWriter, status int, msg string) {
+	write(w, status, &Response{
+		Status:  false,
+		Message: msg,
+		Data:    nil,
+	})
+}
+
+func write(w http.ResponseWriter, status int, data *Response) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/layers/container"
+	"github.com/Xebec19/jibe/api/internal/layers/controllers"
+	"github.com/Xebec19/jibe/api/internal/middleware"
+	"github.com/gorilla/mux"
+)
+
+func registerAccessRoutes(r *mux.Router, c container.Container) {
+
+	accessController := controllers.NewAccessController(&c.Logger, c.Validator, c.AccessService)
+
+	authenticate := middleware.Authenticate(c.Cfg.JwtSecret)
+
+	accessApi := r.PathPrefix("/v1/access").Subrouter()
+
+	accessApi.Use(middleware.BodySizeLimit(c.Cfg.MaxBodySizeAllowed))
+
+	accessApi.Handle("/preview", authenticate(http.HandlerFunc(accessController.PreviewPolicy))).Methods("POST")
+}
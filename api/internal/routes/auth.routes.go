@@ -9,7 +9,7 @@ import (
 
 func registerAuthRoutes(r *mux.Router, c container.Container) {
 
-	authController := controllers.NewAuthController(&c.Logger, &c.Cfg, c.Validator, c.AuthService)
+	authController := controllers.NewAuthController(&c.Logger, &c.Cfg, c.Validator, c.AuthService, c.InviteService)
 
 	authApi := r.PathPrefix("/v1/auth").Subrouter()
 
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/layers/container"
+	"github.com/Xebec19/jibe/api/internal/layers/controllers"
+	"github.com/Xebec19/jibe/api/internal/middleware"
+	"github.com/gorilla/mux"
+)
+
+func registerChatRoutes(r *mux.Router, c container.Container) {
+
+	chatController := controllers.NewChatController(&c.Logger, &c.Cfg, c.ChatService)
+
+	authenticate := middleware.Authenticate(c.Cfg.JwtSecret)
+
+	chatApi := r.PathPrefix("/v1/communities/{id}/chat").Subrouter()
+
+	chatApi.Handle("", authenticate(http.HandlerFunc(chatController.Connect))).Methods("GET")
+
+	chatApi.Handle("/messages", authenticate(http.HandlerFunc(chatController.History))).Methods("GET")
+}
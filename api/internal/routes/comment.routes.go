@@ -0,0 +1,48 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/layers/container"
+	"github.com/Xebec19/jibe/api/internal/layers/controllers"
+	"github.com/Xebec19/jibe/api/internal/middleware"
+	"github.com/gorilla/mux"
+)
+
+func registerCommentRoutes(r *mux.Router, c container.Container) {
+
+	commentController := controllers.NewCommentController(&c.Logger, c.Validator, c.CommentService)
+
+	authenticate := middleware.Authenticate(c.Cfg.JwtSecret)
+	optionalAuthenticate := middleware.OptionalAuthenticate(c.Cfg.JwtSecret)
+
+	postApi := r.PathPrefix("/v1/posts/{id}").Subrouter()
+
+	postApi.Use(middleware.BodySizeLimit(c.Cfg.MaxBodySizeAllowed))
+
+	postApi.Handle("/comments", optionalAuthenticate(http.HandlerFunc(commentController.ListComments))).Methods("GET")
+
+	postApi.Handle("/comments", authenticate(http.HandlerFunc(commentController.CreateComment))).Methods("POST")
+
+	postApi.Handle("/reactions", optionalAuthenticate(http.HandlerFunc(commentController.ListPostReactions))).Methods("GET")
+
+	postApi.Handle("/reactions/{emoji}", authenticate(http.HandlerFunc(commentController.AddPostReaction))).Methods("PUT")
+
+	postApi.Handle("/reactions/{emoji}", authenticate(http.HandlerFunc(commentController.RemovePostReaction))).Methods("DELETE")
+
+	commentApi := r.PathPrefix("/v1/comments").Subrouter()
+
+	commentApi.Use(middleware.BodySizeLimit(c.Cfg.MaxBodySizeAllowed))
+
+	commentApi.Handle("/{id}", authenticate(http.HandlerFunc(commentController.UpdateComment))).Methods("PUT")
+
+	commentApi.Handle("/{id}", authenticate(http.HandlerFunc(commentController.DeleteComment))).Methods("DELETE")
+
+	commentApi.Handle("/{id}/restore", authenticate(http.HandlerFunc(commentController.RestoreComment))).Methods("POST")
+
+	commentApi.Handle("/{id}/replies", optionalAuthenticate(http.HandlerFunc(commentController.ListReplies))).Methods("GET")
+
+	commentApi.Handle("/{id}/reactions/{emoji}", authenticate(http.HandlerFunc(commentController.AddCommentReaction))).Methods("PUT")
+
+	commentApi.Handle("/{id}/reactions/{emoji}", authenticate(http.HandlerFunc(commentController.RemoveCommentReaction))).Methods("DELETE")
+}
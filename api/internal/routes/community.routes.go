@@ -0,0 +1,44 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/layers/container"
+	"github.com/Xebec19/jibe/api/internal/layers/controllers"
+	"github.com/Xebec19/jibe/api/internal/middleware"
+	"github.com/gorilla/mux"
+)
+
+func registerCommunityRoutes(r *mux.Router, c container.Container) {
+
+	communityController := controllers.NewCommunityController(&c.Logger, c.Validator, c.CommunityService, c.HandleService)
+
+	authenticate := middleware.Authenticate(c.Cfg.JwtSecret)
+	optionalAuthenticate := middleware.OptionalAuthenticate(c.Cfg.JwtSecret)
+
+	communityApi := r.PathPrefix("/v1/communities").Subrouter()
+
+	communityApi.Use(middleware.BodySizeLimit(c.Cfg.MaxBodySizeAllowed))
+
+	communityApi.Handle("", authenticate(http.HandlerFunc(communityController.ListCommunities))).Methods("GET")
+
+	communityApi.Handle("", authenticate(http.HandlerFunc(communityController.CreateCommunity))).Methods("POST")
+
+	communityApi.Handle("/{id}", optionalAuthenticate(http.HandlerFunc(communityController.GetCommunity))).Methods("GET")
+
+	communityApi.Handle("/{id}", authenticate(http.HandlerFunc(communityController.UpdateCommunity))).Methods("PUT")
+
+	communityApi.Handle("/{id}", authenticate(http.HandlerFunc(communityController.DeleteCommunity))).Methods("DELETE")
+
+	communityApi.Handle("/{id}/join", authenticate(http.HandlerFunc(communityController.Join))).Methods("POST")
+
+	communityApi.Handle("/{id}/leave", authenticate(http.HandlerFunc(communityController.Leave))).Methods("POST")
+
+	communityApi.Handle("/{id}/members", optionalAuthenticate(http.HandlerFunc(communityController.ListMembers))).Methods("GET")
+
+	communityApi.Handle("/{id}/members", authenticate(http.HandlerFunc(communityController.AddMember))).Methods("POST")
+
+	communityApi.Handle("/{id}/members/{member}/role", authenticate(http.HandlerFunc(communityController.SetRole))).Methods("PUT")
+
+	communityApi.Handle("/{id}/members/{member}", authenticate(http.HandlerFunc(communityController.RemoveMember))).Methods("DELETE")
+}
@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/layers/container"
+	"github.com/Xebec19/jibe/api/internal/layers/controllers"
+	"github.com/Xebec19/jibe/api/internal/middleware"
+	"github.com/gorilla/mux"
+)
+
+func registerEmailRoutes(r *mux.Router, c container.Container) {
+
+	emailController := controllers.NewEmailController(&c.Logger, c.Validator, c.EmailService)
+
+	authenticate := middleware.Authenticate(c.Cfg.JwtSecret)
+
+	emailApi := r.PathPrefix("/v1/email").Subrouter()
+
+	emailApi.Use(middleware.BodySizeLimit(c.Cfg.MaxBodySizeAllowed))
+
+	emailApi.Handle("", authenticate(http.HandlerFunc(emailController.GetEmail))).Methods("GET")
+
+	emailApi.Handle("", authenticate(http.HandlerFunc(emailController.SetEmail))).Methods("PUT")
+
+	emailApi.Handle("", authenticate(http.HandlerFunc(emailController.RemoveEmail))).Methods("DELETE")
+
+	emailApi.Handle("/digest", authenticate(http.HandlerFunc(emailController.SetDigest))).Methods("PUT")
+
+	// the links mailed out work without a session
+	emailApi.HandleFunc("/verify", emailController.VerifyEmail).Methods("POST")
+
+	emailApi.HandleFunc("/unsubscribe", emailController.Unsubscribe).Methods("POST")
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/layers/container"
+	"github.com/Xebec19/jibe/api/internal/layers/controllers"
+	"github.com/Xebec19/jibe/api/internal/middleware"
+	"github.com/gorilla/mux"
+)
+
+func registerFeedRoutes(r *mux.Router, c container.Container) {
+
+	feedController := controllers.NewFeedController(&c.Logger, c.FeedService)
+
+	authenticate := middleware.Authenticate(c.Cfg.JwtSecret)
+
+	feedApi := r.PathPrefix("/v1/feed").Subrouter()
+
+	feedApi.Handle("", authenticate(http.HandlerFunc(feedController.GetFeed))).Methods("GET")
+}
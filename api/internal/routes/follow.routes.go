@@ -0,0 +1,43 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/layers/container"
+	"github.com/Xebec19/jibe/api/internal/layers/controllers"
+	"github.com/Xebec19/jibe/api/internal/middleware"
+	"github.com/gorilla/mux"
+)
+
+func registerFollowRoutes(r *mux.Router, c container.Container) {
+
+	followController := controllers.NewFollowController(&c.Logger, c.Validator, c.FollowService)
+
+	authenticate := middleware.Authenticate(c.Cfg.JwtSecret)
+	optionalAuthenticate := middleware.OptionalAuthenticate(c.Cfg.JwtSecret)
+
+	accountApi := r.PathPrefix("/v1/accounts").Subrouter()
+
+	accountApi.Use(middleware.BodySizeLimit(c.Cfg.MaxBodySizeAllowed))
+
+	// routes of the viewer go first so "me" is not taken for an account
+	accountApi.Handle("/me/blocks", authenticate(http.HandlerFunc(followController.ListBlocks))).Methods("GET")
+
+	accountApi.Handle("/me/mutes", authenticate(http.HandlerFunc(followController.ListMutes))).Methods("GET")
+
+	accountApi.Handle("/me/following/import", authenticate(http.HandlerFunc(followController.Import))).Methods("POST")
+
+	accountApi.Handle("/{account}", optionalAuthenticate(http.HandlerFunc(followController.GetStats))).Methods("GET")
+
+	accountApi.HandleFunc("/{account}/followers", followController.ListFollowers).Methods("GET")
+
+	accountApi.HandleFunc("/{account}/following", followController.ListFollowing).Methods("GET")
+
+	accountApi.Handle("/{account}/follow", authenticate(http.HandlerFunc(followController.Follow))).Methods("POST")
+
+	accountApi.Handle("/{account}/follow", authenticate(http.HandlerFunc(followController.Unfollow))).Methods("DELETE")
+
+	accountApi.Handle("/{account}/{kind:block|mute}", authenticate(http.HandlerFunc(followController.Restrict))).Methods("POST")
+
+	accountApi.Handle("/{account}/{kind:block|mute}", authenticate(http.HandlerFunc(followController.Unrestrict))).Methods("DELETE")
+}
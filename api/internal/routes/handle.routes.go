@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/layers/container"
+	"github.com/Xebec19/jibe/api/internal/layers/controllers"
+	"github.com/Xebec19/jibe/api/internal/middleware"
+	"github.com/gorilla/mux"
+)
+
+func registerHandleRoutes(r *mux.Router, c container.Container) {
+
+	handleController := controllers.NewHandleController(&c.Logger, c.Validator, c.HandleService)
+
+	authenticate := middleware.Authenticate(c.Cfg.JwtSecret)
+	optionalAuthenticate := middleware.OptionalAuthenticate(c.Cfg.JwtSecret)
+
+	handleApi := r.PathPrefix("/v1/handles").Subrouter()
+
+	handleApi.Use(middleware.BodySizeLimit(c.Cfg.MaxBodySizeAllowed))
+
+	handleApi.Handle("", authenticate(http.HandlerFunc(handleController.ClaimHandle))).Methods("POST")
+
+	handleApi.Handle("/{handle}/availability", optionalAuthenticate(http.HandlerFunc(handleController.CheckAvailability))).Methods("GET")
+
+	handleApi.HandleFunc("/{handle}/history", handleController.GetHistory).Methods("GET")
+
+	handleApi.HandleFunc("/{handle}", handleController.ResolveHandle).Methods("GET")
+}
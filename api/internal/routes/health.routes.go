@@ -8,9 +8,11 @@ import (
 
 func registerHealthRoutes(r *mux.Router, c container.Container) {
 
-	healthController := controllers.NewHealthController(&c.Logger)
+	healthController := controllers.NewHealthController(&c.Logger, c.AccessService, c.ChainReader)
 
 	api := r.PathPrefix("/health").Subrouter()
 
 	api.HandleFunc("/", healthController.GetHealthCheckpoint).Methods("GET")
+
+	api.HandleFunc("/metrics", healthController.GetMetrics).Methods("GET")
 }
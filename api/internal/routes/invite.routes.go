@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/layers/container"
+	"github.com/Xebec19/jibe/api/internal/layers/controllers"
+	"github.com/Xebec19/jibe/api/internal/middleware"
+	"github.com/gorilla/mux"
+)
+
+func registerInviteRoutes(r *mux.Router, c container.Container) {
+
+	inviteController := controllers.NewInviteController(&c.Logger, c.Validator, c.InviteService)
+
+	authenticate := middleware.Authenticate(c.Cfg.JwtSecret)
+
+	communityApi := r.PathPrefix("/v1/communities/{id}/invites").Subrouter()
+
+	communityApi.Use(middleware.BodySizeLimit(c.Cfg.MaxBodySizeAllowed))
+
+	communityApi.Handle("", authenticate(http.HandlerFunc(inviteController.ListInvites))).Methods("GET")
+
+	communityApi.Handle("", authenticate(http.HandlerFunc(inviteController.CreateInvite))).Methods("POST")
+
+	communityApi.Handle("/{inviteId}", authenticate(http.HandlerFunc(inviteController.RevokeInvite))).Methods("DELETE")
+
+	inviteApi := r.PathPrefix("/v1/invites").Subrouter()
+
+	inviteApi.Use(middleware.BodySizeLimit(c.Cfg.MaxBodySizeAllowed))
+
+	inviteApi.HandleFunc("/{token}", inviteController.PreviewInvite).Methods("GET")
+
+	inviteApi.Handle("/{token}/redeem", authenticate(http.HandlerFunc(inviteController.RedeemInvite))).Methods("POST")
+}
@@ -0,0 +1,49 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/layers/container"
+	"github.com/Xebec19/jibe/api/internal/layers/controllers"
+	"github.com/Xebec19/jibe/api/internal/middleware"
+	"github.com/gorilla/mux"
+)
+
+// multipartOverhead leaves room for form boundaries and fields around the file
+const multipartOverhead = 64 * 1024
+
+func registerMediaRoutes(r *mux.Router, c container.Container) {
+
+	mediaController := controllers.NewMediaController(&c.Logger, c.MediaService)
+	streamController := controllers.NewStreamController(&c.Logger, c.StreamService)
+
+	authenticate := middleware.Authenticate(c.Cfg.JwtSecret)
+	optionalAuthenticate := middleware.OptionalAuthenticate(c.Cfg.JwtSecret)
+
+	// uploads carry their own limit instead of the JSON body limit
+	uploadLimit := middleware.BodySizeLimit(c.Cfg.MediaMaxUploadSize + multipartOverhead)
+	jsonLimit := middleware.BodySizeLimit(c.Cfg.MaxBodySizeAllowed)
+
+	mediaApi := r.PathPrefix("/v1/media").Subrouter()
+
+	mediaApi.Handle("", uploadLimit(authenticate(http.HandlerFunc(mediaController.UploadMultipart)))).Methods("POST")
+
+	mediaApi.Handle("", uploadLimit(authenticate(http.HandlerFunc(mediaController.UploadStream)))).Methods("PUT")
+
+	mediaApi.Handle("/{id}", jsonLimit(optionalAuthenticate(http.HandlerFunc(mediaController.GetMedia)))).Methods("GET")
+
+	mediaApi.Handle("/{id}", jsonLimit(authenticate(http.HandlerFunc(mediaController.DeleteMedia)))).Methods("DELETE")
+
+	mediaApi.Handle("/{id}/url", jsonLimit(optionalAuthenticate(http.HandlerFunc(mediaController.SignURL)))).Methods("GET")
+
+	mediaApi.Handle("/{id}/download", jsonLimit(optionalAuthenticate(http.HandlerFunc(mediaController.Download)))).Methods("GET")
+
+	// HLS playback, the rendition playlist route has to come before the segments
+	mediaApi.Handle("/{id}/hls/master.m3u8", jsonLimit(optionalAuthenticate(http.HandlerFunc(streamController.MasterPlaylist)))).Methods("GET")
+
+	mediaApi.Handle("/{id}/hls/key", jsonLimit(optionalAuthenticate(http.HandlerFunc(streamController.Key)))).Methods("GET")
+
+	mediaApi.Handle("/{id}/hls/{rendition}/index.m3u8", jsonLimit(optionalAuthenticate(http.HandlerFunc(streamController.MediaPlaylist)))).Methods("GET")
+
+	mediaApi.Handle("/{id}/hls/{rendition}/{segment}", jsonLimit(optionalAuthenticate(http.HandlerFunc(streamController.Segment)))).Methods("GET")
+}
@@ -0,0 +1,37 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/layers/container"
+	"github.com/Xebec19/jibe/api/internal/layers/controllers"
+	"github.com/Xebec19/jibe/api/internal/middleware"
+	"github.com/gorilla/mux"
+)
+
+func registerNotificationRoutes(r *mux.Router, c container.Container) {
+
+	notificationController := controllers.NewNotificationController(&c.Logger, c.Validator, c.NotificationService)
+
+	authenticate := middleware.Authenticate(c.Cfg.JwtSecret)
+
+	notificationApi := r.PathPrefix("/v1/notifications").Subrouter()
+
+	notificationApi.Use(middleware.BodySizeLimit(c.Cfg.MaxBodySizeAllowed))
+
+	notificationApi.Handle("", authenticate(http.HandlerFunc(notificationController.ListNotifications))).Methods("GET")
+
+	notificationApi.Handle("/stream", authenticate(http.HandlerFunc(notificationController.Stream))).Methods("GET")
+
+	notificationApi.Handle("/unread-count", authenticate(http.HandlerFunc(notificationController.CountUnread))).Methods("GET")
+
+	notificationApi.Handle("/preferences", authenticate(http.HandlerFunc(notificationController.GetPreferences))).Methods("GET")
+
+	notificationApi.Handle("/preferences", authenticate(http.HandlerFunc(notificationController.UpdatePreferences))).Methods("PUT")
+
+	notificationApi.Handle("/read", authenticate(http.HandlerFunc(notificationController.MarkAllRead))).Methods("POST")
+
+	notificationApi.Handle("/{id}/read", authenticate(http.HandlerFunc(notificationController.MarkRead))).Methods("POST")
+
+	notificationApi.Handle("/{id}/unread", authenticate(http.HandlerFunc(notificationController.MarkUnread))).Methods("POST")
+}
@@ -0,0 +1,54 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/layers/container"
+	"github.com/Xebec19/jibe/api/internal/layers/controllers"
+	"github.com/Xebec19/jibe/api/internal/middleware"
+	"github.com/gorilla/mux"
+)
+
+func registerPostRoutes(r *mux.Router, c container.Container) {
+
+	postController := controllers.NewPostController(&c.Logger, c.Validator, c.PostService, c.HandleService, c.MediaService)
+
+	authenticate := middleware.Authenticate(c.Cfg.JwtSecret)
+	optionalAuthenticate := middleware.OptionalAuthenticate(c.Cfg.JwtSecret)
+
+	postApi := r.PathPrefix("/v1/posts").Subrouter()
+
+	postApi.Use(middleware.BodySizeLimit(c.Cfg.MaxBodySizeAllowed))
+
+	postApi.Handle("", optionalAuthenticate(http.HandlerFunc(postController.ListPosts))).Methods("GET")
+
+	postApi.Handle("", authenticate(http.HandlerFunc(postController.CreatePost))).Methods("POST")
+
+	postApi.Handle("/{id}", optionalAuthenticate(http.HandlerFunc(postController.GetPost))).Methods("GET")
+
+	postApi.Handle("/{id}", authenticate(http.HandlerFunc(postController.UpdatePost))).Methods("PUT")
+
+	postApi.Handle("/{id}", authenticate(http.HandlerFunc(postController.DeletePost))).Methods("DELETE")
+
+	postApi.Handle("/{id}/publish", authenticate(http.HandlerFunc(postController.PublishPost))).Methods("POST")
+
+	postApi.Handle("/{id}/unpublish", authenticate(http.HandlerFunc(postController.UnpublishPost))).Methods("POST")
+
+	postApi.Handle("/{id}/schedule", authenticate(http.HandlerFunc(postController.SchedulePost))).Methods("POST")
+
+	postApi.Handle("/{id}/drip", authenticate(http.HandlerFunc(postController.SetDrip))).Methods("PUT")
+
+	postApi.Handle("/{id}/drip", authenticate(http.HandlerFunc(postController.RemoveDrip))).Methods("DELETE")
+
+	postApi.Handle("/{id}/revisions", authenticate(http.HandlerFunc(postController.ListRevisions))).Methods("GET")
+
+	postApi.Handle("/{id}/revisions/diff", authenticate(http.HandlerFunc(postController.DiffRevisions))).Methods("GET")
+
+	postApi.Handle("/{id}/revisions/{number:[0-9]+}", authenticate(http.HandlerFunc(postController.GetRevision))).Methods("GET")
+
+	postApi.Handle("/{id}/revisions/{number:[0-9]+}/restore", authenticate(http.HandlerFunc(postController.RestoreRevision))).Methods("POST")
+
+	postApi.Handle("/{id}/attachments", authenticate(http.HandlerFunc(postController.AddAttachment))).Methods("POST")
+
+	postApi.Handle("/{id}/attachments/{attachmentId}", authenticate(http.HandlerFunc(postController.RemoveAttachment))).Methods("DELETE")
+}
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/layers/container"
+	"github.com/Xebec19/jibe/api/internal/layers/controllers"
+	"github.com/Xebec19/jibe/api/internal/middleware"
+	"github.com/gorilla/mux"
+)
+
+func registerPushRoutes(r *mux.Router, c container.Container) {
+
+	pushController := controllers.NewPushController(&c.Logger, c.Validator, c.PushService)
+
+	authenticate := middleware.Authenticate(c.Cfg.JwtSecret)
+
+	pushApi := r.PathPrefix("/v1/push").Subrouter()
+
+	pushApi.Use(middleware.BodySizeLimit(c.Cfg.MaxBodySizeAllowed))
+
+	pushApi.HandleFunc("/vapid-public-key", pushController.PublicKey).Methods("GET")
+
+	pushApi.Handle("/subscriptions", authenticate(http.HandlerFunc(pushController.ListSubscriptions))).Methods("GET")
+
+	pushApi.Handle("/subscriptions", authenticate(http.HandlerFunc(pushController.Subscribe))).Methods("POST")
+
+	pushApi.Handle("/subscriptions/{id}", authenticate(http.HandlerFunc(pushController.Unsubscribe))).Methods("DELETE")
+}
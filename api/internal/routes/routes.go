@@ -3,13 +3,33 @@ package routes
 import (
 	"github.com/Xebec19/jibe/api/internal/layers/container"
 	"github.com/Xebec19/jibe/api/internal/middleware"
+	"github.com/Xebec19/jibe/api/internal/utils"
 	"github.com/gorilla/mux"
 )
 
 func RegisterRoutes(r *mux.Router, c container.Container) {
 
 	r.Use(middleware.HttpLogger(c.Logger))
+	// any client could send the header, so it is never honoured in production
+	r.Use(middleware.AccessCacheBypass(c.Cfg.AccessCacheBypass && !utils.IsProductionEnv(c.Cfg.Env)))
 
 	registerHealthRoutes(r, c)
 	registerAuthRoutes(r, c)
+	registerHandleRoutes(r, c)
+	// comments are nested under posts, they go first so their prefix matches
+	registerCommentRoutes(r, c)
+	registerPostRoutes(r, c)
+	registerAccessRoutes(r, c)
+	registerMediaRoutes(r, c)
+	registerUploadRoutes(r, c)
+	// invites and chat are nested under communities, they go first so their prefix matches
+	registerInviteRoutes(r, c)
+	registerChatRoutes(r, c)
+	registerCommunityRoutes(r, c)
+	registerNotificationRoutes(r, c)
+	registerPushRoutes(r, c)
+	registerEmailRoutes(r, c)
+	registerSearchRoutes(r, c)
+	registerFeedRoutes(r, c)
+	registerFollowRoutes(r, c)
 }
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/layers/container"
+	"github.com/Xebec19/jibe/api/internal/layers/controllers"
+	"github.com/Xebec19/jibe/api/internal/middleware"
+	"github.com/gorilla/mux"
+)
+
+func registerSearchRoutes(r *mux.Router, c container.Container) {
+
+	searchController := controllers.NewSearchController(&c.Logger, c.SearchService)
+
+	optionalAuthenticate := middleware.OptionalAuthenticate(c.Cfg.JwtSecret)
+
+	searchApi := r.PathPrefix("/v1/search").Subrouter()
+
+	searchApi.Handle("", optionalAuthenticate(http.HandlerFunc(searchController.Search))).Methods("GET")
+}
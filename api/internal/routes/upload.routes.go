@@ -0,0 +1,37 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Xebec19/jibe/api/internal/layers/container"
+	"github.com/Xebec19/jibe/api/internal/layers/controllers"
+	"github.com/Xebec19/jibe/api/internal/middleware"
+	"github.com/gorilla/mux"
+)
+
+func registerUploadRoutes(r *mux.Router, c container.Container) {
+
+	uploadController := controllers.NewUploadController(&c.Logger, c.UploadService)
+
+	authenticate := middleware.Authenticate(c.Cfg.JwtSecret)
+
+	// chunks carry their own limit, they can not run past the declared upload length
+	chunkLimit := middleware.BodySizeLimit(c.Cfg.UploadMaxSize + 1)
+	jsonLimit := middleware.BodySizeLimit(c.Cfg.MaxBodySizeAllowed)
+
+	uploadApi := r.PathPrefix("/v1/uploads").Subrouter()
+
+	uploadApi.Use(middleware.TusResumable)
+
+	uploadApi.HandleFunc("", uploadController.Options).Methods("OPTIONS")
+
+	uploadApi.Handle("", jsonLimit(authenticate(http.HandlerFunc(uploadController.CreateUpload)))).Methods("POST")
+
+	uploadApi.HandleFunc("/{id}", uploadController.Options).Methods("OPTIONS")
+
+	uploadApi.Handle("/{id}", jsonLimit(authenticate(http.HandlerFunc(uploadController.HeadUpload)))).Methods("HEAD")
+
+	uploadApi.Handle("/{id}", chunkLimit(authenticate(http.HandlerFunc(uploadController.PatchUpload)))).Methods("PATCH")
+
+	uploadApi.Handle("/{id}", jsonLimit(authenticate(http.HandlerFunc(uploadController.TerminateUpload)))).Methods("DELETE")
+}
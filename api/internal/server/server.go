@@ -4,10 +4,12 @@ import (
 	"context"
 	"log/slog"
 	"net/http"
+	"sync"
 
 	"github.com/Xebec19/jibe/api/internal/db"
 	"github.com/Xebec19/jibe/api/internal/layers/container"
 	"github.com/Xebec19/jibe/api/internal/routes"
+	"github.com/Xebec19/jibe/api/internal/workers"
 	"github.com/Xebec19/jibe/api/pkg/config"
 	"github.com/Xebec19/jibe/api/pkg/logger"
 	"github.com/gorilla/mux"
@@ -17,6 +19,12 @@ import (
 type Server struct {
 	Container container.Container
 	Srv       *http.Server
+	Workers   []workers.Worker
+
+	// workersCtx is cancelled by Shutdown to stop the workers
+	workersCtx  context.Context
+	stopWorkers context.CancelFunc
+	workersWg   sync.WaitGroup
 }
 
 func NewServer(ctx context.Context, cfg *config.Config) (*Server, error) {
@@ -32,8 +40,44 @@ func NewServer(ctx context.Context, cfg *config.Config) (*Server, error) {
 
 	c := container.NewContainer(ctx, cfg, logger, pool, q)
 
+	if err := c.SetupLinkSigning(); err != nil {
+		logger.Error("Link signing setup failed!", "error", err)
+		return nil, err
+	}
+
+	if err := c.SetupChainReader(); err != nil {
+		logger.Error("Chain reader setup failed!", "error", err)
+		return nil, err
+	}
+
+	if err := c.SetupStorage(); err != nil {
+		logger.Error("Storage setup failed!", "error", err)
+		return nil, err
+	}
+
+	if err := c.SetupKeyring(); err != nil {
+		logger.Error("Encryption keyring setup failed!", "error", err)
+		return nil, err
+	}
+
+	if err := c.SetupTranscoder(); err != nil {
+		logger.Error("Transcoder setup failed!", "error", err)
+		return nil, err
+	}
+
+	if err := c.SetupPush(); err != nil {
+		logger.Error("Push setup failed!", "error", err)
+		return nil, err
+	}
+
+	if err := c.SetupMailer(); err != nil {
+		logger.Error("Mailer setup failed!", "error", err)
+		return nil, err
+	}
+
 	c.SetupRepositories()
 	c.SetupServices()
+	jobs := c.SetupWorkers()
 
 	r := mux.NewRouter()
 
@@ -48,14 +92,21 @@ func NewServer(ctx context.Context, cfg *config.Config) (*Server, error) {
 		MaxHeaderBytes: cfg.MaxHeaderBytes,
 	}
 
+	workersCtx, stopWorkers := context.WithCancel(ctx)
+
 	return &Server{
-		Container: c,
-		Srv:       srv,
+		Container:   c,
+		Srv:         srv,
+		Workers:     jobs,
+		workersCtx:  workersCtx,
+		stopWorkers: stopWorkers,
 	}, nil
 }
 
 func (s *Server) Run() error {
 
+	s.startWorkers()
+
 	s.Container.Logger.Info("Server started", "PORT", s.Container.Cfg.Port)
 
 	return s.Srv.ListenAndServe()
@@ -63,9 +114,50 @@ func (s *Server) Run() error {
 
 func (s *Server) Shutdown(ctx context.Context) error {
 
+	s.stopAndWaitWorkers(ctx)
+
 	s.Container.Dbpool.Close()
 
+	s.Container.ChainReader.Close()
+
 	s.Srv.Close()
 
 	return nil
 }
+
+// startWorkers runs every background worker until Shutdown is called
+func (s *Server) startWorkers() {
+
+	for _, w := range s.Workers {
+		s.workersWg.Add(1)
+		go func() {
+			defer s.workersWg.Done()
+
+			s.Container.Logger.Info("Worker started", "worker", w.Name())
+			if err := w.Run(s.workersCtx); err != nil {
+				s.Container.Logger.Error("Worker stopped", "worker", w.Name(), "error", err)
+				return
+			}
+			s.Container.Logger.Info("Worker stopped", "worker", w.Name())
+		}()
+	}
+}
+
+// stopAndWaitWorkers cancels the workers and waits for them to return, or for ctx
+// to expire
+func (s *Server) stopAndWaitWorkers(ctx context.Context) {
+
+	s.stopWorkers()
+
+	done := make(chan struct{})
+	go func() {
+		s.workersWg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.Container.Logger.Warn("Workers did not stop in time")
+	}
+}
@@ -0,0 +1,9 @@
+// templates holds the files rendered by the server, they are built into the binary
+package templates
+
+import "embed"
+
+// Email holds the email templates in the email directory, see mailer.ParseTemplates
+//
+//go:embed email/*.tmpl
+var Email embed.FS
@@ -0,0 +1,52 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+)
+
+// NewChatPresenceWorker keeps the chat connections of this replica marked live, and
+// clears the presence left behind by replicas which stopped without disconnecting
+func NewChatPresenceWorker(logger logger.Logger, chatService services.ChatService, interval time.Duration) Worker {
+
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	return &chatPresenceWorker{
+		logger:      logger,
+		chatService: chatService,
+		interval:    interval,
+	}
+}
+
+type chatPresenceWorker struct {
+	logger      logger.Logger
+	chatService services.ChatService
+	interval    time.Duration
+}
+
+func (w *chatPresenceWorker) Name() string {
+	return "chat presence"
+}
+
+func (w *chatPresenceWorker) Run(ctx context.Context) error {
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if err := w.chatService.Heartbeat(); err != nil {
+			w.logger.Warn("Chat presence heartbeat failed", "error", err)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+)
+
+type EmailWorker interface {
+	Worker
+
+	// Notify wakes the worker for newly queued emails, it never blocks
+	Notify()
+}
+
+// NewEmailWorker sends queued notification emails as they are queued. Due emails are
+// also polled every interval, which picks up retries and the emails queued by other
+// instances
+func NewEmailWorker(logger logger.Logger, emailService services.EmailService, batch int, interval time.Duration) EmailWorker {
+
+	if batch <= 0 {
+		batch = 20
+	}
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	return &emailWorker{
+		logger:       logger,
+		emailService: emailService,
+		batch:        batch,
+		interval:     interval,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+type emailWorker struct {
+	logger       logger.Logger
+	emailService services.EmailService
+	batch        int
+	interval     time.Duration
+	wake         chan struct{}
+}
+
+func (w *emailWorker) Name() string {
+	return "email delivery"
+}
+
+func (w *emailWorker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (w *emailWorker) Run(ctx context.Context) error {
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.deliver(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+func (w *emailWorker) deliver(ctx context.Context) {
+
+	for ctx.Err() == nil {
+		claimed, err := w.emailService.DeliverPending(w.batch)
+		if err != nil {
+			w.logger.Warn("Email delivery pass failed", "error", err)
+			return
+		}
+
+		if claimed < w.batch {
+			return
+		}
+	}
+}
+
+// NewDigestWorker mails the daily and weekly digests which are due every interval,
+// instances share the work as digests are claimed
+func NewDigestWorker(logger logger.Logger, emailService services.EmailService, batch int, interval time.Duration) Worker {
+
+	if batch <= 0 {
+		batch = 50
+	}
+	if interval <= 0 {
+		interval = 15 * time.Minute
+	}
+
+	return &digestWorker{
+		logger:       logger,
+		emailService: emailService,
+		batch:        batch,
+		interval:     interval,
+	}
+}
+
+type digestWorker struct {
+	logger       logger.Logger
+	emailService services.EmailService
+	batch        int
+	interval     time.Duration
+}
+
+func (w *digestWorker) Name() string {
+	return "email digests"
+}
+
+func (w *digestWorker) Run(ctx context.Context) error {
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.send(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *digestWorker) send(ctx context.Context) {
+
+	for ctx.Err() == nil {
+		claimed, err := w.emailService.SendDigests(w.batch)
+		if err != nil {
+			w.logger.Warn("Digest pass failed", "error", err)
+			return
+		}
+
+		if claimed < w.batch {
+			return
+		}
+	}
+}
@@ -0,0 +1,500 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/internal/layers/repositories"
+	"github.com/Xebec19/jibe/api/pkg/chain"
+	"github.com/Xebec19/jibe/api/pkg/gating"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	transferTopic       = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	transferSingleTopic = crypto.Keccak256Hash([]byte("TransferSingle(address,address,address,uint256,uint256)"))
+	transferBatchTopic  = crypto.Keccak256Hash([]byte("TransferBatch(address,address,address,uint256[],uint256[])"))
+)
+
+var transferBatchArgs = abi.Arguments{
+	{Name: "ids", Type: mustABIType("uint256[]")},
+	{Name: "values", Type: mustABIType("uint256[]")},
+}
+
+// TransferHandler is notified of transfers applied to, or rolled back from, the
+// holdings of a chain
+type TransferHandler func(chainID uint64, transfers []domain.TokenTransfer)
+
+type IndexerOptions struct {
+	// StartBlocks is the first block indexed per chain, chains missing here are not indexed
+	StartBlocks map[uint64]uint64
+	// PollInterval is how often every chain is synced to its head
+	PollInterval time.Duration
+	// BlockRange is the most blocks requested in one eth_getLogs call
+	BlockRange uint64
+	// ReorgDepth is how many recent block hashes are kept to find the common ancestor
+	// after a reorg
+	ReorgDepth int
+}
+
+type IndexerWorker interface {
+	Worker
+
+	// Subscribe registers a handler called after every applied or rolled back range
+	Subscribe(handler TransferHandler)
+}
+
+// NewIndexerWorker follows token transfer logs of the contracts referenced by access
+// policies and keeps token_holdings current
+func NewIndexerWorker(logger logger.Logger, reader chain.Reader, indexerRepo repositories.IndexerRepository, opts IndexerOptions) IndexerWorker {
+
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 15 * time.Second
+	}
+	if opts.BlockRange == 0 {
+		opts.BlockRange = 2000
+	}
+	if opts.ReorgDepth <= 0 {
+		opts.ReorgDepth = 128
+	}
+	for chainID, start := range opts.StartBlocks {
+		// the genesis block has no logs, starting at 1 keeps start-1 from underflowing
+		opts.StartBlocks[chainID] = max(start, 1)
+	}
+
+	return &indexerWorker{
+		logger:      logger,
+		reader:      reader,
+		indexerRepo: indexerRepo,
+		opts:        opts,
+	}
+}
+
+type indexerWorker struct {
+	logger      logger.Logger
+	reader      chain.Reader
+	indexerRepo repositories.IndexerRepository
+	opts        IndexerOptions
+
+	mu       sync.RWMutex
+	handlers []TransferHandler
+}
+
+func (w *indexerWorker) Name() string {
+	return "token indexer"
+}
+
+func (w *indexerWorker) Subscribe(handler TransferHandler) {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.handlers = append(w.handlers, handler)
+}
+
+func (w *indexerWorker) Run(ctx context.Context) error {
+
+	if len(w.opts.StartBlocks) == 0 {
+		w.logger.Info("Token indexer disabled, no start blocks configured")
+		<-ctx.Done()
+		return nil
+	}
+
+	ticker := time.NewTicker(w.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.syncAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *indexerWorker) syncAll(ctx context.Context) {
+
+	if err := w.trackPolicyContracts(); err != nil {
+		w.logger.Warn("Indexed contracts refresh failed", "error", err)
+	}
+
+	for chainID := range w.opts.StartBlocks {
+		if err := w.sync(ctx, chainID); err != nil && ctx.Err() == nil {
+			w.logger.Warn("Chain indexing failed", "chain_id", chainID, "error", err)
+		}
+	}
+}
+
+// trackPolicyContracts starts following every token contract used by a stored policy
+func (w *indexerWorker) trackPolicyContracts() error {
+
+	policies, err := w.indexerRepo.ListAccessPolicies()
+	if err != nil {
+		return err
+	}
+
+	for _, raw := range policies {
+		rule, err := gating.Parse(raw)
+		if err != nil {
+			continue
+		}
+
+		for _, ref := range rule.Contracts() {
+			start, ok := w.opts.StartBlocks[ref.ChainID]
+			if !ok {
+				continue
+			}
+
+			if err := w.indexerRepo.TrackContract(ref.ChainID, ref.Address.Hex(), tokenKind(ref.Type), start); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// sync brings the holdings of a chain up to its head, rolling back first when the
+// checkpointed block is no longer canonical
+func (w *indexerWorker) sync(ctx context.Context, chainID uint64) error {
+
+	checkpoint, err := w.indexerRepo.GetCheckpoint(chainID)
+	if errors.Is(err, domain.ErrCheckpointNotFound) {
+		checkpoint = &domain.BlockRef{Number: w.opts.StartBlocks[chainID] - 1}
+	} else if err != nil {
+		return err
+	}
+
+	if checkpoint.Hash != "" {
+		canonical, err := w.blockHash(ctx, chainID, checkpoint.Number)
+		if err != nil {
+			return err
+		}
+
+		if canonical != checkpoint.Hash {
+			if checkpoint, err = w.rollback(ctx, chainID, checkpoint); err != nil {
+				return err
+			}
+		}
+	}
+
+	contracts, err := w.indexerRepo.ListContracts(chainID)
+	if err != nil {
+		return err
+	}
+
+	if len(contracts) == 0 {
+		return nil
+	}
+
+	var following []common.Address
+	for _, c := range contracts {
+		if c.BackfilledTo < checkpoint.Number {
+			if err := w.backfill(ctx, c, checkpoint.Number); err != nil {
+				return err
+			}
+		}
+		following = append(following, common.HexToAddress(c.Address))
+	}
+
+	head, err := w.reader.BlockNumber(ctx, chainID)
+	if err != nil {
+		return err
+	}
+
+	for from := checkpoint.Number + 1; from <= head; {
+		to := min(from+w.opts.BlockRange-1, head)
+
+		hash, err := w.blockHash(ctx, chainID, to)
+		if err != nil {
+			return err
+		}
+
+		transfers, err := w.transfers(ctx, chainID, following, from, to)
+		if err != nil {
+			return err
+		}
+
+		// the logs may have been read from a fork which replaced the header in the
+		// meantime, the next tick retries from the same checkpoint
+		if again, err := w.blockHash(ctx, chainID, to); err != nil || again != hash {
+			return err
+		}
+
+		if err := w.indexerRepo.ApplyRange(chainID, transfers, from, domain.BlockRef{Number: to, Hash: hash}, w.opts.ReorgDepth); err != nil {
+			return err
+		}
+
+		w.notify(chainID, transfers)
+		from = to + 1
+	}
+
+	return nil
+}
+
+// backfill applies the history of a contract which started being followed after the
+// chain's checkpoint, up to the checkpoint
+func (w *indexerWorker) backfill(ctx context.Context, contract domain.IndexedContract, until uint64) error {
+
+	address := []common.Address{common.HexToAddress(contract.Address)}
+
+	for from := contract.BackfilledTo + 1; from <= until; {
+		to := min(from+w.opts.BlockRange-1, until)
+
+		transfers, err := w.transfers(ctx, contract.ChainID, address, from, to)
+		if err != nil {
+			return err
+		}
+
+		if err := w.indexerRepo.ApplyBackfill(contract.ChainID, contract.Address, transfers, to); err != nil {
+			return err
+		}
+
+		w.notify(contract.ChainID, transfers)
+		from = to + 1
+	}
+
+	return nil
+}
+
+// rollback finds the newest stored block which is still canonical and undoes
+// everything indexed after it
+func (w *indexerWorker) rollback(ctx context.Context, chainID uint64, checkpoint *domain.BlockRef) (*domain.BlockRef, error) {
+
+	recent, err := w.indexerRepo.ListRecentBlocks(chainID, w.opts.ReorgDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	var ancestor *domain.BlockRef
+	for _, block := range recent {
+		canonical, err := w.blockHash(ctx, chainID, block.Number)
+		if err != nil {
+			return nil, err
+		}
+		if canonical == block.Hash {
+			ancestor = &block
+			break
+		}
+	}
+
+	if ancestor == nil {
+		// the reorg is deeper than the stored hashes, start over from before the
+		// oldest block we know of
+		oldest := checkpoint.Number
+		if len(recent) > 0 {
+			oldest = recent[len(recent)-1].Number
+		}
+
+		number := max(oldest, 1) - 1
+		hash, err := w.blockHash(ctx, chainID, number)
+		if err != nil {
+			return nil, err
+		}
+
+		w.logger.Warn("Reorg deeper than stored block hashes", "chain_id", chainID, "rollback_to", number)
+		ancestor = &domain.BlockRef{Number: number, Hash: hash}
+	}
+
+	undone, err := w.indexerRepo.RollbackTo(chainID, *ancestor)
+	if err != nil {
+		return nil, err
+	}
+
+	w.logger.Info("Chain reorg rolled back", "chain_id", chainID, "from", checkpoint.Number, "to", ancestor.Number, "transfers", len(undone))
+	w.notify(chainID, undone)
+
+	return ancestor, nil
+}
+
+func (w *indexerWorker) notify(chainID uint64, transfers []domain.TokenTransfer) {
+
+	if len(transfers) == 0 {
+		return
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	for _, handler := range w.handlers {
+		handler(chainID, transfers)
+	}
+}
+
+func (w *indexerWorker) blockHash(ctx context.Context, chainID uint64, number uint64) (string, error) {
+
+	header, err := w.reader.HeaderByNumber(ctx, chainID, number)
+	if err != nil {
+		return "", fmt.Errorf("header of block %d failed %w", number, err)
+	}
+
+	return header.Hash().Hex(), nil
+}
+
+// transfers reads and decodes the transfer logs of the contracts between two blocks
+func (w *indexerWorker) transfers(ctx context.Context, chainID uint64, contracts []common.Address, from, to uint64) ([]domain.TokenTransfer, error) {
+
+	logs, err := w.reader.FilterLogs(ctx, chainID, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(to),
+		Addresses: contracts,
+		Topics:    [][]common.Hash{{transferTopic, transferSingleTopic, transferBatchTopic}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("logs of blocks %d-%d failed %w", from, to, err)
+	}
+
+	var transfers []domain.TokenTransfer
+	blockTimes := make(map[uint64]time.Time)
+
+	for _, log := range logs {
+		if log.Removed {
+			continue
+		}
+
+		decoded, err := decodeTransfer(chainID, log)
+		if err != nil {
+			w.logger.Warn("Transfer log skipped", "chain_id", chainID, "tx", log.TxHash.Hex(), "index", log.Index, "error", err)
+			continue
+		}
+
+		// held_for_days conditions need the time of each transfer. Newer nodes include
+		// it in the log, older ones need the header
+		blockTime, ok := blockTimes[log.BlockNumber]
+		if !ok {
+			if log.BlockTimestamp != 0 {
+				blockTime = time.Unix(int64(log.BlockTimestamp), 0).UTC()
+			} else {
+				header, err := w.reader.HeaderByNumber(ctx, chainID, log.BlockNumber)
+				if err != nil {
+					return nil, fmt.Errorf("header of block %d failed %w", log.BlockNumber, err)
+				}
+				blockTime = time.Unix(int64(header.Time), 0).UTC()
+			}
+			blockTimes[log.BlockNumber] = blockTime
+		}
+
+		for i := range decoded {
+			decoded[i].BlockTime = &blockTime
+		}
+		transfers = append(transfers, decoded...)
+	}
+
+	return transfers, nil
+}
+
+// decodeTransfer turns a Transfer, TransferSingle or TransferBatch log into balance
+// movements. ERC-20 and ERC-721 share the Transfer event and are told apart by the
+// indexed token id
+func decodeTransfer(chainID uint64, log types.Log) ([]domain.TokenTransfer, error) {
+
+	if len(log.Topics) == 0 {
+		return nil, errors.New("log has no topics")
+	}
+
+	base := domain.TokenTransfer{
+		ChainID:     chainID,
+		Contract:    log.Address.Hex(),
+		BlockNumber: log.BlockNumber,
+		BlockHash:   log.BlockHash.Hex(),
+		TxHash:      log.TxHash.Hex(),
+		LogIndex:    log.Index,
+	}
+
+	switch {
+	case log.Topics[0] == transferTopic && len(log.Topics) == 4:
+		base.TokenKind = domain.TokenKindERC721
+		base.From = topicAddress(log.Topics[1])
+		base.To = topicAddress(log.Topics[2])
+		base.TokenID = log.Topics[3].Big()
+		base.Amount = big.NewInt(1)
+		return []domain.TokenTransfer{base}, nil
+
+	case log.Topics[0] == transferTopic && len(log.Topics) == 3:
+		if len(log.Data) != 32 {
+			return nil, fmt.Errorf("transfer data is %d bytes", len(log.Data))
+		}
+		base.TokenKind = domain.TokenKindERC20
+		base.From = topicAddress(log.Topics[1])
+		base.To = topicAddress(log.Topics[2])
+		base.TokenID = new(big.Int)
+		base.Amount = new(big.Int).SetBytes(log.Data)
+		return []domain.TokenTransfer{base}, nil
+
+	case log.Topics[0] == transferSingleTopic && len(log.Topics) == 4:
+		if len(log.Data) != 64 {
+			return nil, fmt.Errorf("transfer single data is %d bytes", len(log.Data))
+		}
+		base.TokenKind = domain.TokenKindERC1155
+		base.From = topicAddress(log.Topics[2])
+		base.To = topicAddress(log.Topics[3])
+		base.TokenID = new(big.Int).SetBytes(log.Data[:32])
+		base.Amount = new(big.Int).SetBytes(log.Data[32:])
+		return []domain.TokenTransfer{base}, nil
+
+	case log.Topics[0] == transferBatchTopic && len(log.Topics) == 4:
+		values, err := transferBatchArgs.Unpack(log.Data)
+		if err != nil {
+			return nil, fmt.Errorf("transfer batch unpacking failed %w", err)
+		}
+
+		ids, amounts := values[0].([]*big.Int), values[1].([]*big.Int)
+		if len(ids) != len(amounts) {
+			return nil, fmt.Errorf("transfer batch has %d ids and %d values", len(ids), len(amounts))
+		}
+
+		transfers := make([]domain.TokenTransfer, 0, len(ids))
+		for i := range ids {
+			t := base
+			t.TokenKind = domain.TokenKindERC1155
+			t.From = topicAddress(log.Topics[2])
+			t.To = topicAddress(log.Topics[3])
+			t.TokenID = ids[i]
+			t.Amount = amounts[i]
+			t.BatchIndex = i
+			transfers = append(transfers, t)
+		}
+		return transfers, nil
+	}
+
+	return nil, fmt.Errorf("unexpected event with %d topics", len(log.Topics))
+}
+
+func topicAddress(topic common.Hash) string {
+	return common.BytesToAddress(topic.Bytes()).Hex()
+}
+
+// tokenKind maps the condition type referencing a contract to the standard it implements
+func tokenKind(ruleType string) string {
+
+	switch ruleType {
+	case gating.TypeERC721Owner:
+		return domain.TokenKindERC721
+	case gating.TypeERC1155Balance:
+		return domain.TokenKindERC1155
+	}
+
+	return domain.TokenKindERC20
+}
+
+func mustABIType(t string) abi.Type {
+
+	typ, err := abi.NewType(t, "", nil)
+	if err != nil {
+		panic(fmt.Sprintf("abi type %s %v", t, err))
+	}
+
+	return typ
+}
@@ -0,0 +1,109 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/layers/domain"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+)
+
+// MediaProcessor claims pending media files and processes them
+type MediaProcessor interface {
+	// ProcessPending processes up to limit files and returns how many it claimed
+	ProcessPending(limit int) (int, error)
+}
+
+type MediaWorker interface {
+	Worker
+
+	// Notify wakes the worker for a newly stored file of its content types, it never
+	// blocks
+	Notify(media domain.MediaFile)
+}
+
+type MediaWorkerOptions struct {
+	// ContentTypes are the files the processor handles
+	ContentTypes map[string]bool
+	// Batch is how many files are claimed per pass
+	Batch int
+	// Interval is how often pending files are polled for
+	Interval time.Duration
+}
+
+// NewMediaWorker runs a processor of uploaded media as files are stored. Pending files
+// are also picked up every interval, so files left behind by a restart or stored by
+// another instance are not missed
+func NewMediaWorker(logger logger.Logger, name string, processor MediaProcessor, opts MediaWorkerOptions) MediaWorker {
+
+	if opts.Batch <= 0 {
+		opts.Batch = 10
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 30 * time.Second
+	}
+
+	return &mediaWorker{
+		logger:    logger,
+		name:      name,
+		processor: processor,
+		opts:      opts,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+type mediaWorker struct {
+	logger    logger.Logger
+	name      string
+	processor MediaProcessor
+	opts      MediaWorkerOptions
+	wake      chan struct{}
+}
+
+func (w *mediaWorker) Name() string {
+	return w.name
+}
+
+func (w *mediaWorker) Notify(media domain.MediaFile) {
+
+	if media.ProcessingStatus != domain.MediaProcessingPending || !w.opts.ContentTypes[media.ContentType] {
+		return
+	}
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (w *mediaWorker) Run(ctx context.Context) error {
+
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		w.process(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+func (w *mediaWorker) process(ctx context.Context) {
+
+	for ctx.Err() == nil {
+		claimed, err := w.processor.ProcessPending(w.opts.Batch)
+		if err != nil {
+			w.logger.Warn("Media processing pass failed", "worker", w.name, "error", err)
+			return
+		}
+
+		if claimed < w.opts.Batch {
+			return
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+)
+
+type PushWorker interface {
+	Worker
+
+	// Notify wakes the worker for newly queued deliveries, it never blocks
+	Notify()
+}
+
+// NewPushWorker sends queued push deliveries as they are queued. Due deliveries are
+// also polled every interval, which picks up retries and the deliveries queued by
+// other instances
+func NewPushWorker(logger logger.Logger, pushService services.PushService, batch int, interval time.Duration) PushWorker {
+
+	if batch <= 0 {
+		batch = 50
+	}
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+
+	return &pushWorker{
+		logger:      logger,
+		pushService: pushService,
+		batch:       batch,
+		interval:    interval,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+type pushWorker struct {
+	logger      logger.Logger
+	pushService services.PushService
+	batch       int
+	interval    time.Duration
+	wake        chan struct{}
+}
+
+func (w *pushWorker) Name() string {
+	return "push delivery"
+}
+
+func (w *pushWorker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (w *pushWorker) Run(ctx context.Context) error {
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.deliver(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+func (w *pushWorker) deliver(ctx context.Context) {
+
+	for ctx.Err() == nil {
+		claimed, err := w.pushService.DeliverPending(w.batch)
+		if err != nil {
+			w.logger.Warn("Push delivery pass failed", "error", err)
+			return
+		}
+
+		if claimed < w.batch {
+			return
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+)
+
+// revisionPruneBatch is how many expired revisions are removed per pass
+const revisionPruneBatch = 500
+
+// NewRevisionCleanupWorker prunes the revisions saved while posts were not published
+// once they are older than retention, revisions of published posts are kept
+func NewRevisionCleanupWorker(logger logger.Logger, postService services.PostService, retention, interval time.Duration) Worker {
+
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	return &revisionCleanupWorker{
+		logger:      logger,
+		postService: postService,
+		retention:   retention,
+		interval:    interval,
+	}
+}
+
+type revisionCleanupWorker struct {
+	logger      logger.Logger
+	postService services.PostService
+	retention   time.Duration
+	interval    time.Duration
+}
+
+func (w *revisionCleanupWorker) Name() string {
+	return "revision cleanup"
+}
+
+func (w *revisionCleanupWorker) Run(ctx context.Context) error {
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.prune(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *revisionCleanupWorker) prune(ctx context.Context) {
+
+	cutoff := time.Now().UTC().Add(-w.retention)
+
+	for ctx.Err() == nil {
+		removed, err := w.postService.PruneRevisions(cutoff, revisionPruneBatch)
+		if err != nil {
+			w.logger.Warn("Expired revision cleanup failed", "error", err)
+			return
+		}
+
+		if removed > 0 {
+			w.logger.Info("Expired revisions removed", "count", removed)
+		}
+
+		if removed < revisionPruneBatch {
+			return
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+)
+
+// NewSchedulerWorker publishes scheduled posts and notifies members of dripped posts
+// which unlocked for them every interval. Schedules and releases are kept in postgres,
+// so what fell due while no instance ran is caught up on the next pass
+func NewSchedulerWorker(logger logger.Logger, postService services.PostService, notificationService services.NotificationService, batch int, interval time.Duration) Worker {
+
+	if batch <= 0 {
+		batch = 50
+	}
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	return &schedulerWorker{
+		logger:              logger,
+		postService:         postService,
+		notificationService: notificationService,
+		batch:               batch,
+		interval:            interval,
+	}
+}
+
+type schedulerWorker struct {
+	logger              logger.Logger
+	postService         services.PostService
+	notificationService services.NotificationService
+	batch               int
+	interval            time.Duration
+}
+
+func (w *schedulerWorker) Name() string {
+	return "content scheduler"
+}
+
+func (w *schedulerWorker) Run(ctx context.Context) error {
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.drain(ctx, "Scheduled publishing pass failed", w.postService.PublishDue)
+		w.drain(ctx, "Drip release pass failed", w.notificationService.ReleaseDrips)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *schedulerWorker) drain(ctx context.Context, failure string, pass func(limit int) (int, error)) {
+
+	for ctx.Err() == nil {
+		done, err := pass(w.batch)
+		if err != nil {
+			w.logger.Warn(failure, "error", err)
+			return
+		}
+
+		if done < w.batch {
+			return
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Xebec19/jibe/api/internal/layers/services"
+	"github.com/Xebec19/jibe/api/pkg/logger"
+)
+
+// uploadPurgeBatch is how many expired uploads are removed per pass
+const uploadPurgeBatch = 100
+
+// NewUploadCleanupWorker removes expired resumable uploads and their chunks, which
+// releases the quota they reserved
+func NewUploadCleanupWorker(logger logger.Logger, uploadService services.UploadService, interval time.Duration) Worker {
+
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+
+	return &uploadCleanupWorker{
+		logger:        logger,
+		uploadService: uploadService,
+		interval:      interval,
+	}
+}
+
+type uploadCleanupWorker struct {
+	logger        logger.Logger
+	uploadService services.UploadService
+	interval      time.Duration
+}
+
+func (w *uploadCleanupWorker) Name() string {
+	return "upload cleanup"
+}
+
+func (w *uploadCleanupWorker) Run(ctx context.Context) error {
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *uploadCleanupWorker) purge(ctx context.Context) {
+
+	for ctx.Err() == nil {
+		removed, err := w.uploadService.PurgeExpired(uploadPurgeBatch)
+		if err != nil {
+			w.logger.Warn("Expired upload cleanup failed", "error", err)
+			return
+		}
+
+		if removed > 0 {
+			w.logger.Info("Expired uploads removed", "count", removed)
+		}
+
+		if removed < uploadPurgeBatch {
+			return
+		}
+	}
+}
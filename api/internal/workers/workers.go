@@ -0,0 +1,10 @@
+// workers contains background jobs started and stopped alongside the http server
+package workers
+
+import "context"
+
+// Worker is a long running job. Run blocks until ctx is cancelled or the job fails
+type Worker interface {
+	Name() string
+	Run(ctx context.Context) error
+}
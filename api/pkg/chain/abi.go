@@ -0,0 +1,33 @@
+package chain
+
+import (
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// only the view functions the reader calls are described. balanceOf is overloaded
+// between ERC-20/721 and ERC-1155 so they live in separate ABIs
+const (
+	tokenABIJSON = `[
+		{"type":"function","name":"balanceOf","stateMutability":"view","inputs":[{"name":"owner","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+		{"type":"function","name":"ownerOf","stateMutability":"view","inputs":[{"name":"tokenId","type":"uint256"}],"outputs":[{"name":"","type":"address"}]}
+	]`
+
+	erc1155ABIJSON = `[
+		{"type":"function","name":"balanceOf","stateMutability":"view","inputs":[{"name":"account","type":"address"},{"name":"id","type":"uint256"}],"outputs":[{"name":"","type":"uint256"}]}
+	]`
+)
+
+var (
+	tokenABI   = mustParseABI(tokenABIJSON)
+	erc1155ABI = mustParseABI(erc1155ABIJSON)
+)
+
+func mustParseABI(raw string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(raw))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
@@ -0,0 +1,98 @@
+// chain reads token balances and ownership from EVM chains. Every chain ID is served
+// by a pool of RPC endpoints with failover, and results are cached per block
+package chain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	ErrUnsupportedChain = errors.New("chain is not configured")
+	ErrNoHealthyRPC     = errors.New("no healthy rpc endpoint")
+)
+
+// Reader is the read-only view of chain state used by gating and profiles
+type Reader interface {
+	// BalanceOf calls balanceOf(owner) on an ERC-20 or ERC-721 contract
+	BalanceOf(ctx context.Context, chainID uint64, token, owner common.Address) (*big.Int, error)
+
+	// OwnerOf calls ownerOf(tokenId) on an ERC-721 contract. Tokens which do not exist,
+	// or were burned, report the zero address
+	OwnerOf(ctx context.Context, chainID uint64, token common.Address, tokenID *big.Int) (common.Address, error)
+
+	// ERC1155BalanceOf calls balanceOf(owner, id) on an ERC-1155 contract
+	ERC1155BalanceOf(ctx context.Context, chainID uint64, token, owner common.Address, tokenID *big.Int) (*big.Int, error)
+
+	// NativeBalance returns the balance of the account in wei
+	NativeBalance(ctx context.Context, chainID uint64, owner common.Address) (*big.Int, error)
+
+	// BlockNumber returns the latest block seen on the chain
+	BlockNumber(ctx context.Context, chainID uint64) (uint64, error)
+
+	// HeaderByNumber returns the header of a block, used to detect reorgs
+	HeaderByNumber(ctx context.Context, chainID uint64, number uint64) (*types.Header, error)
+
+	// FilterLogs returns the logs matching the query
+	FilterLogs(ctx context.Context, chainID uint64, query ethereum.FilterQuery) ([]types.Log, error)
+
+	// Stats reports how many reads were made and how many rpc requests they took
+	Stats() Stats
+
+	// Close releases every rpc connection
+	Close()
+}
+
+// Client is the subset of an rpc client the reader needs. It is implemented by
+// *ethclient.Client and by the client of go-ethereum's simulated backend
+type Client interface {
+	ethereum.BlockNumberReader
+	ethereum.ContractCaller
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error)
+	Close()
+}
+
+type Options struct {
+	// Timeout bounds a single rpc request, the next endpoint is tried when it expires
+	Timeout time.Duration
+	// CacheTTL is how long a result read at a block is served from cache. The latest
+	// block number itself is cached for a fraction of it
+	CacheTTL time.Duration
+	// Cooldown is how long a failing endpoint is skipped before it is tried again
+	Cooldown time.Duration
+
+	// BatchSize is the most reads sent in one Multicall3 aggregate3 call, 0 or 1
+	// sends every read as its own eth_call
+	BatchSize int
+	// BatchWindow is how long a batch waits for more reads before it is sent
+	BatchWindow time.Duration
+	// MulticallAddress overrides Multicall3Address, eg. on a local devnet
+	MulticallAddress common.Address
+}
+
+func (o Options) withDefaults() Options {
+	if o.Timeout <= 0 {
+		o.Timeout = 5 * time.Second
+	}
+	if o.CacheTTL <= 0 {
+		o.CacheTTL = 12 * time.Second
+	}
+	if o.Cooldown <= 0 {
+		o.Cooldown = 30 * time.Second
+	}
+	if o.BatchWindow <= 0 {
+		o.BatchWindow = 5 * time.Millisecond
+	}
+	if o.MulticallAddress == (common.Address{}) {
+		o.MulticallAddress = Multicall3Address
+	}
+	return o
+}
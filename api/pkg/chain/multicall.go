@@ -0,0 +1,244 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Multicall3Address is the address Multicall3 is deployed at on nearly every EVM chain
+var Multicall3Address = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+// ErrCallReverted is returned for a call of a batch which reverted on its own while
+// the rest of the batch succeeded
+var ErrCallReverted = errors.New("call reverted")
+
+const multicallABIJSON = `[
+	{"type":"function","name":"aggregate3","stateMutability":"payable",
+	 "inputs":[{"name":"calls","type":"tuple[]","components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}]}],
+	 "outputs":[{"name":"returnData","type":"tuple[]","components":[{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}]}]},
+	{"type":"function","name":"getEthBalance","stateMutability":"view",
+	 "inputs":[{"name":"addr","type":"address"}],"outputs":[{"name":"balance","type":"uint256"}]}
+]`
+
+var multicallABI = mustParseABI(multicallABIJSON)
+
+type multicallCall struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+type multicallResult struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// Stats counts reads served by the reader against the rpc requests they cost
+type Stats struct {
+	// Reads is the number of contract reads which missed the cache
+	Reads uint64
+	// RoundTrips is the number of eth_call requests sent for them
+	RoundTrips uint64
+}
+
+type callResult struct {
+	data []byte
+	err  error
+}
+
+// pendingCall is a read waiting in a batch. Identical reads submitted while the batch
+// is open share one slot
+type pendingCall struct {
+	target  common.Address
+	data    []byte
+	waiters []chan callResult
+}
+
+type batch struct {
+	calls []*pendingCall
+	index map[string]*pendingCall
+	timer *time.Timer
+}
+
+// batcher groups concurrent reads of a chain at the same block into a single
+// aggregate3 call. A batch is sent when it is full or when the window elapses
+type batcher struct {
+	pool      *pool
+	multicall common.Address
+	size      int
+	window    time.Duration
+
+	mu      sync.Mutex
+	pending map[uint64]*batch
+
+	reads      atomic.Uint64
+	roundTrips atomic.Uint64
+}
+
+func newBatcher(p *pool, opts Options) *batcher {
+	return &batcher{
+		pool:      p,
+		multicall: opts.MulticallAddress,
+		size:      opts.BatchSize,
+		window:    opts.BatchWindow,
+		pending:   make(map[uint64]*batch),
+	}
+}
+
+// call executes data against target at the block, batched with other reads when
+// batching is enabled
+func (b *batcher) call(ctx context.Context, block uint64, target common.Address, data []byte) ([]byte, error) {
+
+	b.reads.Add(1)
+
+	if b.size <= 1 {
+		return b.direct(ctx, block, target, data)
+	}
+
+	res := make(chan callResult, 1)
+	key := target.Hex() + string(data)
+
+	b.mu.Lock()
+	bt := b.pending[block]
+	if bt == nil {
+		bt = &batch{index: make(map[string]*pendingCall)}
+		b.pending[block] = bt
+		bt.timer = time.AfterFunc(b.window, func() { b.flush(block, bt) })
+	}
+
+	if pc, ok := bt.index[key]; ok {
+		pc.waiters = append(pc.waiters, res)
+	} else {
+		pc := &pendingCall{target: target, data: data, waiters: []chan callResult{res}}
+		bt.calls = append(bt.calls, pc)
+		bt.index[key] = pc
+	}
+
+	full := len(bt.calls) >= b.size
+	if full {
+		delete(b.pending, block)
+		bt.timer.Stop()
+	}
+	b.mu.Unlock()
+
+	if full {
+		go b.execute(block, bt)
+	}
+
+	select {
+	case r := <-res:
+		return r.data, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (b *batcher) stats() Stats {
+	return Stats{
+		Reads:      b.reads.Load(),
+		RoundTrips: b.roundTrips.Load(),
+	}
+}
+
+// flush sends the batch once its window elapsed, unless it already went out full
+func (b *batcher) flush(block uint64, bt *batch) {
+
+	b.mu.Lock()
+	if b.pending[block] != bt {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.pending, block)
+	b.mu.Unlock()
+
+	b.execute(block, bt)
+}
+
+func (b *batcher) execute(block uint64, bt *batch) {
+
+	// callers may give up through their own context, the batch itself only stops
+	// on the pool's per request timeout
+	ctx := context.Background()
+
+	if len(bt.calls) == 1 {
+		pc := bt.calls[0]
+		data, err := b.direct(ctx, block, pc.target, pc.data)
+		pc.deliver(callResult{data: data, err: err})
+		return
+	}
+
+	results, err := b.aggregate(ctx, block, bt.calls)
+	if err != nil {
+		for _, pc := range bt.calls {
+			pc.deliver(callResult{err: err})
+		}
+		return
+	}
+
+	for i, pc := range bt.calls {
+		if !results[i].Success {
+			pc.deliver(callResult{err: ErrCallReverted})
+			continue
+		}
+		pc.deliver(callResult{data: results[i].ReturnData})
+	}
+}
+
+func (b *batcher) aggregate(ctx context.Context, block uint64, calls []*pendingCall) ([]multicallResult, error) {
+
+	args := make([]multicallCall, 0, len(calls))
+	for _, pc := range calls {
+		args = append(args, multicallCall{Target: pc.target, AllowFailure: true, CallData: pc.data})
+	}
+
+	data, err := multicallABI.Pack("aggregate3", args)
+	if err != nil {
+		return nil, fmt.Errorf("aggregate3 packing failed %w", err)
+	}
+
+	raw, err := b.direct(ctx, block, b.multicall, data)
+	if err != nil {
+		return nil, fmt.Errorf("aggregate3 call failed %w", err)
+	}
+
+	out, err := multicallABI.Unpack("aggregate3", raw)
+	if err != nil {
+		return nil, fmt.Errorf("aggregate3 unpacking failed, is multicall3 deployed at %s? %w", b.multicall.Hex(), err)
+	}
+
+	results := *abi.ConvertType(out[0], new([]multicallResult)).(*[]multicallResult)
+	if len(results) != len(calls) {
+		return nil, fmt.Errorf("aggregate3 returned %d results for %d calls", len(results), len(calls))
+	}
+
+	return results, nil
+}
+
+// direct sends a single eth_call through the pool
+func (b *batcher) direct(ctx context.Context, block uint64, target common.Address, data []byte) ([]byte, error) {
+
+	var raw []byte
+	err := b.pool.do(ctx, func(ctx context.Context, client Client) error {
+		b.roundTrips.Add(1)
+		var err error
+		raw, err = client.CallContract(ctx, ethereum.CallMsg{To: &target, Data: data}, new(big.Int).SetUint64(block))
+		return err
+	})
+
+	return raw, err
+}
+
+func (pc *pendingCall) deliver(res callResult) {
+	for _, w := range pc.waiters {
+		w <- res
+	}
+}
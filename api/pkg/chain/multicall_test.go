@@ -0,0 +1,121 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// countingClient answers every token read with a balance of one and aggregate3 calls
+// with one result per call, counting the eth_call requests it receives
+type countingClient struct {
+	calls atomic.Uint64
+}
+
+func (c *countingClient) CallContract(ctx context.Context, msg ethereum.CallMsg, block *big.Int) ([]byte, error) {
+
+	c.calls.Add(1)
+
+	balance, err := tokenABI.Methods["balanceOf"].Outputs.Pack(big.NewInt(1))
+	if err != nil {
+		return nil, err
+	}
+
+	if *msg.To != Multicall3Address {
+		return balance, nil
+	}
+
+	method := multicallABI.Methods["aggregate3"]
+	in, err := method.Inputs.Unpack(msg.Data[4:])
+	if err != nil {
+		return nil, err
+	}
+
+	calls := *abi.ConvertType(in[0], new([]multicallCall)).(*[]multicallCall)
+	results := make([]multicallResult, len(calls))
+	for i := range calls {
+		results[i] = multicallResult{Success: true, ReturnData: balance}
+	}
+
+	return method.Outputs.Pack(results)
+}
+
+func (c *countingClient) BlockNumber(ctx context.Context) (uint64, error) {
+	return 1, nil
+}
+
+func (c *countingClient) BalanceAt(ctx context.Context, account common.Address, block *big.Int) (*big.Int, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *countingClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *countingClient) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *countingClient) Close() {}
+
+// BenchmarkConcurrentReads runs a burst of concurrent balance reads per iteration,
+// as a feed page checking the access of many posts does, and reports how many
+// eth_call requests they took with and without Multicall3 batching
+func BenchmarkConcurrentReads(b *testing.B) {
+
+	for _, burst := range []int{16, 64} {
+		for _, size := range []int{0, 16, 64} {
+			name := fmt.Sprintf("reads=%d/batch=%d", burst, size)
+			b.Run(name, func(b *testing.B) {
+				benchmarkReads(b, burst, size)
+			})
+		}
+	}
+}
+
+func benchmarkReads(b *testing.B, burst, size int) {
+
+	client := &countingClient{}
+	r := NewReader(map[uint64][]Client{1: {client}}, Options{
+		BatchSize:   size,
+		BatchWindow: time.Millisecond,
+	})
+	defer r.Close()
+
+	ctx := context.Background()
+	token := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var wg sync.WaitGroup
+		for j := 0; j < burst; j++ {
+			// every owner is new so no read is served from cache
+			owner := common.BigToAddress(big.NewInt(int64(i*burst + j + 1)))
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := r.BalanceOf(ctx, 1, token, owner); err != nil {
+					b.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	b.StopTimer()
+
+	stats := r.Stats()
+	b.ReportMetric(float64(stats.Reads)/float64(b.N), "reads/op")
+	b.ReportMetric(float64(stats.RoundTrips)/float64(b.N), "roundtrips/op")
+}
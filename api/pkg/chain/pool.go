@@ -0,0 +1,113 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// endpoint is one rpc url of a chain
+type endpoint struct {
+	name      string
+	client    Client
+	downUntil time.Time
+}
+
+// pool spreads the calls of a chain over its endpoints. Endpoints are tried in the
+// configured order, one that fails is skipped until its cooldown expires
+type pool struct {
+	chainID   uint64
+	timeout   time.Duration
+	cooldown  time.Duration
+	mu        sync.Mutex
+	endpoints []*endpoint
+}
+
+// do runs fn against the endpoints until one succeeds. Reverts are returned right
+// away since every endpoint would give the same answer
+func (p *pool) do(ctx context.Context, fn func(ctx context.Context, client Client) error) error {
+
+	var lastErr error
+
+	for _, ep := range p.candidates() {
+		callCtx, cancel := context.WithTimeout(ctx, p.timeout)
+		err := fn(callCtx, ep.client)
+		cancel()
+
+		if err == nil {
+			p.markUp(ep)
+			return nil
+		}
+
+		if isReverted(err) || ctx.Err() != nil {
+			return err
+		}
+
+		p.markDown(ep)
+		lastErr = fmt.Errorf("%s: %w", ep.name, err)
+	}
+
+	if lastErr == nil {
+		return ErrNoHealthyRPC
+	}
+
+	return fmt.Errorf("%w on chain %d: %v", ErrNoHealthyRPC, p.chainID, lastErr)
+}
+
+// candidates returns healthy endpoints first, followed by the ones cooling down so a
+// request is still attempted when every endpoint recently failed
+func (p *pool) candidates() []*endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	healthy := make([]*endpoint, 0, len(p.endpoints))
+	down := make([]*endpoint, 0)
+
+	for _, ep := range p.endpoints {
+		if now.Before(ep.downUntil) {
+			down = append(down, ep)
+		} else {
+			healthy = append(healthy, ep)
+		}
+	}
+
+	return append(healthy, down...)
+}
+
+func (p *pool) markDown(ep *endpoint) {
+	p.mu.Lock()
+	ep.downUntil = time.Now().Add(p.cooldown)
+	p.mu.Unlock()
+}
+
+func (p *pool) markUp(ep *endpoint) {
+	p.mu.Lock()
+	ep.downUntil = time.Time{}
+	p.mu.Unlock()
+}
+
+func (p *pool) close() {
+	for _, ep := range p.endpoints {
+		ep.client.Close()
+	}
+}
+
+// isReverted reports if the node executed the call and it reverted, as opposed to a
+// transport or node failure
+func isReverted(err error) bool {
+	if errors.Is(err, ErrCallReverted) {
+		return true
+	}
+
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == 3 {
+		return true
+	}
+	return strings.Contains(err.Error(), "execution reverted")
+}
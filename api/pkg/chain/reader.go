@@ -0,0 +1,288 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+var _ Client = (*ethclient.Client)(nil)
+
+// Dial connects to every configured rpc url, keyed by chain ID
+func Dial(ctx context.Context, urls map[uint64][]string, opts Options) (Reader, error) {
+
+	clients := make(map[uint64][]Client, len(urls))
+	names := make(map[uint64][]string, len(urls))
+
+	for chainID, chainURLs := range urls {
+		for _, url := range chainURLs {
+			client, err := ethclient.DialContext(ctx, url)
+			if err != nil {
+				for _, opened := range clients {
+					for _, c := range opened {
+						c.Close()
+					}
+				}
+				return nil, fmt.Errorf("rpc dial for chain %d failed %w", chainID, err)
+			}
+			clients[chainID] = append(clients[chainID], client)
+			names[chainID] = append(names[chainID], url)
+		}
+	}
+
+	return newReader(clients, names, opts), nil
+}
+
+// NewReader builds a reader over already connected clients, eg. the client of a
+// simulated backend
+func NewReader(clients map[uint64][]Client, opts Options) Reader {
+	return newReader(clients, nil, opts)
+}
+
+func newReader(clients map[uint64][]Client, names map[uint64][]string, opts Options) *reader {
+
+	opts = opts.withDefaults()
+
+	headTTL := opts.CacheTTL / 4
+	if headTTL < time.Second {
+		headTTL = time.Second
+	}
+
+	r := &reader{
+		pools:    make(map[uint64]*pool, len(clients)),
+		batchers: make(map[uint64]*batcher, len(clients)),
+		cache:    newTTLCache(opts.CacheTTL),
+		heads:    newTTLCache(headTTL),
+	}
+
+	for chainID, chainClients := range clients {
+		p := &pool{
+			chainID:  chainID,
+			timeout:  opts.Timeout,
+			cooldown: opts.Cooldown,
+		}
+		for i, client := range chainClients {
+			name := fmt.Sprintf("rpc #%d", i)
+			if i < len(names[chainID]) {
+				name = names[chainID][i]
+			}
+			p.endpoints = append(p.endpoints, &endpoint{name: name, client: client})
+		}
+		r.pools[chainID] = p
+		r.batchers[chainID] = newBatcher(p, opts)
+	}
+
+	return r
+}
+
+type reader struct {
+	pools    map[uint64]*pool
+	batchers map[uint64]*batcher
+	// cache holds call results keyed by chain, block and call data
+	cache *ttlCache
+	// heads holds the latest block number of each chain
+	heads *ttlCache
+}
+
+func (r *reader) BalanceOf(ctx context.Context, chainID uint64, token, owner common.Address) (*big.Int, error) {
+
+	out, err := r.call(ctx, chainID, token, tokenABI, "balanceOf", owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return out[0].(*big.Int), nil
+}
+
+func (r *reader) OwnerOf(ctx context.Context, chainID uint64, token common.Address, tokenID *big.Int) (common.Address, error) {
+
+	out, err := r.call(ctx, chainID, token, tokenABI, "ownerOf", tokenID)
+	if err != nil {
+		if isReverted(err) {
+			return common.Address{}, nil
+		}
+		return common.Address{}, err
+	}
+
+	return out[0].(common.Address), nil
+}
+
+func (r *reader) ERC1155BalanceOf(ctx context.Context, chainID uint64, token, owner common.Address, tokenID *big.Int) (*big.Int, error) {
+
+	out, err := r.call(ctx, chainID, token, erc1155ABI, "balanceOf", owner, tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	return out[0].(*big.Int), nil
+}
+
+func (r *reader) NativeBalance(ctx context.Context, chainID uint64, owner common.Address) (*big.Int, error) {
+
+	b, ok := r.batchers[chainID]
+	if ok && b.size > 1 {
+		// Multicall3 exposes balances so they can ride along with token reads
+		out, err := r.call(ctx, chainID, b.multicall, multicallABI, "getEthBalance", owner)
+		if err != nil {
+			return nil, err
+		}
+		return out[0].(*big.Int), nil
+	}
+
+	p, block, err := r.poolAt(ctx, chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("%d:%d:native:%s", chainID, block, owner.Hex())
+	if cached, ok := r.cache.get(key); ok {
+		return cached.(*big.Int), nil
+	}
+
+	var balance *big.Int
+	err = p.do(ctx, func(ctx context.Context, client Client) error {
+		var err error
+		balance, err = client.BalanceAt(ctx, owner, new(big.Int).SetUint64(block))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.cache.set(key, balance)
+	return balance, nil
+}
+
+func (r *reader) BlockNumber(ctx context.Context, chainID uint64) (uint64, error) {
+
+	p, ok := r.pools[chainID]
+	if !ok {
+		return 0, fmt.Errorf("%w: %d", ErrUnsupportedChain, chainID)
+	}
+
+	key := fmt.Sprint(chainID)
+	if cached, ok := r.heads.get(key); ok {
+		return cached.(uint64), nil
+	}
+
+	var block uint64
+	err := p.do(ctx, func(ctx context.Context, client Client) error {
+		var err error
+		block, err = client.BlockNumber(ctx)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	r.heads.set(key, block)
+	return block, nil
+}
+
+func (r *reader) HeaderByNumber(ctx context.Context, chainID uint64, number uint64) (*types.Header, error) {
+
+	p, ok := r.pools[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedChain, chainID)
+	}
+
+	var header *types.Header
+	err := p.do(ctx, func(ctx context.Context, client Client) error {
+		var err error
+		header, err = client.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+		return err
+	})
+
+	return header, err
+}
+
+func (r *reader) FilterLogs(ctx context.Context, chainID uint64, query ethereum.FilterQuery) ([]types.Log, error) {
+
+	p, ok := r.pools[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedChain, chainID)
+	}
+
+	var logs []types.Log
+	err := p.do(ctx, func(ctx context.Context, client Client) error {
+		var err error
+		logs, err = client.FilterLogs(ctx, query)
+		return err
+	})
+
+	return logs, err
+}
+
+func (r *reader) Stats() Stats {
+
+	var total Stats
+	for _, b := range r.batchers {
+		s := b.stats()
+		total.Reads += s.Reads
+		total.RoundTrips += s.RoundTrips
+	}
+
+	return total
+}
+
+func (r *reader) Close() {
+	for _, p := range r.pools {
+		p.close()
+	}
+}
+
+// poolAt returns the pool of the chain and the block reads should be pinned to
+func (r *reader) poolAt(ctx context.Context, chainID uint64) (*pool, uint64, error) {
+
+	p, ok := r.pools[chainID]
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: %d", ErrUnsupportedChain, chainID)
+	}
+
+	block, err := r.BlockNumber(ctx, chainID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return p, block, nil
+}
+
+// call runs a view function at the latest block, serving repeated calls from cache.
+// Concurrent calls are grouped into Multicall3 batches by the chain's batcher
+func (r *reader) call(ctx context.Context, chainID uint64, contract common.Address, contractABI abi.ABI, method string, args ...any) ([]any, error) {
+
+	data, err := contractABI.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s call packing failed %w", method, err)
+	}
+
+	_, block, err := r.poolAt(ctx, chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("%d:%d:%s:%x", chainID, block, contract.Hex(), data)
+	if cached, ok := r.cache.get(key); ok {
+		return cached.([]any), nil
+	}
+
+	raw, err := r.batchers[chainID].call(ctx, block, contract, data)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := contractABI.Unpack(method, raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s result unpacking failed %w", method, err)
+	}
+
+	r.cache.set(key, out)
+	return out, nil
+}
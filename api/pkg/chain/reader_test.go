@@ -0,0 +1,182 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+)
+
+const simulatedChainID = 1337
+
+var (
+	holder = common.HexToAddress("0x0000000000000000000000000000000000001000")
+
+	// token answers every call with 7, enough to stand for balanceOf
+	token     = common.HexToAddress("0x0000000000000000000000000000000000002000")
+	tokenCode = common.FromHex("0x600760005260206000f3")
+
+	// burned reverts every call, as ownerOf does for a token which does not exist
+	burned     = common.HexToAddress("0x0000000000000000000000000000000000003000")
+	burnedCode = common.FromHex("0x60006000fd")
+
+	holderBalance = big.NewInt(1_000_000_000)
+)
+
+// simulatedClient adapts the client of a simulated backend to Client and counts the
+// reads sent to it, the backend itself is closed by the test
+type simulatedClient struct {
+	simulated.Client
+	balanceReads atomic.Uint64
+}
+
+func (c *simulatedClient) BalanceAt(ctx context.Context, account common.Address, block *big.Int) (*big.Int, error) {
+	c.balanceReads.Add(1)
+	return c.Client.BalanceAt(ctx, account, block)
+}
+
+func (c *simulatedClient) Close() {}
+
+// failingClient stands for an rpc endpoint which is down
+type failingClient struct {
+	calls atomic.Uint64
+}
+
+var errEndpointDown = errors.New("connection refused")
+
+func (c *failingClient) BlockNumber(ctx context.Context) (uint64, error) {
+	c.calls.Add(1)
+	return 0, errEndpointDown
+}
+
+func (c *failingClient) CallContract(ctx context.Context, msg ethereum.CallMsg, block *big.Int) ([]byte, error) {
+	c.calls.Add(1)
+	return nil, errEndpointDown
+}
+
+func (c *failingClient) BalanceAt(ctx context.Context, account common.Address, block *big.Int) (*big.Int, error) {
+	c.calls.Add(1)
+	return nil, errEndpointDown
+}
+
+func (c *failingClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	c.calls.Add(1)
+	return nil, errEndpointDown
+}
+
+func (c *failingClient) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	c.calls.Add(1)
+	return nil, errEndpointDown
+}
+
+func (c *failingClient) Close() {}
+
+func newSimulatedClient(t *testing.T) *simulatedClient {
+	t.Helper()
+
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		holder: {Balance: holderBalance},
+		token:  {Code: tokenCode, Balance: new(big.Int)},
+		burned: {Code: burnedCode, Balance: new(big.Int)},
+	})
+	t.Cleanup(func() { backend.Close() })
+
+	return &simulatedClient{Client: backend.Client()}
+}
+
+func TestReaderReads(t *testing.T) {
+
+	client := newSimulatedClient(t)
+	r := NewReader(map[uint64][]Client{simulatedChainID: {client}}, Options{})
+	defer r.Close()
+
+	ctx := context.Background()
+
+	balance, err := r.NativeBalance(ctx, simulatedChainID, holder)
+	if err != nil {
+		t.Fatalf("native balance read failed: %v", err)
+	}
+	if balance.Cmp(holderBalance) != 0 {
+		t.Errorf("native balance = %s, want %s", balance, holderBalance)
+	}
+
+	tokens, err := r.BalanceOf(ctx, simulatedChainID, token, holder)
+	if err != nil {
+		t.Fatalf("token balance read failed: %v", err)
+	}
+	if tokens.Int64() != 7 {
+		t.Errorf("token balance = %s, want 7", tokens)
+	}
+
+	owner, err := r.OwnerOf(ctx, simulatedChainID, burned, big.NewInt(1))
+	if err != nil {
+		t.Fatalf("owner read of a burned token failed: %v", err)
+	}
+	if owner != (common.Address{}) {
+		t.Errorf("owner of a burned token = %s, want the zero address", owner.Hex())
+	}
+
+	if _, err := r.BalanceOf(ctx, 1, token, holder); !errors.Is(err, ErrUnsupportedChain) {
+		t.Errorf("read on an unknown chain returned %v, want ErrUnsupportedChain", err)
+	}
+}
+
+func TestReaderFailover(t *testing.T) {
+
+	down := &failingClient{}
+	client := newSimulatedClient(t)
+	r := NewReader(map[uint64][]Client{simulatedChainID: {down, client}}, Options{})
+	defer r.Close()
+
+	ctx := context.Background()
+
+	balance, err := r.NativeBalance(ctx, simulatedChainID, holder)
+	if err != nil {
+		t.Fatalf("read with a failing first endpoint failed: %v", err)
+	}
+	if balance.Cmp(holderBalance) != 0 {
+		t.Errorf("native balance = %s, want %s", balance, holderBalance)
+	}
+	if down.calls.Load() == 0 {
+		t.Error("the first endpoint was never tried")
+	}
+
+	tried := down.calls.Load()
+	if _, err := r.BalanceOf(ctx, simulatedChainID, token, holder); err != nil {
+		t.Fatalf("token read after failover failed: %v", err)
+	}
+	if down.calls.Load() != tried {
+		t.Error("the failing endpoint was tried again during its cooldown")
+	}
+}
+
+func TestReaderCache(t *testing.T) {
+
+	client := newSimulatedClient(t)
+	r := NewReader(map[uint64][]Client{simulatedChainID: {client}}, Options{})
+	defer r.Close()
+
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := r.BalanceOf(ctx, simulatedChainID, token, holder); err != nil {
+			t.Fatalf("token read failed: %v", err)
+		}
+		if _, err := r.NativeBalance(ctx, simulatedChainID, holder); err != nil {
+			t.Fatalf("native balance read failed: %v", err)
+		}
+	}
+
+	if stats := r.Stats(); stats.Reads != 1 || stats.RoundTrips != 1 {
+		t.Errorf("stats = %+v, want the repeated token read served from cache", stats)
+	}
+	if reads := client.balanceReads.Load(); reads != 1 {
+		t.Errorf("native balance was read %d times, want the repeat served from cache", reads)
+	}
+}
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"encoding/base64"
+	"fmt"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -21,6 +25,111 @@ type Config struct {
 	WriteTimeout       time.Duration `json:"write_timeout"`
 	IdleTimeout        time.Duration `json:"idle_timeout"`
 	MaxBodySizeAllowed int64         `json:"max_body_size_allowed"`
+
+	// ChainRPCURLs lists rpc endpoints per chain ID in failover order
+	ChainRPCURLs    map[uint64][]string `mapstructure:"CHAIN_RPC_URLS"`
+	ChainRPCTimeout time.Duration       `mapstructure:"CHAIN_RPC_TIMEOUT"`
+	ChainCacheTTL   time.Duration       `mapstructure:"CHAIN_CACHE_TTL"`
+	// MulticallBatchSize caps reads grouped into one Multicall3 call, 1 disables batching
+	MulticallBatchSize int `mapstructure:"MULTICALL_BATCH_SIZE"`
+
+	// IndexerStartBlocks is the first block indexed per chain ID, chains without one
+	// are read over rpc only
+	IndexerStartBlocks  map[uint64]uint64 `mapstructure:"INDEXER_START_BLOCKS"`
+	IndexerPollInterval time.Duration     `mapstructure:"INDEXER_POLL_INTERVAL"`
+	IndexerBlockRange   uint64            `mapstructure:"INDEXER_BLOCK_RANGE"`
+
+	// AccessCacheTTL is how long an access decision is reused, 0 disables the cache
+	AccessCacheTTL time.Duration `mapstructure:"ACCESS_CACHE_TTL"`
+	// AccessCacheBypass honours the X-Access-Cache: bypass request header outside
+	// production
+	AccessCacheBypass bool `mapstructure:"ACCESS_CACHE_BYPASS"`
+
+	// StorageDriver selects where media is kept, local or s3
+	StorageDriver   string `mapstructure:"STORAGE_DRIVER"`
+	StorageLocalDir string `mapstructure:"STORAGE_LOCAL_DIR"`
+	S3Endpoint      string `mapstructure:"S3_ENDPOINT"`
+	S3Region        string `mapstructure:"S3_REGION"`
+	S3Bucket        string `mapstructure:"S3_BUCKET"`
+	S3AccessKey     string `mapstructure:"S3_ACCESS_KEY"`
+	S3SecretKey     string `mapstructure:"S3_SECRET_KEY"`
+	S3UseSSL        bool   `mapstructure:"S3_USE_SSL"`
+	// MediaMaxUploadSize caps a single media upload in bytes
+	MediaMaxUploadSize int64 `mapstructure:"MEDIA_MAX_UPLOAD_SIZE"`
+	// MediaURLTTL is how long a signed download url stays valid
+	MediaURLTTL time.Duration `mapstructure:"MEDIA_URL_TTL"`
+	// MediaURLSecret signs download urls, LinkSigningSecret is used when it is empty
+	MediaURLSecret string `mapstructure:"MEDIA_URL_SECRET"`
+	// LinkSigningSecret signs the links handed out beyond a session, invite and
+	// unsubscribe links. It is kept apart from JwtSecret so rotating one does not break
+	// the other
+	LinkSigningSecret string `mapstructure:"LINK_SIGNING_SECRET"`
+	// StorageQuota caps the bytes stored per creator across files and unfinished
+	// uploads, 0 disables it
+	StorageQuota int64 `mapstructure:"STORAGE_QUOTA"`
+
+	// UploadMaxSize caps the declared length of a resumable upload in bytes
+	UploadMaxSize int64 `mapstructure:"UPLOAD_MAX_SIZE"`
+	// UploadExpiry is how long an unfinished upload is kept after its last chunk
+	UploadExpiry time.Duration `mapstructure:"UPLOAD_EXPIRY"`
+
+	// EncryptionKeys holds the base64 master keys by id, content is stored in plaintext
+	// when empty. EncryptionKeyID is the active key wrapping new data keys
+	EncryptionKeys  map[string][]byte `mapstructure:"ENCRYPTION_KEYS"`
+	EncryptionKeyID string            `json:"encryption_key_id"`
+
+	// ImageVariantWidths are the widths uploaded images are resized to, narrower
+	// images skip the larger ones
+	ImageVariantWidths []int `mapstructure:"IMAGE_VARIANT_WIDTHS"`
+	// ImagePreviewWidth is the width of the blurred preview shown for locked posts
+	ImagePreviewWidth int `mapstructure:"IMAGE_PREVIEW_WIDTH"`
+	// ImageMaxPixels caps the dimensions of images the pipeline decodes
+	ImageMaxPixels int `mapstructure:"IMAGE_MAX_PIXELS"`
+
+	// Transcoder packages videos into HLS, ffmpeg or mock. Left empty ffmpeg is used
+	// when found on the PATH, none disables packaging
+	Transcoder string `mapstructure:"TRANSCODER"`
+	// HLSRenditions maps the rendition heights to their video bitrate in kbit/s
+	HLSRenditions map[int]int `mapstructure:"HLS_RENDITIONS"`
+	// HLSSegmentDuration is the target length of a segment
+	HLSSegmentDuration time.Duration `mapstructure:"HLS_SEGMENT_DURATION"`
+	// HLSSessionTTL is how long the signed playlist and segment urls of a playback
+	// session stay valid
+	HLSSessionTTL time.Duration `mapstructure:"HLS_SESSION_TTL"`
+
+	// ChatRecheckInterval is how often the membership behind an open chat connection
+	// is evaluated again, members who no longer hold the gating tokens are removed
+	ChatRecheckInterval time.Duration `mapstructure:"CHAT_RECHECK_INTERVAL"`
+
+	// VAPIDPublicKey and VAPIDPrivateKey identify the server to browser push services,
+	// push notifications are disabled without them. make vapid generates a pair
+	VAPIDPublicKey  string `mapstructure:"VAPID_PUBLIC_KEY"`
+	VAPIDPrivateKey string `mapstructure:"VAPID_PRIVATE_KEY"`
+	// VAPIDSubject is a mailto: or https: contact handed to push service operators
+	VAPIDSubject string `mapstructure:"VAPID_SUBJECT"`
+	// PushEndpoint replaces the endpoint of every push subscription when set, so
+	// messages reach a local test server instead of the browser vendors. Subscriptions
+	// outside the known push services are only accepted then
+	PushEndpoint string `mapstructure:"PUSH_ENDPOINT"`
+
+	// SMTPHost is the server emails are sent through, email is disabled without it.
+	// MailHog catches them locally on port 1025
+	SMTPHost     string `mapstructure:"SMTP_HOST"`
+	SMTPPort     int    `mapstructure:"SMTP_PORT"`
+	SMTPUsername string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword string `mapstructure:"SMTP_PASSWORD"`
+	// SMTPFrom is the sender of every email, no-reply at the domain by default
+	SMTPFrom string `mapstructure:"SMTP_FROM"`
+
+	// FeedEngagementBoost is how far ahead in the home feed a post moves per e-fold of
+	// reactions and comments, 0 ranks the feed by recency alone
+	FeedEngagementBoost time.Duration `mapstructure:"FEED_ENGAGEMENT_BOOST"`
+	// FeedWindow is how far back the home feed reaches
+	FeedWindow time.Duration `mapstructure:"FEED_WINDOW"`
+
+	// RevisionDraftRetention is how long revisions saved while a post was not published
+	// are kept, the latest revision of a post is never pruned
+	RevisionDraftRetention time.Duration `mapstructure:"REVISION_DRAFT_RETENTION"`
 }
 
 func NewConfig(path string) (*Config, error) {
@@ -40,18 +149,353 @@ func NewConfig(path string) (*Config, error) {
 		refreshTokenTTL = 604800 // default 7 days
 	}
 
+	chainRPCURLs, err := parseChainRPCURLs(os.Getenv("CHAIN_RPC_URLS"))
+	if err != nil {
+		return nil, err
+	}
+
+	chainRPCTimeout, err := strconv.Atoi(os.Getenv("CHAIN_RPC_TIMEOUT"))
+	if err != nil {
+		chainRPCTimeout = 5 // default 5 seconds
+	}
+
+	chainCacheTTL, err := strconv.Atoi(os.Getenv("CHAIN_CACHE_TTL"))
+	if err != nil {
+		chainCacheTTL = 12 // default 12 seconds, about one ethereum block
+	}
+
+	multicallBatchSize, err := strconv.Atoi(os.Getenv("MULTICALL_BATCH_SIZE"))
+	if err != nil {
+		multicallBatchSize = 100
+	}
+
+	indexerStartBlocks, err := parseIndexerStartBlocks(os.Getenv("INDEXER_START_BLOCKS"))
+	if err != nil {
+		return nil, err
+	}
+
+	indexerPollInterval, err := strconv.Atoi(os.Getenv("INDEXER_POLL_INTERVAL"))
+	if err != nil {
+		indexerPollInterval = 15 // default 15 seconds
+	}
+
+	indexerBlockRange, err := strconv.ParseUint(os.Getenv("INDEXER_BLOCK_RANGE"), 10, 64)
+	if err != nil {
+		indexerBlockRange = 2000 // most providers cap eth_getLogs ranges around this
+	}
+
+	accessCacheTTL, err := strconv.Atoi(os.Getenv("ACCESS_CACHE_TTL"))
+	if err != nil {
+		accessCacheTTL = 30 // default 30 seconds
+	}
+
+	accessCacheBypass, err := strconv.ParseBool(os.Getenv("ACCESS_CACHE_BYPASS"))
+	if err != nil {
+		accessCacheBypass = false // default off, it lets a request skip the cache
+	}
+
+	mediaMaxUploadSize, err := strconv.ParseInt(os.Getenv("MEDIA_MAX_UPLOAD_SIZE"), 10, 64)
+	if err != nil {
+		mediaMaxUploadSize = 100 * 1024 * 1024 // default 100 MB
+	}
+
+	mediaURLTTL, err := strconv.Atoi(os.Getenv("MEDIA_URL_TTL"))
+	if err != nil {
+		mediaURLTTL = 300 // default 5 minutes
+	}
+
+	storageQuota, err := strconv.ParseInt(os.Getenv("STORAGE_QUOTA"), 10, 64)
+	if err != nil {
+		storageQuota = 10 * 1024 * 1024 * 1024 // default 10 GB
+	}
+
+	uploadMaxSize, err := strconv.ParseInt(os.Getenv("UPLOAD_MAX_SIZE"), 10, 64)
+	if err != nil {
+		uploadMaxSize = 5 * 1024 * 1024 * 1024 // default 5 GB
+	}
+
+	uploadExpiry, err := strconv.Atoi(os.Getenv("UPLOAD_EXPIRY"))
+	if err != nil {
+		uploadExpiry = 86400 // default 1 day
+	}
+
+	encryptionKeys, encryptionKeyID, err := parseEncryptionKeys(os.Getenv("ENCRYPTION_KEYS"))
+	if err != nil {
+		return nil, err
+	}
+
+	imageVariantWidths, err := parseImageVariantWidths(os.Getenv("IMAGE_VARIANT_WIDTHS"))
+	if err != nil {
+		return nil, err
+	}
+
+	imagePreviewWidth, err := strconv.Atoi(os.Getenv("IMAGE_PREVIEW_WIDTH"))
+	if err != nil {
+		imagePreviewWidth = 480 // default 480 px
+	}
+
+	imageMaxPixels, err := strconv.Atoi(os.Getenv("IMAGE_MAX_PIXELS"))
+	if err != nil {
+		imageMaxPixels = 50_000_000 // default 50 megapixels
+	}
+
+	hlsRenditions, err := parseHLSRenditions(os.Getenv("HLS_RENDITIONS"))
+	if err != nil {
+		return nil, err
+	}
+
+	hlsSegmentDuration, err := strconv.Atoi(os.Getenv("HLS_SEGMENT_DURATION"))
+	if err != nil {
+		hlsSegmentDuration = 6 // default 6 seconds
+	}
+
+	hlsSessionTTL, err := strconv.Atoi(os.Getenv("HLS_SESSION_TTL"))
+	if err != nil {
+		hlsSessionTTL = 21600 // default 6 hours
+	}
+
+	chatRecheckInterval, err := strconv.Atoi(os.Getenv("CHAT_RECHECK_INTERVAL"))
+	if err != nil || chatRecheckInterval <= 0 {
+		chatRecheckInterval = 300 // default 5 minutes
+	}
+
+	smtpPort, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+	if err != nil || smtpPort <= 0 {
+		smtpPort = 587 // default submission port
+	}
+
+	smtpFrom := os.Getenv("SMTP_FROM")
+	if smtpFrom == "" {
+		smtpFrom = "no-reply@" + os.Getenv("DOMAIN")
+	}
+
+	feedEngagementBoost, err := strconv.Atoi(os.Getenv("FEED_ENGAGEMENT_BOOST"))
+	if err != nil || feedEngagementBoost < 0 {
+		feedEngagementBoost = 21600 // default 6 hours
+	}
+
+	feedWindow, err := strconv.Atoi(os.Getenv("FEED_WINDOW"))
+	if err != nil || feedWindow <= 0 {
+		feedWindow = 2592000 // default 30 days
+	}
+
+	revisionDraftRetention, err := strconv.Atoi(os.Getenv("REVISION_DRAFT_RETENTION"))
+	if err != nil || revisionDraftRetention <= 0 {
+		revisionDraftRetention = 7776000 // default 90 days
+	}
+
+	s3UseSSL, _ := strconv.ParseBool(os.Getenv("S3_USE_SSL"))
+
 	return &Config{
-		DbConn:             os.Getenv("DB_CONN"),
-		Env:                os.Getenv("ENV"),
-		Port:               os.Getenv("PORT"),
-		Domain:             os.Getenv("DOMAIN"),
-		JwtSecret:          os.Getenv("JWT_SECRET"),
-		AccessTokenExpiry:  accessTokenTTL,
-		RefreshTokenExpiry: refreshTokenTTL,
-		MaxHeaderBytes:     1 << 20,
-		ReadTimeout:        10 * time.Second,
-		WriteTimeout:       10 * time.Second,
-		IdleTimeout:        10 * time.Second,
-		MaxBodySizeAllowed: 1 * 1024 * 1024,
+		DbConn:              os.Getenv("DB_CONN"),
+		Env:                 os.Getenv("ENV"),
+		Port:                os.Getenv("PORT"),
+		Domain:              os.Getenv("DOMAIN"),
+		JwtSecret:           os.Getenv("JWT_SECRET"),
+		AccessTokenExpiry:   accessTokenTTL,
+		RefreshTokenExpiry:  refreshTokenTTL,
+		MaxHeaderBytes:      1 << 20,
+		ReadTimeout:         10 * time.Second,
+		WriteTimeout:        10 * time.Second,
+		IdleTimeout:         10 * time.Second,
+		MaxBodySizeAllowed:  1 * 1024 * 1024,
+		ChainRPCURLs:        chainRPCURLs,
+		ChainRPCTimeout:     time.Duration(chainRPCTimeout) * time.Second,
+		ChainCacheTTL:       time.Duration(chainCacheTTL) * time.Second,
+		MulticallBatchSize:  multicallBatchSize,
+		IndexerStartBlocks:  indexerStartBlocks,
+		IndexerPollInterval: time.Duration(indexerPollInterval) * time.Second,
+		IndexerBlockRange:   indexerBlockRange,
+		AccessCacheTTL:      time.Duration(accessCacheTTL) * time.Second,
+		AccessCacheBypass:   accessCacheBypass,
+		StorageDriver:       os.Getenv("STORAGE_DRIVER"),
+		StorageLocalDir:     os.Getenv("STORAGE_LOCAL_DIR"),
+		S3Endpoint:          os.Getenv("S3_ENDPOINT"),
+		S3Region:            os.Getenv("S3_REGION"),
+		S3Bucket:            os.Getenv("S3_BUCKET"),
+		S3AccessKey:         os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey:         os.Getenv("S3_SECRET_KEY"),
+		S3UseSSL:            s3UseSSL,
+		MediaMaxUploadSize:  mediaMaxUploadSize,
+		MediaURLTTL:         time.Duration(mediaURLTTL) * time.Second,
+		MediaURLSecret:      os.Getenv("MEDIA_URL_SECRET"),
+		LinkSigningSecret:   os.Getenv("LINK_SIGNING_SECRET"),
+		StorageQuota:        storageQuota,
+		UploadMaxSize:       uploadMaxSize,
+		UploadExpiry:        time.Duration(uploadExpiry) * time.Second,
+		EncryptionKeys:      encryptionKeys,
+		EncryptionKeyID:     encryptionKeyID,
+		ImageVariantWidths:  imageVariantWidths,
+		ImagePreviewWidth:   imagePreviewWidth,
+		ImageMaxPixels:      imageMaxPixels,
+		Transcoder:          os.Getenv("TRANSCODER"),
+		HLSRenditions:       hlsRenditions,
+		HLSSegmentDuration:  time.Duration(hlsSegmentDuration) * time.Second,
+		HLSSessionTTL:       time.Duration(hlsSessionTTL) * time.Second,
+		ChatRecheckInterval: time.Duration(chatRecheckInterval) * time.Second,
+		VAPIDPublicKey:      os.Getenv("VAPID_PUBLIC_KEY"),
+		VAPIDPrivateKey:     os.Getenv("VAPID_PRIVATE_KEY"),
+		VAPIDSubject:        os.Getenv("VAPID_SUBJECT"),
+		PushEndpoint:        os.Getenv("PUSH_ENDPOINT"),
+		SMTPHost:            os.Getenv("SMTP_HOST"),
+		SMTPPort:            smtpPort,
+		SMTPUsername:        os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:        os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:            smtpFrom,
+		FeedEngagementBoost: time.Duration(feedEngagementBoost) * time.Second,
+		FeedWindow:          time.Duration(feedWindow) * time.Second,
+
+		RevisionDraftRetention: time.Duration(revisionDraftRetention) * time.Second,
 	}, nil
 }
+
+// parseChainRPCURLs reads "1=https://a|https://b,137=https://c" into urls per chain ID
+func parseChainRPCURLs(raw string) (map[uint64][]string, error) {
+
+	urls := make(map[uint64][]string)
+
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		chain, list, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("CHAIN_RPC_URLS entry %q is not chainID=url", entry)
+		}
+
+		chainID, err := strconv.ParseUint(strings.TrimSpace(chain), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("CHAIN_RPC_URLS chain id %q is invalid", chain)
+		}
+
+		for _, url := range strings.Split(list, "|") {
+			if url = strings.TrimSpace(url); url != "" {
+				urls[chainID] = append(urls[chainID], url)
+			}
+		}
+	}
+
+	return urls, nil
+}
+
+// parseIndexerStartBlocks reads "1=18000000,137=50000000" into a start block per chain ID
+func parseIndexerStartBlocks(raw string) (map[uint64]uint64, error) {
+
+	blocks := make(map[uint64]uint64)
+
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		chain, block, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("INDEXER_START_BLOCKS entry %q is not chainID=block", entry)
+		}
+
+		chainID, err := strconv.ParseUint(strings.TrimSpace(chain), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("INDEXER_START_BLOCKS chain id %q is invalid", chain)
+		}
+
+		start, err := strconv.ParseUint(strings.TrimSpace(block), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("INDEXER_START_BLOCKS block %q is invalid", block)
+		}
+
+		blocks[chainID] = start
+	}
+
+	return blocks, nil
+}
+
+// parseEncryptionKeys reads "2025-06=base64key,2024-01=base64key" into master keys by
+// id. The first key is the active one, the others are kept until rotation completes
+func parseEncryptionKeys(raw string) (map[string][]byte, string, error) {
+
+	keys := make(map[string][]byte)
+	active := ""
+
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(entry, "=")
+		id = strings.TrimSpace(id)
+		if !ok || id == "" {
+			return nil, "", fmt.Errorf("ENCRYPTION_KEYS entry for %q is not id=key", id)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, "", fmt.Errorf("ENCRYPTION_KEYS key %q is not base64", id)
+		}
+
+		if active == "" {
+			active = id
+		}
+		keys[id] = key
+	}
+
+	return keys, active, nil
+}
+
+// parseImageVariantWidths reads "320,640,1280" into ascending widths, empty keeps the
+// defaults
+func parseImageVariantWidths(raw string) ([]int, error) {
+
+	if strings.TrimSpace(raw) == "" {
+		return []int{320, 640, 1280}, nil
+	}
+
+	var widths []int
+
+	for _, entry := range strings.Split(raw, ",") {
+		width, err := strconv.Atoi(strings.TrimSpace(entry))
+		if err != nil || width <= 0 {
+			return nil, fmt.Errorf("IMAGE_VARIANT_WIDTHS width %q is invalid", entry)
+		}
+		widths = append(widths, width)
+	}
+
+	slices.Sort(widths)
+
+	return slices.Compact(widths), nil
+}
+
+// parseHLSRenditions reads "360=800,720=2800" into video bitrates in kbit/s by
+// rendition height, empty keeps the defaults
+func parseHLSRenditions(raw string) (map[int]int, error) {
+
+	if strings.TrimSpace(raw) == "" {
+		return map[int]int{360: 800, 720: 2800, 1080: 5000}, nil
+	}
+
+	renditions := make(map[int]int)
+
+	for _, entry := range strings.Split(raw, ",") {
+		height, bitrate, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			return nil, fmt.Errorf("HLS_RENDITIONS entry %q is not height=kbps", entry)
+		}
+
+		h, err := strconv.Atoi(strings.TrimSpace(height))
+		if err != nil || h <= 0 || h%2 != 0 {
+			return nil, fmt.Errorf("HLS_RENDITIONS height %q is invalid", height)
+		}
+
+		kbps, err := strconv.Atoi(strings.TrimSpace(bitrate))
+		if err != nil || kbps <= 0 {
+			return nil, fmt.Errorf("HLS_RENDITIONS bitrate %q is invalid", bitrate)
+		}
+
+		renditions[h] = kbps
+	}
+
+	return renditions, nil
+}
@@ -0,0 +1,173 @@
+// envelope encrypts content with random data keys which are stored next to the
+// content wrapped by a master key. Rotating the master key only rewraps data keys,
+// the content itself is left untouched
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// KeySize is the length of master and data keys, AES-256
+const KeySize = 32
+
+var (
+	ErrUnknownKey = errors.New("master key is not configured")
+	ErrDecrypt    = errors.New("ciphertext can not be decrypted")
+)
+
+// Keyring holds the master keys by id. New data keys are wrapped by the active key,
+// the others are only kept to unwrap data keys which were not rotated yet
+type Keyring struct {
+	active string
+	keys   map[string]cipher.AEAD
+}
+
+func NewKeyring(keys map[string][]byte, active string) (*Keyring, error) {
+
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("active master key %q %w", active, ErrUnknownKey)
+	}
+
+	ring := &Keyring{
+		active: active,
+		keys:   make(map[string]cipher.AEAD, len(keys)),
+	}
+
+	for id, key := range keys {
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("master key %q must be %d bytes", id, KeySize)
+		}
+
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		ring.keys[id] = aead
+	}
+
+	return ring, nil
+}
+
+// ActiveKeyID names the master key wrapping new data keys
+func (k *Keyring) ActiveKeyID() string {
+	return k.active
+}
+
+// NewDataKey creates a random data key and returns it wrapped by the active master key
+func (k *Keyring) NewDataKey() (*DataKey, []byte, error) {
+
+	raw := make([]byte, KeySize)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, nil, err
+	}
+
+	dataKey, err := newDataKey(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wrapped, err := seal(k.keys[k.active], raw, []byte(k.active))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return dataKey, wrapped, nil
+}
+
+// Unwrap decrypts a data key wrapped by the master key keyID
+func (k *Keyring) Unwrap(keyID string, wrapped []byte) (*DataKey, error) {
+
+	raw, err := k.unwrap(keyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	return newDataKey(raw)
+}
+
+// Rewrap wraps a data key with the active master key
+func (k *Keyring) Rewrap(keyID string, wrapped []byte) ([]byte, error) {
+
+	raw, err := k.unwrap(keyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	return seal(k.keys[k.active], raw, []byte(k.active))
+}
+
+func (k *Keyring) unwrap(keyID string, wrapped []byte) ([]byte, error) {
+
+	aead, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("master key %q %w", keyID, ErrUnknownKey)
+	}
+
+	// the key id is authenticated, a data key can not be passed off as wrapped by another
+	return open(aead, wrapped, []byte(keyID))
+}
+
+// DataKey encrypts the content of a single record
+type DataKey struct {
+	aead cipher.AEAD
+}
+
+func newDataKey(raw []byte) (*DataKey, error) {
+
+	aead, err := newAEAD(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DataKey{aead: aead}, nil
+}
+
+// Seal encrypts plaintext bound to aad, which must be given again to Open
+func (d *DataKey) Seal(plaintext, aad []byte) ([]byte, error) {
+	return seal(d.aead, plaintext, aad)
+}
+
+func (d *DataKey) Open(ciphertext, aad []byte) ([]byte, error) {
+	return open(d.aead, ciphertext, aad)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal prefixes the ciphertext with its random nonce
+func seal(aead cipher.AEAD, plaintext, aad []byte) ([]byte, error) {
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(aead cipher.AEAD, ciphertext, aad []byte) ([]byte, error) {
+
+	if len(ciphertext) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrDecrypt
+	}
+
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, sealed, aad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return plaintext, nil
+}
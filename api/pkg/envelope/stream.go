@@ -0,0 +1,252 @@
+package envelope
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	streamVersion = 1
+	// SegmentSize is the plaintext length of every stream segment but the last
+	SegmentSize = 64 * 1024
+
+	prefixSize = 7
+	headerSize = 1 + prefixSize
+	tagSize    = 16
+)
+
+var ErrNotSeekable = errors.New("encrypted stream source is not seekable")
+
+// EncryptStream encrypts r in segments, each authenticated on its own so a stream can
+// be decrypted from any offset. A segment's nonce carries its index and whether it is
+// the last one, which rules out reordered or truncated streams
+func (d *DataKey) EncryptStream(r io.Reader) io.Reader {
+	return &encryptReader{key: d, src: r}
+}
+
+type encryptReader struct {
+	key    *DataKey
+	src    io.Reader
+	prefix []byte
+	index  uint32
+	// carry is a plaintext byte read ahead to tell whether a full segment is the last
+	carry []byte
+	out   []byte
+	done  bool
+	err   error
+}
+
+func (e *encryptReader) Read(p []byte) (int, error) {
+
+	for len(e.out) == 0 {
+		if e.err != nil {
+			return 0, e.err
+		}
+		if e.done {
+			return 0, io.EOF
+		}
+		e.err = e.next()
+	}
+
+	n := copy(p, e.out)
+	e.out = e.out[n:]
+	return n, nil
+}
+
+// next encrypts the following segment into out
+func (e *encryptReader) next() error {
+
+	if e.prefix == nil {
+		e.prefix = make([]byte, prefixSize)
+		if _, err := rand.Read(e.prefix); err != nil {
+			return err
+		}
+		e.out = append([]byte{streamVersion}, e.prefix...)
+		return nil
+	}
+
+	plain := make([]byte, SegmentSize)
+	n := copy(plain, e.carry)
+	e.carry = nil
+
+	m, err := io.ReadFull(e.src, plain[n:])
+	n += m
+
+	last := false
+	switch {
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		last = true
+	case err != nil:
+		return err
+	default:
+		peek := make([]byte, 1)
+		k, err := io.ReadFull(e.src, peek)
+		if errors.Is(err, io.EOF) {
+			last = true
+		} else if err != nil {
+			return err
+		}
+		e.carry = peek[:k]
+	}
+
+	e.out = e.key.aead.Seal(nil, segmentNonce(e.prefix, e.index, last), plain[:n], nil)
+	e.index++
+	e.done = last
+
+	return nil
+}
+
+// StreamReader decrypts a stream written by EncryptStream. It seeks within the
+// plaintext, reading only the segments needed
+type StreamReader struct {
+	key  *DataKey
+	src  io.Reader
+	size int64
+
+	// srcPos is the position of src, it is only moved when a read skips segments
+	srcPos int64
+	prefix []byte
+
+	pos      int64
+	segIndex int64
+	segment  []byte
+}
+
+// DecryptStream reads the stream in src holding size bytes of plaintext. Seeking needs
+// src to be an io.Seeker unless reads stay sequential
+func (d *DataKey) DecryptStream(src io.Reader, size int64) *StreamReader {
+	return &StreamReader{key: d, src: src, size: size, segIndex: -1}
+}
+
+func (s *StreamReader) Read(p []byte) (int, error) {
+
+	if s.pos >= s.size {
+		return 0, io.EOF
+	}
+
+	index := s.pos / SegmentSize
+	if index != s.segIndex {
+		if err := s.load(index); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, s.segment[s.pos-index*SegmentSize:])
+	s.pos += int64(n)
+	return n, nil
+}
+
+func (s *StreamReader) Seek(offset int64, whence int) (int64, error) {
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.pos
+	case io.SeekEnd:
+		offset += s.size
+	default:
+		return 0, errors.New("invalid seek whence")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative seek position")
+	}
+
+	s.pos = offset
+	return offset, nil
+}
+
+// Close closes src when it is an io.Closer
+func (s *StreamReader) Close() error {
+
+	if closer, ok := s.src.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+func (s *StreamReader) load(index int64) error {
+
+	if s.prefix == nil {
+		if err := s.seekSource(0); err != nil {
+			return err
+		}
+
+		header := make([]byte, headerSize)
+		if err := s.readSource(header); err != nil {
+			return err
+		}
+		if header[0] != streamVersion {
+			return ErrDecrypt
+		}
+		s.prefix = header[1:]
+	}
+
+	segments := max((s.size+SegmentSize-1)/SegmentSize, 1)
+	plainLen := min(SegmentSize, s.size-index*SegmentSize)
+
+	if err := s.seekSource(headerSize + index*(SegmentSize+tagSize)); err != nil {
+		return err
+	}
+
+	sealed := make([]byte, plainLen+tagSize)
+	if err := s.readSource(sealed); err != nil {
+		return err
+	}
+
+	plain, err := s.key.aead.Open(sealed[:0], segmentNonce(s.prefix, uint32(index), index == segments-1), sealed, nil)
+	if err != nil {
+		return ErrDecrypt
+	}
+
+	s.segIndex = index
+	s.segment = plain
+	return nil
+}
+
+func (s *StreamReader) seekSource(offset int64) error {
+
+	if offset == s.srcPos {
+		return nil
+	}
+
+	seeker, ok := s.src.(io.Seeker)
+	if !ok {
+		return ErrNotSeekable
+	}
+
+	if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	s.srcPos = offset
+	return nil
+}
+
+func (s *StreamReader) readSource(buf []byte) error {
+
+	n, err := io.ReadFull(s.src, buf)
+	s.srcPos += int64(n)
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		// a stream shorter than its recorded size was truncated
+		return ErrDecrypt
+	}
+
+	return err
+}
+
+func segmentNonce(prefix []byte, index uint32, last bool) []byte {
+
+	nonce := make([]byte, prefixSize+5)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[prefixSize:], index)
+	if last {
+		nonce[prefixSize+4] = 1
+	}
+
+	return nonce
+}
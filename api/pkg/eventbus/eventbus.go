@@ -0,0 +1,122 @@
+// eventbus decouples the producers of domain events from their consumers inside this
+// process. Publish queues an event and returns, a single dispatcher hands it to the
+// handlers subscribed to its topic. Events still queued when the process stops are lost
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrFull is returned by Publish when the queue is full, the event is dropped
+var ErrFull = errors.New("event queue is full")
+
+// Event is anything published on the bus, events of the same Go type share a topic
+type Event interface {
+	Topic() string
+}
+
+type Handler func(Event)
+
+type Bus struct {
+	queue chan Event
+
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+	onError  func(Event, error)
+}
+
+// New returns a bus queueing up to buffer events ahead of the dispatcher
+func New(buffer int) *Bus {
+	return &Bus{
+		queue:    make(chan Event, buffer),
+		handlers: make(map[string][]Handler),
+	}
+}
+
+// Subscribe registers h for every event published on topic
+func (b *Bus) Subscribe(topic string, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers[topic] = append(b.handlers[topic], h)
+}
+
+// On subscribes a handler of one event type, the topic is taken from the type
+func On[T Event](b *Bus, h func(T)) {
+	var zero T
+	b.Subscribe(zero.Topic(), func(e Event) {
+		if event, ok := e.(T); ok {
+			h(event)
+		}
+	})
+}
+
+// OnError registers fn to observe dropped events and handlers which panicked
+func (b *Bus) OnError(fn func(Event, error)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.onError = fn
+}
+
+// Publish queues an event without waiting for its handlers
+func (b *Bus) Publish(e Event) error {
+	select {
+	case b.queue <- e:
+		return nil
+	default:
+		b.report(e, ErrFull)
+		return ErrFull
+	}
+}
+
+func (b *Bus) Name() string {
+	return "event bus"
+}
+
+// Run dispatches queued events until ctx is cancelled
+func (b *Bus) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e := <-b.queue:
+			b.dispatch(e)
+		}
+	}
+}
+
+func (b *Bus) dispatch(e Event) {
+
+	b.mu.RLock()
+	handlers := b.handlers[e.Topic()]
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		b.call(h, e)
+	}
+}
+
+// call runs one handler, a panicking handler does not take the others down
+func (b *Bus) call(h Handler, e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			b.report(e, fmt.Errorf("event handler panicked: %v", r))
+		}
+	}()
+
+	h(e)
+}
+
+func (b *Bus) report(e Event, err error) {
+	b.mu.RLock()
+	onError := b.onError
+	b.mu.RUnlock()
+
+	if onError != nil {
+		onError(e, err)
+	}
+}
@@ -0,0 +1,194 @@
+package gating
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// CacheStats counts decision cache traffic since start
+type CacheStats struct {
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Invalidations uint64 `json:"invalidations"`
+	Entries       int    `json:"entries"`
+}
+
+type cacheEntry struct {
+	decision  Decision
+	expires   time.Time
+	contracts []ContractRef
+}
+
+// DecisionCache keeps decisions per address and policy for a short TTL. Entries of an
+// address are dropped as soon as it sends or receives a token its policy depends on,
+// so selling a token revokes access without waiting for the TTL
+type DecisionCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	// byAddress indexes entry keys by the address they were evaluated for
+	byAddress map[common.Address]map[string]struct{}
+
+	// epoch moves on every invalidation, a decision evaluated across one is not stored
+	epoch atomic.Uint64
+
+	hits          atomic.Uint64
+	misses        atomic.Uint64
+	invalidations atomic.Uint64
+}
+
+func NewDecisionCache(ttl time.Duration) *DecisionCache {
+	return &DecisionCache{
+		ttl:       ttl,
+		entries:   make(map[string]cacheEntry),
+		byAddress: make(map[common.Address]map[string]struct{}),
+	}
+}
+
+// PolicyKey identifies a policy by its raw bytes
+func PolicyKey(policy []byte) string {
+	sum := sha256.Sum256(policy)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *DecisionCache) Get(addr common.Address, policyKey string) (Decision, bool) {
+
+	key := cacheKey(addr, policyKey)
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok && time.Now().After(entry.expires) {
+		c.remove(addr, key)
+		ok = false
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		c.misses.Add(1)
+		return Decision{}, false
+	}
+
+	c.hits.Add(1)
+	return entry.decision, true
+}
+
+// Epoch is read before evaluating a policy and handed to Set
+func (c *DecisionCache) Epoch() uint64 {
+	return c.epoch.Load()
+}
+
+// Set stores the decision of the rule for the address, unless an invalidation happened
+// since epoch was read and the decision may already be stale
+func (c *DecisionCache) Set(addr common.Address, policyKey string, rule Rule, decision Decision, epoch uint64) {
+
+	if c.ttl <= 0 {
+		return
+	}
+
+	key := cacheKey(addr, policyKey)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.epoch.Load() != epoch {
+		return
+	}
+
+	c.sweep()
+
+	c.entries[key] = cacheEntry{
+		decision:  decision,
+		expires:   time.Now().Add(c.ttl),
+		contracts: rule.Contracts(),
+	}
+
+	keys, ok := c.byAddress[addr]
+	if !ok {
+		keys = make(map[string]struct{})
+		c.byAddress[addr] = keys
+	}
+	keys[key] = struct{}{}
+}
+
+// Invalidate drops the decisions of the addresses which depend on the contract
+func (c *DecisionCache) Invalidate(chainID uint64, contract common.Address, addrs ...common.Address) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.epoch.Add(1)
+
+	for _, addr := range addrs {
+		for key := range c.byAddress[addr] {
+			if dependsOn(c.entries[key].contracts, chainID, contract) {
+				c.remove(addr, key)
+				c.invalidations.Add(1)
+			}
+		}
+	}
+}
+
+func (c *DecisionCache) Stats() CacheStats {
+
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+
+	return CacheStats{
+		Hits:          c.hits.Load(),
+		Misses:        c.misses.Load(),
+		Invalidations: c.invalidations.Load(),
+		Entries:       entries,
+	}
+}
+
+// sweep drops expired entries once the cache has grown, the caller holds the lock
+func (c *DecisionCache) sweep() {
+
+	if len(c.entries) < 10000 {
+		return
+	}
+
+	now := time.Now()
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			addr, _, _ := strings.Cut(key, ":")
+			c.remove(common.HexToAddress(addr), key)
+		}
+	}
+}
+
+// remove deletes an entry and its index, the caller holds the lock
+func (c *DecisionCache) remove(addr common.Address, key string) {
+
+	delete(c.entries, key)
+
+	if keys, ok := c.byAddress[addr]; ok {
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(c.byAddress, addr)
+		}
+	}
+}
+
+func dependsOn(refs []ContractRef, chainID uint64, contract common.Address) bool {
+
+	for _, ref := range refs {
+		if ref.ChainID == chainID && ref.Address == contract {
+			return true
+		}
+	}
+
+	return false
+}
+
+func cacheKey(addr common.Address, policyKey string) string {
+	return addr.Hex() + ":" + policyKey
+}
@@ -0,0 +1,191 @@
+package gating
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var ErrNoChainReader = errors.New("no chain reader configured")
+
+// ChainReader is the on-chain state the evaluator needs. It is satisfied by
+// chain.Reader and can be replaced by a fake in tests
+type ChainReader interface {
+	// BalanceOf calls balanceOf(owner) on an ERC-20 or ERC-721 contract
+	BalanceOf(ctx context.Context, chainID uint64, token, owner common.Address) (*big.Int, error)
+	// OwnerOf calls ownerOf(tokenId) on an ERC-721 contract
+	OwnerOf(ctx context.Context, chainID uint64, token common.Address, tokenID *big.Int) (common.Address, error)
+	// ERC1155BalanceOf calls balanceOf(owner, id) on an ERC-1155 contract
+	ERC1155BalanceOf(ctx context.Context, chainID uint64, token, owner common.Address, tokenID *big.Int) (*big.Int, error)
+	// NativeBalance returns the balance of the account in wei
+	NativeBalance(ctx context.Context, chainID uint64, owner common.Address) (*big.Int, error)
+}
+
+// Decision is the outcome of evaluating a policy. Explanation names the conditions
+// which failed so the reader knows what they are missing
+type Decision struct {
+	Allowed     bool
+	Explanation string
+}
+
+type Evaluator struct {
+	reader  ChainReader
+	history HistoryReader
+	now     func() time.Time
+}
+
+// NewEvaluator checks current holdings with reader and historical conditions with
+// history. history may be nil, policies using held_for_days or at_block then fail
+// with ErrNoHistory
+func NewEvaluator(reader ChainReader, history HistoryReader) *Evaluator {
+	return &Evaluator{reader: reader, history: history, now: time.Now}
+}
+
+// Evaluate checks the address against the rule. Errors reading chain state are
+// returned rather than treated as a failed condition, callers should deny access
+func (e *Evaluator) Evaluate(ctx context.Context, rule Rule, addr common.Address) (Decision, error) {
+
+	passed, explanation, err := e.eval(ctx, rule, addr)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	if passed {
+		return Decision{Allowed: true}, nil
+	}
+
+	return Decision{Explanation: explanation}, nil
+}
+
+// eval returns if the rule passed and, when it did not, why
+func (e *Evaluator) eval(ctx context.Context, rule Rule, addr common.Address) (bool, string, error) {
+
+	switch rule.Op {
+	case OpAnd:
+		for _, child := range rule.Rules {
+			passed, why, err := e.eval(ctx, child, addr)
+			if err != nil || !passed {
+				return false, why, err
+			}
+		}
+		return true, "", nil
+
+	case OpOr:
+		reasons := make([]string, 0, len(rule.Rules))
+		for _, child := range rule.Rules {
+			passed, why, err := e.eval(ctx, child, addr)
+			if err != nil {
+				return false, "", err
+			}
+			if passed {
+				return true, "", nil
+			}
+			reasons = append(reasons, why)
+		}
+		return false, "none of the options were met: " + strings.Join(reasons, "; or "), nil
+
+	case OpNot:
+		passed, _, err := e.eval(ctx, rule.Rules[0], addr)
+		if err != nil {
+			return false, "", err
+		}
+		if passed {
+			return false, "must not " + rule.Rules[0].Describe(), nil
+		}
+		return true, "", nil
+
+	case OpMin:
+		met := 0
+		reasons := make([]string, 0, len(rule.Rules))
+		for i, child := range rule.Rules {
+			// stop once the threshold can no longer be reached or is already reached
+			if met >= rule.Count || met+len(rule.Rules)-i < rule.Count {
+				break
+			}
+			passed, why, err := e.eval(ctx, child, addr)
+			if err != nil {
+				return false, "", err
+			}
+			if passed {
+				met++
+			} else {
+				reasons = append(reasons, why)
+			}
+		}
+		if met >= rule.Count {
+			return true, "", nil
+		}
+		return false, fmt.Sprintf("needs %d of %d conditions, unmet: %s", rule.Count, len(rule.Rules), strings.Join(reasons, "; ")), nil
+	}
+
+	return e.evalCondition(ctx, rule, addr)
+}
+
+func (e *Evaluator) evalCondition(ctx context.Context, rule Rule, addr common.Address) (bool, string, error) {
+
+	if rule.Type == TypeAllowlist {
+		for _, allowed := range rule.Addresses {
+			if common.HexToAddress(allowed) == addr {
+				return true, "", nil
+			}
+		}
+		return false, "address is not on the allowlist", nil
+	}
+
+	if rule.IsHistorical() {
+		return e.evalHistory(ctx, rule, addr)
+	}
+
+	if e.reader == nil {
+		return false, "", ErrNoChainReader
+	}
+
+	contract := common.HexToAddress(rule.Contract)
+
+	var balance *big.Int
+	var err error
+
+	switch rule.Type {
+	case TypeNativeBalance:
+		balance, err = e.reader.NativeBalance(ctx, rule.ChainID, addr)
+
+	case TypeERC20Balance:
+		balance, err = e.reader.BalanceOf(ctx, rule.ChainID, contract, addr)
+
+	case TypeERC721Owner:
+		if rule.TokenID == "" {
+			balance, err = e.reader.BalanceOf(ctx, rule.ChainID, contract, addr)
+			break
+		}
+
+		owner, err := e.reader.OwnerOf(ctx, rule.ChainID, contract, rule.tokenID())
+		if err != nil {
+			return false, "", fmt.Errorf("ownerOf %s on chain %d failed %w", rule.Contract, rule.ChainID, err)
+		}
+		if owner != addr {
+			return false, "must " + rule.Describe(), nil
+		}
+		return true, "", nil
+
+	case TypeERC1155Balance:
+		balance, err = e.reader.ERC1155BalanceOf(ctx, rule.ChainID, contract, addr, rule.tokenID())
+
+	default:
+		return false, "", fmt.Errorf("%w: unknown type %q", ErrInvalidRule, rule.Type)
+	}
+
+	if err != nil {
+		return false, "", fmt.Errorf("%s read on chain %d failed %w", rule.Type, rule.ChainID, err)
+	}
+
+	if balance.Cmp(rule.minimum()) < 0 {
+		return false, fmt.Sprintf("must %s (has %s)", rule.Describe(), balance), nil
+	}
+
+	return true, "", nil
+}
@@ -0,0 +1,241 @@
+package gating
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// MaxPreviewCandidates bounds how many addresses a preview evaluates
+const MaxPreviewCandidates = 10000
+
+var (
+	ErrNoHistory         = errors.New("no transfer history configured")
+	ErrNotPreviewable    = errors.New("rule can not be previewed at a block")
+	ErrTooManyCandidates = errors.New("too many candidate addresses to preview")
+)
+
+// HistoryReader answers historical conditions from indexed transfers. A nil tokenID
+// covers every token of the contract, eg. any NFT of a collection
+type HistoryReader interface {
+	// BalanceAt returns the balance of owner at the end of block
+	BalanceAt(ctx context.Context, chainID uint64, token common.Address, tokenID *big.Int, owner common.Address, block uint64) (*big.Int, error)
+	// HeldSince returns since when the balance of owner has stayed at or above min.
+	// holding is false when the balance is below min now
+	HeldSince(ctx context.Context, chainID uint64, token common.Address, tokenID *big.Int, owner common.Address, min *big.Int) (since time.Time, holding bool, err error)
+	// HoldersAt returns the balance of every address holding the token at the end of block
+	HoldersAt(ctx context.Context, chainID uint64, token common.Address, tokenID *big.Int, block uint64) (map[common.Address]*big.Int, error)
+}
+
+// evalHistory checks a held_for_days or at_block condition
+func (e *Evaluator) evalHistory(ctx context.Context, rule Rule, addr common.Address) (bool, string, error) {
+
+	if e.history == nil {
+		return false, "", ErrNoHistory
+	}
+
+	contract := common.HexToAddress(rule.Contract)
+
+	if rule.AtBlock > 0 {
+		balance, err := e.history.BalanceAt(ctx, rule.ChainID, contract, rule.historyTokenID(), addr, rule.AtBlock)
+		if err != nil {
+			return false, "", fmt.Errorf("%s history on chain %d failed %w", rule.Type, rule.ChainID, err)
+		}
+		if balance.Cmp(rule.minimum()) < 0 {
+			return false, fmt.Sprintf("must %s (had %s)", rule.Describe(), balance), nil
+		}
+		return true, "", nil
+	}
+
+	// the index trails the chain by a few blocks, the live balance catches tokens sold
+	// since the last indexed block
+	current := rule
+	current.HeldForDays = 0
+	passed, why, err := e.evalCondition(ctx, current, addr)
+	if err != nil || !passed {
+		return false, why, err
+	}
+
+	since, holding, err := e.history.HeldSince(ctx, rule.ChainID, contract, rule.historyTokenID(), addr, rule.minimum())
+	if err != nil {
+		return false, "", fmt.Errorf("%s history on chain %d failed %w", rule.Type, rule.ChainID, err)
+	}
+	if !holding {
+		return false, "must " + rule.Describe(), nil
+	}
+
+	held := time.Duration(rule.HeldForDays) * 24 * time.Hour
+	if e.now().Sub(since) < held {
+		return false, fmt.Sprintf("must %s (held since %s)", rule.Describe(), since.Format(time.DateOnly)), nil
+	}
+
+	return true, "", nil
+}
+
+// historyTokenID is the token a historical condition sums balances over, nil for
+// fungible tokens and for any token of an ERC-721 collection
+func (r Rule) historyTokenID() *big.Int {
+
+	if r.Type == TypeERC20Balance || r.TokenID == "" {
+		return nil
+	}
+	return r.tokenID()
+}
+
+// Qualifying lists the addresses passing the rule at the end of block. Every token
+// condition without its own at_block is checked at block. Candidates are the holders of
+// the referenced tokens and the allowlisted addresses, so a rule which passes for
+// addresses holding nothing, eg. "not", only lists those candidates
+func (e *Evaluator) Qualifying(ctx context.Context, rule Rule, block uint64) ([]common.Address, error) {
+
+	if e.history == nil {
+		return nil, ErrNoHistory
+	}
+
+	snapshot, err := rule.snapshotAt(block)
+	if err != nil {
+		return nil, err
+	}
+
+	cached := &snapshotHistory{history: e.history, holders: make(map[string]map[common.Address]*big.Int)}
+	candidates := make(map[common.Address]struct{})
+
+	if err := snapshot.walk(func(leaf Rule) error {
+		if leaf.Type == TypeAllowlist {
+			for _, a := range leaf.Addresses {
+				candidates[common.HexToAddress(a)] = struct{}{}
+			}
+			return nil
+		}
+
+		holders, err := cached.holdersAt(ctx, leaf.ChainID, common.HexToAddress(leaf.Contract), leaf.historyTokenID(), leaf.AtBlock)
+		if err != nil {
+			return err
+		}
+		for a := range holders {
+			candidates[a] = struct{}{}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(candidates) > MaxPreviewCandidates {
+		return nil, fmt.Errorf("%w: %d, at most %d", ErrTooManyCandidates, len(candidates), MaxPreviewCandidates)
+	}
+
+	preview := &Evaluator{history: cached, now: e.now}
+
+	qualifying := make([]common.Address, 0)
+	for a := range candidates {
+		passed, _, err := preview.eval(ctx, *snapshot, a)
+		if err != nil {
+			return nil, err
+		}
+		if passed {
+			qualifying = append(qualifying, a)
+		}
+	}
+
+	sort.Slice(qualifying, func(i, j int) bool {
+		return qualifying[i].Cmp(qualifying[j]) < 0
+	})
+
+	return qualifying, nil
+}
+
+// snapshotAt copies the rule with every token condition pinned to block. Conditions
+// which need the present, native balances and held_for_days, can not be previewed
+func (r Rule) snapshotAt(block uint64) (*Rule, error) {
+
+	out := r
+
+	if r.Op != "" {
+		out.Rules = make([]Rule, 0, len(r.Rules))
+		for _, child := range r.Rules {
+			snapshot, err := child.snapshotAt(block)
+			if err != nil {
+				return nil, err
+			}
+			out.Rules = append(out.Rules, *snapshot)
+		}
+		return &out, nil
+	}
+
+	switch {
+	case r.Type == TypeAllowlist:
+	case r.Type == TypeNativeBalance:
+		return nil, fmt.Errorf("%w: native_balance is not indexed", ErrNotPreviewable)
+	case r.HeldForDays > 0:
+		return nil, fmt.Errorf("%w: held_for_days depends on the current time", ErrNotPreviewable)
+	case r.AtBlock == 0:
+		out.AtBlock = block
+	}
+
+	return &out, nil
+}
+
+// walk calls fn for every condition of the tree
+func (r Rule) walk(fn func(leaf Rule) error) error {
+
+	if r.Op == "" {
+		return fn(r)
+	}
+
+	for _, child := range r.Rules {
+		if err := child.walk(fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// snapshotHistory serves balances from the holder lists loaded for a preview, so each
+// condition costs a single query however many candidates are evaluated
+type snapshotHistory struct {
+	history HistoryReader
+	holders map[string]map[common.Address]*big.Int
+}
+
+func (s *snapshotHistory) holdersAt(ctx context.Context, chainID uint64, token common.Address, tokenID *big.Int, block uint64) (map[common.Address]*big.Int, error) {
+
+	key := fmt.Sprintf("%d:%s:%v:%d", chainID, token.Hex(), tokenID, block)
+	if holders, ok := s.holders[key]; ok {
+		return holders, nil
+	}
+
+	holders, err := s.history.HoldersAt(ctx, chainID, token, tokenID, block)
+	if err != nil {
+		return nil, err
+	}
+
+	s.holders[key] = holders
+	return holders, nil
+}
+
+func (s *snapshotHistory) BalanceAt(ctx context.Context, chainID uint64, token common.Address, tokenID *big.Int, owner common.Address, block uint64) (*big.Int, error) {
+
+	holders, err := s.holdersAt(ctx, chainID, token, tokenID, block)
+	if err != nil {
+		return nil, err
+	}
+
+	if balance, ok := holders[owner]; ok {
+		return balance, nil
+	}
+	return new(big.Int), nil
+}
+
+func (s *snapshotHistory) HeldSince(ctx context.Context, chainID uint64, token common.Address, tokenID *big.Int, owner common.Address, min *big.Int) (time.Time, bool, error) {
+	return time.Time{}, false, ErrNotPreviewable
+}
+
+func (s *snapshotHistory) HoldersAt(ctx context.Context, chainID uint64, token common.Address, tokenID *big.Int, block uint64) (map[common.Address]*big.Int, error) {
+	return s.holdersAt(ctx, chainID, token, tokenID, block)
+}
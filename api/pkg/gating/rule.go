@@ -0,0 +1,327 @@
+// gating evaluates access policies written as a JSON expression tree of on-chain
+// conditions combined with and/or/not/min operators
+package gating
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	OpAnd = "and"
+	OpOr  = "or"
+	OpNot = "not"
+	// OpMin passes when at least Count of its rules pass
+	OpMin = "min"
+
+	TypeERC20Balance   = "erc20_balance"
+	TypeERC721Owner    = "erc721_owner"
+	TypeERC1155Balance = "erc1155_balance"
+	TypeNativeBalance  = "native_balance"
+	TypeAllowlist      = "allowlist"
+
+	// MaxDepth and MaxConditions bound how expensive a single policy can be to evaluate
+	MaxDepth      = 8
+	MaxConditions = 32
+
+	// MaxHeldForDays bounds how far back a held_for_days condition looks
+	MaxHeldForDays = 3650
+)
+
+var ErrInvalidRule = errors.New("invalid gating rule")
+
+// Rule is a node of the policy tree. Composite nodes set Op, leaves set Type
+//
+//	{"op": "or", "rules": [
+//	  {"type": "erc721_owner", "chain_id": 1, "contract": "0x...", "held_for_days": 30},
+//	  {"type": "erc20_balance", "chain_id": 1, "contract": "0x...", "min": "100", "at_block": 19000000}
+//	]}
+//
+// Token conditions can be historical. HeldForDays requires the balance to have stayed
+// at or above Min for that long, AtBlock checks the balance at the end of a past block.
+// Both are answered from indexed transfer history rather than rpc
+type Rule struct {
+	Op    string `json:"op,omitempty"`
+	Rules []Rule `json:"rules,omitempty"`
+	Count int    `json:"count,omitempty"`
+
+	Type      string   `json:"type,omitempty"`
+	ChainID   uint64   `json:"chain_id,omitempty"`
+	Contract  string   `json:"contract,omitempty"`
+	TokenID   string   `json:"token_id,omitempty"`
+	Min       string   `json:"min,omitempty"`
+	Addresses []string `json:"addresses,omitempty"`
+
+	HeldForDays int    `json:"held_for_days,omitempty"`
+	AtBlock     uint64 `json:"at_block,omitempty"`
+}
+
+// Parse decodes and validates a policy
+func Parse(raw []byte) (*Rule, error) {
+
+	var rule Rule
+	if err := json.Unmarshal(raw, &rule); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+// Validate checks the whole tree for unknown operators, malformed addresses and
+// amounts, and enforces MaxDepth and MaxConditions
+func (r Rule) Validate() error {
+
+	conditions := 0
+	return r.validate(1, &conditions)
+}
+
+func (r Rule) validate(depth int, conditions *int) error {
+
+	if depth > MaxDepth {
+		return fmt.Errorf("%w: nested deeper than %d levels", ErrInvalidRule, MaxDepth)
+	}
+
+	if r.Op != "" && r.Type != "" {
+		return fmt.Errorf("%w: a rule can not set both op and type", ErrInvalidRule)
+	}
+
+	if r.Op != "" {
+		switch r.Op {
+		case OpAnd, OpOr:
+			if len(r.Rules) == 0 {
+				return fmt.Errorf("%w: %s needs at least one rule", ErrInvalidRule, r.Op)
+			}
+		case OpNot:
+			if len(r.Rules) != 1 {
+				return fmt.Errorf("%w: not takes exactly one rule", ErrInvalidRule)
+			}
+		case OpMin:
+			if r.Count < 1 || r.Count > len(r.Rules) {
+				return fmt.Errorf("%w: min count must be between 1 and %d", ErrInvalidRule, len(r.Rules))
+			}
+		default:
+			return fmt.Errorf("%w: unknown op %q", ErrInvalidRule, r.Op)
+		}
+
+		for _, child := range r.Rules {
+			if err := child.validate(depth+1, conditions); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	*conditions++
+	if *conditions > MaxConditions {
+		return fmt.Errorf("%w: more than %d conditions", ErrInvalidRule, MaxConditions)
+	}
+
+	switch r.Type {
+	case TypeAllowlist:
+		if len(r.Addresses) == 0 {
+			return fmt.Errorf("%w: allowlist is empty", ErrInvalidRule)
+		}
+		for _, addr := range r.Addresses {
+			if !common.IsHexAddress(addr) {
+				return fmt.Errorf("%w: %q is not an address", ErrInvalidRule, addr)
+			}
+		}
+		if r.IsHistorical() {
+			return fmt.Errorf("%w: allowlist can not use held_for_days or at_block", ErrInvalidRule)
+		}
+		return nil
+	case TypeNativeBalance:
+	case TypeERC20Balance, TypeERC721Owner, TypeERC1155Balance:
+		if !common.IsHexAddress(r.Contract) {
+			return fmt.Errorf("%w: %q is not a contract address", ErrInvalidRule, r.Contract)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidRule, r.Type)
+	}
+
+	if r.ChainID == 0 {
+		return fmt.Errorf("%w: %s needs a chain_id", ErrInvalidRule, r.Type)
+	}
+
+	if r.Type == TypeERC1155Balance && r.TokenID == "" {
+		return fmt.Errorf("%w: erc1155_balance needs a token_id", ErrInvalidRule)
+	}
+
+	if r.TokenID != "" {
+		if _, ok := parseAmount(r.TokenID); !ok {
+			return fmt.Errorf("%w: token_id %q is not a number", ErrInvalidRule, r.TokenID)
+		}
+	}
+
+	if r.Min != "" {
+		if _, ok := parseAmount(r.Min); !ok {
+			return fmt.Errorf("%w: min %q is not a positive number", ErrInvalidRule, r.Min)
+		}
+	}
+
+	if r.HeldForDays != 0 || r.AtBlock != 0 {
+		if r.Type == TypeNativeBalance {
+			return fmt.Errorf("%w: native_balance can not use held_for_days or at_block", ErrInvalidRule)
+		}
+		if r.HeldForDays != 0 && r.AtBlock != 0 {
+			return fmt.Errorf("%w: held_for_days and at_block can not be combined", ErrInvalidRule)
+		}
+		if r.HeldForDays < 0 || r.HeldForDays > MaxHeldForDays {
+			return fmt.Errorf("%w: held_for_days must be between 1 and %d", ErrInvalidRule, MaxHeldForDays)
+		}
+	}
+
+	return nil
+}
+
+// IsHistorical reports if the condition is answered from transfer history
+func (r Rule) IsHistorical() bool {
+	return r.HeldForDays > 0 || r.AtBlock > 0
+}
+
+// minimum returns the threshold of a balance condition, defaulting to 1
+func (r Rule) minimum() *big.Int {
+	if min, ok := parseAmount(r.Min); ok {
+		return min
+	}
+	return big.NewInt(1)
+}
+
+func (r Rule) tokenID() *big.Int {
+	id, _ := parseAmount(r.TokenID)
+	return id
+}
+
+// Describe renders the condition in words, eg. "hold at least 5 of 0xabc... on chain 1"
+func (r Rule) Describe() string {
+
+	switch r.Op {
+	case OpAnd, OpOr:
+		parts := make([]string, 0, len(r.Rules))
+		for _, child := range r.Rules {
+			parts = append(parts, child.Describe())
+		}
+		return "(" + strings.Join(parts, " "+r.Op+" ") + ")"
+	case OpNot:
+		return "not " + r.Rules[0].Describe()
+	case OpMin:
+		parts := make([]string, 0, len(r.Rules))
+		for _, child := range r.Rules {
+			parts = append(parts, child.Describe())
+		}
+		return fmt.Sprintf("at least %d of [%s]", r.Count, strings.Join(parts, "; "))
+	}
+
+	if r.Type == TypeAllowlist {
+		return "be on the allowlist"
+	}
+
+	return r.describeHolding() + r.describeHistory()
+}
+
+func (r Rule) describeHolding() string {
+
+	switch r.Type {
+	case TypeNativeBalance:
+		return fmt.Sprintf("hold at least %s wei of the native currency on chain %d", r.minimum(), r.ChainID)
+	case TypeERC20Balance:
+		return fmt.Sprintf("hold at least %s units of token %s on chain %d", r.minimum(), r.Contract, r.ChainID)
+	case TypeERC721Owner:
+		if r.TokenID != "" {
+			return fmt.Sprintf("own token #%s of collection %s on chain %d", r.TokenID, r.Contract, r.ChainID)
+		}
+		return fmt.Sprintf("own a token of collection %s on chain %d", r.Contract, r.ChainID)
+	case TypeERC1155Balance:
+		return fmt.Sprintf("hold at least %s of token #%s of %s on chain %d", r.minimum(), r.TokenID, r.Contract, r.ChainID)
+	}
+
+	return r.Type
+}
+
+func (r Rule) describeHistory() string {
+
+	switch {
+	case r.HeldForDays == 1:
+		return " for at least 1 day"
+	case r.HeldForDays > 1:
+		return fmt.Sprintf(" for at least %d days", r.HeldForDays)
+	case r.AtBlock > 0:
+		return fmt.Sprintf(" at block %d", r.AtBlock)
+	}
+
+	return ""
+}
+
+func parseAmount(s string) (*big.Int, bool) {
+	if s == "" {
+		return nil, false
+	}
+	n, ok := new(big.Int).SetString(s, 0)
+	if !ok || n.Sign() < 0 {
+		return nil, false
+	}
+	return n, true
+}
+
+// ContractRef is a token contract a policy depends on
+type ContractRef struct {
+	ChainID uint64
+	Address common.Address
+	// Type is the condition type referencing the contract, eg. TypeERC721Owner
+	Type string
+}
+
+// Contracts lists every token contract referenced by the rule tree
+func (r Rule) Contracts() []ContractRef {
+
+	var refs []ContractRef
+
+	if r.Op != "" {
+		for _, child := range r.Rules {
+			refs = append(refs, child.Contracts()...)
+		}
+		return refs
+	}
+
+	if r.Contract != "" && common.IsHexAddress(r.Contract) {
+		refs = append(refs, ContractRef{
+			ChainID: r.ChainID,
+			Address: common.HexToAddress(r.Contract),
+			Type:    r.Type,
+		})
+	}
+
+	return refs
+}
+
+// Chains lists the chains whose state the rule tree reads, once each
+func (r Rule) Chains() []uint64 {
+
+	if r.Op != "" {
+		var chains []uint64
+		for _, child := range r.Rules {
+			for _, chainID := range child.Chains() {
+				if !slices.Contains(chains, chainID) {
+					chains = append(chains, chainID)
+				}
+			}
+		}
+		return chains
+	}
+
+	if r.Type == TypeAllowlist || r.ChainID == 0 {
+		return nil
+	}
+
+	return []uint64{r.ChainID}
+}
@@ -0,0 +1,168 @@
+// imaging decodes uploaded images and renders the resized variants, blurred previews
+// and BlurHash placeholders served in place of the original
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+
+	_ "image/gif"
+
+	"github.com/buckket/go-blurhash"
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupported = errors.New("image format is not supported")
+	ErrTooLarge    = errors.New("image dimensions exceed the pixel limit")
+)
+
+// previewSource is the width an image is shrunk to before a preview is scaled back
+// up, little more than the colour layout survives it
+const previewSource = 12
+
+// blurHashSource is the width an image is shrunk to before its BlurHash is computed,
+// the hash only keeps a few components so the full image adds nothing but time
+const blurHashSource = 64
+
+// Decode decodes a jpeg, png, gif or webp image and turns it upright as its EXIF
+// orientation says. Images above maxPixels are rejected before their pixels are read
+func Decode(data []byte, maxPixels int) (image.Image, error) {
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if errors.Is(err, image.ErrFormat) {
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if maxPixels > 0 && cfg.Width*cfg.Height > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrTooLarge, cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return orient(img, Orientation(data)), nil
+}
+
+// Resize scales img to width keeping its aspect ratio. Images narrower than width
+// are returned as they are, variants are never upscaled
+func Resize(img image.Image, width int) image.Image {
+
+	bounds := img.Bounds()
+	if bounds.Dx() <= width {
+		return img
+	}
+
+	return scale(img, width, xdraw.CatmullRom)
+}
+
+// Blur renders a strongly blurred copy of img, width wide, by shrinking it to a few
+// pixels and smoothly scaling it back up. Nothing recognisable is left of the content
+func Blur(img image.Image, width int) image.Image {
+
+	width = min(width, img.Bounds().Dx())
+
+	small := scale(img, previewSource, xdraw.ApproxBiLinear)
+	return scale(small, width, xdraw.BiLinear)
+}
+
+// BlurHash encodes img as a short placeholder string, see https://blurha.sh
+func BlurHash(img image.Image) (string, error) {
+
+	bounds := img.Bounds()
+
+	// more components along the longer side keep the layout of wide and tall images
+	x, y := 4, 3
+	if bounds.Dy() > bounds.Dx() {
+		x, y = 3, 4
+	}
+
+	return blurhash.Encode(x, y, Resize(img, blurHashSource))
+}
+
+// Encode writes img as png when it has transparent pixels and as jpeg otherwise.
+// Neither carries any metadata of the source
+func Encode(img image.Image, quality int) ([]byte, string, error) {
+
+	var buf bytes.Buffer
+
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && !opaque.Opaque() {
+		encoder := png.Encoder{CompressionLevel: png.BestCompression}
+		if err := encoder.Encode(&buf, img); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/png", nil
+	}
+
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, "", err
+	}
+
+	return buf.Bytes(), "image/jpeg", nil
+}
+
+func scale(img image.Image, width int, interpolator xdraw.Interpolator) image.Image {
+
+	bounds := img.Bounds()
+	height := max(1, bounds.Dy()*width/bounds.Dx())
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	interpolator.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+
+	return dst
+}
+
+// orient applies an EXIF orientation, 1 to 8, to img
+func orient(img image.Image, orientation int) image.Image {
+
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	// orientations 5 to 8 swap the axes
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,268 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var ErrMalformed = errors.New("image structure is malformed")
+
+var (
+	jpegMagic = []byte{0xff, 0xd8}
+	pngMagic  = []byte("\x89PNG\r\n\x1a\n")
+	exifMagic = []byte("Exif\x00\x00")
+)
+
+// EXIF orientation tag, the only one kept in stripped jpegs
+const tagOrientation = 0x0112
+
+// StripMetadata removes EXIF, XMP, IPTC and text metadata, GPS positions included,
+// without re-encoding the image. A jpeg keeps its orientation so it still displays
+// upright. Formats carrying no such metadata are returned as they are
+func StripMetadata(data []byte) ([]byte, error) {
+
+	switch {
+	case bytes.HasPrefix(data, jpegMagic):
+		return stripJPEG(data)
+	case bytes.HasPrefix(data, pngMagic):
+		return stripPNG(data)
+	case isWebP(data):
+		return stripWebP(data)
+	}
+
+	return data, nil
+}
+
+// Orientation reads the EXIF orientation of a jpeg, 1 when it has none
+func Orientation(data []byte) int {
+
+	if !bytes.HasPrefix(data, jpegMagic) {
+		return 1
+	}
+
+	orientation := 1
+
+	_ = walkJPEG(data, func(marker byte, segment []byte) bool {
+		if marker == 0xe1 && bytes.HasPrefix(segment[4:], exifMagic) {
+			orientation = exifOrientation(segment[4+len(exifMagic):])
+			return false
+		}
+		return true
+	})
+
+	return orientation
+}
+
+func stripJPEG(data []byte) ([]byte, error) {
+
+	out := make([]byte, 0, len(data))
+	out = append(out, jpegMagic...)
+
+	if orientation := Orientation(data); orientation > 1 {
+		out = append(out, orientationSegment(orientation)...)
+	}
+
+	end := len(jpegMagic)
+	err := walkJPEG(data, func(marker byte, segment []byte) bool {
+		end += len(segment)
+		if !strippedJPEGMarker(marker) {
+			out = append(out, segment...)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the entropy coded image data follows the last segment
+	return append(out, data[end:]...), nil
+}
+
+// strippedJPEGMarker drops APP1 (EXIF, XMP), APP13 (IPTC), comments and the vendor
+// APPn segments. JFIF (APP0), ICC profiles (APP2) and Adobe (APP14) affect decoding
+// and are kept
+func strippedJPEGMarker(marker byte) bool {
+
+	switch {
+	case marker == 0xfe:
+		return true
+	case marker == 0xe1:
+		return true
+	case marker >= 0xe3 && marker <= 0xef:
+		return marker != 0xee
+	}
+
+	return false
+}
+
+// walkJPEG calls fn with every marker segment before the image data, the segment
+// includes its marker and length. fn returns false to stop the walk
+func walkJPEG(data []byte, fn func(marker byte, segment []byte) bool) error {
+
+	for i := len(jpegMagic); ; {
+		if i+4 > len(data) || data[i] != 0xff {
+			return ErrMalformed
+		}
+
+		marker := data[i+1]
+		if marker == 0xda {
+			// start of scan, the segment and everything after it belong to the image data
+			return nil
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return ErrMalformed
+		}
+
+		if !fn(marker, data[i:i+2+length]) {
+			return nil
+		}
+		i += 2 + length
+	}
+}
+
+// exifOrientation reads the orientation tag from IFD0 of an EXIF TIFF structure
+func exifOrientation(tiff []byte) int {
+
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+
+		if order.Uint16(tiff[entry:]) == tagOrientation {
+			value := int(order.Uint16(tiff[entry+8:]))
+			if value < 1 || value > 8 {
+				return 1
+			}
+			return value
+		}
+	}
+
+	return 1
+}
+
+// orientationSegment builds an APP1 segment whose EXIF only holds the orientation
+func orientationSegment(orientation int) []byte {
+
+	tiff := []byte{
+		'M', 'M', 0x00, 0x2a, // big endian TIFF header
+		0x00, 0x00, 0x00, 0x08, // IFD0 follows the header
+		0x00, 0x01, // one entry
+		0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, // orientation, SHORT, count 1
+		0x00, byte(orientation), 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, // no further IFD
+	}
+
+	payload := append(append([]byte{}, exifMagic...), tiff...)
+
+	segment := []byte{0xff, 0xe1, 0x00, 0x00}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+
+	return append(segment, payload...)
+}
+
+// strippedPNGChunks hold EXIF and free text, which tools fill with capture details
+var strippedPNGChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+func stripPNG(data []byte) ([]byte, error) {
+
+	out := make([]byte, 0, len(data))
+	out = append(out, pngMagic...)
+
+	for i := len(pngMagic); i < len(data); {
+		if i+12 > len(data) {
+			return nil, ErrMalformed
+		}
+
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, ErrMalformed
+		}
+
+		// chunks carry their own crc, dropping one leaves the others valid
+		if !strippedPNGChunks[string(data[i+4:i+8])] {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+
+	return out, nil
+}
+
+func isWebP(data []byte) bool {
+	return len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP"
+}
+
+// VP8X feature flags announcing EXIF and XMP chunks
+const (
+	webpFlagEXIF = 0x08
+	webpFlagXMP  = 0x04
+)
+
+func stripWebP(data []byte) ([]byte, error) {
+
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+
+	for i := 12; i < len(data); {
+		if i+8 > len(data) {
+			return nil, ErrMalformed
+		}
+
+		fourCC := string(data[i : i+4])
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+
+		// chunks are padded to an even size
+		end := i + 8 + size + size&1
+		if size < 0 || end > len(data) {
+			return nil, ErrMalformed
+		}
+
+		switch fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte{}, data[i:end]...)
+			if size > 0 {
+				chunk[8] &^= webpFlagEXIF | webpFlagXMP
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+
+	return out, nil
+}
@@ -24,9 +24,9 @@ func Token(claims TokenJWTClaims, secret []byte) (string, error) {
 		"iss": claims.Iss,
 		"sub": claims.Sub,
 		"aud": claims.Aud,
-		"exp": claims.Exp,
-		"iat": claims.Iat,
-		"nbf": claims.Nbf,
+		"exp": jwt.NewNumericDate(claims.Exp),
+		"iat": jwt.NewNumericDate(claims.Iat),
+		"nbf": jwt.NewNumericDate(claims.Nbf),
 		"jti": claims.Jti,
 	})
 
@@ -0,0 +1,227 @@
+// mailer sends email over SMTP. Messages carry a plain text and an HTML alternative.
+// Servers offering STARTTLS are upgraded to it, so a local catcher such as MailHog works
+// as well as a relay
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrAddressInvalid = errors.New("email address is invalid")
+	ErrHeaderInvalid  = errors.New("email header is invalid")
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	// Headers are added to the message as they are, eg List-Unsubscribe
+	Headers map[string]string
+}
+
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type Options struct {
+	Host string
+	Port int
+	// Username and Password authenticate with PLAIN, left empty no auth is done
+	Username string
+	Password string
+	// From is the sender, a bare address or "Name <address>"
+	From    string
+	Timeout time.Duration
+}
+
+// NewSMTP returns a sender delivering every message over its own connection
+func NewSMTP(opts Options) (Sender, error) {
+
+	from, err := mail.ParseAddress(opts.From)
+	if err != nil {
+		return nil, fmt.Errorf("%w: from %q", ErrAddressInvalid, opts.From)
+	}
+
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+
+	return &smtpSender{opts: opts, from: from}, nil
+}
+
+type smtpSender struct {
+	opts Options
+	from *mail.Address
+}
+
+func (s *smtpSender) Send(ctx context.Context, msg Message) error {
+
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrAddressInvalid, msg.To)
+	}
+
+	body, err := s.compose(to, msg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.opts.Timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(s.opts.Host, strconv.Itoa(s.opts.Port))
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, s.opts.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.opts.Host}); err != nil {
+			return err
+		}
+	}
+
+	if s.opts.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.opts.Username, s.opts.Password, s.opts.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(s.from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// compose renders the message as multipart/alternative, text first so clients prefer
+// the HTML part
+func (s *smtpSender) compose(to *mail.Address, msg Message) ([]byte, error) {
+
+	var buf bytes.Buffer
+	parts := multipart.NewWriter(&buf)
+
+	header := func(key, value string) error {
+		if strings.ContainsAny(key+value, "\r\n") {
+			return fmt.Errorf("%w: %s", ErrHeaderInvalid, key)
+		}
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+		return nil
+	}
+
+	headers := [][2]string{
+		{"From", s.from.String()},
+		{"To", to.String()},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", s.messageID()},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + parts.Boundary()},
+	}
+	for key, value := range msg.Headers {
+		headers = append(headers, [2]string{key, value})
+	}
+	for _, h := range headers {
+		if err := header(h[0], h[1]); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteString("\r\n")
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		if part.content == "" {
+			continue
+		}
+
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (s *smtpSender) messageID() string {
+
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	_, host, _ := strings.Cut(s.from.Address, "@")
+
+	return "<" + hex.EncodeToString(id) + "@" + host + ">"
+}
+
+// IsPermanent reports if the server refused the message for good, 5xx replies are not
+// worth retrying
+func IsPermanent(err error) bool {
+
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return protoErr.Code >= 500
+	}
+
+	return errors.Is(err, ErrAddressInvalid) || errors.Is(err, ErrHeaderInvalid)
+}
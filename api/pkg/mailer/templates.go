@@ -0,0 +1,105 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Templates renders messages from pairs of templates, name.txt.tmpl and
+// name.html.tmpl. The text template defines the subject in a "subject" block, the HTML
+// ones are parsed along with layout.html.tmpl so they can share it
+type Templates struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+const (
+	textSuffix = ".txt.tmpl"
+	htmlSuffix = ".html.tmpl"
+	layout     = "layout" + htmlSuffix
+)
+
+// ParseTemplates reads every pair in dir of fsys, a missing half is an error so broken
+// templates are found on startup
+func ParseTemplates(fsys fs.FS, dir string) (*Templates, error) {
+
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Templates{
+		text: make(map[string]*texttemplate.Template),
+		html: make(map[string]*htmltemplate.Template),
+	}
+
+	hasLayout := false
+	for _, entry := range entries {
+		if entry.Name() == layout {
+			hasLayout = true
+		}
+	}
+
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), textSuffix)
+		if !ok {
+			continue
+		}
+
+		text, err := texttemplate.ParseFS(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if text.Lookup("subject") == nil {
+			return nil, fmt.Errorf("email template %s defines no subject", name)
+		}
+
+		files := []string{path.Join(dir, name+htmlSuffix)}
+		if hasLayout {
+			files = append(files, path.Join(dir, layout))
+		}
+
+		html, err := htmltemplate.ParseFS(fsys, files...)
+		if err != nil {
+			return nil, err
+		}
+
+		t.text[name] = text
+		t.html[name] = html
+	}
+
+	return t, nil
+}
+
+// Render executes the pair called name with data, the recipient and headers are left
+// to the caller
+func (t *Templates) Render(name string, data any) (Message, error) {
+
+	text, ok := t.text[name]
+	if !ok {
+		return Message{}, fmt.Errorf("email template %s not found", name)
+	}
+
+	var subject, textBody, htmlBody bytes.Buffer
+
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := text.Execute(&textBody, data); err != nil {
+		return Message{}, err
+	}
+	if err := t.html[name].ExecuteTemplate(&htmlBody, name+htmlSuffix, data); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(textBody.String()) + "\n",
+		HTML:    strings.TrimSpace(htmlBody.String()) + "\n",
+	}, nil
+}
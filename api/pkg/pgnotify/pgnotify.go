@@ -0,0 +1,160 @@
+// pgnotify relays Postgres notifications to handlers in this process. One connection
+// LISTENs on every channel a handler was registered for, and is re-established when
+// it drops. Notifications sent while reconnecting are lost, OnReconnect lets handlers
+// recover
+package pgnotify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MaxPayload is the largest payload Postgres accepts in a notification
+const MaxPayload = 7999
+
+// retry bounds the wait between reconnection attempts
+const (
+	minRetry = time.Second
+	maxRetry = 30 * time.Second
+)
+
+type Handler func(payload string)
+
+type Listener struct {
+	pool *pgxpool.Pool
+
+	mu          sync.RWMutex
+	handlers    map[string][]Handler
+	onReconnect []func()
+	onError     func(error)
+}
+
+func NewListener(pool *pgxpool.Pool) *Listener {
+	return &Listener{
+		pool:     pool,
+		handlers: make(map[string][]Handler),
+	}
+}
+
+// Handle registers h for notifications on channel. Channels are listened to from the
+// next connection, so handlers are registered before Run
+func (l *Listener) Handle(channel string, h Handler) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.handlers[channel] = append(l.handlers[channel], h)
+}
+
+// OnReconnect registers fn to run after the connection was re-established
+func (l *Listener) OnReconnect(fn func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.onReconnect = append(l.onReconnect, fn)
+}
+
+// OnError registers fn to observe connection failures, Run retries them on its own
+func (l *Listener) OnError(fn func(error)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.onError = fn
+}
+
+func (l *Listener) Name() string {
+	return "postgres notifications"
+}
+
+// Run listens until ctx is cancelled, reconnecting with backoff on failures
+func (l *Listener) Run(ctx context.Context) error {
+
+	retry := minRetry
+	connected := false
+
+	for {
+		err := l.listen(ctx, func() {
+			if connected {
+				l.reconnected()
+			}
+			connected = true
+			retry = minRetry
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		l.mu.RLock()
+		onError := l.onError
+		l.mu.RUnlock()
+		if onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(retry):
+		}
+		retry = min(retry*2, maxRetry)
+	}
+}
+
+func (l *Listener) listen(ctx context.Context, ready func()) error {
+
+	pooled, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// the session is left LISTENing, it must not go back to the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	l.mu.RLock()
+	channels := make([]string, 0, len(l.handlers))
+	for channel := range l.handlers {
+		channels = append(channels, channel)
+	}
+	l.mu.RUnlock()
+
+	for _, channel := range channels {
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return fmt.Errorf("listen on %s failed %w", channel, err)
+		}
+	}
+
+	ready()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+			return err
+		}
+
+		l.mu.RLock()
+		handlers := l.handlers[notification.Channel]
+		l.mu.RUnlock()
+
+		for _, h := range handlers {
+			h(notification.Payload)
+		}
+	}
+}
+
+func (l *Listener) reconnected() {
+	l.mu.RLock()
+	fns := l.onReconnect
+	l.mu.RUnlock()
+
+	for _, fn := range fns {
+		fn()
+	}
+}
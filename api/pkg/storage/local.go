@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NewLocal stores objects as files under dir. Content types are not persisted, callers
+// keep them alongside the key
+func NewLocal(dir string) (Storage, error) {
+
+	if dir == "" {
+		dir = "uploads"
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("storage directory creation failed %w", err)
+	}
+
+	return &local{root: dir}, nil
+}
+
+type local struct {
+	root string
+}
+
+func (l *local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	// write to a temp file first so a failed upload never leaves a partial object
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (l *local) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+
+	path, err := l.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	return f, &ObjectInfo{Size: stat.Size()}, nil
+}
+
+func (l *local) Delete(ctx context.Context, key string) error {
+
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+// path maps a key inside the root, rejecting keys which would escape it
+func (l *local) path(key string) (string, error) {
+
+	clean := filepath.Clean("/" + key)
+	if clean == "/" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+
+	return filepath.Join(l.root, filepath.FromSlash(clean)), nil
+}
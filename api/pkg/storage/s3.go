@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// NewS3 stores objects in a bucket of an S3 compatible service. The bucket is created
+// when it does not exist, which keeps a local MinIO usable without setup
+func NewS3(ctx context.Context, opts Options) (Storage, error) {
+
+	client, err := minio.New(opts.S3Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(opts.S3AccessKey, opts.S3SecretKey, ""),
+		Secure: opts.S3UseSSL,
+		Region: opts.S3Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s3 client creation failed %w", err)
+	}
+
+	exists, err := client.BucketExists(ctx, opts.S3Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("s3 bucket lookup failed %w", err)
+	}
+
+	if !exists {
+		if err := client.MakeBucket(ctx, opts.S3Bucket, minio.MakeBucketOptions{Region: opts.S3Region}); err != nil {
+			return nil, fmt.Errorf("s3 bucket creation failed %w", err)
+		}
+	}
+
+	return &s3{client: client, bucket: opts.S3Bucket}, nil
+}
+
+type s3 struct {
+	client *minio.Client
+	bucket string
+}
+
+func (s *s3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+
+	return err
+}
+
+func (s *s3) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+
+	obj, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, nil, s.mapError(err)
+	}
+
+	// GetObject is lazy, Stat surfaces a missing key
+	stat, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, nil, s.mapError(err)
+	}
+
+	return obj, &ObjectInfo{Size: stat.Size, ContentType: stat.ContentType}, nil
+}
+
+func (s *s3) Delete(ctx context.Context, key string) error {
+
+	return s.mapError(s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{}))
+}
+
+func (s *s3) mapError(err error) error {
+
+	if err != nil && minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return ErrNotFound
+	}
+
+	return err
+}
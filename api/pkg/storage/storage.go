@@ -0,0 +1,61 @@
+// storage keeps uploaded files in a backend, either a local directory or an S3
+// compatible bucket such as MinIO. Keys are slash separated paths
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Size        int64
+	ContentType string
+}
+
+type Storage interface {
+	// Put streams r to key. size may be -1 when unknown, backends then buffer or use a
+	// multipart upload
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+
+	// Get opens the object for reading, the caller closes it
+	Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error)
+
+	Delete(ctx context.Context, key string) error
+}
+
+type Options struct {
+	Driver string
+
+	// LocalDir is the root directory of the local driver
+	LocalDir string
+
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3UseSSL    bool
+}
+
+// New opens the backend selected by opts.Driver
+func New(ctx context.Context, opts Options) (Storage, error) {
+
+	switch opts.Driver {
+	case DriverLocal, "":
+		return NewLocal(opts.LocalDir)
+	case DriverS3:
+		return NewS3(ctx, opts)
+	}
+
+	return nil, fmt.Errorf("unknown storage driver %q", opts.Driver)
+}
@@ -0,0 +1,240 @@
+// textdiff compares texts line by line. Lines finds a shortest edit script with Myers'
+// algorithm, Hunks groups it into the hunks of a unified diff
+package textdiff
+
+import "strings"
+
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+// MaxEdits caps the edits searched for. Texts differing more are diffed as the old text
+// removed and the new one inserted, which keeps the work of a diff bounded
+const MaxEdits = 1000
+
+// Line is a line of an edit script, Delete lines come from the old text and Insert lines
+// from the new one
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// Hunk is a run of changes along with the unchanged lines around them. FromLine and
+// ToLine are where it starts in the old and new text, counting from 1
+type Hunk struct {
+	FromLine  int
+	FromCount int
+	ToLine    int
+	ToCount   int
+	Lines     []Line
+}
+
+// Split cuts s into lines, a trailing newline does not start another line
+func Split(s string) []string {
+
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(strings.ReplaceAll(s, "\r\n", "\n"), "\n"), "\n")
+}
+
+// Lines returns the edit script turning the lines of a into the lines of b
+func Lines(a, b string) []Line {
+
+	return diff(Split(a), Split(b))
+}
+
+// Count returns how many lines the script inserts and deletes
+func Count(script []Line) (inserted, deleted int) {
+
+	for _, line := range script {
+		switch line.Op {
+		case Insert:
+			inserted++
+		case Delete:
+			deleted++
+		}
+	}
+
+	return inserted, deleted
+}
+
+// Hunks groups the changes of a script with up to context unchanged lines on either
+// side. Changes closer than twice the context share a hunk
+func Hunks(script []Line, context int) []Hunk {
+
+	keep := make([]bool, len(script))
+	for i, line := range script {
+		if line.Op == Equal {
+			continue
+		}
+		for j := max(0, i-context); j <= min(len(script)-1, i+context); j++ {
+			keep[j] = true
+		}
+	}
+
+	var hunks []Hunk
+	var hunk *Hunk
+
+	from, to := 1, 1
+	for i, line := range script {
+		if !keep[i] {
+			hunk = nil
+		} else {
+			if hunk == nil {
+				hunks = append(hunks, Hunk{FromLine: from, ToLine: to})
+				hunk = &hunks[len(hunks)-1]
+			}
+			hunk.Lines = append(hunk.Lines, line)
+			if line.Op != Insert {
+				hunk.FromCount++
+			}
+			if line.Op != Delete {
+				hunk.ToCount++
+			}
+		}
+
+		if line.Op != Insert {
+			from++
+		}
+		if line.Op != Delete {
+			to++
+		}
+	}
+
+	return hunks
+}
+
+func diff(a, b []string) []Line {
+
+	// common ends are cut first, edits usually touch a small part of a text
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	script := make([]Line, 0, max(len(a), len(b)))
+	for _, text := range a[:prefix] {
+		script = append(script, Line{Op: Equal, Text: text})
+	}
+	script = append(script, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, text := range a[len(a)-suffix:] {
+		script = append(script, Line{Op: Equal, Text: text})
+	}
+
+	return script
+}
+
+// myers walks the edit graph of a and b breadth first by number of edits. trace keeps
+// the furthest x reached on every diagonal k = x - y before each round, the script is
+// read back from it
+func myers(a, b []string) []Line {
+
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+
+	limit := min(n+m, MaxEdits)
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+
+	var trace [][]int
+
+	for d := 0; d <= limit; d++ {
+		// only diagonals -d to d can be reached in d edits
+		round := make([]int, 2*d+1)
+		copy(round, v[offset-d:offset+d+1])
+		trace = append(trace, round)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+
+	return replace(a, b)
+}
+
+func backtrack(trace [][]int, a, b []string) []Line {
+
+	var reversed []Line
+
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		round := trace[d]
+		at := func(k int) int { return round[k+d] }
+
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, Line{Op: Equal, Text: a[x]})
+		}
+
+		if x == prevX {
+			y--
+			reversed = append(reversed, Line{Op: Insert, Text: b[y]})
+		} else {
+			x--
+			reversed = append(reversed, Line{Op: Delete, Text: a[x]})
+		}
+	}
+
+	for x > 0 && y > 0 {
+		x--
+		y--
+		reversed = append(reversed, Line{Op: Equal, Text: a[x]})
+	}
+
+	script := make([]Line, len(reversed))
+	for i, line := range reversed {
+		script[len(reversed)-1-i] = line
+	}
+
+	return script
+}
+
+func replace(a, b []string) []Line {
+
+	script := make([]Line, 0, len(a)+len(b))
+	for _, text := range a {
+		script = append(script, Line{Op: Delete, Text: text})
+	}
+	for _, text := range b {
+		script = append(script, Line{Op: Insert, Text: text})
+	}
+
+	return script
+}
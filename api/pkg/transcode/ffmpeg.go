@@ -0,0 +1,171 @@
+package transcode
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// stderrTail is how much of the ffmpeg output is kept for error messages
+const stderrTail = 2048
+
+type ffmpeg struct {
+	ffmpegPath  string
+	ffprobePath string
+}
+
+// NewFFmpeg runs the ffmpeg and ffprobe binaries found on the PATH
+func NewFFmpeg() (Transcoder, error) {
+
+	ffmpegPath, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, err
+	}
+
+	ffprobePath, err := exec.LookPath("ffprobe")
+	if err != nil {
+		return nil, err
+	}
+
+	return &ffmpeg{ffmpegPath: ffmpegPath, ffprobePath: ffprobePath}, nil
+}
+
+func (f *ffmpeg) Probe(ctx context.Context, input string) (*Probe, error) {
+
+	out, err := run(ctx, f.ffprobePath,
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=width,height:format=duration",
+		"-of", "json",
+		input,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var probed struct {
+		Streams []struct {
+			Width  int `json:"width"`
+			Height int `json:"height"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(out, &probed); err != nil {
+		return nil, fmt.Errorf("ffprobe output %w", err)
+	}
+
+	if len(probed.Streams) == 0 || probed.Streams[0].Height == 0 {
+		return nil, fmt.Errorf("no video stream in %s", filepath.Base(input))
+	}
+
+	seconds, _ := strconv.ParseFloat(probed.Format.Duration, 64)
+
+	return &Probe{
+		Width:    probed.Streams[0].Width,
+		Height:   probed.Streams[0].Height,
+		Duration: time.Duration(seconds * float64(time.Second)),
+	}, nil
+}
+
+func (f *ffmpeg) Package(ctx context.Context, input, outDir string, opts Options) ([]Output, error) {
+
+	probe, err := f.Probe(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	keyInfo, err := writeKeyInfo(outDir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	segment := max(1, int(opts.SegmentDuration.Seconds()))
+	outputs := make([]Output, 0, len(opts.Renditions))
+
+	for _, rendition := range opts.Renditions {
+		dir := filepath.Join(outDir, rendition.Name)
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, err
+		}
+
+		// keyframes on segment boundaries keep every segment independently decodable
+		_, err := run(ctx, f.ffmpegPath,
+			"-hide_banner", "-nostdin", "-y",
+			"-i", input,
+			"-map", "0:v:0", "-map", "0:a:0?",
+			"-vf", "scale=-2:"+strconv.Itoa(rendition.Height),
+			"-c:v", "libx264", "-preset", "veryfast", "-profile:v", "main", "-pix_fmt", "yuv420p",
+			"-b:v", strconv.Itoa(rendition.VideoBitrate)+"k",
+			"-maxrate", strconv.Itoa(rendition.VideoBitrate*107/100)+"k",
+			"-bufsize", strconv.Itoa(rendition.VideoBitrate*3/2)+"k",
+			"-force_key_frames", "expr:gte(t,n_forced*"+strconv.Itoa(segment)+")",
+			"-c:a", "aac", "-ac", "2", "-b:a", strconv.Itoa(rendition.AudioBitrate)+"k",
+			"-f", "hls",
+			"-hls_time", strconv.Itoa(segment),
+			"-hls_playlist_type", "vod",
+			"-hls_key_info_file", keyInfo,
+			"-hls_segment_filename", filepath.Join(dir, "seg_%05d.ts"),
+			filepath.Join(dir, PlaylistName),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("rendition %s %w", rendition.Name, err)
+		}
+
+		outputs = append(outputs, Output{
+			Rendition: rendition,
+			Width:     scaledWidth(probe, rendition.Height),
+			Dir:       dir,
+		})
+	}
+
+	return outputs, nil
+}
+
+// writeKeyInfo writes the key and the key info file ffmpeg reads them from
+func writeKeyInfo(outDir string, opts Options) (string, error) {
+
+	if len(opts.Key) != KeySize || len(opts.IV) != KeySize {
+		return "", fmt.Errorf("segment key and iv must be %d bytes", KeySize)
+	}
+
+	keyPath := filepath.Join(outDir, "segment.key")
+	if err := os.WriteFile(keyPath, opts.Key, 0o600); err != nil {
+		return "", err
+	}
+
+	infoPath := filepath.Join(outDir, "segment.keyinfo")
+	info := opts.KeyURI + "\n" + keyPath + "\n" + hex.EncodeToString(opts.IV) + "\n"
+	if err := os.WriteFile(infoPath, []byte(info), 0o600); err != nil {
+		return "", err
+	}
+
+	return infoPath, nil
+}
+
+func run(ctx context.Context, name string, args ...string) ([]byte, error) {
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		tail := stderr.Bytes()
+		if len(tail) > stderrTail {
+			tail = tail[len(tail)-stderrTail:]
+		}
+		return nil, fmt.Errorf("%s failed %w: %s", filepath.Base(name), err, bytes.TrimSpace(tail))
+	}
+
+	return stdout.Bytes(), nil
+}
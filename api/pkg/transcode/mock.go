@@ -0,0 +1,107 @@
+package transcode
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// mockSegments is how many segments the mock writes per rendition
+const mockSegments = 2
+
+// tsPacketSize is the length of an MPEG transport stream packet
+const tsPacketSize = 188
+
+type mock struct{}
+
+// NewMock packages any input into a fixed 1280x720 video of empty transport stream
+// packets. It needs no binaries, for development machines without ffmpeg and tests
+func NewMock() Transcoder {
+	return mock{}
+}
+
+func (mock) Probe(ctx context.Context, input string) (*Probe, error) {
+
+	if _, err := os.Stat(input); err != nil {
+		return nil, err
+	}
+
+	return &Probe{Width: 1280, Height: 720, Duration: 12 * time.Second}, nil
+}
+
+func (m mock) Package(ctx context.Context, input, outDir string, opts Options) ([]Output, error) {
+
+	probe, err := m.Probe(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(opts.Key) != KeySize || len(opts.IV) != KeySize {
+		return nil, fmt.Errorf("segment key and iv must be %d bytes", KeySize)
+	}
+
+	block, err := aes.NewCipher(opts.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	segment := max(time.Second, opts.SegmentDuration)
+	outputs := make([]Output, 0, len(opts.Renditions))
+
+	for _, rendition := range opts.Renditions {
+		dir := filepath.Join(outDir, rendition.Name)
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, err
+		}
+
+		var playlist strings.Builder
+		playlist.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+		fmt.Fprintf(&playlist, "#EXT-X-TARGETDURATION:%d\n", int(segment.Seconds()))
+		playlist.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PLAYLIST-TYPE:VOD\n")
+		fmt.Fprintf(&playlist, "#EXT-X-KEY:METHOD=AES-128,URI=\"%s\",IV=0x%s\n", opts.KeyURI, hex.EncodeToString(opts.IV))
+
+		for i := 0; i < mockSegments; i++ {
+			name := fmt.Sprintf("seg_%05d.ts", i)
+
+			// segments are AES-128-CBC with PKCS#7 padding, as players expect
+			packet := append([]byte{0x47}, bytes.Repeat([]byte{0xff}, tsPacketSize-1)...)
+			plaintext := pad(bytes.Repeat(packet, 16))
+
+			sealed := make([]byte, len(plaintext))
+			cipher.NewCBCEncrypter(block, opts.IV).CryptBlocks(sealed, plaintext)
+
+			if err := os.WriteFile(filepath.Join(dir, name), sealed, 0o600); err != nil {
+				return nil, err
+			}
+
+			fmt.Fprintf(&playlist, "#EXTINF:%.6f,\n%s\n", segment.Seconds(), name)
+		}
+
+		playlist.WriteString("#EXT-X-ENDLIST\n")
+
+		if err := os.WriteFile(filepath.Join(dir, PlaylistName), []byte(playlist.String()), 0o600); err != nil {
+			return nil, err
+		}
+
+		outputs = append(outputs, Output{
+			Rendition: rendition,
+			Width:     scaledWidth(probe, rendition.Height),
+			Dir:       dir,
+		})
+	}
+
+	return outputs, nil
+}
+
+func pad(b []byte) []byte {
+
+	n := aes.BlockSize - len(b)%aes.BlockSize
+	return append(b, bytes.Repeat([]byte{byte(n)}, n)...)
+}
@@ -0,0 +1,48 @@
+package transcode
+
+import (
+	"regexp"
+	"strings"
+)
+
+// keyURIPattern matches the URI attribute of an EXT-X-KEY tag
+var keyURIPattern = regexp.MustCompile(`URI="[^"]*"`)
+
+// Segments lists the segment uris of a media playlist in order
+func Segments(playlist string) []string {
+
+	var segments []string
+
+	for _, line := range strings.Split(playlist, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			segments = append(segments, line)
+		}
+	}
+
+	return segments
+}
+
+// Rewrite points the EXT-X-KEY tags of a media playlist at keyURI and replaces every
+// segment uri by what segmentURI returns for it
+func Rewrite(playlist, keyURI string, segmentURI func(segment string) string) string {
+
+	var out strings.Builder
+	out.Grow(len(playlist) * 2)
+
+	for _, line := range strings.Split(strings.TrimRight(playlist, "\n"), "\n") {
+		line = strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-KEY:"):
+			line = keyURIPattern.ReplaceAllLiteralString(line, `URI="`+keyURI+`"`)
+		case line != "" && !strings.HasPrefix(line, "#"):
+			line = segmentURI(line)
+		}
+
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+
+	return out.String()
+}
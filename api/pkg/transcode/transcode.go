@@ -0,0 +1,116 @@
+// transcode packages videos into HLS renditions. Segments are encrypted with
+// AES-128, the key is fetched by players from a url written into the playlists
+package transcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+const (
+	DriverFFmpeg = "ffmpeg"
+	DriverMock   = "mock"
+	DriverNone   = "none"
+)
+
+// PlaylistName is the media playlist written into the directory of every rendition,
+// next to its segments
+const PlaylistName = "index.m3u8"
+
+// KeySize is the length of the AES-128 segment key and its IV
+const KeySize = 16
+
+var ErrUnknownDriver = errors.New("unknown transcoder driver")
+
+// Rendition is an output quality, scaled to Height keeping the aspect ratio
+type Rendition struct {
+	Name string
+	// Height in pixels, the width follows the source aspect ratio
+	Height int
+	// VideoBitrate and AudioBitrate in kbit/s
+	VideoBitrate int
+	AudioBitrate int
+}
+
+// Bandwidth is the peak bits per second announced in the master playlist, with some
+// headroom for the container overhead
+func (r Rendition) Bandwidth() int {
+	return (r.VideoBitrate + r.AudioBitrate) * 1100
+}
+
+// Probe describes the video stream of a file
+type Probe struct {
+	Width    int
+	Height   int
+	Duration time.Duration
+}
+
+type Options struct {
+	Renditions []Rendition
+	// SegmentDuration is the target length of a segment
+	SegmentDuration time.Duration
+	// Key and IV encrypt every segment with AES-128. KeyURI is written into the
+	// playlists, it is usually replaced when they are served
+	Key    []byte
+	IV     []byte
+	KeyURI string
+}
+
+// Output is a packaged rendition. Dir holds PlaylistName and the segments it lists
+type Output struct {
+	Rendition
+	Width int
+	Dir   string
+}
+
+type Transcoder interface {
+	// Probe reads the dimensions and duration of the video in input
+	Probe(ctx context.Context, input string) (*Probe, error)
+
+	// Package transcodes input into a directory per rendition below outDir
+	Package(ctx context.Context, input, outDir string, opts Options) ([]Output, error)
+}
+
+// Detect names the driver used when none is configured, ffmpeg when it is found on
+// the PATH and none otherwise
+func Detect() string {
+
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return DriverNone
+	}
+	if _, err := exec.LookPath("ffprobe"); err != nil {
+		return DriverNone
+	}
+
+	return DriverFFmpeg
+}
+
+// New creates the transcoder of driver, nil for DriverNone
+func New(driver string) (Transcoder, error) {
+
+	switch driver {
+	case DriverFFmpeg:
+		return NewFFmpeg()
+	case DriverMock:
+		return NewMock(), nil
+	case DriverNone:
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownDriver, driver)
+}
+
+// scaledWidth keeps the aspect ratio of the source at height, rounded to the even
+// widths h264 requires
+func scaledWidth(probe *Probe, height int) int {
+
+	if probe.Height == 0 {
+		return 0
+	}
+
+	width := probe.Width * height / probe.Height
+	return width + width%2
+}
@@ -0,0 +1,335 @@
+// webpush sends Web Push messages (RFC 8030) to browser push services. Payloads are
+// encrypted for the subscription with aes128gcm (RFC 8291) and requests are signed
+// with the application server's VAPID key (RFC 8292)
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// recordSize is the aes128gcm record size, messages are sent as a single record
+	recordSize = 4096
+	// headerSize is salt, record size, key id length and the 65 byte key id
+	headerSize = 16 + 4 + 1 + 65
+	// MaxPayload is the largest plaintext which fits the 4096 bytes push services
+	// accept, the padding delimiter and the GCM tag take 17 bytes
+	MaxPayload = recordSize - headerSize - 17
+
+	// tokenTTL is how long a VAPID token is valid, push services refuse over 24 hours
+	tokenTTL = 12 * time.Hour
+)
+
+var (
+	// ErrGone is returned when the push service no longer knows the subscription, it
+	// should be removed
+	ErrGone            = errors.New("push subscription is gone")
+	ErrPayloadTooLarge = errors.New("push payload is too large")
+	ErrKeysInvalid     = errors.New("push subscription keys are invalid")
+	ErrVAPIDInvalid    = errors.New("vapid keys are invalid")
+)
+
+// Subscription is what PushSubscription.toJSON() returns in the browser, keys are
+// base64url encoded
+type Subscription struct {
+	Endpoint string
+	P256dh   string
+	Auth     string
+}
+
+type Options struct {
+	// TTL is how long the push service keeps an undelivered message
+	TTL time.Duration
+	// Urgency is very-low, low, normal or high, empty leaves it to the push service
+	Urgency string
+	// Topic replaces a pending message with the same topic
+	Topic string
+}
+
+// StatusError is a message the push service rejected
+type StatusError struct {
+	StatusCode int
+	// RetryAfter is the wait the push service asked for, 0 when it gave none
+	RetryAfter time.Duration
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("push service answered %d: %s", e.StatusCode, e.Body)
+}
+
+// Temporary reports if the message may be accepted later
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// VAPID is the key pair identifying the application server to push services
+type VAPID struct {
+	// PublicKey is the uncompressed public key, base64url encoded. Browsers take it as
+	// the applicationServerKey when subscribing
+	PublicKey string
+	Subject   string
+
+	key *ecdsa.PrivateKey
+}
+
+// ParseVAPID reads a key pair made by GenerateVAPID. subject is a mailto: or https:
+// contact for the push service operators
+func ParseVAPID(publicKey, privateKey, subject string) (*VAPID, error) {
+
+	raw, err := base64.RawURLEncoding.DecodeString(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: private key is not base64url", ErrVAPIDInvalid)
+	}
+
+	key, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrVAPIDInvalid, err)
+	}
+
+	public, err := key.PublicKey.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrVAPIDInvalid, err)
+	}
+
+	if base64.RawURLEncoding.EncodeToString(public) != publicKey {
+		return nil, fmt.Errorf("%w: public key does not match the private key", ErrVAPIDInvalid)
+	}
+
+	if subject == "" {
+		return nil, fmt.Errorf("%w: subject is required", ErrVAPIDInvalid)
+	}
+
+	return &VAPID{PublicKey: publicKey, Subject: subject, key: key}, nil
+}
+
+// GenerateVAPID returns a new key pair, base64url encoded
+func GenerateVAPID() (publicKey, privateKey string, err error) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+
+	public, err := key.PublicKey.Bytes()
+	if err != nil {
+		return "", "", err
+	}
+
+	private, err := key.Bytes()
+	if err != nil {
+		return "", "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(public), base64.RawURLEncoding.EncodeToString(private), nil
+}
+
+// token signs the claim that the application server may push to audience
+func (v *VAPID) token(audience string) (string, error) {
+
+	return jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": audience,
+		"exp": time.Now().Add(tokenTTL).Unix(),
+		"sub": v.Subject,
+	}).SignedString(v.key)
+}
+
+type Client struct {
+	vapid    *VAPID
+	http     *http.Client
+	endpoint string
+}
+
+type ClientOptions struct {
+	// Endpoint replaces the endpoint of every subscription when set, so messages can be
+	// sent to a local test server
+	Endpoint string
+	Timeout  time.Duration
+}
+
+func NewClient(vapid *VAPID, opts ClientOptions) *Client {
+
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+
+	return &Client{
+		vapid:    vapid,
+		http:     &http.Client{Timeout: opts.Timeout},
+		endpoint: opts.Endpoint,
+	}
+}
+
+// Send encrypts payload for the subscription and hands it to its push service.
+// ErrGone is returned for subscriptions which expired or were revoked, *StatusError
+// for other rejections
+func (c *Client) Send(ctx context.Context, sub Subscription, payload []byte, opts Options) error {
+
+	body, err := Encrypt(sub, payload)
+	if err != nil {
+		return err
+	}
+
+	endpoint := sub.Endpoint
+	if c.endpoint != "" {
+		endpoint = c.endpoint
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("push endpoint %q is invalid", endpoint)
+	}
+
+	token, err := c.vapid.token(u.Scheme + "://" + u.Host)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(opts.TTL.Seconds())))
+	req.Header.Set("Authorization", "vapid t="+token+", k="+c.vapid.PublicKey)
+	if opts.Urgency != "" {
+		req.Header.Set("Urgency", opts.Urgency)
+	}
+	if opts.Topic != "" {
+		req.Header.Set("Topic", opts.Topic)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		io.Copy(io.Discard, res.Body)
+		return nil
+	}
+
+	if res.StatusCode == http.StatusGone || res.StatusCode == http.StatusNotFound {
+		return ErrGone
+	}
+
+	detail, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+
+	statusErr := &StatusError{StatusCode: res.StatusCode, Body: string(bytes.TrimSpace(detail))}
+	if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		statusErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	return statusErr
+}
+
+// Encrypt seals payload for the subscription as a single aes128gcm record (RFC 8291)
+func Encrypt(sub Subscription, payload []byte) ([]byte, error) {
+
+	if len(payload) > MaxPayload {
+		return nil, ErrPayloadTooLarge
+	}
+
+	uaPublic, err := base64.RawURLEncoding.DecodeString(trimPadding(sub.P256dh))
+	if err != nil {
+		return nil, ErrKeysInvalid
+	}
+	authSecret, err := base64.RawURLEncoding.DecodeString(trimPadding(sub.Auth))
+	if err != nil || len(authSecret) != 16 {
+		return nil, ErrKeysInvalid
+	}
+
+	// a fresh application server key and salt per message
+	asKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	return seal(uaPublic, authSecret, payload, asKey, salt)
+}
+
+func seal(uaPublic, authSecret, payload []byte, asKey *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+
+	uaKey, err := ecdh.P256().NewPublicKey(uaPublic)
+	if err != nil {
+		return nil, ErrKeysInvalid
+	}
+	asPublic := asKey.PublicKey().Bytes()
+
+	ecdhSecret, err := asKey.ECDH(uaKey)
+	if err != nil {
+		return nil, err
+	}
+
+	keyInfo := "WebPush: info\x00" + string(uaPublic) + string(asPublic)
+	ikm, err := hkdf.Key(sha256.New, ecdhSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, headerSize)
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, recordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+
+	// 0x02 delimits the last record
+	plaintext := append(append([]byte{}, payload...), 0x02)
+
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
+
+// trimPadding accepts keys sent with base64 padding
+func trimPadding(s string) string {
+	return string(bytes.TrimRight([]byte(s), "="))
+}